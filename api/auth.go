@@ -0,0 +1,171 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// Scope of the routes a credential has access to
+type Scope int
+
+const (
+	// ScopeRead only gives access to the routes not modifying anything
+	ScopeRead Scope = iota
+	// ScopeAdmin gives access to all the routes
+	ScopeAdmin
+)
+
+// ParseScope parses "read" or "admin"
+func ParseScope(s string) (Scope, error) {
+	switch s {
+	case "read":
+		return ScopeRead, nil
+	case "admin":
+		return ScopeAdmin, nil
+	}
+
+	return ScopeRead, fmt.Errorf("unknown scope %q, must be \"read\" or \"admin\"", s)
+}
+
+type user struct {
+	name     string
+	password string
+	scope    Scope
+	tenant   string
+}
+
+type token struct {
+	value  string
+	scope  Scope
+	tenant string
+}
+
+// identity is the authenticated client of a request
+type identity struct {
+	actor string
+	scope Scope
+	// tenant when set, the client only has access to the jobs it owns
+	tenant string
+}
+
+// canAccess returns true if the job is visible to the client
+func (id identity) canAccess(j core.Job) bool {
+	return id.tenant == "" || core.JobOwner(j) == id.tenant
+}
+
+// parseScopeTenant parses a scope, optionally restricted to a tenant, as
+// "scope@tenant"
+func parseScopeTenant(s string) (Scope, string, error) {
+	var tenant string
+	if i := strings.Index(s, "@"); i >= 0 {
+		s, tenant = s[:i], s[i+1:]
+	}
+
+	scope, err := ParseScope(s)
+	return scope, tenant, err
+}
+
+// parseUser parses a basic auth credential, as "name:password:scope", the
+// scope can be restricted to a tenant as "scope@tenant"
+func parseUser(s string) (user, error) {
+	i, j := strings.Index(s, ":"), strings.LastIndex(s, ":")
+	if i <= 0 || i == j {
+		return user{}, fmt.Errorf("invalid user, must be \"name:password:scope\"")
+	}
+
+	scope, tenant, err := parseScopeTenant(s[j+1:])
+	if err != nil {
+		return user{}, err
+	}
+
+	return user{name: s[:i], password: s[i+1 : j], scope: scope, tenant: tenant}, nil
+}
+
+// parseToken parses a bearer token credential, as "token:scope", the scope
+// can be restricted to a tenant as "scope@tenant"
+func parseToken(s string) (token, error) {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 {
+		return token{}, fmt.Errorf("invalid token, must be \"token:scope\"")
+	}
+
+	scope, tenant, err := parseScopeTenant(s[i+1:])
+	if err != nil {
+		return token{}, err
+	}
+
+	return token{value: s[:i], scope: scope, tenant: tenant}, nil
+}
+
+// authenticate returns the identity of the client of the request, false if
+// the request doesn't carry valid credentials. Without credentials configured
+// every client is admin.
+func (s *Server) authenticate(r *http.Request) (identity, bool) {
+	actor := "anonymous"
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		actor = r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+
+	if len(s.users) == 0 && len(s.tokens) == 0 {
+		return identity{actor: actor, scope: ScopeAdmin}, true
+	}
+
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		value := strings.TrimPrefix(auth, "Bearer ")
+		for i, t := range s.tokens {
+			if equal(value, t.value) {
+				return identity{actor: fmt.Sprintf("token#%d", i+1), scope: t.scope, tenant: t.tenant}, true
+			}
+		}
+
+		return identity{}, false
+	}
+
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return identity{}, false
+	}
+
+	for _, u := range s.users {
+		if equal(name, u.name) && equal(password, u.password) {
+			return identity{actor: u.name, scope: u.scope, tenant: u.tenant}, true
+		}
+	}
+
+	return identity{}, false
+}
+
+// authenticated returns true if the clients must authenticate, with
+// credentials or a client certificate, every client being admin otherwise
+func (s *Server) authenticated() bool {
+	return len(s.users) > 0 || len(s.tokens) > 0 || s.Config.TLSClientCA != ""
+}
+
+// checkListen returns an error if the given address isn't a loopback one
+// while the clients aren't authenticated, any client reaching it would be
+// admin otherwise
+func (s *Server) checkListen(addr string) error {
+	if s.authenticated() {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+
+	return fmt.Errorf("refusing to listen on %q without credentials, every client would be admin, listen on a loopback address or configure users, tokens or a TLS client CA", addr)
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
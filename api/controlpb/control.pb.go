@@ -0,0 +1,1064 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: control.proto
+
+package controlpb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type ListJobsRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListJobsRequest) Reset()         { *m = ListJobsRequest{} }
+func (m *ListJobsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListJobsRequest) ProtoMessage()    {}
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{0}
+}
+
+func (m *ListJobsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListJobsRequest.Unmarshal(m, b)
+}
+func (m *ListJobsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListJobsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListJobsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListJobsRequest.Merge(m, src)
+}
+func (m *ListJobsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListJobsRequest.Size(m)
+}
+func (m *ListJobsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListJobsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListJobsRequest proto.InternalMessageInfo
+
+type ListJobsResponse struct {
+	Jobs                 []*Job   `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListJobsResponse) Reset()         { *m = ListJobsResponse{} }
+func (m *ListJobsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListJobsResponse) ProtoMessage()    {}
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{1}
+}
+
+func (m *ListJobsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListJobsResponse.Unmarshal(m, b)
+}
+func (m *ListJobsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListJobsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListJobsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListJobsResponse.Merge(m, src)
+}
+func (m *ListJobsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListJobsResponse.Size(m)
+}
+func (m *ListJobsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListJobsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListJobsResponse proto.InternalMessageInfo
+
+func (m *ListJobsResponse) GetJobs() []*Job {
+	if m != nil {
+		return m.Jobs
+	}
+	return nil
+}
+
+type Job struct {
+	Name                 string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Schedule             string            `protobuf:"bytes,2,opt,name=schedule,proto3" json:"schedule,omitempty"`
+	Command              string            `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	Owner                string            `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
+	Description          string            `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	Tags                 map[string]string `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Job) Reset()         { *m = Job{} }
+func (m *Job) String() string { return proto.CompactTextString(m) }
+func (*Job) ProtoMessage()    {}
+func (*Job) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{2}
+}
+
+func (m *Job) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Job.Unmarshal(m, b)
+}
+func (m *Job) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Job.Marshal(b, m, deterministic)
+}
+func (m *Job) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Job.Merge(m, src)
+}
+func (m *Job) XXX_Size() int {
+	return xxx_messageInfo_Job.Size(m)
+}
+func (m *Job) XXX_DiscardUnknown() {
+	xxx_messageInfo_Job.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Job proto.InternalMessageInfo
+
+func (m *Job) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Job) GetSchedule() string {
+	if m != nil {
+		return m.Schedule
+	}
+	return ""
+}
+
+func (m *Job) GetCommand() string {
+	if m != nil {
+		return m.Command
+	}
+	return ""
+}
+
+func (m *Job) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *Job) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Job) GetTags() map[string]string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+type ListExecutionsRequest struct {
+	// job when set, only the executions of this job are listed
+	Job                  string   `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListExecutionsRequest) Reset()         { *m = ListExecutionsRequest{} }
+func (m *ListExecutionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListExecutionsRequest) ProtoMessage()    {}
+func (*ListExecutionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{3}
+}
+
+func (m *ListExecutionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListExecutionsRequest.Unmarshal(m, b)
+}
+func (m *ListExecutionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListExecutionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListExecutionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListExecutionsRequest.Merge(m, src)
+}
+func (m *ListExecutionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListExecutionsRequest.Size(m)
+}
+func (m *ListExecutionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListExecutionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListExecutionsRequest proto.InternalMessageInfo
+
+func (m *ListExecutionsRequest) GetJob() string {
+	if m != nil {
+		return m.Job
+	}
+	return ""
+}
+
+type ListExecutionsResponse struct {
+	// executions in progress first, then the recent ones, newest first
+	Executions           []*Execution `protobuf:"bytes,1,rep,name=executions,proto3" json:"executions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ListExecutionsResponse) Reset()         { *m = ListExecutionsResponse{} }
+func (m *ListExecutionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListExecutionsResponse) ProtoMessage()    {}
+func (*ListExecutionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{4}
+}
+
+func (m *ListExecutionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListExecutionsResponse.Unmarshal(m, b)
+}
+func (m *ListExecutionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListExecutionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListExecutionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListExecutionsResponse.Merge(m, src)
+}
+func (m *ListExecutionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListExecutionsResponse.Size(m)
+}
+func (m *ListExecutionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListExecutionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListExecutionsResponse proto.InternalMessageInfo
+
+func (m *ListExecutionsResponse) GetExecutions() []*Execution {
+	if m != nil {
+		return m.Executions
+	}
+	return nil
+}
+
+type Execution struct {
+	Id   string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Job  string               `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	Date *timestamp.Timestamp `protobuf:"bytes,3,opt,name=date,proto3" json:"date,omitempty"`
+	// duration elapsed time of the executions in progress
+	Duration *duration.Duration `protobuf:"bytes,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	Running  bool               `protobuf:"varint,5,opt,name=running,proto3" json:"running,omitempty"`
+	Failed   bool               `protobuf:"varint,6,opt,name=failed,proto3" json:"failed,omitempty"`
+	Skipped  bool               `protobuf:"varint,7,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	Error    string             `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	ExitCode int32              `protobuf:"varint,9,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	// stdout and stderr only set by GetExecution
+	Stdout               string            `protobuf:"bytes,10,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr               string            `protobuf:"bytes,11,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	Params               map[string]string `protobuf:"bytes,12,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Execution) Reset()         { *m = Execution{} }
+func (m *Execution) String() string { return proto.CompactTextString(m) }
+func (*Execution) ProtoMessage()    {}
+func (*Execution) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{5}
+}
+
+func (m *Execution) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Execution.Unmarshal(m, b)
+}
+func (m *Execution) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Execution.Marshal(b, m, deterministic)
+}
+func (m *Execution) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Execution.Merge(m, src)
+}
+func (m *Execution) XXX_Size() int {
+	return xxx_messageInfo_Execution.Size(m)
+}
+func (m *Execution) XXX_DiscardUnknown() {
+	xxx_messageInfo_Execution.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Execution proto.InternalMessageInfo
+
+func (m *Execution) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Execution) GetJob() string {
+	if m != nil {
+		return m.Job
+	}
+	return ""
+}
+
+func (m *Execution) GetDate() *timestamp.Timestamp {
+	if m != nil {
+		return m.Date
+	}
+	return nil
+}
+
+func (m *Execution) GetDuration() *duration.Duration {
+	if m != nil {
+		return m.Duration
+	}
+	return nil
+}
+
+func (m *Execution) GetRunning() bool {
+	if m != nil {
+		return m.Running
+	}
+	return false
+}
+
+func (m *Execution) GetFailed() bool {
+	if m != nil {
+		return m.Failed
+	}
+	return false
+}
+
+func (m *Execution) GetSkipped() bool {
+	if m != nil {
+		return m.Skipped
+	}
+	return false
+}
+
+func (m *Execution) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Execution) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
+func (m *Execution) GetStdout() string {
+	if m != nil {
+		return m.Stdout
+	}
+	return ""
+}
+
+func (m *Execution) GetStderr() string {
+	if m != nil {
+		return m.Stderr
+	}
+	return ""
+}
+
+func (m *Execution) GetParams() map[string]string {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+type GetExecutionRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetExecutionRequest) Reset()         { *m = GetExecutionRequest{} }
+func (m *GetExecutionRequest) String() string { return proto.CompactTextString(m) }
+func (*GetExecutionRequest) ProtoMessage()    {}
+func (*GetExecutionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{6}
+}
+
+func (m *GetExecutionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetExecutionRequest.Unmarshal(m, b)
+}
+func (m *GetExecutionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetExecutionRequest.Marshal(b, m, deterministic)
+}
+func (m *GetExecutionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetExecutionRequest.Merge(m, src)
+}
+func (m *GetExecutionRequest) XXX_Size() int {
+	return xxx_messageInfo_GetExecutionRequest.Size(m)
+}
+func (m *GetExecutionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetExecutionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetExecutionRequest proto.InternalMessageInfo
+
+func (m *GetExecutionRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type TriggerJobRequest struct {
+	Job string `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	// params available to the command template of the job
+	Params               map[string]string `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *TriggerJobRequest) Reset()         { *m = TriggerJobRequest{} }
+func (m *TriggerJobRequest) String() string { return proto.CompactTextString(m) }
+func (*TriggerJobRequest) ProtoMessage()    {}
+func (*TriggerJobRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{7}
+}
+
+func (m *TriggerJobRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TriggerJobRequest.Unmarshal(m, b)
+}
+func (m *TriggerJobRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TriggerJobRequest.Marshal(b, m, deterministic)
+}
+func (m *TriggerJobRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TriggerJobRequest.Merge(m, src)
+}
+func (m *TriggerJobRequest) XXX_Size() int {
+	return xxx_messageInfo_TriggerJobRequest.Size(m)
+}
+func (m *TriggerJobRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TriggerJobRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TriggerJobRequest proto.InternalMessageInfo
+
+func (m *TriggerJobRequest) GetJob() string {
+	if m != nil {
+		return m.Job
+	}
+	return ""
+}
+
+func (m *TriggerJobRequest) GetParams() map[string]string {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+type TriggerJobResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TriggerJobResponse) Reset()         { *m = TriggerJobResponse{} }
+func (m *TriggerJobResponse) String() string { return proto.CompactTextString(m) }
+func (*TriggerJobResponse) ProtoMessage()    {}
+func (*TriggerJobResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{8}
+}
+
+func (m *TriggerJobResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TriggerJobResponse.Unmarshal(m, b)
+}
+func (m *TriggerJobResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TriggerJobResponse.Marshal(b, m, deterministic)
+}
+func (m *TriggerJobResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TriggerJobResponse.Merge(m, src)
+}
+func (m *TriggerJobResponse) XXX_Size() int {
+	return xxx_messageInfo_TriggerJobResponse.Size(m)
+}
+func (m *TriggerJobResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TriggerJobResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TriggerJobResponse proto.InternalMessageInfo
+
+type CancelRunRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelRunRequest) Reset()         { *m = CancelRunRequest{} }
+func (m *CancelRunRequest) String() string { return proto.CompactTextString(m) }
+func (*CancelRunRequest) ProtoMessage()    {}
+func (*CancelRunRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{9}
+}
+
+func (m *CancelRunRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelRunRequest.Unmarshal(m, b)
+}
+func (m *CancelRunRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelRunRequest.Marshal(b, m, deterministic)
+}
+func (m *CancelRunRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelRunRequest.Merge(m, src)
+}
+func (m *CancelRunRequest) XXX_Size() int {
+	return xxx_messageInfo_CancelRunRequest.Size(m)
+}
+func (m *CancelRunRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelRunRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelRunRequest proto.InternalMessageInfo
+
+func (m *CancelRunRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type CancelRunResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelRunResponse) Reset()         { *m = CancelRunResponse{} }
+func (m *CancelRunResponse) String() string { return proto.CompactTextString(m) }
+func (*CancelRunResponse) ProtoMessage()    {}
+func (*CancelRunResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{10}
+}
+
+func (m *CancelRunResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelRunResponse.Unmarshal(m, b)
+}
+func (m *CancelRunResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelRunResponse.Marshal(b, m, deterministic)
+}
+func (m *CancelRunResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelRunResponse.Merge(m, src)
+}
+func (m *CancelRunResponse) XXX_Size() int {
+	return xxx_messageInfo_CancelRunResponse.Size(m)
+}
+func (m *CancelRunResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelRunResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelRunResponse proto.InternalMessageInfo
+
+type StreamLogsRequest struct {
+	// job when set, only the executions of this job are streamed
+	Job                  string   `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamLogsRequest) Reset()         { *m = StreamLogsRequest{} }
+func (m *StreamLogsRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamLogsRequest) ProtoMessage()    {}
+func (*StreamLogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{11}
+}
+
+func (m *StreamLogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamLogsRequest.Unmarshal(m, b)
+}
+func (m *StreamLogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamLogsRequest.Marshal(b, m, deterministic)
+}
+func (m *StreamLogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamLogsRequest.Merge(m, src)
+}
+func (m *StreamLogsRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamLogsRequest.Size(m)
+}
+func (m *StreamLogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamLogsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamLogsRequest proto.InternalMessageInfo
+
+func (m *StreamLogsRequest) GetJob() string {
+	if m != nil {
+		return m.Job
+	}
+	return ""
+}
+
+type LogEntry struct {
+	Job         string               `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	ExecutionId string               `protobuf:"bytes,2,opt,name=execution_id,json=executionId,proto3" json:"execution_id,omitempty"`
+	Date        *timestamp.Timestamp `protobuf:"bytes,3,opt,name=date,proto3" json:"date,omitempty"`
+	// level "notice", "warning" or "error"
+	Level                string   `protobuf:"bytes,4,opt,name=level,proto3" json:"level,omitempty"`
+	Text                 string   `protobuf:"bytes,5,opt,name=text,proto3" json:"text,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LogEntry) Reset()         { *m = LogEntry{} }
+func (m *LogEntry) String() string { return proto.CompactTextString(m) }
+func (*LogEntry) ProtoMessage()    {}
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0c5120591600887d, []int{12}
+}
+
+func (m *LogEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogEntry.Unmarshal(m, b)
+}
+func (m *LogEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogEntry.Marshal(b, m, deterministic)
+}
+func (m *LogEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogEntry.Merge(m, src)
+}
+func (m *LogEntry) XXX_Size() int {
+	return xxx_messageInfo_LogEntry.Size(m)
+}
+func (m *LogEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogEntry proto.InternalMessageInfo
+
+func (m *LogEntry) GetJob() string {
+	if m != nil {
+		return m.Job
+	}
+	return ""
+}
+
+func (m *LogEntry) GetExecutionId() string {
+	if m != nil {
+		return m.ExecutionId
+	}
+	return ""
+}
+
+func (m *LogEntry) GetDate() *timestamp.Timestamp {
+	if m != nil {
+		return m.Date
+	}
+	return nil
+}
+
+func (m *LogEntry) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *LogEntry) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*ListJobsRequest)(nil), "ofelia.control.v1.ListJobsRequest")
+	proto.RegisterType((*ListJobsResponse)(nil), "ofelia.control.v1.ListJobsResponse")
+	proto.RegisterType((*Job)(nil), "ofelia.control.v1.Job")
+	proto.RegisterMapType((map[string]string)(nil), "ofelia.control.v1.Job.TagsEntry")
+	proto.RegisterType((*ListExecutionsRequest)(nil), "ofelia.control.v1.ListExecutionsRequest")
+	proto.RegisterType((*ListExecutionsResponse)(nil), "ofelia.control.v1.ListExecutionsResponse")
+	proto.RegisterType((*Execution)(nil), "ofelia.control.v1.Execution")
+	proto.RegisterMapType((map[string]string)(nil), "ofelia.control.v1.Execution.ParamsEntry")
+	proto.RegisterType((*GetExecutionRequest)(nil), "ofelia.control.v1.GetExecutionRequest")
+	proto.RegisterType((*TriggerJobRequest)(nil), "ofelia.control.v1.TriggerJobRequest")
+	proto.RegisterMapType((map[string]string)(nil), "ofelia.control.v1.TriggerJobRequest.ParamsEntry")
+	proto.RegisterType((*TriggerJobResponse)(nil), "ofelia.control.v1.TriggerJobResponse")
+	proto.RegisterType((*CancelRunRequest)(nil), "ofelia.control.v1.CancelRunRequest")
+	proto.RegisterType((*CancelRunResponse)(nil), "ofelia.control.v1.CancelRunResponse")
+	proto.RegisterType((*StreamLogsRequest)(nil), "ofelia.control.v1.StreamLogsRequest")
+	proto.RegisterType((*LogEntry)(nil), "ofelia.control.v1.LogEntry")
+}
+
+func init() { proto.RegisterFile("control.proto", fileDescriptor_0c5120591600887d) }
+
+var fileDescriptor_0c5120591600887d = []byte{
+	// 788 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0x5f, 0x4f, 0xd4, 0x4e,
+	0x14, 0x4d, 0xf7, 0x1f, 0xbb, 0x77, 0xf9, 0xf1, 0x63, 0x07, 0x24, 0xb5, 0x18, 0x5d, 0x2b, 0x98,
+	0x85, 0x87, 0x2e, 0xae, 0x1a, 0xff, 0xc4, 0x18, 0x23, 0x12, 0x95, 0xf0, 0xa0, 0x65, 0x43, 0xa2,
+	0x2f, 0x64, 0xda, 0x0e, 0xa5, 0xd0, 0x76, 0xea, 0x74, 0x8a, 0xf0, 0x51, 0x7c, 0xf4, 0xd5, 0x4f,
+	0xe1, 0xa7, 0xf2, 0xd9, 0x74, 0x3a, 0xed, 0xae, 0xbb, 0x65, 0x85, 0xc4, 0xb7, 0xb9, 0x77, 0xce,
+	0x9d, 0x39, 0xf7, 0xcc, 0x99, 0x0b, 0xff, 0xd9, 0x34, 0xe4, 0x8c, 0xfa, 0x46, 0xc4, 0x28, 0xa7,
+	0xa8, 0x43, 0x8f, 0x88, 0xef, 0x61, 0x23, 0xcf, 0x9e, 0x3d, 0xd0, 0x6e, 0xbb, 0x94, 0xba, 0x3e,
+	0xe9, 0x0b, 0x80, 0x95, 0x1c, 0xf5, 0x9d, 0x84, 0x61, 0xee, 0xd1, 0x30, 0x2b, 0xd1, 0xee, 0x4c,
+	0xee, 0x73, 0x2f, 0x20, 0x31, 0xc7, 0x41, 0x94, 0x01, 0xf4, 0x0e, 0xfc, 0xbf, 0xe7, 0xc5, 0x7c,
+	0x97, 0x5a, 0xb1, 0x49, 0xbe, 0x24, 0x24, 0xe6, 0xfa, 0x4b, 0x58, 0x1c, 0xa5, 0xe2, 0x88, 0x86,
+	0x31, 0x41, 0x9b, 0x50, 0x3b, 0xa1, 0x56, 0xac, 0x2a, 0xdd, 0x6a, 0xaf, 0x3d, 0x58, 0x31, 0xa6,
+	0x98, 0x18, 0xbb, 0xd4, 0x32, 0x05, 0x46, 0xff, 0xa5, 0x40, 0x75, 0x97, 0x5a, 0x08, 0x41, 0x2d,
+	0xc4, 0x01, 0x51, 0x95, 0xae, 0xd2, 0x6b, 0x99, 0x62, 0x8d, 0x34, 0x68, 0xc6, 0xf6, 0x31, 0x71,
+	0x12, 0x9f, 0xa8, 0x15, 0x91, 0x2f, 0x62, 0xa4, 0xc2, 0x9c, 0x4d, 0x83, 0x00, 0x87, 0x8e, 0x5a,
+	0x15, 0x5b, 0x79, 0x88, 0x96, 0xa1, 0x4e, 0xbf, 0x86, 0x84, 0xa9, 0x35, 0x91, 0xcf, 0x02, 0xd4,
+	0x85, 0xb6, 0x43, 0x62, 0x9b, 0x79, 0x51, 0xda, 0xb0, 0x5a, 0x17, 0x7b, 0xe3, 0x29, 0xf4, 0x08,
+	0x6a, 0x1c, 0xbb, 0xb1, 0xda, 0x10, 0xac, 0xbb, 0xe5, 0xac, 0x8d, 0x21, 0x76, 0xe3, 0x9d, 0x90,
+	0xb3, 0x0b, 0x53, 0xa0, 0xb5, 0x27, 0xd0, 0x2a, 0x52, 0x68, 0x11, 0xaa, 0xa7, 0xe4, 0x42, 0xf6,
+	0x90, 0x2e, 0x53, 0x32, 0x67, 0xd8, 0x4f, 0x72, 0xfe, 0x59, 0xf0, 0xbc, 0xf2, 0x54, 0xd1, 0x37,
+	0xe0, 0x46, 0x2a, 0xdc, 0xce, 0x39, 0xb1, 0x93, 0xf4, 0xfe, 0x5c, 0xd1, 0xf4, 0x90, 0x13, 0x6a,
+	0xe5, 0x87, 0x9c, 0x50, 0x4b, 0x3f, 0x80, 0x95, 0x49, 0xa8, 0x54, 0xfa, 0x05, 0x00, 0x29, 0xb2,
+	0x52, 0xef, 0x5b, 0x25, 0xcc, 0x8b, 0x52, 0x73, 0x0c, 0xaf, 0xff, 0xac, 0x42, 0xab, 0xd8, 0x41,
+	0x0b, 0x50, 0xf1, 0x1c, 0x79, 0x6d, 0xc5, 0x73, 0x72, 0x1e, 0x95, 0x82, 0x07, 0x32, 0xa0, 0xe6,
+	0x60, 0x4e, 0x84, 0xe0, 0xed, 0x81, 0x66, 0x64, 0x76, 0x31, 0x72, 0xbb, 0x18, 0xc3, 0xdc, 0x2e,
+	0xa6, 0xc0, 0xa1, 0xc7, 0xd0, 0xcc, 0x1d, 0x26, 0x1e, 0xa3, 0x3d, 0xb8, 0x39, 0x55, 0xf3, 0x46,
+	0x02, 0xcc, 0x02, 0x9a, 0x3e, 0x2d, 0x4b, 0xc2, 0xd0, 0x0b, 0x5d, 0xf1, 0x4c, 0x4d, 0x33, 0x0f,
+	0xd1, 0x0a, 0x34, 0x8e, 0xb0, 0xe7, 0x13, 0x47, 0x6d, 0x88, 0x0d, 0x19, 0xa5, 0x15, 0xf1, 0xa9,
+	0x17, 0x45, 0xc4, 0x51, 0xe7, 0xb2, 0x0a, 0x19, 0xa6, 0xfa, 0x13, 0xc6, 0x28, 0x53, 0x9b, 0x99,
+	0xfe, 0x22, 0x40, 0xab, 0xd0, 0x22, 0xe7, 0x1e, 0x3f, 0xb4, 0xa9, 0x43, 0xd4, 0x56, 0x57, 0xe9,
+	0xd5, 0xcd, 0x66, 0x9a, 0xd8, 0xa6, 0x0e, 0x49, 0x2f, 0x89, 0xb9, 0x43, 0x13, 0xae, 0x82, 0xa8,
+	0x91, 0x91, 0xcc, 0x13, 0xc6, 0xd4, 0x76, 0x91, 0x27, 0x8c, 0xa1, 0x57, 0xd0, 0x88, 0x30, 0xc3,
+	0x41, 0xac, 0xce, 0x0b, 0xfd, 0x7b, 0xb3, 0xf4, 0x37, 0x3e, 0x08, 0x68, 0xe6, 0x20, 0x59, 0xa7,
+	0x3d, 0x83, 0xf6, 0x58, 0xfa, 0x5a, 0x2e, 0x5a, 0x87, 0xa5, 0xb7, 0x64, 0xe4, 0x8c, 0xdc, 0x43,
+	0x13, 0x6f, 0xa9, 0xff, 0x50, 0xa0, 0x33, 0x64, 0x9e, 0xeb, 0x12, 0x96, 0x7e, 0xbd, 0xcb, 0x9c,
+	0x86, 0xde, 0x15, 0xbd, 0x54, 0x44, 0x2f, 0x5b, 0x25, 0xbd, 0x4c, 0x9d, 0xf3, 0xaf, 0x7b, 0x5a,
+	0x06, 0x34, 0x7e, 0x47, 0x66, 0x75, 0x5d, 0x87, 0xc5, 0x6d, 0x1c, 0xda, 0xc4, 0x37, 0x93, 0x4b,
+	0xdb, 0x5c, 0x82, 0xce, 0x18, 0x46, 0x16, 0xae, 0x43, 0x67, 0x9f, 0x33, 0x82, 0x83, 0x3d, 0xea,
+	0xce, 0xf8, 0x64, 0xdf, 0x14, 0x68, 0xee, 0x51, 0xb7, 0xa0, 0x3b, 0xa1, 0xcc, 0x5d, 0x98, 0x2f,
+	0x7e, 0xce, 0xa1, 0xe7, 0x48, 0xd6, 0xed, 0x22, 0xf7, 0xde, 0xb9, 0xf6, 0xf7, 0x58, 0x86, 0xba,
+	0x4f, 0xce, 0x88, 0x9f, 0x0f, 0x2a, 0x11, 0xa4, 0x83, 0x90, 0x93, 0x73, 0x2e, 0x27, 0x94, 0x58,
+	0x0f, 0xbe, 0xd7, 0x60, 0x6e, 0x3b, 0x7b, 0x01, 0xb4, 0x0f, 0xcd, 0x7c, 0xe0, 0x22, 0xbd, 0xe4,
+	0x79, 0x26, 0x06, 0xb4, 0x76, 0x6f, 0x26, 0x46, 0xce, 0x11, 0x02, 0x0b, 0x7f, 0x4e, 0x18, 0xd4,
+	0xbb, 0xa4, 0x6c, 0x6a, 0x5e, 0x69, 0x1b, 0x57, 0x40, 0xca, 0x6b, 0x86, 0x30, 0x3f, 0xee, 0x56,
+	0x74, 0xbf, 0xa4, 0xb4, 0xc4, 0xce, 0xda, 0xcc, 0x91, 0x86, 0x3e, 0x01, 0x8c, 0xfc, 0x82, 0xd6,
+	0xae, 0x62, 0x59, 0x6d, 0xfd, 0x2f, 0x28, 0x49, 0xf8, 0x00, 0x5a, 0x85, 0xa1, 0x50, 0x99, 0x92,
+	0x93, 0x96, 0xd4, 0xd6, 0x66, 0x83, 0xe4, 0xb9, 0x1f, 0x01, 0x46, 0x9e, 0x2c, 0xa5, 0x3c, 0x65,
+	0x59, 0x6d, 0xb5, 0x4c, 0x67, 0x69, 0xd8, 0x2d, 0xe5, 0xf5, 0xe6, 0xe7, 0x9e, 0xeb, 0xf1, 0xe3,
+	0xc4, 0x32, 0x6c, 0x1a, 0xf4, 0x03, 0x3b, 0xc1, 0x0e, 0xa3, 0x71, 0x3f, 0xab, 0xe9, 0xe3, 0xc8,
+	0xeb, 0xcb, 0xba, 0xc8, 0xb2, 0x1a, 0xc2, 0x93, 0x0f, 0x7f, 0x0f, 0x00, 0x1d, 0xad, 0x3d, 0x00,
+	0x33, 0x08, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// ControlClient is the client API for Control service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ControlClient interface {
+	// ListJobs lists the jobs, read scope.
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// ListExecutions lists the executions in progress and the recent ones,
+	// read scope.
+	ListExecutions(ctx context.Context, in *ListExecutionsRequest, opts ...grpc.CallOption) (*ListExecutionsResponse, error)
+	// GetExecution returns a recent execution with its full output, read scope.
+	GetExecution(ctx context.Context, in *GetExecutionRequest, opts ...grpc.CallOption) (*Execution, error)
+	// TriggerJob runs a job right away, admin scope.
+	TriggerJob(ctx context.Context, in *TriggerJobRequest, opts ...grpc.CallOption) (*TriggerJobResponse, error)
+	// CancelRun cancels a one-time run, admin scope.
+	CancelRun(ctx context.Context, in *CancelRunRequest, opts ...grpc.CallOption) (*CancelRunResponse, error)
+	// StreamLogs streams the messages logged about the executions, as they
+	// start and finish, until the call is canceled, read scope.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Control_StreamLogsClient, error)
+}
+
+type controlClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewControlClient(cc *grpc.ClientConn) ControlClient {
+	return &controlClient{cc}
+}
+
+func (c *controlClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, "/ofelia.control.v1.Control/ListJobs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) ListExecutions(ctx context.Context, in *ListExecutionsRequest, opts ...grpc.CallOption) (*ListExecutionsResponse, error) {
+	out := new(ListExecutionsResponse)
+	err := c.cc.Invoke(ctx, "/ofelia.control.v1.Control/ListExecutions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) GetExecution(ctx context.Context, in *GetExecutionRequest, opts ...grpc.CallOption) (*Execution, error) {
+	out := new(Execution)
+	err := c.cc.Invoke(ctx, "/ofelia.control.v1.Control/GetExecution", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) TriggerJob(ctx context.Context, in *TriggerJobRequest, opts ...grpc.CallOption) (*TriggerJobResponse, error) {
+	out := new(TriggerJobResponse)
+	err := c.cc.Invoke(ctx, "/ofelia.control.v1.Control/TriggerJob", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) CancelRun(ctx context.Context, in *CancelRunRequest, opts ...grpc.CallOption) (*CancelRunResponse, error) {
+	out := new(CancelRunResponse)
+	err := c.cc.Invoke(ctx, "/ofelia.control.v1.Control/CancelRun", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Control_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Control_serviceDesc.Streams[0], "/ofelia.control.v1.Control/StreamLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Control_StreamLogsClient interface {
+	Recv() (*LogEntry, error)
+	grpc.ClientStream
+}
+
+type controlStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlStreamLogsClient) Recv() (*LogEntry, error) {
+	m := new(LogEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ControlServer is the server API for Control service.
+type ControlServer interface {
+	// ListJobs lists the jobs, read scope.
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// ListExecutions lists the executions in progress and the recent ones,
+	// read scope.
+	ListExecutions(context.Context, *ListExecutionsRequest) (*ListExecutionsResponse, error)
+	// GetExecution returns a recent execution with its full output, read scope.
+	GetExecution(context.Context, *GetExecutionRequest) (*Execution, error)
+	// TriggerJob runs a job right away, admin scope.
+	TriggerJob(context.Context, *TriggerJobRequest) (*TriggerJobResponse, error)
+	// CancelRun cancels a one-time run, admin scope.
+	CancelRun(context.Context, *CancelRunRequest) (*CancelRunResponse, error)
+	// StreamLogs streams the messages logged about the executions, as they
+	// start and finish, until the call is canceled, read scope.
+	StreamLogs(*StreamLogsRequest, Control_StreamLogsServer) error
+}
+
+// UnimplementedControlServer can be embedded to have forward compatible implementations.
+type UnimplementedControlServer struct {
+}
+
+func (*UnimplementedControlServer) ListJobs(ctx context.Context, req *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (*UnimplementedControlServer) ListExecutions(ctx context.Context, req *ListExecutionsRequest) (*ListExecutionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListExecutions not implemented")
+}
+func (*UnimplementedControlServer) GetExecution(ctx context.Context, req *GetExecutionRequest) (*Execution, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExecution not implemented")
+}
+func (*UnimplementedControlServer) TriggerJob(ctx context.Context, req *TriggerJobRequest) (*TriggerJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerJob not implemented")
+}
+func (*UnimplementedControlServer) CancelRun(ctx context.Context, req *CancelRunRequest) (*CancelRunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelRun not implemented")
+}
+func (*UnimplementedControlServer) StreamLogs(req *StreamLogsRequest, srv Control_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+
+func RegisterControlServer(s *grpc.Server, srv ControlServer) {
+	s.RegisterService(&_Control_serviceDesc, srv)
+}
+
+func _Control_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ofelia.control.v1.Control/ListJobs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_ListExecutions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListExecutionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListExecutions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ofelia.control.v1.Control/ListExecutions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListExecutions(ctx, req.(*ListExecutionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_GetExecution_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetExecutionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetExecution(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ofelia.control.v1.Control/GetExecution",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetExecution(ctx, req.(*GetExecutionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_TriggerJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).TriggerJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ofelia.control.v1.Control/TriggerJob",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).TriggerJob(ctx, req.(*TriggerJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_CancelRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).CancelRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ofelia.control.v1.Control/CancelRun",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).CancelRun(ctx, req.(*CancelRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServer).StreamLogs(m, &controlStreamLogsServer{stream})
+}
+
+type Control_StreamLogsServer interface {
+	Send(*LogEntry) error
+	grpc.ServerStream
+}
+
+type controlStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlStreamLogsServer) Send(m *LogEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Control_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "ofelia.control.v1.Control",
+	HandlerType: (*ControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListJobs",
+			Handler:    _Control_ListJobs_Handler,
+		},
+		{
+			MethodName: "ListExecutions",
+			Handler:    _Control_ListExecutions_Handler,
+		},
+		{
+			MethodName: "GetExecution",
+			Handler:    _Control_GetExecution_Handler,
+		},
+		{
+			MethodName: "TriggerJob",
+			Handler:    _Control_TriggerJob_Handler,
+		},
+		{
+			MethodName: "CancelRun",
+			Handler:    _Control_CancelRun_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _Control_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "control.proto",
+}
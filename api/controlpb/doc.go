@@ -0,0 +1,8 @@
+// Package controlpb is the gRPC Control service of control.proto, the
+// messages and the Go client and server of the service, generated by protoc
+// with the protoc-gen-go plugin of the github.com/golang/protobuf version
+// required by go.mod, installed with
+// "go install github.com/golang/protobuf/protoc-gen-go".
+package controlpb
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. control.proto
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// Peer is another instance of ofelia, queried through its HTTP API
+type Peer struct {
+	Name   string
+	Client *Client
+}
+
+// ParsePeer parses a peer given as "name=url", the name being the host of the
+// URL if omitted, authenticated by the given bearer token, if any
+func ParsePeer(peer, token string) (Peer, error) {
+	name, addr := "", peer
+	if parts := strings.SplitN(peer, "=", 2); len(parts) == 2 && !strings.Contains(parts[0], "/") {
+		name, addr = parts[0], parts[1]
+	}
+
+	client, err := NewClient(addr, token)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	if name == "" {
+		u, _ := url.Parse(addr)
+		name = u.Hostname()
+	}
+
+	return Peer{Name: name, Client: client}, nil
+}
+
+// Federation merges the jobs and the executions of several instances, giving
+// a single view of them across the hosts
+type Federation struct {
+	// Name of the local instance, when served by an instance
+	Name  string
+	Peers []Peer
+}
+
+// NewFederation returns the federation of the local instance with the given
+// name, if served by an instance, and of the given peers
+func NewFederation(name string, peers []string, token string) (*Federation, error) {
+	f := &Federation{Name: name}
+	names := map[string]bool{name: name != ""}
+	for _, p := range peers {
+		peer, err := ParsePeer(p, token)
+		if err != nil {
+			return nil, err
+		}
+
+		if names[peer.Name] {
+			return nil, fmt.Errorf("duplicated instance name %q, name the peers as name=url", peer.Name)
+		}
+
+		names[peer.Name] = true
+		f.Peers = append(f.Peers, peer)
+	}
+
+	return f, nil
+}
+
+// InstanceJob is a job of an instance of a federation
+type InstanceJob struct {
+	Instance string
+	Job
+}
+
+// InstanceExecution is a recent execution of an instance of a federation
+type InstanceExecution struct {
+	Instance string
+	core.RecentExecution
+}
+
+// FederatedJobs are the jobs of the instances of a federation
+type FederatedJobs struct {
+	Jobs []InstanceJob
+	// Errors the errors of the instances unable to be queried, by name
+	Errors map[string]string `json:",omitempty"`
+}
+
+// FederatedExecutions are the recent executions of the instances of a
+// federation, the newest first
+type FederatedExecutions struct {
+	Executions []InstanceExecution
+	// Errors the errors of the instances unable to be queried, by name
+	Errors map[string]string `json:",omitempty"`
+}
+
+// Jobs returns the jobs of the given local instance, unless nil, and of the
+// peers, by instance
+func (f *Federation) Jobs(local []Job) *FederatedJobs {
+	jobs := make([][]Job, len(f.Peers))
+	result := &FederatedJobs{Jobs: []InstanceJob{}}
+	result.Errors = f.each(func(i int, p Peer) (err error) {
+		jobs[i], err = p.Client.Jobs()
+		return err
+	})
+
+	add := func(instance string, jobs []Job) {
+		for _, j := range jobs {
+			result.Jobs = append(result.Jobs, InstanceJob{Instance: instance, Job: j})
+		}
+	}
+
+	if local != nil {
+		add(f.Name, local)
+	}
+
+	for i, p := range f.Peers {
+		add(p.Name, jobs[i])
+	}
+
+	return result
+}
+
+// Executions returns the recent executions of the given local instance,
+// unless nil, and of the peers, the newest first
+func (f *Federation) Executions(local []core.RecentExecution) *FederatedExecutions {
+	executions := make([][]core.RecentExecution, len(f.Peers))
+	result := &FederatedExecutions{Executions: []InstanceExecution{}}
+	result.Errors = f.each(func(i int, p Peer) (err error) {
+		executions[i], err = p.Client.Executions()
+		return err
+	})
+
+	add := func(instance string, executions []core.RecentExecution) {
+		for _, e := range executions {
+			result.Executions = append(result.Executions, InstanceExecution{Instance: instance, RecentExecution: e})
+		}
+	}
+
+	if local != nil {
+		add(f.Name, local)
+	}
+
+	for i, p := range f.Peers {
+		add(p.Name, executions[i])
+	}
+
+	sort.SliceStable(result.Executions, func(i, j int) bool {
+		return result.Executions[i].Date.After(result.Executions[j].Date)
+	})
+
+	return result
+}
+
+// each queries the peers in parallel, returning the errors by name, if any
+func (f *Federation) each(query func(i int, p Peer) error) map[string]string {
+	errs := make([]error, len(f.Peers))
+
+	var wg sync.WaitGroup
+	for i, p := range f.Peers {
+		wg.Add(1)
+		go func(i int, p Peer) {
+			defer wg.Done()
+			errs[i] = query(i, p)
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	var failed map[string]string
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if failed == nil {
+			failed = make(map[string]string)
+		}
+
+		failed[f.Peers[i].Name] = err.Error()
+	}
+
+	return failed
+}
+
+// federationJobs serves the jobs of all the instances of the federation, of
+// all their jobs, so not to the tenants
+func (s *Server) federationJobs(w http.ResponseWriter, r *http.Request, id identity) {
+	if s.federated(w, id) {
+		writeJSON(w, http.StatusOK, s.Federation.Jobs(s.jobs(id)))
+	}
+}
+
+// federationExecutions serves the recent executions of all the instances of
+// the federation, so not to the tenants
+func (s *Server) federationExecutions(w http.ResponseWriter, r *http.Request, id identity) {
+	if s.federated(w, id) {
+		writeJSON(w, http.StatusOK, s.Federation.Executions(s.executions(id)))
+	}
+}
+
+// federated writes the error of the request when the federation is disabled
+// or the identity is a tenant
+func (s *Server) federated(w http.ResponseWriter, id identity) bool {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+
+	if s.Federation == nil {
+		http.Error(w, "the federation is disabled", http.StatusNotImplemented)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+// startPeer starts another instance with a job named as given, returning its
+// scheduler and URL
+func (s *SuiteServer) startPeer(c *C, job string, config *Config) (*core.Scheduler, string) {
+	sched := core.NewScheduler(&testLogger{})
+	j := &testJob{called: make(chan bool, 1)}
+	j.Name = job
+	j.Schedule = "@hourly"
+	j.Command = "echo " + job
+	c.Assert(sched.AddJob(j), IsNil)
+
+	srv, err := NewServer(sched, config)
+	c.Assert(err, IsNil)
+
+	peer := httptest.NewServer(srv.Handler())
+	s.closers = append(s.closers, peer.Close)
+	return sched, peer.URL
+}
+
+func (s *SuiteServer) TestParsePeer(c *C) {
+	p, err := ParsePeer("db=https://10.0.0.1:8081/", "")
+	c.Assert(err, IsNil)
+	c.Assert(p.Name, Equals, "db")
+	c.Assert(p.Client.base, Equals, "https://10.0.0.1:8081")
+
+	p, err = ParsePeer("http://web-1:8081", "")
+	c.Assert(err, IsNil)
+	c.Assert(p.Name, Equals, "web-1")
+
+	_, err = ParsePeer("db=10.0.0.1:8081", "")
+	c.Assert(err, ErrorMatches, `invalid API URL "10.0.0.1:8081".*`)
+
+	_, err = NewFederation("web-1", []string{"http://web-1:8081"}, "")
+	c.Assert(err, ErrorMatches, `duplicated instance name "web-1".*`)
+}
+
+func (s *SuiteServer) TestExecutions(c *C) {
+	w := s.request(c, &Config{}, "GET", "/api/executions", nil)
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(w.Body.String(), Equals, "[]\n")
+
+	e := core.NewExecution()
+	e.Start()
+	e.OutputStream.Write([]byte("foo"))
+	e.Stop(nil)
+
+	s.sched.Recent = core.NewRecentExecutions(0)
+	s.sched.Recent.Add(s.job, e)
+
+	w = s.request(c, &Config{}, "GET", "/api/executions", nil)
+	var executions []core.RecentExecution
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &executions), IsNil)
+	c.Assert(executions, HasLen, 1)
+	c.Assert(executions[0].ID, Equals, e.ID)
+	c.Assert(executions[0].Stdout, Equals, "")
+
+	s.job.Owner = "team-a"
+	w = s.request(c, &Config{Tokens: []string{"t0k3n:read@team-b"}}, "GET", "/api/executions", bearer("t0k3n"))
+	c.Assert(w.Body.String(), Equals, "[]\n")
+}
+
+func (s *SuiteServer) TestFederation(c *C) {
+	w := s.request(c, &Config{}, "GET", "/api/federation/jobs", nil)
+	c.Assert(w.Code, Equals, http.StatusNotImplemented)
+
+	peer, addr := s.startPeer(c, "bar", &Config{Users: []string{"peer:s3cr3t:read"}})
+
+	federation, err := NewFederation("local", []string{"remote=" + addr, "down=http://127.0.0.1:1"}, "")
+	c.Assert(err, IsNil)
+	federation.Peers[0].Client, err = NewClient("http://peer:s3cr3t@"+addr[len("http://"):], "")
+	c.Assert(err, IsNil)
+
+	srv, err := NewServer(s.sched, &Config{Tokens: []string{"t0k3n:read@team-b"}})
+	c.Assert(err, IsNil)
+	srv.Federation = federation
+
+	get := func(path string, v interface{}) int {
+		w := httptest.NewRecorder()
+		srv.handler(authenticateSocket, true).ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if v != nil {
+			c.Assert(json.Unmarshal(w.Body.Bytes(), v), IsNil)
+		}
+
+		return w.Code
+	}
+
+	jobs := &FederatedJobs{}
+	c.Assert(get("/api/federation/jobs", jobs), Equals, http.StatusOK)
+	c.Assert(jobs.Jobs, DeepEquals, []InstanceJob{
+		{Instance: "local", Job: Job{Name: "foo", Schedule: "@daily", Command: "echo foo"}},
+		{Instance: "remote", Job: Job{Name: "bar", Schedule: "@hourly", Command: "echo bar"}},
+	})
+	c.Assert(jobs.Errors, HasLen, 1)
+	c.Assert(jobs.Errors["down"], Matches, ".*connection refused.*")
+
+	older, newer := core.NewExecution(), core.NewExecution()
+	older.Date, newer.Date = time.Now().Add(-time.Minute), time.Now()
+	s.sched.Recent = core.NewRecentExecutions(0)
+	s.sched.Recent.Add(s.job, older)
+	peer.Recent = core.NewRecentExecutions(0)
+	peer.Recent.Add(peer.Jobs[0], newer)
+
+	executions := &FederatedExecutions{}
+	c.Assert(get("/api/federation/executions", executions), Equals, http.StatusOK)
+	c.Assert(executions.Executions, HasLen, 2)
+	c.Assert(executions.Executions[0].Instance, Equals, "remote")
+	c.Assert(executions.Executions[0].ID, Equals, newer.ID)
+	c.Assert(executions.Executions[1].Instance, Equals, "local")
+	c.Assert(executions.Executions[1].ID, Equals, older.ID)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/federation/jobs", nil)
+	bearer("t0k3n")(r)
+	srv.Handler().ServeHTTP(w, r)
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/mcuadros/ofelia/api/controlpb"
+	"github.com/mcuadros/ofelia/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultGRPCAddr the gRPC API only listens on localhost by default
+const DefaultGRPCAddr = "127.0.0.1:8082"
+
+// logBuffer max number of log entries waiting to be streamed to a client, the
+// entries logged while the buffer is full are dropped
+const logBuffer = 1000
+
+// grpcScopes scopes of the gRPC methods requiring more than the read scope
+var grpcScopes = map[string]Scope{
+	"/ofelia.control.v1.Control/TriggerJob": ScopeAdmin,
+	"/ofelia.control.v1.Control/CancelRun":  ScopeAdmin,
+}
+
+type identityKey struct{}
+
+// StartGRPC starts serving the gRPC Control service, see control.proto, on
+// the given address, in background, with the credentials and the TLS of the
+// HTTP API
+func (s *Server) StartGRPC(addr string) error {
+	if addr == "" {
+		addr = DefaultGRPCAddr
+	}
+
+	if err := s.checkListen(addr); err != nil {
+		return err
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := s.authorize(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+
+			return h(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+			ctx, err := s.authorize(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+
+			return h(srv, &identityStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.grpcListener = l
+	s.grpc = grpc.NewServer(opts...)
+	controlpb.RegisterControlServer(s.grpc, &controlServer{Server: s})
+	go func() {
+		if err := s.grpc.Serve(l); err != nil {
+			s.Scheduler.Logger.Errorf("gRPC API server error: %s", err)
+		}
+	}()
+
+	s.Scheduler.Logger.Noticef("gRPC API listening on %s", l.Addr())
+	return nil
+}
+
+// GRPCAddr returns the address the gRPC server is listening on
+func (s *Server) GRPCAddr() net.Addr {
+	return s.grpcListener.Addr()
+}
+
+// stopGRPC stops the gRPC server, waiting for the calls in progress until the
+// given context is done, the streams of the logs never ending by themselves
+func (s *Server) stopGRPC(ctx context.Context) {
+	if s.grpc == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
+
+// authorize authenticates the client of a gRPC call with the credentials of
+// the HTTP API, sent as the "authorization" metadata, and checks its scope.
+// The returned context carries its identity.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	r := &http.Request{Header: make(http.Header)}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			r.Header.Add("Authorization", v)
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &info.State
+		}
+	}
+
+	id, ok := s.authenticate(r)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	if id.scope < grpcScopes[method] {
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	return context.WithValue(ctx, identityKey{}, id), nil
+}
+
+// callIdentity returns the identity of the client of a gRPC call
+func callIdentity(ctx context.Context) identity {
+	id, _ := ctx.Value(identityKey{}).(identity)
+	return id
+}
+
+// identityStream is a server stream carrying the identity of its client
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
+
+// controlServer implements the gRPC Control service with the scheduler of the
+// server
+type controlServer struct {
+	*Server
+}
+
+func (c *controlServer) ListJobs(ctx context.Context, req *controlpb.ListJobsRequest) (*controlpb.ListJobsResponse, error) {
+	id := callIdentity(ctx)
+	resp := &controlpb.ListJobsResponse{}
+	for _, j := range c.Scheduler.GetJobs() {
+		if !id.canAccess(j) {
+			continue
+		}
+
+		job := &controlpb.Job{
+			Name:        j.GetName(),
+			Schedule:    j.GetSchedule(),
+			Command:     j.GetCommand(),
+			Owner:       core.JobOwner(j),
+			Description: core.JobDescription(j),
+		}
+
+		job.Tags, _ = core.JobTags(j)
+		resp.Jobs = append(resp.Jobs, job)
+	}
+
+	return resp, nil
+}
+
+func (c *controlServer) ListExecutions(ctx context.Context, req *controlpb.ListExecutionsRequest) (*controlpb.ListExecutionsResponse, error) {
+	resp := &controlpb.ListExecutionsResponse{}
+	running := c.Scheduler.State().Executions
+	// the newest first, as the recent ones
+	sort.SliceStable(running, func(i, j int) bool { return running[i].Date.After(running[j].Date) })
+	for _, e := range running {
+		if c.canAccess(ctx, req.Job, e.Job) {
+			resp.Executions = append(resp.Executions, &controlpb.Execution{
+				Id:       e.ID,
+				Job:      e.Job,
+				Date:     timestampProto(e.Date),
+				Duration: ptypes.DurationProto(e.Elapsed),
+				Running:  true,
+				Params:   e.Params,
+			})
+		}
+	}
+
+	if c.Scheduler.Recent != nil {
+		for _, e := range c.Scheduler.Recent.List() {
+			if c.canAccess(ctx, req.Job, e.Job) {
+				msg := recentExecutionProto(e)
+				msg.Stdout, msg.Stderr = "", ""
+				resp.Executions = append(resp.Executions, msg)
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+func (c *controlServer) GetExecution(ctx context.Context, req *controlpb.GetExecutionRequest) (*controlpb.Execution, error) {
+	var e *core.RecentExecution
+	if c.Scheduler.Recent != nil {
+		e = c.Scheduler.Recent.Get(req.Id)
+	}
+
+	if e == nil || !c.canAccess(ctx, "", e.Job) {
+		return nil, status.Error(codes.NotFound, core.ErrExecutionNotFound.Error())
+	}
+
+	return recentExecutionProto(e), nil
+}
+
+func (c *controlServer) TriggerJob(ctx context.Context, req *controlpb.TriggerJobRequest) (*controlpb.TriggerJobResponse, error) {
+	id := callIdentity(ctx)
+	if j := c.Scheduler.GetJob(req.Job); j == nil || !id.canAccess(j) {
+		return nil, status.Error(codes.NotFound, core.ErrJobNotFound.Error())
+	}
+
+	if err := c.Scheduler.TriggerJobWithParams("grpc:"+id.actor, req.Job, req.Params); err != nil {
+		if err == core.ErrJobNotFound {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &controlpb.TriggerJobResponse{}, nil
+}
+
+func (c *controlServer) CancelRun(ctx context.Context, req *controlpb.CancelRunRequest) (*controlpb.CancelRunResponse, error) {
+	id := callIdentity(ctx)
+	for _, run := range c.Scheduler.OneTimeRuns() {
+		if run.ID != req.Id {
+			continue
+		}
+
+		// the runs of the jobs of other tenants are not found
+		if !c.canAccess(ctx, "", run.Job) {
+			break
+		}
+
+		if err := c.Scheduler.CancelRun("grpc:"+id.actor, req.Id); err == nil {
+			return &controlpb.CancelRunResponse{}, nil
+		}
+	}
+
+	return nil, status.Error(codes.NotFound, core.ErrRunNotFound.Error())
+}
+
+func (c *controlServer) StreamLogs(req *controlpb.StreamLogsRequest, stream controlpb.Control_StreamLogsServer) error {
+	ctx := stream.Context()
+	entries := make(chan *controlpb.LogEntry, logBuffer)
+
+	// number of messages of the executions in progress already streamed
+	var mu sync.Mutex
+	sent := make(map[string]int)
+
+	unsubscribe := c.Scheduler.Events.Subscribe(func(e core.Event) {
+		var exec *core.Execution
+		finished := true
+		switch e := e.(type) {
+		case *core.ExecutionStarted:
+			exec, finished = e.Execution, false
+		case *core.ExecutionFinished:
+			exec = e.Execution
+		case *core.ExecutionSkipped:
+			exec = e.Execution
+		default:
+			return
+		}
+
+		job := e.GetJob()
+		if !c.canAccess(ctx, req.Job, job.GetName()) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		messages := exec.Messages()
+		for _, m := range messages[sent[exec.ID]:] {
+			select {
+			case entries <- &controlpb.LogEntry{
+				Job:         job.GetName(),
+				ExecutionId: exec.ID,
+				Date:        timestampProto(m.Date),
+				Level:       m.Level,
+				Text:        m.Text,
+			}:
+			default:
+				c.Scheduler.Logger.Errorf("Log stream buffer full, message of job %q not streamed", job.GetName())
+			}
+		}
+
+		if finished {
+			delete(sent, exec.ID)
+		} else {
+			sent[exec.ID] = len(messages)
+		}
+	})
+
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-entries:
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// canAccess returns true if the client of the call can access the job with
+// the given name, and it's the wanted one if any
+func (c *controlServer) canAccess(ctx context.Context, wanted, name string) bool {
+	if wanted != "" && name != wanted {
+		return false
+	}
+
+	id := callIdentity(ctx)
+	if id.tenant == "" {
+		return true
+	}
+
+	// the executions of the removed jobs are only visible without tenant
+	j := c.Scheduler.GetJob(name)
+	return j != nil && id.canAccess(j)
+}
+
+func recentExecutionProto(e *core.RecentExecution) *controlpb.Execution {
+	return &controlpb.Execution{
+		Id:       e.ID,
+		Job:      e.Job,
+		Date:     timestampProto(e.Date),
+		Duration: ptypes.DurationProto(e.Duration),
+		Failed:   e.Failed,
+		Skipped:  e.Skipped,
+		Error:    e.Error,
+		ExitCode: int32(e.ExitCode),
+		Stdout:   e.Stdout,
+		Stderr:   e.Stderr,
+		Params:   e.Params,
+	}
+}
+
+func timestampProto(t time.Time) *timestamp.Timestamp {
+	ts, _ := ptypes.TimestampProto(t)
+	return ts
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/descriptor"
+	"github.com/mcuadros/ofelia/api/controlpb"
+	"github.com/mcuadros/ofelia/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	. "gopkg.in/check.v1"
+)
+
+func (s *SuiteServer) grpcClient(c *C, config *Config) controlpb.ControlClient {
+	srv, err := NewServer(s.sched, config)
+	c.Assert(err, IsNil)
+	c.Assert(srv.StartGRPC("127.0.0.1:0"), IsNil)
+
+	conn, err := grpc.Dial(srv.GRPCAddr().String(), grpc.WithInsecure())
+	c.Assert(err, IsNil)
+
+	s.closers = append(s.closers, func() {
+		conn.Close()
+		srv.Stop()
+	})
+
+	return controlpb.NewControlClient(conn)
+}
+
+func withToken(t string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+t)
+}
+
+func (s *SuiteServer) TestGRPCJobs(c *C) {
+	s.job.Tags = []string{"tier=critical"}
+	client := s.grpcClient(c, &Config{Tokens: []string{"r3ad:read", "adm1n:admin"}})
+
+	_, err := client.ListJobs(context.Background(), &controlpb.ListJobsRequest{})
+	c.Assert(status.Code(err), Equals, codes.Unauthenticated)
+
+	resp, err := client.ListJobs(withToken("r3ad"), &controlpb.ListJobsRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(resp.Jobs, HasLen, 1)
+	c.Assert(resp.Jobs[0].Name, Equals, "foo")
+	c.Assert(resp.Jobs[0].Command, Equals, "echo foo")
+	c.Assert(resp.Jobs[0].Tags, DeepEquals, map[string]string{"tier": "critical"})
+
+	req := &controlpb.TriggerJobRequest{Job: "foo", Params: map[string]string{"matrix": "bar"}}
+	_, err = client.TriggerJob(withToken("r3ad"), req)
+	c.Assert(status.Code(err), Equals, codes.PermissionDenied)
+
+	_, err = client.TriggerJob(withToken("adm1n"), req)
+	c.Assert(err, IsNil)
+
+	select {
+	case <-s.job.called:
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	c.Assert(s.job.params, DeepEquals, map[string]string{"matrix": "bar"})
+
+	_, err = client.TriggerJob(withToken("adm1n"), &controlpb.TriggerJobRequest{Job: "bar"})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	_, err = client.CancelRun(withToken("adm1n"), &controlpb.CancelRunRequest{Id: "missing"})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+}
+
+func (s *SuiteServer) TestGRPCExecutions(c *C) {
+	e := core.NewExecution()
+	e.Start()
+	e.OutputStream.Write([]byte("foo"))
+	e.Stop(nil)
+
+	s.sched.Recent = core.NewRecentExecutions(0)
+	s.sched.Recent.Add(s.job, e)
+
+	s.job.Owner = "team-a"
+	client := s.grpcClient(c, &Config{Tokens: []string{"t0k3n:read", "b:read@team-b"}})
+
+	resp, err := client.ListExecutions(withToken("t0k3n"), &controlpb.ListExecutionsRequest{Job: "foo"})
+	c.Assert(err, IsNil)
+	c.Assert(resp.Executions, HasLen, 1)
+	c.Assert(resp.Executions[0].Id, Equals, e.ID)
+	c.Assert(resp.Executions[0].Stdout, Equals, "")
+
+	exec, err := client.GetExecution(withToken("t0k3n"), &controlpb.GetExecutionRequest{Id: e.ID})
+	c.Assert(err, IsNil)
+	c.Assert(exec.Job, Equals, "foo")
+	c.Assert(exec.Stdout, Equals, "foo")
+
+	_, err = client.GetExecution(withToken("b"), &controlpb.GetExecutionRequest{Id: e.ID})
+	c.Assert(status.Code(err), Equals, codes.NotFound)
+
+	resp, err = client.ListExecutions(withToken("b"), &controlpb.ListExecutionsRequest{})
+	c.Assert(err, IsNil)
+	c.Assert(resp.Executions, HasLen, 0)
+}
+
+func (s *SuiteServer) TestGRPCStreamLogs(c *C) {
+	client := s.grpcClient(c, &Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamLogs(ctx, &controlpb.StreamLogsRequest{Job: "foo"})
+	c.Assert(err, IsNil)
+
+	// waits for the server to subscribe to the events
+	time.Sleep(100 * time.Millisecond)
+
+	e := core.NewExecution()
+	ectx := core.NewContext(s.sched, s.job, e)
+	ectx.Start()
+	ectx.Log("Started - echo foo")
+	s.sched.Events.Publish(&core.ExecutionStarted{Job: s.job, Execution: e})
+
+	ectx.Stop(nil)
+	ectx.Log("Finished")
+	s.sched.Events.Publish(&core.ExecutionFinished{Job: s.job, Execution: e})
+
+	for _, text := range []string{"Started - echo foo", "Finished"} {
+		entry, err := stream.Recv()
+		c.Assert(err, IsNil)
+		c.Assert(entry.Job, Equals, "foo")
+		c.Assert(entry.ExecutionId, Equals, e.ID)
+		c.Assert(entry.Level, Equals, core.LevelNotice)
+		c.Assert(entry.Text, Equals, text)
+	}
+}
+
+func (s *SuiteServer) TestGRPCDescriptor(c *C) {
+	fd, md := descriptor.ForMessage(&controlpb.Execution{})
+	c.Assert(fd.GetName(), Equals, "control.proto")
+	c.Assert(fd.GetPackage(), Equals, "ofelia.control.v1")
+	c.Assert(md.GetName(), Equals, "Execution")
+	c.Assert(md.GetField()[8].GetName(), Equals, "exit_code")
+	c.Assert(fd.GetService()[0].GetMethod(), HasLen, 6)
+	c.Assert(fd.GetService()[0].GetMethod()[5].GetServerStreaming(), Equals, true)
+}
@@ -0,0 +1,739 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	"google.golang.org/grpc"
+)
+
+// DefaultAddr the API only listens on localhost by default
+const DefaultAddr = "127.0.0.1:8081"
+
+const shutdownTimeout = 5 * time.Second
+
+// maxPayload max size of the body of a webhook call
+const maxPayload = 1 << 20
+
+// defaultExplainCount and maxExplainCount number of next times of the
+// schedules explained
+const (
+	defaultExplainCount = 5
+	maxExplainCount     = 100
+)
+
+// Config configuration of the HTTP API
+type Config struct {
+	// Addr address to listen on, DefaultAddr if empty
+	Addr string
+	// Users basic auth credentials, as "name:password:scope"
+	Users []string
+	// Tokens bearer token credentials, as "token:scope"
+	Tokens []string
+	// TLSCert and TLSKey files enable HTTPS
+	TLSCert string
+	TLSKey  string
+	// TLSClientCA file of the CAs of the client certificates, when set the
+	// clients must provide a certificate signed by them
+	TLSClientCA string
+}
+
+// Server is the HTTP API of a scheduler
+type Server struct {
+	Scheduler *core.Scheduler
+	Config    *Config
+
+	users    []user
+	tokens   []token
+	server   *http.Server
+	listener net.Listener
+	socket   *http.Server
+
+	// Applier when set, reconciles the jobs to the job sets applied through
+	// the API
+	Applier Applier
+	// Federation when set, merges the jobs and executions of other instances
+	// with the ones of this instance
+	Federation *Federation
+
+	grpc         *grpc.Server
+	grpcListener net.Listener
+}
+
+// Applier reconciles the jobs of the scheduler to exactly match the given job
+// set, a YAML document, on behalf of the given actor. When dryRun, it only
+// returns the diff.
+type Applier func(actor string, doc []byte, dryRun bool) (*ApplyResult, error)
+
+// ApplyResult is the diff of the jobs of an applied job set
+type ApplyResult struct {
+	DryRun  bool     `json:",omitempty"`
+	Added   []string `json:",omitempty"`
+	Updated []string `json:",omitempty"`
+	Removed []string `json:",omitempty"`
+	// Changed names of the changed params of the updated jobs, not their
+	// values, that may be secrets
+	Changed map[string][]string `json:",omitempty"`
+}
+
+// NewServer returns the API server of the given scheduler
+func NewServer(s *core.Scheduler, c *Config) (*Server, error) {
+	srv := &Server{Scheduler: s, Config: c}
+	for _, u := range c.Users {
+		parsed, err := parseUser(u)
+		if err != nil {
+			return nil, err
+		}
+
+		srv.users = append(srv.users, parsed)
+	}
+
+	for _, t := range c.Tokens {
+		parsed, err := parseToken(t)
+		if err != nil {
+			return nil, err
+		}
+
+		srv.tokens = append(srv.tokens, parsed)
+	}
+
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return nil, fmt.Errorf("both the TLS certificate and key are required")
+	}
+
+	if c.TLSClientCA != "" && c.TLSCert == "" {
+		return nil, fmt.Errorf("the TLS client CA requires the TLS certificate and key")
+	}
+
+	return srv, nil
+}
+
+// Secrets returns the passwords and tokens of the credentials
+func (s *Server) Secrets() []string {
+	var secrets []string
+	for _, u := range s.users {
+		secrets = append(secrets, u.password)
+	}
+
+	for _, t := range s.tokens {
+		secrets = append(secrets, t.value)
+	}
+
+	return secrets
+}
+
+// Handler returns the handler of all the routes
+func (s *Server) Handler() http.Handler {
+	return s.handler(s.authenticate, s.authenticated())
+}
+
+// authenticator returns the identity of the client of a request, false if
+// the request doesn't carry valid credentials
+type authenticator func(r *http.Request) (identity, bool)
+
+// handler returns the handler of the routes, the job sets only being applied
+// when apply, the clients being authenticated
+func (s *Server) handler(auth authenticator, apply bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/status", route(auth, ScopeRead, http.MethodGet, s.status))
+	mux.Handle("/api/jobs", route(auth, ScopeRead, http.MethodGet, s.listJobs))
+	mux.Handle("/api/jobs/", route(auth, ScopeAdmin, http.MethodPost, s.jobAction))
+	mux.Handle("/api/runs", route(auth, ScopeRead, http.MethodGet, s.listRuns))
+	mux.Handle("/api/runs/", route(auth, ScopeAdmin, http.MethodDelete, s.cancelRun))
+	mux.Handle("/api/executions", route(auth, ScopeRead, http.MethodGet, s.listExecutions))
+	mux.Handle("/api/executions/", route(auth, ScopeRead, http.MethodGet, s.execution))
+	mux.Handle("/api/hooks/", route(auth, ScopeAdmin, http.MethodPost, s.hook))
+	mux.Handle("/api/silences", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			route(auth, ScopeAdmin, http.MethodPost, s.silence).ServeHTTP(w, r)
+			return
+		}
+
+		route(auth, ScopeRead, http.MethodGet, s.listSilences).ServeHTTP(w, r)
+	}))
+	mux.Handle("/api/silences/", route(auth, ScopeAdmin, http.MethodDelete, s.unsilence))
+	mux.Handle("/api/explain", route(auth, ScopeRead, http.MethodGet, s.explain))
+	if apply {
+		mux.Handle("/api/apply", route(auth, ScopeAdmin, http.MethodPost, s.apply))
+	}
+
+	mux.Handle("/api/federation/jobs", route(auth, ScopeRead, http.MethodGet, s.federationJobs))
+	mux.Handle("/api/federation/executions", route(auth, ScopeRead, http.MethodGet, s.federationExecutions))
+	mux.Handle("/debug/vars", route(auth, ScopeRead, http.MethodGet, s.vars))
+
+	return mux
+}
+
+// Start starts listening, serving the requests in background
+func (s *Server) Start() error {
+	addr := s.Config.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	if err := s.checkListen(addr); err != nil {
+		return err
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	s.listener = l
+	s.server = &http.Server{Handler: s.Handler(), TLSConfig: tlsConfig}
+	go func() {
+		if err := s.server.Serve(l); err != http.ErrServerClosed {
+			s.Scheduler.Logger.Errorf("API server error: %s", err)
+		}
+	}()
+
+	s.Scheduler.Logger.Noticef("API listening on %s", l.Addr())
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Stop stops the server, the socket and the gRPC server, waiting for the
+// requests in progress
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	s.stopGRPC(ctx)
+
+	for _, srv := range []*http.Server{s.server, s.socket} {
+		if srv == nil {
+			continue
+		}
+
+		if err := srv.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if s.Config.TLSCert == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.Config.TLSCert, s.Config.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if s.Config.TLSClientCA == "" {
+		return c, nil
+	}
+
+	pem, err := ioutil.ReadFile(s.Config.TLSClientCA)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ClientCAs = x509.NewCertPool()
+	if !c.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found at %q", s.Config.TLSClientCA)
+	}
+
+	c.ClientAuth = tls.RequireAndVerifyClientCert
+	return c, nil
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, id identity)
+
+// route returns the handler of a route requiring the given scope and method
+func route(auth authenticator, scope Scope, method string, h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := auth(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ofelia"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if id.scope < scope {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		h(w, r, id)
+	})
+}
+
+// vars serves the metrics, of all the jobs, so not to the tenants
+func (s *Server) vars(w http.ResponseWriter, r *http.Request, id identity) {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	expvar.Handler().ServeHTTP(w, r)
+}
+
+// Status is the status of the scheduler as reported by the API
+type Status struct {
+	Running bool
+	Jobs    int
+	// LastReload the last reload of the config, if it was reloaded
+	LastReload *core.Reload `json:",omitempty"`
+}
+
+// status serves the status of the scheduler, of all the jobs, so not to the
+// tenants
+func (s *Server) status(w http.ResponseWriter, r *http.Request, id identity) {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &Status{
+		Running:    s.Scheduler.IsRunning(),
+		Jobs:       len(s.Scheduler.GetJobs()),
+		LastReload: s.Scheduler.LastReload(),
+	})
+}
+
+// Job is a job as listed by the API
+type Job struct {
+	Name     string
+	Schedule string
+	Command  string
+	Owner    string `json:",omitempty"`
+	// Description and Tags annotations of the job, if any
+	Description string            `json:",omitempty"`
+	Tags        map[string]string `json:",omitempty"`
+	// Orphaned when the container of the job was found gone, if it was
+	Orphaned *time.Time `json:",omitempty"`
+	// Skips the scheduled occurrences left to skip, if any
+	Skips int `json:",omitempty"`
+	// Completed when the job ran its "@at" schedule, if it did
+	Completed *time.Time `json:",omitempty"`
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request, id identity) {
+	writeJSON(w, http.StatusOK, s.jobs(id))
+}
+
+// jobs returns the jobs the given identity can access
+func (s *Server) jobs(id identity) []Job {
+	jobs := []Job{}
+	for _, j := range s.Scheduler.GetJobs() {
+		if !id.canAccess(j) {
+			continue
+		}
+
+		job := Job{
+			Name:        j.GetName(),
+			Schedule:    j.GetSchedule(),
+			Command:     j.GetCommand(),
+			Owner:       core.JobOwner(j),
+			Description: core.JobDescription(j),
+		}
+
+		job.Tags, _ = core.JobTags(j)
+		job.Skips = s.Scheduler.Skips(j)
+
+		if since := s.Scheduler.OrphanedSince(j); !since.IsZero() {
+			job.Orphaned = &since
+		}
+
+		if completed := s.Scheduler.Completed(j); !completed.IsZero() {
+			job.Completed = &completed
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+// jobAction performs the action at /api/jobs/<name>/<action> on the job
+func (s *Server) jobAction(w http.ResponseWriter, r *http.Request, id identity) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var action handlerFunc
+	switch path[i+1:] {
+	case "run":
+		action = s.runJob
+	case "skip":
+		action = s.skipJob
+	case "once":
+		action = s.runOnce
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	// the jobs of other tenants are not found, not to disclose them
+	name := path[:i]
+	if j := s.Scheduler.GetJob(name); j == nil || !id.canAccess(j) {
+		http.Error(w, core.ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	action(w, r, id)
+}
+
+// jobName returns the name of the job at /api/jobs/<name>/<action>
+func jobName(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	return path[:strings.LastIndex(path, "/")]
+}
+
+// runJob runs the job at /api/jobs/<name>/run
+func (s *Server) runJob(w http.ResponseWriter, r *http.Request, id identity) {
+	if err := s.Scheduler.TriggerJob("api:"+id.actor, jobName(r)); err != nil {
+		if err == core.ErrJobNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// SkipRequest is the body of the requests skipping the next occurrences of a
+// job, zero resumes its schedule
+type SkipRequest struct {
+	Count int
+}
+
+// skipJob skips the next occurrences of the job at /api/jobs/<name>/skip
+func (s *Server) skipJob(w http.ResponseWriter, r *http.Request, id identity) {
+	req := &SkipRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayload)).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Scheduler.SkipJob("api:"+id.actor, jobName(r), req.Count); err != nil {
+		if err == core.ErrJobNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// OnceRequest is the body of the requests running a job once at a given time
+type OnceRequest struct {
+	At time.Time
+}
+
+// runOnce runs the job at /api/jobs/<name>/once at the given time
+func (s *Server) runOnce(w http.ResponseWriter, r *http.Request, id identity) {
+	req := &OnceRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayload)).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.Scheduler.ScheduleRun("api:"+id.actor, jobName(r), req.At)
+	if err != nil {
+		if err == core.ErrJobNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, run)
+}
+
+// listRuns serves the one-time runs of the jobs
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request, id identity) {
+	runs := []core.OneTimeRun{}
+	for _, run := range s.Scheduler.OneTimeRuns() {
+		if j := s.Scheduler.GetJob(run.Job); j != nil && id.canAccess(j) {
+			runs = append(runs, run)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// cancelRun cancels the one-time run at /api/runs/<id>
+func (s *Server) cancelRun(w http.ResponseWriter, r *http.Request, id identity) {
+	runID := strings.TrimPrefix(r.URL.Path, "/api/runs/")
+	for _, run := range s.Scheduler.OneTimeRuns() {
+		if run.ID != runID {
+			continue
+		}
+
+		// the runs of the jobs of other tenants are not found
+		if j := s.Scheduler.GetJob(run.Job); j == nil || !id.canAccess(j) {
+			break
+		}
+
+		if err := s.Scheduler.CancelRun("api:"+id.actor, runID); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	http.Error(w, core.ErrRunNotFound.Error(), http.StatusNotFound)
+}
+
+func (s *Server) listExecutions(w http.ResponseWriter, r *http.Request, id identity) {
+	writeJSON(w, http.StatusOK, s.executions(id))
+}
+
+// executions returns the recent executions of the jobs the given identity can
+// access, the newest first, without their output
+func (s *Server) executions(id identity) []core.RecentExecution {
+	executions := []core.RecentExecution{}
+	if s.Scheduler.Recent == nil {
+		return executions
+	}
+
+	for _, e := range s.Scheduler.Recent.List() {
+		if id.tenant != "" {
+			if j := s.Scheduler.GetJob(e.Job); j == nil || !id.canAccess(j) {
+				continue
+			}
+		}
+
+		e := *e
+		e.Stdout, e.Stderr = "", ""
+		executions = append(executions, e)
+	}
+
+	return executions
+}
+
+// execution returns the recent execution at /api/executions/<id>, with its
+// full output, the page linked from the notifications
+func (s *Server) execution(w http.ResponseWriter, r *http.Request, id identity) {
+	var e *core.RecentExecution
+	if s.Scheduler.Recent != nil {
+		e = s.Scheduler.Recent.Get(strings.TrimPrefix(r.URL.Path, "/api/executions/"))
+	}
+
+	// the executions of the jobs of other tenants, or removed, are not found
+	if e != nil && id.tenant != "" {
+		if j := s.Scheduler.GetJob(e.Job); j == nil || !id.canAccess(j) {
+			e = nil
+		}
+	}
+
+	if e == nil {
+		http.Error(w, core.ErrExecutionNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, e)
+}
+
+// hook runs the job at /api/hooks/<name>, if its trigger is webhook, with the
+// body of the request as payload
+func (s *Server) hook(w http.ResponseWriter, r *http.Request, id identity) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/hooks/")
+	j := s.Scheduler.GetJob(name)
+	if j == nil || !id.canAccess(j) || core.JobTrigger(j) != core.TriggerWebhook {
+		http.Error(w, core.ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	params := map[string]string{core.PayloadParam: string(payload)}
+	if err := s.Scheduler.TriggerJobWithParams("webhook:"+id.actor, name, params); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// SilenceRequest is the body of the requests silencing the notifications
+type SilenceRequest struct {
+	// Duration of the silence, e.g. "30m"
+	Duration string
+	// Tags when set, only the jobs with any of them are silenced
+	Tags []string `json:",omitempty"`
+}
+
+// silence silences the notifications, of all the jobs, so not by the tenants
+func (s *Server) silence(w http.ResponseWriter, r *http.Request, id identity) {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	req := &SilenceRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayload)).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 {
+		http.Error(w, fmt.Sprintf("invalid duration %q", req.Duration), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, s.Scheduler.Silence("api:"+id.actor, d, req.Tags))
+}
+
+// listSilences serves the active silences, of all the jobs, so not to the
+// tenants
+func (s *Server) listSilences(w http.ResponseWriter, r *http.Request, id identity) {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	silences := s.Scheduler.Silences()
+	if silences == nil {
+		silences = []core.Silence{}
+	}
+
+	writeJSON(w, http.StatusOK, silences)
+}
+
+// unsilence lifts the silence at /api/silences/<id>
+func (s *Server) unsilence(w http.ResponseWriter, r *http.Request, id identity) {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	silence := strings.TrimPrefix(r.URL.Path, "/api/silences/")
+	if err := s.Scheduler.Unsilence("api:"+id.actor, silence); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// explain serves the description and the next times of the schedule of the
+// query, parsed as the schedules of the jobs, in the time zone of the query
+// or the local one
+func (s *Server) explain(w http.ResponseWriter, r *http.Request, id identity) {
+	q := r.URL.Query()
+	loc := time.Local
+	if tz := q.Get("timezone"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, fmt.Sprintf("invalid timezone %q", tz), http.StatusBadRequest)
+			return
+		}
+	}
+
+	count := defaultExplainCount
+	if c := q.Get("count"); c != "" {
+		var err error
+		if count, err = strconv.Atoi(c); err != nil || count < 1 || count > maxExplainCount {
+			http.Error(w, fmt.Sprintf("invalid count %q, must be between 1 and %d", c, maxExplainCount), http.StatusBadRequest)
+			return
+		}
+	}
+
+	e, err := s.Scheduler.Explain(q.Get("schedule"), loc, count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, e)
+}
+
+// apply reconciles the jobs to the job set of the body, all the jobs, so not
+// by the tenants, only the diff is returned with the dry-run query
+func (s *Server) apply(w http.ResponseWriter, r *http.Request, id identity) {
+	if id.tenant != "" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if s.Applier == nil {
+		http.Error(w, "applying job sets is disabled", http.StatusNotImplemented)
+		return
+	}
+
+	var dryRun bool
+	if v := r.URL.Query().Get("dry-run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid dry-run %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	doc, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := s.Applier("api:"+id.actor, doc, dryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
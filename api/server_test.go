@@ -0,0 +1,573 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type SuiteServer struct {
+	sched *core.Scheduler
+	job   *testJob
+	// closers close the servers started by a test
+	closers []func()
+}
+
+var _ = Suite(&SuiteServer{})
+
+type testJob struct {
+	core.BareJob
+	called chan bool
+	params map[string]string
+}
+
+func (j *testJob) Run(ctx *core.Context) error {
+	j.params = ctx.Execution.Params
+	j.called <- true
+	return nil
+}
+
+type testLogger struct{}
+
+func (*testLogger) Criticalf(format string, args ...interface{}) {}
+func (*testLogger) Debugf(format string, args ...interface{})    {}
+func (*testLogger) Errorf(format string, args ...interface{})    {}
+func (*testLogger) Noticef(format string, args ...interface{})   {}
+func (*testLogger) Warningf(format string, args ...interface{})  {}
+
+type testAuditLog struct {
+	entries []*core.AuditEntry
+}
+
+func (l *testAuditLog) Record(e *core.AuditEntry) error {
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+func (s *SuiteServer) SetUpTest(c *C) {
+	s.sched = core.NewScheduler(&testLogger{})
+	s.job = &testJob{called: make(chan bool, 1)}
+	s.job.Name = "foo"
+	s.job.Schedule = "@daily"
+	s.job.Command = "echo foo"
+	c.Assert(s.sched.AddJob(s.job), IsNil)
+}
+
+func (s *SuiteServer) TearDownTest(c *C) {
+	for _, stop := range s.closers {
+		stop()
+	}
+
+	s.closers = nil
+}
+
+func (s *SuiteServer) request(c *C, config *Config, method, path string, auth func(r *http.Request)) *httptest.ResponseRecorder {
+	srv, err := NewServer(s.sched, config)
+	c.Assert(err, IsNil)
+
+	r := httptest.NewRequest(method, path, nil)
+	if auth != nil {
+		auth(r)
+	}
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func basic(user, password string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.SetBasicAuth(user, password)
+	}
+}
+
+func bearer(t string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+t)
+	}
+}
+
+func (s *SuiteServer) TestListJobs(c *C) {
+	w := s.request(c, &Config{}, "GET", "/api/jobs", nil)
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	var jobs []Job
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &jobs), IsNil)
+	c.Assert(jobs, DeepEquals, []Job{{Name: "foo", Schedule: "@daily", Command: "echo foo"}})
+}
+
+func (s *SuiteServer) TestStatus(c *C) {
+	w := s.request(c, &Config{}, "GET", "/api/status", nil)
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	var status Status
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &status), IsNil)
+	c.Assert(status, DeepEquals, Status{Jobs: 1})
+
+	s.sched.RecordReload(&core.Reload{Added: []string{"foo"}})
+	w = s.request(c, &Config{}, "GET", "/api/status", nil)
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &status), IsNil)
+	c.Assert(status.LastReload.Added, DeepEquals, []string{"foo"})
+}
+
+func (s *SuiteServer) TestRunJob(c *C) {
+	audit := &testAuditLog{}
+	s.sched.Audit = audit
+
+	config := &Config{Users: []string{"admin:p:ss:admin"}}
+	w := s.request(c, config, "POST", "/api/jobs/foo/run", basic("admin", "p:ss"))
+	c.Assert(w.Code, Equals, http.StatusAccepted)
+
+	select {
+	case <-s.job.called:
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	c.Assert(audit.entries, HasLen, 1)
+	c.Assert(audit.entries[0].Actor, Equals, "api:admin")
+	c.Assert(audit.entries[0].Action, Equals, core.AuditTrigger)
+
+	w = s.request(c, config, "POST", "/api/jobs/bar/run", basic("admin", "p:ss"))
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+
+	w = s.request(c, config, "GET", "/api/jobs/foo/run", basic("admin", "p:ss"))
+	c.Assert(w.Code, Equals, http.StatusMethodNotAllowed)
+}
+
+func (s *SuiteServer) TestExecution(c *C) {
+	w := s.request(c, &Config{}, "GET", "/api/executions/foo", nil)
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+
+	e := core.NewExecution()
+	e.Start()
+	e.OutputStream.Write([]byte("foo\nbar"))
+	e.Stop(nil)
+
+	s.sched.Recent = core.NewRecentExecutions(0)
+	s.sched.Recent.Add(s.job, e)
+
+	w = s.request(c, &Config{}, "GET", "/api/executions/"+e.ID, nil)
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	var got core.RecentExecution
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &got), IsNil)
+	c.Assert(got.Job, Equals, "foo")
+	c.Assert(got.ID, Equals, e.ID)
+	c.Assert(got.Stdout, Equals, "foo\nbar")
+
+	s.job.Owner = "team-a"
+	config := &Config{Tokens: []string{"t0k3n:read@team-b"}}
+	w = s.request(c, config, "GET", "/api/executions/"+e.ID, bearer("t0k3n"))
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+}
+
+func (s *SuiteServer) TestHook(c *C) {
+	srv, err := NewServer(s.sched, &Config{Tokens: []string{"t0k3n:admin"}})
+	c.Assert(err, IsNil)
+
+	hook := func(path string) int {
+		r := httptest.NewRequest("POST", path, strings.NewReader(`{"ref": "main"}`))
+		bearer("t0k3n")(r)
+
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// only the jobs triggered by webhooks
+	c.Assert(hook("/api/hooks/foo"), Equals, http.StatusNotFound)
+
+	s.job.Trigger = core.TriggerWebhook
+	c.Assert(hook("/api/hooks/foo"), Equals, http.StatusAccepted)
+
+	select {
+	case <-s.job.called:
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	c.Assert(s.job.params, DeepEquals, map[string]string{core.PayloadParam: `{"ref": "main"}`})
+}
+
+func (s *SuiteServer) TestScopes(c *C) {
+	config := &Config{
+		Users:  []string{"viewer:foo:read", "admin:bar:admin"},
+		Tokens: []string{"t0k3n:read"},
+	}
+
+	testCases := []struct {
+		method, path string
+		auth         func(r *http.Request)
+		code         int
+	}{
+		{"GET", "/api/jobs", nil, http.StatusUnauthorized},
+		{"GET", "/api/jobs", basic("viewer", "bar"), http.StatusUnauthorized},
+		{"GET", "/api/jobs", basic("viewer", "foo"), http.StatusOK},
+		{"GET", "/api/jobs", bearer("t0k3n"), http.StatusOK},
+		{"GET", "/api/jobs", bearer("foo"), http.StatusUnauthorized},
+		{"GET", "/debug/vars", bearer("t0k3n"), http.StatusOK},
+		{"POST", "/api/jobs/bar/run", basic("viewer", "foo"), http.StatusForbidden},
+		{"POST", "/api/jobs/bar/run", bearer("t0k3n"), http.StatusForbidden},
+		{"POST", "/api/jobs/bar/run", basic("admin", "bar"), http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		w := s.request(c, config, tc.method, tc.path, tc.auth)
+		c.Assert(w.Code, Equals, tc.code, Commentf("%s %s", tc.method, tc.path))
+	}
+}
+
+func (s *SuiteServer) TestTenants(c *C) {
+	s.job.Owner = "team-a"
+
+	other := &testJob{called: make(chan bool, 1)}
+	other.Name = "bar"
+	other.Owner = "team-b"
+	s.sched.AddTriggeredJob(other)
+
+	config := &Config{
+		Users:  []string{"a:foo:admin@team-a", "admin:bar:admin"},
+		Tokens: []string{"t0k3n:read@team-b"},
+	}
+
+	w := s.request(c, config, "GET", "/api/jobs", basic("a", "foo"))
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	var jobs []Job
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &jobs), IsNil)
+	c.Assert(jobs, DeepEquals, []Job{{Name: "foo", Schedule: "@daily", Command: "echo foo", Owner: "team-a"}})
+
+	w = s.request(c, config, "GET", "/api/jobs", basic("admin", "bar"))
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &jobs), IsNil)
+	c.Assert(jobs, HasLen, 2)
+
+	w = s.request(c, config, "POST", "/api/jobs/bar/run", basic("a", "foo"))
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+
+	w = s.request(c, config, "POST", "/api/jobs/foo/run", basic("a", "foo"))
+	c.Assert(w.Code, Equals, http.StatusAccepted)
+	<-s.job.called
+
+	w = s.request(c, config, "GET", "/debug/vars", bearer("t0k3n"))
+	c.Assert(w.Code, Equals, http.StatusForbidden)
+}
+
+func (s *SuiteServer) TestNewServerInvalid(c *C) {
+	_, err := NewServer(s.sched, &Config{Users: []string{"foo:bar:baz"}})
+	c.Assert(err, ErrorMatches, `unknown scope "baz".*`)
+
+	_, err = NewServer(s.sched, &Config{Users: []string{"foo:bar"}})
+	c.Assert(err, NotNil)
+
+	_, err = NewServer(s.sched, &Config{Tokens: []string{"foo"}})
+	c.Assert(err, NotNil)
+
+	_, err = NewServer(s.sched, &Config{TLSCert: "foo"})
+	c.Assert(err, NotNil)
+
+	_, err = NewServer(s.sched, &Config{TLSClientCA: "foo"})
+	c.Assert(err, NotNil)
+}
+
+func (s *SuiteServer) TestMutualTLS(c *C) {
+	dir, err := ioutil.TempDir("", "api")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	ca, caKey := newCert(c, dir, "ca", nil, nil)
+	newCert(c, dir, "server", ca, caKey)
+	client, clientKey := newCert(c, dir, "client", ca, caKey)
+
+	srv, err := NewServer(s.sched, &Config{
+		Addr:        "127.0.0.1:0",
+		TLSCert:     filepath.Join(dir, "server.crt"),
+		TLSKey:      filepath.Join(dir, "server.key"),
+		TLSClientCA: filepath.Join(dir, "ca.crt"),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(srv.Start(), IsNil)
+	defer srv.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	url := "https://" + srv.Addr().String() + "/api/jobs"
+	anonymous := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+
+	_, err = anonymous.Get(url)
+	c.Assert(err, NotNil)
+
+	authenticated := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs: roots,
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{client.Raw},
+				PrivateKey:  clientKey,
+			}},
+		},
+	}}
+
+	resp, err := authenticated.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+}
+
+// newCert writes a certificate and its key, signed by the given parent, or
+// self signed as CA if nil
+func newCert(c *C, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, IsNil)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600), IsNil)
+
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	return cert, key
+}
+
+func (s *SuiteServer) TestSilences(c *C) {
+	audit := &testAuditLog{}
+	s.sched.Audit = audit
+
+	srv, err := NewServer(s.sched, &Config{
+		Users: []string{"admin:bar:admin", "viewer:foo:read", "a:foo:admin@team-a"},
+	})
+	c.Assert(err, IsNil)
+
+	do := func(method, path, body string, auth func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		auth(r)
+
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("POST", "/api/silences", `{"Duration": "30m", "Tags": ["role=db"]}`, basic("admin", "bar"))
+	c.Assert(w.Code, Equals, http.StatusCreated)
+
+	silence := &core.Silence{}
+	c.Assert(json.Unmarshal(w.Body.Bytes(), silence), IsNil)
+	c.Assert(silence.Actor, Equals, "api:admin")
+	c.Assert(silence.Tags, DeepEquals, []string{"role=db"})
+
+	c.Assert(audit.entries, HasLen, 1)
+	c.Assert(audit.entries[0].Action, Equals, core.AuditSilence)
+
+	w = do("GET", "/api/silences", "", basic("viewer", "foo"))
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	var silences []core.Silence
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &silences), IsNil)
+	c.Assert(silences, HasLen, 1)
+	c.Assert(silences[0].ID, Equals, silence.ID)
+
+	c.Assert(do("POST", "/api/silences", `{"Duration": "30m"}`, basic("viewer", "foo")).Code, Equals, http.StatusForbidden)
+	c.Assert(do("POST", "/api/silences", `{"Duration": "30m"}`, basic("a", "foo")).Code, Equals, http.StatusForbidden)
+	c.Assert(do("POST", "/api/silences", `{"Duration": "soon"}`, basic("admin", "bar")).Code, Equals, http.StatusBadRequest)
+	c.Assert(do("DELETE", "/api/silences/"+silence.ID, "", basic("viewer", "foo")).Code, Equals, http.StatusForbidden)
+
+	c.Assert(do("DELETE", "/api/silences/"+silence.ID, "", basic("admin", "bar")).Code, Equals, http.StatusNoContent)
+	c.Assert(do("DELETE", "/api/silences/"+silence.ID, "", basic("admin", "bar")).Code, Equals, http.StatusNotFound)
+	c.Assert(s.sched.Silences(), HasLen, 0)
+	c.Assert(audit.entries, HasLen, 2)
+}
+
+func (s *SuiteServer) TestOverrides(c *C) {
+	audit := &testAuditLog{}
+	s.sched.Audit = audit
+	s.job.Owner = "team-a"
+
+	srv, err := NewServer(s.sched, &Config{
+		Users: []string{"admin:bar:admin", "b:foo:admin@team-b"},
+	})
+	c.Assert(err, IsNil)
+
+	do := func(method, path, body string, auth func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		auth(r)
+
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	admin := basic("admin", "bar")
+	c.Assert(do("POST", "/api/jobs/foo/skip", `{"Count": 2}`, admin).Code, Equals, http.StatusNoContent)
+	c.Assert(do("POST", "/api/jobs/foo/skip", `{"Count": -1}`, admin).Code, Equals, http.StatusBadRequest)
+	c.Assert(do("POST", "/api/jobs/bar/skip", `{"Count": 1}`, admin).Code, Equals, http.StatusNotFound)
+	c.Assert(do("POST", "/api/jobs/foo/skip", `{"Count": 1}`, basic("b", "foo")).Code, Equals, http.StatusNotFound)
+	c.Assert(do("POST", "/api/jobs/foo/pause", `{}`, admin).Code, Equals, http.StatusNotFound)
+	c.Assert(s.sched.Skips(s.job), Equals, 2)
+
+	w := do("GET", "/api/jobs", "", admin)
+	var jobs []Job
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &jobs), IsNil)
+	c.Assert(jobs[0].Skips, Equals, 2)
+
+	at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w = do("POST", "/api/jobs/foo/once", `{"At": "`+at+`"}`, admin)
+	c.Assert(w.Code, Equals, http.StatusCreated)
+
+	run := &core.OneTimeRun{}
+	c.Assert(json.Unmarshal(w.Body.Bytes(), run), IsNil)
+	c.Assert(run.Job, Equals, "foo")
+	c.Assert(run.At.Format(time.RFC3339), Equals, at)
+
+	c.Assert(do("POST", "/api/jobs/foo/once", `{"At": "2000-01-01T00:00:00Z"}`, admin).Code, Equals, http.StatusBadRequest)
+
+	w = do("GET", "/api/runs", "", admin)
+	var runs []core.OneTimeRun
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &runs), IsNil)
+	c.Assert(runs, HasLen, 1)
+
+	w = do("GET", "/api/runs", "", basic("b", "foo"))
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &runs), IsNil)
+	c.Assert(runs, HasLen, 0)
+
+	c.Assert(do("DELETE", "/api/runs/"+run.ID, "", basic("b", "foo")).Code, Equals, http.StatusNotFound)
+	c.Assert(do("DELETE", "/api/runs/"+run.ID, "", admin).Code, Equals, http.StatusNoContent)
+	c.Assert(do("DELETE", "/api/runs/"+run.ID, "", admin).Code, Equals, http.StatusNotFound)
+
+	c.Assert(audit.entries, HasLen, 3)
+	c.Assert(audit.entries[0].Action, Equals, core.AuditSkip)
+	c.Assert(audit.entries[1].Action, Equals, core.AuditRunOnce)
+	c.Assert(audit.entries[2].Action, Equals, core.AuditCancelRun)
+}
+
+func (s *SuiteServer) TestExplain(c *C) {
+	w := s.request(c, &Config{}, "GET", "/api/explain?schedule="+url.QueryEscape("0 3 * * 1-5")+"&timezone=Europe/Madrid&count=2", nil)
+	c.Assert(w.Code, Equals, http.StatusOK)
+
+	e := &core.Explanation{}
+	c.Assert(json.Unmarshal(w.Body.Bytes(), e), IsNil)
+	c.Assert(e.Description, Equals, "At 03:00 on Monday through Friday")
+	c.Assert(e.Location, Equals, "Europe/Madrid")
+	c.Assert(e.Next, HasLen, 2)
+
+	c.Assert(s.request(c, &Config{}, "GET", "/api/explain?schedule=0+3", nil).Code, Equals, http.StatusBadRequest)
+	c.Assert(s.request(c, &Config{}, "GET", "/api/explain?schedule=@daily&timezone=Mars/Olympus", nil).Code, Equals, http.StatusBadRequest)
+	c.Assert(s.request(c, &Config{}, "GET", "/api/explain?schedule=@daily&count=1000", nil).Code, Equals, http.StatusBadRequest)
+}
+
+func (s *SuiteServer) TestApply(c *C) {
+	srv, err := NewServer(s.sched, &Config{
+		Users: []string{"admin:bar:admin", "viewer:foo:read", "a:foo:admin@team-a"},
+	})
+	c.Assert(err, IsNil)
+
+	do := func(path, body string, auth func(r *http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		auth(r)
+
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	c.Assert(do("/api/apply", "", basic("admin", "bar")).Code, Equals, http.StatusNotImplemented)
+
+	var actor, doc string
+	srv.Applier = func(a string, d []byte, dryRun bool) (*ApplyResult, error) {
+		if len(d) == 0 {
+			return nil, fmt.Errorf("empty job set")
+		}
+
+		actor, doc = a, string(d)
+		return &ApplyResult{DryRun: dryRun, Added: []string{"bar"}, Removed: []string{"foo"}}, nil
+	}
+
+	w := do("/api/apply?dry-run=true", "job-local: {}", basic("admin", "bar"))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(actor, Equals, "api:admin")
+	c.Assert(doc, Equals, "job-local: {}")
+
+	result := &ApplyResult{}
+	c.Assert(json.Unmarshal(w.Body.Bytes(), result), IsNil)
+	c.Assert(result, DeepEquals, &ApplyResult{DryRun: true, Added: []string{"bar"}, Removed: []string{"foo"}})
+
+	c.Assert(do("/api/apply", "", basic("admin", "bar")).Code, Equals, http.StatusBadRequest)
+	c.Assert(do("/api/apply?dry-run=maybe", "job-local: {}", basic("admin", "bar")).Code, Equals, http.StatusBadRequest)
+	c.Assert(do("/api/apply", "job-local: {}", basic("viewer", "foo")).Code, Equals, http.StatusForbidden)
+	c.Assert(do("/api/apply", "job-local: {}", basic("a", "foo")).Code, Equals, http.StatusForbidden)
+}
+
+func (s *SuiteServer) TestListenWithoutCredentials(c *C) {
+	srv, err := NewServer(s.sched, &Config{Addr: "0.0.0.0:0"})
+	c.Assert(err, IsNil)
+	c.Assert(srv.Start(), ErrorMatches, `refusing to listen on "0.0.0.0:0" without credentials.*`)
+	c.Assert(srv.StartGRPC(":0"), ErrorMatches, `refusing to listen on ":0" without credentials.*`)
+
+	for _, addr := range []string{"127.0.0.1:0", "[::1]:0", "localhost:0"} {
+		c.Assert(srv.checkListen(addr), IsNil)
+	}
+
+	srv, err = NewServer(s.sched, &Config{Addr: "0.0.0.0:0", Tokens: []string{"t0k3n:admin"}})
+	c.Assert(err, IsNil)
+	c.Assert(srv.checkListen(srv.Config.Addr), IsNil)
+}
+
+func (s *SuiteServer) TestApplyWithoutCredentials(c *C) {
+	srv, err := NewServer(s.sched, &Config{})
+	c.Assert(err, IsNil)
+	srv.Applier = func(a string, d []byte, dryRun bool) (*ApplyResult, error) {
+		return &ApplyResult{DryRun: dryRun}, nil
+	}
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest("POST", "/api/apply", strings.NewReader("job-local: {}")))
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+
+	w = httptest.NewRecorder()
+	srv.handler(authenticateSocket, true).ServeHTTP(w, httptest.NewRequest("POST", "/api/apply", strings.NewReader("job-local: {}")))
+	c.Assert(w.Code, Equals, http.StatusOK)
+}
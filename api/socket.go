@@ -0,0 +1,367 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// DefaultSocket path of the unix socket of the control API
+const DefaultSocket = "/var/run/ofelia.sock"
+
+const socketPermissions = 0660
+
+// socketActor is the actor of the requests received through the socket
+const socketActor = "socket"
+
+// StartSocket starts serving the API on a unix socket, in background. The
+// access is restricted by the permissions of the socket file, so no
+// credentials are required and every client is admin.
+func (s *Server) StartSocket(path string) error {
+	if path == "" {
+		path = DefaultSocket
+	}
+
+	// a socket left behind by a previous process prevents listening
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(path, socketPermissions); err != nil {
+		l.Close()
+		return err
+	}
+
+	s.socket = &http.Server{Handler: s.handler(authenticateSocket, true)}
+	go func() {
+		if err := s.socket.Serve(l); err != http.ErrServerClosed {
+			s.Scheduler.Logger.Errorf("API socket error: %s", err)
+		}
+	}()
+
+	s.Scheduler.Logger.Noticef("API listening on %s", path)
+	return nil
+}
+
+func authenticateSocket(r *http.Request) (identity, bool) {
+	return identity{actor: socketActor, scope: ScopeAdmin}, true
+}
+
+// clientTimeout max time of the requests of the clients of remote APIs
+const clientTimeout = 10 * time.Second
+
+// Client of the API served on a unix socket, or by a remote instance
+type Client struct {
+	http *http.Client
+	base string
+}
+
+// NewSocketClient returns a client of the API served on the given socket,
+// DefaultSocket if empty
+func NewSocketClient(path string) *Client {
+	if path == "" {
+		path = DefaultSocket
+	}
+
+	return &Client{base: "http://ofelia", http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}}
+}
+
+// NewClient returns a client of the API served at the given URL, e.g.
+// https://host:8081, authenticated by the given bearer token, if any, or by
+// the user and password of the URL
+func NewClient(addr, token string) (*Client, error) {
+	u, err := url.Parse(addr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid API URL %q, must be an http or https URL", addr)
+	}
+
+	t := &credentialsTransport{token: token}
+	if u.User != nil {
+		t.user = u.User.Username()
+		t.password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	return &Client{
+		base: strings.TrimSuffix(u.String(), "/"),
+		http: &http.Client{Timeout: clientTimeout, Transport: t},
+	}, nil
+}
+
+// credentialsTransport sets the credentials of the requests of a client
+type credentialsTransport struct {
+	token    string
+	user     string
+	password string
+}
+
+func (t *credentialsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// the request must not be modified, but a copy
+	r = r.WithContext(r.Context())
+	r.Header = r.Header.Clone()
+	switch {
+	case t.token != "":
+		r.Header.Set("Authorization", "Bearer "+t.token)
+	case t.user != "":
+		r.SetBasicAuth(t.user, t.password)
+	}
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// Jobs returns the jobs of the scheduler
+func (c *Client) Jobs() ([]Job, error) {
+	resp, err := c.http.Get(c.base + "/api/jobs")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var jobs []Job
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Status returns the status of the scheduler
+func (c *Client) Status() (*Status, error) {
+	resp, err := c.http.Get(c.base + "/api/status")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	status := &Status{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Executions returns the recent executions, the newest first, without their
+// output
+func (c *Client) Executions() ([]core.RecentExecution, error) {
+	resp, err := c.http.Get(c.base + "/api/executions")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var executions []core.RecentExecution
+	if err := json.NewDecoder(resp.Body).Decode(&executions); err != nil {
+		return nil, err
+	}
+
+	return executions, nil
+}
+
+// Run runs the given job, without waiting for it to finish
+func (c *Client) Run(name string) error {
+	resp, err := c.http.Post(c.base+"/api/jobs/"+url.PathEscape(name)+"/run", "", nil)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	return checkResponse(resp, http.StatusAccepted)
+}
+
+// Skip skips the next n scheduled occurrences of the given job, zero resumes
+// its schedule
+func (c *Client) Skip(name string, n int) error {
+	body, _ := json.Marshal(&SkipRequest{Count: n})
+	resp, err := c.http.Post(c.base+"/api/jobs/"+url.PathEscape(name)+"/skip", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	return checkResponse(resp, http.StatusNoContent)
+}
+
+// RunOnce runs the given job once at the given time, besides its schedule
+func (c *Client) RunOnce(name string, at time.Time) (*core.OneTimeRun, error) {
+	body, _ := json.Marshal(&OnceRequest{At: at})
+	resp, err := c.http.Post(c.base+"/api/jobs/"+url.PathEscape(name)+"/once", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+
+	run := &core.OneTimeRun{}
+	if err := json.NewDecoder(resp.Body).Decode(run); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// Runs returns the one-time runs not run yet
+func (c *Client) Runs() ([]core.OneTimeRun, error) {
+	resp, err := c.http.Get(c.base + "/api/runs")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var runs []core.OneTimeRun
+	if err := json.NewDecoder(resp.Body).Decode(&runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// CancelRun cancels the one-time run with the given ID
+func (c *Client) CancelRun(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.base+"/api/runs/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	return checkResponse(resp, http.StatusNoContent)
+}
+
+// Silence silences the notifications of the jobs with any of the given tags,
+// or of all the jobs without tags, for the given duration
+func (c *Client) Silence(d time.Duration, tags []string) (*core.Silence, error) {
+	body, _ := json.Marshal(&SilenceRequest{Duration: d.String(), Tags: tags})
+	resp, err := c.http.Post(c.base+"/api/silences", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+
+	silence := &core.Silence{}
+	if err := json.NewDecoder(resp.Body).Decode(silence); err != nil {
+		return nil, err
+	}
+
+	return silence, nil
+}
+
+// Silences returns the active silences
+func (c *Client) Silences() ([]core.Silence, error) {
+	resp, err := c.http.Get(c.base + "/api/silences")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var silences []core.Silence
+	if err := json.NewDecoder(resp.Body).Decode(&silences); err != nil {
+		return nil, err
+	}
+
+	return silences, nil
+}
+
+// Unsilence lifts the silence with the given ID
+func (c *Client) Unsilence(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.base+"/api/silences/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	return checkResponse(resp, http.StatusNoContent)
+}
+
+// Apply reconciles the jobs of the scheduler to exactly match the given job
+// set, a YAML document, returning the diff. When dryRun, nothing is changed.
+func (c *Client) Apply(doc []byte, dryRun bool) (*ApplyResult, error) {
+	u := c.base + "/api/apply"
+	if dryRun {
+		u += "?dry-run=true"
+	}
+
+	resp, err := c.http.Post(u, "application/yaml", bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func checkResponse(resp *http.Response, status int) error {
+	if resp.StatusCode == status {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+func (s *SuiteServer) TestSocket(c *C) {
+	dir, err := ioutil.TempDir("", "api")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	audit := &testAuditLog{}
+	s.sched.Audit = audit
+
+	// the credentials are not required through the socket
+	srv, err := NewServer(s.sched, &Config{Users: []string{"admin:bar:admin"}})
+	c.Assert(err, IsNil)
+
+	path := filepath.Join(dir, "ofelia.sock")
+	c.Assert(ioutil.WriteFile(path, nil, 0600), IsNil)
+	c.Assert(srv.StartSocket(path), NotNil)
+	c.Assert(os.Remove(path), IsNil)
+
+	c.Assert(srv.StartSocket(path), IsNil)
+	defer srv.Stop()
+
+	fi, err := os.Stat(path)
+	c.Assert(err, IsNil)
+	c.Assert(fi.Mode().Perm(), Equals, os.FileMode(socketPermissions))
+
+	client := NewSocketClient(path)
+	jobs, err := client.Jobs()
+	c.Assert(err, IsNil)
+	c.Assert(jobs, DeepEquals, []Job{{Name: "foo", Schedule: "@daily", Command: "echo foo"}})
+
+	c.Assert(client.Run("foo"), IsNil)
+	select {
+	case <-s.job.called:
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	c.Assert(audit.entries, HasLen, 1)
+	c.Assert(audit.entries[0].Actor, Equals, "api:socket")
+	c.Assert(audit.entries[0].Action, Equals, core.AuditTrigger)
+
+	c.Assert(client.Run("bar"), ErrorMatches, "404 Not Found: .*")
+
+	silence, err := client.Silence(time.Hour, []string{"role=db"})
+	c.Assert(err, IsNil)
+	c.Assert(silence.Actor, Equals, "api:socket")
+
+	silences, err := client.Silences()
+	c.Assert(err, IsNil)
+	c.Assert(silences, HasLen, 1)
+
+	c.Assert(client.Unsilence(silence.ID), IsNil)
+	c.Assert(client.Unsilence(silence.ID), ErrorMatches, "404 Not Found: .*")
+
+	c.Assert(client.Skip("foo", 1), IsNil)
+	c.Assert(client.Skip("bar", 1), ErrorMatches, "404 Not Found: .*")
+
+	run, err := client.RunOnce("foo", time.Now().Add(time.Hour))
+	c.Assert(err, IsNil)
+
+	runs, err := client.Runs()
+	c.Assert(err, IsNil)
+	c.Assert(runs, HasLen, 1)
+
+	c.Assert(client.CancelRun(run.ID), IsNil)
+	c.Assert(client.CancelRun(run.ID), ErrorMatches, "404 Not Found: .*")
+}
@@ -0,0 +1,34 @@
+package broker
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka publishes the messages to a Kafka topic
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+func newKafka(u *url.URL, topic string) *Kafka {
+	return &Kafka{writer: kafka.NewWriter(kafka.WriterConfig{
+		Brokers: strings.Split(u.Host, ","),
+		Topic:   topic,
+	})}
+}
+
+// Publish writes the message to the topic
+func (k *Kafka) Publish(payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	return k.writer.WriteMessages(ctx, kafka.Message{Value: payload})
+}
+
+// Close flushes the pending messages and closes the connections
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
@@ -0,0 +1,66 @@
+package broker
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttQoS the messages are delivered at least once
+const mqttQoS = 1
+
+// disconnectQuiesce milliseconds waiting for the pending messages on Close
+const disconnectQuiesce = 250
+
+// MQTT publishes the messages to a MQTT topic
+type MQTT struct {
+	client mqtt.Client
+	topic  string
+}
+
+func newMQTT(u *url.URL, topic string) (*MQTT, error) {
+	scheme := "tcp"
+	if u.Scheme == "mqtts" {
+		scheme = "ssl"
+	}
+
+	hostname, _ := os.Hostname()
+	opts := mqtt.NewClientOptions().
+		AddBroker(scheme + "://" + u.Host).
+		SetClientID("ofelia-" + hostname).
+		SetConnectTimeout(publishTimeout)
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		opts.SetUsername(u.User.Username()).SetPassword(password)
+	}
+
+	client := mqtt.NewClient(opts)
+	if err := wait(client.Connect()); err != nil {
+		return nil, err
+	}
+
+	return &MQTT{client: client, topic: topic}, nil
+}
+
+// Publish publishes the message to the topic, waiting for the broker to
+// acknowledge it
+func (m *MQTT) Publish(payload []byte) error {
+	return wait(m.client.Publish(m.topic, mqttQoS, false, payload))
+}
+
+// Close disconnects from the broker
+func (m *MQTT) Close() error {
+	m.client.Disconnect(disconnectQuiesce)
+	return nil
+}
+
+func wait(t mqtt.Token) error {
+	if !t.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timeout after %s", publishTimeout)
+	}
+
+	return t.Error()
+}
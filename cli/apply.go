@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/mcuadros/ofelia/api"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mitchellh/mapstructure"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ApplyCommand reconciles the jobs of a running daemon to exactly match a
+// declarative job set, through its socket
+type ApplyCommand struct {
+	Socket string `long:"socket" description:"unix socket of the daemon" default:"/var/run/ofelia.sock"`
+	DryRun bool   `long:"dry-run" description:"only prints the changes, without applying them"`
+}
+
+// Execute runs the apply command
+func (c *ApplyCommand) Execute(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("the job set file is required, e.g. jobs.yaml")
+	}
+
+	doc, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	// checked before being sent, to report the errors with the file name
+	if err := (&Config{}).readJobSet(doc); err != nil {
+		return fmt.Errorf("%s: %s", args[0], err)
+	}
+
+	result, err := api.NewSocketClient(c.Socket).Apply(doc, c.DryRun)
+	if err != nil {
+		return err
+	}
+
+	printApplyResult(os.Stdout, result)
+	return nil
+}
+
+// printApplyResult prints the jobs added, updated, with their changed params,
+// and removed by an applied job set
+func printApplyResult(out io.Writer, r *api.ApplyResult) {
+	for _, name := range r.Added {
+		fmt.Fprintf(out, "+ %s\n", name)
+	}
+
+	for _, name := range r.Updated {
+		fmt.Fprintf(out, "~ %s (%s)\n", name, strings.Join(r.Changed[name], ", "))
+	}
+
+	for _, name := range r.Removed {
+		fmt.Fprintf(out, "- %s\n", name)
+	}
+
+	summary := fmt.Sprintf("%d added, %d updated, %d removed", len(r.Added), len(r.Updated), len(r.Removed))
+	if r.DryRun {
+		summary = "Dry run, nothing applied: " + summary
+	}
+
+	fmt.Fprintln(out, summary)
+}
+
+// readJobSet reads the jobs of a YAML job set, laid out as the YAML dump of
+// the config: the params of the jobs by type and name. Only the built-in job
+// types are supported.
+func (c *Config) readJobSet(doc []byte) error {
+	var sections map[string]interface{}
+	if err := yaml.Unmarshal(doc, &sections); err != nil {
+		return err
+	}
+
+	for t := range sections {
+		if !isBuiltinJobType(t) {
+			return fmt.Errorf("unknown job type %q, only the jobs of the built-in types can be applied", t)
+		}
+	}
+
+	var set map[string]map[string]map[string]interface{}
+	if err := yaml.Unmarshal(doc, &set); err != nil {
+		return err
+	}
+
+	return c.decodeJobSet(set)
+}
+
+// decodeJobSet adds the jobs of the given job set, the params of the jobs by
+// type and name, to the config, the jobs already declared by the config being
+// rejected
+func (c *Config) decodeJobSet(set map[string]map[string]map[string]interface{}) error {
+	targets := map[string]interface{}{
+		jobExec:       &c.ExecJobs,
+		jobRun:        &c.RunJobs,
+		jobServiceRun: &c.ServiceJobs,
+		jobLocal:      &c.LocalJobs,
+		jobPipeline:   &c.PipelineJobs,
+		jobBackup:     &c.BackupJobs,
+		jobECS:        &c.ECSJobs,
+		jobNomad:      &c.NomadJobs,
+	}
+
+	for t, jobs := range set {
+		target, ok := targets[t]
+		if !ok {
+			return fmt.Errorf("unknown job type %q, only the jobs of the built-in types can be applied", t)
+		}
+
+		declared := reflect.ValueOf(target).Elem()
+		for name, params := range jobs {
+			if declared.MapIndex(reflect.ValueOf(name)).IsValid() {
+				return fmt.Errorf("%s %q already declared", t, name)
+			}
+
+			if params == nil {
+				jobs[name] = make(map[string]interface{})
+			}
+		}
+
+		c.extractPluginParams(t, jobs)
+		if err := mapstructure.WeakDecode(jobs, target); err != nil {
+			return fmt.Errorf("%s: %s", t, err)
+		}
+	}
+
+	return nil
+}
+
+// jobSetApplier reconciles the jobs of a scheduler to the applied job sets,
+// built with the global config and the notifiers of the daemon config
+type jobSetApplier struct {
+	sched  *core.Scheduler
+	config *Config
+	client *docker.Client
+	// name of the reconciliation in the logs
+	name string
+
+	// mu serializes the applied job sets
+	mu sync.Mutex
+}
+
+func newJobSetApplier(sched *core.Scheduler, config *Config, name string) (*jobSetApplier, error) {
+	client, err := (&Config{}).buildDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &jobSetApplier{
+		sched:  sched,
+		config: config,
+		client: client,
+		name:   name,
+	}, nil
+}
+
+// Apply reconciles the jobs of the scheduler to the given job set, it is an
+// api.Applier
+func (a *jobSetApplier) Apply(actor string, doc []byte, dryRun bool) (*api.ApplyResult, error) {
+	config := &Config{Global: a.config.Global, Groups: a.config.Groups, notifiers: a.config.notifiers}
+	if err := config.readJobSet(doc); err != nil {
+		return nil, err
+	}
+
+	return a.apply(actor, config, dryRun)
+}
+
+// apply reconciles the jobs of the scheduler to the jobs of the given config,
+// checked as a whole before changing the jobs of the scheduler
+func (a *jobSetApplier) apply(actor string, config *Config, dryRun bool) (*api.ApplyResult, error) {
+	jobs, err := config.buildJobs(a.client, a.sched.DockerMonitor)
+	if err != nil {
+		return nil, err
+	}
+
+	check := core.NewScheduler(a.sched.Logger)
+	check.Subscriber = a.sched.Subscriber
+	check.DockerAPI = a.sched.DockerAPI
+	check.Jobs = jobs
+	if err := checkTriggers(check); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return (&jobsReconciler{sched: a.sched, actor: actor, name: a.name, dryRun: dryRun}).reconcile(jobs), nil
+}
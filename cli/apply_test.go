@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+
+	"github.com/mcuadros/ofelia/api"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteApply struct{}
+
+var _ = Suite(&SuiteApply{})
+
+func (s *SuiteApply) TestReadJobSet(c *C) {
+	config := &Config{}
+	c.Assert(config.readJobSet([]byte(`
+job-local:
+  backup:
+    schedule: '@daily'
+    command: backup /data
+    tags: [db, nightly]
+    slack-webhook: https://hooks.slack.com/services/backup
+job-run:
+  clean:
+    schedule: '@hourly'
+    image: alpine
+    volume:
+    - /tmp:/tmp
+`)), IsNil)
+
+	c.Assert(config.LocalJobs, HasLen, 1)
+	c.Assert(config.LocalJobs["backup"].Command, Equals, "backup /data")
+	c.Assert(config.LocalJobs["backup"].Tags, DeepEquals, []string{"db", "nightly"})
+	c.Assert(config.LocalJobs["backup"].SlackWebhook, Equals, "https://hooks.slack.com/services/backup")
+	c.Assert(config.RunJobs["clean"].Image, Equals, "alpine")
+	c.Assert(config.RunJobs["clean"].Volume, DeepEquals, []string{"/tmp:/tmp"})
+
+	c.Assert((&Config{}).readJobSet([]byte("global:\n  default-user: nobody\n")), ErrorMatches, `unknown job type "global".*`)
+	c.Assert((&Config{}).readJobSet([]byte("job-local: [foo]\n")), NotNil)
+}
+
+func (s *SuiteApply) TestApply(c *C) {
+	sched, err := BuildFromString(`
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+
+		[job-local "updated"]
+		schedule = @every 10s
+		command = echo foo
+
+		[job-local "removed"]
+		schedule = @every 10s
+		command = echo removed
+	`)
+	c.Assert(err, IsNil)
+
+	audit := &testAuditLog{}
+	sched.Audit = audit
+
+	kept := sched.GetJob("kept")
+	a := &jobSetApplier{sched: sched, config: &Config{}, name: "Apply"}
+	doc := []byte(`
+job-local:
+  kept:
+    schedule: '@every 10s'
+    command: echo kept
+  updated:
+    schedule: '@every 10s'
+    command: echo bar
+  added:
+    schedule: '@every 10s'
+    command: echo added
+`)
+
+	expected := &api.ApplyResult{
+		DryRun:  true,
+		Added:   []string{"added"},
+		Updated: []string{"updated"},
+		Removed: []string{"removed"},
+		Changed: map[string][]string{"updated": {"Command"}},
+	}
+
+	r, err := a.Apply("api:admin", doc, true)
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, expected)
+	c.Assert(sched.Jobs, HasLen, 3)
+	c.Assert(sched.GetJob("removed"), NotNil)
+	c.Assert(audit.entries, HasLen, 0)
+
+	expected.DryRun = false
+	r, err = a.Apply("api:admin", doc, false)
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, expected)
+
+	c.Assert(sched.Jobs, HasLen, 3)
+	c.Assert(sched.GetJob("removed"), IsNil)
+	c.Assert(sched.GetJob("added"), NotNil)
+	c.Assert(sched.GetJob("kept"), Equals, kept)
+	c.Assert(sched.GetJob("updated").GetCommand(), Equals, "echo bar")
+	c.Assert(sched.LastReload().Added, DeepEquals, []string{"added"})
+
+	c.Assert(audit.entries, HasLen, 3)
+	for _, e := range audit.entries {
+		c.Assert(e.Actor, Equals, "api:admin")
+	}
+
+	r, err = a.Apply("api:admin", doc, false)
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, &api.ApplyResult{})
+}
+
+func (s *SuiteApply) TestApplyInvalid(c *C) {
+	sched, err := BuildFromString(`
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+	`)
+	c.Assert(err, IsNil)
+
+	a := &jobSetApplier{sched: sched, config: &Config{}, name: "Apply"}
+	_, err = a.Apply("api:admin", []byte(`
+job-local:
+  foo:
+    schedule: '@every 10s'
+    command: echo foo
+    on-success: bar
+`), false)
+	c.Assert(err, ErrorMatches, `job "foo": unknown job "bar".*`)
+	c.Assert(sched.Jobs, HasLen, 1)
+	c.Assert(sched.GetJob("kept"), NotNil)
+
+	_, err = a.Apply("api:admin", []byte(`
+job-local:
+  foo:
+    schedule: '@every 10s'
+    command: echo foo
+    on-success: bar
+  bar:
+    command: echo bar
+    on-failure: foo
+`), false)
+	c.Assert(err, ErrorMatches, `job "bar": on-success/on-failure cycle bar -> foo -> bar`)
+	c.Assert(sched.Jobs, HasLen, 1)
+}
+
+func (s *SuiteApply) TestPrintApplyResult(c *C) {
+	var buf bytes.Buffer
+	printApplyResult(&buf, &api.ApplyResult{
+		Added:   []string{"foo"},
+		Updated: []string{"bar"},
+		Removed: []string{"baz", "qux"},
+		Changed: map[string][]string{"bar": {"Command", "Schedule"}},
+	})
+
+	c.Assert(buf.String(), Equals, ""+
+		"+ foo\n"+
+		"~ bar (Command, Schedule)\n"+
+		"- baz\n"+
+		"- qux\n"+
+		"1 added, 1 updated, 2 removed\n",
+	)
+
+	buf.Reset()
+	printApplyResult(&buf, &api.ApplyResult{DryRun: true})
+	c.Assert(buf.String(), Equals, "Dry run, nothing applied: 0 added, 0 updated, 0 removed\n")
+}
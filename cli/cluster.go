@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mcuadros/ofelia/api"
+)
+
+// ClusterCommand lists the jobs, or the recent executions, of several running
+// daemons, through their HTTP APIs, merged in a single view
+type ClusterCommand struct {
+	Peers      []string `long:"peer" required:"true" description:"HTTP API of a daemon, as name=url, the name being the host of the URL if omitted, repeatable"`
+	Token      string   `long:"token" env:"OFELIA_FEDERATION_TOKEN" description:"bearer token of the APIs, basic auth credentials may be given in the URLs instead"`
+	Executions bool     `long:"executions" description:"lists the recent executions instead of the jobs"`
+}
+
+// Execute runs the cluster command
+func (c *ClusterCommand) Execute(args []string) error {
+	federation, err := api.NewFederation("", c.Peers, c.Token)
+	if err != nil {
+		return err
+	}
+
+	var errs map[string]string
+	if c.Executions {
+		executions := federation.Executions(nil)
+		errs = executions.Errors
+		err = printInstanceExecutions(os.Stdout, executions.Executions)
+	} else {
+		jobs := federation.Jobs(nil)
+		errs = jobs.Errors
+		err = printInstanceJobs(os.Stdout, jobs.Jobs)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	printUnreachable(os.Stdout, errs)
+	if len(errs) == len(c.Peers) {
+		return fmt.Errorf("no daemon reachable")
+	}
+
+	return nil
+}
+
+func printInstanceJobs(out io.Writer, jobs []api.InstanceJob) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tNAME\tSCHEDULE\tOWNER\tORPHANED\tCOMMAND")
+	for _, j := range jobs {
+		var orphaned string
+		if j.Orphaned != nil {
+			orphaned = j.Orphaned.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", j.Instance, j.Name, j.Schedule, j.Owner, orphaned, j.Command)
+	}
+
+	return w.Flush()
+}
+
+func printInstanceExecutions(out io.Writer, executions []api.InstanceExecution) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tJOB\tID\tDATE\tDURATION\tRESULT")
+	for _, e := range executions {
+		result := "ok"
+		switch {
+		case e.Skipped:
+			result = "skipped"
+		case e.Failed:
+			result = "failed"
+		}
+
+		fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Instance, e.Job, e.ID, e.Date.Format(time.RFC3339), e.Duration.Round(time.Millisecond), result,
+		)
+	}
+
+	return w.Flush()
+}
+
+// printUnreachable prints the errors of the daemons unable to be queried, if
+// any, by name
+func printUnreachable(out io.Writer, errs map[string]string) {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for i, name := range names {
+		if i == 0 {
+			fmt.Fprintln(out)
+		}
+
+		fmt.Fprintf(out, "Unreachable: %s: %s\n", name, errs[name])
+	}
+}
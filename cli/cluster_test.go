@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/mcuadros/ofelia/api"
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteCluster struct{}
+
+var _ = Suite(&SuiteCluster{})
+
+func (s *SuiteCluster) TestPrintInstanceJobs(c *C) {
+	var buf bytes.Buffer
+	c.Assert(printInstanceJobs(&buf, []api.InstanceJob{
+		{Instance: "web-1", Job: api.Job{Name: "foo", Schedule: "@daily", Command: "echo foo", Owner: "team-a"}},
+		{Instance: "db", Job: api.Job{Name: "backup", Schedule: "@hourly", Command: "backup"}},
+	}), IsNil)
+
+	c.Assert(buf.String(), Equals, ""+
+		"INSTANCE  NAME    SCHEDULE  OWNER   ORPHANED  COMMAND\n"+
+		"web-1     foo     @daily    team-a            echo foo\n"+
+		"db        backup  @hourly                     backup\n",
+	)
+}
+
+func (s *SuiteCluster) TestPrintInstanceExecutions(c *C) {
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	c.Assert(printInstanceExecutions(&buf, []api.InstanceExecution{
+		{Instance: "db", RecentExecution: core.RecentExecution{Job: "backup", ID: "a1b2", Date: date, Duration: 1500 * time.Millisecond, Failed: true}},
+		{Instance: "web-1", RecentExecution: core.RecentExecution{Job: "foo", ID: "c3d4", Date: date, Duration: time.Second}},
+	}), IsNil)
+
+	c.Assert(buf.String(), Equals, ""+
+		"INSTANCE  JOB     ID    DATE                  DURATION  RESULT\n"+
+		"db        backup  a1b2  2020-01-01T00:00:00Z  1.5s      failed\n"+
+		"web-1     foo     c3d4  2020-01-01T00:00:00Z  1s        ok\n",
+	)
+}
+
+func (s *SuiteCluster) TestPrintUnreachable(c *C) {
+	var buf bytes.Buffer
+	printUnreachable(&buf, nil)
+	c.Assert(buf.String(), Equals, "")
+
+	printUnreachable(&buf, map[string]string{"web-2": "connection refused", "db": "401 Unauthorized: unauthorized"})
+	c.Assert(buf.String(), Equals, "\nUnreachable: db: 401 Unauthorized: unauthorized\nUnreachable: web-2: connection refused\n")
+}
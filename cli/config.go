@@ -1,23 +1,52 @@
 package cli
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/mcuadros/ofelia/broker"
 	"github.com/mcuadros/ofelia/core"
 	"github.com/mcuadros/ofelia/middlewares"
-	logging "github.com/op/go-logging"
 
 	defaults "github.com/mcuadros/go-defaults"
+	logging "github.com/op/go-logging"
 	gcfg "gopkg.in/gcfg.v1"
 )
 
 const (
-	logFormat     = "%{time} %{color} %{shortfile} ▶ %{level}%{color:reset} %{message}"
 	jobExec       = "job-exec"
 	jobRun        = "job-run"
 	jobServiceRun = "job-service-run"
 	jobLocal      = "job-local"
+	jobPipeline   = "job-pipeline"
+	jobBackup     = "job-backup"
+	jobECS        = "job-ecs"
+	jobNomad      = "job-nomad"
+)
+
+// groupSection section of the settings shared by the jobs of a group
+const groupSection = "group"
+
+// prefixes of the references to the secrets of each backend, e.g.
+// "vault:kv/data/backup#password"
+const (
+	secretsVault = "vault"
+	secretsAWS   = "aws-sm"
+)
+
+// defaultSecretsTTL time the secrets are cached without secrets-ttl
+const defaultSecretsTTL = 5 * time.Minute
+
+// actors of the control actions recorded in the audit log
+const (
+	auditActorConfig       = "config"
+	auditActorDockerLabels = "docker-labels"
 )
 
 var IsDockerEnv bool
@@ -25,18 +54,64 @@ var IsDockerEnv bool
 // Config contains the configuration
 type Config struct {
 	Global struct {
-		middlewares.SlackConfig `mapstructure:",squash"`
-		middlewares.SaveConfig  `mapstructure:",squash"`
-		middlewares.MailConfig  `mapstructure:",squash"`
+		middlewares.SlackConfig        `mapstructure:",squash"`
+		middlewares.SaveConfig         `mapstructure:",squash"`
+		middlewares.MailConfig         `mapstructure:",squash"`
+		middlewares.DockerHealthConfig `mapstructure:",squash"`
+		middlewares.NotifyConfig       `mapstructure:",squash"`
+		LogConfig                      `mapstructure:",squash"`
+		core.WatchConfig               `mapstructure:",squash"`
+		QueueFile                      string `gcfg:"queue-file" mapstructure:"queue-file"`
+		PullCache                      string `gcfg:"pull-cache" mapstructure:"pull-cache"`
+		Pool                           []string
+		OrphanRemoveAfter              string `gcfg:"orphan-remove-after" mapstructure:"orphan-remove-after"`
+		AuditLog                       string `gcfg:"audit-log" mapstructure:"audit-log"`
+		HistoryDriver                  string `gcfg:"history-driver" mapstructure:"history-driver"`
+		HistoryDSN                     string `gcfg:"history-dsn" mapstructure:"history-dsn" secret:"true"`
+		PublishURL                     string `gcfg:"publish-url" mapstructure:"publish-url" secret:"true"`
+		NATSURL                        string `gcfg:"nats-url" mapstructure:"nats-url" secret:"true"`
+		JobOverridesGlobal             string `gcfg:"job-overrides-global" mapstructure:"job-overrides-global" default:"true"`
+		Redact                         []string
+		DockerAPI                      []string `gcfg:"docker-api" mapstructure:"docker-api"`
+		Rootless                       string   `gcfg:"rootless" mapstructure:"rootless"`
+		DefaultUser                    string   `gcfg:"default-user" mapstructure:"default-user"`
+		SecretsConfig                  `mapstructure:",squash"`
 	}
-	ExecJobs    map[string]*ExecJobConfig    `gcfg:"job-exec" mapstructure:"job-exec,squash"`
-	RunJobs     map[string]*RunJobConfig     `gcfg:"job-run" mapstructure:"job-run,squash"`
-	ServiceJobs map[string]*RunServiceConfig `gcfg:"job-service-run" mapstructure:"job-service-run,squash"`
-	LocalJobs   map[string]*LocalJobConfig   `gcfg:"job-local" mapstructure:"job-local,squash"`
+	Groups       map[string]*GroupConfig       `gcfg:"group" mapstructure:"group,squash"`
+	ExecJobs     map[string]*ExecJobConfig     `gcfg:"job-exec" mapstructure:"job-exec,squash"`
+	RunJobs      map[string]*RunJobConfig      `gcfg:"job-run" mapstructure:"job-run,squash"`
+	ServiceJobs  map[string]*RunServiceConfig  `gcfg:"job-service-run" mapstructure:"job-service-run,squash"`
+	LocalJobs    map[string]*LocalJobConfig    `gcfg:"job-local" mapstructure:"job-local,squash"`
+	PipelineJobs map[string]*PipelineJobConfig `gcfg:"job-pipeline" mapstructure:"job-pipeline,squash"`
+	BackupJobs   map[string]*BackupJobConfig   `gcfg:"job-backup" mapstructure:"job-backup,squash"`
+	ECSJobs      map[string]*ECSJobConfig      `gcfg:"job-ecs" mapstructure:"job-ecs,squash"`
+	NomadJobs    map[string]*NomadJobConfig    `gcfg:"job-nomad" mapstructure:"job-nomad,squash"`
+
+	// custom jobs of the types registered with core.RegisterJobType
+	custom sectionParams
+	// extra params of the sections, of the middlewares not embedded in them
+	extra sectionParams
+	// notifiers params of the named notifiers, by notifier and name
+	notifiers sectionParams
+	// named the named notifiers, built from their params
+	named namedNotifiers
 }
 
 // BuildFromDockerLabels builds a scheduler using the config from a docker labels
-func BuildFromDockerLabels() (*core.Scheduler, error) {
+func BuildFromDockerLabels(opts DockerLabelsOptions) (*core.Scheduler, error) {
+	config, err := readDockerLabelsConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.build()
+}
+
+func readDockerLabelsConfig(opts DockerLabelsOptions) (*Config, error) {
+	if err := opts.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
 	config := &Config{}
 
 	dockerClient, err := config.buildDockerClient()
@@ -44,7 +119,15 @@ func BuildFromDockerLabels() (*core.Scheduler, error) {
 		return nil, err
 	}
 
-	labels, err := getLabels(dockerClient)
+	if opts.Logger == nil {
+		if _, err := config.buildLogger(); err != nil {
+			return nil, err
+		}
+
+		opts.Logger = dockerLogger()
+	}
+
+	labels, err := getLabels(dockerClient, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -53,29 +136,75 @@ func BuildFromDockerLabels() (*core.Scheduler, error) {
 		return nil, err
 	}
 
-	return config.build()
+	config.applyLabelPolicy(opts.Policy, opts.Logger)
+	return config, nil
 }
 
 // BuildFromFile builds a scheduler using the config from a file
 func BuildFromFile(filename string) (*core.Scheduler, error) {
-	config := &Config{}
-	if err := gcfg.ReadFileInto(config, filename); err != nil {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
 		return nil, err
 	}
 
-	return config.build()
+	scheduler, err := BuildFromString(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return scheduler, nil
+}
+
+func readConfigFile(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := config.readString(string(content)); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return config, nil
 }
 
 // BuildFromString builds a scheduler using the config from a string
 func BuildFromString(configString string) (*core.Scheduler, error) {
 	config := &Config{}
-	if err := gcfg.ReadStringInto(config, configString); err != nil {
+	if err := config.readString(configString); err != nil {
 		return nil, err
 	}
 
 	return config.build()
 }
 
+func (config *Config) readString(configString string) error {
+	configString, err := joinMultilineValues(configString)
+	if err != nil {
+		return err
+	}
+
+	configString, crontabs, err := extractCrontabs(configString)
+	if err != nil {
+		return err
+	}
+
+	configString, custom, extra, notifiers, err := extractSections(configString)
+	if err != nil {
+		return err
+	}
+
+	config.custom = custom
+	config.extra = extra
+	config.notifiers = notifiers
+	if err := gcfg.ReadStringInto(config, configString); err != nil {
+		return err
+	}
+
+	return config.buildCrontabJobs(crontabs)
+}
+
 func (config *Config) build() (*core.Scheduler, error) {
 	defaults.SetDefaults(config)
 
@@ -84,44 +213,550 @@ func (config *Config) build() (*core.Scheduler, error) {
 		return nil, err
 	}
 
-	sched := core.NewScheduler(config.buildLogger())
-	config.buildSchedulerMiddlewares(sched)
+	logger, err := config.buildLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	sched := core.NewScheduler(logger)
+	if err := config.buildSchedulerMiddlewares(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerQueue(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerPullCache(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerPool(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerGroups(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerAudit(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerHistory(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerPublisher(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerSubscriber(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerOrphans(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerSecrets(sched); err != nil {
+		return nil, err
+	}
+
+	core.WatchFreshness(sched)
+	core.WatchDurations(sched)
+	middlewares.AlertEvents(sched)
+
+	sched.DockerMonitor = core.NewDockerMonitor(dockerClient, dockerLogger())
+	jobs, err := config.buildJobs(dockerClient, sched.DockerMonitor)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range jobs {
+		addJob(sched, j)
+		sched.Record(core.NewAuditEntry(auditActorConfig, core.AuditLoad, nil, j))
+	}
+
+	if err := core.ValidateDockerAPI(config.Global.DockerAPI); err != nil {
+		return nil, fmt.Errorf("global: %s", err)
+	}
+
+	sched.DockerAPI = config.Global.DockerAPI
+	if sched.Runtime, err = config.runtime(); err != nil {
+		return nil, fmt.Errorf("global: %s", err)
+	}
+
+	if err := checkTriggers(sched); err != nil {
+		return nil, err
+	}
+
+	if err := config.buildSchedulerRedactor(sched); err != nil {
+		return nil, err
+	}
+
+	return sched, nil
+}
+
+// runtime returns the capabilities of the docker daemon set by the rootless
+// option, nil to probe them
+func (config *Config) runtime() (*core.RuntimeCapabilities, error) {
+	switch config.Global.Rootless {
+	case "", "auto":
+		return nil, nil
+	case "true", "false":
+		return &core.RuntimeCapabilities{Rootless: config.Global.Rootless == "true", Forced: true}, nil
+	}
+
+	return nil, fmt.Errorf("invalid rootless %q, must be auto, true or false", config.Global.Rootless)
+}
+
+// setDefaultUser sets the user of a job, when not set, to the default-user
+func (config *Config) setDefaultUser(user *string) {
+	if *user == "" {
+		*user = config.Global.DefaultUser
+	}
+}
 
+// buildJobs builds all the jobs of the config, ready to be added to a scheduler,
+// the jobs using docker are checked with the given monitor if any
+func (config *Config) buildJobs(dockerClient *docker.Client, monitor *core.DockerMonitor) ([]core.Job, error) {
+	health := middlewares.NewDockerHealth(&config.Global.DockerHealthConfig, monitor)
+	if err := config.Global.WatchConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("global: %s", err)
+	}
+
+	var all []core.Job
+	jobs := make(map[string]core.Job)
 	for name, job := range config.ExecJobs {
+		config.setDefaultUser(&job.User)
 		defaults.SetDefaults(job)
 
 		job.Client = dockerClient
 		job.Name = name
-		job.buildMiddlewares()
-		sched.AddJob(job)
+		if err := config.buildMiddlewares(jobExec, job); err != nil {
+			return nil, err
+		}
+
+		job.Use(health)
+		all = append(all, job)
+		jobs[name] = job
 	}
 
 	for name, job := range config.RunJobs {
+		config.setDefaultUser(&job.User)
 		defaults.SetDefaults(job)
 
 		job.Client = dockerClient
 		job.Name = name
-		job.buildMiddlewares()
-		sched.AddJob(job)
+		job.WatchConfig.Inherit(config.Global.WatchConfig)
+		if err := job.WatchConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("job %q: %s", name, err)
+		}
+		if err := config.buildMiddlewares(jobRun, job); err != nil {
+			return nil, err
+		}
+
+		job.Use(health)
+		all = append(all, job)
+		jobs[name] = job
 	}
 
 	for name, job := range config.LocalJobs {
 		defaults.SetDefaults(job)
 
 		job.Name = name
-		job.buildMiddlewares()
-		sched.AddJob(job)
+		if err := config.buildMiddlewares(jobLocal, job); err != nil {
+			return nil, err
+		}
+
+		all = append(all, job)
+		jobs[name] = job
 	}
 
 	for name, job := range config.ServiceJobs {
+		config.setDefaultUser(&job.User)
 		defaults.SetDefaults(job)
 		job.Name = name
 		job.Client = dockerClient
-		job.buildMiddlewares()
-		sched.AddJob(job)
+		job.WatchConfig.Inherit(config.Global.WatchConfig)
+		if err := job.WatchConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("job %q: %s", name, err)
+		}
+		if err := config.buildMiddlewares(jobServiceRun, job); err != nil {
+			return nil, err
+		}
+
+		job.Use(health)
+		all = append(all, job)
+		jobs[name] = job
 	}
 
-	return sched, nil
+	for name, job := range config.BackupJobs {
+		config.setDefaultUser(&job.User)
+		defaults.SetDefaults(job)
+
+		job.Client = dockerClient
+		job.Name = name
+		if err := config.buildMiddlewares(jobBackup, job); err != nil {
+			return nil, err
+		}
+
+		job.Use(health)
+		all = append(all, job)
+		jobs[name] = job
+	}
+
+	for name, job := range config.ECSJobs {
+		defaults.SetDefaults(job)
+
+		job.Name = name
+		// the watch-interval isn't inherited, the ECS API being rate limited
+		if job.MaxRuntime == "" {
+			job.MaxRuntime = config.Global.MaxRuntime
+		}
+		if err := job.Validate(); err != nil {
+			return nil, fmt.Errorf("job %q: %s", name, err)
+		}
+		if err := config.buildMiddlewares(jobECS, job); err != nil {
+			return nil, err
+		}
+
+		all = append(all, job)
+		jobs[name] = job
+	}
+
+	for name, job := range config.NomadJobs {
+		defaults.SetDefaults(job)
+
+		job.Name = name
+		if job.MaxRuntime == "" {
+			job.MaxRuntime = config.Global.MaxRuntime
+		}
+		if err := job.Validate(); err != nil {
+			return nil, fmt.Errorf("job %q: %s", name, err)
+		}
+		if err := config.buildMiddlewares(jobNomad, job); err != nil {
+			return nil, err
+		}
+
+		all = append(all, job)
+		jobs[name] = job
+	}
+
+	named, err := config.buildNotifiers()
+	if err != nil {
+		return nil, err
+	}
+
+	custom, err := buildCustomJobs(config.custom, named)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range custom {
+		all = append(all, job)
+		jobs[job.GetName()] = job
+	}
+
+	for name, job := range config.PipelineJobs {
+		defaults.SetDefaults(job)
+		job.Name = name
+		job.Client = dockerClient
+		if err := job.buildSteps(jobs); err != nil {
+			return nil, err
+		}
+
+		if err := config.buildMiddlewares(jobPipeline, job); err != nil {
+			return nil, err
+		}
+
+		job.Use(health)
+		all = append(all, job)
+	}
+
+	if err := config.buildGroupMiddlewares(all); err != nil {
+		return nil, err
+	}
+
+	return targetedJobs(all, core.Hostname())
+}
+
+// targetedJobs returns the jobs targeting the given host, the other ones are
+// only logged, see core.JobTargets
+func targetedJobs(jobs []core.Job, host string) ([]core.Job, error) {
+	var targeted []core.Job
+	for _, j := range jobs {
+		ok, err := core.JobTargets(j, host)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if !ok {
+			logging.MustGetLogger(logScheduler).Debugf(
+				"Job %q not scheduled, targets the hosts %s, not %q",
+				j.GetName(), strings.Join(core.JobHosts(j), ","), host,
+			)
+
+			continue
+		}
+
+		targeted = append(targeted, j)
+	}
+
+	return targeted, nil
+}
+
+// buildGroupMiddlewares adds to the jobs of every group the notifiers of the
+// group, after their own ones, and the middleware sending the status of the
+// group
+func (config *Config) buildGroupMiddlewares(jobs []core.Job) error {
+	groups := make(map[string][]core.Middleware)
+	for name, g := range config.Groups {
+		if g.Notifiers != "" {
+			return fmt.Errorf("group %q: notifiers is only supported by the jobs", name)
+		}
+
+		ms, err := middlewares.BuildGlobal(middlewareDecoder(g, config.extra.get(groupSection, name)))
+		if err != nil {
+			return fmt.Errorf("group %q: %s", name, err)
+		}
+
+		groups[name] = append(ms, middlewares.NewGroupStatus(name, ms))
+	}
+
+	for _, j := range jobs {
+		name := core.JobGroup(j)
+		if name == "" {
+			continue
+		}
+
+		ms, ok := groups[name]
+		if !ok {
+			return fmt.Errorf("job %q: unknown group %q", j.GetName(), name)
+		}
+
+		j.Use(ms...)
+	}
+
+	return nil
+}
+
+// addJob adds the job to the scheduler, the jobs without schedule are only run
+// when triggered
+func addJob(sched *core.Scheduler, j core.Job) {
+	if j.GetSchedule() == "" {
+		sched.AddTriggeredJob(j)
+		return
+	}
+
+	sched.AddJob(j)
+}
+
+func checkTriggers(sched *core.Scheduler) error {
+	for _, j := range sched.GetJobs() {
+		if err := checkExternalTrigger(sched, j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if err := checkStdinFrom(sched, j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if err := checkInheritGlobals(j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if _, err := core.JobScheduleSpread(j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if _, err := core.JobMaxSkew(j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if _, err := core.JobMisfire(j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if _, err := core.JobTags(j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if err := core.ValidateSkipIfUnchanged(j); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if err := core.CheckDockerAPI(j, sched.DockerAPI); err != nil {
+			return fmt.Errorf("job %q: %s", j.GetName(), err)
+		}
+
+		if core.JobRemoveAfterRun(j) && core.JobAt(j).IsZero() {
+			return fmt.Errorf("job %q: remove-after-run requires an %q schedule", j.GetName(), core.AtDescriptor)
+		}
+
+		if c, ok := j.(interface{ ValidateCommand() error }); ok {
+			if err := c.ValidateCommand(); err != nil {
+				return fmt.Errorf("job %q: %s", j.GetName(), err)
+			}
+		}
+
+		if j, ok := j.(*RunJobConfig); ok {
+			if err := j.ValidateSidecars(); err != nil {
+				return fmt.Errorf("job %q: %s", j.GetName(), err)
+			}
+
+			if err := j.ValidateWorkspace(); err != nil {
+				return fmt.Errorf("job %q: %s", j.GetName(), err)
+			}
+		}
+
+		if j, ok := j.(*LocalJobConfig); ok {
+			if err := j.ValidateFlock(); err != nil {
+				return fmt.Errorf("job %q: %s", j.GetName(), err)
+			}
+
+			if err := j.ValidateCgroup(); err != nil {
+				return fmt.Errorf("job %q: %s", j.GetName(), err)
+			}
+
+			if err := j.ValidateIsolation(); err != nil {
+				return fmt.Errorf("job %q: %s", j.GetName(), err)
+			}
+		}
+
+		for _, m := range j.Middlewares() {
+			if t, ok := m.(*middlewares.Trigger); ok {
+				if err := t.Check(sched); err != nil {
+					return fmt.Errorf("job %q: %s", j.GetName(), err)
+				}
+			}
+		}
+	}
+
+	if cycle := triggerCycle(sched.GetJobs()); cycle != nil {
+		return fmt.Errorf("job %q: on-success/on-failure cycle %s", cycle[0], strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// triggerCycle returns the names of the jobs of a cycle of on-success and
+// on-failure triggers, the first job ending it too, e.g. [foo bar foo], nil if
+// none. The executions of a cycle would trigger each other forever.
+func triggerCycle(jobs []core.Job) []string {
+	var names []string
+	next := make(map[string][]string)
+	for _, j := range jobs {
+		names = append(names, j.GetName())
+		for _, m := range j.Middlewares() {
+			t, ok := m.(*middlewares.Trigger)
+			if !ok {
+				continue
+			}
+
+			for _, name := range []string{t.OnSuccess, t.OnFailure} {
+				if name != "" {
+					next[j.GetName()] = append(next[j.GetName()], name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	// the jobs being visited are in the path, the visited ones are done
+	var path []string
+	done := make(map[string]bool)
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		for i, n := range path {
+			if n == name {
+				return append(append([]string{}, path[i:]...), name)
+			}
+		}
+
+		if done[name] {
+			return nil
+		}
+
+		path = append(path, name)
+		for _, n := range next[name] {
+			if cycle := visit(n); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		done[name] = true
+		return nil
+	}
+
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// checkExternalTrigger checks the trigger option of the job, the message
+// triggers require the nats-url
+func checkExternalTrigger(sched *core.Scheduler, j core.Job) error {
+	value := core.JobTrigger(j)
+	if value == "" {
+		return nil
+	}
+
+	kind, _, err := core.ParseTrigger(value)
+	if err != nil {
+		return err
+	}
+
+	if kind == core.TriggerNATS && sched.Subscriber == nil {
+		return fmt.Errorf("trigger %q requires the nats-url in the [global] section", value)
+	}
+
+	return nil
+}
+
+// checkStdinFrom checks the stdin-from job exists, and the job is able to read
+// its output
+func checkStdinFrom(sched *core.Scheduler, j core.Job) error {
+	from := core.JobStdinFrom(j)
+	if from == "" {
+		return nil
+	}
+
+	switch j := j.(type) {
+	case *RunServiceConfig, *PipelineJobConfig:
+		return fmt.Errorf("stdin-from is unsupported by %s jobs", jobType(j))
+	case *RunJobConfig:
+		if j.Container != "" {
+			return core.ErrStdinExistingContainer
+		}
+	}
+
+	if from == j.GetName() || sched.GetJob(from) == nil {
+		return fmt.Errorf("unknown job %q at stdin-from", from)
+	}
+
+	return nil
+}
+
+// checkInheritGlobals checks the inherit-globals of the job is a boolean
+func checkInheritGlobals(j core.Job) error {
+	g, ok := j.(interface{ GetInheritGlobals() string })
+	if !ok || g.GetInheritGlobals() == "" {
+		return nil
+	}
+
+	if _, err := strconv.ParseBool(g.GetInheritGlobals()); err != nil {
+		return fmt.Errorf("invalid inherit-globals %q, must be true or false", g.GetInheritGlobals())
+	}
+
+	return nil
 }
 
 func (*Config) buildDockerClient() (*docker.Client, error) {
@@ -130,23 +765,306 @@ func (*Config) buildDockerClient() (*docker.Client, error) {
 		return nil, err
 	}
 
+	core.InstrumentDockerClient(dockerClient)
 	return dockerClient, nil
 }
 
-func (config *Config) buildLogger() core.Logger {
-	stdout := logging.NewLogBackend(os.Stdout, "", 0)
-	// Set the backends to be used.
-	logging.SetBackend(stdout)
-	logging.SetFormatter(logging.MustStringFormatter(logFormat))
+func (config *Config) buildLogger() (core.Logger, error) {
+	return setupLogging(&config.Global.LogConfig)
+}
+
+func (config *Config) buildSchedulerMiddlewares(sched *core.Scheduler) error {
+	ms, err := middlewares.BuildGlobal(middlewareDecoder(&config.Global, config.extra.get(globalSection, "")))
+	if err != nil {
+		return fmt.Errorf("global: %s", err)
+	}
+
+	overrides, err := strconv.ParseBool(config.Global.JobOverridesGlobal)
+	if err != nil {
+		return fmt.Errorf("global: invalid job-overrides-global %q, must be true or false", config.Global.JobOverridesGlobal)
+	}
+
+	if config.Global.Notifiers != "" {
+		return fmt.Errorf("global: notifiers is only supported by the jobs")
+	}
 
-	return logging.MustGetLogger("ofelia")
+	sched.JobOverridesGlobal = overrides
+	sched.Use(ms...)
+	return nil
 }
 
-func (config *Config) buildSchedulerMiddlewares(sched *core.Scheduler) {
-	global := &config.Global
-	sched.Use(middlewares.NewSlack(&global.SlackConfig))
-	sched.Use(middlewares.NewSave(&global.SaveConfig))
-	sched.Use(middlewares.NewMail(&global.MailConfig))
+// buildMiddlewares adds to the job the middlewares of the given section config
+func (config *Config) buildMiddlewares(section string, j core.Job) error {
+	named, err := config.buildNotifiers()
+	if err != nil {
+		return err
+	}
+
+	return buildMiddlewares(section, j, config.extra.get(section, j.GetName()), named)
+}
+
+func (config *Config) buildSchedulerQueue(sched *core.Scheduler) error {
+	if config.Global.QueueFile == "" {
+		return nil
+	}
+
+	q, err := core.NewBoltQueue(config.Global.QueueFile)
+	if err != nil {
+		return err
+	}
+
+	sched.Queue = q
+	return nil
+}
+
+func (config *Config) buildSchedulerPullCache(sched *core.Scheduler) error {
+	if config.Global.PullCache == "" {
+		return nil
+	}
+
+	window, err := time.ParseDuration(config.Global.PullCache)
+	if err != nil {
+		return fmt.Errorf("invalid pull-cache %q: %s", config.Global.PullCache, err)
+	}
+
+	sched.PullCache = core.NewPullCache(window)
+	return nil
+}
+
+// buildSchedulerPool builds the execution pool of the pool params, as
+// "<job type>:<limit>", e.g. "job-run:4"
+func (config *Config) buildSchedulerPool(sched *core.Scheduler) error {
+	if len(config.Global.Pool) == 0 {
+		return nil
+	}
+
+	limits := make(map[string]int)
+	for _, p := range config.Global.Pool {
+		parts := strings.SplitN(p, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid pool %q, must be <job type>:<limit>", p)
+		}
+
+		t := strings.TrimSpace(parts[0])
+		switch t {
+		case jobExec, jobRun, jobServiceRun, jobLocal, jobPipeline, jobBackup, jobECS, jobNomad:
+		default:
+			return fmt.Errorf("invalid pool %q, unknown job type %q", p, t)
+		}
+
+		limit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || limit < 1 {
+			return fmt.Errorf("invalid pool %q, the limit must be a positive number", p)
+		}
+
+		limits[t] = limit
+	}
+
+	sched.Pool = core.NewExecutionPool(jobType, limits)
+	return nil
+}
+
+// buildSchedulerGroups registers the groups in the scheduler, before their jobs
+func (config *Config) buildSchedulerGroups(sched *core.Scheduler) error {
+	for name, g := range config.Groups {
+		var offset time.Duration
+		if g.ScheduleOffset != "" {
+			var err error
+			offset, err = time.ParseDuration(g.ScheduleOffset)
+			if err != nil || offset < 0 {
+				return fmt.Errorf("group %q: invalid schedule-offset %q, must be a positive duration", name, g.ScheduleOffset)
+			}
+		}
+
+		var spread time.Duration
+		if g.ScheduleSpread != "" {
+			var err error
+			spread, err = time.ParseDuration(g.ScheduleSpread)
+			if err != nil || spread < 0 {
+				return fmt.Errorf("group %q: invalid schedule-spread %q, must be a positive duration", name, g.ScheduleSpread)
+			}
+		}
+
+		if g.MaxConcurrent < 0 {
+			return fmt.Errorf("group %q: invalid max-concurrent %d, must be a positive number", name, g.MaxConcurrent)
+		}
+
+		group := core.NewGroup(name, offset, g.MaxConcurrent)
+		group.ScheduleSpread = spread
+		sched.AddGroup(group)
+	}
+
+	return nil
+}
+
+// buildSchedulerOrphans watches the jobs whose container is gone, removing
+// them after the orphan-remove-after if set
+func (config *Config) buildSchedulerOrphans(sched *core.Scheduler) error {
+	var grace time.Duration
+	if config.Global.OrphanRemoveAfter != "" {
+		var err error
+		grace, err = time.ParseDuration(config.Global.OrphanRemoveAfter)
+		if err != nil || grace <= 0 {
+			return fmt.Errorf("invalid orphan-remove-after %q, must be a positive duration", config.Global.OrphanRemoveAfter)
+		}
+	}
+
+	core.WatchOrphans(sched, grace)
+	return nil
+}
+
+func (config *Config) buildSchedulerSecrets(sched *core.Scheduler) error {
+	c := &config.Global.SecretsConfig
+	addr, token := c.VaultAddr, c.VaultToken
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	if addr == "" && c.AWSSecretsRegion == "" {
+		return nil
+	}
+
+	ttl := defaultSecretsTTL
+	if c.SecretsTTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(c.SecretsTTL)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid secrets-ttl %q, must be a duration", c.SecretsTTL)
+		}
+	}
+
+	secrets := core.NewSecrets(ttl)
+	if addr != "" {
+		secrets.Register(secretsVault, core.NewVaultProvider(addr, token))
+	}
+
+	if c.AWSSecretsRegion != "" {
+		secrets.Register(secretsAWS, core.NewAWSSecretsProvider(c.AWSSecretsRegion))
+	}
+
+	sched.Secrets = secrets
+	return nil
+}
+
+func (config *Config) buildSchedulerAudit(sched *core.Scheduler) error {
+	if config.Global.AuditLog == "" {
+		return nil
+	}
+
+	l, err := core.NewFileAuditLog(config.Global.AuditLog)
+	if err != nil {
+		return err
+	}
+
+	sched.Audit = l
+	return nil
+}
+
+func (config *Config) buildSchedulerHistory(sched *core.Scheduler) error {
+	if config.Global.HistoryDSN == "" {
+		return nil
+	}
+
+	h, err := core.NewSQLHistoryStore(config.Global.HistoryDriver, config.Global.HistoryDSN)
+	if err != nil {
+		return err
+	}
+
+	sched.History = h
+	return nil
+}
+
+func (config *Config) buildSchedulerPublisher(sched *core.Scheduler) error {
+	if config.Global.PublishURL == "" {
+		return nil
+	}
+
+	p, err := broker.New(config.Global.PublishURL)
+	if err != nil {
+		return err
+	}
+
+	sched.Publisher = p
+	return nil
+}
+
+func (config *Config) buildSchedulerSubscriber(sched *core.Scheduler) error {
+	if config.Global.NATSURL == "" {
+		return nil
+	}
+
+	n, err := broker.NewNATS(config.Global.NATSURL)
+	if err != nil {
+		return err
+	}
+
+	sched.Subscriber = n
+	return nil
+}
+
+func (config *Config) buildSchedulerRedactor(sched *core.Scheduler) error {
+	r := core.NewRedactor()
+	r.AddSecretsOf(&config.Global)
+	for _, expr := range config.Global.Redact {
+		if err := r.AddPattern(expr); err != nil {
+			return fmt.Errorf("invalid redact pattern %q: %s", expr, err)
+		}
+	}
+
+	sched.Redactor = r
+	addSecrets(sched)
+	return nil
+}
+
+// addSecrets adds to the redactor of the scheduler the secret options of the
+// jobs and middlewares
+func addSecrets(sched *core.Scheduler) {
+	if sched.Redactor == nil {
+		return
+	}
+
+	for _, m := range sched.Middlewares() {
+		sched.Redactor.AddSecretsOf(m)
+	}
+
+	for _, j := range sched.GetJobs() {
+		sched.Redactor.AddSecretsOf(j)
+		for _, m := range j.Middlewares() {
+			sched.Redactor.AddSecretsOf(m)
+		}
+	}
+}
+
+// SecretsConfig the backends of the secrets referenced by the environment
+// variables of the jobs
+type SecretsConfig struct {
+	// SecretsTTL time the secrets are cached in memory, 5m by default
+	SecretsTTL string `gcfg:"secrets-ttl" mapstructure:"secrets-ttl"`
+	// VaultAddr address of the HashiCorp Vault server, VAULT_ADDR by default
+	VaultAddr  string `gcfg:"vault-addr" mapstructure:"vault-addr"`
+	VaultToken string `gcfg:"vault-token" mapstructure:"vault-token" secret:"true"`
+	// AWSSecretsRegion region of AWS Secrets Manager, enabling it
+	AWSSecretsRegion string `gcfg:"aws-secrets-region" mapstructure:"aws-secrets-region"`
+}
+
+// GroupConfig contains the settings shared by the jobs of a group, the ones
+// declaring it with the group param
+type GroupConfig struct {
+	// ScheduleOffset delays the scheduled executions of the jobs, e.g. "30m"
+	ScheduleOffset string `gcfg:"schedule-offset" mapstructure:"schedule-offset"`
+	// ScheduleSpread spreads the scheduled executions of the jobs across the
+	// hosts within this window, e.g. "30m"
+	ScheduleSpread string `gcfg:"schedule-spread" mapstructure:"schedule-spread"`
+	// MaxConcurrent maximum number of executions of the jobs running at once
+	MaxConcurrent            int `gcfg:"max-concurrent" mapstructure:"max-concurrent"`
+	middlewares.SlackConfig  `mapstructure:",squash"`
+	middlewares.SaveConfig   `mapstructure:",squash"`
+	middlewares.MailConfig   `mapstructure:",squash"`
+	middlewares.NotifyConfig `mapstructure:",squash"`
 }
 
 // ExecJobConfig contains all configuration params needed to build a ExecJob
@@ -156,14 +1074,8 @@ type ExecJobConfig struct {
 	middlewares.SlackConfig   `mapstructure:",squash"`
 	middlewares.SaveConfig    `mapstructure:",squash"`
 	middlewares.MailConfig    `mapstructure:",squash"`
-}
-
-func (config *ExecJobConfig) buildMiddlewares() {
-	job := &config.ExecJob
-	job.Use(middlewares.NewOverlap(&config.OverlapConfig))
-	job.Use(middlewares.NewSlack(&config.SlackConfig))
-	job.Use(middlewares.NewSave(&config.SaveConfig))
-	job.Use(middlewares.NewMail(&config.MailConfig))
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
 }
 
 // RunServiceConfig contains all configuration params needed to build a RunJob
@@ -173,6 +1085,8 @@ type RunServiceConfig struct {
 	middlewares.SlackConfig   `mapstructure:",squash"`
 	middlewares.SaveConfig    `mapstructure:",squash"`
 	middlewares.MailConfig    `mapstructure:",squash"`
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
 }
 
 type RunJobConfig struct {
@@ -181,14 +1095,8 @@ type RunJobConfig struct {
 	middlewares.SlackConfig   `mapstructure:",squash"`
 	middlewares.SaveConfig    `mapstructure:",squash"`
 	middlewares.MailConfig    `mapstructure:",squash"`
-}
-
-func (config *RunJobConfig) buildMiddlewares() {
-	job := &config.RunJob
-	job.Use(middlewares.NewOverlap(&config.OverlapConfig))
-	job.Use(middlewares.NewSlack(&config.SlackConfig))
-	job.Use(middlewares.NewSave(&config.SaveConfig))
-	job.Use(middlewares.NewMail(&config.MailConfig))
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
 }
 
 // LocalJobConfig contains all configuration params needed to build a RunJob
@@ -198,20 +1106,65 @@ type LocalJobConfig struct {
 	middlewares.SlackConfig   `mapstructure:",squash"`
 	middlewares.SaveConfig    `mapstructure:",squash"`
 	middlewares.MailConfig    `mapstructure:",squash"`
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
+}
+
+// PipelineJobConfig contains all configuration params needed to build a PipelineJob
+type PipelineJobConfig struct {
+	core.PipelineJob          `mapstructure:",squash"`
+	middlewares.OverlapConfig `mapstructure:",squash"`
+	middlewares.SlackConfig   `mapstructure:",squash"`
+	middlewares.SaveConfig    `mapstructure:",squash"`
+	middlewares.MailConfig    `mapstructure:",squash"`
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
 }
 
-func (config *LocalJobConfig) buildMiddlewares() {
-	job := &config.LocalJob
-	job.Use(middlewares.NewOverlap(&config.OverlapConfig))
-	job.Use(middlewares.NewSlack(&config.SlackConfig))
-	job.Use(middlewares.NewSave(&config.SaveConfig))
-	job.Use(middlewares.NewMail(&config.MailConfig))
+// BackupJobConfig contains all configuration params needed to build a BackupJob
+type BackupJobConfig struct {
+	core.BackupJob            `mapstructure:",squash"`
+	middlewares.OverlapConfig `mapstructure:",squash"`
+	middlewares.SlackConfig   `mapstructure:",squash"`
+	middlewares.SaveConfig    `mapstructure:",squash"`
+	middlewares.MailConfig    `mapstructure:",squash"`
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
 }
 
-func (config *RunServiceConfig) buildMiddlewares() {
-	job := &config.RunServiceJob
-	job.Use(middlewares.NewOverlap(&config.OverlapConfig))
-	job.Use(middlewares.NewSlack(&config.SlackConfig))
-	job.Use(middlewares.NewSave(&config.SaveConfig))
-	job.Use(middlewares.NewMail(&config.MailConfig))
+// ECSJobConfig contains all configuration params needed to build a ECSJob
+type ECSJobConfig struct {
+	core.ECSJob               `mapstructure:",squash"`
+	middlewares.OverlapConfig `mapstructure:",squash"`
+	middlewares.SlackConfig   `mapstructure:",squash"`
+	middlewares.SaveConfig    `mapstructure:",squash"`
+	middlewares.MailConfig    `mapstructure:",squash"`
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
+}
+
+// NomadJobConfig contains all configuration params needed to build a NomadJob
+type NomadJobConfig struct {
+	core.NomadJob             `mapstructure:",squash"`
+	middlewares.OverlapConfig `mapstructure:",squash"`
+	middlewares.SlackConfig   `mapstructure:",squash"`
+	middlewares.SaveConfig    `mapstructure:",squash"`
+	middlewares.MailConfig    `mapstructure:",squash"`
+	middlewares.TriggerConfig `mapstructure:",squash"`
+	middlewares.NotifyConfig  `mapstructure:",squash"`
+}
+
+func (config *PipelineJobConfig) buildSteps(jobs map[string]core.Job) error {
+	var steps []core.Job
+	for _, name := range config.Steps {
+		j, ok := jobs[name]
+		if !ok {
+			return fmt.Errorf("unknown step %q at pipeline %q", name, config.Name)
+		}
+
+		steps = append(steps, j)
+	}
+
+	config.SetStepJobs(steps...)
+	return nil
 }
@@ -1,8 +1,19 @@
 package cli
 
 import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	docker "github.com/fsouza/go-dockerclient"
+	dockertest "github.com/fsouza/go-dockerclient/testing"
 	defaults "github.com/mcuadros/go-defaults"
 	"github.com/mcuadros/ofelia/core"
 	"github.com/mcuadros/ofelia/middlewares"
@@ -37,6 +48,840 @@ func (s *SuiteConfig) TestBuildFromString(c *C) {
 	c.Assert(sh.Jobs, HasLen, 5)
 }
 
+func (s *SuiteConfig) TestBuildFromStringPullCache(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		pull-cache = 10m
+
+		[job-run "foo"]
+		schedule = @every 10s
+		image = busybox
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.PullCache, NotNil)
+	c.Assert(sh.PullCache.Window, Equals, 10*time.Minute)
+
+	_, err = BuildFromString(`
+		[global]
+		pull-cache = foo
+	`)
+
+	c.Assert(err, ErrorMatches, `invalid pull-cache "foo".*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringWatchConfig(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		watch-interval = 1s
+		max-runtime = 72h
+
+		[job-run "foo"]
+		schedule = @every 10s
+		image = busybox
+		max-runtime = 96h
+
+		[job-service-run "bar"]
+		schedule = @every 10s
+		image = busybox
+	`)
+
+	c.Assert(err, IsNil)
+	foo := sh.GetJob("foo").(*RunJobConfig)
+	c.Assert(foo.WatchInterval, Equals, "1s")
+	c.Assert(foo.MaxRuntime, Equals, "96h")
+
+	bar := sh.GetJob("bar").(*RunServiceConfig)
+	c.Assert(bar.WatchConfig, DeepEquals, core.WatchConfig{WatchInterval: "1s", MaxRuntime: "72h"})
+
+	_, err = BuildFromString(`
+		[global]
+		watch-interval = foo
+	`)
+
+	c.Assert(err, ErrorMatches, `global: invalid watch-interval "foo".*`)
+
+	_, err = BuildFromString(`
+		[job-run "foo"]
+		schedule = @every 10s
+		image = busybox
+		max-runtime = 1d
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid max-runtime "1d".*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringDockerAPI(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		docker-api = containers
+		docker-api = exec
+		docker-api = post
+
+		[job-exec "foo"]
+		schedule = @every 10s
+		container = web
+		command = echo foo
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.DockerAPI, DeepEquals, []string{"containers", "exec", "post"})
+
+	_, err = BuildFromString(`
+		[global]
+		docker-api = containers
+		docker-api = post
+
+		[job-run "foo"]
+		schedule = @every 10s
+		image = busybox
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": requires the docker API sections images, not allowed by docker-api`)
+
+	_, err = BuildFromString(`
+		[global]
+		docker-api = system
+	`)
+
+	c.Assert(err, ErrorMatches, `global: unknown docker API section "system".*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringRootless(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		rootless = true
+		default-user = 1000:1000
+
+		[job-exec "foo"]
+		schedule = @every 10s
+		container = web
+		command = echo foo
+
+		[job-run "bar"]
+		schedule = @every 10s
+		image = busybox
+		user = nobody
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Runtime, DeepEquals, &core.RuntimeCapabilities{Rootless: true, Forced: true})
+	c.Assert(sh.GetJob("foo").(*ExecJobConfig).User, Equals, "1000:1000")
+	c.Assert(sh.GetJob("bar").(*RunJobConfig).User, Equals, "nobody")
+
+	sh, err = BuildFromString(`
+		[job-exec "foo"]
+		schedule = @every 10s
+		container = web
+		command = echo foo
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Runtime, IsNil)
+	c.Assert(sh.GetJob("foo").(*ExecJobConfig).User, Equals, "root")
+
+	_, err = BuildFromString(`
+		[global]
+		rootless = maybe
+	`)
+
+	c.Assert(err, ErrorMatches, `global: invalid rootless "maybe", must be auto, true or false`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringPool(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		pool = job-run:4
+		pool = job-exec: 10
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Pool, NotNil)
+	c.Assert(sh.Pool.Limit(jobRun), Equals, 4)
+	c.Assert(sh.Pool.Limit(jobExec), Equals, 10)
+	c.Assert(sh.Pool.Limit(jobLocal), Equals, 0)
+
+	for pool, expected := range map[string]string{
+		"job-run":      `invalid pool "job-run", must be .*`,
+		"job-cron:4":   `invalid pool "job-cron:4", unknown job type "job-cron"`,
+		"job-run:none": `invalid pool "job-run:none", the limit must be a positive number`,
+		"job-run:0":    `invalid pool "job-run:0", the limit must be a positive number`,
+	} {
+		_, err = BuildFromString("[global]\npool = " + pool)
+		c.Assert(err, ErrorMatches, expected)
+	}
+}
+
+func (s *SuiteConfig) TestBuildFromStringOrphans(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		orphan-remove-after = 1h
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Orphans, NotNil)
+
+	_, err = BuildFromString(`
+		[global]
+		orphan-remove-after = never
+	`)
+
+	c.Assert(err, ErrorMatches, `invalid orphan-remove-after "never", must be a positive duration`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringCommand(c *C) {
+	sh, err := BuildFromString(`
+		[job-run "foo"]
+		schedule = @hourly
+		shell = true
+		command = """
+			echo foo | wc -c
+			echo "bar"
+			"""
+
+		[job-run "bar"]
+		schedule = @hourly
+		command-array = psql
+		command-array = -c
+		command-array = "SELECT 'foo' AS \"name\""
+	`)
+
+	c.Assert(err, IsNil)
+	foo := sh.GetJob("foo").(*RunJobConfig)
+	c.Assert(foo.Shell, Equals, true)
+	c.Assert(foo.Command, Equals, "echo foo | wc -c\necho \"bar\"")
+	bar := sh.GetJob("bar").(*RunJobConfig)
+	c.Assert(bar.CommandArray, DeepEquals, []string{"psql", "-c", `SELECT 'foo' AS "name"`})
+
+	_, err = BuildFromString(`
+		[job-exec "foo"]
+		schedule = @hourly
+		command = echo foo
+		command-array = echo
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": command and command-array are exclusive`)
+
+	_, err = BuildFromString(`
+		[job-exec "foo"]
+		schedule = @hourly
+		command = """
+			echo foo
+	`)
+
+	c.Assert(err, ErrorMatches, `line 4: multi-line value of "command" not terminated`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringScript(c *C) {
+	sh, err := BuildFromString(`
+		[job-run "foo"]
+		schedule = @hourly
+		image = python:3
+		interpreter = python3
+		script = <<EOF
+			import sys
+			print("foo", file=sys.stderr)
+			EOF
+	`)
+
+	c.Assert(err, IsNil)
+	foo := sh.GetJob("foo").(*RunJobConfig)
+	c.Assert(foo.Interpreter, Equals, "python3")
+	c.Assert(core.JobScript(foo), Equals, "import sys\nprint(\"foo\", file=sys.stderr)")
+
+	_, err = BuildFromString(`
+		[job-exec "foo"]
+		schedule = @hourly
+		command = echo foo
+		script = echo foo
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": script is exclusive with command and command-array`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringEnvFile(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+		env-file = /secrets/foo.env
+
+		[job-exec "bar"]
+		schedule = @hourly
+		command = echo bar
+		env-file = /secrets/bar.env
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(core.JobEnvFile(sh.GetJob("foo")), Equals, "/secrets/foo.env")
+	c.Assert(core.JobEnvFile(sh.GetJob("bar")), Equals, "/secrets/bar.env")
+}
+
+func (s *SuiteConfig) TestBuildFromStringECS(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		max-runtime = 2h
+
+		[job-ecs "migrate"]
+		schedule = @daily
+		region = eu-west-1
+		cluster = prod
+		task-definition = migrate:12
+		launch-type = FARGATE
+		subnet = subnet-1
+		subnet = subnet-2
+		assign-public-ip = true
+	`)
+
+	c.Assert(err, IsNil)
+	j, ok := sh.GetJob("migrate").(*ECSJobConfig)
+	c.Assert(ok, Equals, true)
+	c.Assert(j.TaskDefinition, Equals, "migrate:12")
+	c.Assert(j.Subnet, DeepEquals, []string{"subnet-1", "subnet-2"})
+	c.Assert(j.AssignPublicIP, Equals, true)
+	c.Assert(j.MaxRuntime, Equals, "2h")
+	c.Assert(j.WatchInterval, Equals, "")
+
+	_, err = BuildFromString(`
+		[job-ecs "migrate"]
+		schedule = @daily
+		region = eu-west-1
+		cluster = prod
+	`)
+
+	c.Assert(err, ErrorMatches, `job "migrate": region, cluster and task-definition are required`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringNomad(c *C) {
+	sh, err := BuildFromString(`
+		[job-nomad "report"]
+		schedule = @daily
+		nomad-job = report
+		namespace = batch
+		meta = period=daily
+		meta = format=csv
+	`)
+
+	c.Assert(err, IsNil)
+	j, ok := sh.GetJob("report").(*NomadJobConfig)
+	c.Assert(ok, Equals, true)
+	c.Assert(j.JobID, Equals, "report")
+	c.Assert(j.Meta, DeepEquals, []string{"period=daily", "format=csv"})
+
+	_, err = BuildFromString(`
+		[job-nomad "report"]
+		schedule = @daily
+	`)
+
+	c.Assert(err, ErrorMatches, `job "report": nomad-job is required`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringAnnotations(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+		description = Says foo
+		owner = team-a
+		tags = tier=critical
+		tags = env=production
+	`)
+
+	c.Assert(err, IsNil)
+	j := sh.GetJob("foo")
+	c.Assert(core.JobDescription(j), Equals, "Says foo")
+	c.Assert(core.JobOwner(j), Equals, "team-a")
+
+	tags, err := core.JobTags(j)
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, map[string]string{"tier": "critical", "env": "production"})
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+		tags = critical
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid tag "critical", must be <name>=<value>`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringSecrets(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Secrets, IsNil)
+
+	sh, err = BuildFromString(`
+		[global]
+		vault-addr = https://vault:8200
+		vault-token = s3cr3t-token
+		aws-secrets-region = eu-west-1
+		secrets-ttl = 1m
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Secrets, NotNil)
+	c.Assert(sh.Secrets.TTL, Equals, time.Minute)
+	c.Assert(sh.Secrets.IsReference("vault:kv/data/backup#password"), Equals, true)
+	c.Assert(sh.Secrets.IsReference("aws-sm:prod/backup#password"), Equals, true)
+	c.Assert(sh.Redactor.Redact("s3cr3t-token"), Equals, core.RedactedMask)
+
+	_, err = BuildFromString(`
+		[global]
+		vault-addr = https://vault:8200
+		secrets-ttl = forever
+	`)
+
+	c.Assert(err, ErrorMatches, `invalid secrets-ttl "forever", must be a duration`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringGroup(c *C) {
+	sh, err := BuildFromString(`
+		[group "backups"]
+		schedule-offset = 30m
+		schedule-spread = 1h
+		max-concurrent = 2
+		slack-webhook = http://localhost/backups
+
+		[job-local "foo"]
+		schedule = @daily
+		command = echo foo
+		group = backups
+
+		[job-local "bar"]
+		schedule = @daily
+		command = echo bar
+		group = backups
+		slack-webhook = http://localhost/bar
+
+		[job-local "baz"]
+		schedule = @daily
+		command = echo baz
+	`)
+
+	c.Assert(err, IsNil)
+	g := sh.GetGroup("backups")
+	c.Assert(g, NotNil)
+	c.Assert(g.ScheduleOffset, Equals, 30*time.Minute)
+	c.Assert(g.ScheduleSpread, Equals, time.Hour)
+	c.Assert(g.MaxConcurrent, Equals, 2)
+
+	webhooks := func(name string) []string {
+		var urls []string
+		var status bool
+		for _, m := range sh.GetJob(name).Middlewares() {
+			switch m := m.(type) {
+			case *middlewares.Slack:
+				urls = append(urls, m.SlackWebhook)
+			case *middlewares.GroupStatus:
+				status = true
+			}
+		}
+
+		if status {
+			urls = append(urls, "status")
+		}
+
+		return urls
+	}
+
+	c.Assert(webhooks("foo"), DeepEquals, []string{"http://localhost/backups", "status"})
+	c.Assert(webhooks("bar"), DeepEquals, []string{"http://localhost/bar", "status"})
+	c.Assert(webhooks("baz"), IsNil)
+
+	for config, expected := range map[string]string{
+		"[job-local \"foo\"]\nschedule = @daily\ngroup = qux": `job "foo": unknown group "qux"`,
+		"[group \"qux\"]\nschedule-offset = soon":             `group "qux": invalid schedule-offset "soon", must be a positive duration`,
+		"[group \"qux\"]\nschedule-spread = -5m":              `group "qux": invalid schedule-spread "-5m", must be a positive duration`,
+		"[group \"qux\"]\nmax-concurrent = -1":                `group "qux": invalid max-concurrent -1, must be a positive number`,
+	} {
+		_, err = BuildFromString(config)
+		c.Assert(err, ErrorMatches, expected)
+	}
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @daily
+		command = echo foo
+		schedule-spread = later
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid schedule-spread "later": .*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringAuditLog(c *C) {
+	dir, err := ioutil.TempDir("", "audit")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "audit.log")
+	sh, err := BuildFromString(`
+		[global]
+		audit-log = ` + filename + `
+
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Audit, NotNil)
+
+	content, err := ioutil.ReadFile(filename)
+	c.Assert(err, IsNil)
+	c.Assert(string(content), Matches, `\{"Date":".*","Actor":"config","Action":"load","Job":"foo","Diff":\{.*"Command":\{"After":"echo foo"\}.*\}\}\n`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringRedact(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		redact = "token=(\\w+)"
+		smtp-password = hunter2
+		email-to = foo@example.com
+
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		slack-webhook = https://hooks.slack.com/services/foo
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(
+		sh.Redactor.Redact("hunter2 token=abc https://hooks.slack.com/services/foo"),
+		Equals,
+		"***** token=***** *****",
+	)
+
+	_, err = BuildFromString(`
+		[global]
+		redact = (
+	`)
+
+	c.Assert(err, ErrorMatches, `invalid redact pattern "\(".*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringHistory(c *C) {
+	_, err := BuildFromString(`
+		[global]
+		history-driver = sqlite
+		history-dsn = /tmp/ofelia.db
+	`)
+
+	c.Assert(err, ErrorMatches, `unsupported history driver "sqlite".*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringMaxSkew(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		max-skew = 5m
+	`)
+
+	c.Assert(err, IsNil)
+	max, err := core.JobMaxSkew(sh.GetJob("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(max, Equals, 5*time.Minute)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		max-skew = foo
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid max-skew "foo".*`)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		max-skew = 5m
+		misfire = later
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid misfire "later", .*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringTrigger(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		command = echo $OFELIA_PAYLOAD
+		trigger = webhook
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(core.JobTrigger(sh.GetJob("foo")), Equals, core.TriggerWebhook)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		command = echo $OFELIA_PAYLOAD
+		trigger = nats:builds
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": trigger "nats:builds" requires the nats-url .*`)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		command = echo foo
+		trigger = cron
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid trigger "cron".*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringInheritGlobals(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		slack-webhook = http://example.com/global
+
+		[job-local "foo"]
+		schedule = @daily
+		command = echo foo
+		inherit-globals = false
+		mail-only-on-error = true
+		email-to = foo@example.com
+		email-from = ofelia@example.com
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Start(), IsNil)
+	defer sh.Stop()
+
+	ms := sh.GetJob("foo").Middlewares()
+	c.Assert(ms, HasLen, 1)
+	c.Assert(ms[0], FitsTypeOf, &middlewares.Mail{})
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @daily
+		command = echo foo
+		inherit-globals = never
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid inherit-globals "never", must be true or false`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringPush(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		push-gateway = http://pushgateway:9091
+		push-token = s3cr3t
+
+		[job-local "foo"]
+		schedule = @daily
+		command = echo foo
+	`)
+
+	c.Assert(err, IsNil)
+
+	var push *middlewares.Push
+	for _, m := range sh.Middlewares() {
+		if p, ok := m.(*middlewares.Push); ok {
+			push = p
+		}
+	}
+
+	c.Assert(push, NotNil)
+	c.Assert(push.PushGateway, Equals, "http://pushgateway:9091")
+	c.Assert(sh.Redactor.Redact("token s3cr3t"), Equals, "token *****")
+}
+
+func (s *SuiteConfig) TestBuildFromStringJobOverridesGlobal(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		slack-webhook = http://example.com/global
+		job-overrides-global = false
+
+		[job-local "same"]
+		schedule = @daily
+		command = echo foo
+		slack-webhook = http://example.com/global
+
+		[job-local "other"]
+		schedule = @daily
+		command = echo foo
+		slack-webhook = http://example.com/other
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.JobOverridesGlobal, Equals, false)
+	c.Assert(sh.Start(), IsNil)
+	defer sh.Stop()
+
+	c.Assert(sh.GetJob("same").Middlewares(), HasLen, 1)
+
+	ms := sh.GetJob("other").Middlewares()
+	c.Assert(ms, HasLen, 2)
+	c.Assert(ms[0].(*middlewares.Slack).SlackWebhook, Equals, "http://example.com/other")
+	c.Assert(ms[1].(*middlewares.Slack).SlackWebhook, Equals, "http://example.com/global")
+
+	_, err = BuildFromString(`
+		[global]
+		job-overrides-global = maybe
+	`)
+
+	c.Assert(err, ErrorMatches, `global: invalid job-overrides-global "maybe", must be true or false`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringStdinFrom(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "dump"]
+		schedule = @daily
+		command = echo foo
+		on-success = upload
+
+		[job-local "upload"]
+		command = cat
+		stdin-from = dump
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(core.JobStdinFrom(sh.GetJob("upload")), Equals, "dump")
+
+	_, err = BuildFromString(`
+		[job-local "upload"]
+		command = cat
+		stdin-from = dump
+	`)
+
+	c.Assert(err, ErrorMatches, `job "upload": unknown job "dump" at stdin-from`)
+
+	_, err = BuildFromString(`
+		[job-local "dump"]
+		command = echo foo
+
+		[job-run "upload"]
+		container = uploader
+		stdin-from = dump
+	`)
+
+	c.Assert(err, ErrorMatches, `job "upload": stdin-from requires a new container.*`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringBackup(c *C) {
+	sh, err := BuildFromString(`
+		[job-backup "db"]
+		schedule = @daily
+		container = postgres
+		command = pg_dump app
+		destination = /backups
+		keep = 7
+		slack-webhook = http://example.com/hook
+	`)
+
+	c.Assert(err, IsNil)
+
+	j := sh.GetJob("db").(*BackupJobConfig)
+	c.Assert(j.Container, Equals, "postgres")
+	c.Assert(j.Destination, Equals, "/backups")
+	c.Assert(j.Keep, Equals, 7)
+	c.Assert(j.Compress, Equals, core.BackupGzip)
+	c.Assert(j.User, Equals, "root")
+	c.Assert(jobType(j), Equals, jobBackup)
+	c.Assert(j.Middlewares(), HasLen, 2)
+}
+
+func (s *SuiteConfig) TestBuildFromStringExpectedDuration(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		expected-duration = 10m
+	`)
+
+	c.Assert(err, IsNil)
+
+	ms := sh.GetJob("foo").Middlewares()
+	c.Assert(ms, HasLen, 1)
+	c.Assert(ms[0].(*middlewares.Deadline).ExpectedDuration, Equals, "10m")
+}
+
+func (s *SuiteConfig) TestBuildFromStringPipeline(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		command = echo foo
+
+		[job-local "bar"]
+		schedule = @every 10s
+		command = echo bar
+
+		[job-pipeline "qux"]
+		schedule = @every 10s
+		steps = foo
+		steps = bar
+		continue-on-error = foo
+  `)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Jobs, HasLen, 3)
+
+	_, err = BuildFromString(`
+		[job-pipeline "qux"]
+		schedule = @every 10s
+		steps = foo
+  `)
+
+	c.Assert(err, ErrorMatches, `unknown step "foo" at pipeline "qux"`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringTriggers(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		on-failure = bar
+
+		[job-local "bar"]
+		command = echo bar
+  `)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Jobs, HasLen, 2)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		on-success = qux
+  `)
+
+	c.Assert(err, ErrorMatches, `job "foo": unknown job "qux" at on-success/on-failure`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringTriggersCycle(c *C) {
+	_, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		on-failure = foo
+  `)
+
+	c.Assert(err, ErrorMatches, `job "foo": on-success/on-failure cycle foo -> foo`)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		on-success = bar
+
+		[job-local "bar"]
+		command = echo bar
+		on-success = baz
+
+		[job-local "baz"]
+		command = echo baz
+		on-failure = bar
+  `)
+
+	c.Assert(err, ErrorMatches, `job "bar": on-success/on-failure cycle bar -> baz -> bar`)
+}
+
 func (s *SuiteConfig) TestJobDefaultsSet(c *C) {
 	j := &RunJobConfig{}
 	j.Pull = "false"
@@ -63,7 +908,7 @@ func (s *SuiteConfig) TestExecJobBuildEmpty(c *C) {
 func (s *SuiteConfig) TestExecJobBuild(c *C) {
 	j := &ExecJobConfig{}
 	j.OverlapConfig.NoOverlap = true
-	j.buildMiddlewares()
+	c.Assert(buildMiddlewares(jobExec, j, nil, nil), IsNil)
 
 	c.Assert(j.Middlewares(), HasLen, 1)
 }
@@ -147,20 +992,26 @@ func (s *SuiteConfig) TestLabelsConfig(c *C) {
 			ExpectedConfig: Config{
 				LocalJobs: map[string]*LocalJobConfig{
 					"job1": {LocalJob: core.LocalJob{BareJob: core.BareJob{
-						Schedule: "schedule1",
-						Command:  "command1",
+						Schedule:        "schedule1",
+						Command:         "command1",
+						SourceContainer: "some",
+						DockerLabels:    true,
 					}}},
 				},
 				RunJobs: map[string]*RunJobConfig{
 					"job2": {RunJob: core.RunJob{BareJob: core.BareJob{
-						Schedule: "schedule2",
-						Command:  "command2",
+						Schedule:        "schedule2",
+						Command:         "command2",
+						SourceContainer: "some",
+						DockerLabels:    true,
 					}}},
 				},
 				ServiceJobs: map[string]*RunServiceConfig{
 					"job3": {RunServiceJob: core.RunServiceJob{BareJob: core.BareJob{
-						Schedule: "schedule3",
-						Command:  "command3",
+						Schedule:        "schedule3",
+						Command:         "command3",
+						SourceContainer: "some",
+						DockerLabels:    true,
 					}}},
 				},
 			},
@@ -183,13 +1034,17 @@ func (s *SuiteConfig) TestLabelsConfig(c *C) {
 			ExpectedConfig: Config{
 				ExecJobs: map[string]*ExecJobConfig{
 					"job1": {ExecJob: core.ExecJob{BareJob: core.BareJob{
-						Schedule: "schedule1",
-						Command:  "command1",
+						Schedule:        "schedule1",
+						Command:         "command1",
+						SourceContainer: "some",
+						DockerLabels:    true,
 					}}},
 					"job2": {ExecJob: core.ExecJob{
 						BareJob: core.BareJob{
-							Schedule: "schedule2",
-							Command:  "command2",
+							Schedule:        "schedule2",
+							Command:         "command2",
+							SourceContainer: "other",
+							DockerLabels:    true,
 						},
 						Container: "other",
 					}},
@@ -211,8 +1066,10 @@ func (s *SuiteConfig) TestLabelsConfig(c *C) {
 				ExecJobs: map[string]*ExecJobConfig{
 					"job1": {
 						ExecJob: core.ExecJob{BareJob: core.BareJob{
-							Schedule: "schedule1",
-							Command:  "command1",
+							Schedule:        "schedule1",
+							Command:         "command1",
+							SourceContainer: "some",
+							DockerLabels:    true,
 						}},
 						OverlapConfig: middlewares.OverlapConfig{NoOverlap: true},
 					},
@@ -237,15 +1094,19 @@ func (s *SuiteConfig) TestLabelsConfig(c *C) {
 				RunJobs: map[string]*RunJobConfig{
 					"job1": {RunJob: core.RunJob{
 						BareJob: core.BareJob{
-							Schedule: "schedule1",
-							Command:  "command1",
+							Schedule:        "schedule1",
+							Command:         "command1",
+							SourceContainer: "some",
+							DockerLabels:    true,
 						},
 						Volume: []string{"/test/tmp:/test/tmp:ro"},
 					}},
 					"job2": {RunJob: core.RunJob{
 						BareJob: core.BareJob{
-							Schedule: "schedule2",
-							Command:  "command2",
+							Schedule:        "schedule2",
+							Command:         "command2",
+							SourceContainer: "some",
+							DockerLabels:    true,
 						},
 						Volume: []string{"/test/tmp:/test/tmp:ro", "/test/tmp:/test/tmp:rw"},
 					}},
@@ -253,6 +1114,73 @@ func (s *SuiteConfig) TestLabelsConfig(c *C) {
 			},
 			Comment: "Test run job with volumes",
 		},
+		{
+			Labels: map[string]map[string]string{
+				"app_web_2": {
+					requiredLabelName:   "true",
+					composeProjectLabel: "app",
+					composeServiceLabel: "web",
+					labelPrefix + "." + jobExec + ".job1.schedule": "schedule1",
+					labelPrefix + "." + jobExec + ".job1.command":  "command1",
+				},
+				"app_web_1": {
+					requiredLabelName:   "true",
+					composeProjectLabel: "app",
+					composeServiceLabel: "web",
+					labelPrefix + "." + jobExec + ".job1.schedule": "schedule1",
+					labelPrefix + "." + jobExec + ".job1.command":  "command1",
+				},
+			},
+			ExpectedConfig: Config{
+				ExecJobs: map[string]*ExecJobConfig{
+					"job1": {ExecJob: core.ExecJob{
+						BareJob: core.BareJob{
+							Schedule:        "schedule1",
+							Command:         "command1",
+							SourceContainer: "app_web_1",
+							DockerLabels:    true,
+							SourceService:   "web",
+							SourceProject:   "app",
+						},
+						Container: "app_web_1",
+					}},
+				},
+			},
+			Comment: "Exec jobs from replicas of a service are deduplicated",
+		},
+		{
+			Labels: map[string]map[string]string{
+				"web.1": {
+					requiredLabelName: "true",
+					swarmServiceLabel: "web",
+					labelPrefix + "." + jobExec + ".job1.schedule": "schedule1",
+					labelPrefix + "." + jobExec + ".job1.replica":  "any",
+				},
+				"web.2": {
+					requiredLabelName: "true",
+					swarmServiceLabel: "web",
+					labelPrefix + "." + jobExec + ".job1.schedule": "schedule1",
+					labelPrefix + "." + jobExec + ".job1.replica":  "any",
+				},
+				"other": {
+					requiredLabelName: "true",
+					labelPrefix + "." + jobExec + ".job1.schedule": "schedule2",
+				},
+			},
+			ExpectedConfig: Config{
+				ExecJobs: map[string]*ExecJobConfig{
+					"web.job1": {ExecJob: core.ExecJob{
+						BareJob:        core.BareJob{Schedule: "schedule1", SourceService: "web", DockerLabels: true},
+						ContainerLabel: []string{swarmServiceLabel + "=web"},
+					}},
+					"other.job1": {ExecJob: core.ExecJob{
+						BareJob:   core.BareJob{Schedule: "schedule2", SourceContainer: "other", DockerLabels: true},
+						Container: "other",
+					}},
+				},
+			},
+			Comment: "Exec jobs run in any replica, same job name on different services",
+		},
 	}
 
 	for _, t := range testcases {
@@ -262,3 +1190,270 @@ func (s *SuiteConfig) TestLabelsConfig(c *C) {
 		c.Assert(conf, DeepEquals, t.ExpectedConfig)
 	}
 }
+
+func (s *SuiteConfig) TestGetLabelsFilters(c *C) {
+	server, err := dockertest.NewServer("127.0.0.1:0", nil, nil)
+	c.Assert(err, IsNil)
+	defer server.Stop()
+
+	client, err := docker.NewClient(server.URL())
+	c.Assert(err, IsNil)
+
+	inputbuf := bytes.NewBuffer(nil)
+	tr := tar.NewWriter(inputbuf)
+	tr.WriteHeader(&tar.Header{Name: "Dockerfile"})
+	tr.Write([]byte("FROM base\n"))
+	tr.Close()
+
+	err = client.BuildImage(docker.BuildImageOptions{
+		Name:         "test",
+		InputStream:  inputbuf,
+		OutputStream: bytes.NewBuffer(nil),
+	})
+	c.Assert(err, IsNil)
+
+	for name, scope := range map[string]string{"foo": "prod", "bar": "dev"} {
+		cont, err := client.CreateContainer(docker.CreateContainerOptions{
+			Name: name,
+			Config: &docker.Config{Image: "test", Labels: map[string]string{
+				requiredLabelName:  "true",
+				"ofelia.scope":     scope,
+				"com.example.test": "true",
+			}},
+		})
+		c.Assert(err, IsNil)
+		c.Assert(client.StartContainer(cont.ID, nil), IsNil)
+	}
+
+	labels, err := getLabels(client, DockerLabelsOptions{Filters: []string{"ofelia.scope=prod"}, Workers: 1})
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, map[string]map[string]string{
+		"foo": {requiredLabelName: "true", "ofelia.scope": "prod"},
+	})
+
+	labels, err = getLabels(client, DockerLabelsOptions{
+		Filters:    []string{"ofelia.scope=prod"},
+		Workers:    1,
+		OwnerLabel: "com.example.test",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(labels["foo"][ownerLabelName], Equals, "true")
+
+	_, err = getLabels(client, DockerLabelsOptions{Filters: []string{"ofelia.scope=qa"}})
+	c.Assert(err, NotNil)
+}
+
+func (s *SuiteConfig) TestGetLabelsConcurrently(c *C) {
+	server, err := dockertest.NewServer("127.0.0.1:0", nil, nil)
+	c.Assert(err, IsNil)
+	defer server.Stop()
+
+	client, err := docker.NewClient(server.URL())
+	c.Assert(err, IsNil)
+
+	c.Assert(client.PullImage(docker.PullImageOptions{Repository: "base"}, docker.AuthConfiguration{}), IsNil)
+
+	expected := make(map[string]map[string]string)
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("web-%d", i)
+		cont, err := client.CreateContainer(docker.CreateContainerOptions{
+			Name: name,
+			Config: &docker.Config{Image: "base", Labels: map[string]string{
+				requiredLabelName:              "true",
+				"ofelia.job-exec.foo.schedule": "@daily",
+			}},
+		})
+		c.Assert(err, IsNil)
+		c.Assert(client.StartContainer(cont.ID, nil), IsNil)
+
+		expected[name] = map[string]string{requiredLabelName: "true", "ofelia.job-exec.foo.schedule": "@daily"}
+	}
+
+	// every inspection waits for two of them to be in progress at once, up to
+	// a second, so the inspections only overlap when run concurrently
+	var mu sync.Mutex
+	var once sync.Once
+	var inProgress, overlapping int
+	overlap := make(chan struct{})
+	server.CustomHandler("/containers/.+/json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if inProgress++; inProgress == 2 {
+			once.Do(func() { close(overlap) })
+		}
+		mu.Unlock()
+
+		select {
+		case <-overlap:
+			mu.Lock()
+			overlapping++
+			mu.Unlock()
+		case <-time.After(time.Second):
+		}
+
+		server.DefaultHandler().ServeHTTP(w, r)
+
+		mu.Lock()
+		inProgress--
+		mu.Unlock()
+	}))
+
+	labels, err := getLabels(client, DockerLabelsOptions{Workers: 4})
+	c.Assert(err, IsNil)
+	c.Assert(labels, DeepEquals, expected)
+	c.Assert(overlapping > 0, Equals, true)
+}
+
+func (s *SuiteConfig) TestLabelsOwner(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:               "true",
+			serviceLabelName:                "true",
+			"ofelia.job-local.foo.schedule": "@hourly",
+			"ofelia.job-local.foo.owner":    "team-b",
+		},
+		"web": {
+			requiredLabelName:              "true",
+			ownerLabelName:                 "team-a",
+			"ofelia.job-exec.bar.schedule": "@daily",
+			"ofelia.job-exec.bar.owner":    "team-b",
+		},
+		"worker": {
+			requiredLabelName:              "true",
+			"ofelia.job-exec.baz.schedule": "@daily",
+			"ofelia.job-exec.baz.owner":    "team-b",
+		},
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(config.LocalJobs["foo"].Owner, Equals, "")
+	c.Assert(config.ExecJobs["bar"].Owner, Equals, "team-a")
+	c.Assert(config.ExecJobs["baz"].Owner, Equals, "")
+	c.Assert(config.Global, DeepEquals, Config{}.Global)
+}
+
+func (s *SuiteConfig) TestLabelsSource(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:                     "true",
+			serviceLabelName:                      "true",
+			"ofelia.job-run.foo.schedule":         "@hourly",
+			"ofelia.job-run.foo.source-container": "other",
+		},
+		"shop_web_1": {
+			requiredLabelName:              "true",
+			composeProjectLabel:            "shop",
+			composeServiceLabel:            "web",
+			"ofelia.job-exec.bar.schedule": "@daily",
+		},
+		"shop_worker_1": {
+			requiredLabelName:              "true",
+			composeProjectLabel:            "shop",
+			composeServiceLabel:            "worker",
+			"ofelia.job-exec.baz.schedule": "@daily",
+			"ofelia.job-exec.baz.replica":  "any",
+		},
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(config.RunJobs["foo"].GetProvenance(), DeepEquals, core.Provenance{Container: "ofelia"})
+	c.Assert(config.ExecJobs["bar"].GetProvenance(), DeepEquals, core.Provenance{Container: "shop_web_1", Service: "web", Project: "shop"})
+	c.Assert(config.ExecJobs["baz"].GetProvenance(), DeepEquals, core.Provenance{Service: "worker", Project: "shop"})
+}
+
+func (s *SuiteConfig) TestLabelsGroup(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:                     "true",
+			serviceLabelName:                      "true",
+			"ofelia.group.backups.max-concurrent": "2",
+			"ofelia.job-local.foo.schedule":       "@hourly",
+			"ofelia.job-local.foo.group":          "backups",
+		},
+		"web": {
+			requiredLabelName:                  "true",
+			"ofelia.group.web.schedule-offset": "5m",
+		},
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(config.Groups, HasLen, 1)
+	c.Assert(config.Groups["backups"].MaxConcurrent, Equals, 2)
+	c.Assert(config.LocalJobs["foo"].Group, Equals, "backups")
+}
+
+func (s *SuiteConfig) TestCanonicalLabels(c *C) {
+	labels := canonicalLabels("cron", map[string]string{
+		"cron.enabled":                 "true",
+		"cron.job-exec.foo.schedule":   "@hourly",
+		"ofelia.job-exec.bar.schedule": "@daily",
+		"com.example.foo":              "bar",
+		composeServiceLabel:            "web",
+	})
+
+	c.Assert(labels, DeepEquals, map[string]string{
+		requiredLabelName:              "true",
+		"ofelia.job-exec.foo.schedule": "@hourly",
+		composeServiceLabel:            "web",
+	})
+}
+
+func (s *SuiteConfig) TestBuildFromStringAt(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "migrate"]
+		schedule = @at 2025-07-01T03:00:00Z
+		command = ./migrate
+		remove-after-run = true
+	`)
+
+	c.Assert(err, IsNil)
+	j := sh.GetJob("migrate")
+	c.Assert(core.JobAt(j), Equals, time.Date(2025, 7, 1, 3, 0, 0, 0, time.UTC))
+	c.Assert(core.JobRemoveAfterRun(j), Equals, true)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		remove-after-run = true
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": remove-after-run requires an "@at" schedule`)
+}
+
+func (s *SuiteConfig) TestBuildFromStringHosts(c *C) {
+	defer os.Unsetenv(core.HostnameEnv)
+	os.Setenv(core.HostnameEnv, "edge-fra1")
+
+	sh, err := BuildFromString(`
+		[job-local "edge"]
+		schedule = @every 10s
+		command = echo edge
+		hosts = edge-*,db01
+
+		[job-local "db"]
+		schedule = @every 10s
+		command = echo db
+		hosts = db01
+
+		[job-local "all"]
+		schedule = @every 10s
+		command = echo all
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Jobs, HasLen, 2)
+	c.Assert(sh.GetJob("edge"), NotNil)
+	c.Assert(sh.GetJob("all"), NotNil)
+
+	_, err = BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		hosts = edge-[
+	`)
+
+	c.Assert(err, ErrorMatches, `job "foo": invalid hosts pattern "edge-\["`)
+}
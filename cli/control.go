@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mcuadros/ofelia/api"
+	"github.com/mcuadros/ofelia/core"
+)
+
+// StatusCommand lists the jobs of a running daemon, through its socket
+type StatusCommand struct {
+	Socket string `long:"socket" description:"unix socket of the daemon" default:"/var/run/ofelia.sock"`
+}
+
+// Execute runs the status command
+func (c *StatusCommand) Execute(args []string) error {
+	client := api.NewSocketClient(c.Socket)
+	jobs, err := client.Jobs()
+	if err != nil {
+		return err
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+
+	if err := printJobs(os.Stdout, jobs); err != nil {
+		return err
+	}
+
+	printReload(os.Stdout, status.LastReload)
+	return nil
+}
+
+func printJobs(out io.Writer, jobs []api.Job) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\tOWNER\tORPHANED\tCOMMAND\tDESCRIPTION")
+	for _, j := range jobs {
+		var orphaned string
+		if j.Orphaned != nil {
+			orphaned = j.Orphaned.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", j.Name, j.Schedule, j.Owner, orphaned, j.Command, j.Description)
+	}
+
+	return w.Flush()
+}
+
+// printReload prints the last reload of the config, if any
+func printReload(out io.Writer, r *core.Reload) {
+	if r == nil {
+		return
+	}
+
+	if r.Error != "" {
+		fmt.Fprintf(out, "\nLast reload: %s, failed: %s\n", r.Date.Format(time.RFC3339), r.Error)
+		return
+	}
+
+	fmt.Fprintf(
+		out, "\nLast reload: %s, %d added, %d updated, %d removed\n",
+		r.Date.Format(time.RFC3339), len(r.Added), len(r.Updated), len(r.Removed),
+	)
+}
+
+// RunCommand runs a job of a running daemon, through its socket
+type RunCommand struct {
+	Socket string `long:"socket" description:"unix socket of the daemon" default:"/var/run/ofelia.sock"`
+}
+
+// Execute runs the run command
+func (c *RunCommand) Execute(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("the name of the job to run is required")
+	}
+
+	if err := api.NewSocketClient(c.Socket).Run(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Job %q triggered\n", args[0])
+	return nil
+}
+
+// SilenceCommand silences the notifications of a running daemon, through its
+// socket, e.g. during a deploy. The jobs still run.
+type SilenceCommand struct {
+	Socket string   `long:"socket" description:"unix socket of the daemon" default:"/var/run/ofelia.sock"`
+	Tags   []string `long:"tags" description:"only silences the jobs with any of these tags, comma separated"`
+	List   bool     `long:"list" description:"lists the active silences"`
+	Remove string   `long:"remove" description:"lifts the silence with this id"`
+}
+
+// Execute runs the silence command
+func (c *SilenceCommand) Execute(args []string) error {
+	client := api.NewSocketClient(c.Socket)
+	switch {
+	case c.List:
+		silences, err := client.Silences()
+		if err != nil {
+			return err
+		}
+
+		return printSilences(os.Stdout, silences)
+	case c.Remove != "":
+		if err := client.Unsilence(c.Remove); err != nil {
+			return err
+		}
+
+		fmt.Printf("Silence %s lifted\n", c.Remove)
+		return nil
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("the duration of the silence is required, e.g. 30m")
+	}
+
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid duration %q", args[0])
+	}
+
+	silence, err := client.Silence(d, splitTags(c.Tags))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Notifications silenced until %s, id %s\n", silence.Until.Format(time.RFC3339), silence.ID)
+	return nil
+}
+
+// splitTags returns the given tags, splitting the comma separated ones
+func splitTags(values []string) []string {
+	var tags []string
+	for _, v := range values {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
+
+func printSilences(out io.Writer, silences []core.Silence) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUNTIL\tTAGS\tACTOR")
+	for _, s := range silences {
+		tags := strings.Join(s.Tags, ",")
+		if tags == "" {
+			tags = "*"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.Until.Format(time.RFC3339), tags, s.Actor)
+	}
+
+	return w.Flush()
+}
+
+// SkipCommand skips the next occurrences of a job of a running daemon,
+// through its socket
+type SkipCommand struct {
+	Socket string `long:"socket" description:"unix socket of the daemon" default:"/var/run/ofelia.sock"`
+}
+
+// Execute runs the skip command
+func (c *SkipCommand) Execute(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("the name of the job is required, and optionally the number of occurrences to skip")
+	}
+
+	n := 1
+	if len(args) == 2 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return fmt.Errorf("invalid number of occurrences %q", args[1])
+		}
+	}
+
+	if err := api.NewSocketClient(c.Socket).Skip(args[0], n); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		fmt.Printf("Job %q resumes its schedule\n", args[0])
+		return nil
+	}
+
+	fmt.Printf("Job %q skips its next %d occurrences\n", args[0], n)
+	return nil
+}
+
+// OnceCommand runs a job of a running daemon once at a given time, besides
+// its schedule, through its socket
+type OnceCommand struct {
+	Socket string `long:"socket" description:"unix socket of the daemon" default:"/var/run/ofelia.sock"`
+	List   bool   `long:"list" description:"lists the one-time runs"`
+	Cancel string `long:"cancel" description:"cancels the one-time run with this id"`
+}
+
+// Execute runs the once command
+func (c *OnceCommand) Execute(args []string) error {
+	client := api.NewSocketClient(c.Socket)
+	switch {
+	case c.List:
+		runs, err := client.Runs()
+		if err != nil {
+			return err
+		}
+
+		return printRuns(os.Stdout, runs)
+	case c.Cancel != "":
+		if err := client.CancelRun(c.Cancel); err != nil {
+			return err
+		}
+
+		fmt.Printf("Run %s cancelled\n", c.Cancel)
+		return nil
+	}
+
+	if len(args) != 2 {
+		return fmt.Errorf("the name of the job and the time to run it at are required")
+	}
+
+	at, err := parseRunTime(args[1], time.Now())
+	if err != nil {
+		return err
+	}
+
+	run, err := client.RunOnce(args[0], at)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Job %q runs once at %s, id %s\n", run.Job, run.At.Format(time.RFC3339), run.ID)
+	return nil
+}
+
+// parseRunTime parses the time of a one-time run, as RFC 3339, e.g.
+// 2020-01-01T03:00:00Z, or as a duration from now, e.g. 2h
+func parseRunTime(value string, now time.Time) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, must be RFC 3339, e.g. 2020-01-01T03:00:00Z, or a duration, e.g. 2h", value)
+}
+
+func printRuns(out io.Writer, runs []core.OneTimeRun) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tJOB\tAT\tACTOR")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.ID, r.Job, r.At.Format(time.RFC3339), r.Actor)
+	}
+
+	return w.Flush()
+}
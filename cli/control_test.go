@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/mcuadros/ofelia/api"
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteControl struct{}
+
+var _ = Suite(&SuiteControl{})
+
+func (s *SuiteControl) TestPrintJobs(c *C) {
+	orphaned := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	c.Assert(printJobs(&buf, []api.Job{
+		{Name: "foo", Schedule: "@daily", Command: "echo foo", Owner: "team-a", Description: "Says foo"},
+		{Name: "bar-baz", Schedule: "@every 5s", Command: "date", Orphaned: &orphaned},
+	}), IsNil)
+
+	c.Assert(buf.String(), Equals, ""+
+		"NAME     SCHEDULE   OWNER   ORPHANED              COMMAND   DESCRIPTION\n"+
+		"foo      @daily     team-a                        echo foo  Says foo\n"+
+		"bar-baz  @every 5s          2020-01-01T00:00:00Z  date      \n",
+	)
+}
+
+func (s *SuiteControl) TestPrintReload(c *C) {
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	printReload(&buf, nil)
+	c.Assert(buf.String(), Equals, "")
+
+	printReload(&buf, &core.Reload{Date: date, Added: []string{"foo"}, Removed: []string{"bar", "baz"}})
+	c.Assert(buf.String(), Equals, "\nLast reload: 2020-01-01T00:00:00Z, 1 added, 0 updated, 2 removed\n")
+
+	buf.Reset()
+	printReload(&buf, &core.Reload{Date: date, Error: "connection refused"})
+	c.Assert(buf.String(), Equals, "\nLast reload: 2020-01-01T00:00:00Z, failed: connection refused\n")
+}
+
+func (s *SuiteControl) TestPrintSilences(c *C) {
+	until := time.Date(2020, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	c.Assert(printSilences(&buf, []core.Silence{
+		{ID: "a1b2", Actor: "api:socket", Until: until, Tags: []string{"role=db", "env"}},
+		{ID: "c3d4", Actor: "api:admin", Until: until},
+	}), IsNil)
+
+	c.Assert(buf.String(), Equals, ""+
+		"ID    UNTIL                 TAGS         ACTOR\n"+
+		"a1b2  2020-01-01T00:30:00Z  role=db,env  api:socket\n"+
+		"c3d4  2020-01-01T00:30:00Z  *            api:admin\n",
+	)
+}
+
+func (s *SuiteControl) TestSplitTags(c *C) {
+	c.Assert(splitTags([]string{"role=db, env", "tier"}), DeepEquals, []string{"role=db", "env", "tier"})
+	c.Assert(splitTags(nil), IsNil)
+}
+
+func (s *SuiteControl) TestParseRunTime(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	at, err := parseRunTime("2020-01-02T03:00:00Z", now)
+	c.Assert(err, IsNil)
+	c.Assert(at, Equals, time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC))
+
+	at, err = parseRunTime("2h", now)
+	c.Assert(err, IsNil)
+	c.Assert(at, Equals, now.Add(2*time.Hour))
+
+	_, err = parseRunTime("tomorrow", now)
+	c.Assert(err, ErrorMatches, `invalid time "tomorrow", .*`)
+}
+
+func (s *SuiteControl) TestPrintRuns(c *C) {
+	at := time.Date(2020, 1, 1, 3, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	c.Assert(printRuns(&buf, []core.OneTimeRun{
+		{ID: "a1b2", Job: "backup", Actor: "api:socket", At: at},
+	}), IsNil)
+
+	c.Assert(buf.String(), Equals, ""+
+		"ID    JOB     AT                    ACTOR\n"+
+		"a1b2  backup  2020-01-01T03:00:00Z  api:socket\n",
+	)
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConvertCommand translates a crontab into the job-local sections of an INI
+// config, written to the standard output
+type ConvertCommand struct {
+	FromCrontab string `long:"from-crontab" description:"crontab file to convert" required:"true"`
+	System      bool   `long:"system" description:"the lines have the user after the schedule, by default only for /etc/crontab and /etc/cron.d"`
+}
+
+// Execute runs the convert command
+func (c *ConvertCommand) Execute(args []string) error {
+	f, err := os.Open(c.FromCrontab)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	lines, err := readCrontabLines(f)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := parseCrontab(lines, c.System || isSystemCrontab(c.FromCrontab))
+	if err != nil {
+		return fmt.Errorf("%s: %s", c.FromCrontab, err)
+	}
+
+	prefix := strings.TrimPrefix(filepath.Base(c.FromCrontab), ".")
+	writeCrontabJobs(os.Stdout, prefix, jobs)
+	return nil
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	crontabSection = "crontab"
+	crontabShell   = "/bin/sh"
+	// crontabPath default PATH of cron, set when the crontab sets variables
+	// without PATH, since the variables replace the environment of the jobs
+	crontabPath = "PATH=/usr/bin:/bin"
+)
+
+var crontabVariable = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+
+// crontabLine a line of a crontab, with its number in the file
+type crontabLine struct {
+	Number int
+	Text   string
+}
+
+// crontabJob a job of a crontab, translated to the fields of a job-local
+type crontabJob struct {
+	Line        crontabLine
+	Schedule    string
+	Command     string
+	User        string
+	Environment []string
+}
+
+// LocalJob returns the job-local running the command of the crontab job with
+// the given name
+func (j *crontabJob) LocalJob(name string) *LocalJobConfig {
+	c := &LocalJobConfig{}
+	c.Name = name
+	c.Schedule = j.Schedule
+	c.Command = j.Command
+	c.Environment = j.Environment
+
+	return c
+}
+
+// readCrontabLines returns the lines of the given crontab, numbered from 1
+func readCrontabLines(r io.Reader) ([]crontabLine, error) {
+	var lines []crontabLine
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		lines = append(lines, crontabLine{Number: n, Text: scanner.Text()})
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseCrontab returns the jobs of the given crontab lines, the lines of the
+// system crontabs, as /etc/crontab, have the user after the schedule. The
+// commands run with the SHELL of the crontab, /bin/sh by default, and the
+// variables set before them.
+func parseCrontab(lines []crontabLine, system bool) ([]*crontabJob, error) {
+	shell := crontabShell
+	var env []string
+
+	var jobs []*crontabJob
+	for _, l := range lines {
+		text := strings.TrimSpace(l.Text)
+		if text == "" || text[0] == '#' || text[0] == ';' {
+			continue
+		}
+
+		if m := crontabVariable.FindStringSubmatch(text); m != nil {
+			value := unquoteCrontabValue(m[2])
+			if m[1] == "SHELL" {
+				shell = value
+			}
+
+			env = setCrontabVariable(env, m[1], value)
+			continue
+		}
+
+		j, err := parseCrontabJob(text, system, shell)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", l.Number, err)
+		}
+
+		j.Line = l
+		if len(env) != 0 {
+			j.Environment = append([]string(nil), env...)
+			if !hasCrontabVariable(env, "PATH") {
+				j.Environment = append(j.Environment, crontabPath)
+			}
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+func parseCrontabJob(text string, system bool, shell string) (*crontabJob, error) {
+	fields := 5
+	if strings.HasPrefix(text, "@") {
+		fields = 1
+	}
+
+	if system {
+		fields++
+	}
+
+	parts := strings.Fields(text)
+	if len(parts) <= fields {
+		return nil, fmt.Errorf("missing command in %q", text)
+	}
+
+	j := &crontabJob{Schedule: strings.Join(parts[:fields], " ")}
+	if system {
+		j.User = parts[fields-1]
+		j.Schedule = strings.Join(parts[:fields-1], " ")
+	}
+
+	if j.Schedule == "@reboot" {
+		return nil, fmt.Errorf("@reboot is not supported")
+	}
+
+	if _, err := cron.ParseStandard(j.Schedule); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %s", j.Schedule, err)
+	}
+
+	// the command starts after the fields, keeping its spaces
+	command := text
+	for i := 0; i < fields; i++ {
+		command = strings.TrimSpace(command)
+		command = command[strings.IndexFunc(command, isCrontabSpace):]
+	}
+
+	command, err := unescapeCrontabCommand(strings.TrimSpace(command))
+	if err != nil {
+		return nil, err
+	}
+
+	j.Command = fmt.Sprintf("%s -c %s", shell, quoteArg(command))
+	return j, nil
+}
+
+func isCrontabSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// unescapeCrontabCommand returns the command with the escaped % unescaped, the
+// unescaped % feeding the rest of the line to the stdin are not supported
+func unescapeCrontabCommand(command string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(command); i++ {
+		switch {
+		case command[i] == '\\' && i+1 < len(command) && command[i+1] == '%':
+			b.WriteByte('%')
+			i++
+		case command[i] == '%':
+			return "", fmt.Errorf("the %% of the command feeding its stdin are not supported, escape them as \\%%")
+		default:
+			b.WriteByte(command[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+func unquoteCrontabValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+func setCrontabVariable(env []string, name, value string) []string {
+	for i, v := range env {
+		if strings.HasPrefix(v, name+"=") {
+			env[i] = name + "=" + value
+			return env
+		}
+	}
+
+	return append(env, name+"="+value)
+}
+
+func hasCrontabVariable(env []string, name string) bool {
+	for _, v := range env {
+		if strings.HasPrefix(v, name+"=") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quoteArg quotes the given argument to be parsed as a single argument of the
+// command of a job
+func quoteArg(arg string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+// isSystemCrontab returns true if the crontab at the given path has the user
+// after the schedule, as /etc/crontab and the crontabs of /etc/cron.d
+func isSystemCrontab(path string) bool {
+	path = filepath.Clean(path)
+	return path == "/etc/crontab" || filepath.Dir(path) == "/etc/cron.d"
+}
+
+// extractCrontabs removes from the INI config the crontab sections, returning
+// their lines by section name. The removed lines are blanked, to keep the line
+// numbers of the errors.
+func extractCrontabs(config string) (string, map[string][]crontabLine, error) {
+	crontabs := make(map[string][]crontabLine)
+	var out []string
+	var name string
+	var in bool
+
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			in = strings.ToLower(m[1]) == crontabSection
+			if in {
+				name = m[2]
+				if name == "" {
+					name = crontabSection
+				}
+
+				line = ""
+			}
+		} else if in {
+			crontabs[name] = append(crontabs[name], crontabLine{Number: n, Text: line})
+			line = ""
+		}
+
+		out = append(out, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+
+	return strings.Join(out, "\n"), crontabs, nil
+}
+
+// buildCrontabJobs adds to the job-local of the config the jobs of the crontab
+// sections, named after the section, "crontab" by default, and their position
+func (config *Config) buildCrontabJobs(crontabs map[string][]crontabLine) error {
+	var sections []string
+	for section := range crontabs {
+		sections = append(sections, section)
+	}
+
+	sort.Strings(sections)
+	for _, section := range sections {
+		jobs, err := parseCrontab(crontabs[section], false)
+		if err != nil {
+			return fmt.Errorf("crontab %q: %s", section, err)
+		}
+
+		if config.LocalJobs == nil && len(jobs) != 0 {
+			config.LocalJobs = make(map[string]*LocalJobConfig)
+		}
+
+		for i, j := range jobs {
+			name := fmt.Sprintf("%s-%d", section, i+1)
+			if _, ok := config.LocalJobs[name]; ok {
+				return fmt.Errorf("crontab %q: job-local %q already defined", section, name)
+			}
+
+			config.LocalJobs[name] = j.LocalJob(name)
+		}
+	}
+
+	return nil
+}
+
+// writeCrontabJobs writes the given crontab jobs as job-local sections of an
+// INI config, named after the prefix and their position
+func writeCrontabJobs(w io.Writer, prefix string, jobs []*crontabJob) {
+	for i, j := range jobs {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "; line %d: %s\n", j.Line.Number, strings.TrimSpace(j.Line.Text))
+		if j.User != "" {
+			fmt.Fprintf(w, "; ran as %s, now runs as the user of ofelia\n", j.User)
+		}
+
+		fmt.Fprintf(w, "[%s %q]\n", jobLocal, fmt.Sprintf("%s-%d", prefix, i+1))
+		fmt.Fprintf(w, "schedule = %s\n", iniValue(j.Schedule))
+		fmt.Fprintf(w, "command = %s\n", iniValue(j.Command))
+		for _, e := range j.Environment {
+			fmt.Fprintf(w, "environment = %s\n", iniValue(e))
+		}
+	}
+}
+
+// iniValue returns the given value quoted if needed to be read as is by gcfg
+func iniValue(value string) string {
+	if !strings.ContainsAny(value, `"\;#`) && strings.TrimSpace(value) == value {
+		return value
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\t", `\t`)
+	return `"` + r.Replace(value) + `"`
+}
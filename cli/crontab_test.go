@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gobs/args"
+	"gopkg.in/gcfg.v1"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteCrontab struct{}
+
+var _ = Suite(&SuiteCrontab{})
+
+func (s *SuiteCrontab) TestParseCrontab(c *C) {
+	lines, err := readCrontabLines(strings.NewReader(`
+# m h dom mon dow command
+MAILTO=""
+*/5 * * * * /usr/bin/check   --all
+@daily  echo "50\% done" > /tmp/log
+`))
+	c.Assert(err, IsNil)
+
+	jobs, err := parseCrontab(lines, false)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 2)
+
+	c.Assert(jobs[0].Line.Number, Equals, 4)
+	c.Assert(jobs[0].Schedule, Equals, "*/5 * * * *")
+	c.Assert(jobs[0].Command, Equals, `/bin/sh -c "/usr/bin/check   --all"`)
+	c.Assert(jobs[0].Environment, DeepEquals, []string{"MAILTO=", crontabPath})
+
+	c.Assert(jobs[1].Schedule, Equals, "@daily")
+	c.Assert(args.GetArgs(jobs[1].Command), DeepEquals, []string{
+		"/bin/sh", "-c", `echo "50% done" > /tmp/log`,
+	})
+}
+
+func (s *SuiteCrontab) TestParseCrontabSystem(c *C) {
+	lines, err := readCrontabLines(strings.NewReader(`
+SHELL=/bin/bash
+PATH=/usr/local/bin:/usr/bin
+17 * * * * root cd / && run-parts --report /etc/cron.hourly
+`))
+	c.Assert(err, IsNil)
+
+	jobs, err := parseCrontab(lines, true)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 1)
+	c.Assert(jobs[0].Schedule, Equals, "17 * * * *")
+	c.Assert(jobs[0].User, Equals, "root")
+	c.Assert(jobs[0].Command, Equals, `/bin/bash -c "cd / && run-parts --report /etc/cron.hourly"`)
+	c.Assert(jobs[0].Environment, DeepEquals, []string{"SHELL=/bin/bash", "PATH=/usr/local/bin:/usr/bin"})
+}
+
+func (s *SuiteCrontab) TestParseCrontabErrors(c *C) {
+	for text, expected := range map[string]string{
+		"@reboot /bin/true":    `line 1: @reboot is not supported`,
+		"* * * * *":            `line 1: missing command in "\* \* \* \* \*"`,
+		"61 * * * * /bin/true": `line 1: invalid schedule "61 \* \* \* \*": .*`,
+		"* * * * * date +%Y":   `line 1: the % of the command feeding its stdin are not supported, .*`,
+	} {
+		lines, _ := readCrontabLines(strings.NewReader(text))
+		_, err := parseCrontab(lines, false)
+		c.Assert(err, ErrorMatches, expected)
+	}
+}
+
+func (s *SuiteCrontab) TestIsSystemCrontab(c *C) {
+	c.Assert(isSystemCrontab("/etc/crontab"), Equals, true)
+	c.Assert(isSystemCrontab("/etc/cron.d/backup"), Equals, true)
+	c.Assert(isSystemCrontab("/var/spool/cron/crontabs/root"), Equals, false)
+}
+
+func (s *SuiteCrontab) TestWriteCrontabJobs(c *C) {
+	lines, _ := readCrontabLines(strings.NewReader(`0 5 * * * root echo "a;b" # done`))
+	jobs, err := parseCrontab(lines, true)
+	c.Assert(err, IsNil)
+
+	var b bytes.Buffer
+	writeCrontabJobs(&b, "crontab", jobs)
+
+	config := &Config{}
+	c.Assert(gcfg.ReadStringInto(config, b.String()), IsNil)
+	c.Assert(config.LocalJobs, HasLen, 1)
+
+	j := config.LocalJobs["crontab-1"]
+	c.Assert(j, NotNil)
+	c.Assert(j.Schedule, Equals, "0 5 * * *")
+	c.Assert(j.Command, Equals, jobs[0].Command)
+	c.Assert(args.GetArgs(j.Command), DeepEquals, []string{"/bin/sh", "-c", `echo "a;b" # done`})
+}
+
+func (s *SuiteCrontab) TestBuildFromStringCrontab(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+
+		[crontab]
+		# comment
+		PATH=/bin
+		@hourly echo bar
+
+		[crontab "backup"]
+		0 3 * * * /usr/bin/backup
+		`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Jobs, HasLen, 3)
+
+	config := &Config{}
+	c.Assert(config.readString(`
+		[crontab]
+		@hourly echo bar
+		`), IsNil)
+	c.Assert(config.LocalJobs["crontab-1"].Command, Equals, `/bin/sh -c "echo bar"`)
+	c.Assert(config.LocalJobs["crontab-1"].Schedule, Equals, "@hourly")
+
+	_, err = BuildFromString(`
+		[crontab]
+		@reboot echo bar
+		`)
+	c.Assert(err, ErrorMatches, `crontab "crontab": line 3: @reboot is not supported`)
+}
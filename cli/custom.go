@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	defaults "github.com/mcuadros/go-defaults"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+	"github.com/mitchellh/mapstructure"
+)
+
+var sectionHeader = regexp.MustCompile(`^\s*\[\s*([^\s"\]]+)(?:\s+"([^"]*)")?\s*\]`)
+
+func isBuiltinJobType(t string) bool {
+	switch t {
+	case jobExec, jobRun, jobServiceRun, jobLocal, jobPipeline, jobBackup, jobECS, jobNomad:
+		return true
+	}
+
+	return false
+}
+
+// sectionParams params of config sections, by section type and name
+type sectionParams map[string]map[string]map[string]interface{}
+
+func (c sectionParams) get(section, name string) map[string]interface{} {
+	return c[section][name]
+}
+
+func (c sectionParams) set(jobType, name, param string, value interface{}) {
+	if _, ok := c[jobType]; !ok {
+		c[jobType] = make(map[string]map[string]interface{})
+	}
+
+	if _, ok := c[jobType][name]; !ok {
+		c[jobType][name] = make(map[string]interface{})
+	}
+
+	if value == nil {
+		return
+	}
+
+	// repeated params are lists, as in gcfg
+	if prev, ok := c[jobType][name][param]; ok {
+		switch p := prev.(type) {
+		case []string:
+			c[jobType][name][param] = append(p, value.(string))
+		case string:
+			c[jobType][name][param] = []string{p, value.(string)}
+		}
+
+		return
+	}
+
+	c[jobType][name][param] = value
+}
+
+// extractSections removes from the INI config the sections of the custom job
+// types, the sections of the named notifiers, and the params of the
+// middlewares not known by the built-in sections, returning their params. The
+// removed lines are blanked, to keep the line numbers of the errors.
+func extractSections(config string) (string, sectionParams, sectionParams, sectionParams, error) {
+	custom := make(sectionParams)
+	extra := make(sectionParams)
+	notifiers := make(sectionParams)
+	var out []string
+	var section, name string
+	var target sectionParams
+	var plugin map[string]bool
+
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			section, name, target = strings.ToLower(m[1]), m[2], nil
+			plugin = pluginParams(section)
+			if _, ok := core.GetJobFactory(section); ok && !isBuiltinJobType(section) {
+				target = custom
+				custom.set(section, name, "", nil)
+				line = ""
+			} else if middlewares.IsNotifier(section) {
+				if name == "" {
+					return "", nil, nil, nil, fmt.Errorf("line %d: %s section requires a name, e.g. [%s \"ops\"]", n, section, section)
+				}
+
+				target = notifiers
+				notifiers.set(section, name, "", nil)
+				line = ""
+			}
+
+			out = append(out, line)
+			continue
+		}
+
+		param, value, ok, err := parseParam(line)
+		if err != nil && target != nil {
+			return "", nil, nil, nil, fmt.Errorf("line %d: %s", n, err)
+		}
+
+		switch {
+		case target != nil:
+			if ok {
+				target.set(section, name, param, value)
+			}
+		case ok && err == nil && plugin[param]:
+			extra.set(section, name, param, value)
+		default:
+			out = append(out, line)
+			continue
+		}
+
+		out = append(out, "")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	return strings.Join(out, "\n"), custom, extra, notifiers, nil
+}
+
+func parseParam(line string) (string, string, bool, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == ';' || line[0] == '#' || line[0] == '[' {
+		return "", "", false, nil
+	}
+
+	parts := strings.SplitN(line, "=", 2)
+	param := strings.ToLower(strings.TrimSpace(parts[0]))
+	if len(parts) == 1 {
+		// blank value, as in gcfg, means true
+		return param, "true", true, nil
+	}
+
+	value := strings.TrimSpace(parts[1])
+	if strings.HasPrefix(value, `"`) {
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return "", "", false, fmt.Errorf("invalid value of %q: %s", param, err)
+		}
+
+		return param, v, true, nil
+	}
+
+	if i := strings.IndexAny(value, ";#"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return param, value, true, nil
+}
+
+// buildCustomJobs returns the jobs of the custom types, sorted by type and name
+func buildCustomJobs(c sectionParams, named namedNotifiers) ([]core.Job, error) {
+	var types []string
+	for t := range c {
+		types = append(types, t)
+	}
+
+	sort.Strings(types)
+
+	var jobs []core.Job
+	for _, t := range types {
+		var names []string
+		for name := range c[t] {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			j, err := buildCustomJob(t, name, c[t][name], named)
+			if err != nil {
+				return nil, err
+			}
+
+			jobs = append(jobs, j)
+		}
+	}
+
+	return jobs, nil
+}
+
+func buildCustomJob(jobType, name string, params map[string]interface{}, named namedNotifiers) (core.Job, error) {
+	f, ok := core.GetJobFactory(jobType)
+	if !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+
+	j := f()
+	p := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		p[k] = v
+	}
+
+	p["name"] = name
+	if err := mapstructure.WeakDecode(p, j); err != nil {
+		return nil, fmt.Errorf("%s %q: %s", jobType, name, err)
+	}
+
+	defaults.SetDefaults(j)
+	if err := buildMiddlewares(jobType, j, params, named); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type customTestJob struct {
+	core.BareJob `mapstructure:",squash"`
+	Target       string
+	Retries      int
+	Tag          []string
+	Mode         string `default:"fast"`
+}
+
+func (j *customTestJob) Run(ctx *core.Context) error {
+	return nil
+}
+
+func init() {
+	core.RegisterJobType("job-custom-test", func() core.Job {
+		return &customTestJob{}
+	})
+}
+
+type SuiteCustom struct{}
+
+var _ = Suite(&SuiteCustom{})
+
+func (s *SuiteCustom) TestBuildFromString(c *C) {
+	sh, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+
+		[job-custom-test "bar"]
+		schedule = @every 5s
+		target = "http://example.com/#foo"
+		retries = 3 ; comment
+		tag = a
+		tag = b
+
+		[job-pipeline "qux"]
+		schedule = @daily
+		steps = foo
+		steps = bar
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Jobs, HasLen, 3)
+
+	j, ok := sh.GetJob("bar").(*customTestJob)
+	c.Assert(ok, Equals, true)
+	c.Assert(j.Schedule, Equals, "@every 5s")
+	c.Assert(j.Target, Equals, "http://example.com/#foo")
+	c.Assert(j.Retries, Equals, 3)
+	c.Assert(j.Tag, DeepEquals, []string{"a", "b"})
+	c.Assert(j.Mode, Equals, "fast")
+}
+
+func (s *SuiteCustom) TestBuildFromStringUnknownSection(c *C) {
+	_, err := BuildFromString(`
+		[job-custom-test "bar"]
+		target = foo
+
+		[job-foo "qux"]
+	`)
+
+	c.Assert(err, ErrorMatches, `(?s).*"job-foo".*`)
+}
+
+func (s *SuiteCustom) TestBuildFromDockerLabels(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:                   "true",
+			serviceLabelName:                    "true",
+			"ofelia.job-custom-test.bar.target": "foo",
+			"ofelia.job-custom-test.bar.tag":    "a",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	jobs, err := buildCustomJobs(config.custom, nil)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 1)
+	c.Assert(jobs[0].(*customTestJob).Target, Equals, "foo")
+	c.Assert(jobs[0].(*customTestJob).Tag, DeepEquals, []string{"a"})
+}
@@ -1,21 +1,59 @@
 package cli
 
 import (
+	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/mcuadros/ofelia/api"
 	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
 )
 
 // DaemonCommand daemon process
 type DaemonCommand struct {
-	ConfigFile         string `long:"config" description:"configuration file" default:"/etc/ofelia.conf"`
-	DockerLabelsConfig bool   `short:"d" long:"docker" description:"read configurations from docker labels"`
+	ConfigFlags
+	DockerPollInterval time.Duration `long:"docker-poll-interval" description:"interval to re-read the docker labels and update the jobs, disabled by default"`
+	API                bool          `long:"api" description:"enable the HTTP API"`
+	APIAddr            string        `long:"api-addr" description:"address the HTTP API listens on" default:"127.0.0.1:8081"`
+	APIUsers           []string      `long:"api-user" env:"OFELIA_API_USERS" env-delim:"," description:"basic auth credential of the API, as name:password:scope, the scope being read or admin"`
+	APITokens          []string      `long:"api-token" env:"OFELIA_API_TOKENS" env-delim:"," description:"bearer token of the API, as token:scope, the scope being read or admin"`
+	APITLSCert         string        `long:"api-tls-cert" description:"certificate file, enables HTTPS on the API"`
+	APITLSKey          string        `long:"api-tls-key" description:"key file of the API certificate"`
+	APITLSClientCA     string        `long:"api-tls-client-ca" description:"CA file of the client certificates required by the API"`
+	APIExternalURL     string        `long:"api-external-url" description:"URL of the HTTP API as reached by the readers of the notifications, which link to the executions"`
+	GRPC               bool          `long:"grpc" description:"enable the gRPC control API, with the credentials and TLS of the HTTP API"`
+	GRPCAddr           string        `long:"grpc-addr" description:"address the gRPC API listens on" default:"127.0.0.1:8082"`
+	Socket             bool          `long:"socket" description:"enable the control API on a unix socket, used by the status and run commands"`
+	SocketPath         string        `long:"socket-path" description:"path of the control socket" default:"/var/run/ofelia.sock"`
+	FederationPeers    []string      `long:"federation-peer" description:"HTTP API of another daemon, as name=url, whose jobs and executions are merged with the local ones by the federation routes of the API, repeatable"`
+	FederationToken    string        `long:"federation-token" env:"OFELIA_FEDERATION_TOKEN" description:"bearer token of the APIs of the federation peers"`
+	FederationName     string        `long:"federation-name" description:"name of this daemon in the federation, the hostname by default"`
+	GitURL             string        `long:"git-url" description:"URL of a git repository holding the config file, pulled every --git-interval, the jobs being reloaded on every new commit"`
+	GitBranch          string        `long:"git-branch" description:"branch of the git repository" default:"main"`
+	GitSSHKey          string        `long:"git-ssh-key" description:"private key file of the ssh URLs of the git repository"`
+	GitPath            string        `long:"git-path" description:"path of the config file in the git repository" default:"ofelia.ini"`
+	GitDir             string        `long:"git-dir" description:"directory where the git repository is checked out" default:"/var/lib/ofelia/git"`
+	GitInterval        time.Duration `long:"git-interval" description:"interval to pull the git repository" default:"1m"`
+	KVBackend          string        `long:"kv-backend" description:"key-value store holding jobs, watched to reload them on every change" choice:"consul" choice:"etcd"`
+	KVAddr             string        `long:"kv-addr" description:"URL of the key-value store, e.g. http://127.0.0.1:8500"`
+	KVPrefix           string        `long:"kv-prefix" description:"prefix of the keys of the jobs in the key-value store" default:"ofelia"`
+	KVToken            string        `long:"kv-token" env:"OFELIA_KV_TOKEN" description:"ACL token of consul, or user:password of etcd"`
+	SelfTest           bool          `long:"self-test" description:"check the images, containers, networks, volumes and notifiers of the jobs before starting, exiting on failure"`
 
-	scheduler *core.Scheduler
-	signals   chan os.Signal
-	done      chan bool
+	config     *Config
+	scheduler  *core.Scheduler
+	reconciler *labelsReconciler
+	git        *gitSync
+	kv         *kvSync
+	api        *api.Server
+	signals    chan os.Signal
+	done       chan bool
+	stopDebug  func()
 }
 
 // Execute runs the daemon
@@ -27,6 +65,14 @@ func (c *DaemonCommand) Execute(args []string) error {
 		return err
 	}
 
+	c.probeRuntime()
+
+	if c.SelfTest {
+		if failed := writeSelfTestReport(os.Stdout, selfTest(c.scheduler)); failed > 0 {
+			return fmt.Errorf("self-test failed, %d checks failed", failed)
+		}
+	}
+
 	if err := c.start(); err != nil {
 		return err
 	}
@@ -38,22 +84,303 @@ func (c *DaemonCommand) Execute(args []string) error {
 	return nil
 }
 
+// boot builds the scheduler, keeping the config to build the applied job sets,
+// the config file being checked out first when synced from git
 func (c *DaemonCommand) boot() (err error) {
-	if c.DockerLabelsConfig {
-		c.scheduler, err = BuildFromDockerLabels()
-	} else {
-		c.scheduler, err = BuildFromFile(c.ConfigFile)
+	var commit string
+	if c.GitURL != "" {
+		if commit, err = c.checkoutGit(); err != nil {
+			return err
+		}
+	}
+
+	if c.config, err = c.readConfig(); err != nil {
+		return err
+	}
+
+	if c.KVBackend != "" {
+		if err := c.loadKV(); err != nil {
+			return err
+		}
+	}
+
+	c.scheduler, err = c.config.build()
+	if err != nil && !c.DockerLabelsConfig {
+		return fmt.Errorf("%s: %s", c.ConfigFile, err)
+	}
+
+	if err == nil && c.git != nil {
+		c.git.global = fingerprintGlobal(c.config)
+		c.git.setCommit(commit)
 	}
 
 	return
 }
 
+// loadKV adds the jobs of the key-value store to the config, watched once
+// the daemon starts
+func (c *DaemonCommand) loadKV() error {
+	if c.DockerLabelsConfig || c.GitURL != "" {
+		return fmt.Errorf("kv-backend can't be used with the docker labels config nor git-url")
+	}
+
+	store, err := newKVStore(c.KVBackend, c.KVAddr, c.KVToken)
+	if err != nil {
+		return err
+	}
+
+	c.kv = newKVSync(store, c.KVPrefix, c.ConfigFile)
+	return c.kv.load(c.config)
+}
+
+// checkoutGit checks out the git repository, the config file being the one
+// within, returning the commit checked out
+func (c *DaemonCommand) checkoutGit() (string, error) {
+	if c.DockerLabelsConfig {
+		return "", fmt.Errorf("git-url can't be used with the docker labels config")
+	}
+
+	if c.GitInterval <= 0 {
+		return "", fmt.Errorf("invalid git-interval %s, must be positive", c.GitInterval)
+	}
+
+	c.git = newGitSync(GitSyncOptions{
+		URL:    c.GitURL,
+		Branch: c.GitBranch,
+		SSHKey: c.GitSSHKey,
+		Path:   c.GitPath,
+		Dir:    c.GitDir,
+	})
+
+	commit, err := c.git.checkout()
+	if err != nil {
+		return "", err
+	}
+
+	c.ConfigFile = c.git.configFile()
+	return commit, nil
+}
+
+// probeRuntime probes the capabilities of the docker daemon, unless set with
+// the rootless option or no job uses docker
+func (c *DaemonCommand) probeRuntime() {
+	logger := c.scheduler.Logger
+	if c.scheduler.Runtime != nil {
+		logger.Noticef("Docker runtime capabilities: %s", c.scheduler.Runtime)
+		return
+	}
+
+	if !c.DockerLabelsConfig && len(core.DockerAPIUsed(c.scheduler.Jobs)) == 0 {
+		return
+	}
+
+	client, err := (&Config{}).buildDockerClient()
+	if err == nil {
+		c.scheduler.Runtime, err = core.ProbeRuntime(client)
+	}
+
+	if err != nil {
+		logger.Warningf("Unable to probe the docker runtime capabilities: %s", err)
+		return
+	}
+
+	logger.Noticef("Docker runtime capabilities: %s", c.scheduler.Runtime)
+}
+
+// ConfigFlags flags of the commands reading the config, from a file or from
+// the docker labels
+type ConfigFlags struct {
+	ConfigFile         string   `long:"config" description:"configuration file" default:"/etc/ofelia.conf"`
+	DockerLabelsConfig bool     `short:"d" long:"docker" description:"read configurations from docker labels"`
+	DockerFilters      []string `long:"docker-filter" description:"only read labels from containers matching this label filter, e.g. ofelia.scope=prod"`
+	DockerProject      string   `long:"docker-project" description:"only read labels from containers of this compose project"`
+	DockerLabelPrefix  []string `long:"docker-label-prefix" description:"prefix of the labels, can be provided multiple times to support several prefixes" default:"ofelia"`
+	DockerWorkers      int      `long:"docker-workers" description:"max number of containers inspected at the same time while reading the labels" default:"8"`
+	DockerOwnerLabel   string   `long:"docker-owner-label" description:"container label identifying the owner of its jobs, when the container has no ofelia.owner label" default:"com.docker.compose.project"`
+	LabelAllowImage    []string `long:"label-allow-image" description:"image allowed to the jobs of the docker labels, * matching any characters, can be provided multiple times, e.g. alpine:*"`
+	LabelAllowCommand  []string `long:"label-allow-command" description:"regular expression matching the whole commands allowed to the jobs of the docker labels, can be provided multiple times"`
+	LabelDenyVolume    []string `long:"label-deny-volume" description:"host path not mounted by the jobs of the docker labels, nor its subpaths and parents, can be provided multiple times, e.g. /var/run/docker.sock"`
+	LabelAllowUser     []string `long:"label-allow-user" description:"user allowed to run the commands of the jobs of the docker labels, can be provided multiple times"`
+}
+
+func (c *ConfigFlags) dockerLabelsOptions() DockerLabelsOptions {
+	filters := c.DockerFilters
+	if c.DockerProject != "" {
+		filters = append(filters, composeProjectLabel+"="+c.DockerProject)
+	}
+
+	return DockerLabelsOptions{
+		Prefixes:   c.DockerLabelPrefix,
+		Filters:    filters,
+		Workers:    c.DockerWorkers,
+		OwnerLabel: c.DockerOwnerLabel,
+		Policy: LabelPolicy{
+			AllowImages:   c.LabelAllowImage,
+			AllowCommands: c.LabelAllowCommand,
+			DenyVolumes:   c.LabelDenyVolume,
+			AllowUsers:    c.LabelAllowUser,
+		},
+	}
+}
+
+// readConfig reads the config from the docker labels or from the file
+func (c *ConfigFlags) readConfig() (*Config, error) {
+	if c.DockerLabelsConfig {
+		return readDockerLabelsConfig(c.dockerLabelsOptions())
+	}
+
+	return readConfigFile(c.ConfigFile)
+}
+
 func (c *DaemonCommand) start() error {
+	if err := c.setExternalURL(); err != nil {
+		return err
+	}
+
+	// the executions listed and read by the gRPC API and the federation
+	if (c.GRPC || len(c.FederationPeers) > 0) && c.scheduler.Recent == nil {
+		c.scheduler.Recent = core.NewRecentExecutions(core.DefaultRecentExecutions)
+	}
+
 	c.setSignals()
+	c.stopDebug = handleDebugSignals(c.scheduler)
 	if err := c.scheduler.Start(); err != nil {
 		return err
 	}
 
+	middlewares.AnnounceStart(c.scheduler)
+
+	if c.DockerLabelsConfig && c.DockerPollInterval > 0 {
+		r, err := newLabelsReconciler(c.scheduler, c.dockerLabelsOptions())
+		if err != nil {
+			return err
+		}
+
+		c.reconciler = r
+		c.reconciler.Start(c.DockerPollInterval)
+	}
+
+	if c.git != nil {
+		applier, err := newJobSetApplier(c.scheduler, c.config, "Git sync")
+		if err != nil {
+			return err
+		}
+
+		c.git.applier = applier
+		c.git.Start(c.GitInterval)
+	}
+
+	if c.kv != nil {
+		applier, err := newJobSetApplier(c.scheduler, c.config, "KV sync")
+		if err != nil {
+			return err
+		}
+
+		c.kv.applier = applier
+		c.kv.Start()
+		if c.KVToken != "" && c.scheduler.Redactor != nil {
+			c.scheduler.Redactor.AddSecret(c.KVToken)
+		}
+	}
+
+	if c.API || c.Socket || c.GRPC || len(c.FederationPeers) > 0 {
+		return c.startAPI()
+	}
+
+	return nil
+}
+
+// setExternalURL makes the notifications link to the executions, kept in
+// memory to be read from the HTTP API
+func (c *DaemonCommand) setExternalURL() error {
+	if c.APIExternalURL == "" {
+		return nil
+	}
+
+	if !c.API {
+		return fmt.Errorf("api-external-url requires the HTTP API, enabled with --api")
+	}
+
+	if u, err := url.Parse(c.APIExternalURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid api-external-url %q, must be an absolute URL", c.APIExternalURL)
+	}
+
+	c.scheduler.ExternalURL = c.APIExternalURL
+	c.scheduler.Recent = core.NewRecentExecutions(core.DefaultRecentExecutions)
+	return nil
+}
+
+func (c *DaemonCommand) startAPI() error {
+	srv, err := api.NewServer(c.scheduler, &api.Config{
+		Addr:        c.APIAddr,
+		Users:       c.APIUsers,
+		Tokens:      c.APITokens,
+		TLSCert:     c.APITLSCert,
+		TLSKey:      c.APITLSKey,
+		TLSClientCA: c.APITLSClientCA,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the applied job sets would be reverted by the docker labels reconciliation
+	// and the syncs of the config
+	if c.reconciler == nil && c.git == nil && c.kv == nil {
+		applier, err := newJobSetApplier(c.scheduler, c.config, "Apply")
+		if err != nil {
+			return err
+		}
+
+		srv.Applier = applier.Apply
+	}
+
+	if len(c.FederationPeers) > 0 {
+		if !c.API && !c.Socket {
+			return fmt.Errorf("federation-peer requires the HTTP API or the socket, enabled with --api or --socket")
+		}
+
+		name := c.FederationName
+		if name == "" {
+			name = hostname()
+		}
+
+		srv.Federation, err = api.NewFederation(name, c.FederationPeers, c.FederationToken)
+		if err != nil {
+			return err
+		}
+
+		if c.FederationToken != "" && c.scheduler.Redactor != nil {
+			c.scheduler.Redactor.AddSecret(c.FederationToken)
+		}
+	}
+
+	if c.scheduler.Redactor != nil {
+		for _, secret := range srv.Secrets() {
+			c.scheduler.Redactor.AddSecret(secret)
+		}
+	}
+
+	if c.API {
+		if err := srv.Start(); err != nil {
+			return err
+		}
+	}
+
+	if c.GRPC {
+		if err := srv.StartGRPC(c.GRPCAddr); err != nil {
+			srv.Stop()
+			return err
+		}
+	}
+
+	if c.Socket {
+		if err := srv.StartSocket(c.SocketPath); err != nil {
+			srv.Stop()
+			return err
+		}
+	}
+
+	c.api = srv
 	return nil
 }
 
@@ -75,10 +402,57 @@ func (c *DaemonCommand) setSignals() {
 
 func (c *DaemonCommand) shutdown() error {
 	<-c.done
+	c.stopDebug()
 	if !c.scheduler.IsRunning() {
 		return nil
 	}
 
+	if c.api != nil {
+		if err := c.api.Stop(); err != nil {
+			c.scheduler.Logger.Errorf("Unable to stop the API: %s", err)
+		}
+	}
+
+	if c.reconciler != nil {
+		c.reconciler.Stop()
+	}
+
+	if c.git != nil {
+		c.git.Stop()
+	}
+
+	if c.kv != nil {
+		c.kv.Stop()
+	}
+
 	c.scheduler.Logger.Warningf("Waiting running jobs.")
-	return c.scheduler.Stop()
+	if err := c.scheduler.Stop(); err != nil {
+		return err
+	}
+
+	middlewares.AnnounceStop(c.scheduler)
+
+	if h, ok := c.scheduler.History.(io.Closer); ok {
+		if err := h.Close(); err != nil {
+			c.scheduler.Logger.Errorf("Unable to close the history: %s", err)
+		}
+	}
+
+	if p, ok := c.scheduler.Publisher.(io.Closer); ok {
+		if err := p.Close(); err != nil {
+			c.scheduler.Logger.Errorf("Unable to close the publisher: %s", err)
+		}
+	}
+
+	if s, ok := c.scheduler.Subscriber.(io.Closer); ok {
+		if err := s.Close(); err != nil {
+			c.scheduler.Logger.Errorf("Unable to close the subscriber: %s", err)
+		}
+	}
+
+	if c.scheduler.Queue != nil {
+		return c.scheduler.Queue.Close()
+	}
+
+	return nil
 }
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// maxStackSize max size of the goroutine stacks dumped
+const maxStackSize = 64 << 20
+
+// handleDebugSignals dumps the state of the scheduler on SIGUSR1 and toggles
+// the debug logging on SIGUSR2, until the returned function is called
+func handleDebugSignals(sched *core.Scheduler) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for sig := range signals {
+			switch sig {
+			case syscall.SIGUSR1:
+				dumpState(sched)
+			case syscall.SIGUSR2:
+				toggleDebugLogging(sched.Logger)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
+
+// dumpState logs the jobs, the executions in progress and the goroutine
+// stacks
+func dumpState(sched *core.Scheduler) {
+	state := sched.State()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "State dump, running: %t, %d jobs, %d executions in progress\n",
+		state.Running, len(state.Jobs), len(state.Executions),
+	)
+
+	for _, j := range state.Jobs {
+		fmt.Fprintf(&b, "Job %q - schedule: %q - running: %d - prev: %s - next: %s",
+			j.Name, j.Schedule, j.Running, formatStateTime(j.Prev), formatStateTime(j.Next),
+		)
+
+		if !j.Orphaned.IsZero() {
+			fmt.Fprintf(&b, " - orphaned: %s", formatStateTime(j.Orphaned))
+		}
+
+		b.WriteString("\n")
+	}
+
+	for _, e := range state.Executions {
+		fmt.Fprintf(&b, "Execution %s of job %q - started: %s - elapsed: %s",
+			e.ID, e.Job, formatStateTime(e.Date), e.Elapsed,
+		)
+
+		if v, ok := e.Params[core.MatrixParam]; ok {
+			fmt.Fprintf(&b, " - matrix: %q", v)
+		}
+
+		b.WriteString("\n")
+	}
+
+	b.WriteString("Goroutines:\n")
+	b.Write(goroutineStacks())
+
+	sched.Logger.Noticef("%s", b.String())
+}
+
+func formatStateTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// goroutineStacks returns the stacks of all the goroutines
+func goroutineStacks() []byte {
+	for size := 1 << 16; ; size *= 2 {
+		buf := make([]byte, size)
+		if n := runtime.Stack(buf, true); n < size || size >= maxStackSize {
+			return buf[:n]
+		}
+	}
+}
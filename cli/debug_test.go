@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDebug struct{}
+
+var _ = Suite(&SuiteDebug{})
+
+type noticeLogger struct {
+	core.Logger
+	notices []string
+}
+
+func (l *noticeLogger) Noticef(format string, args ...interface{}) {
+	l.notices = append(l.notices, fmt.Sprintf(format, args...))
+}
+
+func (s *SuiteDebug) TestDumpState(c *C) {
+	sched, err := BuildFromString(`
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+	`)
+	c.Assert(err, IsNil)
+
+	logger := &noticeLogger{Logger: sched.Logger}
+	sched.Logger = logger
+
+	dumpState(sched)
+	c.Assert(logger.notices, HasLen, 1)
+	c.Assert(logger.notices[0], Matches, `(?s)State dump, running: false, 1 jobs, 0 executions in progress
+Job "foo" - schedule: "@hourly" - running: 0 - prev: - - next: -
+Goroutines:
+goroutine .*TestDumpState.*`)
+}
@@ -3,22 +3,127 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/mcuadros/ofelia/core"
 	"github.com/mitchellh/mapstructure"
 )
 
 const (
 	labelPrefix = "ofelia"
 
-	requiredLabelName   = labelPrefix + ".enabled"
-	requiredLabelFilter = requiredLabelName + "=true"
-	serviceLabelName    = labelPrefix + ".service"
+	requiredLabelName = labelPrefix + ".enabled"
+	serviceLabelName  = labelPrefix + ".service"
+	// ownerLabelName identifies the owner of the jobs declared by a container
+	ownerLabelName = labelPrefix + ".owner"
+	ownerParamName = "owner"
+
+	// params of the provenance of the jobs, set from the container declaring
+	// them, never from its labels
+	sourceContainerParamName = "source-container"
+	sourceServiceParamName   = "source-service"
+	sourceProjectParamName   = "source-project"
+	// dockerLabelsParamName marks the jobs declared by docker labels, never
+	// unset by their labels
+	dockerLabelsParamName = "docker-labels"
+
+	// labels identifying the replicas of a same service
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	swarmServiceLabel   = "com.docker.swarm.service.name"
+
+	// replicaParamName job-exec param choosing the replica where the command
+	// runs, by default the first one by name, with "any" the replica is chosen
+	// at every execution
+	replicaParamName = "replica"
+	replicaAny       = "any"
+
+	defaultDiscoveryWorkers = 8
+	// discoveryProgressInterval minimum time between two progress reports
+	// while inspecting containers
+	discoveryProgressInterval = 2 * time.Second
 )
 
-func getLabels(d *docker.Client) (map[string]map[string]string, error) {
+// DockerLabelsOptions configures how the configuration is read from the labels
+type DockerLabelsOptions struct {
+	// Prefixes of the labels, by default "ofelia". When a label is provided
+	// with several prefixes, the first prefix in the list takes precedence.
+	Prefixes []string
+	// Filters label filters that the containers must match, e.g. "foo=bar"
+	Filters []string
+	// Workers max number of containers inspected at the same time, when the
+	// labels are not included in the list of containers, by default 8
+	Workers int
+	// Logger when set, reports the progress of the discovery
+	Logger core.Logger
+	// OwnerLabel container label identifying the owner of its jobs, when the
+	// container has no owner label, by default the compose project
+	OwnerLabel string
+	// Policy restricts the jobs declared by the labels
+	Policy LabelPolicy
+}
+
+func (o *DockerLabelsOptions) ownerLabel() string {
+	if o.OwnerLabel == "" {
+		return composeProjectLabel
+	}
+
+	return o.OwnerLabel
+}
+
+func (o *DockerLabelsOptions) prefixes() []string {
+	if len(o.Prefixes) == 0 {
+		return []string{labelPrefix}
+	}
+
+	return o.Prefixes
+}
+
+func (o *DockerLabelsOptions) workers() int {
+	if o.Workers <= 0 {
+		return defaultDiscoveryWorkers
+	}
+
+	return o.Workers
+}
+
+func (o *DockerLabelsOptions) logf(format string, args ...interface{}) {
+	if o.Logger != nil {
+		o.Logger.Noticef(format, args...)
+	}
+}
+
+// getLabels returns the labels of the containers with the required label and
+// matching all the label filters. The labels are returned with the default
+// prefix, whatever the prefix they were declared with.
+func getLabels(d *docker.Client, opts DockerLabelsOptions) (map[string]map[string]string, error) {
+	labels, found, err := readLabels(d, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if found == 0 {
+		var required []string
+		for _, prefix := range opts.prefixes() {
+			required = append(required, prefix+".enabled=true")
+		}
+
+		return nil, fmt.Errorf(
+			"couldn't find containers with label '%s'",
+			strings.Join(required, "' or '"),
+		)
+	}
+
+	return labels, nil
+}
+
+// readLabels is like getLabels, but not failing when no container is found,
+// the number of containers found is returned instead
+func readLabels(d *docker.Client, opts DockerLabelsOptions) (map[string]map[string]string, int, error) {
 	// sleep before querying containers
 	// because docker not always propagating labels in time
 	// so ofelia app can't find it's own container
@@ -26,37 +131,150 @@ func getLabels(d *docker.Client) (map[string]map[string]string, error) {
 		time.Sleep(1 * time.Second)
 	}
 
-	conts, err := d.ListContainers(docker.ListContainersOptions{
-		Filters: map[string][]string{
-			"label": {requiredLabelFilter},
-		},
-	})
-	if err != nil {
-		return nil, err
+	var labels = make(map[string]map[string]string)
+	var found int
+	start := time.Now()
+
+	// in reverse order, so the labels of the first prefixes overwrite the rest
+	prefixes := opts.prefixes()
+	for i := len(prefixes) - 1; i >= 0; i-- {
+		prefix := prefixes[i]
+		filter := prefix + ".enabled=true"
+
+		conts, err := d.ListContainers(docker.ListContainersOptions{
+			Filters: map[string][]string{
+				"label": append([]string{filter}, opts.Filters...),
+			},
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		found += len(conts)
+		read, err := readContainers(d, conts, prefix, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, c := range read {
+			if c.name == "" {
+				continue
+			}
+
+			if _, ok := labels[c.name]; !ok {
+				labels[c.name] = make(map[string]string)
+			}
+
+			for k, v := range c.labels {
+				labels[c.name][k] = v
+			}
+
+			if _, ok := labels[c.name][ownerLabelName]; !ok && c.owner != "" {
+				labels[c.name][ownerLabelName] = c.owner
+			}
+		}
 	}
 
+	opts.logf("Read the labels of %d containers in %s", found, time.Since(start))
+	return labels, found, nil
+}
+
+// containerLabels labels of a container, with the default prefix, and its
+// owner from the owner label
+type containerLabels struct {
+	name   string
+	labels map[string]string
+	owner  string
+}
+
+// readContainers inspects the given containers, listed with the given prefix,
+// with a bounded pool of workers, returning their labels in the same order.
+// The containers removed since listed are skipped, with no name.
+func readContainers(d *docker.Client, conts []docker.APIContainers, prefix string, opts DockerLabelsOptions) ([]containerLabels, error) {
+	read := make([]containerLabels, len(conts))
 	if len(conts) == 0 {
-		return nil, fmt.Errorf("couldn't find containers with label '%s'", requiredLabelFilter)
+		return read, nil
 	}
 
-	var labels = make(map[string]map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	var inspected int
+	last := time.Now()
 
-	for _, c := range conts {
-		if len(c.Names) > 0 && len(c.Labels) > 0 {
-			name := strings.TrimPrefix(c.Names[0], "/")
-			for k := range c.Labels {
-				// Remove all irrelevant labels
-				if !strings.HasPrefix(k, labelPrefix) {
-					delete(c.Labels, k)
-					continue
+	indexes := make(chan int)
+	for w := 0; w < opts.workers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				cont, err := d.InspectContainerWithOptions(docker.InspectContainerOptions{
+					ID: conts[i].ID,
+				})
+
+				if _, ok := err.(*docker.NoSuchContainer); ok {
+					err = nil
+				} else if err == nil {
+					read[i] = readContainer(prefix, cont, opts)
+				}
+
+				mu.Lock()
+				inspected++
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+
+				if time.Since(last) >= discoveryProgressInterval {
+					last = time.Now()
+					opts.logf("Inspected %d/%d containers", inspected, len(conts))
 				}
+				mu.Unlock()
 			}
+		}()
+	}
 
-			labels[name] = c.Labels
+	for i := range conts {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("error inspecting containers: %s", firstErr)
+	}
+
+	return read, nil
+}
+
+// readContainer returns the labels of the given inspected container, declared
+// with the given prefix
+func readContainer(prefix string, cont *docker.Container, opts DockerLabelsOptions) containerLabels {
+	if cont.Config == nil || len(cont.Config.Labels) == 0 {
+		return containerLabels{}
+	}
+
+	return containerLabels{
+		name:   strings.TrimPrefix(cont.Name, "/"),
+		labels: canonicalLabels(prefix, cont.Config.Labels),
+		owner:  cont.Config.Labels[opts.ownerLabel()],
+	}
+}
+
+// canonicalLabels returns the relevant labels, replacing the given prefix with
+// the default one, all the irrelevant labels are removed
+func canonicalLabels(prefix string, labels map[string]string) map[string]string {
+	canonical := make(map[string]string)
+	for k, v := range labels {
+		switch {
+		case isReplicaLabel(k):
+			canonical[k] = v
+		case strings.HasPrefix(k, prefix+"."):
+			canonical[labelPrefix+strings.TrimPrefix(k, prefix)] = v
 		}
 	}
 
-	return labels, nil
+	return canonical
 }
 
 func (c *Config) buildFromDockerLabels(labels map[string]map[string]string) error {
@@ -64,20 +282,43 @@ func (c *Config) buildFromDockerLabels(labels map[string]map[string]string) erro
 	localJobs := make(map[string]map[string]interface{})
 	runJobs := make(map[string]map[string]interface{})
 	serviceJobs := make(map[string]map[string]interface{})
+	pipelineJobs := make(map[string]map[string]interface{})
+	backupJobs := make(map[string]map[string]interface{})
+	ecsJobs := make(map[string]map[string]interface{})
+	nomadJobs := make(map[string]map[string]interface{})
 	globalConfigs := make(map[string]interface{})
+	groups := make(map[string]map[string]interface{})
+	replicatedJobs := make(map[string]map[string]*replicatedJob)
 
 	jobTypes := map[string]map[string]map[string]interface{}{
 		jobExec:       execJobs,
 		jobLocal:      localJobs,
 		jobRun:        runJobs,
 		jobServiceRun: serviceJobs,
+		jobPipeline:   pipelineJobs,
+		jobBackup:     backupJobs,
+		jobECS:        ecsJobs,
+		jobNomad:      nomadJobs,
+	}
+
+	var customTypes []string
+	for _, t := range core.JobTypes() {
+		if !isBuiltinJobType(t) {
+			jobTypes[t] = make(map[string]map[string]interface{})
+			customTypes = append(customTypes, t)
+		}
 	}
 
 	for containerName, containerLabels := range labels {
 		serviceLabelValue, hasServiceLabel := containerLabels[serviceLabelName]
 		isServiceContainer := hasServiceLabel && serviceLabelValue == "true"
+		owner := containerLabels[ownerLabelName]
 
 		for labelName, labelValue := range containerLabels {
+			if isReplicaLabel(labelName) || labelName == ownerLabelName {
+				continue
+			}
+
 			selectors := strings.Split(labelName, ".")
 
 			// Handle short labels
@@ -97,15 +338,46 @@ func (c *Config) buildFromDockerLabels(labels map[string]map[string]string) erro
 
 			// Only job exec can be provided on the non-service container
 			if jobType == jobExec {
-				if _, ok := execJobs[jobName]; !ok {
-					execJobs[jobName] = make(map[string]interface{})
+				if isServiceContainer {
+					if _, ok := execJobs[jobName]; !ok {
+						execJobs[jobName] = make(map[string]interface{})
+					}
+
+					setJobParam(execJobs[jobName], jobParam, labelValue)
+					setOwner(execJobs[jobName], owner)
+					setSource(execJobs[jobName], containerName, containerLabels)
+					continue
 				}
 
-				setJobParam(execJobs[jobName], jobParam, labelValue)
 				// Since this label was placed not on the service container
-				// this means we need to `exec` command in this container
-				if !isServiceContainer {
-					execJobs[jobName]["container"] = containerName
+				// this means we need to `exec` command in this container, or
+				// in any of its replicas
+				scope, filter := replicaScope(containerName, containerLabels)
+				if _, ok := replicatedJobs[jobName]; !ok {
+					replicatedJobs[jobName] = make(map[string]*replicatedJob)
+				}
+
+				r, ok := replicatedJobs[jobName][scope]
+				if !ok {
+					r = newReplicatedJob(filter)
+					replicatedJobs[jobName][scope] = r
+				}
+
+				r.containers[containerName] = true
+				setJobParam(r.params, jobParam, labelValue)
+				setOwner(r.params, owner)
+				// the container is known once the replica is chosen
+				setSource(r.params, "", containerLabels)
+				continue
+			}
+
+			if jobType == groupSection {
+				if isServiceContainer {
+					if _, ok := groups[jobName]; !ok {
+						groups[jobName] = make(map[string]interface{})
+					}
+
+					setJobParam(groups[jobName], jobParam, labelValue)
 				}
 
 				continue
@@ -118,17 +390,59 @@ func (c *Config) buildFromDockerLabels(labels map[string]map[string]string) erro
 						jobMap[jobName] = make(map[string]interface{})
 					}
 					setJobParam(jobMap[jobName], jobParam, labelValue)
+					setOwner(jobMap[jobName], owner)
+					setSource(jobMap[jobName], containerName, containerLabels)
 				}
 			}
 		}
 	}
 
+	for jobName, scopes := range replicatedJobs {
+		for scope, r := range scopes {
+			// the same job declared by different services is qualified with
+			// the scope, to keep them apart
+			name := jobName
+			if _, exists := execJobs[name]; exists || len(scopes) > 1 {
+				name = scope + "." + jobName
+			}
+
+			execJobs[name] = r.build()
+		}
+	}
+
+	for _, t := range customTypes {
+		for name, params := range jobTypes[t] {
+			if c.custom == nil {
+				c.custom = make(sectionParams)
+			}
+
+			c.custom.set(t, name, "", nil)
+			for k, v := range params {
+				c.custom[t][name][k] = v
+			}
+		}
+	}
+
+	c.extractPluginParams(globalSection, map[string]map[string]interface{}{"": globalConfigs})
+	c.extractPluginParams(groupSection, groups)
+	for t, jobs := range jobTypes {
+		if isBuiltinJobType(t) {
+			c.extractPluginParams(t, jobs)
+		}
+	}
+
 	if len(globalConfigs) > 0 {
 		if err := mapstructure.WeakDecode(globalConfigs, &c.Global); err != nil {
 			return err
 		}
 	}
 
+	if len(groups) > 0 {
+		if err := mapstructure.WeakDecode(groups, &c.Groups); err != nil {
+			return err
+		}
+	}
+
 	if len(execJobs) > 0 {
 		if err := mapstructure.WeakDecode(execJobs, &c.ExecJobs); err != nil {
 			return err
@@ -153,12 +467,94 @@ func (c *Config) buildFromDockerLabels(labels map[string]map[string]string) erro
 		}
 	}
 
+	if len(pipelineJobs) > 0 {
+		if err := mapstructure.WeakDecode(pipelineJobs, &c.PipelineJobs); err != nil {
+			return err
+		}
+	}
+
+	if len(backupJobs) > 0 {
+		if err := mapstructure.WeakDecode(backupJobs, &c.BackupJobs); err != nil {
+			return err
+		}
+	}
+
+	if len(ecsJobs) > 0 {
+		if err := mapstructure.WeakDecode(ecsJobs, &c.ECSJobs); err != nil {
+			return err
+		}
+	}
+
+	if len(nomadJobs) > 0 {
+		if err := mapstructure.WeakDecode(nomadJobs, &c.NomadJobs); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// extractPluginParams moves to the extra params of the config the params of
+// the middlewares not known by the given built-in section
+func (c *Config) extractPluginParams(section string, jobs map[string]map[string]interface{}) {
+	plugin := pluginParams(section)
+	for name, params := range jobs {
+		for k, v := range params {
+			if !plugin[k] {
+				continue
+			}
+
+			if c.extra == nil {
+				c.extra = make(sectionParams)
+			}
+
+			c.extra.set(section, name, "", nil)
+			c.extra[section][name][k] = v
+			delete(params, k)
+		}
+	}
+}
+
+// setOwner sets the owner of the job to the owner of the container declaring
+// it, if any, never to an owner set by its labels
+func setOwner(params map[string]interface{}, owner string) {
+	if owner != "" {
+		params[ownerParamName] = owner
+	} else {
+		delete(params, ownerParamName)
+	}
+}
+
+// setSource sets the provenance of the job to the given container declaring
+// it, with its compose or swarm service and compose project, replacing the
+// ones set by its labels, marking it as declared by docker labels
+func setSource(params map[string]interface{}, container string, labels map[string]string) {
+	params[dockerLabelsParamName] = true
+	service := labels[swarmServiceLabel]
+	if service == "" {
+		service = labels[composeServiceLabel]
+	}
+
+	for name, value := range map[string]string{
+		sourceContainerParamName: container,
+		sourceServiceParamName:   service,
+		sourceProjectParamName:   labels[composeProjectLabel],
+	} {
+		if value != "" {
+			params[name] = value
+		} else {
+			delete(params, name)
+		}
+	}
+}
+
 func setJobParam(params map[string]interface{}, paramName, paramVal string) {
-	if paramName == "volume" {
-		arr := []string{} // Allow providing JSON arr of volume mounts
+	switch paramName {
+	case ownerParamName:
+		// the owner is the one of the container, see setOwner
+		return
+	case "volume", "steps", "continue-on-error", "matrix", "container-label", "command-array", "tags", "notify-only-tags", "notify-exclude-tags", "hosts":
+		arr := []string{} // Allow providing JSON arr of list params
 		if err := json.Unmarshal([]byte(paramVal), &arr); err == nil {
 			params[paramName] = arr
 			return
@@ -167,3 +563,62 @@ func setJobParam(params map[string]interface{}, paramName, paramVal string) {
 
 	params[paramName] = paramVal
 }
+
+// replicatedJob is a job-exec declared by all the replicas of a service, the
+// replicas share the same labels so a single job is scheduled for all of them
+type replicatedJob struct {
+	params     map[string]interface{}
+	containers map[string]bool
+	filter     []string
+}
+
+func newReplicatedJob(filter []string) *replicatedJob {
+	return &replicatedJob{
+		params:     make(map[string]interface{}),
+		containers: make(map[string]bool),
+		filter:     filter,
+	}
+}
+
+func (r *replicatedJob) build() map[string]interface{} {
+	replica := r.params[replicaParamName]
+	delete(r.params, replicaParamName)
+
+	if replica == replicaAny && len(r.filter) > 0 {
+		r.params["container-label"] = r.filter
+		return r.params
+	}
+
+	var names []string
+	for name := range r.containers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	r.params["container"] = names[0]
+	r.params[sourceContainerParamName] = names[0]
+	return r.params
+}
+
+// replicaScope returns the name of the service the container is replica of,
+// and the label filter matching all its replicas. A container not belonging to
+// a service is its own scope.
+func replicaScope(containerName string, labels map[string]string) (string, []string) {
+	if service, ok := labels[swarmServiceLabel]; ok {
+		return service, []string{swarmServiceLabel + "=" + service}
+	}
+
+	if service, ok := labels[composeServiceLabel]; ok {
+		project := labels[composeProjectLabel]
+		return project + "_" + service, []string{
+			composeProjectLabel + "=" + project,
+			composeServiceLabel + "=" + service,
+		}
+	}
+
+	return containerName, nil
+}
+
+func isReplicaLabel(name string) bool {
+	return name == composeProjectLabel || name == composeServiceLabel || name == swarmServiceLabel
+}
@@ -0,0 +1,366 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	defaults "github.com/mcuadros/go-defaults"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	dumpFormatINI  = "ini"
+	dumpFormatYAML = "yaml"
+)
+
+// ConfigCommand groups the commands about the config
+type ConfigCommand struct{}
+
+// ConfigDumpCommand prints the config as read by the daemon, with the defaults
+// applied, the crontab sections translated and the labels of all the
+// containers merged
+type ConfigDumpCommand struct {
+	ConfigFlags
+	Format      string `long:"format" description:"output format" choice:"ini" choice:"yaml" default:"ini"`
+	ShowSecrets bool   `long:"show-secrets" description:"print the values of the secret options, masked by default"`
+}
+
+// Execute runs the config dump command
+func (c *ConfigDumpCommand) Execute(args []string) error {
+	config, err := c.readConfig()
+	if err != nil {
+		return err
+	}
+
+	return config.dump(os.Stdout, c.Format, c.ShowSecrets)
+}
+
+// dumpSection a section of the config, with its params in order
+type dumpSection struct {
+	Type   string
+	Name   string
+	Params []dumpParam
+}
+
+// dumpParam a param of a section, the value is a string, a bool, a number or
+// a slice of them
+type dumpParam struct {
+	Key   string
+	Value interface{}
+}
+
+func (config *Config) dump(w io.Writer, format string, secrets bool) error {
+	sections, err := config.dumpSections(secrets)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case dumpFormatINI:
+		writeINISections(w, sections)
+		return nil
+	case dumpFormatYAML:
+		return writeYAMLSections(w, sections)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// dumpSections returns the sections of the config with the defaults applied,
+// the global one first, then the groups and the jobs sorted by type and name. The zero values
+// are omitted, the secrets masked unless secrets is true.
+func (config *Config) dumpSections(secrets bool) ([]dumpSection, error) {
+	defaults.SetDefaults(config)
+	sections := []dumpSection{{
+		Type:   globalSection,
+		Params: config.dumpParams(globalSection, "", &config.Global, secrets),
+	}}
+
+	for _, notifier := range sortedKeys(reflect.ValueOf(config.notifiers)) {
+		for _, name := range sortedKeys(reflect.ValueOf(config.notifiers[notifier])) {
+			params, err := dumpNotifierParams(notifier, config.notifiers.get(notifier, name), secrets)
+			if err != nil {
+				return nil, fmt.Errorf("%s %q: %s", notifier, name, err)
+			}
+
+			sections = append(sections, dumpSection{Type: notifier, Name: name, Params: params})
+		}
+	}
+
+	named, err := config.buildNotifiers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range []struct {
+		name string
+		jobs interface{}
+	}{
+		{groupSection, config.Groups},
+		{jobExec, config.ExecJobs},
+		{jobRun, config.RunJobs},
+		{jobServiceRun, config.ServiceJobs},
+		{jobLocal, config.LocalJobs},
+		{jobPipeline, config.PipelineJobs},
+		{jobBackup, config.BackupJobs},
+		{jobECS, config.ECSJobs},
+		{jobNomad, config.NomadJobs},
+	} {
+		jobs := reflect.ValueOf(t.jobs)
+		for _, name := range sortedKeys(jobs) {
+			job := jobs.MapIndex(reflect.ValueOf(name)).Interface()
+			defaults.SetDefaults(job)
+			sections = append(sections, dumpSection{
+				Type:   t.name,
+				Name:   name,
+				Params: config.dumpParams(t.name, name, job, secrets),
+			})
+		}
+	}
+
+	for _, t := range sortedKeys(reflect.ValueOf(config.custom)) {
+		for _, name := range sortedKeys(reflect.ValueOf(config.custom[t])) {
+			params := config.custom.get(t, name)
+			job, err := buildCustomJob(t, name, params, named)
+			if err != nil {
+				return nil, err
+			}
+
+			// the params of the middlewares follow the fields of the job
+			var ps []dumpParam
+			appendStructParams(&ps, reflect.ValueOf(job), secrets)
+			for _, p := range dumpMap(params) {
+				if !hasDumpParam(ps, p.Key) {
+					ps = append(ps, p)
+				}
+			}
+
+			sections = append(sections, dumpSection{Type: t, Name: name, Params: ps})
+		}
+	}
+
+	return sections, nil
+}
+
+// dumpNotifierParams returns the params of a named notifier, decoded as the
+// config of the notifier, with its defaults
+func dumpNotifierParams(notifier string, params map[string]interface{}, secrets bool) ([]dumpParam, error) {
+	var ps []dumpParam
+	for _, p := range middlewares.Plugins() {
+		if p.Name != notifier {
+			continue
+		}
+
+		decode := middlewareDecoder(&struct{}{}, params)
+		for _, c := range []interface{}{p.Config(), &middlewares.NotifyConfig{}} {
+			if err := decode(c); err != nil {
+				return nil, err
+			}
+
+			appendStructParams(&ps, reflect.ValueOf(c), secrets)
+		}
+	}
+
+	return ps, nil
+}
+
+func hasDumpParam(params []dumpParam, key string) bool {
+	for _, p := range params {
+		if p.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dumpParams returns the params of the given section, the fields of the struct
+// followed by the extra params of the middlewares
+func (config *Config) dumpParams(section, name string, v interface{}, secrets bool) []dumpParam {
+	var params []dumpParam
+	appendStructParams(&params, reflect.ValueOf(v), secrets)
+
+	return append(params, dumpMap(config.extra.get(section, name))...)
+}
+
+// appendStructParams appends the fields of the given struct, named as read by
+// gcfg or mapstructure, including the fields of its embedded structs
+func appendStructParams(params *[]dumpParam, v reflect.Value, secrets bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		f, field := t.Field(i), v.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if f.Anonymous {
+			if f.Type.Kind() == reflect.Struct {
+				appendStructParams(params, field, secrets)
+			}
+
+			continue
+		}
+
+		key := strings.Split(f.Tag.Get("gcfg"), ",")[0]
+		if key == "" {
+			key = strings.Split(f.Tag.Get("mapstructure"), ",")[0]
+		}
+
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+
+		if key == "name" || key == "-" {
+			continue
+		}
+
+		value, ok := dumpValue(field)
+		if !ok {
+			continue
+		}
+
+		if f.Tag.Get("secret") == "true" && !secrets {
+			value = core.RedactedMask
+		}
+
+		*params = append(*params, dumpParam{Key: key, Value: value})
+	}
+}
+
+// dumpValue returns the value of the given field, false if it is a zero value
+// or a type not read from the config
+func dumpValue(v reflect.Value) (interface{}, bool) {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String(), d != 0
+	}
+
+	switch v.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		zero := reflect.Zero(v.Type()).Interface()
+		return v.Interface(), v.Interface() != zero
+	case reflect.Slice:
+		var values []interface{}
+		for i := 0; i < v.Len(); i++ {
+			if value, ok := dumpValue(v.Index(i)); ok {
+				values = append(values, value)
+			}
+		}
+
+		return values, len(values) != 0
+	}
+
+	return nil, false
+}
+
+// dumpMap returns the params of the given map, sorted by key
+func dumpMap(m map[string]interface{}) []dumpParam {
+	var params []dumpParam
+	for _, k := range sortedKeys(reflect.ValueOf(m)) {
+		if k == "name" {
+			continue
+		}
+
+		v := m[k]
+		if values, ok := v.([]string); ok {
+			list := make([]interface{}, len(values))
+			for i, value := range values {
+				list[i] = value
+			}
+
+			v = list
+		}
+
+		params = append(params, dumpParam{Key: k, Value: v})
+	}
+
+	return params
+}
+
+func sortedKeys(m reflect.Value) []string {
+	var keys []string
+	for _, k := range m.MapKeys() {
+		keys = append(keys, k.String())
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func writeINISections(w io.Writer, sections []dumpSection) {
+	for i, s := range sections {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		if s.Name == "" {
+			fmt.Fprintf(w, "[%s]\n", s.Type)
+		} else {
+			fmt.Fprintf(w, "[%s %q]\n", s.Type, s.Name)
+		}
+
+		for _, p := range s.Params {
+			values, ok := p.Value.([]interface{})
+			if !ok {
+				values = []interface{}{p.Value}
+			}
+
+			for _, v := range values {
+				fmt.Fprintf(w, "%s = %s\n", p.Key, iniValue(fmt.Sprint(v)))
+			}
+		}
+	}
+}
+
+// writeYAMLSections writes the sections as a YAML document, the global params
+// under "global" and the jobs under their type and name
+func writeYAMLSections(w io.Writer, sections []dumpSection) error {
+	var doc yaml.MapSlice
+	types := make(map[string]int)
+	for _, s := range sections {
+		params := yaml.MapSlice{}
+		for _, p := range s.Params {
+			params = append(params, yaml.MapItem{Key: p.Key, Value: p.Value})
+		}
+
+		if s.Name == "" {
+			doc = append(doc, yaml.MapItem{Key: s.Type, Value: params})
+			continue
+		}
+
+		i, ok := types[s.Type]
+		if !ok {
+			i = len(doc)
+			types[s.Type] = i
+			doc = append(doc, yaml.MapItem{Key: s.Type, Value: yaml.MapSlice{}})
+		}
+
+		jobs := doc[i].Value.(yaml.MapSlice)
+		doc[i].Value = append(jobs, yaml.MapItem{Key: s.Name, Value: params})
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
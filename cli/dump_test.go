@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDump struct{}
+
+var _ = Suite(&SuiteDump{})
+
+const dumpConfig = `
+[global]
+slack-webhook = https://hooks.example.com/secret
+
+[job-exec "foo"]
+schedule = @every 10s
+container = bar
+command = echo foo
+
+[job-local "baz"]
+schedule = @hourly
+command = echo baz
+environment = A=1
+environment = B=2
+
+[crontab]
+@daily echo qux
+`
+
+func (s *SuiteDump) TestDumpINI(c *C) {
+	config := &Config{}
+	c.Assert(config.readString(dumpConfig), IsNil)
+
+	var b bytes.Buffer
+	c.Assert(config.dump(&b, dumpFormatINI, false), IsNil)
+	c.Assert(b.String(), Equals, `[global]
+slack-webhook = *****
+job-overrides-global = true
+
+[job-exec "foo"]
+schedule = @every 10s
+command = echo foo
+container = bar
+user = root
+
+[job-local "baz"]
+schedule = @hourly
+command = echo baz
+environment = A=1
+environment = B=2
+
+[job-local "crontab-1"]
+schedule = @daily
+command = "/bin/sh -c \"echo qux\""
+`)
+
+	b.Reset()
+	c.Assert(config.dump(&b, dumpFormatINI, true), IsNil)
+
+	dumped := &Config{}
+	c.Assert(dumped.readString(b.String()), IsNil)
+	c.Assert(dumped.Global.SlackWebhook, Equals, "https://hooks.example.com/secret")
+	c.Assert(dumped.LocalJobs, HasLen, 2)
+	c.Assert(dumped.LocalJobs["baz"].Environment, DeepEquals, []string{"A=1", "B=2"})
+	c.Assert(dumped.LocalJobs["crontab-1"].Command, Equals, config.LocalJobs["crontab-1"].Command)
+}
+
+func (s *SuiteDump) TestDumpYAML(c *C) {
+	config := &Config{}
+	c.Assert(config.readString(dumpConfig), IsNil)
+
+	var b bytes.Buffer
+	c.Assert(config.dump(&b, dumpFormatYAML, false), IsNil)
+	c.Assert(b.String(), Equals, `global:
+  slack-webhook: '*****'
+  job-overrides-global: "true"
+job-exec:
+  foo:
+    schedule: '@every 10s'
+    command: echo foo
+    container: bar
+    user: root
+job-local:
+  baz:
+    schedule: '@hourly'
+    command: echo baz
+    environment:
+    - A=1
+    - B=2
+  crontab-1:
+    schedule: '@daily'
+    command: /bin/sh -c "echo qux"
+`)
+}
+
+func (s *SuiteDump) TestDumpCustom(c *C) {
+	config := &Config{}
+	c.Assert(config.readString(`
+		[job-custom-test "foo"]
+		schedule = @every 10s
+		target = bar
+		tag = a
+		tag = b
+		`), IsNil)
+
+	var b bytes.Buffer
+	c.Assert(config.dump(&b, dumpFormatINI, false), IsNil)
+	c.Assert(b.String(), Equals, `[global]
+job-overrides-global = true
+
+[job-custom-test "foo"]
+schedule = @every 10s
+target = bar
+tag = a
+tag = b
+mode = fast
+`)
+}
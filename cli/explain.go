@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	logging "github.com/op/go-logging"
+)
+
+// ExplainCommand describes a schedule and lists its next times, parsed as the
+// schedules of the jobs, without a config file
+type ExplainCommand struct {
+	Timezone string `long:"timezone" description:"time zone of the next times, e.g. Europe/Madrid, by default the local one"`
+	Count    int    `long:"count" description:"number of next times listed" default:"5"`
+}
+
+// Execute runs the explain command
+func (c *ExplainCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("the schedule is required, e.g. ofelia explain \"0 3 * * 1-5\"")
+	}
+
+	loc := time.Local
+	if c.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("invalid --timezone %q: %s", c.Timezone, err)
+		}
+	}
+
+	// the schedules not quoted are split in several args
+	e, err := core.NewScheduler(logging.MustGetLogger(logScheduler)).Explain(strings.Join(args, " "), loc, c.Count)
+	if err != nil {
+		return err
+	}
+
+	printExplanation(os.Stdout, e)
+	return nil
+}
+
+func printExplanation(out io.Writer, e *core.Explanation) {
+	fmt.Fprintf(out, "%s: %s\n", e.Schedule, e.Description)
+	if len(e.Next) == 0 {
+		fmt.Fprintln(out, "It doesn't run anymore")
+		return
+	}
+
+	fmt.Fprintf(out, "Next times (%s):\n", e.Location)
+	for _, t := range e.Next {
+		fmt.Fprintf(out, "- %s\n", t.Format(time.RFC1123Z))
+	}
+}
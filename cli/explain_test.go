@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteExplain struct{}
+
+var _ = Suite(&SuiteExplain{})
+
+func (s *SuiteExplain) TestPrintExplanation(c *C) {
+	out := &bytes.Buffer{}
+	printExplanation(out, &core.Explanation{
+		Schedule:    "0 3 * * 1-5",
+		Description: "At 03:00 on Monday through Friday",
+		Location:    "UTC",
+		Next: []time.Time{
+			time.Date(2020, 1, 6, 3, 0, 0, 0, time.UTC),
+			time.Date(2020, 1, 7, 3, 0, 0, 0, time.UTC),
+		},
+	})
+
+	c.Assert(out.String(), Equals, ""+
+		"0 3 * * 1-5: At 03:00 on Monday through Friday\n"+
+		"Next times (UTC):\n"+
+		"- Mon, 06 Jan 2020 03:00:00 +0000\n"+
+		"- Tue, 07 Jan 2020 03:00:00 +0000\n",
+	)
+
+	out.Reset()
+	printExplanation(out, &core.Explanation{Schedule: "@at 2020-01-01T00:00:00Z", Description: "Once at 2020-01-01T00:00:00Z"})
+	c.Assert(out.String(), Equals, "@at 2020-01-01T00:00:00Z: Once at 2020-01-01T00:00:00Z\nIt doesn't run anymore\n")
+}
+
+func (s *SuiteExplain) TestExecuteInvalid(c *C) {
+	c.Assert((&ExplainCommand{}).Execute(nil), ErrorMatches, "the schedule is required.*")
+	c.Assert((&ExplainCommand{Timezone: "Mars/Olympus"}).Execute([]string{"@daily"}), ErrorMatches, `invalid --timezone "Mars/Olympus".*`)
+	c.Assert((&ExplainCommand{Count: 5}).Execute([]string{"0", "3"}), NotNil)
+}
@@ -0,0 +1,253 @@
+package cli
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logging "github.com/op/go-logging"
+)
+
+const (
+	defaultFluentTag    = "ofelia"
+	defaultFluentBuffer = 1024
+	fluentDialTimeout   = 5 * time.Second
+	fluentWriteTimeout  = 10 * time.Second
+	fluentFlushInterval = time.Second
+	fluentMaxBackoff    = 30 * time.Second
+)
+
+// fluentRecord a log record waiting to be forwarded
+type fluentRecord struct {
+	time                   time.Time
+	level, module, message string
+}
+
+// fluentBackend is a logging backend forwarding the records to Fluentd or
+// fluent-bit with the forward protocol. The records are buffered, up to the
+// given size dropping the oldest ones, and sent in batches by a background
+// goroutine, reconnecting with backoff when the connection is lost.
+type fluentBackend struct {
+	network, addr, tag string
+	size               int
+
+	mu      sync.Mutex
+	records []fluentRecord
+	dropped int
+
+	wake chan struct{}
+	done chan struct{}
+	conn net.Conn
+}
+
+// newFluentBackend returns a backend for the given address, "host:port" or
+// "unix:///path/to/socket"
+func newFluentBackend(addr, tag string, size int) *fluentBackend {
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix://") {
+		network, addr = "unix", strings.TrimPrefix(addr, "unix://")
+	}
+
+	if tag == "" {
+		tag = defaultFluentTag
+	}
+
+	if size <= 0 {
+		size = defaultFluentBuffer
+	}
+
+	b := &fluentBackend{
+		network: network,
+		addr:    addr,
+		tag:     tag,
+		size:    size,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	go b.loop()
+	return b
+}
+
+// Log buffers the record, it never blocks on the network
+func (b *fluentBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	b.mu.Lock()
+	b.push(fluentRecord{rec.Time, level.String(), rec.Module, rec.Message()})
+	b.mu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// push appends the records to the buffer, dropping the oldest ones when it's
+// full, must be called with the lock held
+func (b *fluentBackend) push(records ...fluentRecord) {
+	b.records = append(b.records, records...)
+	if over := len(b.records) - b.size; over > 0 {
+		b.records = b.records[over:]
+		b.dropped += over
+	}
+}
+
+// Close stops the background goroutine, trying a last flush of the buffer
+func (b *fluentBackend) Close() {
+	close(b.done)
+}
+
+func (b *fluentBackend) loop() {
+	ticker := time.NewTicker(fluentFlushInterval)
+	defer ticker.Stop()
+
+	var backoff time.Duration
+	var retry time.Time
+	for {
+		select {
+		case <-b.done:
+			b.flush()
+			if b.conn != nil {
+				b.conn.Close()
+			}
+
+			return
+		case <-b.wake:
+		case <-ticker.C:
+		}
+
+		if time.Now().Before(retry) {
+			continue
+		}
+
+		if b.flush() {
+			backoff = 0
+			continue
+		}
+
+		backoff *= 2
+		if backoff == 0 {
+			backoff = fluentFlushInterval
+		} else if backoff > fluentMaxBackoff {
+			backoff = fluentMaxBackoff
+		}
+
+		retry = time.Now().Add(backoff)
+	}
+}
+
+// flush sends the buffered records, putting them back in the buffer if they
+// can't be sent, returns false if the connection failed
+func (b *fluentBackend) flush() bool {
+	b.mu.Lock()
+	records, dropped := b.records, b.dropped
+	b.records, b.dropped = nil, 0
+	b.mu.Unlock()
+
+	if len(records) == 0 {
+		return true
+	}
+
+	batch := records
+	if dropped > 0 {
+		batch = append([]fluentRecord{{
+			time:    time.Now(),
+			level:   logging.WARNING.String(),
+			module:  logScheduler,
+			message: "Fluent buffer full, dropped " + strconv.Itoa(dropped) + " log records",
+		}}, records...)
+	}
+
+	if err := b.send(encodeFluentForward(b.tag, batch)); err != nil {
+		if b.conn != nil {
+			b.conn.Close()
+			b.conn = nil
+		}
+
+		b.mu.Lock()
+		pending := b.records
+		b.records = nil
+		b.dropped += dropped
+		b.push(append(records, pending...)...)
+		b.mu.Unlock()
+		return false
+	}
+
+	return true
+}
+
+func (b *fluentBackend) send(msg []byte) error {
+	if b.conn == nil {
+		conn, err := net.DialTimeout(b.network, b.addr, fluentDialTimeout)
+		if err != nil {
+			return err
+		}
+
+		b.conn = conn
+	}
+
+	b.conn.SetWriteDeadline(time.Now().Add(fluentWriteTimeout))
+	_, err := b.conn.Write(msg)
+	return err
+}
+
+// encodeFluentForward encodes the records as a message of the forward mode of
+// the protocol, [tag, [[time, record], ...]], in msgpack, the times being
+// EventTime, with nanoseconds
+func encodeFluentForward(tag string, records []fluentRecord) []byte {
+	b := msgpackArray(nil, 2)
+	b = msgpackString(b, tag)
+	b = msgpackArray(b, len(records))
+	for _, r := range records {
+		b = msgpackArray(b, 2)
+		b = append(b, 0xd7, 0x00, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-8:], uint32(r.time.Unix()))
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(r.time.Nanosecond()))
+
+		b = msgpackMap(b, 3)
+		b = msgpackString(msgpackString(b, "level"), r.level)
+		b = msgpackString(msgpackString(b, "module"), r.module)
+		b = msgpackString(msgpackString(b, "message"), r.message)
+	}
+
+	return b
+}
+
+func msgpackArray(b []byte, n int) []byte {
+	return msgpackHeader(b, n, 0x90, 16, 0xdc, 0xdd)
+}
+
+func msgpackMap(b []byte, n int) []byte {
+	return msgpackHeader(b, n, 0x80, 16, 0xde, 0xdf)
+}
+
+func msgpackString(b []byte, s string) []byte {
+	if len(s) < 256 && len(s) >= 32 {
+		b = append(b, 0xd9, byte(len(s)))
+	} else {
+		b = msgpackHeader(b, len(s), 0xa0, 32, 0xda, 0xdb)
+	}
+
+	return append(b, s...)
+}
+
+// msgpackHeader appends the header of a value of the given length, in its
+// fixed format when shorter than fixed, or with a 16 or 32 bits length
+func msgpackHeader(b []byte, n int, fix byte, fixed int, code16, code32 byte) []byte {
+	switch {
+	case n < fixed:
+		return append(b, fix|byte(n))
+	case n <= 0xffff:
+		b = append(b, code16, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(n))
+	default:
+		b = append(b, code32, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(n))
+	}
+
+	return b
+}
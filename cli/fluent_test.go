@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteFluent struct{}
+
+var _ = Suite(&SuiteFluent{})
+
+func (s *SuiteFluent) TestEncodeFluentForward(c *C) {
+	msg := encodeFluentForward("ofelia", []fluentRecord{
+		{time.Unix(1, 2), "INFO", "scheduler", "foo"},
+	})
+
+	c.Assert(msg, DeepEquals, []byte{
+		0x92, 0xa6, 'o', 'f', 'e', 'l', 'i', 'a',
+		0x91, 0x92, 0xd7, 0x00, 0, 0, 0, 1, 0, 0, 0, 2,
+		0x83,
+		0xa5, 'l', 'e', 'v', 'e', 'l', 0xa4, 'I', 'N', 'F', 'O',
+		0xa6, 'm', 'o', 'd', 'u', 'l', 'e', 0xa9, 's', 'c', 'h', 'e', 'd', 'u', 'l', 'e', 'r',
+		0xa7, 'm', 'e', 's', 's', 'a', 'g', 'e', 0xa3, 'f', 'o', 'o',
+	})
+
+	c.Assert(msgpackString(nil, string(make([]byte, 40)))[:2], DeepEquals, []byte{0xd9, 40})
+	c.Assert(msgpackString(nil, string(make([]byte, 300)))[:3], DeepEquals, []byte{0xda, 0x01, 0x2c})
+	c.Assert(msgpackArray(nil, 20), DeepEquals, []byte{0xdc, 0, 20})
+}
+
+func (s *SuiteFluent) TestFluentBackendBuffer(c *C) {
+	b := &fluentBackend{size: 2}
+	b.push(fluentRecord{message: "1"}, fluentRecord{message: "2"}, fluentRecord{message: "3"})
+	c.Assert(b.records, HasLen, 2)
+	c.Assert(b.records[0].message, Equals, "2")
+	c.Assert(b.dropped, Equals, 1)
+}
+
+func (s *SuiteFluent) TestFluentBackendReconnects(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	addr := l.Addr().String()
+	l.Close()
+
+	b := newFluentBackend(addr, "test", 0)
+	defer b.Close()
+
+	b.Log(logging.NOTICE, 0, &logging.Record{Time: time.Now(), Module: logScheduler, Args: []interface{}{"buffered"}})
+
+	// the record is kept while the address can't be reached
+	time.Sleep(100 * time.Millisecond)
+	b.mu.Lock()
+	c.Assert(b.records, HasLen, 1)
+	b.mu.Unlock()
+
+	l, err = net.Listen("tcp", addr)
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := l.Accept()
+	c.Assert(err, IsNil)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg, _ := ioutil.ReadAll(conn)
+	c.Assert(bytes.Contains(msg, []byte("test")), Equals, true)
+	c.Assert(bytes.Contains(msg, []byte("buffered")), Equals, true)
+}
+
+func (s *SuiteFluent) TestFluentLogBackendReload(c *C) {
+	b := fluentLogBackend(&LogConfig{LogFluentAddr: "127.0.0.1:1"})
+	c.Assert(b, NotNil)
+	c.Assert(b.tag, Equals, defaultFluentTag)
+	c.Assert(fluentLogBackend(&LogConfig{LogFluentAddr: "127.0.0.1:1"}), Equals, b)
+
+	other := fluentLogBackend(&LogConfig{LogFluentAddr: "unix:///tmp/fluent.sock", LogFluentTag: "foo"})
+	c.Assert(other != b, Equals, true)
+	c.Assert(other.network, Equals, "unix")
+	c.Assert(other.addr, Equals, "/tmp/fluent.sock")
+
+	c.Assert(fluentLogBackend(&LogConfig{}), IsNil)
+}
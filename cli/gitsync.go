@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	defaults "github.com/mcuadros/go-defaults"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+)
+
+// gitTimeout max time of every git command
+const gitTimeout = 2 * time.Minute
+
+// GitSyncOptions where the config synced from git is read from
+type GitSyncOptions struct {
+	// URL of the repository
+	URL string
+	// Branch checked out
+	Branch string
+	// SSHKey file of the private key of the ssh URLs, if any
+	SSHKey string
+	// Path of the config file in the repository
+	Path string
+	// Dir where the repository is checked out
+	Dir string
+}
+
+// gitSync periodically pulls a git repository and reloads the jobs of the
+// config file within on every new commit, once validated
+type gitSync struct {
+	opts    GitSyncOptions
+	applier *jobSetApplier
+	// commit the commit of the jobs of the scheduler
+	commit string
+	// global the fingerprint of the global section of the config, its changes
+	// requiring a restart
+	global string
+	// failing when true, the failure of the sync of failed was announced,
+	// failed being the commit failing to be applied, empty if not checked out
+	failing bool
+	failed  string
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newGitSync(opts GitSyncOptions) *gitSync {
+	return &gitSync{
+		opts:    opts,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// configFile returns the path of the checked out config file
+func (g *gitSync) configFile() string {
+	return filepath.Join(g.opts.Dir, g.opts.Path)
+}
+
+// checkout clones the repository, or updates it to the last commit of the
+// branch, returning the commit
+func (g *gitSync) checkout() (string, error) {
+	if _, err := os.Stat(filepath.Join(g.opts.Dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(g.opts.Dir), 0755); err != nil {
+			return "", err
+		}
+
+		_, err := g.git("", "clone", "--quiet", "--depth=1", "--single-branch", "--branch="+g.opts.Branch, "--", g.opts.URL, g.opts.Dir)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := g.git(g.opts.Dir, "fetch", "--quiet", "--depth=1", "--", g.opts.URL, g.opts.Branch); err != nil {
+			return "", err
+		}
+
+		if _, err := g.git(g.opts.Dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+
+	return g.git(g.opts.Dir, "rev-parse", "HEAD")
+}
+
+// git runs the given git command in the given dir, returning its output
+func (g *gitSync) git(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if g.opts.SSHKey != "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+sshCommand(g.opts.SSHKey))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+
+		return "", fmt.Errorf("git %s: %s", args[0], err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// sshCommand returns the ssh command of git authenticating with the given key,
+// run by a shell so with the path of the key quoted
+func sshCommand(key string) string {
+	return "ssh -i " + shellQuote(key) + " -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new"
+}
+
+// shellQuote quotes the given string as a single argument of sh
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Start syncs the config every interval, until Stop is called
+func (g *gitSync) Start(interval time.Duration) {
+	go func() {
+		defer close(g.stopped)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				g.sync()
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sync, waiting for the running one to finish
+func (g *gitSync) Stop() {
+	close(g.done)
+	<-g.stopped
+}
+
+// sync pulls the repository and reloads the jobs of a new commit, recording
+// the result in the metrics and announcing the failures
+func (g *gitSync) sync() {
+	sched := g.applier.sched
+	commit, err := g.checkout()
+	if err == nil && commit != g.commit {
+		err = g.apply(commit)
+	}
+
+	if err != nil {
+		core.GitSyncFailures.Add(1)
+		sched.Logger.Errorf("Git sync failed: %s", err)
+		sched.RecordReload(&core.Reload{Error: err.Error()})
+		if !g.failing || commit != g.failed {
+			g.failing, g.failed = true, commit
+			middlewares.Announce(sched, "Ofelia config sync failed", fmt.Sprintf(
+				"Ofelia config sync failed on %s: %s", hostname(), err,
+			))
+		}
+
+		return
+	}
+
+	g.failing, g.failed = false, ""
+	core.GitSyncSuccess.Set(sched.Clock.Now().Unix())
+}
+
+// apply reloads the jobs of the config file of the given commit
+func (g *gitSync) apply(commit string) error {
+	config, err := readConfigFile(g.configFile())
+	if err != nil {
+		return fmt.Errorf("commit %s: %s", shortCommit(commit), err)
+	}
+
+	if _, err := g.applier.apply("git:"+shortCommit(commit), config, false); err != nil {
+		return fmt.Errorf("commit %s: %s", shortCommit(commit), err)
+	}
+
+	if global := fingerprintGlobal(config); global != g.global {
+		g.applier.sched.Logger.Warningf("Git sync: the [global] section changed at commit %s, applied on restart", shortCommit(commit))
+		g.global = global
+	}
+
+	g.applier.sched.Logger.Noticef("Git sync: commit %s applied", shortCommit(commit))
+	g.setCommit(commit)
+	return nil
+}
+
+func (g *gitSync) setCommit(commit string) {
+	g.commit = commit
+	core.GitSyncCommit.Set(commit)
+}
+
+// fingerprintGlobal returns a value that changes when the global section of
+// the config changes, with the defaults applied
+func fingerprintGlobal(c *Config) string {
+	global := &Config{Global: c.Global}
+	defaults.SetDefaults(global)
+
+	b, _ := json.Marshal(global.Global)
+	return string(b)
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+
+	return commit
+}
+
+func hostname() string {
+	host, _ := os.Hostname()
+	return host
+}
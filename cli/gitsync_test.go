@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteGitSync struct {
+	origin string
+}
+
+var _ = Suite(&SuiteGitSync{})
+
+func (s *SuiteGitSync) SetUpTest(c *C) {
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git not installed")
+	}
+
+	s.origin = c.MkDir()
+	s.git(c, "init", "--quiet")
+	s.git(c, "checkout", "--quiet", "-b", "main")
+}
+
+func (s *SuiteGitSync) git(c *C, args ...string) {
+	args = append([]string{"-c", "user.name=ofelia", "-c", "user.email=ofelia@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.origin
+
+	out, err := cmd.CombinedOutput()
+	c.Assert(err, IsNil, Commentf("%s", out))
+}
+
+func (s *SuiteGitSync) commit(c *C, config string) {
+	err := ioutil.WriteFile(filepath.Join(s.origin, "ofelia.ini"), []byte(config), 0644)
+	c.Assert(err, IsNil)
+
+	s.git(c, "add", "ofelia.ini")
+	s.git(c, "commit", "--quiet", "-m", "config")
+}
+
+func (s *SuiteGitSync) TestSync(c *C) {
+	s.commit(c, `
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+	`)
+
+	g := newGitSync(GitSyncOptions{
+		URL:    "file://" + s.origin,
+		Branch: "main",
+		Path:   "ofelia.ini",
+		Dir:    filepath.Join(c.MkDir(), "checkout"),
+	})
+
+	commit, err := g.checkout()
+	c.Assert(err, IsNil)
+	c.Assert(commit, HasLen, 40)
+
+	sched, err := BuildFromFile(g.configFile())
+	c.Assert(err, IsNil)
+	g.applier = &jobSetApplier{sched: sched, name: "Git sync"}
+	g.global = fingerprintGlobal(&Config{})
+	g.setCommit(commit)
+
+	audit := &testAuditLog{}
+	sched.Audit = audit
+
+	g.sync()
+	c.Assert(audit.entries, HasLen, 0)
+
+	s.commit(c, `
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo bar
+
+		[job-local "bar"]
+		schedule = @every 10s
+		command = echo bar
+	`)
+
+	g.sync()
+	c.Assert(sched.Jobs, HasLen, 2)
+	c.Assert(sched.GetJob("foo").GetCommand(), Equals, "echo bar")
+	c.Assert(g.commit, Not(Equals), commit)
+	c.Assert(core.GitSyncCommit.Value(), Equals, g.commit)
+	c.Assert(audit.entries, HasLen, 2)
+	c.Assert(audit.entries[0].Actor, Equals, "git:"+shortCommit(g.commit))
+
+	failures := core.GitSyncFailures.Value()
+	commit = g.commit
+	s.commit(c, `
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		on-success = baz
+	`)
+
+	g.sync()
+	c.Assert(g.failing, Equals, true)
+	c.Assert(g.commit, Equals, commit)
+	c.Assert(core.GitSyncFailures.Value(), Equals, failures+1)
+	c.Assert(sched.Jobs, HasLen, 2)
+	c.Assert(sched.LastReload().Error, Matches, `commit \w+: job "foo": unknown job "baz".*`)
+}
+
+func (s *SuiteGitSync) TestCheckoutInvalid(c *C) {
+	g := newGitSync(GitSyncOptions{
+		URL:    "file://" + s.origin,
+		Branch: "main",
+		Dir:    filepath.Join(c.MkDir(), "checkout"),
+	})
+
+	_, err := g.checkout()
+	c.Assert(err, ErrorMatches, "(?s)git clone: .*")
+}
+
+func (s *SuiteGitSync) TestCheckoutSSHKey(c *C) {
+	// a ssh recording the key it is given, failing the clone
+	bin := c.MkDir()
+	args := filepath.Join(bin, "args")
+	script := "#!/bin/sh\nprintf '%s' \"$2\" > " + shellQuote(args) + "\nexit 1\n"
+	c.Assert(ioutil.WriteFile(filepath.Join(bin, "ssh"), []byte(script), 0755), IsNil)
+
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+
+	key := filepath.Join(c.MkDir(), "it's a key $(touch pwned)")
+	g := newGitSync(GitSyncOptions{
+		URL:    "ssh://git@example.com/config.git",
+		Branch: "main",
+		Dir:    filepath.Join(c.MkDir(), "checkout"),
+		SSHKey: key,
+	})
+
+	_, err := g.checkout()
+	c.Assert(err, ErrorMatches, "(?s)git clone: .*")
+
+	given, err := ioutil.ReadFile(args)
+	c.Assert(err, IsNil)
+	c.Assert(string(given), Equals, key)
+}
@@ -0,0 +1,7 @@
+package cli
+
+// the drivers of the databases supported by the history
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
@@ -0,0 +1,371 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The key-value stores the jobs are read from
+const (
+	kvConsul = "consul"
+	kvEtcd   = "etcd"
+)
+
+// kvWait max time a watch of the keys waits for a change
+const kvWait = 5 * time.Minute
+
+// kvStore is a key-value store holding the params of the jobs
+type kvStore interface {
+	// Watch returns the keys under the given prefix, with their values, and
+	// the index of their version, waiting for a version newer than the given
+	// index, at most kvWait, unless the index is zero
+	Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error)
+}
+
+// newKVStore returns the store of the given backend, served at the given URL,
+// the token being the ACL token of consul or the "user:password" of etcd
+func newKVStore(backend, addr, token string) (kvStore, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid kv-addr %q, must be an absolute URL", addr)
+	}
+
+	addr = strings.TrimSuffix(addr, "/")
+	client := &http.Client{Timeout: kvWait + time.Minute}
+	switch backend {
+	case kvConsul:
+		return &consulStore{addr: addr, token: token, client: client}, nil
+	case kvEtcd:
+		s := &etcdStore{addr: addr, client: client}
+		if token != "" {
+			parts := strings.SplitN(token, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid kv-token of etcd, must be user:password")
+			}
+
+			s.user, s.password = parts[0], parts[1]
+		}
+
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("unknown kv-backend %q, must be %s or %s", backend, kvConsul, kvEtcd)
+}
+
+// consulStore reads the keys with the KV HTTP API of consul, watched with
+// blocking queries
+type consulStore struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+type consulPair struct {
+	Key   string
+	Value []byte
+}
+
+func (s *consulStore) Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error) {
+	q := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", fmt.Sprintf("%ds", int(kvWait.Seconds())))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.addr+"/v1/kv/"+prefix+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, 0, fmt.Errorf("consul: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	next, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: invalid index %q", resp.Header.Get("X-Consul-Index"))
+	}
+
+	pairs := make(map[string]string)
+	if resp.StatusCode == http.StatusNotFound {
+		return pairs, next, nil
+	}
+
+	var list []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, 0, fmt.Errorf("consul: %s", err)
+	}
+
+	for _, p := range list {
+		pairs[p.Key] = string(p.Value)
+	}
+
+	return pairs, next, nil
+}
+
+// etcdStore reads the keys with the JSON gateway of the v3 API of etcd, the
+// changes being watched before reading them again
+type etcdStore struct {
+	addr     string
+	user     string
+	password string
+	client   *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+type etcdHeader struct {
+	Revision string `json:"revision"`
+}
+
+type etcdPair struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+func (s *etcdStore) Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error) {
+	if index > 0 {
+		wait, cancel := context.WithTimeout(ctx, kvWait)
+		err := s.wait(wait, prefix, index)
+		cancel()
+
+		if err != nil && wait.Err() == nil {
+			return nil, 0, err
+		}
+	}
+
+	return s.list(ctx, prefix)
+}
+
+// list returns the keys under the prefix and the revision of the store
+func (s *etcdStore) list(ctx context.Context, prefix string) (map[string]string, uint64, error) {
+	resp := &struct {
+		Header etcdHeader `json:"header"`
+		KVs    []etcdPair `json:"kvs"`
+	}{}
+
+	err := s.post(ctx, "/v3/kv/range", map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixEnd(prefix)),
+	}, resp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	revision, err := strconv.ParseUint(resp.Header.Revision, 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("etcd: invalid revision %q", resp.Header.Revision)
+	}
+
+	pairs := make(map[string]string)
+	for _, kv := range resp.KVs {
+		pairs[string(kv.Key)] = string(kv.Value)
+	}
+
+	return pairs, revision, nil
+}
+
+// wait waits for a change of the keys under the prefix after the given
+// revision
+func (s *etcdStore) wait(ctx context.Context, prefix string, revision uint64) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"create_request": map[string]string{
+			"key":            base64.StdEncoding.EncodeToString([]byte(prefix)),
+			"range_end":      base64.StdEncoding.EncodeToString(prefixEnd(prefix)),
+			"start_revision": strconv.FormatUint(revision+1, 10),
+		},
+	})
+
+	resp, err := s.do(ctx, "/v3/watch", body)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	d := json.NewDecoder(resp.Body)
+	for {
+		msg := &struct {
+			Result struct {
+				Events []json.RawMessage `json:"events"`
+				// Canceled the revision was compacted
+				Canceled bool `json:"canceled"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+
+		if err := d.Decode(msg); err != nil {
+			return fmt.Errorf("etcd: %s", err)
+		}
+
+		if msg.Error != nil {
+			return fmt.Errorf("etcd: %s", msg.Error.Message)
+		}
+
+		if len(msg.Result.Events) > 0 || msg.Result.Canceled {
+			return nil
+		}
+	}
+}
+
+// post posts the given request to the given path, decoding the response
+func (s *etcdStore) post(ctx context.Context, path string, req, resp interface{}) error {
+	body, _ := json.Marshal(req)
+	r, err := s.do(ctx, path, body)
+	if err != nil {
+		return err
+	}
+
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+		return fmt.Errorf("etcd: %s", err)
+	}
+
+	return nil
+}
+
+// do posts the given body to the given path, authenticated if a user is set,
+// failing if the response isn't successful
+func (s *etcdStore) do(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.addr+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			// the token expired, authenticated again by the next request
+			s.mu.Lock()
+			s.token = ""
+			s.mu.Unlock()
+		}
+
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("etcd: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return resp, nil
+}
+
+// authenticate returns the token of the user, empty without user
+func (s *etcdStore) authenticate(ctx context.Context) (string, error) {
+	if s.user == "" {
+		return "", nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"name": s.user, "password": s.password})
+	req, err := http.NewRequest(http.MethodPost, s.addr+"/v3/auth/authenticate", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("etcd: authentication failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	auth := &struct {
+		Token string `json:"token"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(auth); err != nil {
+		return "", fmt.Errorf("etcd: %s", err)
+	}
+
+	s.token = auth.Token
+	return s.token, nil
+}
+
+// prefixEnd returns the end of the range of the keys with the given prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	// all the keys
+	return []byte{0}
+}
+
+// kvJobSet returns the job set of the given keys, as
+// "<prefix>/<job-type>/<job-name>/<param>", the other keys being ignored, the
+// JSON arrays being the values of the list params, as in the docker labels
+func kvJobSet(prefix string, pairs map[string]string) map[string]map[string]map[string]interface{} {
+	set := make(map[string]map[string]map[string]interface{})
+	for key, value := range pairs {
+		parts := strings.Split(strings.TrimPrefix(key, prefix), "/")
+		if !strings.HasPrefix(key, prefix) || len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			continue
+		}
+
+		jobs, ok := set[parts[0]]
+		if !ok {
+			jobs = make(map[string]map[string]interface{})
+			set[parts[0]] = jobs
+		}
+
+		params, ok := jobs[parts[1]]
+		if !ok {
+			params = make(map[string]interface{})
+			jobs[parts[1]] = params
+		}
+
+		setJobParam(params, parts[2], value)
+	}
+
+	return set
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteKV struct{}
+
+var _ = Suite(&SuiteKV{})
+
+func (s *SuiteKV) TestKVJobSet(c *C) {
+	set := kvJobSet("ofelia/", map[string]string{
+		"ofelia/job-local/backup/schedule": "@daily",
+		"ofelia/job-local/backup/tags":     `["db", "nightly"]`,
+		"ofelia/job-run/clean/image":       "alpine",
+		"ofelia/job-run/":                  "",
+		"ofelia/readme":                    "managed by terraform",
+		"other/job-local/foo/schedule":     "@daily",
+	})
+
+	c.Assert(set, DeepEquals, map[string]map[string]map[string]interface{}{
+		"job-local": {"backup": {"schedule": "@daily", "tags": []string{"db", "nightly"}}},
+		"job-run":   {"clean": {"image": "alpine"}},
+	})
+}
+
+func (s *SuiteKV) TestNewKVStoreInvalid(c *C) {
+	_, err := newKVStore("zookeeper", "http://127.0.0.1:2181", "")
+	c.Assert(err, ErrorMatches, `unknown kv-backend "zookeeper".*`)
+
+	_, err = newKVStore(kvConsul, "127.0.0.1:8500", "")
+	c.Assert(err, ErrorMatches, `invalid kv-addr .*`)
+
+	_, err = newKVStore(kvEtcd, "http://127.0.0.1:2379", "root")
+	c.Assert(err, ErrorMatches, `invalid kv-token .*`)
+}
+
+func (s *SuiteKV) TestConsulStore(c *C) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/v1/kv/ofelia/")
+		c.Assert(r.Header.Get("X-Consul-Token"), Equals, "s3cr3t")
+		queries = append(queries, r.URL.RawQuery)
+
+		w.Header().Set("X-Consul-Index", "42")
+		json.NewEncoder(w).Encode([]consulPair{{Key: "ofelia/job-local/foo/schedule", Value: []byte("@daily")}})
+	}))
+	defer srv.Close()
+
+	store, err := newKVStore(kvConsul, srv.URL, "s3cr3t")
+	c.Assert(err, IsNil)
+
+	pairs, index, err := store.Watch(context.Background(), "ofelia/", 0)
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, uint64(42))
+	c.Assert(pairs, DeepEquals, map[string]string{"ofelia/job-local/foo/schedule": "@daily"})
+
+	_, _, err = store.Watch(context.Background(), "ofelia/", 41)
+	c.Assert(err, IsNil)
+	c.Assert(queries, DeepEquals, []string{"recurse=true", "index=41&recurse=true&wait=300s"})
+}
+
+func (s *SuiteKV) TestEtcdStore(c *C) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	var watched map[string]map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/auth/authenticate" {
+			fmt.Fprint(w, `{"token": "t0k3n"}`)
+			return
+		}
+
+		c.Assert(r.Header.Get("Authorization"), Equals, "t0k3n")
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			var req map[string]string
+			c.Assert(json.NewDecoder(r.Body).Decode(&req), IsNil)
+			c.Assert(req, DeepEquals, map[string]string{"key": b64("ofelia/"), "range_end": b64("ofelia0")})
+
+			fmt.Fprintf(w, `{"header": {"revision": "7"}, "kvs": [{"key": %q, "value": %q}]}`, b64("ofelia/job-local/foo/schedule"), b64("@daily"))
+		case "/v3/watch":
+			c.Assert(json.NewDecoder(r.Body).Decode(&watched), IsNil)
+			fmt.Fprint(w, `{"result": {"created": true}}`)
+			fmt.Fprint(w, `{"result": {"events": [{"type": "PUT"}]}}`)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := newKVStore(kvEtcd, srv.URL, "root:pass")
+	c.Assert(err, IsNil)
+
+	pairs, index, err := store.Watch(context.Background(), "ofelia/", 0)
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, uint64(7))
+	c.Assert(pairs, DeepEquals, map[string]string{"ofelia/job-local/foo/schedule": "@daily"})
+	c.Assert(watched, IsNil)
+
+	_, index, err = store.Watch(context.Background(), "ofelia/", 6)
+	c.Assert(err, IsNil)
+	c.Assert(index, Equals, uint64(7))
+	c.Assert(watched["create_request"]["start_revision"], Equals, "7")
+}
+
+func (s *SuiteKV) TestPrefixEnd(c *C) {
+	c.Assert(string(prefixEnd("ofelia/")), Equals, "ofelia0")
+	c.Assert(prefixEnd("a\xff"), DeepEquals, []byte("b"))
+	c.Assert(prefixEnd(""), DeepEquals, []byte{0})
+}
+
+// memoryKV is a key-value store returning the keys at once, its index being
+// incremented by every change
+type memoryKV struct {
+	pairs map[string]string
+	index uint64
+}
+
+func (m *memoryKV) Watch(ctx context.Context, prefix string, index uint64) (map[string]string, uint64, error) {
+	return m.pairs, m.index, nil
+}
+
+func (s *SuiteKV) TestSync(c *C) {
+	file := filepath.Join(c.MkDir(), "ofelia.ini")
+	c.Assert(ioutil.WriteFile(file, []byte(`
+		[job-local "static"]
+		schedule = @every 10s
+		command = echo static
+	`), 0644), IsNil)
+
+	store := &memoryKV{index: 1, pairs: map[string]string{
+		"ofelia/job-local/foo/schedule": "@every 10s",
+		"ofelia/job-local/foo/command":  "echo foo",
+	}}
+
+	k := newKVSync(store, "/ofelia", file)
+	config, err := readConfigFile(file)
+	c.Assert(err, IsNil)
+	c.Assert(k.load(config), IsNil)
+	c.Assert(k.index, Equals, uint64(1))
+
+	sched, err := config.build()
+	c.Assert(err, IsNil)
+	c.Assert(sched.Jobs, HasLen, 2)
+
+	audit := &testAuditLog{}
+	sched.Audit = audit
+	k.applier = &jobSetApplier{sched: sched, name: "KV sync"}
+
+	c.Assert(k.sync(context.Background()), IsNil)
+	c.Assert(audit.entries, HasLen, 0)
+
+	store.index = 2
+	store.pairs["ofelia/job-local/foo/command"] = "echo bar"
+	c.Assert(k.sync(context.Background()), IsNil)
+	c.Assert(sched.Jobs, HasLen, 2)
+	c.Assert(sched.GetJob("foo").GetCommand(), Equals, "echo bar")
+	c.Assert(sched.GetJob("static"), NotNil)
+	c.Assert(audit.entries, HasLen, 1)
+	c.Assert(audit.entries[0].Actor, Equals, "kv:2")
+	c.Assert(core.KVSyncIndex.Value(), Equals, int64(2))
+
+	store.index = 3
+	store.pairs["ofelia/job-local/static/command"] = "echo foo"
+	c.Assert(k.sync(context.Background()), ErrorMatches, `ofelia/: job-local "static" already declared`)
+	c.Assert(k.index, Equals, uint64(2))
+
+	delete(store.pairs, "ofelia/job-local/static/command")
+	delete(store.pairs, "ofelia/job-local/foo/command")
+	delete(store.pairs, "ofelia/job-local/foo/schedule")
+	c.Assert(k.sync(context.Background()), IsNil)
+	c.Assert(sched.Jobs, HasLen, 1)
+	c.Assert(sched.GetJob("foo"), IsNil)
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+)
+
+// kvRetry time waited after a failed watch of the key-value store
+const kvRetry = 10 * time.Second
+
+// kvSync watches the jobs of a key-value store and reloads them on every
+// change, along with the jobs of the config file, once validated
+type kvSync struct {
+	store  kvStore
+	prefix string
+	// configFile the file of the global section and the static jobs
+	configFile string
+	applier    *jobSetApplier
+	// index the index of the keys of the jobs of the scheduler
+	index uint64
+	// failing when true, the failure of the sync was announced
+	failing bool
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func newKVSync(store kvStore, prefix, configFile string) *kvSync {
+	return &kvSync{
+		store:      store,
+		prefix:     strings.Trim(prefix, "/") + "/",
+		configFile: configFile,
+		stopped:    make(chan struct{}),
+	}
+}
+
+// load reads the jobs of the store into the given config, without waiting
+func (k *kvSync) load(config *Config) error {
+	pairs, index, err := k.store.Watch(context.Background(), k.prefix, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := k.decode(config, pairs); err != nil {
+		return err
+	}
+
+	k.setIndex(index)
+	return nil
+}
+
+// decode adds the jobs of the given keys to the config
+func (k *kvSync) decode(config *Config, pairs map[string]string) error {
+	if err := config.decodeJobSet(kvJobSet(k.prefix, pairs)); err != nil {
+		return fmt.Errorf("%s: %s", k.prefix, err)
+	}
+
+	return nil
+}
+
+// Start watches the store, until Stop is called
+func (k *kvSync) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+
+	go func() {
+		defer close(k.stopped)
+
+		for ctx.Err() == nil {
+			if err := k.sync(ctx); err != nil && ctx.Err() == nil {
+				k.fail(err)
+
+				select {
+				case <-time.After(kvRetry):
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the watch, waiting for the running sync to finish
+func (k *kvSync) Stop() {
+	k.cancel()
+	<-k.stopped
+}
+
+// sync waits for a change of the keys and reloads the jobs
+func (k *kvSync) sync(ctx context.Context) error {
+	pairs, index, err := k.store.Watch(ctx, k.prefix, k.index)
+	if err != nil || index == k.index {
+		return err
+	}
+
+	config, err := readConfigFile(k.configFile)
+	if err != nil {
+		return err
+	}
+
+	if err := k.decode(config, pairs); err != nil {
+		return err
+	}
+
+	if _, err := k.applier.apply(fmt.Sprintf("kv:%d", index), config, false); err != nil {
+		return err
+	}
+
+	k.applier.sched.Logger.Noticef("KV sync: index %d applied", index)
+	k.setIndex(index)
+	k.failing = false
+	core.KVSyncSuccess.Set(k.applier.sched.Clock.Now().Unix())
+	return nil
+}
+
+// fail records the failure of the sync in the metrics, announcing it once
+// until a sync succeeds
+func (k *kvSync) fail(err error) {
+	sched := k.applier.sched
+	core.KVSyncFailures.Add(1)
+	sched.Logger.Errorf("KV sync failed: %s", err)
+	sched.RecordReload(&core.Reload{Error: err.Error()})
+	if k.failing {
+		return
+	}
+
+	k.failing = true
+	middlewares.Announce(sched, "Ofelia config sync failed", fmt.Sprintf(
+		"Ofelia config sync failed on %s: %s", hostname(), err,
+	))
+}
+
+func (k *kvSync) setIndex(index uint64) {
+	k.index = index
+	core.KVSyncIndex.Set(int64(index))
+}
@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// LabelPolicy restricts the jobs read from the docker labels, since any
+// container with labels can declare jobs run by ofelia. The jobs violating the
+// policy are logged and rejected, an empty policy allows everything.
+type LabelPolicy struct {
+	// AllowImages images of the jobs and their sidecars, "*" matching any
+	// characters, e.g. "alpine:*" or "registry.example.com/*"
+	AllowImages []string
+	// AllowCommands regular expressions, one of them matching the whole
+	// command, command-array, script or interpreter of the jobs
+	AllowCommands []string
+	// DenyVolumes host paths not mounted by the jobs, nor their subpaths and
+	// parents, e.g. "/var/run/docker.sock"
+	DenyVolumes []string
+	// AllowUsers users running the commands of the jobs in the containers
+	AllowUsers []string
+}
+
+// Validate checks the regular expressions of the allowed commands
+func (p *LabelPolicy) Validate() error {
+	for _, c := range p.AllowCommands {
+		if _, err := regexp.Compile(c); err != nil {
+			return fmt.Errorf("invalid label-allow-command %q: %s", c, err)
+		}
+	}
+
+	return nil
+}
+
+// restricts returns true if the policy restricts anything
+func (p *LabelPolicy) restricts() bool {
+	return len(p.AllowImages) > 0 || len(p.AllowCommands) > 0 || len(p.DenyVolumes) > 0 || len(p.AllowUsers) > 0
+}
+
+// errTypeNotAllowed rejects the jobs whose images and commands are defined
+// outside of ofelia, so can't be checked by a policy
+var errTypeNotAllowed = fmt.Errorf("job type not allowed by a label policy")
+
+// applyLabelPolicy removes the jobs violating the given policy, logging them,
+// and the pipelines with any of them as step. With a policy restricting
+// anything, the ECS, Nomad and custom jobs are rejected.
+func (c *Config) applyLabelPolicy(p LabelPolicy, logger core.Logger) {
+	rejected := make(map[string]bool)
+	reject := func(jobType, name string, err error) {
+		rejected[name] = true
+		if logger != nil {
+			logger.Errorf("Rejected the %s %q of the docker labels, by the label policy: %s", jobType, name, err)
+		}
+	}
+
+	for name, j := range c.ExecJobs {
+		if err := p.checkExec(&j.ExecJob); err != nil {
+			reject(jobExec, name, err)
+			delete(c.ExecJobs, name)
+		}
+	}
+
+	for name, j := range c.RunJobs {
+		if err := p.checkRun(&j.RunJob); err != nil {
+			reject(jobRun, name, err)
+			delete(c.RunJobs, name)
+		}
+	}
+
+	for name, j := range c.ServiceJobs {
+		if err := p.checkService(&j.RunServiceJob); err != nil {
+			reject(jobServiceRun, name, err)
+			delete(c.ServiceJobs, name)
+		}
+	}
+
+	for name, j := range c.LocalJobs {
+		if err := p.checkLocal(&j.LocalJob); err != nil {
+			reject(jobLocal, name, err)
+			delete(c.LocalJobs, name)
+		}
+	}
+
+	for name, j := range c.BackupJobs {
+		if err := p.checkBackup(&j.BackupJob); err != nil {
+			reject(jobBackup, name, err)
+			delete(c.BackupJobs, name)
+		}
+	}
+
+	if p.restricts() {
+		for name := range c.ECSJobs {
+			reject(jobECS, name, errTypeNotAllowed)
+			delete(c.ECSJobs, name)
+		}
+
+		for name := range c.NomadJobs {
+			reject(jobNomad, name, errTypeNotAllowed)
+			delete(c.NomadJobs, name)
+		}
+
+		for t, jobs := range c.custom {
+			for name := range jobs {
+				reject(t, name, errTypeNotAllowed)
+			}
+
+			delete(c.custom, t)
+		}
+	}
+
+	for name, j := range c.PipelineJobs {
+		for _, step := range j.Steps {
+			if rejected[step] {
+				reject(jobPipeline, name, fmt.Errorf("step %q rejected", step))
+				delete(c.PipelineJobs, name)
+				break
+			}
+		}
+	}
+}
+
+func (p *LabelPolicy) checkExec(j *core.ExecJob) error {
+	if err := p.checkUser(j.User); err != nil {
+		return err
+	}
+
+	if err := p.checkEnvFile(j.EnvFile); err != nil {
+		return err
+	}
+
+	return p.checkCommand(j.Command, &j.CommandConfig)
+}
+
+func (p *LabelPolicy) checkRun(j *core.RunJob) error {
+	if err := p.checkUser(j.User); err != nil {
+		return err
+	}
+
+	if err := p.checkImage(j.Image); err != nil {
+		return err
+	}
+
+	if err := p.checkEnvFile(j.EnvFile); err != nil {
+		return err
+	}
+
+	if err := p.checkCommand(j.Command, &j.CommandConfig); err != nil {
+		return err
+	}
+
+	for _, v := range j.Volume {
+		if err := p.checkVolume(v); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range j.Sidecar {
+		sc, err := core.ParseSidecar(s)
+		if err != nil {
+			return err
+		}
+
+		if err := p.checkImage(sc.Image); err != nil {
+			return fmt.Errorf("sidecar %q: %s", sc.Name, err)
+		}
+
+		if err := p.checkCommand(sc.Command, nil); err != nil {
+			return fmt.Errorf("sidecar %q: %s", sc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// checkLocal checks the local job, and its lock file and chroot, paths of the
+// host used by ofelia
+func (p *LabelPolicy) checkLocal(j *core.LocalJob) error {
+	if err := p.checkEnvFile(j.EnvFile); err != nil {
+		return err
+	}
+
+	if j.Flock != "" {
+		path := j.Flock
+		if !filepath.IsAbs(path) && j.Dir != "" {
+			path = filepath.Join(j.Dir, path)
+		}
+
+		if d := p.deniedPath(path); d != "" {
+			return fmt.Errorf("flock %q denied, exposes %q", j.Flock, d)
+		}
+	}
+
+	if j.Chroot != "" {
+		if d := p.deniedPath(j.Chroot); d != "" {
+			return fmt.Errorf("chroot %q denied, exposes %q", j.Chroot, d)
+		}
+	}
+
+	return p.checkCommand(j.Command, nil)
+}
+
+// checkBackup checks the backup job as an exec job, and its destination, a
+// directory of the host written by ofelia unless a S3 bucket
+func (p *LabelPolicy) checkBackup(j *core.BackupJob) error {
+	if err := p.checkExec(&j.ExecJob); err != nil {
+		return err
+	}
+
+	if j.Destination == "" || strings.HasPrefix(j.Destination, "s3://") {
+		return nil
+	}
+
+	if d := p.deniedPath(j.Destination); d != "" {
+		return fmt.Errorf("destination %q denied, exposes %q", j.Destination, d)
+	}
+
+	return nil
+}
+
+func (p *LabelPolicy) checkService(j *core.RunServiceJob) error {
+	if err := p.checkUser(j.User); err != nil {
+		return err
+	}
+
+	if err := p.checkImage(j.Image); err != nil {
+		return err
+	}
+
+	return p.checkCommand(j.Command, nil)
+}
+
+// checkImage checks the given image, the empty ones being the image of an
+// existing container
+func (p *LabelPolicy) checkImage(image string) error {
+	if image == "" || len(p.AllowImages) == 0 {
+		return nil
+	}
+
+	for _, pattern := range p.AllowImages {
+		if matchWildcard(pattern, image) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("image %q not allowed", image)
+}
+
+// checkCommand checks the command, command-array, script and interpreter of a
+// job, the empty ones being the default command of the image, or /bin/sh
+func (p *LabelPolicy) checkCommand(command string, cc *core.CommandConfig) error {
+	if len(p.AllowCommands) == 0 {
+		return nil
+	}
+
+	commands := []string{command}
+	if cc != nil {
+		commands = append(commands, strings.Join(cc.CommandArray, " "), cc.Script, cc.Interpreter)
+	}
+
+	for _, cmd := range commands {
+		if cmd != "" && !p.allowsCommand(cmd) {
+			return fmt.Errorf("command %q not allowed", cmd)
+		}
+	}
+
+	return nil
+}
+
+func (p *LabelPolicy) allowsCommand(cmd string) bool {
+	for _, c := range p.AllowCommands {
+		re, err := regexp.Compile("^(?:" + c + ")$")
+		if err == nil && re.MatchString(cmd) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkVolume checks the host path of the given bind, the named volumes are
+// allowed
+func (p *LabelPolicy) checkVolume(volume string) error {
+	host := strings.SplitN(volume, ":", 2)[0]
+	if !filepath.IsAbs(host) {
+		return nil
+	}
+
+	if d := p.deniedPath(host); d != "" {
+		return fmt.Errorf("volume %q denied, exposes %q", volume, d)
+	}
+
+	return nil
+}
+
+// checkEnvFile checks the env-file, a file of the host read by ofelia
+func (p *LabelPolicy) checkEnvFile(file string) error {
+	if file == "" {
+		return nil
+	}
+
+	if d := p.deniedPath(file); d != "" {
+		return fmt.Errorf("env-file %q denied, exposes %q", file, d)
+	}
+
+	return nil
+}
+
+// deniedPath returns the denied volume the given path of the host is, or is
+// a subpath or a parent of, empty if none. The relative paths are resolved
+// from the working directory of ofelia.
+func (p *LabelPolicy) deniedPath(path string) string {
+	host, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+
+	for _, d := range p.DenyVolumes {
+		d = filepath.Clean(d)
+		if host == d || strings.HasPrefix(host, d+"/") || strings.HasPrefix(d, strings.TrimSuffix(host, "/")+"/") {
+			return d
+		}
+	}
+
+	return ""
+}
+
+// checkUser checks the given user, by default root
+func (p *LabelPolicy) checkUser(user string) error {
+	if len(p.AllowUsers) == 0 {
+		return nil
+	}
+
+	if user == "" {
+		user = "root"
+	}
+
+	for _, u := range p.AllowUsers {
+		if u == user {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("user %q not allowed", user)
+}
+
+// matchWildcard returns true if s matches the pattern, "*" matching any
+// characters
+func matchWildcard(pattern, s string) bool {
+	re := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+	return regexp.MustCompile(re).MatchString(s)
+}
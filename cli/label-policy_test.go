@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteLabelPolicy struct{}
+
+var _ = Suite(&SuiteLabelPolicy{})
+
+type errorLogger struct {
+	core.Logger
+	errors []string
+}
+
+func (l *errorLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func (s *SuiteLabelPolicy) TestApplyLabelPolicy(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:                      "true",
+			serviceLabelName:                       "true",
+			"ofelia.job-run.backup.schedule":       "@daily",
+			"ofelia.job-run.backup.image":          "registry.example.com/tools/backup:1.2",
+			"ofelia.job-run.backup.command":        "backup /data",
+			"ofelia.job-run.backup.user":           "nobody",
+			"ofelia.job-run.escape.schedule":       "@daily",
+			"ofelia.job-run.escape.image":          "registry.example.com/tools/backup:1.2",
+			"ofelia.job-run.escape.command":        "backup /data",
+			"ofelia.job-run.escape.user":           "nobody",
+			"ofelia.job-run.escape.volume":         `["/var/run:/var/run"]`,
+			"ofelia.job-run.miner.schedule":        "@daily",
+			"ofelia.job-run.miner.image":           "miner:latest",
+			"ofelia.job-run.miner.user":            "nobody",
+			"ofelia.job-local.shell.schedule":      "@daily",
+			"ofelia.job-local.shell.command":       "rm -rf /",
+			"ofelia.job-pipeline.nightly.steps":    `["backup", "shell"]`,
+			"ofelia.job-pipeline.nightly.schedule": "@daily",
+		},
+		"web": {
+			requiredLabelName:                "true",
+			"ofelia.job-exec.clean.schedule": "@hourly",
+			"ofelia.job-exec.clean.command":  "backup /tmp",
+			"ofelia.job-exec.clean.user":     "www-data",
+			"ofelia.job-exec.sudo.schedule":  "@hourly",
+			"ofelia.job-exec.sudo.command":   "backup /tmp",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	logger := &errorLogger{}
+	config.applyLabelPolicy(LabelPolicy{
+		AllowImages:   []string{"registry.example.com/*"},
+		AllowCommands: []string{`backup /\w+`},
+		DenyVolumes:   []string{"/var/run/docker.sock"},
+		AllowUsers:    []string{"www-data", "nobody"},
+	}, logger)
+
+	c.Assert(config.RunJobs, HasLen, 1)
+	c.Assert(config.RunJobs["backup"], NotNil)
+	c.Assert(config.LocalJobs, HasLen, 0)
+	c.Assert(config.PipelineJobs, HasLen, 0)
+	c.Assert(config.ExecJobs, HasLen, 1)
+	c.Assert(config.ExecJobs["clean"], NotNil)
+
+	sort.Strings(logger.errors)
+	c.Assert(logger.errors, DeepEquals, []string{
+		`Rejected the job-exec "sudo" of the docker labels, by the label policy: user "root" not allowed`,
+		`Rejected the job-local "shell" of the docker labels, by the label policy: command "rm -rf /" not allowed`,
+		`Rejected the job-pipeline "nightly" of the docker labels, by the label policy: step "shell" rejected`,
+		`Rejected the job-run "escape" of the docker labels, by the label policy: volume "/var/run:/var/run" denied, exposes "/var/run/docker.sock"`,
+		`Rejected the job-run "miner" of the docker labels, by the label policy: image "miner:latest" not allowed`,
+	})
+}
+
+func (s *SuiteLabelPolicy) TestApplyLabelPolicyEmpty(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:                 "true",
+			serviceLabelName:                  "true",
+			"ofelia.job-local.shell.schedule": "@daily",
+			"ofelia.job-local.shell.command":  "rm -rf /",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	config.applyLabelPolicy(LabelPolicy{}, nil)
+	c.Assert(config.LocalJobs, HasLen, 1)
+}
+
+func (s *SuiteLabelPolicy) TestApplyLabelPolicyJobTypes(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"db": {
+			requiredLabelName:                      "true",
+			serviceLabelName:                       "true",
+			"ofelia.job-backup.dump.schedule":      "@daily",
+			"ofelia.job-backup.dump.command":       "pg_dumpall",
+			"ofelia.job-backup.dump.destination":   "/backups",
+			"ofelia.job-backup.escape.schedule":    "@daily",
+			"ofelia.job-backup.escape.command":     "pg_dumpall",
+			"ofelia.job-backup.escape.destination": "/etc/cron.d",
+			"ofelia.job-backup.s3.schedule":        "@daily",
+			"ofelia.job-backup.s3.command":         "pg_dumpall",
+			"ofelia.job-backup.s3.destination":     "s3://backups/db",
+			"ofelia.job-exec.env.schedule":         "@daily",
+			"ofelia.job-exec.env.command":          "pg_dumpall",
+			"ofelia.job-exec.env.env-file":         "/etc/ofelia/secrets.env",
+			"ofelia.job-ecs.task.schedule":         "@daily",
+			"ofelia.job-ecs.task.task-definition":  "miner",
+			"ofelia.job-nomad.dispatch.schedule":   "@daily",
+			"ofelia.job-nomad.dispatch.nomad-job":  "miner",
+		},
+	})
+	c.Assert(err, IsNil)
+	config.custom = sectionParams{"job-k8s": {"miner": {"schedule": "@daily"}}}
+
+	logger := &errorLogger{}
+	config.applyLabelPolicy(LabelPolicy{DenyVolumes: []string{"/etc"}}, logger)
+
+	c.Assert(config.BackupJobs, HasLen, 2)
+	c.Assert(config.BackupJobs["dump"], NotNil)
+	c.Assert(config.BackupJobs["s3"], NotNil)
+	c.Assert(config.ExecJobs, HasLen, 0)
+	c.Assert(config.ECSJobs, HasLen, 0)
+	c.Assert(config.NomadJobs, HasLen, 0)
+	c.Assert(config.custom, HasLen, 0)
+
+	sort.Strings(logger.errors)
+	c.Assert(logger.errors, DeepEquals, []string{
+		`Rejected the job-backup "escape" of the docker labels, by the label policy: destination "/etc/cron.d" denied, exposes "/etc"`,
+		`Rejected the job-ecs "task" of the docker labels, by the label policy: job type not allowed by a label policy`,
+		`Rejected the job-exec "env" of the docker labels, by the label policy: env-file "/etc/ofelia/secrets.env" denied, exposes "/etc"`,
+		`Rejected the job-k8s "miner" of the docker labels, by the label policy: job type not allowed by a label policy`,
+		`Rejected the job-nomad "dispatch" of the docker labels, by the label policy: job type not allowed by a label policy`,
+	})
+}
+
+func (s *SuiteLabelPolicy) TestCheckRun(c *C) {
+	p := &LabelPolicy{
+		AllowImages:   []string{"alpine:*", "registry.example.com/*"},
+		AllowCommands: []string{`echo \w+`},
+		DenyVolumes:   []string{"/var/run/docker.sock"},
+	}
+
+	j := &core.RunJob{Image: "alpine:3.12", Volume: []string{"/srv/data:/data", "cache:/cache"}}
+	j.Command = "echo foo"
+	c.Assert(p.checkRun(j), IsNil)
+
+	j.Image = "alpine"
+	c.Assert(p.checkRun(j), ErrorMatches, `image "alpine" not allowed`)
+
+	j.Image = "registry.example.com/tools/backup:1.2"
+	j.Command = "echo foo; sh"
+	c.Assert(p.checkRun(j), ErrorMatches, `command "echo foo; sh" not allowed`)
+
+	j.Command, j.Script = "", "echo foo\nsh"
+	c.Assert(p.checkRun(j), ErrorMatches, `command "echo foo\\nsh" not allowed`)
+
+	j.Script, j.Interpreter = "echo foo", "python3"
+	c.Assert(p.checkRun(j), ErrorMatches, `command "python3" not allowed`)
+
+	j.Interpreter = "echo bar"
+	c.Assert(p.checkRun(j), IsNil)
+
+	j.Script, j.Interpreter = "", ""
+	j.Sidecar = []string{"db=postgres:12"}
+	c.Assert(p.checkRun(j), ErrorMatches, `sidecar "db": image "postgres:12" not allowed`)
+
+	j.Sidecar = nil
+	j.Volume = []string{"/var/run/docker.sock:/var/run/docker.sock:ro"}
+	c.Assert(p.checkRun(j), ErrorMatches, `volume "/var/run/docker.sock:/var/run/docker.sock:ro" denied, exposes "/var/run/docker.sock"`)
+}
+
+func (s *SuiteLabelPolicy) TestCheckLocal(c *C) {
+	p := &LabelPolicy{DenyVolumes: []string{"/etc"}}
+
+	j := &core.LocalJob{Flock: "/run/lock/backup.lock", Chroot: "/srv/jail"}
+	c.Assert(p.checkLocal(j), IsNil)
+
+	j.Flock = "/etc/passwd"
+	c.Assert(p.checkLocal(j), ErrorMatches, `flock "/etc/passwd" denied, exposes "/etc"`)
+
+	j.Flock, j.Dir = "shadow", "/etc"
+	c.Assert(p.checkLocal(j), ErrorMatches, `flock "shadow" denied, exposes "/etc"`)
+
+	j.Flock, j.Chroot = "", "/"
+	c.Assert(p.checkLocal(j), ErrorMatches, `chroot "/" denied, exposes "/etc"`)
+}
+
+func (s *SuiteLabelPolicy) TestCheckVolume(c *C) {
+	p := &LabelPolicy{DenyVolumes: []string{"/var/run/docker.sock", "/etc/"}}
+
+	c.Assert(p.checkVolume("/srv/data:/data"), IsNil)
+	c.Assert(p.checkVolume("docker.sock:/data"), IsNil)
+	c.Assert(p.checkVolume("/var/run/docker.sock.bak:/data"), IsNil)
+	c.Assert(p.checkVolume("/var/run/docker.sock:/sock"), NotNil)
+	c.Assert(p.checkVolume("/var/run/../run/docker.sock:/sock"), NotNil)
+	c.Assert(p.checkVolume("/var/run:/host"), NotNil)
+	c.Assert(p.checkVolume("/:/host"), NotNil)
+	c.Assert(p.checkVolume("/etc/ssl:/ssl"), NotNil)
+}
+
+func (s *SuiteLabelPolicy) TestCheckUser(c *C) {
+	c.Assert((&LabelPolicy{}).checkUser(""), IsNil)
+
+	p := &LabelPolicy{AllowUsers: []string{"nobody"}}
+	c.Assert(p.checkUser("nobody"), IsNil)
+	c.Assert(p.checkUser(""), ErrorMatches, `user "root" not allowed`)
+}
+
+func (s *SuiteLabelPolicy) TestValidate(c *C) {
+	c.Assert((&LabelPolicy{AllowCommands: []string{`echo \w+`}}).Validate(), IsNil)
+
+	err := (&LabelPolicy{AllowCommands: []string{`echo (`}}).Validate()
+	c.Assert(err, ErrorMatches, `invalid label-allow-command "echo \(": .*`)
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	logging "github.com/op/go-logging"
+)
+
+// plainLogFormat is the format of the logs when log-pretty is false, without
+// colors nor symbols, for the log processors
+const plainLogFormat = "%{time:2006-01-02T15:04:05.000Z07:00} %{level} %{shortfile} %{message}"
+
+const (
+	prettyTimeFormat  = "2006-01-02 15:04:05"
+	prettyColumnWidth = 12
+	colorReset        = "\033[0m"
+	colorDim          = "\033[2m"
+)
+
+// jobLogMessage matches the messages logged about the executions of the jobs,
+// `[Job "<name>" (<execution>)] <message>`
+var jobLogMessage = regexp.MustCompile(`^\[Job ("(?:[^"\\]|\\.)*") \(([^)]*)\)\] ((?s).*)$`)
+
+var levelColors = map[logging.Level]string{
+	logging.CRITICAL: "\033[1;31m",
+	logging.ERROR:    "\033[31m",
+	logging.WARNING:  "\033[33m",
+	logging.NOTICE:   "\033[32m",
+	logging.INFO:     "\033[37m",
+	logging.DEBUG:    "\033[36m",
+}
+
+// levelEmojis are the status of the executions, by the level of the message
+var levelEmojis = map[logging.Level]string{
+	logging.CRITICAL: "❌",
+	logging.ERROR:    "❌",
+	logging.WARNING:  "⚠️ ",
+	logging.NOTICE:   "✅",
+	logging.INFO:     "ℹ️ ",
+	logging.DEBUG:    "🔎",
+}
+
+// prettyFormatter formats the logs for humans: colored by level, and the
+// status of the execution, with the name of the job, or the component, in a
+// column as wide as the longest name seen so far, and the execution at the
+// end of the line
+type prettyFormatter struct {
+	emoji bool
+
+	mu    sync.Mutex
+	width int
+}
+
+func (f *prettyFormatter) Format(calldepth int, r *logging.Record, w io.Writer) error {
+	name, msg, execution := "["+r.Module+"]", r.Message(), ""
+	if m := jobLogMessage.FindStringSubmatch(msg); m != nil {
+		if job, err := strconv.Unquote(m[1]); err == nil {
+			name, execution, msg = job, m[2], m[3]
+		}
+	}
+
+	var line strings.Builder
+	line.WriteString(r.Time.Format(prettyTimeFormat))
+	line.WriteString(" ")
+	line.WriteString(levelColors[r.Level])
+	fmt.Fprintf(&line, "%-8s", r.Level)
+	line.WriteString(colorReset)
+
+	if f.emoji {
+		line.WriteString(levelEmojis[r.Level])
+		line.WriteString(" ")
+	}
+
+	line.WriteString(f.column(name))
+	line.WriteString(" ")
+	line.WriteString(msg)
+	if execution != "" {
+		line.WriteString(" " + colorDim + "(" + execution + ")" + colorReset)
+	}
+
+	_, err := io.WriteString(w, line.String())
+	return err
+}
+
+// column pads the name to the width of the column, widening it when the name
+// doesn't fit
+func (f *prettyFormatter) column(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.width < prettyColumnWidth {
+		f.width = prettyColumnWidth
+	}
+
+	if n := len([]rune(name)); n > f.width {
+		f.width = n
+	}
+
+	return fmt.Sprintf("%-*s", f.width, name)
+}
+
+// logFormatter returns the formatter of the config, the pretty one unless
+// log-pretty is false
+func (c *LogConfig) logFormatter() (logging.Formatter, error) {
+	pretty := true
+	if c.LogPretty != "" {
+		var err error
+		if pretty, err = strconv.ParseBool(c.LogPretty); err != nil {
+			return nil, fmt.Errorf("invalid log-pretty %q, must be true or false", c.LogPretty)
+		}
+	}
+
+	if !pretty {
+		return logging.MustStringFormatter(plainLogFormat), nil
+	}
+
+	return &prettyFormatter{emoji: c.LogEmoji}, nil
+}
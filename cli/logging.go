@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+	logging "github.com/op/go-logging"
+)
+
+// components of the logs, each one with its own level
+const (
+	logScheduler   = "scheduler"
+	logDocker      = "docker"
+	logMiddlewares = "middlewares"
+)
+
+var logComponents = []string{logScheduler, logDocker, logMiddlewares}
+
+// LogConfig levels of the logs, "debug", "info", "warn" or "error", by
+// default "info", and the outputs besides the standard output
+type LogConfig struct {
+	LogLevel            string `gcfg:"log-level" mapstructure:"log-level"`
+	LogLevelScheduler   string `gcfg:"log-level-scheduler" mapstructure:"log-level-scheduler"`
+	LogLevelDocker      string `gcfg:"log-level-docker" mapstructure:"log-level-docker"`
+	LogLevelMiddlewares string `gcfg:"log-level-middlewares" mapstructure:"log-level-middlewares"`
+	// LogPretty "false" writes plain logs, without colors nor symbols, by
+	// default the logs are colored and aligned by job
+	LogPretty string `gcfg:"log-pretty" mapstructure:"log-pretty"`
+	// LogEmoji prefixes the pretty logs with the status as an emoji
+	LogEmoji bool `gcfg:"log-emoji" mapstructure:"log-emoji"`
+	// LogFluentAddr address of Fluentd or fluent-bit, "host:port" or
+	// "unix:///path/to/socket", the logs are forwarded to it when set
+	LogFluentAddr string `gcfg:"log-fluent-addr" mapstructure:"log-fluent-addr"`
+	// LogFluentTag tag of the forwarded records, "ofelia" by default
+	LogFluentTag string `gcfg:"log-fluent-tag" mapstructure:"log-fluent-tag"`
+	// LogFluentBuffer records kept while Fluentd can't be reached, 1024 by
+	// default
+	LogFluentBuffer int `gcfg:"log-fluent-buffer" mapstructure:"log-fluent-buffer"`
+}
+
+// levels returns the level of each component, its own or the global one
+func (c *LogConfig) levels() (map[string]logging.Level, error) {
+	global, err := parseLogLevel("log-level", c.LogLevel, logging.INFO)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := map[string]string{
+		logScheduler:   c.LogLevelScheduler,
+		logDocker:      c.LogLevelDocker,
+		logMiddlewares: c.LogLevelMiddlewares,
+	}
+
+	levels := make(map[string]logging.Level)
+	for _, component := range logComponents {
+		levels[component], err = parseLogLevel("log-level-"+component, overrides[component], global)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return levels, nil
+}
+
+func parseLogLevel(param, value string, def logging.Level) (logging.Level, error) {
+	switch value {
+	case "":
+		return def, nil
+	case "debug":
+		return logging.DEBUG, nil
+	case "info":
+		return logging.INFO, nil
+	case "warn":
+		return logging.WARNING, nil
+	case "error":
+		return logging.ERROR, nil
+	}
+
+	return def, fmt.Errorf("invalid %s %q, must be debug, info, warn or error", param, value)
+}
+
+// logLevels are the levels set by the config, restored when the debug
+// logging is disabled
+var logLevels struct {
+	sync.Mutex
+	configured map[string]logging.Level
+	debug      bool
+}
+
+// logFluent is the running fluent backend, kept across the reloads while its
+// config doesn't change, so the buffered records aren't lost
+var logFluent struct {
+	sync.Mutex
+	backend *fluentBackend
+	config  [3]string
+}
+
+// setupLogging sets the backend and the levels of the logs, returning the
+// logger of the scheduler
+func setupLogging(c *LogConfig) (core.Logger, error) {
+	levels, err := c.levels()
+	if err != nil {
+		return nil, err
+	}
+
+	formatter, err := c.logFormatter()
+	if err != nil {
+		return nil, err
+	}
+
+	backends := []logging.Backend{logging.NewLogBackend(os.Stdout, "", 0)}
+	if fluent := fluentLogBackend(c); fluent != nil {
+		backends = append(backends, fluent)
+	}
+
+	backend := logging.SetBackend(backends...)
+	logging.SetFormatter(formatter)
+
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	for component, level := range levels {
+		backend.SetLevel(level, component)
+	}
+
+	logLevels.configured, logLevels.debug = levels, false
+	middlewares.Logger = logging.MustGetLogger(logMiddlewares)
+	return logging.MustGetLogger(logScheduler), nil
+}
+
+// fluentLogBackend returns the fluent backend of the config, if any, closing
+// the previous one when the config changed
+func fluentLogBackend(c *LogConfig) *fluentBackend {
+	logFluent.Lock()
+	defer logFluent.Unlock()
+
+	config := [3]string{c.LogFluentAddr, c.LogFluentTag, strconv.Itoa(c.LogFluentBuffer)}
+	if logFluent.backend != nil && logFluent.config == config {
+		return logFluent.backend
+	}
+
+	if logFluent.backend != nil {
+		logFluent.backend.Close()
+		logFluent.backend = nil
+	}
+
+	if c.LogFluentAddr != "" {
+		logFluent.backend = newFluentBackend(c.LogFluentAddr, c.LogFluentTag, c.LogFluentBuffer)
+		logFluent.config = config
+	}
+
+	return logFluent.backend
+}
+
+// dockerLogger returns the logger of the docker related components
+func dockerLogger() core.Logger {
+	return logging.MustGetLogger(logDocker)
+}
+
+// toggleDebugLogging switches all the components to the debug level, or back
+// to their configured levels
+func toggleDebugLogging(l core.Logger) {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+
+	logLevels.debug = !logLevels.debug
+	for _, component := range logComponents {
+		level, ok := logLevels.configured[component]
+		if !ok {
+			level = logging.INFO
+		}
+
+		if logLevels.debug {
+			level = logging.DEBUG
+		}
+
+		logging.SetLevel(level, component)
+	}
+
+	if logLevels.debug {
+		l.Noticef("Debug logging enabled")
+		return
+	}
+
+	l.Noticef("Debug logging disabled")
+}
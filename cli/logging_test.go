@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteLogging struct{}
+
+var _ = Suite(&SuiteLogging{})
+
+func (s *SuiteLogging) TestLevels(c *C) {
+	levels, err := (&LogConfig{}).levels()
+	c.Assert(err, IsNil)
+	c.Assert(levels, DeepEquals, map[string]logging.Level{
+		logScheduler:   logging.INFO,
+		logDocker:      logging.INFO,
+		logMiddlewares: logging.INFO,
+	})
+
+	levels, err = (&LogConfig{LogLevel: "warn", LogLevelDocker: "debug"}).levels()
+	c.Assert(err, IsNil)
+	c.Assert(levels, DeepEquals, map[string]logging.Level{
+		logScheduler:   logging.WARNING,
+		logDocker:      logging.DEBUG,
+		logMiddlewares: logging.WARNING,
+	})
+
+	_, err = (&LogConfig{LogLevelMiddlewares: "verbose"}).levels()
+	c.Assert(err, ErrorMatches, `invalid log-level-middlewares "verbose", .*`)
+}
+
+func (s *SuiteLogging) TestBuildFromStringLogLevel(c *C) {
+	_, err := BuildFromString(`
+		[global]
+		log-level = error
+		log-level-scheduler = debug
+
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+	`)
+	c.Assert(err, IsNil)
+	c.Assert(logging.GetLevel(logScheduler), Equals, logging.DEBUG)
+	c.Assert(logging.GetLevel(logDocker), Equals, logging.ERROR)
+
+	_, err = BuildFromString(`
+		[global]
+		log-level = trace
+	`)
+	c.Assert(err, ErrorMatches, `invalid log-level "trace", .*`)
+}
+
+func (s *SuiteLogging) TestToggleDebugLogging(c *C) {
+	_, err := setupLogging(&LogConfig{LogLevelDocker: "error"})
+	c.Assert(err, IsNil)
+
+	logger := &noticeLogger{}
+	toggleDebugLogging(logger)
+	for _, component := range logComponents {
+		c.Assert(logging.GetLevel(component), Equals, logging.DEBUG)
+	}
+
+	toggleDebugLogging(logger)
+	c.Assert(logging.GetLevel(logScheduler), Equals, logging.INFO)
+	c.Assert(logging.GetLevel(logDocker), Equals, logging.ERROR)
+	c.Assert(logger.notices, DeepEquals, []string{"Debug logging enabled", "Debug logging disabled"})
+}
+
+func (s *SuiteLogging) TestPrettyFormatter(c *C) {
+	f := &prettyFormatter{emoji: true}
+	record := func(level logging.Level, module string, args ...interface{}) *logging.Record {
+		return &logging.Record{Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Level: level, Module: module, Args: args}
+	}
+
+	var b bytes.Buffer
+	c.Assert(f.Format(0, record(logging.NOTICE, logScheduler, `[Job "foo" (abc)] Started`), &b), IsNil)
+	c.Assert(b.String(), Equals, "2020-01-02 03:04:05 \033[32mNOTICE  \033[0m✅ foo          Started \033[2m(abc)\033[0m")
+
+	b.Reset()
+	c.Assert(f.Format(0, record(logging.ERROR, logDocker, "Docker unreachable"), &b), IsNil)
+	c.Assert(b.String(), Equals, "2020-01-02 03:04:05 \033[31mERROR   \033[0m❌ [docker]     Docker unreachable")
+
+	// the column widens for the longer names, and stays wide
+	b.Reset()
+	f.Format(0, record(logging.NOTICE, logScheduler, `[Job "a-very-long-job-name" (abc)] Started`), &b)
+	b.Reset()
+	f.Format(0, record(logging.NOTICE, logScheduler, `[Job "foo" (abc)] Started`), &b)
+	c.Assert(strings.Contains(b.String(), "foo"+strings.Repeat(" ", 18)+"Started"), Equals, true)
+}
+
+func (s *SuiteLogging) TestLogFormatter(c *C) {
+	f, err := (&LogConfig{}).logFormatter()
+	c.Assert(err, IsNil)
+	c.Assert(f, FitsTypeOf, &prettyFormatter{})
+
+	f, err = (&LogConfig{LogPretty: "false"}).logFormatter()
+	c.Assert(err, IsNil)
+
+	var b bytes.Buffer
+	r := &logging.Record{Time: time.Now(), Level: logging.NOTICE, Args: []interface{}{`[Job "foo" (abc)] Started`}}
+	c.Assert(f.Format(0, r, &b), IsNil)
+	c.Assert(b.String(), Matches, `\S+ NOTICE \S+ \[Job "foo" \(abc\)\] Started`)
+
+	_, err = (&LogConfig{LogPretty: "maybe"}).logFormatter()
+	c.Assert(err, ErrorMatches, `invalid log-pretty "maybe", .*`)
+}
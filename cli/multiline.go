@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// multilineDelimiter delimits the multi-line values of the INI config
+const multilineDelimiter = `"""`
+
+// heredoc starts a multi-line value ended by a line with the given word, e.g.
+// "<<EOF"
+var heredoc = regexp.MustCompile(`^<<(\w+)$`)
+
+var multilineEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// joinMultilineValues replaces the multi-line values of the INI config, the
+// lines between a param with the value `"""` and a line with `"""`, or with
+// the value `<<WORD` and a line with `WORD`, with a single line quoted value,
+// without the indentation common to all the lines.
+// The joined lines are blanked, to keep the line numbers of the errors.
+func joinMultilineValues(config string) (string, error) {
+	var out []string
+	var param, end string
+	var start int
+	var block []string
+	var in bool
+
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if in {
+			if strings.TrimSpace(line) != end {
+				block = append(block, line)
+				continue
+			}
+
+			value := multilineEscaper.Replace(strings.Join(dedent(block), "\n"))
+			out[start-1] = fmt.Sprintf("%s = \"%s\"", param, value)
+			for i := 0; i <= len(block); i++ {
+				out = append(out, "")
+			}
+
+			in, block = false, nil
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			value := strings.TrimSpace(parts[1])
+			if value == multilineDelimiter {
+				param, end, start, in = parts[0], multilineDelimiter, n, true
+			} else if m := heredoc.FindStringSubmatch(value); m != nil {
+				param, end, start, in = parts[0], m[1], n, true
+			}
+		}
+
+		out = append(out, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if in {
+		return "", fmt.Errorf("line %d: multi-line value of %q not terminated", start, strings.TrimSpace(param))
+	}
+
+	return strings.Join(out, "\n"), nil
+}
+
+// dedent removes from the lines the indentation common to all the non blank
+// ones
+func dedent(lines []string) []string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+
+		out[i] = strings.TrimRight(l, " \t")
+	}
+
+	return out
+}
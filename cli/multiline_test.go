@@ -0,0 +1,26 @@
+package cli
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuiteMultiline struct{}
+
+var _ = Suite(&SuiteMultiline{})
+
+func (s *SuiteMultiline) TestJoinMultilineValues(c *C) {
+	config, err := joinMultilineValues("[job-local \"foo\"]\ncommand = \"\"\"\n  echo \"foo\" \\\n    | wc -c\n  echo bar\n  \"\"\"\nschedule = @hourly")
+	c.Assert(err, IsNil)
+	c.Assert(config, Equals, "[job-local \"foo\"]\ncommand  = \"echo \\\"foo\\\" \\\\\\n  | wc -c\\necho bar\"\n\n\n\n\nschedule = @hourly")
+}
+
+func (s *SuiteMultiline) TestJoinMultilineValuesHeredoc(c *C) {
+	config, err := joinMultilineValues("[job-run \"foo\"]\nscript = <<EOF\n    #!/bin/sh\n    echo \"\"\"\nEOF\nimage = alpine")
+	c.Assert(err, IsNil)
+	c.Assert(config, Equals, "[job-run \"foo\"]\nscript  = \"#!/bin/sh\\necho \\\"\\\"\\\"\"\n\n\n\nimage = alpine")
+}
+
+func (s *SuiteMultiline) TestJoinMultilineValuesNotTerminated(c *C) {
+	_, err := joinMultilineValues("[job-local \"foo\"]\ncommand = \"\"\"\n  echo foo")
+	c.Assert(err, ErrorMatches, `line 2: multi-line value of "command" not terminated`)
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+)
+
+// namedNotifiers the notifiers declared as [<notifier> "<name>"] sections,
+// e.g. [slack "ops"], by name. Several notifiers of different kinds can share
+// a name, e.g. [slack "ops"] and [mail "ops"].
+type namedNotifiers map[string][]core.Middleware
+
+// buildNotifiers builds the named notifiers of the config, once
+func (config *Config) buildNotifiers() (namedNotifiers, error) {
+	if config.named != nil {
+		return config.named, nil
+	}
+
+	named := make(namedNotifiers)
+	for _, notifier := range sortedKeys(reflect.ValueOf(config.notifiers)) {
+		for _, name := range sortedKeys(reflect.ValueOf(config.notifiers[notifier])) {
+			params := config.notifiers.get(notifier, name)
+			if err := checkNotifierParams(notifier, params); err != nil {
+				return nil, fmt.Errorf("%s %q: %s", notifier, name, err)
+			}
+
+			m, err := middlewares.BuildNotifier(notifier, middlewareDecoder(&struct{}{}, params))
+			if err != nil {
+				return nil, fmt.Errorf("%s %q: %s", notifier, name, err)
+			}
+
+			if m == nil {
+				return nil, fmt.Errorf("%s %q: not configured, the notifier is disabled", notifier, name)
+			}
+
+			named[name] = append(named[name], m)
+		}
+	}
+
+	config.named = named
+	return named, nil
+}
+
+// checkNotifierParams returns an error if any of the params isn't one of the
+// notifier or of the routing of the notifications
+func checkNotifierParams(notifier string, params map[string]interface{}) error {
+	known := map[string]bool{}
+	for _, p := range middlewares.Plugins() {
+		if p.Name != notifier {
+			continue
+		}
+
+		for _, config := range []interface{}{p.Config(), &middlewares.NotifyConfig{}} {
+			t := reflect.TypeOf(config).Elem()
+			for i := 0; i < t.NumField(); i++ {
+				known[paramName(t.Field(i))] = true
+			}
+		}
+	}
+
+	var unknown []string
+	for param := range params {
+		if !known[param] {
+			unknown = append(unknown, param)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown param %q", unknown[0])
+	}
+
+	return nil
+}
+
+// use adds to the job the named notifiers with the given names, after its own
+// middlewares, several notifiers of the same kind can be used
+func (n namedNotifiers) use(j core.Job, names []string) error {
+	for _, name := range names {
+		ms, ok := n[name]
+		if !ok {
+			return fmt.Errorf("unknown notifier %q", name)
+		}
+
+		if d, ok := j.(interface{ UseDistinct(...core.Middleware) }); ok {
+			d.UseDistinct(ms...)
+			continue
+		}
+
+		j.Use(ms...)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+
+	"github.com/mcuadros/ofelia/middlewares"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteNotifiers struct{}
+
+var _ = Suite(&SuiteNotifiers{})
+
+func (s *SuiteNotifiers) TestBuildFromString(c *C) {
+	sh, err := BuildFromString(`
+		[slack "ops"]
+		slack-webhook = http://ops.example.com
+		slack-only-on-error = true
+
+		[slack "dev"]
+		slack-webhook = http://dev.example.com
+		notify-exclude-tags = noisy
+
+		[mail "ops"]
+		smtp-host = smtp.example.com
+		email-to = ops@example.com
+
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+		slack-webhook = http://foo.example.com
+		notifiers = ops, dev
+
+		[job-local "bar"]
+		schedule = @hourly
+		command = echo bar
+	`)
+
+	c.Assert(err, IsNil)
+
+	var webhooks []string
+	var mails int
+	for _, m := range sh.GetJob("foo").Middlewares() {
+		switch m := m.(type) {
+		case *middlewares.Slack:
+			webhooks = append(webhooks, m.SlackWebhook)
+			if m.SlackWebhook == "http://dev.example.com" {
+				c.Assert(m.TagFilter.ExcludeTags, DeepEquals, []string{"noisy"})
+			}
+		case *middlewares.Mail:
+			mails++
+		}
+	}
+
+	c.Assert(webhooks, DeepEquals, []string{"http://foo.example.com", "http://ops.example.com", "http://dev.example.com"})
+	c.Assert(mails, Equals, 1)
+	c.Assert(sh.GetJob("bar").Middlewares(), HasLen, 0)
+}
+
+func (s *SuiteNotifiers) TestBuildFromStringErrors(c *C) {
+	for config, msg := range map[string]string{
+		`
+		[job-local "foo"]
+		schedule = @hourly
+		command = echo foo
+		notifiers = ops
+		`: `job-local "foo": unknown notifier "ops"`,
+		`
+		[slack "ops"]
+		slack-webook = http://ops.example.com
+		`: `slack "ops": unknown param "slack-webook"`,
+		`
+		[push "ops"]
+		push-retries = 3
+		`: `push "ops": not configured, .*`,
+		`
+		[slack]
+		slack-webhook = http://ops.example.com
+		`: `line 2: slack section requires a name, .*`,
+		`
+		[global]
+		notifiers = ops
+		`: `global: notifiers is only supported by the jobs`,
+	} {
+		_, err := BuildFromString(config)
+		c.Assert(err, ErrorMatches, msg)
+	}
+}
+
+func (s *SuiteNotifiers) TestDump(c *C) {
+	config := &Config{}
+	c.Assert(config.readString(`
+		[slack "ops"]
+		slack-webhook = http://ops.example.com
+	`), IsNil)
+
+	var b bytes.Buffer
+	c.Assert(config.dump(&b, dumpFormatINI, false), IsNil)
+	c.Assert(b.String(), Matches, `(?s).*\[slack "ops"\]\nslack-webhook = \*+\n.*`)
+}
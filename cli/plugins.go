@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	defaults "github.com/mcuadros/go-defaults"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+	"github.com/mitchellh/mapstructure"
+)
+
+const globalSection = "global"
+
+// sectionConfigs types of the config of the built-in sections
+var sectionConfigs = map[string]reflect.Type{
+	globalSection: reflect.TypeOf(Config{}.Global),
+	groupSection:  reflect.TypeOf(GroupConfig{}),
+	jobExec:       reflect.TypeOf(ExecJobConfig{}),
+	jobRun:        reflect.TypeOf(RunJobConfig{}),
+	jobServiceRun: reflect.TypeOf(RunServiceConfig{}),
+	jobLocal:      reflect.TypeOf(LocalJobConfig{}),
+	jobPipeline:   reflect.TypeOf(PipelineJobConfig{}),
+	jobBackup:     reflect.TypeOf(BackupJobConfig{}),
+	jobECS:        reflect.TypeOf(ECSJobConfig{}),
+	jobNomad:      reflect.TypeOf(NomadJobConfig{}),
+}
+
+// pluginParams returns the params of the registered middlewares not embedded
+// in the config of the given built-in section, these are extracted from the
+// section and decoded by the middlewares.
+func pluginParams(section string) map[string]bool {
+	t, ok := sectionConfigs[section]
+	if !ok {
+		return nil
+	}
+
+	params := make(map[string]bool)
+	for _, p := range middlewares.Plugins() {
+		if (section == globalSection || section == groupSection) && !p.Global {
+			continue
+		}
+
+		config := reflect.TypeOf(p.Config()).Elem()
+		if hasEmbedded(t, config) {
+			continue
+		}
+
+		for i := 0; i < config.NumField(); i++ {
+			params[paramName(config.Field(i))] = true
+		}
+	}
+
+	return params
+}
+
+func hasEmbedded(t, embedded reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == embedded {
+			return true
+		}
+	}
+
+	return false
+}
+
+func paramName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("mapstructure"), ",")[0]; name != "" {
+		return name
+	}
+
+	return strings.ToLower(f.Name)
+}
+
+// buildMiddlewares adds to the job the middlewares of all the registered
+// plugins, the job is the config of the section, its extra params are the
+// ones of the middlewares not embedded in it. The named notifiers listed by
+// the job are added after them.
+func buildMiddlewares(section string, j core.Job, extra map[string]interface{}, named namedNotifiers) error {
+	decode := middlewareDecoder(j, extra)
+	ms, err := middlewares.Build(decode)
+	if err != nil {
+		return fmt.Errorf("%s %q: %s", section, j.GetName(), err)
+	}
+
+	j.Use(ms...)
+
+	c := &middlewares.NotifyConfig{}
+	if err := decode(c); err != nil {
+		return fmt.Errorf("%s %q: %s", section, j.GetName(), err)
+	}
+
+	if err := named.use(j, c.NotifierNames()); err != nil {
+		return fmt.Errorf("%s %q: %s", section, j.GetName(), err)
+	}
+
+	return nil
+}
+
+// middlewareDecoder returns the decoder of the middleware configs of a section,
+// the configs embedded in the section config are copied, the others are decoded
+// from the extra params.
+func middlewareDecoder(section interface{}, extra map[string]interface{}) func(interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(section))
+	return func(config interface{}) error {
+		c := reflect.ValueOf(config).Elem()
+		if v.Kind() == reflect.Struct {
+			for i := 0; i < v.NumField(); i++ {
+				if f := v.Field(i); v.Type().Field(i).Anonymous && f.Type() == c.Type() {
+					c.Set(f)
+					return nil
+				}
+			}
+		}
+
+		if len(extra) > 0 {
+			if err := mapstructure.WeakDecode(extra, config); err != nil {
+				return err
+			}
+		}
+
+		defaults.SetDefaults(config)
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+	. "gopkg.in/check.v1"
+)
+
+type pluginTestConfig struct {
+	PluginTestURL string `gcfg:"plugin-test-url" mapstructure:"plugin-test-url"`
+}
+
+type pluginTest struct {
+	pluginTestConfig
+}
+
+func (m *pluginTest) ContinueOnStop() bool {
+	return true
+}
+
+func (m *pluginTest) Run(ctx *core.Context) error {
+	return ctx.Next()
+}
+
+func init() {
+	middlewares.Register(middlewares.Plugin{
+		Name:   "plugin-test",
+		Stage:  middlewares.StageNotify,
+		Global: true,
+		Config: func() interface{} { return &pluginTestConfig{} },
+		New: func(c interface{}) core.Middleware {
+			if c.(*pluginTestConfig).PluginTestURL == "" {
+				return nil
+			}
+
+			return &pluginTest{*c.(*pluginTestConfig)}
+		},
+	})
+}
+
+type SuitePlugins struct{}
+
+var _ = Suite(&SuitePlugins{})
+
+func (s *SuitePlugins) TestBuildFromString(c *C) {
+	sh, err := BuildFromString(`
+		[global]
+		plugin-test-url = http://global
+
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		plugin-test-url = http://foo
+		no-overlap = true
+
+		[job-local "bar"]
+		schedule = @every 10s
+		command = echo bar
+	`)
+
+	c.Assert(err, IsNil)
+	c.Assert(sh.Middlewares(), HasLen, 1)
+	c.Assert(sh.Middlewares()[0].(*pluginTest).PluginTestURL, Equals, "http://global")
+
+	ms := sh.GetJob("foo").Middlewares()
+	c.Assert(ms, HasLen, 2)
+	c.Assert(ms[0], FitsTypeOf, &middlewares.Overlap{})
+	c.Assert(ms[1].(*pluginTest).PluginTestURL, Equals, "http://foo")
+
+	c.Assert(sh.GetJob("bar").Middlewares(), HasLen, 0)
+}
+
+func (s *SuitePlugins) TestBuildFromStringGlobalOnly(c *C) {
+	_, err := BuildFromString(`
+		[global]
+		no-overlap = true
+	`)
+
+	c.Assert(err, NotNil)
+}
+
+func (s *SuitePlugins) TestBuildFromDockerLabels(c *C) {
+	config := &Config{}
+	err := config.buildFromDockerLabels(map[string]map[string]string{
+		"ofelia": {
+			requiredLabelName:                      "true",
+			serviceLabelName:                       "true",
+			"ofelia.plugin-test-url":               "http://global",
+			"ofelia.job-local.foo.schedule":        "@every 10s",
+			"ofelia.job-local.foo.command":         "echo foo",
+			"ofelia.job-local.foo.plugin-test-url": "http://foo",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	jobs, err := config.buildJobs(nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(jobs, HasLen, 1)
+	c.Assert(jobs[0].Middlewares(), HasLen, 1)
+	c.Assert(jobs[0].Middlewares()[0].(*pluginTest).PluginTestURL, Equals, "http://foo")
+	c.Assert(config.extra.get(globalSection, ""), DeepEquals, map[string]interface{}{
+		"plugin-test-url": "http://global",
+	})
+}
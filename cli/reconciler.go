@@ -0,0 +1,324 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/mcuadros/ofelia/api"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+)
+
+// labelsReconciler periodically reads the docker labels and converges the jobs
+// of the scheduler to them, healing any change missed by the scheduler
+type labelsReconciler struct {
+	sched   *core.Scheduler
+	client  *docker.Client
+	opts    DockerLabelsOptions
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newLabelsReconciler(sched *core.Scheduler, opts DockerLabelsOptions) (*labelsReconciler, error) {
+	client, err := (&Config{}).buildDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &labelsReconciler{
+		sched:   sched,
+		client:  client,
+		opts:    opts,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// Start runs the reconciliation every interval, until Stop is called
+func (r *labelsReconciler) Start(interval time.Duration) {
+	go func() {
+		defer close(r.stopped)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				if err := r.sync(); err != nil {
+					r.sched.Logger.Errorf("Docker labels reconciliation failed: %s", err)
+				}
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the reconciliation, waiting for the running one to finish
+func (r *labelsReconciler) Stop() {
+	close(r.done)
+	<-r.stopped
+}
+
+func (r *labelsReconciler) sync() error {
+	start := time.Now()
+	defer func() { core.ReconcileDuration.Set(time.Since(start).Seconds()) }()
+
+	jobs, err := r.read()
+	if err != nil {
+		r.sched.RecordReload(&core.Reload{Error: err.Error()})
+		return err
+	}
+
+	r.reconcile(jobs)
+	return nil
+}
+
+// read reads the jobs of the docker labels
+func (r *labelsReconciler) read() ([]core.Job, error) {
+	labels, _, err := readLabels(r.client, r.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := config.buildFromDockerLabels(labels); err != nil {
+		return nil, err
+	}
+
+	config.applyLabelPolicy(r.opts.Policy, r.sched.Logger)
+
+	return config.buildJobs(r.client, r.sched.DockerMonitor)
+}
+
+// reconcile adds, updates and removes the jobs of the scheduler to match the
+// ones of the docker labels, but the jobs of on-success/on-failure cycles,
+// logged and rejected
+func (r *labelsReconciler) reconcile(jobs []core.Job) {
+	for cycle := triggerCycle(jobs); cycle != nil; cycle = triggerCycle(jobs) {
+		r.sched.Logger.Errorf(
+			"Rejected the jobs %s of the docker labels, on-success/on-failure cycle",
+			strings.Join(cycle, " -> "),
+		)
+
+		jobs = withoutJobs(jobs, cycle)
+	}
+
+	(&jobsReconciler{
+		sched: r.sched,
+		actor: auditActorDockerLabels,
+		name:  "Docker labels reconciliation",
+	}).reconcile(jobs)
+}
+
+// withoutJobs returns the given jobs but the ones with the given names
+func withoutJobs(jobs []core.Job, names []string) []core.Job {
+	removed := make(map[string]bool, len(names))
+	for _, name := range names {
+		removed[name] = true
+	}
+
+	var kept []core.Job
+	for _, j := range jobs {
+		if !removed[j.GetName()] {
+			kept = append(kept, j)
+		}
+	}
+
+	return kept
+}
+
+// jobsReconciler converges the jobs of a scheduler to a desired set of jobs
+type jobsReconciler struct {
+	sched *core.Scheduler
+	// actor of the changes recorded in the audit log
+	actor string
+	// name of the reconciliation in the logs
+	name string
+	// dryRun when true, the scheduler isn't changed, only the diff returned
+	dryRun bool
+}
+
+// reconcile adds, updates and removes the jobs of the scheduler to match the
+// given ones, logging the params changed and recording the reload, returning
+// the diff
+func (r *jobsReconciler) reconcile(jobs []core.Job) *api.ApplyResult {
+	desired := make(map[string]core.Job)
+	for _, j := range jobs {
+		desired[jobKey(j)] = j
+	}
+
+	diff := &api.ApplyResult{DryRun: r.dryRun}
+	current := make(map[string]core.Job)
+	for _, j := range r.sched.GetJobs() {
+		current[jobKey(j)] = j
+	}
+
+	for _, key := range sortedJobKeys(current) {
+		j := current[key]
+		if _, ok := desired[key]; ok {
+			continue
+		}
+
+		if !r.dryRun {
+			if err := r.sched.RemoveJob(j); err != nil {
+				r.log("remove", j, err)
+				continue
+			}
+
+			r.log("removed", j, nil)
+			r.sched.Record(core.NewAuditEntry(r.actor, core.AuditRemove, j, nil))
+		}
+
+		diff.Removed = append(diff.Removed, j.GetName())
+	}
+
+	for _, key := range sortedJobKeys(desired) {
+		j := desired[key]
+		c, ok := current[key]
+		if !ok {
+			// removed after running its "@at" schedule
+			if core.JobRemoveAfterRun(j) && !r.sched.Completed(j).IsZero() {
+				continue
+			}
+
+			if !r.dryRun {
+				addJob(r.sched, j)
+				r.log("added", j, nil)
+				r.sched.Record(core.NewAuditEntry(r.actor, core.AuditAdd, nil, j))
+			}
+
+			diff.Added = append(diff.Added, j.GetName())
+			continue
+		}
+
+		if fingerprint(c) == fingerprint(j) {
+			continue
+		}
+
+		entry := core.NewAuditEntry(r.actor, core.AuditUpdate, c, j)
+		if !r.dryRun {
+			if err := r.sched.RemoveJob(c); err != nil {
+				r.log("update", j, err)
+				continue
+			}
+
+			addJob(r.sched, j)
+			r.logUpdate(j, entry.Diff)
+			r.sched.Record(entry)
+		}
+
+		diff.Updated = append(diff.Updated, j.GetName())
+		if changed := changedParams(entry.Diff); len(changed) > 0 {
+			if diff.Changed == nil {
+				diff.Changed = make(map[string][]string)
+			}
+
+			diff.Changed[j.GetName()] = changed
+		}
+	}
+
+	if r.dryRun {
+		return diff
+	}
+
+	if err := checkTriggers(r.sched); err != nil {
+		r.sched.Logger.Warningf("%s: %s", r.name, err)
+	}
+
+	addSecrets(r.sched)
+	r.sched.RecordReload(&core.Reload{Added: diff.Added, Updated: diff.Updated, Removed: diff.Removed})
+	middlewares.AnnounceReload(r.sched, diff.Added, diff.Updated, diff.Removed)
+	return diff
+}
+
+func (r *jobsReconciler) log(action string, j core.Job, err error) {
+	if err != nil {
+		r.sched.Logger.Errorf(
+			"%s: action=%s job=%q error=%q",
+			r.name, action, j.GetName(), err,
+		)
+
+		return
+	}
+
+	r.sched.Logger.Noticef(
+		"%s: action=%s job=%q type=%s schedule=%q",
+		r.name, action, j.GetName(), jobType(j), j.GetSchedule(),
+	)
+}
+
+// logUpdate logs the update of the job with the names of its changed params,
+// not their values, that may be secrets
+func (r *jobsReconciler) logUpdate(j core.Job, diff map[string]core.AuditChange) {
+	r.sched.Logger.Noticef(
+		"%s: action=updated job=%q type=%s schedule=%q changed=%s",
+		r.name, j.GetName(), jobType(j), j.GetSchedule(), strings.Join(changedParams(diff), ","),
+	)
+}
+
+// changedParams returns the sorted names of the params of the given diff
+func changedParams(diff map[string]core.AuditChange) []string {
+	changed := make([]string, 0, len(diff))
+	for param := range diff {
+		changed = append(changed, param)
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+func sortedJobKeys(jobs map[string]core.Job) []string {
+	keys := make([]string, 0, len(jobs))
+	for k := range jobs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func jobKey(j core.Job) string {
+	return jobType(j) + "." + j.GetName()
+}
+
+func jobType(j core.Job) string {
+	switch j.(type) {
+	case *ExecJobConfig:
+		return jobExec
+	case *RunJobConfig:
+		return jobRun
+	case *LocalJobConfig:
+		return jobLocal
+	case *RunServiceConfig:
+		return jobServiceRun
+	case *PipelineJobConfig:
+		return jobPipeline
+	case *BackupJobConfig:
+		return jobBackup
+	case *ECSJobConfig:
+		return jobECS
+	case *NomadJobConfig:
+		return jobNomad
+	}
+
+	return fmt.Sprintf("%T", j)
+}
+
+// fingerprint returns a value that changes when the config of the job changes,
+// the pipelines include the config of its steps
+func fingerprint(j core.Job) string {
+	b, _ := json.Marshal(j)
+	if p, ok := j.(*PipelineJobConfig); ok {
+		for _, step := range p.StepJobs() {
+			b = append(b, fingerprint(step)...)
+		}
+	}
+
+	return string(b)
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+	gcfg "gopkg.in/gcfg.v1"
+)
+
+type SuiteReconciler struct{}
+
+var _ = Suite(&SuiteReconciler{})
+
+func (s *SuiteReconciler) TestReconcile(c *C) {
+	sched, err := BuildFromString(`
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+
+		[job-local "updated"]
+		schedule = @every 10s
+		command = echo foo
+
+		[job-local "removed"]
+		schedule = @every 10s
+		command = echo removed
+	`)
+	c.Assert(err, IsNil)
+
+	kept := sched.GetJob("kept")
+
+	config := &Config{}
+	err = gcfg.ReadStringInto(config, `
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+
+		[job-local "updated"]
+		schedule = @every 10s
+		command = echo bar
+
+		[job-local "added"]
+		schedule = @every 10s
+		command = echo added
+	`)
+	c.Assert(err, IsNil)
+
+	jobs, err := config.buildJobs(nil, nil)
+	c.Assert(err, IsNil)
+
+	audit := &testAuditLog{}
+	sched.Audit = audit
+
+	r := &labelsReconciler{sched: sched}
+	r.reconcile(jobs)
+
+	c.Assert(sched.Jobs, HasLen, 3)
+	c.Assert(sched.GetJob("removed"), IsNil)
+	c.Assert(sched.GetJob("added"), NotNil)
+	c.Assert(sched.GetJob("kept"), Equals, kept)
+	c.Assert(sched.GetJob("updated").GetCommand(), Equals, "echo bar")
+
+	actions := make(map[string]string)
+	for _, e := range audit.entries {
+		c.Assert(e.Actor, Equals, auditActorDockerLabels)
+		actions[e.Job] = e.Action
+	}
+
+	c.Assert(actions, DeepEquals, map[string]string{
+		"removed": core.AuditRemove,
+		"added":   core.AuditAdd,
+		"updated": core.AuditUpdate,
+	})
+
+	reload := sched.LastReload()
+	c.Assert(reload, NotNil)
+	c.Assert(reload.Date.IsZero(), Equals, false)
+	c.Assert(reload.Added, DeepEquals, []string{"added"})
+	c.Assert(reload.Updated, DeepEquals, []string{"updated"})
+	c.Assert(reload.Removed, DeepEquals, []string{"removed"})
+}
+
+type testAuditLog struct {
+	entries []*core.AuditEntry
+}
+
+func (l *testAuditLog) Record(e *core.AuditEntry) error {
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+func (s *SuiteReconciler) TestFingerprintPipelineSteps(c *C) {
+	step := &LocalJobConfig{}
+	step.Command = "echo foo"
+
+	p := &PipelineJobConfig{}
+	p.SetStepJobs(step)
+	before := fingerprint(p)
+
+	step.Command = "echo bar"
+	c.Assert(fingerprint(p), Not(Equals), before)
+	c.Assert(jobKey(p), Equals, "job-pipeline.")
+
+	var j core.Job = step
+	c.Assert(jobType(j), Equals, jobLocal)
+}
+
+func (s *SuiteReconciler) TestReconcileTriggersCycle(c *C) {
+	sched, err := BuildFromString(`
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+	`)
+	c.Assert(err, IsNil)
+
+	logger := &errorLogger{Logger: sched.Logger}
+	sched.Logger = logger
+
+	config := &Config{}
+	c.Assert(gcfg.ReadStringInto(config, `
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+
+		[job-local "self"]
+		schedule = @every 10s
+		command = echo self
+		on-failure = self
+
+		[job-local "foo"]
+		schedule = @every 10s
+		command = echo foo
+		on-success = bar
+
+		[job-local "bar"]
+		command = echo bar
+		on-success = foo
+	`), IsNil)
+
+	jobs, err := config.buildJobs(nil, nil)
+	c.Assert(err, IsNil)
+
+	r := &labelsReconciler{sched: sched}
+	r.reconcile(jobs)
+
+	c.Assert(sched.GetJobs(), HasLen, 1)
+	c.Assert(sched.GetJob("kept"), NotNil)
+	c.Assert(logger.errors, DeepEquals, []string{
+		"Rejected the jobs bar -> foo -> bar of the docker labels, on-success/on-failure cycle",
+		"Rejected the jobs self -> self of the docker labels, on-success/on-failure cycle",
+	})
+}
+
+func (s *SuiteReconciler) TestReconcileConcurrently(c *C) {
+	sched, err := BuildFromString(`
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+	`)
+	c.Assert(err, IsNil)
+
+	config := &Config{}
+	c.Assert(gcfg.ReadStringInto(config, `
+		[job-local "kept"]
+		schedule = @every 10s
+		command = echo kept
+	`), IsNil)
+
+	jobs, err := config.buildJobs(nil, nil)
+	c.Assert(err, IsNil)
+
+	// the jobs added through the API while reconciling
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			j := &core.LocalJob{}
+			j.Name, j.Schedule, j.Command = "added", "@every 10s", "echo added"
+			sched.AddJob(j)
+			sched.RemoveJob(j)
+		}
+	}()
+
+	r := &labelsReconciler{sched: sched}
+	for i := 0; i < 100; i++ {
+		r.reconcile(jobs)
+	}
+
+	<-done
+	c.Assert(sched.GetJob("kept"), NotNil)
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+)
+
+// selfTestTimeout timeout of the connections to the notifiers
+const selfTestTimeout = 5 * time.Second
+
+// selfTestResult the result of a check of the self-test
+type selfTestResult struct {
+	// Subject of the check, e.g. `job "foo"` or `smtp`
+	Subject string
+	Check   string
+	Err     error
+}
+
+// selfTester runs non-destructive checks of the jobs of a scheduler and of
+// their notifiers, as the images, containers and networks they use
+type selfTester struct {
+	results []selfTestResult
+	// checked the notifier addresses already checked, shared by many jobs
+	checked map[string]bool
+}
+
+// selfTest runs the checks of the jobs of the given scheduler, returning their
+// results
+func selfTest(sched *core.Scheduler) []selfTestResult {
+	t := &selfTester{checked: make(map[string]bool)}
+	t.checkMiddlewares(sched.Middlewares())
+	for _, j := range sched.GetJobs() {
+		t.checkJob(j)
+		t.checkMiddlewares(j.Middlewares())
+	}
+
+	return t.results
+}
+
+func (t *selfTester) add(subject, check string, err error) {
+	t.results = append(t.results, selfTestResult{Subject: subject, Check: check, Err: err})
+}
+
+func (t *selfTester) checkJob(j core.Job) {
+	subject := fmt.Sprintf("%s %q", jobType(j), j.GetName())
+	switch j := j.(type) {
+	case *ExecJobConfig:
+		t.checkExecTarget(subject, j.Client, j.Container, j.ContainerLabel)
+	case *BackupJobConfig:
+		t.checkExecTarget(subject, j.Client, j.Container, j.ContainerLabel)
+	case *RunJobConfig:
+		if j.Image == "" || j.Container != "" {
+			t.checkContainer(subject, j.Client, j.Container)
+			return
+		}
+
+		t.checkImage(subject, j.Client, j.Image)
+		for _, s := range j.Sidecar {
+			if sc, err := core.ParseSidecar(s); err == nil {
+				t.checkImage(subject, j.Client, sc.Image)
+			}
+		}
+
+		t.checkNetwork(subject, j.Client, j.Network)
+		t.checkVolumes(subject, j.Volume)
+	case *RunServiceConfig:
+		t.checkImage(subject, j.Client, j.Image)
+		t.checkNetwork(subject, j.Client, j.Network)
+	}
+}
+
+func (t *selfTester) checkExecTarget(subject string, c *docker.Client, container string, labels []string) {
+	if len(labels) == 0 {
+		t.checkContainer(subject, c, container)
+		return
+	}
+
+	check := fmt.Sprintf("running containers with labels %q", strings.Join(labels, ","))
+	conts, err := c.ListContainers(docker.ListContainersOptions{
+		Filters: map[string][]string{"label": labels},
+	})
+
+	if err == nil && len(conts) == 0 {
+		err = fmt.Errorf("none found")
+	}
+
+	t.add(subject, check, err)
+}
+
+func (t *selfTester) checkContainer(subject string, c *docker.Client, container string) {
+	_, err := c.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container})
+	t.add(subject, fmt.Sprintf("container %s exists", container), err)
+}
+
+// checkImage checks the image is found locally, or in its registry
+func (t *selfTester) checkImage(subject string, c *docker.Client, image string) {
+	check := fmt.Sprintf("image %s resolvable", image)
+	if _, err := c.InspectImage(image); err == nil {
+		t.add(subject, check, nil)
+		return
+	}
+
+	_, err := c.InspectDistribution(image)
+	t.add(subject, check, err)
+}
+
+func (t *selfTester) checkNetwork(subject string, c *docker.Client, network string) {
+	if network == "" {
+		return
+	}
+
+	networks, err := c.FilteredListNetworks(docker.NetworkFilterOpts{
+		"name": map[string]bool{network: true},
+	})
+
+	if err == nil && len(networks) == 0 {
+		err = fmt.Errorf("not found")
+	}
+
+	t.add(subject, fmt.Sprintf("network %s exists", network), err)
+}
+
+// checkVolumes checks the host paths of the bind mounts exist, the host paths
+// aren't visible when running inside a container, neither the named volumes
+func (t *selfTester) checkVolumes(subject string, volumes []string) {
+	if IsDockerEnv {
+		return
+	}
+
+	for _, v := range volumes {
+		path := strings.Split(v, ":")[0]
+		if !filepath.IsAbs(path) {
+			continue
+		}
+
+		_, err := os.Stat(path)
+		t.add(subject, fmt.Sprintf("volume host path %s exists", path), err)
+	}
+}
+
+// checkMiddlewares checks the SMTP servers and the slack webhooks of the given
+// middlewares are reachable, without sending anything
+func (t *selfTester) checkMiddlewares(ms []core.Middleware) {
+	for _, m := range ms {
+		switch m := m.(type) {
+		case *middlewares.Mail:
+			t.checkAddress("smtp", net.JoinHostPort(m.SMTPHost, strconv.Itoa(m.SMTPPort)))
+		case *middlewares.Slack:
+			u, err := url.Parse(m.SlackWebhook)
+			if err != nil {
+				t.add("slack", "webhook valid", err)
+				continue
+			}
+
+			port := u.Port()
+			if port == "" {
+				port = "443"
+				if u.Scheme == "http" {
+					port = "80"
+				}
+			}
+
+			t.checkAddress("slack", net.JoinHostPort(u.Hostname(), port))
+		}
+	}
+}
+
+func (t *selfTester) checkAddress(subject, addr string) {
+	if t.checked[addr] {
+		return
+	}
+
+	t.checked[addr] = true
+	conn, err := net.DialTimeout("tcp", addr, selfTestTimeout)
+	if err == nil {
+		conn.Close()
+	}
+
+	t.add(subject, fmt.Sprintf("%s reachable", addr), err)
+}
+
+// writeSelfTestReport writes a line per check, returning the number of checks
+// failed
+func writeSelfTestReport(w io.Writer, results []selfTestResult) int {
+	var failed int
+	for _, r := range results {
+		if r.Err == nil {
+			fmt.Fprintf(w, "OK   %s: %s\n", r.Subject, r.Check)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(w, "FAIL %s: %s: %s\n", r.Subject, r.Check, r.Err)
+	}
+
+	fmt.Fprintf(w, "%d checks, %d failed\n", len(results), failed)
+	return failed
+}
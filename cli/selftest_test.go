@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	docker "github.com/fsouza/go-dockerclient"
+	dockertest "github.com/fsouza/go-dockerclient/testing"
+	"github.com/mcuadros/ofelia/core"
+	"github.com/mcuadros/ofelia/middlewares"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSelfTest struct{}
+
+var _ = Suite(&SuiteSelfTest{})
+
+func (s *SuiteSelfTest) TestSelfTest(c *C) {
+	server, err := dockertest.NewServer("127.0.0.1:0", nil, nil)
+	c.Assert(err, IsNil)
+	defer server.Stop()
+
+	client, err := docker.NewClient(server.URL())
+	c.Assert(err, IsNil)
+
+	c.Assert(client.PullImage(docker.PullImageOptions{Repository: "test"}, docker.AuthConfiguration{}), IsNil)
+	_, err = client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "foo",
+		Config: &docker.Config{Image: "test"},
+	})
+	c.Assert(err, IsNil)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	dir, err := ioutil.TempDir("", "ofelia")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	exec := &ExecJobConfig{ExecJob: core.ExecJob{Client: client, Container: "foo"}}
+	exec.Name, exec.Schedule = "exec", "@hourly"
+	missing := &ExecJobConfig{ExecJob: core.ExecJob{Client: client, Container: "bar"}}
+	missing.Name, missing.Schedule = "missing", "@hourly"
+	run := &RunJobConfig{RunJob: core.RunJob{
+		Client:  client,
+		Image:   "test",
+		Network: "baz",
+		Volume:  []string{dir + ":/data", filepath.Join(dir, "qux") + ":/qux", "named:/named"},
+	}}
+	run.Name, run.Schedule = "run", "@hourly"
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	smtpPort, _ := strconv.Atoi(port)
+	exec.Use(middlewares.NewMail(&middlewares.MailConfig{
+		EmailTo:   "foo@example.com",
+		SMTPHost:  "127.0.0.1",
+		SMTPPort:  smtpPort,
+		EmailFrom: "ofelia@example.com",
+	}))
+	missing.Use(middlewares.NewMail(&middlewares.MailConfig{
+		EmailTo:   "foo@example.com",
+		SMTPHost:  "127.0.0.1",
+		SMTPPort:  smtpPort,
+		EmailFrom: "ofelia@example.com",
+	}))
+
+	sched := core.NewScheduler(dockerLogger())
+	c.Assert(sched.AddJob(exec), IsNil)
+	c.Assert(sched.AddJob(missing), IsNil)
+	c.Assert(sched.AddJob(run), IsNil)
+
+	IsDockerEnv = false
+	results := selfTest(sched)
+
+	failed := make(map[string]bool)
+	for _, r := range results {
+		failed[r.Subject+": "+r.Check] = r.Err != nil
+	}
+
+	c.Assert(failed, DeepEquals, map[string]bool{
+		`job-exec "exec": container foo exists`:                  false,
+		`job-exec "missing": container bar exists`:               true,
+		`job-run "run": image test resolvable`:                   false,
+		`job-run "run": network baz exists`:                      true,
+		`job-run "run": volume host path ` + dir + ` exists`:     false,
+		`job-run "run": volume host path ` + dir + `/qux exists`: true,
+		`smtp: ` + l.Addr().String() + ` reachable`:              false,
+	})
+
+	var buf bytes.Buffer
+	c.Assert(writeSelfTestReport(&buf, results), Equals, 3)
+	c.Assert(buf.String(), Matches, `(?s)OK   job-exec "exec": container foo exists\n.*FAIL job-exec "missing": container bar exists: .*7 checks, 3 failed\n`)
+}
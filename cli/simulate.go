@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+var simulateTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// SimulateCommand fast-forwards the schedules of the config file, listing the
+// executions of the jobs in a period of time
+type SimulateCommand struct {
+	ConfigFile string `long:"config" description:"configuration file" default:"/etc/ofelia.conf"`
+	From       string `long:"from" description:"start of the period, e.g. 2020-01-01 or 2020-01-01T10:00, by default now"`
+	To         string `long:"to" description:"end of the period, by default 24h after the start"`
+	Limit      int    `long:"limit" description:"max number of executions listed" default:"1000"`
+	Run        bool   `long:"execute" description:"run the executions in order, with the clock set to their time"`
+}
+
+// Execute runs the simulation command
+func (c *SimulateCommand) Execute(args []string) error {
+	from, to, err := c.period(time.Now())
+	if err != nil {
+		return err
+	}
+
+	sched, err := BuildFromFile(c.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	sim := core.NewSimulation(sched)
+	firings, err := sim.Firings(from, to, c.Limit)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Found %d executions from %s to %s:\n", len(firings), from.Format(time.RFC3339), to.Format(time.RFC3339))
+	for _, f := range firings {
+		fmt.Printf("- %s name: %s command: %q\n", f.Time.Format(time.RFC3339), f.Job.GetName(), f.Job.GetCommand())
+		if c.Run {
+			sim.Run(f)
+		}
+	}
+
+	return nil
+}
+
+func (c *SimulateCommand) period(now time.Time) (time.Time, time.Time, error) {
+	from := now
+	if c.From != "" {
+		var err error
+		if from, err = parseSimulateTime(c.From); err != nil {
+			return from, from, fmt.Errorf("invalid --from: %s", err)
+		}
+	}
+
+	to := from.Add(24 * time.Hour)
+	if c.To != "" {
+		var err error
+		if to, err = parseSimulateTime(c.To); err != nil {
+			return from, to, fmt.Errorf("invalid --to: %s", err)
+		}
+	}
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("--to %q is before --from %q", c.To, c.From)
+	}
+
+	return from, to, nil
+}
+
+func parseSimulateTime(value string) (time.Time, error) {
+	for _, layout := range simulateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown time format %q", value)
+}
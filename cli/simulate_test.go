@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSimulate struct{}
+
+var _ = Suite(&SuiteSimulate{})
+
+func (s *SuiteSimulate) TestPeriod(c *C) {
+	now := time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)
+
+	from, to, err := (&SimulateCommand{}).period(now)
+	c.Assert(err, IsNil)
+	c.Assert(from, Equals, now)
+	c.Assert(to, Equals, now.Add(24*time.Hour))
+
+	from, to, err = (&SimulateCommand{From: "2020-02-01", To: "2020-02-01T12:30"}).period(now)
+	c.Assert(err, IsNil)
+	c.Assert(from, Equals, time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local))
+	c.Assert(to, Equals, time.Date(2020, 2, 1, 12, 30, 0, 0, time.Local))
+
+	_, _, err = (&SimulateCommand{From: "2020-02-01", To: "2020-01-01"}).period(now)
+	c.Assert(err, NotNil)
+
+	_, _, err = (&SimulateCommand{From: "foo"}).period(now)
+	c.Assert(err, ErrorMatches, `invalid --from: unknown time format "foo"`)
+}
@@ -1,6 +1,11 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mcuadros/ofelia/core"
+)
 
 // ValidateCommand validates the config file
 type ValidateCommand struct {
@@ -26,5 +31,9 @@ func (c *ValidateCommand) Execute(args []string) error {
 		)
 	}
 
+	if sections := core.DockerAPIUsed(config.Jobs); len(sections) > 0 {
+		fmt.Printf("Docker API sections used: %s\n", strings.Join(sections, ", "))
+	}
+
 	return nil
 }
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AfterDescriptor the schedules starting with it run the job the given
+// duration after its previous execution finished, e.g. "@after 15m", instead
+// of at a fixed rate
+const AfterDescriptor = "@after"
+
+// afterSchedule fires once, the given delay after the time it is scheduled
+// at, the job being scheduled again when the execution finishes, see
+// Scheduler.reschedule
+type afterSchedule struct {
+	delay time.Duration
+	fired bool
+}
+
+func (s *afterSchedule) Next(t time.Time) time.Time {
+	if s.fired {
+		return time.Time{}
+	}
+
+	s.fired = true
+	return t.Add(s.delay)
+}
+
+// parseAfter returns the delay of the given "@after" schedule, false if the
+// schedule isn't one
+func parseAfter(schedule string) (time.Duration, bool, error) {
+	fields := strings.Fields(schedule)
+	if len(fields) == 0 || fields[0] != AfterDescriptor {
+		return 0, false, nil
+	}
+
+	if len(fields) != 2 {
+		return 0, true, fmt.Errorf("invalid schedule %q, must be \"@after <duration>\", e.g. \"@after 15m\"", schedule)
+	}
+
+	d, err := time.ParseDuration(fields[1])
+	if err != nil || d < time.Second {
+		return 0, true, fmt.Errorf("invalid delay %q of schedule %q, must be a duration of at least 1s, e.g. 15m", fields[1], schedule)
+	}
+
+	return d, true, nil
+}
+
+// JobAfter returns the delay between the end of an execution of the given job
+// and the start of the next one if its schedule is "@after", zero otherwise
+func JobAfter(j Job) time.Duration {
+	d, _, _ := parseAfter(j.GetSchedule())
+	return d
+}
+
+// reschedule schedules the job with an "@after" schedule again, from now,
+// if it is still registered
+func (s *Scheduler) reschedule(j Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.entries[j]
+	if !ok {
+		return
+	}
+
+	s.cron.Remove(id)
+	s.entries[j] = s.cron.Schedule(&afterSchedule{delay: JobAfter(j)}, &jobWrapper{s, j})
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteAfter struct{}
+
+var _ = Suite(&SuiteAfter{})
+
+func (s *SuiteAfter) TestParseAfter(c *C) {
+	d, ok, err := parseAfter("@after 15m")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(d, Equals, 15*time.Minute)
+
+	_, ok, err = parseAfter("@every 15m")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	_, _, err = parseAfter("@after")
+	c.Assert(err, ErrorMatches, `invalid schedule "@after", must be "@after <duration>".*`)
+
+	_, _, err = parseAfter("@after 10ms")
+	c.Assert(err, ErrorMatches, `invalid delay "10ms" of schedule "@after 10ms", must be a duration of at least 1s.*`)
+}
+
+func (s *SuiteAfter) TestAfterSchedule(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := &afterSchedule{delay: 15 * time.Minute}
+
+	c.Assert(schedule.Next(now), Equals, now.Add(15*time.Minute))
+	// the next execution is scheduled when the running one finishes
+	c.Assert(schedule.Next(now.Add(15*time.Minute)).IsZero(), Equals, true)
+}
+
+func (s *SuiteAfter) TestReschedule(c *C) {
+	sc := NewScheduler(&TestLogger{})
+
+	job := &scheduledJob{scheduled: make(chan time.Time, 2)}
+	job.Name = "foo"
+	job.Schedule = "@after 1h"
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(JobAfter(job), Equals, time.Hour)
+
+	c.Assert(sc.Start(), IsNil)
+	defer sc.Stop()
+
+	w := &jobWrapper{sc, job}
+	first, _ := w.entry()
+
+	w.Run()
+	c.Assert(job.scheduled, HasLen, 1)
+
+	finished := time.Now()
+	next, ok := w.entry()
+	c.Assert(ok, Equals, true)
+	c.Assert(next.ID, Not(Equals), first.ID)
+	c.Assert(next.Next.After(first.Next), Equals, true)
+	c.Assert(next.Next.After(finished.Add(time.Hour)), Equals, false)
+	c.Assert(sc.cron.Entries(), HasLen, 1)
+
+	// removed while running
+	c.Assert(sc.RemoveJob(job), IsNil)
+	w.Run()
+	c.Assert(sc.cron.Entries(), HasLen, 0)
+}
+
+func (s *SuiteAfter) TestSimulation(c *C) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	sc := NewScheduler(&TestLogger{})
+
+	job := &TestJob{}
+	job.Schedule = "@after 15m"
+	c.Assert(sc.AddJob(job), IsNil)
+
+	firings, err := NewSimulation(sc).Firings(from, from.Add(time.Hour), 0)
+	c.Assert(err, IsNil)
+	c.Assert(firings, HasLen, 4)
+	for i := 1; i < len(firings); i++ {
+		c.Assert(firings[i].Time.Sub(firings[i-1].Time), Equals, 15*time.Minute)
+	}
+}
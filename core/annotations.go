@@ -0,0 +1,60 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JobDescription returns the description of the given job, empty if none
+func JobDescription(j Job) string {
+	if d, ok := j.(interface{ GetDescription() string }); ok {
+		return d.GetDescription()
+	}
+
+	return ""
+}
+
+// JobOwner returns the owner of the given job, empty if it has none
+func JobOwner(j Job) string {
+	if o, ok := j.(interface{ GetOwner() string }); ok {
+		return o.GetOwner()
+	}
+
+	return ""
+}
+
+// JobTags returns the tags of the given job, declared as "<name>=<value>",
+// by name
+func JobTags(j Job) (map[string]string, error) {
+	t, ok := j.(interface{ GetTags() []string })
+	if !ok || len(t.GetTags()) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range t.GetTags() {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid tag %q, must be <name>=<value>", tag)
+		}
+
+		tags[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return tags, nil
+}
+
+// JobHasTags returns true if the given job has any of the given tags, a tag
+// being "<name>", matching any value, or "<name>=<value>"
+func JobHasTags(j Job, tags []string) bool {
+	own, _ := JobTags(j)
+	for _, tag := range tags {
+		parts := strings.SplitN(tag, "=", 2)
+		value, ok := own[strings.TrimSpace(parts[0])]
+		if ok && (len(parts) == 1 || strings.TrimSpace(parts[1]) == value) {
+			return true
+		}
+	}
+
+	return false
+}
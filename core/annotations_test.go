@@ -0,0 +1,31 @@
+package core
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuiteAnnotations struct{}
+
+var _ = Suite(&SuiteAnnotations{})
+
+func (s *SuiteAnnotations) TestJobAnnotations(c *C) {
+	j := &TestJob{}
+	j.Description = "Says foo"
+	j.Owner = "team-a"
+	j.Tags = []string{"tier=critical", " env = production "}
+
+	c.Assert(JobDescription(j), Equals, "Says foo")
+	c.Assert(JobOwner(j), Equals, "team-a")
+
+	tags, err := JobTags(j)
+	c.Assert(err, IsNil)
+	c.Assert(tags, DeepEquals, map[string]string{"tier": "critical", "env": "production"})
+
+	j.Tags = []string{"critical"}
+	_, err = JobTags(j)
+	c.Assert(err, ErrorMatches, `invalid tag "critical", must be <name>=<value>`)
+
+	tags, err = JobTags(&TestJob{})
+	c.Assert(err, IsNil)
+	c.Assert(tags, IsNil)
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// durationSamples number of successful executions the usual duration of
+	// a job is computed from, the most recent ones
+	durationSamples = 100
+	// minDurationSamples number of successful executions required to detect
+	// anomalies
+	minDurationSamples = 10
+	// minAnomalyDeviation deviations below it are never anomalies, e.g. the
+	// jobs running always in about the same milliseconds
+	minAnomalyDeviation = time.Second
+)
+
+// HistoryReader is a HistoryStore able to read the stored executions, used to
+// know the usual duration of the jobs since the start
+type HistoryReader interface {
+	// Durations returns the durations of the last successful executions of
+	// the job with the given name, up to limit, from the most recent
+	Durations(job string, limit int) ([]time.Duration, error)
+}
+
+// DurationMonitor emits a ExecutionAnomaly event when the duration of a
+// successful execution of a job with a duration anomaly threshold deviates
+// from the mean duration of its previous successful executions. The jobs are
+// tracked by name, the durations are read from the history of the scheduler
+// if it's a HistoryReader.
+type DurationMonitor struct {
+	s           *Scheduler
+	unsubscribe func()
+
+	mu   sync.Mutex
+	jobs map[string]*durationStats
+}
+
+type durationStats struct {
+	// samples durations, from the oldest
+	samples []time.Duration
+}
+
+// WatchDurations returns a DurationMonitor watching the jobs of the given
+// scheduler, the jobs have to be added after it
+func WatchDurations(s *Scheduler) *DurationMonitor {
+	m := &DurationMonitor{s: s, jobs: make(map[string]*durationStats)}
+	m.unsubscribe = s.Events.Subscribe(m.handle)
+	return m
+}
+
+// Stop stops watching the jobs
+func (m *DurationMonitor) Stop() {
+	m.unsubscribe()
+}
+
+func (m *DurationMonitor) handle(e Event) {
+	switch e := e.(type) {
+	case *JobRegistered:
+		m.register(e.Job)
+	case *ExecutionFinished:
+		if !e.Execution.Failed {
+			m.finished(e.Job, e.Execution)
+		}
+	}
+}
+
+func (m *DurationMonitor) register(j Job) {
+	if durationAnomaly(j) == "" {
+		return
+	}
+
+	if _, _, err := parseDurationAnomaly(durationAnomaly(j)); err != nil {
+		m.s.Logger.Errorf("Job %q: %s", j.GetName(), err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.jobs[j.GetName()]; ok {
+		return
+	}
+
+	stats := &durationStats{}
+	m.jobs[j.GetName()] = stats
+
+	// the subscribers must not block
+	if h, ok := m.s.History.(HistoryReader); ok {
+		go m.load(j.GetName(), stats, h)
+	}
+}
+
+// load adds the durations stored in the history, before the ones of the
+// executions finished meanwhile
+func (m *DurationMonitor) load(name string, stats *durationStats, h HistoryReader) {
+	durations, err := h.Durations(name, durationSamples)
+	if err != nil {
+		m.s.Logger.Errorf("Unable to read the durations of job %q from the history: %s", name, err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := make([]time.Duration, 0, len(durations)+len(stats.samples))
+	for i := len(durations) - 1; i >= 0; i-- {
+		stored = append(stored, durations[i])
+	}
+
+	stats.samples = lastSamples(append(stored, stats.samples...))
+}
+
+func (m *DurationMonitor) finished(j Job, e *Execution) {
+	stddevs, percent, err := parseDurationAnomaly(durationAnomaly(j))
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	stats, ok := m.jobs[j.GetName()]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+
+	mean, stddev := stats.meanStdDev()
+	anomalous := len(stats.samples) >= minDurationSamples &&
+		isAnomaly(e.Duration, mean, stddev, stddevs, percent)
+
+	stats.samples = lastSamples(append(stats.samples, e.Duration))
+	m.mu.Unlock()
+
+	if !anomalous {
+		return
+	}
+
+	m.s.Logger.Warningf(
+		"Job %q execution %s took %s, usually %s ± %s",
+		j.GetName(), e.ID, e.Duration, mean, stddev,
+	)
+
+	m.s.Events.Publish(&ExecutionAnomaly{Job: j, Execution: e, Mean: mean, StdDev: stddev})
+}
+
+// lastSamples returns the most recent durationSamples of the given samples
+func lastSamples(samples []time.Duration) []time.Duration {
+	if len(samples) > durationSamples {
+		return samples[len(samples)-durationSamples:]
+	}
+
+	return samples
+}
+
+func (s *durationStats) meanStdDev() (mean, stddev time.Duration) {
+	if len(s.samples) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, d := range s.samples {
+		sum += float64(d)
+	}
+
+	m := sum / float64(len(s.samples))
+
+	var variance float64
+	for _, d := range s.samples {
+		variance += (float64(d) - m) * (float64(d) - m)
+	}
+
+	variance /= float64(len(s.samples))
+	return time.Duration(m), time.Duration(math.Sqrt(variance))
+}
+
+// isAnomaly returns if the duration deviates from the mean more than the given
+// number of standard deviations, or percentage of the mean, the one not zero
+func isAnomaly(d, mean, stddev time.Duration, stddevs, percent float64) bool {
+	deviation := math.Abs(float64(d - mean))
+	if deviation < float64(minAnomalyDeviation) {
+		return false
+	}
+
+	if percent > 0 {
+		return deviation > float64(mean)*percent/100
+	}
+
+	return deviation > float64(stddev)*stddevs
+}
+
+// parseDurationAnomaly parses a threshold, as a number of standard deviations,
+// e.g. "3", or a percentage of the mean, e.g. "50%"
+func parseDurationAnomaly(value string) (stddevs, percent float64, err error) {
+	v := strings.TrimSpace(value)
+	isPercent := strings.HasSuffix(v, "%")
+
+	n, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid duration-anomaly %q, must be a number of standard deviations, e.g. 3, or a percentage, e.g. 50%%", value)
+	}
+
+	if isPercent {
+		return 0, n, nil
+	}
+
+	return n, 0, nil
+}
+
+func durationAnomaly(j Job) string {
+	if a, ok := j.(interface{ GetDurationAnomaly() string }); ok {
+		return a.GetDurationAnomaly()
+	}
+
+	return ""
+}
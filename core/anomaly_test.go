@@ -0,0 +1,122 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteAnomaly struct{}
+
+var _ = Suite(&SuiteAnomaly{})
+
+func (s *SuiteAnomaly) TestParseDurationAnomaly(c *C) {
+	stddevs, percent, err := parseDurationAnomaly("3")
+	c.Assert(err, IsNil)
+	c.Assert(stddevs, Equals, 3.0)
+	c.Assert(percent, Equals, 0.0)
+
+	stddevs, percent, err = parseDurationAnomaly("50%")
+	c.Assert(err, IsNil)
+	c.Assert(stddevs, Equals, 0.0)
+	c.Assert(percent, Equals, 50.0)
+
+	for _, value := range []string{"", "-1", "foo", "%"} {
+		_, _, err = parseDurationAnomaly(value)
+		c.Assert(err, ErrorMatches, "invalid duration-anomaly .*")
+	}
+}
+
+func (s *SuiteAnomaly) TestIsAnomaly(c *C) {
+	mean, stddev := 10*time.Minute, time.Minute
+	c.Assert(isAnomaly(12*time.Minute, mean, stddev, 3, 0), Equals, false)
+	c.Assert(isAnomaly(14*time.Minute, mean, stddev, 3, 0), Equals, true)
+	c.Assert(isAnomaly(6*time.Minute, mean, stddev, 3, 0), Equals, true)
+	c.Assert(isAnomaly(14*time.Minute, mean, stddev, 0, 50), Equals, false)
+	c.Assert(isAnomaly(16*time.Minute, mean, stddev, 0, 50), Equals, true)
+
+	// the small deviations are ignored
+	c.Assert(isAnomaly(10*time.Millisecond, time.Millisecond, 0, 3, 0), Equals, false)
+}
+
+func finishedExecution(d time.Duration) *Execution {
+	e := NewExecution()
+	e.Duration = d
+	return e
+}
+
+func (s *SuiteAnomaly) TestAnomaly(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	m := WatchDurations(sc)
+	defer m.Stop()
+
+	var anomalies []*ExecutionAnomaly
+	sc.Events.Subscribe(func(e Event) {
+		if e, ok := e.(*ExecutionAnomaly); ok {
+			anomalies = append(anomalies, e)
+		}
+	})
+
+	job := &TestJob{}
+	job.Name = "foo"
+	job.DurationAnomaly = "3"
+	sc.AddTriggeredJob(job)
+
+	for i := 0; i < minDurationSamples; i++ {
+		d := 10*time.Minute + time.Duration(i%2)*time.Minute
+		sc.Events.Publish(&ExecutionFinished{Job: job, Execution: finishedExecution(d)})
+	}
+
+	c.Assert(anomalies, HasLen, 0)
+
+	// the failed executions are ignored
+	failed := finishedExecution(time.Hour)
+	failed.Failed = true
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: failed})
+	c.Assert(anomalies, HasLen, 0)
+
+	slow := finishedExecution(time.Hour)
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: slow})
+	c.Assert(anomalies, HasLen, 1)
+	c.Assert(anomalies[0].Execution, Equals, slow)
+	c.Assert(anomalies[0].Mean, Equals, 10*time.Minute+30*time.Second)
+	c.Assert(anomalies[0].StdDev, Equals, 30*time.Second)
+}
+
+type testHistoryReader struct {
+	testHistoryStore
+	durations []time.Duration
+}
+
+func (h *testHistoryReader) Durations(job string, limit int) ([]time.Duration, error) {
+	return h.durations, nil
+}
+
+func (s *SuiteAnomaly) TestLoadHistory(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.History = &testHistoryReader{durations: []time.Duration{time.Minute, 2 * time.Minute}}
+	m := WatchDurations(sc)
+	defer m.Stop()
+
+	job := &TestJob{}
+	job.Name = "foo"
+	job.DurationAnomaly = "50%"
+	sc.AddTriggeredJob(job)
+
+	for i := 0; i < 100; i++ {
+		m.mu.Lock()
+		n := len(m.jobs["foo"].samples)
+		m.mu.Unlock()
+
+		if n == 2 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c.Assert(m.jobs["foo"].samples, DeepEquals, []time.Duration{2 * time.Minute, time.Minute})
+}
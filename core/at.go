@@ -0,0 +1,99 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AtDescriptor the schedules starting with it run the job once at the given
+// time, as RFC 3339, e.g. "@at 2025-07-01T03:00:00Z"
+const AtDescriptor = "@at"
+
+// atSchedule fires once at the given time
+type atSchedule struct {
+	at time.Time
+}
+
+func (s atSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+
+	return time.Time{}
+}
+
+// parseAt returns the time of the given "@at" schedule, false if the schedule
+// isn't one
+func parseAt(schedule string) (time.Time, bool, error) {
+	fields := strings.Fields(schedule)
+	if len(fields) == 0 || fields[0] != AtDescriptor {
+		return time.Time{}, false, nil
+	}
+
+	if len(fields) != 2 {
+		return time.Time{}, true, fmt.Errorf("invalid schedule %q, must be \"@at <time>\", e.g. \"@at 2025-07-01T03:00:00Z\"", schedule)
+	}
+
+	at, err := time.Parse(time.RFC3339, fields[1])
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("invalid time %q of schedule %q, must be RFC 3339, e.g. 2025-07-01T03:00:00Z", fields[1], schedule)
+	}
+
+	return at, true, nil
+}
+
+// JobAt returns the time the given job runs at if its schedule is "@at", zero
+// otherwise
+func JobAt(j Job) time.Time {
+	at, _, _ := parseAt(j.GetSchedule())
+	return at
+}
+
+// JobRemoveAfterRun returns true if the given job with an "@at" schedule is
+// removed after running
+func JobRemoveAfterRun(j Job) bool {
+	if r, ok := j.(interface{ GetRemoveAfterRun() bool }); ok {
+		return r.GetRemoveAfterRun()
+	}
+
+	return false
+}
+
+// completion of the one-shot schedule of a job
+type completion struct {
+	schedule string
+	date     time.Time
+}
+
+// complete marks the one-shot schedule of the job as completed, removing the
+// job if remove-after-run is set
+func (s *Scheduler) complete(j Job) {
+	s.overridesMu.Lock()
+	s.completed[j.GetName()] = completion{schedule: j.GetSchedule(), date: s.Clock.Now()}
+	s.overridesMu.Unlock()
+
+	if !JobRemoveAfterRun(j) {
+		s.Logger.Noticef("Job %q completed its schedule %q", j.GetName(), j.GetSchedule())
+		return
+	}
+
+	s.Logger.Noticef("Job %q completed its schedule %q, removing it", j.GetName(), j.GetSchedule())
+	if err := s.RemoveJob(j); err != nil && err != ErrJobNotFound {
+		s.Logger.Errorf("Unable to remove completed job %q: %s", j.GetName(), err)
+	}
+}
+
+// Completed returns the time the given job completed its "@at" schedule, zero
+// if it didn't
+func (s *Scheduler) Completed(j Job) time.Time {
+	s.overridesMu.Lock()
+	defer s.overridesMu.Unlock()
+
+	c, ok := s.completed[j.GetName()]
+	if !ok || c.schedule != j.GetSchedule() {
+		return time.Time{}
+	}
+
+	return c.date
+}
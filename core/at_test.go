@@ -0,0 +1,90 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteAt struct{}
+
+var _ = Suite(&SuiteAt{})
+
+func (s *SuiteAt) TestParseAt(c *C) {
+	at, ok, err := parseAt("@at 2025-07-01T03:00:00Z")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(at, Equals, time.Date(2025, 7, 1, 3, 0, 0, 0, time.UTC))
+
+	_, ok, err = parseAt("@every 10s")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	_, ok, err = parseAt("@at")
+	c.Assert(ok, Equals, true)
+	c.Assert(err, ErrorMatches, `invalid schedule "@at", must be "@at <time>".*`)
+
+	_, _, err = parseAt("@at tomorrow")
+	c.Assert(err, ErrorMatches, `invalid time "tomorrow" of schedule "@at tomorrow", must be RFC 3339.*`)
+}
+
+func (s *SuiteAt) TestAtSchedule(c *C) {
+	at := time.Date(2025, 7, 1, 3, 0, 0, 0, time.UTC)
+	schedule := atSchedule{at: at}
+
+	c.Assert(schedule.Next(at.Add(-time.Hour)), Equals, at)
+	c.Assert(schedule.Next(at).IsZero(), Equals, true)
+	c.Assert(schedule.Next(at.Add(time.Hour)).IsZero(), Equals, true)
+}
+
+func (s *SuiteAt) TestSchedule(c *C) {
+	sc := NewScheduler(&TestLogger{})
+
+	job := &TestJob{}
+	job.Schedule = "@at 2025-07-01T03:00:00Z"
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(JobAt(job), Equals, time.Date(2025, 7, 1, 3, 0, 0, 0, time.UTC))
+
+	invalid := &TestJob{}
+	invalid.Schedule = "@at 2025-07-01"
+	c.Assert(sc.AddJob(invalid), ErrorMatches, "invalid time .*")
+	c.Assert(JobAt(invalid).IsZero(), Equals, true)
+
+	// without the offset of its group
+	sc.AddGroup(&Group{Name: "nightly", ScheduleOffset: time.Hour})
+	job.Group = "nightly"
+	schedule, err := sc.schedule(job)
+	c.Assert(err, IsNil)
+	c.Assert(schedule, Equals, atSchedule{at: JobAt(job)})
+}
+
+func (s *SuiteAt) TestComplete(c *C) {
+	sc := NewScheduler(&TestLogger{})
+
+	kept := &scheduledJob{scheduled: make(chan time.Time, 1)}
+	kept.Name = "kept"
+	kept.Schedule = "@at 2025-07-01T03:00:00Z"
+	c.Assert(sc.AddJob(kept), IsNil)
+
+	removed := &scheduledJob{scheduled: make(chan time.Time, 1)}
+	removed.Name = "removed"
+	removed.Schedule = "@at 2025-07-01T03:00:00Z"
+	removed.RemoveAfterRun = true
+	c.Assert(sc.AddJob(removed), IsNil)
+
+	c.Assert(sc.Completed(kept).IsZero(), Equals, true)
+
+	(&jobWrapper{sc, kept}).Run()
+	(&jobWrapper{sc, removed}).Run()
+	c.Assert(kept.scheduled, HasLen, 1)
+	c.Assert(removed.scheduled, HasLen, 1)
+
+	c.Assert(sc.Completed(kept).IsZero(), Equals, false)
+	c.Assert(sc.Completed(removed).IsZero(), Equals, false)
+	c.Assert(sc.GetJob("kept"), NotNil)
+	c.Assert(sc.GetJob("removed"), IsNil)
+
+	// rescheduled
+	kept.Schedule = "@at 2026-07-01T03:00:00Z"
+	c.Assert(sc.Completed(kept).IsZero(), Equals, true)
+}
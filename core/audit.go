@@ -0,0 +1,136 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Audit actions recorded by the scheduler
+const (
+	AuditLoad    = "load"
+	AuditAdd     = "add"
+	AuditRemove  = "remove"
+	AuditUpdate  = "update"
+	AuditTrigger = "trigger"
+	// AuditSilence and AuditUnsilence are recorded without job, see Silence
+	AuditSilence   = "silence"
+	AuditUnsilence = "unsilence"
+	// AuditSkip, AuditRunOnce and AuditCancelRun override the schedule of a
+	// job, see SkipJob and ScheduleRun
+	AuditSkip      = "skip"
+	AuditRunOnce   = "run-once"
+	AuditCancelRun = "cancel-run"
+)
+
+// AuditLog records the control actions performed on a scheduler, entries are
+// only appended, never modified.
+type AuditLog interface {
+	Record(e *AuditEntry) error
+}
+
+// AuditEntry is a control action on a job, or on the scheduler
+type AuditEntry struct {
+	Date time.Time
+	// Actor who performed the action, e.g. "docker-labels"
+	Actor  string
+	Action string
+	Job    string
+	// Diff the params of the job changed by the action, by name
+	Diff map[string]AuditChange `json:",omitempty"`
+}
+
+// AuditChange the values of a param before and after an action
+type AuditChange struct {
+	Before interface{} `json:",omitempty"`
+	After  interface{} `json:",omitempty"`
+}
+
+// NewAuditEntry returns an entry of the given action on a job, before is the
+// job replaced or removed, after the job added, any of them can be nil.
+func NewAuditEntry(actor, action string, before, after Job) *AuditEntry {
+	e := &AuditEntry{Actor: actor, Action: action}
+	if after != nil {
+		e.Job = after.GetName()
+	} else if before != nil {
+		e.Job = before.GetName()
+	}
+
+	b, a := jobParams(before), jobParams(after)
+	for k, v := range b {
+		if !reflect.DeepEqual(v, a[k]) {
+			e.addChange(k, AuditChange{Before: v, After: a[k]})
+		}
+	}
+
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			e.addChange(k, AuditChange{After: v})
+		}
+	}
+
+	return e
+}
+
+func (e *AuditEntry) addChange(param string, c AuditChange) {
+	if e.Diff == nil {
+		e.Diff = make(map[string]AuditChange)
+	}
+
+	e.Diff[param] = c
+}
+
+// jobParams returns the params of the job, as they are encoded in JSON
+func jobParams(j Job) map[string]interface{} {
+	if j == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(j)
+	if err != nil {
+		return nil
+	}
+
+	var params map[string]interface{}
+	json.Unmarshal(b, &params)
+	return params
+}
+
+// FileAuditLog is an AuditLog appending the entries, as JSON lines, to a file
+type FileAuditLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileAuditLog opens, or creates if missing, the given file
+func NewFileAuditLog(filename string) (*FileAuditLog, error) {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAuditLog{f: f}, nil
+}
+
+// Record appends the entry to the file
+func (l *FileAuditLog) Record(e *AuditEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	return l.f.Sync()
+}
+
+func (l *FileAuditLog) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteAudit struct{}
+
+var _ = Suite(&SuiteAudit{})
+
+func (s *SuiteAudit) TestNewAuditEntry(c *C) {
+	before := NewLocalJob(WithName("foo"), WithSchedule("@hourly"), WithCommand("echo foo"))
+	after := NewLocalJob(WithName("foo"), WithSchedule("@daily"), WithCommand("echo foo"))
+
+	e := NewAuditEntry("bar", AuditUpdate, before, after)
+	c.Assert(e.Actor, Equals, "bar")
+	c.Assert(e.Job, Equals, "foo")
+	c.Assert(e.Diff, DeepEquals, map[string]AuditChange{
+		"Schedule": {Before: "@hourly", After: "@daily"},
+	})
+
+	e = NewAuditEntry("bar", AuditRemove, before, nil)
+	c.Assert(e.Job, Equals, "foo")
+	c.Assert(e.Diff["Command"], DeepEquals, AuditChange{Before: "echo foo"})
+
+	e = NewAuditEntry("bar", AuditAdd, nil, after)
+	c.Assert(e.Diff["Command"], DeepEquals, AuditChange{After: "echo foo"})
+}
+
+func (s *SuiteAudit) TestFileAuditLog(c *C) {
+	dir, err := ioutil.TempDir("", "audit")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "audit.log")
+	for i := 0; i < 2; i++ {
+		l, err := NewFileAuditLog(filename)
+		c.Assert(err, IsNil)
+
+		sc := NewScheduler(&TestLogger{}, WithClock(fixedClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))))
+		sc.Audit = l
+
+		job := &TestJob{}
+		job.Name = "foo"
+		sc.AddTriggeredJob(job)
+
+		c.Assert(sc.TriggerJob("bar", "foo"), IsNil)
+		c.Assert(sc.TriggerJob("bar", "qux"), Equals, ErrJobNotFound)
+		sc.wg.Wait()
+		c.Assert(l.Close(), IsNil)
+	}
+
+	f, err := os.Open(filename)
+	c.Assert(err, IsNil)
+	defer f.Close()
+
+	var entries []*AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := &AuditEntry{}
+		c.Assert(json.Unmarshal(scanner.Bytes(), e), IsNil)
+		entries = append(entries, e)
+	}
+
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[1], DeepEquals, &AuditEntry{
+		Date:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Actor:  "bar",
+		Action: AuditTrigger,
+		Job:    "foo",
+	})
+}
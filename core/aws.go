@@ -0,0 +1,185 @@
+package core
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// awsTimeout timeout of the requests to the AWS services
+const awsTimeout = 30 * time.Second
+
+// AWSClient calls the APIs of the AWS services, e.g. Secrets Manager, ECS or
+// CloudWatch. The credentials are read by default from the AWS environment
+// variables, the AWS credentials file or the IAM role.
+type AWSClient struct {
+	Region string
+	// Endpoint of all the services, by default the one of each service in the
+	// region
+	Endpoint    string
+	Credentials *credentials.Credentials
+
+	client *http.Client
+	clock  Clock
+}
+
+// NewAWSClient returns a AWSClient of the given region
+func NewAWSClient(region string) *AWSClient {
+	return &AWSClient{
+		Region: region,
+		Credentials: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		}),
+		client: &http.Client{Timeout: awsTimeout},
+		clock:  systemClock{},
+	}
+}
+
+// Call calls the action of a service with a JSON API, e.g. "secretsmanager"
+// and "secretsmanager.GetSecretValue", decoding its response in out
+func (c *AWSClient) Call(service, action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-amz-json-1.1")
+	header.Set("X-Amz-Target", action)
+
+	resp, err := c.post(service, header, body)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Query calls the action of a service with a query API, e.g. "monitoring"
+// and "PutMetricData", of the given version, with the given params
+func (c *AWSClient) Query(service, action, version string, params url.Values) error {
+	form := url.Values{"Action": {action}, "Version": {version}}
+	for k, v := range params {
+		form[k] = v
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	resp, err := c.post(service, header, []byte(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// post posts the signed body to the service, failing if the response isn't
+// successful
+func (c *AWSClient) post(service string, header http.Header, body []byte) (*http.Response, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, c.Region)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = header
+	creds, err := c.Credentials.Get()
+	if err != nil {
+		return nil, fmt.Errorf("no AWS credentials: %s", err)
+	}
+
+	signAWSRequest(req, body, creds, c.Region, service, c.clock.Now())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s returned %s: %s", service, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return resp, nil
+}
+
+// signAWSRequest signs the request to the given service with the signature
+// version 4 of AWS
+func signAWSRequest(req *http.Request, body []byte, creds credentials.Value, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := strings.Join([]string{amzDate[:8], region, service, "aws4_request"}, "/")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		(&url.URL{Path: req.URL.Path}).EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signed,
+		hexSHA256(body),
+	}, "\n")
+
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonical))}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{amzDate[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	req.Header.Del("Host")
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign)),
+	))
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
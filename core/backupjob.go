@@ -0,0 +1,188 @@
+package core
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Compressions of the backups
+const (
+	BackupGzip = "gzip"
+	BackupNone = "none"
+)
+
+// backupDateFormat date of the backups in their file names, sortable
+const backupDateFormat = "20060102T150405Z"
+
+// BackupJob runs a dump command in a container, as ExecJob, storing its output
+// compressed in a dated file, in a directory or a S3 bucket, and removing the
+// oldest backups
+type BackupJob struct {
+	ExecJob `mapstructure:",squash"`
+	// Destination of the backups, a directory, e.g. "/backups", or a S3 bucket
+	// and prefix, e.g. "s3://backups/db"
+	Destination string
+	// Extension of the backup files before the compression one, e.g. "sql"
+	Extension string
+	Compress  string `default:"gzip"`
+	// MinSize the dumps smaller than this size in bytes fail the execution,
+	// e.g. the empty dumps
+	MinSize int64 `gcfg:"min-size" mapstructure:"min-size"`
+	// Keep number of backups kept, the oldest are removed, all if zero
+	Keep int
+
+	S3Endpoint  string `gcfg:"s3-endpoint" mapstructure:"s3-endpoint" default:"s3.amazonaws.com"`
+	S3Region    string `gcfg:"s3-region" mapstructure:"s3-region"`
+	S3AccessKey string `gcfg:"s3-access-key" mapstructure:"s3-access-key"`
+	S3SecretKey string `gcfg:"s3-secret-key" mapstructure:"s3-secret-key" secret:"true"`
+}
+
+func NewBackupJob(c *docker.Client, opts ...JobOption) *BackupJob {
+	j := &BackupJob{ExecJob: ExecJob{Client: c}}
+	j.apply(opts)
+	return j
+}
+
+func (j *BackupJob) Run(ctx *Context) error {
+	if j.Compress != BackupGzip && j.Compress != BackupNone {
+		return fmt.Errorf("invalid compress %q, must be gzip or none", j.Compress)
+	}
+
+	storage, err := newBackupStorage(j)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(storage.TempDir(), ".ofelia-backup-")
+	if err != nil {
+		return fmt.Errorf("error creating backup file: %s", err)
+	}
+
+	defer os.Remove(tmp.Name())
+	size, err := j.dump(ctx, tmp)
+	if err != nil {
+		return err
+	}
+
+	if size < j.MinSize {
+		return fmt.Errorf("backup too small, %d bytes, min %d", size, j.MinSize)
+	}
+
+	name := j.backupName(ctx.Execution.Date)
+	if err := storage.Store(name, tmp.Name()); err != nil {
+		return fmt.Errorf("error storing backup %q: %s", name, err)
+	}
+
+	ctx.Log(fmt.Sprintf("Stored backup %s, %d bytes uncompressed", name, size))
+	if err := j.rotate(ctx, storage); err != nil {
+		ctx.Warn("failed to remove old backups: " + err.Error())
+	}
+
+	return nil
+}
+
+// dump runs the dump command, writing its output to the given file, closed
+// once written, returning the size of the output
+func (j *BackupJob) dump(ctx *Context, f *os.File) (int64, error) {
+	var w io.WriteCloser = nopWriteCloser{f}
+	if j.Compress == BackupGzip {
+		w = gzip.NewWriter(f)
+	}
+
+	c := &countingWriter{w: w}
+	err := j.run(ctx, c)
+	if cerr := w.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("error compressing backup: %s", cerr)
+	}
+
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("error writing backup: %s", cerr)
+	}
+
+	return c.n, err
+}
+
+// backupName returns the name of the backup of the given date, e.g.
+// "db-20200101T000000Z.sql.gz"
+func (j *BackupJob) backupName(date time.Time) string {
+	return j.backupPrefix() + date.UTC().Format(backupDateFormat) + j.backupSuffix()
+}
+
+func (j *BackupJob) backupPrefix() string {
+	return j.Name + "-"
+}
+
+func (j *BackupJob) backupSuffix() string {
+	var suffix string
+	if j.Extension != "" {
+		suffix = "." + j.Extension
+	}
+
+	if j.Compress == BackupGzip {
+		suffix += ".gz"
+	}
+
+	return suffix
+}
+
+// rotate removes the oldest backups, keeping the last Keep ones
+func (j *BackupJob) rotate(ctx *Context, s backupStorage) error {
+	if j.Keep <= 0 {
+		return nil
+	}
+
+	names, err := s.List(j.backupPrefix())
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, name := range names {
+		date := strings.TrimSuffix(strings.TrimPrefix(name, j.backupPrefix()), j.backupSuffix())
+		if _, err := time.Parse(backupDateFormat, date); err == nil {
+			backups = append(backups, name)
+		}
+	}
+
+	if len(backups) <= j.Keep {
+		return nil
+	}
+
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-j.Keep] {
+		if err := s.Remove(name); err != nil {
+			return err
+		}
+
+		ctx.Log("Removed old backup " + name)
+	}
+
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteBackupJob struct {
+	client *docker.Client
+	dir    string
+}
+
+var _ = Suite(&SuiteBackupJob{})
+
+// SetUpTest uses the docker server and container of the exec jobs tests
+func (s *SuiteBackupJob) SetUpTest(c *C) {
+	exec := &SuiteExecJob{}
+	exec.SetUpTest(c)
+
+	s.client = exec.client
+	s.dir = c.MkDir()
+}
+
+func (s *SuiteBackupJob) job() *BackupJob {
+	job := NewBackupJob(s.client)
+	job.Name = "db"
+	job.Container = ContainerFixture
+	job.Command = "pg_dump app"
+	job.Destination = s.dir
+	job.Extension = "sql"
+	job.Compress = BackupGzip
+	return job
+}
+
+func (s *SuiteBackupJob) run(job *BackupJob, date time.Time) *Execution {
+	e := NewExecution()
+	e.clock = fixedClock(date)
+	e.Start()
+
+	ctx := &Context{Logger: &TestLogger{}, Job: job, Execution: e}
+	e.Stop(job.Run(ctx))
+	return e
+}
+
+func (s *SuiteBackupJob) TestRun(c *C) {
+	date := time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC)
+	e := s.run(s.job(), date)
+	c.Assert(e.Error, IsNil)
+
+	_, err := os.Stat(filepath.Join(s.dir, "db-20200101T020000Z.sql.gz"))
+	c.Assert(err, IsNil)
+}
+
+func (s *SuiteBackupJob) TestRunMinSize(c *C) {
+	job := s.job()
+	job.MinSize = 1024
+
+	e := s.run(job, time.Now())
+	c.Assert(e.Error, ErrorMatches, "backup too small, 0 bytes, min 1024")
+
+	files, err := ioutil.ReadDir(s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 0)
+}
+
+func (s *SuiteBackupJob) TestRotate(c *C) {
+	for _, name := range []string{
+		"db-20191230T020000Z.sql.gz",
+		"db-20191231T020000Z.sql.gz",
+		"db-notes.txt",
+		"other-20191230T020000Z.sql.gz",
+	} {
+		c.Assert(ioutil.WriteFile(filepath.Join(s.dir, name), nil, 0644), IsNil)
+	}
+
+	job := s.job()
+	job.Keep = 2
+
+	e := s.run(job, time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC))
+	c.Assert(e.Error, IsNil)
+
+	files, err := ioutil.ReadDir(s.dir)
+	c.Assert(err, IsNil)
+
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+
+	c.Assert(names, DeepEquals, []string{
+		"db-20191231T020000Z.sql.gz",
+		"db-20200101T020000Z.sql.gz",
+		"db-notes.txt",
+		"other-20191230T020000Z.sql.gz",
+	})
+}
+
+func (s *SuiteBackupJob) TestNewBackupStorage(c *C) {
+	job := s.job()
+	job.Destination = "s3://backups/db/"
+	job.S3Endpoint = "http://minio:9000"
+
+	storage, err := newBackupStorage(job)
+	c.Assert(err, IsNil)
+
+	s3 := storage.(*s3BackupStorage)
+	c.Assert(s3.bucket, Equals, "backups")
+	c.Assert(s3.prefix, Equals, "db/")
+	c.Assert(s3.client.EndpointURL().String(), Equals, "http://minio:9000")
+
+	job.Destination = "s3://"
+	_, err = newBackupStorage(job)
+	c.Assert(err, ErrorMatches, `missing bucket .*`)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// backupStorage stores the backups of a BackupJob
+type backupStorage interface {
+	// TempDir directory where the backups are written before being stored
+	TempDir() string
+	// Store stores the backup file at the given path with the given name
+	Store(name, path string) error
+	// List returns the names of the backups with the given prefix
+	List(prefix string) ([]string, error)
+	Remove(name string) error
+}
+
+func newBackupStorage(j *BackupJob) (backupStorage, error) {
+	if j.Destination == "" {
+		return nil, fmt.Errorf("missing backup destination")
+	}
+
+	if strings.HasPrefix(j.Destination, "s3://") {
+		return newS3BackupStorage(j)
+	}
+
+	return localBackupStorage(j.Destination), nil
+}
+
+// localBackupStorage stores the backups in a directory
+type localBackupStorage string
+
+// TempDir is the directory itself, so the backups are renamed, not copied
+func (s localBackupStorage) TempDir() string {
+	return string(s)
+}
+
+func (s localBackupStorage) Store(name, path string) error {
+	return os.Rename(path, filepath.Join(string(s), name))
+}
+
+func (s localBackupStorage) List(prefix string) ([]string, error) {
+	files, err := ioutil.ReadDir(string(s))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasPrefix(f.Name(), prefix) {
+			names = append(names, f.Name())
+		}
+	}
+
+	return names, nil
+}
+
+func (s localBackupStorage) Remove(name string) error {
+	return os.Remove(filepath.Join(string(s), name))
+}
+
+// s3BackupStorage stores the backups in a S3 bucket, under a prefix
+type s3BackupStorage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3BackupStorage(j *BackupJob) (*s3BackupStorage, error) {
+	parts := strings.SplitN(strings.TrimPrefix(j.Destination, "s3://"), "/", 2)
+	s := &s3BackupStorage{bucket: parts[0]}
+	if s.bucket == "" {
+		return nil, fmt.Errorf("missing bucket at destination %q", j.Destination)
+	}
+
+	if len(parts) == 2 && strings.Trim(parts[1], "/") != "" {
+		s.prefix = strings.Trim(parts[1], "/") + "/"
+	}
+
+	// without keys, the credentials are read from the AWS environment variables
+	creds := credentials.NewEnvAWS()
+	if j.S3AccessKey != "" {
+		creds = credentials.NewStaticV4(j.S3AccessKey, j.S3SecretKey, "")
+	}
+
+	// the endpoints are HTTPS, unless given as http://host:port
+	endpoint := strings.TrimPrefix(j.S3Endpoint, "https://")
+	secure := !strings.HasPrefix(endpoint, "http://")
+	client, err := minio.New(strings.TrimPrefix(endpoint, "http://"), &minio.Options{
+		Creds:  creds,
+		Secure: secure,
+		Region: j.S3Region,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.client = client
+	return s, nil
+}
+
+func (s *s3BackupStorage) TempDir() string {
+	return ""
+}
+
+func (s *s3BackupStorage) Store(name, path string) error {
+	_, err := s.client.FPutObject(context.Background(), s.bucket, s.prefix+name, path, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *s3BackupStorage) List(prefix string) ([]string, error) {
+	var names []string
+	for o := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{
+		Prefix: s.prefix + prefix,
+	}) {
+		if o.Err != nil {
+			return nil, o.Err
+		}
+
+		names = append(names, strings.TrimPrefix(o.Key, s.prefix))
+	}
+
+	return names, nil
+}
+
+func (s *s3BackupStorage) Remove(name string) error {
+	return s.client.RemoveObject(context.Background(), s.bucket, s.prefix+name, minio.RemoveObjectOptions{})
+}
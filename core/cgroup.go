@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// cgroupRoot mount point of the cgroup v2 hierarchy, overridden by the
+	// tests
+	cgroupRoot = "/sys/fs/cgroup"
+	// systemdRun command running the executions in a systemd slice,
+	// overridden by the tests
+	systemdRun = "systemd-run"
+)
+
+// ValidateCgroup checks the slice and cgroup options of the job
+func (j *LocalJob) ValidateCgroup() error {
+	if j.Slice != "" && j.Cgroup != "" {
+		return fmt.Errorf("slice and cgroup are exclusive")
+	}
+
+	if len(j.SliceProperty) > 0 && j.Slice == "" {
+		return fmt.Errorf("slice-property requires a slice")
+	}
+
+	if len(j.CgroupLimit) > 0 && j.Cgroup == "" {
+		return fmt.Errorf("cgroup-limit requires a cgroup")
+	}
+
+	for _, p := range j.SliceProperty {
+		if _, _, err := parseSetting("slice-property", p, "MemoryMax=512M"); err != nil {
+			return err
+		}
+	}
+
+	if j.Cgroup != "" {
+		if _, err := j.cgroupDir(); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range j.CgroupLimit {
+		file, _, err := parseSetting("cgroup-limit", l, "memory.max=512M")
+		if err != nil {
+			return err
+		}
+
+		if !strings.Contains(file, ".") || strings.ContainsRune(file, '/') {
+			return fmt.Errorf("invalid cgroup-limit %q, must be an interface file of a controller, e.g. memory.max=512M", l)
+		}
+	}
+
+	return nil
+}
+
+// parseSetting parses a "<key>=<value>" setting of the given option
+func parseSetting(option, s, example string) (key, value string, err error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("invalid %s %q, must be <name>=<value>, e.g. %s", option, s, example)
+	}
+
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), nil
+}
+
+// sliceCommand returns the given command run in a transient scope of the
+// slice of the job, with its properties
+func (j *LocalJob) sliceCommand(ctx *Context, args []string) []string {
+	cmd := []string{
+		systemdRun, "--scope", "--quiet",
+		"--slice=" + j.Slice,
+		"--description=ofelia job " + ctx.Job.GetName(),
+	}
+
+	for _, p := range j.SliceProperty {
+		cmd = append(cmd, "--property="+p)
+	}
+
+	return append(append(cmd, "--"), args...)
+}
+
+// cgroupDir returns the directory of the cgroup of the job, under the
+// root of the hierarchy
+func (j *LocalJob) cgroupDir() (string, error) {
+	rel := filepath.Clean(strings.TrimPrefix(j.Cgroup, "/"))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("invalid cgroup %q, must be a path of the cgroup hierarchy, e.g. ofelia/jobs", j.Cgroup)
+	}
+
+	return filepath.Join(cgroupRoot, rel), nil
+}
+
+// moveToCgroup moves the started command to the given cgroup, killing it if
+// not moved
+func (j *LocalJob) moveToCgroup(cmd *exec.Cmd, dir string) error {
+	procs := filepath.Join(dir, "cgroup.procs")
+	if err := ioutil.WriteFile(procs, []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("unable to move the command to the cgroup %q: %s", j.Cgroup, err)
+	}
+
+	return nil
+}
+
+// prepareCgroup creates the cgroup of the job, enabling the controllers of its
+// limits in its ancestors, and sets the limits
+func (j *LocalJob) prepareCgroup() (string, error) {
+	dir, err := j.cgroupDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("unable to create the cgroup %q: %s", j.Cgroup, err)
+	}
+
+	limits := make(map[string]string)
+	controllers := make(map[string]bool)
+	for _, l := range j.CgroupLimit {
+		file, value, err := parseSetting("cgroup-limit", l, "memory.max=512M")
+		if err != nil {
+			return "", err
+		}
+
+		limits[file] = value
+		controllers[file[:strings.Index(file, ".")]] = true
+	}
+
+	if len(controllers) > 0 {
+		var enable []string
+		for c := range controllers {
+			enable = append(enable, "+"+c)
+		}
+
+		sort.Strings(enable)
+
+		// the controllers are enabled from the root down, a cgroup only
+		// enables the ones enabled by its parent
+		var ancestors []string
+		for parent := filepath.Dir(dir); parent != cgroupRoot; parent = filepath.Dir(parent) {
+			ancestors = append([]string{parent}, ancestors...)
+		}
+
+		for _, parent := range append([]string{cgroupRoot}, ancestors...) {
+			control := filepath.Join(parent, "cgroup.subtree_control")
+			if err := ioutil.WriteFile(control, []byte(strings.Join(enable, " ")), 0644); err != nil {
+				return "", fmt.Errorf("unable to enable the controllers %s in %q: %s", strings.Join(enable, " "), parent, err)
+			}
+		}
+	}
+
+	for file, value := range limits {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			return "", fmt.Errorf("unable to set the cgroup-limit %s=%s: %s", file, value, err)
+		}
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteCgroup struct {
+	dir string
+}
+
+var _ = Suite(&SuiteCgroup{})
+
+func (s *SuiteCgroup) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	cgroupRoot = s.dir
+}
+
+func (s *SuiteCgroup) TearDownTest(c *C) {
+	cgroupRoot = "/sys/fs/cgroup"
+	systemdRun = "systemd-run"
+}
+
+func (s *SuiteCgroup) read(c *C, path ...string) string {
+	b, err := ioutil.ReadFile(filepath.Join(append([]string{s.dir}, path...)...))
+	c.Assert(err, IsNil)
+	return string(b)
+}
+
+func (s *SuiteCgroup) TestRunSlice(c *C) {
+	// prints the arguments instead of running the command in a scope
+	systemdRun = filepath.Join(s.dir, "systemd-run")
+	c.Assert(ioutil.WriteFile(systemdRun, []byte("#!/bin/sh\necho \"$@\"\n"), 0755), IsNil)
+
+	j := &LocalJob{Slice: "batch.slice", SliceProperty: []string{"MemoryMax=512M", "CPUQuota=50%"}}
+	j.Name = "backup"
+	j.Command = `echo foo`
+
+	e := NewExecution()
+	c.Assert(j.Run(&Context{Job: j, Execution: e}), IsNil)
+	c.Assert(e.OutputStream.String(), Equals, "--scope --quiet --slice=batch.slice --description=ofelia job backup "+
+		"--property=MemoryMax=512M --property=CPUQuota=50% -- echo foo\n")
+}
+
+func (s *SuiteCgroup) TestRunCgroup(c *C) {
+	j := &LocalJob{Cgroup: "ofelia/jobs", CgroupLimit: []string{"memory.max=512M", "pids.max=10"}}
+	j.Command = `sh -c "echo $$"`
+
+	e := NewExecution()
+	c.Assert(j.Run(&Context{Job: j, Execution: e}), IsNil)
+
+	pid := strings.TrimSpace(e.OutputStream.String())
+	c.Assert(s.read(c, "ofelia", "jobs", "cgroup.procs"), Equals, pid)
+	c.Assert(s.read(c, "ofelia", "jobs", "memory.max"), Equals, "512M")
+	c.Assert(s.read(c, "ofelia", "jobs", "pids.max"), Equals, "10")
+	c.Assert(s.read(c, "cgroup.subtree_control"), Equals, "+memory +pids")
+	c.Assert(s.read(c, "ofelia", "cgroup.subtree_control"), Equals, "+memory +pids")
+}
+
+func (s *SuiteCgroup) TestRunCgroupUnwritable(c *C) {
+	cgroupRoot = filepath.Join(s.dir, "missing", "file")
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "missing"), nil, 0644), IsNil)
+
+	j := &LocalJob{Cgroup: "ofelia"}
+	j.Command = `echo foo`
+
+	e := NewExecution()
+	err := j.Run(&Context{Job: j, Execution: e})
+	c.Assert(err, ErrorMatches, `unable to create the cgroup "ofelia": .*`)
+	c.Assert(e.OutputStream.String(), Equals, "")
+}
+
+func (s *SuiteCgroup) TestValidateCgroup(c *C) {
+	c.Assert((&LocalJob{}).ValidateCgroup(), IsNil)
+	c.Assert((&LocalJob{Slice: "batch.slice", SliceProperty: []string{"MemoryMax=1G"}}).ValidateCgroup(), IsNil)
+	c.Assert((&LocalJob{Cgroup: "/ofelia/jobs", CgroupLimit: []string{"cpu.max=50000 100000"}}).ValidateCgroup(), IsNil)
+
+	err := (&LocalJob{Slice: "batch.slice", Cgroup: "ofelia"}).ValidateCgroup()
+	c.Assert(err, ErrorMatches, `slice and cgroup are exclusive`)
+
+	err = (&LocalJob{SliceProperty: []string{"MemoryMax=1G"}}).ValidateCgroup()
+	c.Assert(err, ErrorMatches, `slice-property requires a slice`)
+
+	err = (&LocalJob{CgroupLimit: []string{"memory.max=1G"}}).ValidateCgroup()
+	c.Assert(err, ErrorMatches, `cgroup-limit requires a cgroup`)
+
+	err = (&LocalJob{Slice: "batch.slice", SliceProperty: []string{"MemoryMax"}}).ValidateCgroup()
+	c.Assert(err, ErrorMatches, `invalid slice-property "MemoryMax", must be <name>=<value>, e.g. MemoryMax=512M`)
+
+	err = (&LocalJob{Cgroup: "../escape"}).ValidateCgroup()
+	c.Assert(err, ErrorMatches, `invalid cgroup "../escape", must be a path of the cgroup hierarchy, e.g. ofelia/jobs`)
+
+	err = (&LocalJob{Cgroup: "ofelia", CgroupLimit: []string{"max=1G"}}).ValidateCgroup()
+	c.Assert(err, ErrorMatches, `invalid cgroup-limit "max=1G", must be an interface file of a controller, e.g. memory.max=512M`)
+}
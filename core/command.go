@@ -0,0 +1,110 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/gobs/args"
+)
+
+// shellCommand the command running the command of the jobs with shell
+var shellCommand = []string{"/bin/sh", "-c"}
+
+// CommandConfig how the command of a job is split in arguments, by default
+// as the words of a command line, with their quotes removed
+type CommandConfig struct {
+	// CommandArray the arguments of the command, used as they are instead of
+	// the command, e.g. for arguments with nested quotes
+	CommandArray []string `gcfg:"command-array" mapstructure:"command-array" json:",omitempty"`
+	// Shell when true, the command is run by "/bin/sh -c", e.g. for commands
+	// with pipes, "&&" or several lines
+	Shell bool `json:",omitempty"`
+	// Script an inline script, fed to the stdin of the interpreter instead of
+	// running the command
+	Script string `json:",omitempty"`
+	// Interpreter the command running the script, by default /bin/sh
+	Interpreter string `json:",omitempty"`
+}
+
+// JobScript returns the inline script of the given job, empty if none
+func JobScript(j Job) string {
+	if s, ok := j.(interface{ GetScript() string }); ok {
+		return s.GetScript()
+	}
+
+	return ""
+}
+
+// GetScript returns the inline script of the job
+func (c *CommandConfig) GetScript() string {
+	return c.Script
+}
+
+// validate checks the command-array isn't used with the given command, nor
+// with shell, and the script with none of them nor with the given stdin-from
+func (c *CommandConfig) validate(command, stdinFrom string) error {
+	if c.Script != "" {
+		switch {
+		case command != "" || len(c.CommandArray) > 0:
+			return errors.New("script is exclusive with command and command-array")
+		case c.Shell:
+			return errors.New("shell is unsupported with script")
+		case stdinFrom != "":
+			return errors.New("stdin-from is unsupported with script, it's fed to the interpreter")
+		}
+	}
+
+	if len(c.CommandArray) == 0 {
+		return nil
+	}
+
+	if command != "" {
+		return errors.New("command and command-array are exclusive")
+	}
+
+	if c.Shell {
+		return errors.New("shell is unsupported with command-array")
+	}
+
+	return nil
+}
+
+// commandArgs returns the arguments of the given command, rendered with the
+// params of the execution
+func (c *CommandConfig) commandArgs(ctx *Context, command string) ([]string, error) {
+	if c.Script != "" {
+		return args.GetArgs(c.interpreter()), nil
+	}
+
+	if len(c.CommandArray) > 0 {
+		argv := make([]string, len(c.CommandArray))
+		for i, a := range c.CommandArray {
+			v, err := ctx.Render(a)
+			if err != nil {
+				return nil, err
+			}
+
+			argv[i] = v
+		}
+
+		return argv, nil
+	}
+
+	cmd, err := ctx.Render(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Shell {
+		return append(append([]string{}, shellCommand...), cmd), nil
+	}
+
+	return args.GetArgs(cmd), nil
+}
+
+func (c *CommandConfig) interpreter() string {
+	if c.Interpreter == "" {
+		return shellCommand[0]
+	}
+
+	return c.Interpreter
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"io/ioutil"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteCommand struct{}
+
+var _ = Suite(&SuiteCommand{})
+
+func (s *SuiteCommand) TestCommandArgs(c *C) {
+	ctx := &Context{Execution: NewExecution()}
+
+	cfg := &CommandConfig{}
+	argv, err := cfg.commandArgs(ctx, `echo "foo bar"`)
+	c.Assert(err, IsNil)
+	c.Assert(argv, DeepEquals, []string{"echo", "foo bar"})
+
+	cfg.Shell = true
+	argv, err = cfg.commandArgs(ctx, "echo foo | wc -c\necho bar")
+	c.Assert(err, IsNil)
+	c.Assert(argv, DeepEquals, []string{"/bin/sh", "-c", "echo foo | wc -c\necho bar"})
+}
+
+func (s *SuiteCommand) TestCommandArgsArray(c *C) {
+	job := &TestJob{}
+	job.Templated = true
+
+	ctx := &Context{Job: job, Execution: NewExecution()}
+	ctx.Execution.Params = map[string]string{MatrixParam: "foo"}
+
+	cfg := &CommandConfig{CommandArray: []string{"psql", "-c", `SELECT '{{.matrix}}' AS "name"`}}
+	argv, err := cfg.commandArgs(ctx, "")
+	c.Assert(err, IsNil)
+	c.Assert(argv, DeepEquals, []string{"psql", "-c", `SELECT 'foo' AS "name"`})
+}
+
+func (s *SuiteCommand) TestCommandArgsScript(c *C) {
+	ctx := &Context{Execution: NewExecution()}
+
+	cfg := &CommandConfig{Script: "echo foo"}
+	argv, err := cfg.commandArgs(ctx, "")
+	c.Assert(err, IsNil)
+	c.Assert(argv, DeepEquals, []string{"/bin/sh"})
+
+	cfg.Interpreter = "python3 -u"
+	argv, err = cfg.commandArgs(ctx, "")
+	c.Assert(err, IsNil)
+	c.Assert(argv, DeepEquals, []string{"python3", "-u"})
+}
+
+func (s *SuiteCommand) TestStdinScript(c *C) {
+	j := &ExecJob{}
+	j.Script = "echo {{.matrix}}"
+	j.Templated = true
+
+	ctx := NewContext(NewScheduler(&TestLogger{}), j, NewExecution())
+	ctx.Execution.Params = map[string]string{MatrixParam: "foo"}
+
+	stdin, err := ctx.Stdin()
+	c.Assert(err, IsNil)
+	script, err := ioutil.ReadAll(stdin)
+	c.Assert(err, IsNil)
+	c.Assert(string(script), Equals, "echo foo")
+}
+
+func (s *SuiteCommand) TestValidate(c *C) {
+	cfg := &CommandConfig{}
+	c.Assert(cfg.validate("echo foo", ""), IsNil)
+
+	cfg.CommandArray = []string{"echo", "foo"}
+	c.Assert(cfg.validate("", ""), IsNil)
+	c.Assert(cfg.validate("echo foo", ""), ErrorMatches, "command and command-array are exclusive")
+
+	cfg.Shell = true
+	c.Assert(cfg.validate("", ""), ErrorMatches, "shell is unsupported with command-array")
+
+	cfg = &CommandConfig{Script: "echo foo"}
+	c.Assert(cfg.validate("", ""), IsNil)
+	c.Assert(cfg.validate("echo foo", ""), ErrorMatches, "script is exclusive with command and command-array")
+	c.Assert(cfg.validate("", "dump"), ErrorMatches, "stdin-from is unsupported with script.*")
+
+	cfg.Shell = true
+	c.Assert(cfg.validate("", ""), ErrorMatches, "shell is unsupported with script")
+}
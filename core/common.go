@@ -1,11 +1,14 @@
 package core
 
 import (
+	"bytes"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/armon/circbuf"
@@ -19,6 +22,17 @@ var (
 	ErrUnexpected         = errors.New("error unexpected, docker has returned exit code -1, maybe wrong user?")
 	ErrMaxTimeRunning     = errors.New("the job has exceed the maximum allowed time running")
 	ErrLocalImageNotFound = errors.New("couldn't find image on the host")
+	// ErrStdinExistingContainer the stdin of a existing container can't be fed
+	ErrStdinExistingContainer = errors.New("stdin-from requires a new container, unsupported with container")
+	// ErrScriptExistingContainer the script is fed to the stdin of a new
+	// container only
+	ErrScriptExistingContainer = errors.New("script requires a new container, unsupported with container")
+	// ErrContainerUnhealthy the container waited by wait-healthy became
+	// unhealthy
+	ErrContainerUnhealthy = errors.New("container is unhealthy")
+	// ErrSidecarExistingContainer the sidecars are started along a new
+	// container only
+	ErrSidecarExistingContainer = errors.New("sidecar requires a new container, unsupported with container")
 )
 
 const (
@@ -44,10 +58,15 @@ type Context struct {
 	Logger    Logger
 	Job       Job
 	Execution *Execution
+	// Volumes extra binds to be mounted in the containers created by the job
+	Volumes []string
 
 	current     int
 	executed    bool
 	middlewares []Middleware
+	// step marks the context of a step of a pipeline, run by its middlewares
+	// without the checks and the slots of the execution of the pipeline
+	step bool
 }
 
 func NewContext(s *Scheduler, j Job, e *Execution) *Context {
@@ -61,6 +80,10 @@ func NewContext(s *Scheduler, j Job, e *Execution) *Context {
 }
 
 func (c *Context) Start() {
+	if c.Scheduler != nil {
+		c.Execution.clock = c.Scheduler.Clock
+	}
+
 	c.Execution.Start()
 	c.Job.NotifyStart()
 }
@@ -92,9 +115,85 @@ func (c *Context) doNext() error {
 	}
 
 	c.executed = true
+	if c.step {
+		return c.runStep()
+	}
+
+	if err := c.checkSkew(); err != nil {
+		return err
+	}
+
+	checksum, err := c.checkUnchanged()
+	if err != nil {
+		return err
+	}
+
+	if JobNoop(c.Job) {
+		c.logNoop()
+		return nil
+	}
+
+	// the group slot first, not to hold a slot of the pool while waiting
+	releaseGroup := c.acquireGroup()
+	release := c.acquirePool()
+	err = c.Job.Run(c)
+	release()
+	releaseGroup()
+
+	if err == nil {
+		c.recordOutput()
+		c.recordChecksum(checksum)
+	}
+
+	c.redactOutput()
+	c.diffOutput(err == nil)
+
+	return c.redactor().RedactError(err)
+}
+
+// runStep runs the job of a step of a pipeline, without the checks and the
+// slots of the execution of the pipeline, only logging the noop ones
+func (c *Context) runStep() error {
+	if JobNoop(c.Job) {
+		c.logNoop()
+		return nil
+	}
+
 	return c.Job.Run(c)
 }
 
+// redactOutput masks the secrets in the output streams of the execution,
+// before the middlewares report them
+func (c *Context) redactOutput() {
+	r := c.redactor()
+	if r == nil {
+		return
+	}
+
+	for _, b := range []*circbuf.Buffer{c.Execution.OutputStream, c.Execution.ErrorStream} {
+		if b.TotalWritten() == 0 {
+			continue
+		}
+
+		redacted := r.Redact(b.String())
+		b.Reset()
+		b.Write([]byte(redacted))
+	}
+}
+
+func (c *Context) redactor() *Redactor {
+	if c.Scheduler == nil {
+		return nil
+	}
+
+	return c.Scheduler.Redactor
+}
+
+// Redact returns the given text with the secrets masked
+func (c *Context) Redact(s string) string {
+	return c.redactor().Redact(s)
+}
+
 func (c *Context) getNext() (Middleware, bool) {
 	if c.current >= len(c.middlewares) {
 		return nil, true
@@ -113,22 +212,84 @@ func (c *Context) Stop(err error) {
 	c.Job.NotifyStop()
 }
 
+// JobTemplated returns true if the command of the given job is a template
+// rendered with the params of the execution, the commands of the other jobs
+// being run as is, whatever their braces
+func JobTemplated(j Job) bool {
+	if t, ok := j.(interface{ GetTemplated() bool }); ok {
+		return t.GetTemplated()
+	}
+
+	return false
+}
+
+// Render renders the given command as a template with the params of the
+// execution, e.g. `pg_dump {{.matrix}}`, but the payload and path of the
+// triggers, given in the environment only. The commands of the jobs not
+// templated, see JobTemplated, are returned as is.
+func (c *Context) Render(command string) (string, error) {
+	if !JobTemplated(c.Job) {
+		return command, nil
+	}
+
+	t, err := template.New("command").Option("missingkey=zero").Parse(command)
+	if err != nil {
+		return "", fmt.Errorf("error parsing command template: %s", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	params := make(map[string]string, len(c.Execution.Params))
+	for k, v := range c.Execution.Params {
+		params[k] = v
+	}
+
+	for _, k := range triggerEnvParams {
+		delete(params, k)
+	}
+
+	if err := t.Execute(buf, params); err != nil {
+		return "", fmt.Errorf("error rendering command template: %s", err)
+	}
+
+	return buf.String(), nil
+}
+
 func (c *Context) Log(msg string) {
+	msg = c.Redact(msg)
 	args := []interface{}{c.Job.GetName(), c.Execution.ID, msg}
 
 	switch {
 	case c.Execution.Failed:
 		c.Logger.Errorf(logPrefix, args...)
+		c.Execution.addMessage(LevelError, msg)
 	case c.Execution.Skipped:
 		c.Logger.Warningf(logPrefix, args...)
+		c.Execution.addMessage(LevelWarning, msg)
 	default:
 		c.Logger.Noticef(logPrefix, args...)
+		c.Execution.addMessage(LevelNotice, msg)
 	}
 }
 
 func (c *Context) Warn(msg string) {
+	msg = c.Redact(msg)
 	args := []interface{}{c.Job.GetName(), c.Execution.ID, msg}
 	c.Logger.Warningf(logPrefix, args...)
+	c.Execution.addMessage(LevelWarning, msg)
+}
+
+// The levels of the messages logged about the executions
+const (
+	LevelNotice  = "notice"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// LogMessage is a message logged about a execution, redacted
+type LogMessage struct {
+	Date  time.Time
+	Level string
+	Text  string
 }
 
 // Execution contains all the information relative to a Job execution.
@@ -140,8 +301,29 @@ type Execution struct {
 	Failed    bool
 	Skipped   bool
 	Error     error
+	// ExitCode exit code of the command, if it ran
+	ExitCode int `json:",omitempty"`
+	// PullDuration time spent pulling images, if any
+	PullDuration time.Duration `json:",omitempty"`
+	// NotificationErrors errors of the notifiers failing to report the
+	// execution, by notifier
+	NotificationErrors map[string]string `json:",omitempty"`
+	// Params available to the command template, e.g. the matrix value
+	Params map[string]string `json:",omitempty"`
+	// ScheduledAt time the execution was scheduled at, zero if triggered
+	ScheduledAt time.Time `json:",omitempty"`
+	// Skew delay between the scheduled time and the run of the command
+	Skew time.Duration `json:",omitempty"`
+	// OutputDiff changed lines of the output since the last successful
+	// execution, if the job has output-diff and the output changed
+	OutputDiff string `json:",omitempty"`
 
 	OutputStream, ErrorStream *circbuf.Buffer `json:"-"`
+
+	clock Clock
+
+	messagesMu sync.Mutex
+	messages   []LogMessage
 }
 
 // NewExecution returns a new Execution, with a random ID
@@ -158,7 +340,31 @@ func NewExecution() *Execution {
 // Start start the exection, initialize the running flags and the start date.
 func (e *Execution) Start() {
 	e.IsRunning = true
-	e.Date = time.Now()
+	e.Date = e.now()
+}
+
+func (e *Execution) now() time.Time {
+	if e.clock == nil {
+		return time.Now()
+	}
+
+	return e.clock.Now()
+}
+
+func (e *Execution) addMessage(level, text string) {
+	e.messagesMu.Lock()
+	defer e.messagesMu.Unlock()
+
+	e.messages = append(e.messages, LogMessage{Date: e.now(), Level: level, Text: text})
+}
+
+// Messages returns the messages logged about the execution so far, e.g. its
+// start or the warnings of its middlewares
+func (e *Execution) Messages() []LogMessage {
+	e.messagesMu.Lock()
+	defer e.messagesMu.Unlock()
+
+	return append([]LogMessage(nil), e.messages...)
 }
 
 // Stop stops the executions, if a ErrSkippedExecution is given the exection
@@ -166,7 +372,7 @@ func (e *Execution) Start() {
 // failed. Also mark the exection as IsRunning false and save the duration time
 func (e *Execution) Stop(err error) {
 	e.IsRunning = false
-	e.Duration = time.Since(e.Date)
+	e.Duration = e.now().Sub(e.Date)
 
 	if err != nil && err != ErrSkippedExecution {
 		e.Error = err
@@ -213,6 +419,69 @@ func (c *middlewareContainer) Use(ms ...Middleware) {
 	}
 }
 
+// UseDistinct adds the given middlewares, several middlewares of the same type
+// can be used, the ones equal to a middleware already used are ignored, see
+// SameMiddleware
+func (c *middlewareContainer) UseDistinct(ms ...Middleware) {
+	if c.m == nil {
+		c.m = make(map[string]Middleware)
+	}
+
+	for _, m := range ms {
+		if m == nil || c.uses(m) {
+			continue
+		}
+
+		t := reflect.TypeOf(m).String()
+		for n := 1; c.m[t] != nil; n++ {
+			t = fmt.Sprintf("%s#%d", reflect.TypeOf(m), n)
+		}
+
+		c.order = append(c.order, t)
+		c.m[t] = m
+	}
+}
+
+func (c *middlewareContainer) uses(m Middleware) bool {
+	for _, used := range c.m {
+		if SameMiddleware(used, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SameMiddleware returns true if the given middlewares are of the same type,
+// with equal exported fields, e.g. two slack middlewares with the same config
+func SameMiddleware(a, b Middleware) bool {
+	if a == b {
+		return true
+	}
+
+	va, vb := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+	if va.Type() != vb.Type() || va.Kind() != reflect.Struct {
+		return false
+	}
+
+	// the middlewares without exported fields have no config to compare
+	var exported bool
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
+
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			return false
+		}
+
+		exported = true
+	}
+
+	return exported
+}
+
 func (c *middlewareContainer) Middlewares() []Middleware {
 	var ms []Middleware
 	for _, t := range c.order {
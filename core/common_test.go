@@ -184,6 +184,24 @@ func (s *SuiteCommon) TestContextNext(c *C) {
 	c.Assert(j.Called, Equals, 1)
 }
 
+func (s *SuiteCommon) TestContextNextNoop(c *C) {
+	m := &TestMiddleware{Nested: true}
+
+	j := &TestJob{}
+	j.Noop = true
+	j.Use(m)
+
+	h := NewScheduler(&TestLogger{})
+	ctx := NewContext(h, j, NewExecution())
+	ctx.Start()
+
+	c.Assert(ctx.Next(), IsNil)
+	c.Assert(m.Called, Equals, 1)
+	c.Assert(j.Called, Equals, 0)
+	c.Assert(ctx.Execution.IsRunning, Equals, false)
+	c.Assert(ctx.Execution.Failed, Equals, false)
+}
+
 func (s *SuiteCommon) TestExecutionStart(c *C) {
 	exe := &Execution{}
 	exe.Start()
@@ -230,6 +248,69 @@ func (s *SuiteCommon) TestExecutionStopErrorSkip(c *C) {
 	c.Assert(exe.Duration.Seconds() > .0, Equals, true)
 }
 
+func (s *SuiteCommon) TestContextLogMessages(c *C) {
+	job := &TestJob{}
+	job.Name = "foo"
+
+	r := NewRedactor()
+	r.AddSecret("s3cr3t")
+	sh := NewScheduler(&TestLogger{})
+	sh.Redactor = r
+
+	ctx := NewContext(sh, job, NewExecution())
+	ctx.Log("Started - echo s3cr3t")
+	ctx.Warn("foo")
+
+	m := ctx.Execution.Messages()
+	c.Assert(m, HasLen, 2)
+	c.Assert(m[0].Level, Equals, LevelNotice)
+	c.Assert(m[0].Text, Equals, "Started - echo *****")
+	c.Assert(m[1].Level, Equals, LevelWarning)
+	c.Assert(m[1].Text, Equals, "foo")
+}
+
+func (s *SuiteCommon) TestContextRender(c *C) {
+	job := &TestJob{}
+	job.Templated = true
+	ctx := &Context{Job: job, Execution: NewExecution()}
+
+	cmd, err := ctx.Render("echo {{.matrix}}")
+	c.Assert(err, IsNil)
+	c.Assert(cmd, Equals, "echo ")
+
+	ctx.Execution.Params = map[string]string{MatrixParam: "foo"}
+	cmd, err = ctx.Render("echo {{.matrix}}")
+	c.Assert(err, IsNil)
+	c.Assert(cmd, Equals, "echo foo")
+
+	_, err = ctx.Render("echo {{.matrix")
+	c.Assert(err, NotNil)
+}
+
+func (s *SuiteCommon) TestContextRenderTriggerParams(c *C) {
+	job := &TestJob{}
+	job.Templated = true
+	ctx := &Context{Job: job, Execution: NewExecution()}
+	ctx.Execution.Params = map[string]string{PayloadParam: "'; rm -rf / #", PathParam: "/tmp/x'y"}
+
+	cmd, err := ctx.Render("deploy '{{.payload}}' '{{.path}}'")
+	c.Assert(err, IsNil)
+	c.Assert(cmd, Equals, "deploy '' ''")
+}
+
+func (s *SuiteCommon) TestContextRenderNotTemplated(c *C) {
+	ctx := &Context{Job: &TestJob{}, Execution: NewExecution()}
+	ctx.Execution.Params = map[string]string{MatrixParam: "foo"}
+
+	cmd, err := ctx.Render(`docker ps --format '{{.Names}}' | grep {{.matrix}}`)
+	c.Assert(err, IsNil)
+	c.Assert(cmd, Equals, `docker ps --format '{{.Names}}' | grep {{.matrix}}`)
+
+	cmd, err = ctx.Render("echo {{.matrix")
+	c.Assert(err, IsNil)
+	c.Assert(cmd, Equals, "echo {{.matrix")
+}
+
 func (s *SuiteCommon) TestMiddlewareContainerUseTwice(c *C) {
 	mA := &TestMiddleware{}
 	mB := &TestMiddleware{}
@@ -267,6 +348,22 @@ func (s *SuiteCommon) TestMiddlewareContainerUseOder(c *C) {
 	c.Assert(ms[1], Equals, mA)
 }
 
+func (s *SuiteCommon) TestUseDistinct(c *C) {
+	mA, mB, mC := &TestMiddleware{}, &TestMiddleware{Nested: true}, &TestMiddleware{}
+
+	j := &TestJob{}
+	j.Use(mA)
+	j.UseDistinct(mB, mC, nil)
+	c.Assert(j.Middlewares(), DeepEquals, []Middleware{mA, mB})
+	c.Assert(j.Middlewares()[1], Equals, mB)
+}
+
+func (s *SuiteCommon) TestSameMiddleware(c *C) {
+	c.Assert(SameMiddleware(&TestMiddleware{}, &TestMiddleware{}), Equals, true)
+	c.Assert(SameMiddleware(&TestMiddleware{}, &TestMiddleware{Nested: true}), Equals, false)
+	c.Assert(SameMiddleware(&TestMiddleware{}, &TestMiddlewareAltA{}), Equals, false)
+}
+
 type TestMiddleware struct {
 	Called int
 	Nested bool
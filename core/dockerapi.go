@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const defaultAPIRetryBackoff = time.Second
+
+// DockerAPIConfig configures the timeout of the docker API calls of a job, and
+// the retries of the calls failing with transient errors, as a network blip.
+type DockerAPIConfig struct {
+	// APITimeout timeout of every API call, e.g. "30s", by default none
+	APITimeout string `gcfg:"api-timeout" mapstructure:"api-timeout"`
+	// APIRetries number of retries of the idempotent API calls
+	APIRetries int `gcfg:"api-retries" mapstructure:"api-retries"`
+	// APIRetryBackoff delay before the first retry, doubled on every retry
+	APIRetryBackoff string `gcfg:"api-retry-backoff" mapstructure:"api-retry-backoff"`
+}
+
+// call runs the given API call with the configured timeout
+func (c *DockerAPIConfig) call(fn func(context.Context) error) error {
+	timeout, err := parseDuration("api-timeout", c.APITimeout, 0)
+	if err != nil {
+		return err
+	}
+
+	if timeout == 0 {
+		return fn(context.Background())
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return fn(tctx)
+}
+
+// retry runs the given idempotent API call with the configured timeout,
+// retrying it with exponential backoff while it fails with transient errors
+func (c *DockerAPIConfig) retry(ctx *Context, op string, fn func(context.Context) error) error {
+	backoff, err := parseDuration("api-retry-backoff", c.APIRetryBackoff, defaultAPIRetryBackoff)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		err := c.call(fn)
+		if err == nil || i >= c.APIRetries || !isTransientError(err) {
+			return err
+		}
+
+		ctx.Warn(fmt.Sprintf("%s failed, retrying in %s: %s", op, backoff, err))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func parseDuration(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %s", name, value, err)
+	}
+
+	return d, nil
+}
+
+// isTransientError reports whether the error of a API call may not happen
+// again on a retry
+func isTransientError(err error) bool {
+	switch e := err.(type) {
+	case *docker.Error:
+		return e.Status >= 500
+	case net.Error:
+		// includes the url.Error returned on EOF
+		return true
+	}
+
+	switch err {
+	case context.DeadlineExceeded, io.EOF, io.ErrUnexpectedEOF, docker.ErrConnectionRefused:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	docker "github.com/fsouza/go-dockerclient"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDockerAPI struct{}
+
+var _ = Suite(&SuiteDockerAPI{})
+
+func (s *SuiteDockerAPI) TestRetry(c *C) {
+	ctx := NewContext(NewScheduler(&TestLogger{}), &TestJob{}, NewExecution())
+	cfg := &DockerAPIConfig{APIRetries: 2, APIRetryBackoff: "1ms"}
+
+	var calls int
+	err := cfg.retry(ctx, "test", func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return io.EOF
+		}
+
+		return nil
+	})
+
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 3)
+
+	calls = 0
+	err = cfg.retry(ctx, "test", func(context.Context) error {
+		calls++
+		return &docker.Error{Status: 404}
+	})
+
+	c.Assert(err, NotNil)
+	c.Assert(calls, Equals, 1)
+}
+
+func (s *SuiteDockerAPI) TestCallTimeout(c *C) {
+	cfg := &DockerAPIConfig{APITimeout: "10ms"}
+	err := cfg.call(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	c.Assert(err, Equals, context.DeadlineExceeded)
+
+	cfg = &DockerAPIConfig{APITimeout: "foo"}
+	err = cfg.call(func(context.Context) error { return nil })
+	c.Assert(err, ErrorMatches, `invalid api-timeout "foo".*`)
+}
+
+func (s *SuiteDockerAPI) TestIsTransientError(c *C) {
+	c.Assert(isTransientError(io.ErrUnexpectedEOF), Equals, true)
+	c.Assert(isTransientError(&docker.Error{Status: 503}), Equals, true)
+	c.Assert(isTransientError(&docker.Error{Status: 400}), Equals, false)
+	c.Assert(isTransientError(errors.New("foo")), Equals, false)
+	c.Assert(isTransientError(docker.ErrConnectionRefused), Equals, true)
+}
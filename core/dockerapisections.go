@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Sections of the docker API used by the jobs, named as the ones of the
+// docker socket proxies, e.g. tecnativa/docker-socket-proxy. The ping and
+// version endpoints are always used.
+const (
+	DockerAPIContainers = "containers"
+	DockerAPIExec       = "exec"
+	DockerAPIImages     = "images"
+	DockerAPINetworks   = "networks"
+	DockerAPIVolumes    = "volumes"
+	DockerAPIServices   = "services"
+	DockerAPITasks      = "tasks"
+	// DockerAPIPost the write calls of all the sections
+	DockerAPIPost = "post"
+)
+
+var dockerAPISections = []string{
+	DockerAPIContainers, DockerAPIExec, DockerAPIImages, DockerAPINetworks,
+	DockerAPIVolumes, DockerAPIServices, DockerAPITasks, DockerAPIPost,
+}
+
+// ValidateDockerAPI checks the given sections of the docker API are known
+func ValidateDockerAPI(sections []string) error {
+	for _, s := range sections {
+		if !containsString(dockerAPISections, s) {
+			return fmt.Errorf("unknown docker API section %q, must be one of %s", s, strings.Join(dockerAPISections, ", "))
+		}
+	}
+
+	return nil
+}
+
+// JobDockerAPI returns the sections of the docker API used by the given job,
+// sorted, none for the jobs not using docker
+func JobDockerAPI(j Job) []string {
+	var sections []string
+	if d, ok := j.(interface{ DockerAPI() []string }); ok {
+		sections = d.DockerAPI()
+	}
+
+	for _, p := range JobSkipIfUnchanged(j) {
+		if container, _, err := ParseChecksumPath(p); err == nil && container != "" {
+			sections = append(sections, DockerAPIContainers)
+		}
+	}
+
+	return uniqueSorted(sections)
+}
+
+// DockerAPIUsed returns the sections of the docker API used by the given
+// jobs, sorted, e.g. to be allowed by a docker socket proxy
+func DockerAPIUsed(jobs []Job) []string {
+	var sections []string
+	for _, j := range jobs {
+		sections = append(sections, JobDockerAPI(j)...)
+	}
+
+	return uniqueSorted(sections)
+}
+
+// CheckDockerAPI checks the given job only uses the given sections of the
+// docker API, all the sections are allowed if none is given
+func CheckDockerAPI(j Job, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, s := range JobDockerAPI(j) {
+		if !containsString(allowed, s) {
+			missing = append(missing, s)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("requires the docker API sections %s, not allowed by docker-api", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// DockerAPI returns the sections of the docker API used by the job
+func (j *ExecJob) DockerAPI() []string {
+	return []string{DockerAPIContainers, DockerAPIExec, DockerAPIPost}
+}
+
+// DockerAPI returns the sections of the docker API used by the job
+func (j *RunJob) DockerAPI() []string {
+	sections := []string{DockerAPIContainers, DockerAPIPost}
+	if j.Image != "" {
+		sections = append(sections, DockerAPIImages)
+	}
+
+	if j.Network != "" {
+		sections = append(sections, DockerAPINetworks)
+	}
+
+	if j.Workspace {
+		sections = append(sections, DockerAPIVolumes)
+	}
+
+	return sections
+}
+
+// DockerAPI returns the sections of the docker API used by the job
+func (j *RunServiceJob) DockerAPI() []string {
+	return []string{DockerAPIImages, DockerAPIServices, DockerAPITasks, DockerAPIPost}
+}
+
+// DockerAPI returns the sections of the docker API used by the job, its steps
+// using their own
+func (j *PipelineJob) DockerAPI() []string {
+	if j.Workspace == "" {
+		return nil
+	}
+
+	return []string{DockerAPIVolumes, DockerAPIPost}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func uniqueSorted(list []string) []string {
+	var unique []string
+	for _, s := range list {
+		if !containsString(unique, s) {
+			unique = append(unique, s)
+		}
+	}
+
+	sort.Strings(unique)
+	return unique
+}
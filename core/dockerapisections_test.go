@@ -0,0 +1,46 @@
+package core
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDockerAPISections struct{}
+
+var _ = Suite(&SuiteDockerAPISections{})
+
+func (s *SuiteDockerAPISections) TestJobDockerAPI(c *C) {
+	c.Assert(JobDockerAPI(&LocalJob{}), HasLen, 0)
+	c.Assert(JobDockerAPI(&ExecJob{}), DeepEquals, []string{"containers", "exec", "post"})
+	c.Assert(JobDockerAPI(&BackupJob{}), DeepEquals, []string{"containers", "exec", "post"})
+	c.Assert(JobDockerAPI(&RunJob{Container: "foo"}), DeepEquals, []string{"containers", "post"})
+	c.Assert(JobDockerAPI(&RunJob{Image: "alpine", Network: "backend", Workspace: true}), DeepEquals,
+		[]string{"containers", "images", "networks", "post", "volumes"})
+	c.Assert(JobDockerAPI(&RunServiceJob{}), DeepEquals, []string{"images", "post", "services", "tasks"})
+	c.Assert(JobDockerAPI(&PipelineJob{}), HasLen, 0)
+	c.Assert(JobDockerAPI(&PipelineJob{Workspace: "/workspace"}), DeepEquals, []string{"post", "volumes"})
+
+	j := &LocalJob{}
+	j.SkipIfUnchanged = []string{"/srv/site", "web:/var/www"}
+	c.Assert(JobDockerAPI(j), DeepEquals, []string{"containers"})
+}
+
+func (s *SuiteDockerAPISections) TestDockerAPIUsed(c *C) {
+	used := DockerAPIUsed([]Job{&LocalJob{}, &ExecJob{}, &RunJob{Image: "alpine"}})
+	c.Assert(used, DeepEquals, []string{"containers", "exec", "images", "post"})
+}
+
+func (s *SuiteDockerAPISections) TestCheckDockerAPI(c *C) {
+	j := &RunJob{Image: "alpine", Network: "backend"}
+	c.Assert(CheckDockerAPI(j, nil), IsNil)
+	c.Assert(CheckDockerAPI(j, []string{"containers", "images", "networks", "post"}), IsNil)
+
+	err := CheckDockerAPI(j, []string{"containers", "post"})
+	c.Assert(err, ErrorMatches, `requires the docker API sections images, networks, not allowed by docker-api`)
+}
+
+func (s *SuiteDockerAPISections) TestValidateDockerAPI(c *C) {
+	c.Assert(ValidateDockerAPI([]string{"containers", "exec"}), IsNil)
+
+	err := ValidateDockerAPI([]string{"container"})
+	c.Assert(err, ErrorMatches, `unknown docker API section "container", must be one of .*`)
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// DockerMonitor watches the docker daemon, pinging it periodically while is
+// reachable and, after a failure, with exponential backoff until it's
+// reachable again.
+type DockerMonitor struct {
+	Client *docker.Client
+	Logger Logger
+	// Interval between pings while the daemon is reachable
+	Interval time.Duration
+	// MinBackoff and MaxBackoff bound the delay between pings while the daemon
+	// is unreachable
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// running guards done, set while the monitoring runs
+	running  sync.Mutex
+	done     chan struct{}
+	mu       sync.Mutex
+	err      error
+	failures int
+	ready    chan struct{}
+}
+
+func NewDockerMonitor(c *docker.Client, l Logger) *DockerMonitor {
+	ready := make(chan struct{})
+	close(ready)
+
+	return &DockerMonitor{
+		Client:     c,
+		Logger:     l,
+		Interval:   10 * time.Second,
+		MinBackoff: time.Second,
+		MaxBackoff: time.Minute,
+		ready:      ready,
+	}
+}
+
+// Err returns the error of the last ping, nil while the daemon is reachable.
+// The monitoring starts on the first call.
+func (m *DockerMonitor) Err() error {
+	m.start()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Wait blocks until the daemon is reachable.
+func (m *DockerMonitor) Wait() {
+	m.start()
+
+	m.mu.Lock()
+	ready := m.ready
+	m.mu.Unlock()
+
+	<-ready
+}
+
+// Stop stops the monitoring, started again by the next call to Err or Wait.
+func (m *DockerMonitor) Stop() {
+	m.running.Lock()
+	defer m.running.Unlock()
+
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+}
+
+func (m *DockerMonitor) start() {
+	m.running.Lock()
+	defer m.running.Unlock()
+
+	if m.done != nil {
+		return
+	}
+
+	m.done = make(chan struct{})
+	delay := m.check()
+	go m.loop(delay, m.done)
+}
+
+func (m *DockerMonitor) loop(delay time.Duration, done chan struct{}) {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			t.Reset(m.check())
+		case <-done:
+			return
+		}
+	}
+}
+
+// check pings the daemon and returns the delay until the next ping
+func (m *DockerMonitor) check() time.Duration {
+	err := m.Client.Ping()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case err == nil && m.err != nil:
+		m.Logger.Noticef("Docker daemon is reachable again")
+		close(m.ready)
+	case err != nil && m.err == nil:
+		m.Logger.Errorf("Docker daemon is unreachable: %s", err)
+		m.ready = make(chan struct{})
+	}
+
+	m.err = err
+	if err == nil {
+		m.failures = 0
+		return m.Interval
+	}
+
+	delay := m.MinBackoff << uint(m.failures)
+	if delay > m.MaxBackoff || delay <= 0 {
+		delay = m.MaxBackoff
+	}
+
+	m.failures++
+	return delay
+}
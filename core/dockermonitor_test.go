@@ -0,0 +1,92 @@
+package core
+
+import (
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/fsouza/go-dockerclient/testing"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDockerMonitor struct {
+	server *testing.DockerServer
+	client *docker.Client
+}
+
+var _ = Suite(&SuiteDockerMonitor{})
+
+func (s *SuiteDockerMonitor) SetUpTest(c *C) {
+	var err error
+	s.server, err = testing.NewServer("127.0.0.1:0", nil, nil)
+	c.Assert(err, IsNil)
+
+	s.client, err = docker.NewClient(s.server.URL())
+	c.Assert(err, IsNil)
+}
+
+func (s *SuiteDockerMonitor) TearDownTest(c *C) {
+	s.server.Stop()
+}
+
+func (s *SuiteDockerMonitor) TestCheck(c *C) {
+	m := NewDockerMonitor(s.client, &TestLogger{})
+	m.MinBackoff = time.Second
+	m.MaxBackoff = 3 * time.Second
+	c.Assert(m.check(), Equals, m.Interval)
+	c.Assert(m.err, IsNil)
+
+	s.server.PrepareFailure("ping", "/_ping")
+	c.Assert(m.check(), Equals, time.Second)
+	c.Assert(m.check(), Equals, 2*time.Second)
+	c.Assert(m.check(), Equals, 3*time.Second)
+	c.Assert(m.err, NotNil)
+
+	waited := make(chan struct{})
+	go func() {
+		<-m.ready
+		close(waited)
+	}()
+
+	s.server.ResetFailure("ping")
+	c.Assert(m.check(), Equals, m.Interval)
+	c.Assert(m.err, IsNil)
+
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		c.Fatal("still waiting after the daemon is reachable")
+	}
+}
+
+func (s *SuiteDockerMonitor) TestErr(c *C) {
+	s.server.PrepareFailure("ping", "/_ping")
+
+	m := NewDockerMonitor(s.client, &TestLogger{})
+	defer m.Stop()
+
+	c.Assert(m.Err(), NotNil)
+}
+
+func (s *SuiteDockerMonitor) TestStop(c *C) {
+	m := NewDockerMonitor(s.client, &TestLogger{})
+	m.Stop()
+
+	c.Assert(m.Err(), IsNil)
+	c.Assert(m.done, NotNil)
+
+	sched := NewScheduler(&TestLogger{})
+	sched.DockerMonitor = m
+	job := &TestJob{}
+	job.Schedule = "@hourly"
+	c.Assert(sched.AddJob(job), IsNil)
+	c.Assert(sched.Start(), IsNil)
+	c.Assert(sched.Stop(), IsNil)
+	c.Assert(m.done, IsNil)
+	m.Stop()
+
+	// started again by the next check
+	s.server.PrepareFailure("ping", "/_ping")
+	c.Assert(m.Err(), NotNil)
+	c.Assert(m.done, NotNil)
+	m.Stop()
+}
@@ -0,0 +1,365 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobs/args"
+)
+
+// ECS launch types
+const (
+	ECSFargate  = "FARGATE"
+	ECSEC2      = "EC2"
+	ECSExternal = "EXTERNAL"
+)
+
+const (
+	ecsService  = "ecs"
+	ecsTarget   = "AmazonEC2ContainerServiceV20141113."
+	logsService = "logs"
+	logsTarget  = "Logs_20140328."
+
+	// defaultECSWatchInterval time between two descriptions of the task, the
+	// ECS API being rate limited
+	defaultECSWatchInterval = 6 * time.Second
+)
+
+// ECSJob runs a one-off task of AWS ECS, waiting for it to stop. The logs of
+// the container, sent to CloudWatch by the awslogs driver, are written to the
+// output of the execution and its exit code is the one of the execution.
+type ECSJob struct {
+	BareJob `mapstructure:",squash"`
+	// Region of the cluster, e.g. "eu-west-1"
+	Region  string
+	Cluster string
+	// TaskDefinition family, family:revision or ARN of the task definition
+	TaskDefinition string `gcfg:"task-definition" mapstructure:"task-definition"`
+	// LaunchType FARGATE, EC2 or EXTERNAL, the capacity provider strategy of
+	// the cluster if empty
+	LaunchType string `gcfg:"launch-type" mapstructure:"launch-type"`
+	// Container whose command and environment are overridden, whose logs are
+	// read and whose exit code is the result, the first of the task
+	// definition by default
+	Container string
+	// Subnet and SecurityGroup of the awsvpc network of the task, required by
+	// FARGATE
+	Subnet         []string
+	SecurityGroup  []string `gcfg:"security-group" mapstructure:"security-group"`
+	AssignPublicIP bool     `gcfg:"assign-public-ip" mapstructure:"assign-public-ip"`
+	Environment    []string
+
+	EnvConfig   `mapstructure:",squash"`
+	WatchConfig `mapstructure:",squash"`
+
+	aws *AWSClient
+}
+
+func NewECSJob(opts ...JobOption) *ECSJob {
+	j := &ECSJob{}
+	j.apply(opts)
+	return j
+}
+
+// Validate checks the options of the job
+func (j *ECSJob) Validate() error {
+	if j.Region == "" || j.Cluster == "" || j.TaskDefinition == "" {
+		return fmt.Errorf("region, cluster and task-definition are required")
+	}
+
+	switch j.LaunchType {
+	case "", ECSFargate, ECSEC2, ECSExternal:
+	default:
+		return fmt.Errorf("invalid launch-type %q, must be FARGATE, EC2 or EXTERNAL", j.LaunchType)
+	}
+
+	if _, err := j.watchInterval(); err != nil {
+		return err
+	}
+
+	_, err := j.maxRuntime()
+	return err
+}
+
+func (j *ECSJob) Run(ctx *Context) error {
+	if err := j.Validate(); err != nil {
+		return err
+	}
+
+	if j.aws == nil {
+		j.aws = NewAWSClient(j.Region)
+	}
+
+	def, err := j.describeTaskDefinition()
+	if err != nil {
+		return err
+	}
+
+	container, err := def.container(j.Container)
+	if err != nil {
+		return err
+	}
+
+	arn, err := j.runTask(ctx, container.Name)
+	if err != nil {
+		return err
+	}
+
+	ctx.Log(fmt.Sprintf("Started ECS task %s", arn))
+	task, err := j.waitTask(arn)
+	j.writeLogs(ctx, container, arn)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range task.Containers {
+		if c.Name != container.Name {
+			continue
+		}
+
+		if c.ExitCode == nil {
+			return fmt.Errorf("ECS task stopped without exit code: %s", task.reason(c))
+		}
+
+		ctx.Execution.ExitCode = *c.ExitCode
+		return exitCodeError(*c.ExitCode)
+	}
+
+	return fmt.Errorf("ECS task stopped without container %q: %s", container.Name, task.StoppedReason)
+}
+
+type ecsContainerDefinition struct {
+	Name             string
+	LogConfiguration *struct {
+		LogDriver string
+		Options   map[string]string
+	}
+}
+
+type ecsTaskDefinition struct {
+	ContainerDefinitions []ecsContainerDefinition
+}
+
+// container returns the container definition with the given name, the first
+// one if empty
+func (d *ecsTaskDefinition) container(name string) (*ecsContainerDefinition, error) {
+	for i, c := range d.ContainerDefinitions {
+		if name == "" || c.Name == name {
+			return &d.ContainerDefinitions[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("container %q not found in the ECS task definition", name)
+}
+
+type ecsContainer struct {
+	Name     string
+	ExitCode *int
+	Reason   string
+}
+
+type ecsTask struct {
+	TaskArn       string
+	LastStatus    string
+	StoppedReason string
+	Containers    []ecsContainer
+}
+
+// reason returns why the task stopped the container
+func (t *ecsTask) reason(c ecsContainer) string {
+	if c.Reason != "" {
+		return c.Reason
+	}
+
+	return t.StoppedReason
+}
+
+func (j *ECSJob) describeTaskDefinition() (*ecsTaskDefinition, error) {
+	var out struct{ TaskDefinition ecsTaskDefinition }
+	in := map[string]interface{}{"taskDefinition": j.TaskDefinition}
+	if err := j.aws.Call(ecsService, ecsTarget+"DescribeTaskDefinition", in, &out); err != nil {
+		return nil, fmt.Errorf("error describing ECS task definition: %s", err)
+	}
+
+	return &out.TaskDefinition, nil
+}
+
+func (j *ECSJob) runTask(ctx *Context, container string) (string, error) {
+	override, err := j.buildOverride(ctx, container)
+	if err != nil {
+		return "", err
+	}
+
+	in := map[string]interface{}{
+		"cluster":        j.Cluster,
+		"taskDefinition": j.TaskDefinition,
+		"count":          1,
+		"startedBy":      "ofelia",
+		"overrides": map[string]interface{}{
+			"containerOverrides": []interface{}{override},
+		},
+	}
+
+	if j.LaunchType != "" {
+		in["launchType"] = j.LaunchType
+	}
+
+	if len(j.Subnet) > 0 {
+		assign := "DISABLED"
+		if j.AssignPublicIP {
+			assign = "ENABLED"
+		}
+
+		vpc := map[string]interface{}{"subnets": j.Subnet, "assignPublicIp": assign}
+		if len(j.SecurityGroup) > 0 {
+			vpc["securityGroups"] = j.SecurityGroup
+		}
+
+		in["networkConfiguration"] = map[string]interface{}{"awsvpcConfiguration": vpc}
+	}
+
+	var out struct {
+		Tasks    []ecsTask
+		Failures []struct{ Arn, Reason, Detail string }
+	}
+
+	if err := j.aws.Call(ecsService, ecsTarget+"RunTask", in, &out); err != nil {
+		return "", fmt.Errorf("error running ECS task: %s", err)
+	}
+
+	if len(out.Failures) > 0 {
+		f := out.Failures[0]
+		return "", fmt.Errorf("error running ECS task: %s %s", f.Reason, f.Detail)
+	}
+
+	if len(out.Tasks) == 0 {
+		return "", fmt.Errorf("error running ECS task: no task started")
+	}
+
+	return out.Tasks[0].TaskArn, nil
+}
+
+// buildOverride returns the override of the container, with the command of
+// the job, if any, and its environment
+func (j *ECSJob) buildOverride(ctx *Context, container string) (map[string]interface{}, error) {
+	override := map[string]interface{}{"name": container}
+	if j.Command != "" {
+		cmd, err := ctx.Render(j.Command)
+		if err != nil {
+			return nil, err
+		}
+
+		override["command"] = args.GetArgs(cmd)
+	}
+
+	env, err := ctx.resolveSecrets(j.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	extra, err := ctx.Env()
+	if err != nil {
+		return nil, err
+	}
+
+	var vars []map[string]string
+	for _, v := range append(env, extra...) {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid environment variable %q", v)
+		}
+
+		vars = append(vars, map[string]string{"name": parts[0], "value": parts[1]})
+	}
+
+	if len(vars) > 0 {
+		override["environment"] = vars
+	}
+
+	return override, nil
+}
+
+// waitTask waits for the task to stop, stopping it after the max-runtime
+func (j *ECSJob) waitTask(arn string) (*ecsTask, error) {
+	interval, _ := j.watchInterval()
+	limit, _ := j.maxRuntime()
+
+	start := time.Now()
+	for {
+		var out struct{ Tasks []ecsTask }
+		in := map[string]interface{}{"cluster": j.Cluster, "tasks": []string{arn}}
+		if err := j.aws.Call(ecsService, ecsTarget+"DescribeTasks", in, &out); err != nil {
+			return nil, fmt.Errorf("error describing ECS task: %s", err)
+		}
+
+		if len(out.Tasks) == 0 {
+			return nil, fmt.Errorf("ECS task %s not found", arn)
+		}
+
+		if out.Tasks[0].LastStatus == "STOPPED" {
+			return &out.Tasks[0], nil
+		}
+
+		if time.Since(start) > limit {
+			in := map[string]interface{}{"cluster": j.Cluster, "task": arn, "reason": "max-runtime exceeded"}
+			if err := j.aws.Call(ecsService, ecsTarget+"StopTask", in, nil); err != nil {
+				return nil, fmt.Errorf("error stopping ECS task: %s", err)
+			}
+
+			return nil, ErrMaxTimeRunning
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// writeLogs writes to the output of the execution the CloudWatch logs of the
+// container, if sent by the awslogs driver with a stream prefix
+func (j *ECSJob) writeLogs(ctx *Context, container *ecsContainerDefinition, arn string) {
+	logs := container.LogConfiguration
+	if logs == nil || logs.LogDriver != "awslogs" || logs.Options["awslogs-stream-prefix"] == "" {
+		return
+	}
+
+	client := j.aws
+	if region := logs.Options["awslogs-region"]; region != "" && region != client.Region {
+		c := *client
+		c.Region = region
+		client = &c
+	}
+
+	id := arn[strings.LastIndex(arn, "/")+1:]
+	in := map[string]interface{}{
+		"logGroupName":  logs.Options["awslogs-group"],
+		"logStreamName": logs.Options["awslogs-stream-prefix"] + "/" + container.Name + "/" + id,
+		"startFromHead": true,
+	}
+
+	for {
+		var out struct {
+			Events           []struct{ Message string }
+			NextForwardToken string
+		}
+
+		if err := client.Call(logsService, logsTarget+"GetLogEvents", in, &out); err != nil {
+			ctx.Warn(fmt.Sprintf("Error reading the logs of the ECS task: %s", err))
+			return
+		}
+
+		for _, e := range out.Events {
+			fmt.Fprintln(ctx.Execution.OutputStream, e.Message)
+		}
+
+		// the same token is returned at the end of the stream
+		if len(out.Events) == 0 || out.NextForwardToken == in["nextToken"] {
+			return
+		}
+
+		in["nextToken"] = out.NextForwardToken
+	}
+}
+
+func (j *ECSJob) watchInterval() (time.Duration, error) {
+	return parseDuration("watch-interval", j.WatchInterval, defaultECSWatchInterval)
+}
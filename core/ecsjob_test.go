@@ -0,0 +1,151 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteECSJob struct {
+	servers []*httptest.Server
+}
+
+var _ = Suite(&SuiteECSJob{})
+
+const ecsTestArn = "arn:aws:ecs:eu-west-1:123456789012:task/prod/0123abcd"
+
+func (s *SuiteECSJob) newJob(c *C, handler func(action string, in map[string]interface{}) string) *ECSJob {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]interface{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&in), IsNil)
+
+		target := r.Header.Get("X-Amz-Target")
+		out := handler(target[strings.Index(target, ".")+1:], in)
+		if out == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "InvalidParameterException"}`))
+			return
+		}
+
+		w.Write([]byte(out))
+	}))
+
+	s.servers = append(s.servers, ts)
+
+	job := &ECSJob{Region: "eu-west-1", Cluster: "prod", TaskDefinition: "migrate"}
+	job.WatchInterval = "1ms"
+	job.aws = NewAWSClient(job.Region)
+	job.aws.Endpoint = ts.URL
+	job.aws.Credentials = credentials.NewStaticV4("key", "secret", "")
+	return job
+}
+
+func (s *SuiteECSJob) TearDownTest(c *C) {
+	for _, ts := range s.servers {
+		ts.Close()
+	}
+
+	s.servers = nil
+}
+
+func (s *SuiteECSJob) TestRun(c *C) {
+	var described int
+	job := s.newJob(c, func(action string, in map[string]interface{}) string {
+		switch action {
+		case "DescribeTaskDefinition":
+			c.Assert(in["taskDefinition"], Equals, "migrate")
+			return `{"taskDefinition": {"containerDefinitions": [{"name": "app", "logConfiguration": {
+				"logDriver": "awslogs",
+				"options": {"awslogs-group": "/ecs/migrate", "awslogs-stream-prefix": "ecs"}
+			}}]}}`
+		case "RunTask":
+			c.Assert(in["launchType"], Equals, ECSFargate)
+			override := in["overrides"].(map[string]interface{})["containerOverrides"].([]interface{})[0]
+			c.Assert(override, DeepEquals, map[string]interface{}{
+				"name":        "app",
+				"command":     []interface{}{"migrate", "--all"},
+				"environment": []interface{}{map[string]interface{}{"name": "FOO", "value": "bar"}},
+			})
+
+			vpc := in["networkConfiguration"].(map[string]interface{})["awsvpcConfiguration"]
+			c.Assert(vpc, DeepEquals, map[string]interface{}{
+				"subnets":        []interface{}{"subnet-1"},
+				"assignPublicIp": "DISABLED",
+			})
+
+			return `{"tasks": [{"taskArn": "` + ecsTestArn + `"}]}`
+		case "DescribeTasks":
+			described++
+			if described == 1 {
+				return `{"tasks": [{"lastStatus": "RUNNING"}]}`
+			}
+
+			return `{"tasks": [{"lastStatus": "STOPPED", "containers": [{"name": "app", "exitCode": 3}]}]}`
+		case "GetLogEvents":
+			c.Assert(in["logGroupName"], Equals, "/ecs/migrate")
+			c.Assert(in["logStreamName"], Equals, "ecs/app/0123abcd")
+			if in["nextToken"] == "f/2" {
+				return `{"events": [], "nextForwardToken": "f/2"}`
+			}
+
+			return `{"events": [{"message": "foo"}, {"message": "bar"}], "nextForwardToken": "f/2"}`
+		}
+
+		return ""
+	})
+
+	job.Command = "migrate --all"
+	job.LaunchType = ECSFargate
+	job.Subnet = []string{"subnet-1"}
+	job.Environment = []string{"FOO=bar"}
+
+	e := NewExecution()
+	err := job.Run(&Context{Logger: &TestLogger{}, Job: job, Execution: e})
+	c.Assert(err, ErrorMatches, "error non-zero exit code: 3")
+	c.Assert(e.ExitCode, Equals, 3)
+	c.Assert(described, Equals, 2)
+	c.Assert(e.OutputStream.String(), Equals, "foo\nbar\n")
+}
+
+func (s *SuiteECSJob) TestRunFailure(c *C) {
+	job := s.newJob(c, func(action string, in map[string]interface{}) string {
+		switch action {
+		case "DescribeTaskDefinition":
+			return `{"taskDefinition": {"containerDefinitions": [{"name": "app"}]}}`
+		case "RunTask":
+			return `{"failures": [{"reason": "RESOURCE:MEMORY", "detail": "no capacity"}]}`
+		}
+
+		return ""
+	})
+
+	err := job.Run(&Context{Logger: &TestLogger{}, Job: job, Execution: NewExecution()})
+	c.Assert(err, ErrorMatches, "error running ECS task: RESOURCE:MEMORY no capacity")
+}
+
+func (s *SuiteECSJob) TestRunUnknownContainer(c *C) {
+	job := s.newJob(c, func(action string, in map[string]interface{}) string {
+		return `{"taskDefinition": {"containerDefinitions": [{"name": "app"}]}}`
+	})
+
+	job.Container = "worker"
+	err := job.Run(&Context{Logger: &TestLogger{}, Job: job, Execution: NewExecution()})
+	c.Assert(err, ErrorMatches, `container "worker" not found in the ECS task definition`)
+}
+
+func (s *SuiteECSJob) TestValidate(c *C) {
+	job := &ECSJob{Region: "eu-west-1", Cluster: "prod"}
+	c.Assert(job.Validate(), ErrorMatches, "region, cluster and task-definition are required")
+
+	job.TaskDefinition = "migrate"
+	job.LaunchType = "fargate"
+	c.Assert(job.Validate(), ErrorMatches, `invalid launch-type "fargate", must be FARGATE, EC2 or EXTERNAL`)
+
+	job.LaunchType = ECSEC2
+	c.Assert(job.Validate(), IsNil)
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// paramEnvPrefix the prefix of the environment variables with the params of
+// the execution, e.g. OFELIA_PARENT_EXIT_CODE or OFELIA_PAYLOAD
+const paramEnvPrefix = "OFELIA_"
+
+// EnvConfig the environment of the command of a job
+type EnvConfig struct {
+	// EnvFile file with the environment variables of the command, read at
+	// every execution, e.g. to pick up rotated credentials
+	EnvFile string `gcfg:"env-file" mapstructure:"env-file" json:",omitempty"`
+}
+
+// GetEnvFile returns the environment file of the job
+func (c *EnvConfig) GetEnvFile() string {
+	return c.EnvFile
+}
+
+// JobEnvFile returns the environment file of the given job, empty if none
+func JobEnvFile(j Job) string {
+	if e, ok := j.(interface{ GetEnvFile() string }); ok {
+		return e.GetEnvFile()
+	}
+
+	return ""
+}
+
+// Env returns the environment variables of the command of the execution, the
+// ones of the env-file of the job, read now, followed by the parent params as
+// OFELIA_PARENT, OFELIA_PARENT_EXIT_CODE... if it's run by the on-success or
+// on-failure of other job, and the payload and path of its trigger as
+// OFELIA_PAYLOAD and OFELIA_PATH. The values of the env-file referencing a
+// secret are resolved.
+func (c *Context) Env() ([]string, error) {
+	var env []string
+	if file := JobEnvFile(c.Job); file != "" {
+		var err error
+		if env, err = readEnvFile(file); err != nil {
+			return nil, err
+		}
+
+		if env, err = c.resolveSecrets(env); err != nil {
+			return nil, err
+		}
+	}
+
+	var params []string
+	for k, v := range c.Execution.Params {
+		if k == ParentParam || strings.HasPrefix(k, ParentParam+"_") {
+			params = append(params, paramEnvPrefix+strings.ToUpper(k)+"="+v)
+		}
+	}
+
+	for _, k := range triggerEnvParams {
+		if v, ok := c.Execution.Params[k]; ok {
+			params = append(params, paramEnvPrefix+strings.ToUpper(k)+"="+v)
+		}
+	}
+
+	sort.Strings(params)
+	return append(env, params...), nil
+}
+
+// localEnv returns the given environment of a local command with the
+// variables of the execution, the environment of the daemon if none is given
+func (c *Context) localEnv(env []string) ([]string, error) {
+	env, err := c.resolveSecrets(env)
+	if err != nil {
+		return nil, err
+	}
+
+	extra, err := c.Env()
+	if err != nil || len(extra) == 0 {
+		return env, err
+	}
+
+	if env == nil {
+		env = os.Environ()
+	}
+
+	return append(append([]string{}, env...), extra...), nil
+}
+
+// resolveSecrets resolves the given environment variables referencing a
+// secret with the Secrets of the scheduler, if any, the secrets being redacted.
+// The jobs declared by docker labels can't reference secrets, any container
+// with labels would read the secrets reachable by ofelia otherwise.
+func (c *Context) resolveSecrets(env []string) ([]string, error) {
+	if c.Scheduler == nil || c.Scheduler.Secrets == nil {
+		return env, nil
+	}
+
+	if JobDeclaredByLabels(c.Job) {
+		for _, v := range env {
+			if parts := strings.SplitN(v, "=", 2); len(parts) == 2 && c.Scheduler.Secrets.IsReference(parts[1]) {
+				return nil, fmt.Errorf("%s: %s", parts[0], ErrLabelsSecret)
+			}
+		}
+
+		return env, nil
+	}
+
+	return c.Scheduler.Secrets.resolveEnv(env, c.Scheduler.Redactor)
+}
+
+// readEnvFile reads the variables of the given file, in the format of the
+// docker env files: a KEY=VALUE per line, the lines starting with # being
+// comments, a KEY alone taking the value of the variable of the daemon if set
+func readEnvFile(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env-file: %s", err)
+	}
+
+	defer f.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("error reading env-file %s: line %d: invalid variable %q", filename, n, parts[0])
+		}
+
+		if len(parts) == 2 {
+			env = append(env, key+"="+parts[1])
+		} else if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading env-file: %s", err)
+	}
+
+	return env, nil
+}
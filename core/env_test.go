@@ -0,0 +1,91 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteEnv struct {
+	dir string
+}
+
+var _ = Suite(&SuiteEnv{})
+
+func (s *SuiteEnv) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *SuiteEnv) TestEnv(c *C) {
+	ctx := &Context{Execution: NewExecution()}
+	env, err := ctx.Env()
+	c.Assert(err, IsNil)
+	c.Assert(env, HasLen, 0)
+
+	env, err = ctx.localEnv(nil)
+	c.Assert(err, IsNil)
+	c.Assert(env, IsNil)
+
+	ctx.Execution.Params = map[string]string{
+		ParentParam:         "dump",
+		ParentExitCodeParam: "2",
+		MatrixParam:         "foo",
+	}
+
+	env, err = ctx.Env()
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"OFELIA_PARENT=dump", "OFELIA_PARENT_EXIT_CODE=2"})
+
+	env, err = ctx.localEnv([]string{"FOO=bar"})
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"FOO=bar", "OFELIA_PARENT=dump", "OFELIA_PARENT_EXIT_CODE=2"})
+
+	env, err = ctx.localEnv(nil)
+	c.Assert(err, IsNil)
+	c.Assert(len(env) > 2, Equals, true)
+}
+
+func (s *SuiteEnv) TestEnvTrigger(c *C) {
+	ctx := &Context{Execution: NewExecution()}
+	ctx.Execution.Params = map[string]string{
+		PayloadParam: "'; rm -rf / #",
+		PathParam:    "/data/a b.csv",
+	}
+
+	env, err := ctx.Env()
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"OFELIA_PATH=/data/a b.csv", "OFELIA_PAYLOAD='; rm -rf / #"})
+}
+
+func (s *SuiteEnv) TestEnvFile(c *C) {
+	file := filepath.Join(s.dir, "job.env")
+	c.Assert(ioutil.WriteFile(file, []byte("# credentials\nTOKEN=foo\n\n  URL=http://foo?a=b\nOFELIA_TEST_ENV\nOFELIA_TEST_UNSET\n"), 0600), IsNil)
+	os.Setenv("OFELIA_TEST_ENV", "bar")
+	defer os.Unsetenv("OFELIA_TEST_ENV")
+
+	j := &LocalJob{}
+	j.EnvFile = file
+
+	ctx := NewContext(NewScheduler(&TestLogger{}), j, NewExecution())
+	ctx.Execution.Params = map[string]string{ParentParam: "dump"}
+
+	env, err := ctx.Env()
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"TOKEN=foo", "URL=http://foo?a=b", "OFELIA_TEST_ENV=bar", "OFELIA_PARENT=dump"})
+
+	// read again at every execution
+	c.Assert(ioutil.WriteFile(file, []byte("TOKEN=rotated\n"), 0600), IsNil)
+	env, err = ctx.Env()
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"TOKEN=rotated", "OFELIA_PARENT=dump"})
+
+	c.Assert(ioutil.WriteFile(file, []byte("MY TOKEN=foo\n"), 0600), IsNil)
+	_, err = ctx.Env()
+	c.Assert(err, ErrorMatches, `error reading env-file .*: line 1: invalid variable "MY TOKEN"`)
+
+	j.EnvFile = filepath.Join(s.dir, "missing.env")
+	_, err = ctx.Env()
+	c.Assert(err, ErrorMatches, "error reading env-file: .*no such file or directory")
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is emitted by the scheduler, one of JobRegistered, JobRemoved,
+// ExecutionStarted, ExecutionFinished, ExecutionSkipped, ExecutionOverdue,
+// ExecutionAnomaly, JobStale or JobOrphaned.
+type Event interface {
+	// GetJob returns the job the event refers to
+	GetJob() Job
+}
+
+// JobRegistered is emitted when a job is added to the scheduler
+type JobRegistered struct {
+	Job Job
+}
+
+// JobRemoved is emitted when a job is removed from the scheduler
+type JobRemoved struct {
+	Job Job
+}
+
+// ExecutionStarted is emitted when a execution starts
+type ExecutionStarted struct {
+	Job       Job
+	Execution *Execution
+}
+
+// ExecutionFinished is emitted when a execution finishes, successful or not
+type ExecutionFinished struct {
+	Job       Job
+	Execution *Execution
+}
+
+// ExecutionSkipped is emitted when a execution is skipped
+type ExecutionSkipped struct {
+	Job       Job
+	Execution *Execution
+}
+
+// ExecutionOverdue is emitted when a execution is still running after its
+// expected duration
+type ExecutionOverdue struct {
+	Job       Job
+	Execution *Execution
+	Expected  time.Duration
+}
+
+// ExecutionAnomaly is emitted when the duration of a successful execution
+// deviates from the usual duration of the job beyond its threshold
+type ExecutionAnomaly struct {
+	Job       Job
+	Execution *Execution
+	// Mean and StdDev of the duration of the previous successful executions
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// JobStale is emitted when a job didn't succeed within its max time between
+// successes
+type JobStale struct {
+	Job Job
+	// LastSuccess end of the last successful execution, or the time the job
+	// was registered if it never succeeded
+	LastSuccess time.Time
+	Window      time.Duration
+}
+
+// JobOrphaned is emitted when the container of a job is found gone, see
+// OrphanMonitor
+type JobOrphaned struct {
+	Job       Job
+	Container string
+	// Grace period before the job is removed, zero if it isn't removed
+	Grace time.Duration
+}
+
+func (e *JobRegistered) GetJob() Job     { return e.Job }
+func (e *JobRemoved) GetJob() Job        { return e.Job }
+func (e *ExecutionStarted) GetJob() Job  { return e.Job }
+func (e *ExecutionFinished) GetJob() Job { return e.Job }
+func (e *ExecutionSkipped) GetJob() Job  { return e.Job }
+func (e *ExecutionOverdue) GetJob() Job  { return e.Job }
+func (e *ExecutionAnomaly) GetJob() Job  { return e.Job }
+func (e *JobStale) GetJob() Job          { return e.Job }
+func (e *JobOrphaned) GetJob() Job       { return e.Job }
+
+// EventBus delivers the events to the subscribers, synchronously and in the
+// order they were subscribed, the subscribers must not block.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]func(Event)
+	order       []int
+	next        int
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[int]func(Event))}
+}
+
+// Subscribe calls fn on every event, until the returned func is called.
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.subscribers[id] = fn
+	b.order = append(b.order, id)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers, id)
+		for i, sid := range b.order {
+			if sid == id {
+				b.order = append(b.order[:i], b.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish delivers the event to all the subscribers, if any.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	fns := make([]func(Event), 0, len(b.order))
+	for _, id := range b.order {
+		fns = append(fns, b.subscribers[id])
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(e)
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuiteEvents struct{}
+
+var _ = Suite(&SuiteEvents{})
+
+func (s *SuiteEvents) TestSubscribe(c *C) {
+	b := NewEventBus()
+
+	var got []Event
+	unsubscribe := b.Subscribe(func(e Event) {
+		got = append(got, e)
+	})
+
+	job := &TestJob{}
+	b.Publish(&JobRegistered{Job: job})
+	unsubscribe()
+	b.Publish(&JobRemoved{Job: job})
+
+	c.Assert(got, HasLen, 1)
+	c.Assert(got[0].GetJob(), Equals, job)
+}
+
+func (s *SuiteEvents) TestSchedulerEvents(c *C) {
+	job := &TestJob{}
+	job.Schedule = "@hourly"
+
+	sc := NewScheduler(&TestLogger{})
+
+	var got []string
+	sc.Events.Subscribe(func(e Event) {
+		switch e.(type) {
+		case *JobRegistered:
+			got = append(got, "registered")
+		case *ExecutionStarted:
+			got = append(got, "started")
+		case *ExecutionFinished:
+			got = append(got, "finished")
+		case *ExecutionSkipped:
+			got = append(got, "skipped")
+		case *JobRemoved:
+			got = append(got, "removed")
+		}
+	})
+
+	c.Assert(sc.AddJob(job), IsNil)
+	(&jobWrapper{sc, job}).Run()
+	c.Assert(sc.RemoveJob(job), IsNil)
+
+	c.Assert(got, DeepEquals, []string{"registered", "started", "finished", "removed"})
+}
@@ -1,48 +1,236 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
-	"github.com/gobs/args"
 )
 
+const defaultRequireHealthyTimeout = time.Minute
+
+// healthPollInterval time between two inspections of a container waited by
+// require-healthy
+var healthPollInterval = time.Second
+
 type ExecJob struct {
 	BareJob   `mapstructure:",squash"`
 	Client    *docker.Client `json:"-"`
 	Container string
-	User      string `default:"root"`
-	TTY       bool   `default:"false"`
+	// ContainerLabel when set, the command is executed in one of the running
+	// containers matching all the labels, chosen at every execution
+	ContainerLabel []string `gcfg:"container-label" mapstructure:"container-label"`
+	User           string   `default:"root"`
+	TTY            bool     `default:"false"`
+	// RequireHealthy when true, the command waits for the healthcheck of the
+	// container to report healthy, the execution is skipped if the container
+	// is restarting
+	RequireHealthy bool `gcfg:"require-healthy" mapstructure:"require-healthy"`
+	// RequireHealthyTimeout maximum wait of require-healthy, e.g. "2m"
+	RequireHealthyTimeout string `gcfg:"require-healthy-timeout" mapstructure:"require-healthy-timeout"`
+
+	CommandConfig   `mapstructure:",squash"`
+	EnvConfig       `mapstructure:",squash"`
+	DockerAPIConfig `mapstructure:",squash"`
 }
 
-func NewExecJob(c *docker.Client) *ExecJob {
-	return &ExecJob{Client: c}
+func NewExecJob(c *docker.Client, opts ...JobOption) *ExecJob {
+	j := &ExecJob{Client: c}
+	j.apply(opts)
+	return j
 }
 
 func (j *ExecJob) Run(ctx *Context) error {
-	exec, err := j.buildExec()
+	return j.run(ctx, ctx.Execution.OutputStream)
+}
+
+// run executes the command, writing its stdout to the given writer
+func (j *ExecJob) run(ctx *Context, stdout io.Writer) error {
+	cmd, err := j.commandArgs(ctx, j.Command)
+	if err != nil {
+		return err
+	}
+
+	container, err := j.resolveContainer(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err := j.startExec(ctx.Execution, exec); err != nil {
+	if err := j.waitHealthy(ctx, container); err != nil {
+		return err
+	}
+
+	stdin, err := ctx.Stdin()
+	if err != nil {
+		return err
+	}
+
+	exec, err := j.buildExec(ctx, cmd, container, stdin != nil)
+	if err != nil {
+		// the container may be recreated since resolved, e.g. by compose up
+		again, rerr := j.resolveContainer(ctx)
+		if rerr != nil || again == container {
+			return err
+		}
+
+		ctx.Log(fmt.Sprintf("Container resolved again after %q", err))
+		if exec, err = j.buildExec(ctx, cmd, again, stdin != nil); err != nil {
+			return err
+		}
+	}
+
+	if err := j.startExec(ctx.Execution, exec, stdin, stdout); err != nil {
 		return err
 	}
 
-	return j.inspectExec(exec)
+	return j.inspectExec(ctx, exec)
 }
 
-func (j *ExecJob) buildExec() (*docker.Exec, error) {
-	exec, err := j.Client.CreateExec(docker.CreateExecOptions{
-		AttachStdin:  false,
-		AttachStdout: true,
-		AttachStderr: true,
-		Tty:          j.TTY,
-		Cmd:          args.GetArgs(j.Command),
-		Container:    j.Container,
-		User:         j.User,
+// resolveContainer returns the ID of the container the command is executed in,
+// resolved at every execution, so the recreated containers are found by their
+// name or labels
+func (j *ExecJob) resolveContainer(ctx *Context) (string, error) {
+	if len(j.ContainerLabel) == 0 {
+		return j.inspectContainerID(ctx)
+	}
+
+	var conts []docker.APIContainers
+	err := j.retry(ctx, "listing containers", func(c context.Context) (err error) {
+		conts, err = j.Client.ListContainers(docker.ListContainersOptions{
+			Filters: map[string][]string{"label": j.ContainerLabel},
+			Context: c,
+		})
+
+		return
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("error listing containers: %s", err)
+	}
+
+	if len(conts) == 0 {
+		return "", fmt.Errorf("couldn't find running containers with labels %q", strings.Join(j.ContainerLabel, ","))
+	}
+
+	return conts[rand.Intn(len(conts))].ID, nil
+}
+
+func (j *ExecJob) inspectContainerID(ctx *Context) (string, error) {
+	var c *docker.Container
+	err := j.retry(ctx, "inspecting container", func(tctx context.Context) (err error) {
+		c, err = j.Client.InspectContainerWithOptions(docker.InspectContainerOptions{
+			ID:      j.Container,
+			Context: tctx,
+		})
+
+		return
 	})
 
+	if _, ok := err.(*docker.NoSuchContainer); ok {
+		return "", &ContainerGoneError{Container: j.Container}
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("error inspecting container: %s", err)
+	}
+
+	return c.ID, nil
+}
+
+// waitHealthy waits for the healthcheck of the container to report healthy, if
+// require-healthy is set. ErrSkippedExecution is returned if the container is
+// restarting.
+func (j *ExecJob) waitHealthy(ctx *Context, container string) error {
+	if !j.RequireHealthy {
+		return nil
+	}
+
+	timeout, err := parseDuration("require-healthy-timeout", j.RequireHealthyTimeout, defaultRequireHealthyTimeout)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for {
+		var c *docker.Container
+		err := j.retry(ctx, "inspecting container", func(tctx context.Context) (err error) {
+			c, err = j.Client.InspectContainerWithOptions(docker.InspectContainerOptions{
+				ID:      container,
+				Context: tctx,
+			})
+
+			return
+		})
+
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			return &ContainerGoneError{Container: container}
+		}
+
+		if err != nil {
+			return fmt.Errorf("error inspecting container: %s", err)
+		}
+
+		name := strings.TrimPrefix(c.Name, "/")
+		switch {
+		case c.State.Restarting:
+			ctx.Log(fmt.Sprintf("Skipping, container %s is restarting", name))
+			return ErrSkippedExecution
+		case !c.State.Running:
+			return fmt.Errorf("container %s is not running", name)
+		case c.State.Health.Status == "":
+			return fmt.Errorf("container %s has no healthcheck, required by require-healthy", name)
+		case c.State.Health.Status == "healthy":
+			if waited := time.Since(start); waited > healthPollInterval {
+				ctx.Log(fmt.Sprintf("Container %s healthy after %s", name, waited.Round(time.Millisecond)))
+			}
+
+			return nil
+		}
+
+		if time.Since(start) > timeout {
+			return fmt.Errorf("container %s not healthy after %s, health status %q", name, timeout, c.State.Health.Status)
+		}
+
+		time.Sleep(healthPollInterval)
+	}
+}
+
+// ValidateCommand checks the command options of the job
+func (j *ExecJob) ValidateCommand() error {
+	return j.CommandConfig.validate(j.Command, j.StdinFrom)
+}
+
+func (j *ExecJob) buildExec(ctx *Context, cmd []string, container string, stdin bool) (*docker.Exec, error) {
+	env, err := ctx.Env()
+	if err != nil {
+		return nil, err
+	}
+
+	var exec *docker.Exec
+	err = j.retry(ctx, "creating exec", func(c context.Context) (err error) {
+		exec, err = j.Client.CreateExec(docker.CreateExecOptions{
+			AttachStdin:  stdin,
+			AttachStdout: true,
+			AttachStderr: true,
+			Tty:          j.TTY,
+			Cmd:          cmd,
+			Env:          env,
+			Container:    container,
+			User:         j.User,
+			Context:      c,
+		})
+
+		return
+	})
+
+	if _, ok := err.(*docker.NoSuchContainer); ok {
+		return exec, &ContainerGoneError{Container: container}
+	}
+
 	if err != nil {
 		return exec, fmt.Errorf("error creating exec: %s", err)
 	}
@@ -50,10 +238,11 @@ func (j *ExecJob) buildExec() (*docker.Exec, error) {
 	return exec, nil
 }
 
-func (j *ExecJob) startExec(e *Execution, exec *docker.Exec) error {
+func (j *ExecJob) startExec(e *Execution, exec *docker.Exec, stdin io.Reader, stdout io.Writer) error {
 	err := j.Client.StartExec(exec.ID, docker.StartExecOptions{
+		InputStream:  stdin,
 		Tty:          j.TTY,
-		OutputStream: e.OutputStream,
+		OutputStream: stdout,
 		ErrorStream:  e.ErrorStream,
 		RawTerminal:  j.TTY,
 	})
@@ -65,19 +254,17 @@ func (j *ExecJob) startExec(e *Execution, exec *docker.Exec) error {
 	return nil
 }
 
-func (j *ExecJob) inspectExec(exec *docker.Exec) error {
-	i, err := j.Client.InspectExec(exec.ID)
+func (j *ExecJob) inspectExec(ctx *Context, exec *docker.Exec) error {
+	var i *docker.ExecInspect
+	err := j.retry(ctx, "inspecting exec", func(context.Context) (err error) {
+		i, err = j.Client.InspectExec(exec.ID)
+		return
+	})
 
 	if err != nil {
 		return fmt.Errorf("error inspecting exec: %s", err)
 	}
 
-	switch i.ExitCode {
-	case 0:
-		return nil
-	case -1:
-		return ErrUnexpected
-	default:
-		return fmt.Errorf("error non-zero exit code: %d", i.ExitCode)
-	}
+	ctx.Execution.ExitCode = i.ExitCode
+	return exitCodeError(i.ExitCode)
 }
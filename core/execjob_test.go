@@ -3,6 +3,7 @@ package core
 import (
 	"archive/tar"
 	"bytes"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/fsouza/go-dockerclient/testing"
@@ -63,6 +64,90 @@ func (s *SuiteExecJob) TestRun(c *C) {
 	c.Assert(exec.ProcessConfig.Tty, Equals, true)
 }
 
+func (s *SuiteExecJob) TestRunContainerGone(c *C) {
+	job := &ExecJob{Client: s.client}
+	job.Container = "gone"
+	job.Command = "echo foo"
+
+	err := job.Run(&Context{Execution: NewExecution()})
+	c.Assert(err, DeepEquals, &ContainerGoneError{Container: "gone"})
+}
+
+func (s *SuiteExecJob) TestResolveContainerRecreated(c *C) {
+	job := &ExecJob{Client: s.client}
+	job.Container = ContainerFixture
+
+	id, err := job.resolveContainer(&Context{})
+	c.Assert(err, IsNil)
+
+	c.Assert(s.client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: true}), IsNil)
+	recreated, err := s.client.CreateContainer(docker.CreateContainerOptions{
+		Name:   ContainerFixture,
+		Config: &docker.Config{Image: "test"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(recreated.ID, Not(Equals), id)
+
+	id, err = job.resolveContainer(&Context{})
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, recreated.ID)
+}
+
+func (s *SuiteExecJob) TestResolveContainerByLabel(c *C) {
+	container, err := s.client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "replica",
+		Config: &docker.Config{Image: "test", Labels: map[string]string{"app": "foo"}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(s.client.StartContainer(container.ID, nil), IsNil)
+
+	job := &ExecJob{Client: s.client}
+	job.ContainerLabel = []string{"app=foo"}
+
+	id, err := job.resolveContainer(&Context{})
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, container.ID)
+
+	job.ContainerLabel = []string{"app=bar"}
+	_, err = job.resolveContainer(&Context{})
+	c.Assert(err, NotNil)
+}
+
+func (s *SuiteExecJob) TestWaitHealthy(c *C) {
+	defer func(d time.Duration) { healthPollInterval = d }(healthPollInterval)
+	healthPollInterval = time.Millisecond * 10
+
+	job := &ExecJob{Client: s.client}
+	job.Container = ContainerFixture
+	job.RequireHealthy = true
+	job.RequireHealthyTimeout = "100ms"
+	ctx := &Context{Job: job, Execution: NewExecution(), Logger: &TestLogger{}}
+
+	container, err := s.client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: ContainerFixture})
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		state docker.State
+		err   string
+	}{
+		{docker.State{Running: true, Health: docker.Health{Status: "healthy"}}, ""},
+		{docker.State{Running: true, Restarting: true}, "skipped execution"},
+		{docker.State{}, "container test-container is not running"},
+		{docker.State{Running: true}, "container test-container has no healthcheck, .*"},
+		{docker.State{Running: true, Health: docker.Health{Status: "starting"}},
+			`container test-container not healthy after 100ms, health status "starting"`},
+	} {
+		c.Assert(s.server.MutateContainer(container.ID, t.state), IsNil)
+
+		err := job.waitHealthy(ctx, ContainerFixture)
+		if t.err == "" {
+			c.Assert(err, IsNil)
+		} else {
+			c.Assert(err, ErrorMatches, t.err)
+		}
+	}
+}
+
 func (s *SuiteExecJob) buildContainer(c *C) {
 	inputbuf := bytes.NewBuffer(nil)
 	tr := tar.NewWriter(inputbuf)
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Explanation is a schedule explained, with its next times
+type Explanation struct {
+	Schedule    string
+	Description string
+	// Location the time zone of the next times
+	Location string
+	Next     []time.Time
+}
+
+// Explain parses the given schedule with the parser of the jobs, returning its
+// description and its next n times after now, in the given location
+func (s *Scheduler) Explain(schedule string, loc *time.Location, n int) (*Explanation, error) {
+	sched, err := s.schedule(&LocalJob{BareJob: BareJob{Schedule: schedule}})
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Explanation{
+		Schedule:    schedule,
+		Description: DescribeSchedule(schedule),
+		Location:    loc.String(),
+	}
+
+	for t := s.Clock.Now().In(loc); len(e.Next) < n; {
+		if t = sched.Next(t); t.IsZero() {
+			break
+		}
+
+		e.Next = append(e.Next, t.In(loc))
+	}
+
+	return e, nil
+}
+
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "At 00:00 on January 1",
+	"@annually": "At 00:00 on January 1",
+	"@monthly":  "At 00:00 on day-of-month 1",
+	"@weekly":   "At 00:00 on Sunday",
+	"@daily":    "At 00:00",
+	"@midnight": "At 00:00",
+	"@hourly":   "At minute 0",
+}
+
+var (
+	monthNames = []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	dowNames   = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+// cronField a field of a cron expression, with its bounds and the names of
+// its values, if any
+type cronField struct {
+	unit  string
+	max   int
+	names []string
+}
+
+var (
+	minuteField = cronField{unit: "minute", max: 59}
+	hourField   = cronField{unit: "hour", max: 23}
+	domField    = cronField{unit: "day-of-month", max: 31}
+	monthField  = cronField{unit: "month", max: 12, names: monthNames}
+	dowField    = cronField{unit: "day-of-week", max: 6, names: dowNames}
+)
+
+// DescribeSchedule returns a human description of the given schedule, e.g.
+// "At 03:00 on Monday through Friday" for "0 3 * * 1-5". The schedule is
+// expected to be valid, the ones not understood are returned as is.
+func DescribeSchedule(schedule string) string {
+	schedule = strings.TrimSpace(schedule)
+	if strings.HasPrefix(schedule, "TZ=") || strings.HasPrefix(schedule, "CRON_TZ=") {
+		i := strings.IndexAny(schedule, " \t")
+		if i < 0 {
+			return schedule
+		}
+
+		tz := schedule[strings.Index(schedule, "=")+1 : i]
+		return fmt.Sprintf("%s, in the time zone %s", DescribeSchedule(schedule[i:]), tz)
+	}
+
+	if d, ok := scheduleDescriptors[schedule]; ok {
+		return d
+	}
+
+	if at, ok, err := parseAt(schedule); ok && err == nil {
+		return "Once at " + at.Format(time.RFC3339)
+	}
+
+	if d, ok, err := parseAfter(schedule); ok && err == nil {
+		return fmt.Sprintf("%s after the end of the previous execution", d)
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) == 2 && fields[0] == "@every" {
+		if d, err := time.ParseDuration(fields[1]); err == nil {
+			return fmt.Sprintf("Every %s", d)
+		}
+	}
+
+	if len(fields) != 5 {
+		return schedule
+	}
+
+	min, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+	parts := []string{describeTime(min, hour)}
+	switch {
+	case !isWildcard(dom) && !isWildcard(dow):
+		parts = append(parts, "on "+domField.describe(dom)+" or on "+dowField.describe(dow))
+	case !isWildcard(dom):
+		parts = append(parts, "on "+domField.describe(dom))
+	case !isWildcard(dow):
+		parts = append(parts, "on "+dowField.describe(dow))
+	}
+
+	if !isWildcard(month) {
+		parts = append(parts, "in "+monthField.describe(month))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func describeTime(min, hour string) string {
+	m, errM := strconv.Atoi(min)
+	h, errH := strconv.Atoi(hour)
+	if errM == nil && errH == nil {
+		return fmt.Sprintf("At %02d:%02d", h, m)
+	}
+
+	if hour == "*" {
+		return "At " + minuteField.describe(min)
+	}
+
+	return "At " + minuteField.describe(min) + " past " + hourField.describe(hour)
+}
+
+func isWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// describe returns the description of the values of the field, e.g. "minute
+// 0 and 30" or "every 15th minute"
+func (f cronField) describe(field string) string {
+	items := strings.Split(field, ",")
+	described := make([]string, len(items))
+	for i, item := range items {
+		described[i] = f.describeItem(item)
+	}
+
+	d := joinWords(described)
+	if f.names == nil && !strings.HasPrefix(d, "every") {
+		d = f.unit + " " + d
+	}
+
+	return d
+}
+
+func (f cronField) describeItem(item string) string {
+	rng, step := item, ""
+	if i := strings.Index(item, "/"); i >= 0 {
+		rng, step = item[:i], item[i+1:]
+	}
+
+	from, to := rng, ""
+	if i := strings.Index(rng, "-"); i >= 0 {
+		from, to = rng[:i], rng[i+1:]
+	}
+
+	if step == "" {
+		switch {
+		case isWildcard(from):
+			return "every " + f.unit
+		case to == "":
+			return f.value(from)
+		default:
+			return f.value(from) + " through " + f.value(to)
+		}
+	}
+
+	n, _ := strconv.Atoi(step)
+	every := fmt.Sprintf("every %s %s", ordinal(n), f.unit)
+	switch {
+	case isWildcard(from):
+		return every
+	case to == "":
+		return every + " from " + f.value(from) + " through " + f.value(strconv.Itoa(f.max))
+	default:
+		return every + " from " + f.value(from) + " through " + f.value(to)
+	}
+}
+
+// value returns the name of the given value of the field, if it has names
+func (f cronField) value(v string) string {
+	if f.names == nil {
+		return v
+	}
+
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 && n < len(f.names) {
+		return f.names[n]
+	}
+
+	for _, name := range f.names {
+		if name != "" && strings.EqualFold(name[:3], v) {
+			return name
+		}
+	}
+
+	return v
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+
+	return strconv.Itoa(n) + suffix
+}
+
+// joinWords joins the given words as "a, b and c"
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
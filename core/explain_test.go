@@ -0,0 +1,67 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteExplain struct{}
+
+var _ = Suite(&SuiteExplain{})
+
+func (s *SuiteExplain) TestDescribeSchedule(c *C) {
+	for schedule, expected := range map[string]string{
+		"0 3 * * 1-5":                     "At 03:00 on Monday through Friday",
+		"*/15 * * * *":                    "At every 15th minute",
+		"* * * * *":                       "At every minute",
+		"5 * * * *":                       "At minute 5",
+		"0,30 9-17 * * *":                 "At minute 0 and 30 past hour 9 through 17",
+		"0 */2 * * *":                     "At minute 0 past every 2nd hour",
+		"0 0 1,15 * *":                    "At 00:00 on day-of-month 1 and 15",
+		"0 0 1 * mon":                     "At 00:00 on day-of-month 1 or on Monday",
+		"30 4 * jan-mar ?":                "At 04:30 in January through March",
+		"0 12 * * 5/2":                    "At 12:00 on every 2nd day-of-week from Friday through Saturday",
+		"@daily":                          "At 00:00",
+		"@every 90m":                      "Every 1h30m0s",
+		"@at 2025-07-01T03:00:00Z":        "Once at 2025-07-01T03:00:00Z",
+		"@after 15m":                      "15m0s after the end of the previous execution",
+		"CRON_TZ=Europe/Madrid 0 3 * * *": "At 03:00, in the time zone Europe/Madrid",
+		"invalid":                         "invalid",
+	} {
+		c.Assert(DescribeSchedule(schedule), Equals, expected, Commentf("%s", schedule))
+	}
+}
+
+func (s *SuiteExplain) TestOrdinal(c *C) {
+	c.Assert(ordinal(1), Equals, "1st")
+	c.Assert(ordinal(2), Equals, "2nd")
+	c.Assert(ordinal(3), Equals, "3rd")
+	c.Assert(ordinal(11), Equals, "11th")
+	c.Assert(ordinal(22), Equals, "22nd")
+}
+
+func (s *SuiteExplain) TestExplain(c *C) {
+	// a Friday
+	now := time.Date(2020, 1, 3, 12, 0, 0, 0, time.UTC)
+	sc := NewScheduler(&TestLogger{}, WithClock(fixedClock(now)))
+
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	c.Assert(err, IsNil)
+
+	e, err := sc.Explain("0 3 * * 1-5", madrid, 3)
+	c.Assert(err, IsNil)
+	c.Assert(e.Description, Equals, "At 03:00 on Monday through Friday")
+	c.Assert(e.Location, Equals, "Europe/Madrid")
+	c.Assert(e.Next, HasLen, 3)
+	c.Assert(e.Next[0].Equal(time.Date(2020, 1, 6, 3, 0, 0, 0, madrid)), Equals, true)
+	c.Assert(e.Next[2].Equal(time.Date(2020, 1, 8, 3, 0, 0, 0, madrid)), Equals, true)
+	c.Assert(e.Next[0].Location(), Equals, madrid)
+
+	e, err = sc.Explain("@at 2020-01-04T00:00:00Z", time.UTC, 3)
+	c.Assert(err, IsNil)
+	c.Assert(e.Next, HasLen, 1)
+
+	_, err = sc.Explain("0 3 * *", time.UTC, 3)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// JobFactory returns a new job of a custom type, the configuration of the job
+// is decoded into the returned value, so it should be a pointer to a struct
+// embedding BareJob.
+type JobFactory func() Job
+
+var (
+	jobFactoriesMu sync.RWMutex
+	jobFactories   = make(map[string]JobFactory)
+)
+
+// RegisterJobType registers a custom job type, the jobs of this type are
+// declared in the config sections named after the type, e.g. [job-foo "name"],
+// or with the labels of the type, e.g. ofelia.job-foo.name.schedule. It panics
+// if the type is already registered.
+func RegisterJobType(section string, f JobFactory) {
+	jobFactoriesMu.Lock()
+	defer jobFactoriesMu.Unlock()
+
+	if _, ok := jobFactories[section]; ok {
+		panic(fmt.Sprintf("job type %q already registered", section))
+	}
+
+	jobFactories[section] = f
+}
+
+// GetJobFactory returns the factory of the given job type, if registered.
+func GetJobFactory(section string) (JobFactory, bool) {
+	jobFactoriesMu.RLock()
+	defer jobFactoriesMu.RUnlock()
+
+	f, ok := jobFactories[section]
+	return f, ok
+}
+
+// JobTypes returns the registered job types, sorted.
+func JobTypes() []string {
+	jobFactoriesMu.RLock()
+	defer jobFactoriesMu.RUnlock()
+
+	var types []string
+	for t := range jobFactories {
+		types = append(types, t)
+	}
+
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileDebounce time a file has to be unchanged to trigger the job, so a file
+// being written triggers it once
+var fileDebounce = time.Second
+
+// fileSubscriber is the MessageSubscriber of the watch triggers, the subjects
+// are directories and the payloads the paths of the files created or changed
+// in them
+type fileSubscriber struct {
+	logger Logger
+}
+
+// Subscribe watches the given directory, calling the handler with the path of
+// every file created or changed, once unchanged for fileDebounce
+func (f *fileSubscriber) Subscribe(dir string, handler func(payload []byte)) (unsubscribe func() error, err error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	d := &debouncer{timers: make(map[string]*time.Timer), handler: handler}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.watch(w, d)
+	}()
+
+	return func() error {
+		err := w.Close()
+		<-done
+		d.stop()
+		return err
+	}, nil
+}
+
+func (f *fileSubscriber) watch(w *fsnotify.Watcher, d *debouncer) {
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return
+			}
+
+			if e.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				d.touch(e.Name)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+
+			f.logger.Errorf("File watch error: %s", err)
+		}
+	}
+}
+
+// debouncer calls the handler with a path once it isn't touched for
+// fileDebounce
+type debouncer struct {
+	mu      sync.Mutex
+	stopped bool
+	timers  map[string]*time.Timer
+	handler func(payload []byte)
+}
+
+func (d *debouncer) touch(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.timers[path]; ok {
+		t.Reset(fileDebounce)
+		return
+	}
+
+	d.timers[path] = time.AfterFunc(fileDebounce, func() {
+		d.mu.Lock()
+		delete(d.timers, path)
+		stopped := d.stopped
+		d.mu.Unlock()
+
+		if !stopped {
+			d.handler([]byte(path))
+		}
+	})
+}
+
+func (d *debouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	for _, t := range d.timers {
+		t.Stop()
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteFileWatch struct{}
+
+var _ = Suite(&SuiteFileWatch{})
+
+func (s *SuiteFileWatch) TestWatchTrigger(c *C) {
+	defer func(d time.Duration) { fileDebounce = d }(fileDebounce)
+	fileDebounce = 50 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "ofelia-watch")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	sc := NewScheduler(&TestLogger{})
+	job := &paramsJob{params: make(chan map[string]string, 2)}
+	job.Name = "foo"
+	job.Trigger = "watch:" + dir
+	sc.AddTriggeredJob(job)
+	c.Assert(sc.Start(), IsNil)
+
+	// a file written in several steps triggers the job once
+	path := filepath.Join(dir, "bar.csv")
+	f, err := os.Create(path)
+	c.Assert(err, IsNil)
+	for i := 0; i < 3; i++ {
+		_, err := f.WriteString("baz\n")
+		c.Assert(err, IsNil)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.Assert(f.Close(), IsNil)
+
+	select {
+	case params := <-job.params:
+		c.Assert(params, DeepEquals, map[string]string{PathParam: path})
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	select {
+	case <-job.params:
+		c.Fatal("job run twice")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	c.Assert(sc.Stop(), IsNil)
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	// FlockSkip the execution is skipped if the lock file is locked
+	FlockSkip = "skip"
+	// FlockWait the execution waits for the lock file to be unlocked, up to
+	// the flock-timeout, if any
+	FlockWait = "wait"
+)
+
+// flockBackoff interval between the attempts to lock a locked lock file,
+// overridden by the tests
+var flockBackoff = 250 * time.Millisecond
+
+// ValidateFlock checks the flock options of the job
+func (j *LocalJob) ValidateFlock() error {
+	if j.Flock == "" {
+		if j.FlockPolicy != "" || j.FlockTimeout != "" {
+			return fmt.Errorf("flock-policy and flock-timeout require a flock")
+		}
+
+		return nil
+	}
+
+	_, _, err := j.flockOptions()
+	return err
+}
+
+func (j *LocalJob) flockOptions() (policy string, timeout time.Duration, err error) {
+	policy = j.FlockPolicy
+	switch policy {
+	case "":
+		policy = FlockSkip
+	case FlockSkip, FlockWait:
+	default:
+		return "", 0, fmt.Errorf("invalid flock-policy %q, must be %q or %q", policy, FlockSkip, FlockWait)
+	}
+
+	if j.FlockTimeout != "" {
+		timeout, err = time.ParseDuration(j.FlockTimeout)
+		if err != nil || timeout <= 0 {
+			return "", 0, fmt.Errorf("invalid flock-timeout %q, must be a positive duration", j.FlockTimeout)
+		}
+
+		if policy != FlockWait {
+			return "", 0, fmt.Errorf("flock-timeout requires the flock-policy %q", FlockWait)
+		}
+	}
+
+	return policy, timeout, nil
+}
+
+// lock takes an exclusive advisory lock, as flock(1), on the lock file of the
+// job, created if missing, returning the function releasing it. The execution
+// is skipped if the file is locked, unless waiting for it.
+func (j *LocalJob) lock(ctx *Context) (func(), error) {
+	if j.Flock == "" {
+		return func() {}, nil
+	}
+
+	policy, timeout, err := j.flockOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := ctx.Render(j.Flock)
+	if err != nil {
+		return nil, err
+	}
+
+	if !filepath.IsAbs(path) && j.Dir != "" {
+		path = filepath.Join(j.Dir, path)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the lock file: %s", err)
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for waiting := false; ; waiting = true {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+
+		if err != syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, fmt.Errorf("unable to lock %q: %s", path, err)
+		}
+
+		if policy == FlockSkip {
+			f.Close()
+			ctx.Log(fmt.Sprintf("Skipping, %q is locked", path))
+			return nil, ErrSkippedExecution
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			f.Close()
+			ctx.Log(fmt.Sprintf("Skipping, %q still locked after %s", path, timeout))
+			return nil, ErrSkippedExecution
+		}
+
+		if !waiting {
+			ctx.Log(fmt.Sprintf("Waiting for %q to be unlocked", path))
+		}
+
+		time.Sleep(flockBackoff)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
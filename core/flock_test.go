@@ -0,0 +1,117 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteFlock struct {
+	file string
+}
+
+var _ = Suite(&SuiteFlock{})
+
+func (s *SuiteFlock) SetUpTest(c *C) {
+	s.file = filepath.Join(c.MkDir(), "job.lock")
+	flockBackoff = time.Millisecond
+}
+
+func (s *SuiteFlock) TearDownTest(c *C) {
+	flockBackoff = 250 * time.Millisecond
+}
+
+// hold locks the lock file as another tool of the host would
+func (s *SuiteFlock) hold(c *C) *os.File {
+	f, err := os.OpenFile(s.file, os.O_RDONLY|os.O_CREATE, 0644)
+	c.Assert(err, IsNil)
+	c.Assert(syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB), IsNil)
+	return f
+}
+
+func (s *SuiteFlock) run(j *LocalJob) (*Execution, error) {
+	e := NewExecution()
+	err := j.Run(&Context{Job: j, Logger: &TestLogger{}, Execution: e})
+	return e, err
+}
+
+func (s *SuiteFlock) TestRunUnlocked(c *C) {
+	j := &LocalJob{Flock: s.file}
+	j.Command = `echo foo`
+
+	e, err := s.run(j)
+	c.Assert(err, IsNil)
+	c.Assert(e.OutputStream.String(), Equals, "foo\n")
+
+	// released after the execution
+	s.hold(c).Close()
+}
+
+func (s *SuiteFlock) TestRunSkip(c *C) {
+	f := s.hold(c)
+	defer f.Close()
+
+	j := &LocalJob{Flock: s.file}
+	j.Command = `echo foo`
+
+	e, err := s.run(j)
+	c.Assert(err, Equals, ErrSkippedExecution)
+	c.Assert(e.OutputStream.String(), Equals, "")
+}
+
+func (s *SuiteFlock) TestRunWait(c *C) {
+	f := s.hold(c)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		f.Close()
+	}()
+
+	j := &LocalJob{Flock: s.file, FlockPolicy: FlockWait}
+	j.Command = `echo foo`
+
+	e, err := s.run(j)
+	c.Assert(err, IsNil)
+	c.Assert(e.OutputStream.String(), Equals, "foo\n")
+}
+
+func (s *SuiteFlock) TestRunWaitTimeout(c *C) {
+	f := s.hold(c)
+	defer f.Close()
+
+	j := &LocalJob{Flock: s.file, FlockPolicy: FlockWait, FlockTimeout: "10ms"}
+	j.Command = `echo foo`
+
+	_, err := s.run(j)
+	c.Assert(err, Equals, ErrSkippedExecution)
+}
+
+func (s *SuiteFlock) TestRunRelativeToDir(c *C) {
+	j := &LocalJob{Flock: "job.lock", Dir: filepath.Dir(s.file)}
+	j.Command = `echo foo`
+
+	_, err := s.run(j)
+	c.Assert(err, IsNil)
+
+	_, err = os.Stat(s.file)
+	c.Assert(err, IsNil)
+}
+
+func (s *SuiteFlock) TestValidateFlock(c *C) {
+	c.Assert((&LocalJob{}).ValidateFlock(), IsNil)
+	c.Assert((&LocalJob{Flock: s.file, FlockPolicy: FlockWait, FlockTimeout: "1m"}).ValidateFlock(), IsNil)
+
+	err := (&LocalJob{FlockPolicy: FlockWait}).ValidateFlock()
+	c.Assert(err, ErrorMatches, `flock-policy and flock-timeout require a flock`)
+
+	err = (&LocalJob{Flock: s.file, FlockPolicy: "block"}).ValidateFlock()
+	c.Assert(err, ErrorMatches, `invalid flock-policy "block", must be "skip" or "wait"`)
+
+	err = (&LocalJob{Flock: s.file, FlockPolicy: FlockWait, FlockTimeout: "soon"}).ValidateFlock()
+	c.Assert(err, ErrorMatches, `invalid flock-timeout "soon", must be a positive duration`)
+
+	err = (&LocalJob{Flock: s.file, FlockTimeout: "1m"}).ValidateFlock()
+	c.Assert(err, ErrorMatches, `flock-timeout requires the flock-policy "wait"`)
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// FreshnessMonitor emits a JobStale event when a job doesn't succeed within
+// its max time between successes. The jobs are tracked by name, so the last
+// success is kept when a job is replaced, and a removed job is still reported
+// if it isn't added again, e.g. when the container declaring it disappears.
+type FreshnessMonitor struct {
+	s           *Scheduler
+	unsubscribe func()
+
+	mu   sync.Mutex
+	jobs map[string]*freshness
+}
+
+type freshness struct {
+	job         Job
+	window      time.Duration
+	lastSuccess time.Time
+	timer       *time.Timer
+}
+
+// WatchFreshness returns a FreshnessMonitor watching the jobs of the given
+// scheduler, the jobs have to be added after it
+func WatchFreshness(s *Scheduler) *FreshnessMonitor {
+	m := &FreshnessMonitor{s: s, jobs: make(map[string]*freshness)}
+	m.unsubscribe = s.Events.Subscribe(m.handle)
+	return m
+}
+
+// Stop stops watching the jobs
+func (m *FreshnessMonitor) Stop() {
+	m.unsubscribe()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.jobs {
+		f.timer.Stop()
+	}
+}
+
+func (m *FreshnessMonitor) handle(e Event) {
+	switch e := e.(type) {
+	case *JobRegistered:
+		m.register(e.Job)
+	case *ExecutionFinished:
+		if !e.Execution.Failed {
+			m.succeeded(e.Job)
+		}
+	}
+}
+
+func (m *FreshnessMonitor) register(j Job) {
+	value := maxTimeBetweenSuccess(j)
+	if value == "" {
+		return
+	}
+
+	window, err := parseDuration("max-time-between-success", value, 0)
+	if err != nil {
+		m.s.Logger.Errorf("Job %q: %s", j.GetName(), err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.jobs[j.GetName()]
+	if !ok {
+		f = &freshness{lastSuccess: m.s.Clock.Now()}
+		m.jobs[j.GetName()] = f
+	}
+
+	f.job, f.window = j, window
+	m.arm(f)
+}
+
+func (m *FreshnessMonitor) succeeded(j Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.jobs[j.GetName()]
+	if !ok {
+		return
+	}
+
+	f.lastSuccess = m.s.Clock.Now()
+	m.arm(f)
+}
+
+// arm schedules the stale event at the end of the window, a stale job is
+// reported once, until it succeeds again
+func (m *FreshnessMonitor) arm(f *freshness) {
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+
+	wait := f.lastSuccess.Add(f.window).Sub(m.s.Clock.Now())
+	f.timer = time.AfterFunc(wait, func() {
+		m.mu.Lock()
+		e := &JobStale{Job: f.job, LastSuccess: f.lastSuccess, Window: f.window}
+		m.mu.Unlock()
+
+		m.s.Logger.Warningf(
+			"Job %q hasn't succeeded in %s, last success at %s",
+			e.Job.GetName(), e.Window, e.LastSuccess.Format(time.RFC3339),
+		)
+
+		m.s.Events.Publish(e)
+	})
+}
+
+func maxTimeBetweenSuccess(j Job) string {
+	if f, ok := j.(interface{ GetMaxTimeBetweenSuccess() string }); ok {
+		return f.GetMaxTimeBetweenSuccess()
+	}
+
+	return ""
+}
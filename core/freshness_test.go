@@ -0,0 +1,88 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteFreshness struct{}
+
+var _ = Suite(&SuiteFreshness{})
+
+func (s *SuiteFreshness) TestStale(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	m := WatchFreshness(sc)
+	defer m.Stop()
+
+	stale := make(chan *JobStale, 2)
+	sc.Events.Subscribe(func(e Event) {
+		if e, ok := e.(*JobStale); ok {
+			stale <- e
+		}
+	})
+
+	job := &TestJob{}
+	job.Name = "foo"
+	job.MaxTimeBetweenSuccess = "50ms"
+	sc.AddTriggeredJob(job)
+
+	// a success postpones the stale event
+	time.Sleep(30 * time.Millisecond)
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: NewExecution()})
+	registered := time.Now()
+
+	select {
+	case e := <-stale:
+		c.Assert(e.Job, Equals, job)
+		c.Assert(e.Window, Equals, 50*time.Millisecond)
+		c.Assert(time.Since(registered) >= 50*time.Millisecond, Equals, true)
+	case <-time.After(time.Second):
+		c.Fatal("stale event not emitted")
+	}
+
+	// reported once
+	select {
+	case <-stale:
+		c.Fatal("stale event emitted twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (s *SuiteFreshness) TestRemoved(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	m := WatchFreshness(sc)
+	defer m.Stop()
+
+	stale := make(chan *JobStale, 1)
+	sc.Events.Subscribe(func(e Event) {
+		if e, ok := e.(*JobStale); ok {
+			stale <- e
+		}
+	})
+
+	job := &TestJob{}
+	job.Name = "foo"
+	job.Schedule = "@daily"
+	job.MaxTimeBetweenSuccess = "20ms"
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(sc.RemoveJob(job), IsNil)
+
+	select {
+	case e := <-stale:
+		c.Assert(e.Job, Equals, job)
+	case <-time.After(time.Second):
+		c.Fatal("stale event not emitted")
+	}
+}
+
+func (s *SuiteFreshness) TestWithout(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	m := WatchFreshness(sc)
+	defer m.Stop()
+
+	job := &TestJob{}
+	job.Name = "foo"
+	sc.AddTriggeredJob(job)
+	c.Assert(m.jobs, HasLen, 0)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Group settings shared by the jobs declaring its name with the group param,
+// see JobGroup
+type Group struct {
+	Name string
+	// ScheduleOffset delays the scheduled executions of the jobs, e.g. to run
+	// all the backups 30 minutes after the time of their schedules
+	ScheduleOffset time.Duration
+	// ScheduleSpread window the scheduled executions of the jobs without their
+	// own one are spread over across the hosts, see JobScheduleSpread
+	ScheduleSpread time.Duration
+	// MaxConcurrent maximum number of executions of the jobs running at once,
+	// unbounded if zero
+	MaxConcurrent int
+
+	pool *ExecutionPool
+}
+
+// NewGroup returns a group delaying the scheduled executions of its jobs by
+// the given offset, running at most maxConcurrent of them at once
+func NewGroup(name string, offset time.Duration, maxConcurrent int) *Group {
+	g := &Group{Name: name, ScheduleOffset: offset, MaxConcurrent: maxConcurrent}
+	if maxConcurrent > 0 {
+		g.pool = NewExecutionPool(JobGroup, map[string]int{name: maxConcurrent})
+	}
+
+	return g
+}
+
+// JobGroup returns the name of the group of the given job, empty if none
+func JobGroup(j Job) string {
+	g, ok := j.(interface{ GetGroup() string })
+	if !ok {
+		return ""
+	}
+
+	return g.GetGroup()
+}
+
+// AddGroup registers a group, before adding its jobs
+func (s *Scheduler) AddGroup(g *Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.groups[g.Name] = g
+}
+
+// GetGroup returns the group with the given name, nil if not registered
+func (s *Scheduler) GetGroup(name string) *Group {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.groups[name]
+}
+
+// schedule returns the schedule of the given job, delayed by the offset of
+// its group and the offset of the host within its spread, if any
+func (s *Scheduler) schedule(j Job) (cron.Schedule, error) {
+	parser := s.parser
+	if parser == nil {
+		parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	}
+
+	// a one-shot schedule runs at the exact time, without the offset
+	if at, ok, err := parseAt(j.GetSchedule()); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		return atSchedule{at: at}, nil
+	}
+
+	// the simulations assume the executions take no time
+	if d, ok, err := parseAfter(j.GetSchedule()); ok {
+		if err != nil {
+			return nil, err
+		}
+
+		return cron.Every(d), nil
+	}
+
+	schedule, err := parser.Parse(j.GetSchedule())
+	if err != nil {
+		return nil, err
+	}
+
+	offset := s.scheduleSpread(j)
+	if g := s.GetGroup(JobGroup(j)); g != nil {
+		offset += g.ScheduleOffset
+	}
+
+	if offset > 0 {
+		schedule = &offsetSchedule{Schedule: schedule, offset: offset}
+	}
+
+	return schedule, nil
+}
+
+// offsetSchedule is a schedule with all its times delayed by the offset
+type offsetSchedule struct {
+	cron.Schedule
+	offset time.Duration
+}
+
+func (s *offsetSchedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t.Add(-s.offset))
+	if next.IsZero() {
+		return next
+	}
+
+	return next.Add(s.offset)
+}
+
+// acquireGroup takes a slot of the group of the job, if it bounds the
+// concurrent executions of its jobs
+func (c *Context) acquireGroup() func() {
+	if c.Scheduler == nil {
+		return func() {}
+	}
+
+	g := c.Scheduler.GetGroup(JobGroup(c.Job))
+	if g == nil || g.pool == nil {
+		return func() {}
+	}
+
+	return g.pool.acquire(c.Job, func(string) {
+		c.Log(fmt.Sprintf("Waiting for a slot of the group %s, %d running", g.Name, g.MaxConcurrent))
+	})
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteGroup struct{}
+
+var _ = Suite(&SuiteGroup{})
+
+func (s *SuiteGroup) TestJobGroup(c *C) {
+	j := &TestJob{}
+	c.Assert(JobGroup(j), Equals, "")
+
+	j.Group = "backups"
+	c.Assert(JobGroup(j), Equals, "backups")
+}
+
+func (s *SuiteGroup) TestAddJobScheduleOffset(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.AddGroup(NewGroup("backups", 30*time.Minute, 0))
+	c.Assert(sc.GetGroup("backups").ScheduleOffset, Equals, 30*time.Minute)
+	c.Assert(sc.GetGroup("foo"), IsNil)
+
+	j := &TestJob{}
+	j.Schedule, j.Group = "@daily", "backups"
+	c.Assert(sc.AddJob(j), IsNil)
+
+	from := time.Date(2020, 1, 1, 0, 10, 0, 0, time.UTC)
+	schedule := sc.cron.Entry(sc.entries[j]).Schedule
+	c.Assert(schedule.Next(from), Equals, time.Date(2020, 1, 1, 0, 30, 0, 0, time.UTC))
+	c.Assert(schedule.Next(from.Add(30*time.Minute)), Equals, time.Date(2020, 1, 2, 0, 30, 0, 0, time.UTC))
+
+	own := &TestJob{}
+	own.Schedule = "@daily"
+	c.Assert(sc.AddJob(own), IsNil)
+	schedule = sc.cron.Entry(sc.entries[own]).Schedule
+	c.Assert(schedule.Next(from), Equals, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+}
+
+func (s *SuiteGroup) TestContextNextGroup(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.AddGroup(NewGroup("backups", 0, 1))
+
+	j := &TestJob{}
+	j.Group = "backups"
+	release := (&Context{Scheduler: sc, Job: j}).acquireGroup()
+
+	ctx := NewContext(sc, j, NewExecution())
+	ctx.Start()
+
+	done := make(chan error)
+	go func() { done <- ctx.Next() }()
+
+	select {
+	case <-done:
+		c.Fatal("ran without a slot of the group")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	c.Assert(<-done, IsNil)
+	c.Assert(j.Called, Equals, 1)
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"os"
+	"time"
+)
+
+// historyBuffer max number of executions waiting to be stored, the executions
+// finished while the buffer is full are dropped
+const historyBuffer = 1000
+
+// HistoryRecord is an execution, as stored in the history
+type HistoryRecord struct {
+	// Host running the scheduler, to tell apart the executions of several
+	// hosts stored in the same place
+	Host        string
+	Job         string
+	ExecutionID string
+	Date        time.Time
+	Duration    time.Duration
+	Failed      bool
+	Skipped     bool
+	Error       string
+}
+
+// HistoryStore stores the executions, e.g. in a database
+type HistoryStore interface {
+	Store(r *HistoryRecord) error
+}
+
+// NewHistoryRecord returns the record of the given finished execution, the
+// error redacted by the given redactor
+func NewHistoryRecord(host string, j Job, e *Execution, r *Redactor) *HistoryRecord {
+	rec := &HistoryRecord{
+		Host:        host,
+		Job:         j.GetName(),
+		ExecutionID: e.ID,
+		Date:        e.Date,
+		Duration:    e.Duration,
+		Failed:      e.Failed,
+		Skipped:     e.Skipped,
+	}
+
+	if e.Error != nil {
+		rec.Error = r.Redact(e.Error.Error())
+	}
+
+	return rec
+}
+
+// RecordHistory stores the finished and skipped executions of the scheduler in
+// the given store, in background, until the returned func is called. The
+// executions are stored in order, the failures are logged.
+func RecordHistory(s *Scheduler, store HistoryStore) (stop func()) {
+	host, _ := os.Hostname()
+	records := make(chan *HistoryRecord, historyBuffer)
+
+	unsubscribe := s.Events.Subscribe(func(e Event) {
+		var rec *HistoryRecord
+		switch e := e.(type) {
+		case *ExecutionFinished:
+			rec = NewHistoryRecord(host, e.Job, e.Execution, s.Redactor)
+		case *ExecutionSkipped:
+			rec = NewHistoryRecord(host, e.Job, e.Execution, s.Redactor)
+		default:
+			return
+		}
+
+		select {
+		case records <- rec:
+		default:
+			s.Logger.Errorf("History buffer full, execution %s of job %q not stored", rec.ExecutionID, rec.Job)
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for rec := range records {
+			if err := store.Store(rec); err != nil {
+				s.Logger.Errorf("Unable to store execution %s of job %q in the history: %s", rec.ExecutionID, rec.Job, err)
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		close(records)
+		<-done
+	}
+}
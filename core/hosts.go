@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// HostnameEnv the environment variable overriding the host name matched by
+// the hosts of the jobs
+const HostnameEnv = "OFELIA_HOSTNAME"
+
+// Hostname returns the name of the host matched by the hosts of the jobs,
+// HostnameEnv if set
+func Hostname() string {
+	if h := os.Getenv(HostnameEnv); h != "" {
+		return h
+	}
+
+	h, _ := os.Hostname()
+	return h
+}
+
+// JobHosts returns the patterns of the hosts targeted by the given job, the
+// comma separated ones split, none if it targets all the hosts
+func JobHosts(j Job) []string {
+	h, ok := j.(interface{ GetHosts() []string })
+	if !ok {
+		return nil
+	}
+
+	var hosts []string
+	for _, v := range h.GetHosts() {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				hosts = append(hosts, p)
+			}
+		}
+	}
+
+	return hosts
+}
+
+// JobTargets returns true if the given job targets the given host, matching
+// any of its hosts, as a shell pattern, e.g. "edge-*", or if it has no hosts
+func JobTargets(j Job, host string) (bool, error) {
+	hosts := JobHosts(j)
+	if len(hosts) == 0 {
+		return true, nil
+	}
+
+	targeted := false
+	for _, p := range hosts {
+		ok, err := path.Match(p, host)
+		if err != nil {
+			return false, fmt.Errorf("invalid hosts pattern %q", p)
+		}
+
+		targeted = targeted || ok
+	}
+
+	return targeted, nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"os"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteHosts struct{}
+
+var _ = Suite(&SuiteHosts{})
+
+func (s *SuiteHosts) TestJobTargets(c *C) {
+	j := &TestJob{}
+	c.Assert(JobHosts(j), IsNil)
+
+	ok, err := JobTargets(j, "web01")
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	j.Hosts = []string{"edge-*, db01", "cache0[1-3]"}
+	c.Assert(JobHosts(j), DeepEquals, []string{"edge-*", "db01", "cache0[1-3]"})
+
+	for host, expected := range map[string]bool{
+		"edge-fra1": true,
+		"db01":      true,
+		"cache02":   true,
+		"cache04":   false,
+		"db02":      false,
+		"web01":     false,
+	} {
+		ok, err := JobTargets(j, host)
+		c.Assert(err, IsNil)
+		c.Assert(ok, Equals, expected, Commentf("host %s", host))
+	}
+
+	j.Hosts = []string{"edge-["}
+	_, err = JobTargets(j, "edge-1")
+	c.Assert(err, ErrorMatches, `invalid hosts pattern "edge-\["`)
+}
+
+func (s *SuiteHosts) TestHostname(c *C) {
+	defer os.Unsetenv(HostnameEnv)
+
+	os.Unsetenv(HostnameEnv)
+	h, _ := os.Hostname()
+	c.Assert(Hostname(), Equals, h)
+
+	os.Setenv(HostnameEnv, "edge-fra1")
+	c.Assert(Hostname(), Equals, "edge-fra1")
+}
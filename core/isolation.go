@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ValidateIsolation checks the isolation options of the job
+func (j *LocalJob) ValidateIsolation() error {
+	if j.Chroot != "" && !filepath.IsAbs(j.Chroot) {
+		return fmt.Errorf("invalid chroot %q, must be an absolute path", j.Chroot)
+	}
+
+	if j.Chroot != "" && j.Slice != "" {
+		return fmt.Errorf("chroot and slice are exclusive")
+	}
+
+	if (j.PrivateTmp || j.NoNewPrivileges) && !isolationSupported {
+		return fmt.Errorf("private-tmp and no-new-privileges are only supported on Linux")
+	}
+
+	return nil
+}
+
+// start starts the command, with a private /tmp and without gaining
+// privileges if required
+func (j *LocalJob) start(cmd *exec.Cmd) error {
+	if !j.PrivateTmp && !j.NoNewPrivileges {
+		return cmd.Start()
+	}
+
+	return startIsolated(cmd, j.Chroot, j.PrivateTmp, j.NoNewPrivileges)
+}
+
+// lookPathIn returns the path inside root of the given executable, looked up
+// in the PATH as exec.LookPath, the relative paths being relative to dir
+func lookPathIn(root, dir, file string) (string, error) {
+	if strings.Contains(file, "/") {
+		p := file
+		if !filepath.IsAbs(p) {
+			p = filepath.Join("/", dir, p)
+		}
+
+		if isExecutable(filepath.Join(root, p)) {
+			return p, nil
+		}
+
+		return "", fmt.Errorf("executable %q not found in the chroot %q", file, root)
+	}
+
+	for _, d := range filepath.SplitList(os.Getenv("PATH")) {
+		if !filepath.IsAbs(d) {
+			continue
+		}
+
+		p := filepath.Join(d, file)
+		if isExecutable(filepath.Join(root, p)) {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("executable %q not found in the $PATH of the chroot %q", file, root)
+}
+
+func isExecutable(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir() && info.Mode()&0111 != 0
+}
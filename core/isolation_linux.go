@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+const (
+	isolationSupported = true
+	prSetNoNewPrivs    = 38
+)
+
+// startIsolated starts the command from a thread of its own, in a mount
+// namespace with a tmpfs mounted on the /tmp of the given root, and without
+// gaining privileges, both inherited by the command. The thread is never
+// unlocked, so discarded once the command started.
+func startIsolated(cmd *exec.Cmd, root string, privateTmp, noNewPrivs bool) error {
+	errc := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		errc <- isolateThread(cmd, root, privateTmp, noNewPrivs)
+	}()
+
+	return <-errc
+}
+
+func isolateThread(cmd *exec.Cmd, root string, privateTmp, noNewPrivs bool) error {
+	if privateTmp {
+		if err := syscall.Unshare(syscall.CLONE_NEWNS | syscall.CLONE_FS); err != nil {
+			return fmt.Errorf("unable to create the mount namespace of the private tmp: %s", err)
+		}
+
+		if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("unable to make the mounts private: %s", err)
+		}
+
+		tmp := filepath.Join("/", root, "tmp")
+		if err := syscall.Mount("tmpfs", tmp, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return fmt.Errorf("unable to mount the private tmp on %q: %s", tmp, err)
+		}
+	}
+
+	if noNewPrivs {
+		if _, _, e := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); e != 0 {
+			return fmt.Errorf("unable to set no-new-privileges: %s", e)
+		}
+	}
+
+	return cmd.Start()
+}
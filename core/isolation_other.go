@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+const isolationSupported = false
+
+func startIsolated(cmd *exec.Cmd, root string, privateTmp, noNewPrivs bool) error {
+	return fmt.Errorf("private-tmp and no-new-privileges are only supported on Linux")
+}
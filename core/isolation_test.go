@@ -0,0 +1,116 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteIsolation struct{}
+
+var _ = Suite(&SuiteIsolation{})
+
+func (s *SuiteIsolation) run(j *LocalJob) (*Execution, error) {
+	e := NewExecution()
+	err := j.Run(&Context{Job: j, Execution: e})
+	return e, err
+}
+
+func (s *SuiteIsolation) TestRunNoNewPrivileges(c *C) {
+	if !isolationSupported {
+		c.Skip("Linux only")
+	}
+
+	j := &LocalJob{NoNewPrivileges: true}
+	j.Command = `grep NoNewPrivs /proc/self/status`
+
+	e, err := s.run(j)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Fields(e.OutputStream.String()), DeepEquals, []string{"NoNewPrivs:", "1"})
+
+	// the privileges of the other threads of ofelia are kept
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	status, err := ioutil.ReadFile("/proc/thread-self/status")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(status), "NoNewPrivs:\t1"), Equals, false)
+}
+
+func (s *SuiteIsolation) TestRunPrivateTmp(c *C) {
+	if !isolationSupported || os.Geteuid() != 0 {
+		c.Skip("Linux only, as root")
+	}
+
+	name := filepath.Base(c.MkDir())
+	j := &LocalJob{PrivateTmp: true}
+	j.Command = `sh -c "touch /tmp/` + name + ` && ls /tmp"`
+
+	e, err := s.run(j)
+	if err != nil && strings.Contains(err.Error(), syscall.EPERM.Error()) {
+		c.Skip("mount namespaces not permitted")
+	}
+
+	c.Assert(err, IsNil)
+	c.Assert(e.OutputStream.String(), Equals, name+"\n")
+
+	_, err = os.Stat(filepath.Join("/tmp", name))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *SuiteIsolation) TestLookPathIn(c *C) {
+	root := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(root, "bin"), 0755), IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(root, "srv"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(root, "bin", "tool"), nil, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(root, "srv", "run.sh"), nil, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(root, "srv", "data"), nil, 0644), IsNil)
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", "/usr/bin:/bin")
+
+	p, err := lookPathIn(root, "", "tool")
+	c.Assert(err, IsNil)
+	c.Assert(p, Equals, "/bin/tool")
+
+	p, err = lookPathIn(root, "/srv", "./run.sh")
+	c.Assert(err, IsNil)
+	c.Assert(p, Equals, "/srv/run.sh")
+
+	_, err = lookPathIn(root, "", "/srv/data")
+	c.Assert(err, ErrorMatches, `executable "/srv/data" not found in the chroot ".*"`)
+
+	_, err = lookPathIn(root, "", "missing")
+	c.Assert(err, ErrorMatches, `executable "missing" not found in the \$PATH of the chroot ".*"`)
+}
+
+func (s *SuiteIsolation) TestBuildCommandChroot(c *C) {
+	root := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(root, "bin"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(root, "bin", "sh"), nil, 0755), IsNil)
+
+	j := &LocalJob{Chroot: root, Dir: "/srv"}
+	j.Command = `sh -c "echo foo"`
+
+	cmd, err := j.buildCommand(&Context{Job: j, Execution: NewExecution()})
+	c.Assert(err, IsNil)
+	c.Assert(cmd.Path, Equals, "/bin/sh")
+	c.Assert(cmd.Dir, Equals, "/srv")
+	c.Assert(cmd.SysProcAttr.Chroot, Equals, root)
+}
+
+func (s *SuiteIsolation) TestValidateIsolation(c *C) {
+	c.Assert((&LocalJob{}).ValidateIsolation(), IsNil)
+	c.Assert((&LocalJob{Chroot: "/srv/jail"}).ValidateIsolation(), IsNil)
+
+	err := (&LocalJob{Chroot: "jail"}).ValidateIsolation()
+	c.Assert(err, ErrorMatches, `invalid chroot "jail", must be an absolute path`)
+
+	err = (&LocalJob{Chroot: "/srv/jail", Slice: "batch.slice"}).ValidateIsolation()
+	c.Assert(err, ErrorMatches, `chroot and slice are exclusive`)
+}
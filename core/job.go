@@ -8,6 +8,76 @@ type BareJob struct {
 	Schedule string
 	Name     string
 	Command  string
+	// Matrix values, the job runs once per value at every trigger
+	Matrix []string
+	// Templated when true, the command is rendered as a template with the
+	// params of the execution, see JobTemplated
+	Templated bool `json:",omitempty"`
+	// Owner tenant the job belongs to, e.g. the compose project of the
+	// container declaring it
+	Owner string `json:",omitempty"`
+	// SourceContainer, SourceService and SourceProject the container, the
+	// compose or swarm service and the compose project declaring the job with
+	// its docker labels, see JobProvenance
+	SourceContainer string `gcfg:"source-container" mapstructure:"source-container" json:",omitempty"`
+	SourceService   string `gcfg:"source-service" mapstructure:"source-service" json:",omitempty"`
+	SourceProject   string `gcfg:"source-project" mapstructure:"source-project" json:",omitempty"`
+	// DockerLabels true when the job is declared by docker labels, any
+	// container with labels being able to declare jobs, see
+	// JobDeclaredByLabels
+	DockerLabels bool `gcfg:"docker-labels" mapstructure:"docker-labels" json:",omitempty"`
+	// Description of the job, shown in the list of the jobs and the
+	// notifications
+	Description string `json:",omitempty"`
+	// Tags free-form "<name>=<value>" annotations of the job, shown in the
+	// notifications and added as labels to its metrics, see JobTags
+	Tags []string `gcfg:"tags" mapstructure:"tags" json:",omitempty"`
+	// MaxTimeBetweenSuccess when set, e.g. "26h", the job is reported as stale
+	// if it doesn't succeed within this time, see WatchFreshness
+	MaxTimeBetweenSuccess string `gcfg:"max-time-between-success" mapstructure:"max-time-between-success" json:",omitempty"`
+	// DurationAnomaly when set, the successful executions deviating from the
+	// mean duration by more than this number of standard deviations, e.g. "3",
+	// or this percentage, e.g. "50%", are reported, see WatchDurations
+	DurationAnomaly string `gcfg:"duration-anomaly" mapstructure:"duration-anomaly" json:",omitempty"`
+	// Trigger when set, the job also runs on external events, "webhook",
+	// "nats:<subject>" or "watch:<directory>", see ParseTrigger
+	Trigger string `json:",omitempty"`
+	// StdinFrom when set, the last successful output of the job with this
+	// name is fed to the stdin of the command
+	StdinFrom string `gcfg:"stdin-from" mapstructure:"stdin-from" json:",omitempty"`
+	// Noop when true, the executions run the middlewares and log the command,
+	// without running it, see JobNoop
+	Noop bool `json:",omitempty"`
+	// InheritGlobals when "false", the job doesn't use the middlewares of the
+	// scheduler, only its own ones, see JobInheritGlobals
+	InheritGlobals string `gcfg:"inherit-globals" mapstructure:"inherit-globals" json:",omitempty"`
+	// MaxSkew when set, e.g. "5m", the scheduled executions starting later
+	// than this after their scheduled time are skipped, see JobMaxSkew
+	MaxSkew string `gcfg:"max-skew" mapstructure:"max-skew" json:",omitempty"`
+	// Misfire what happens to the executions starting later than max-skew,
+	// "skip" (default), "run-now" or "queue", see JobMisfire
+	Misfire string `json:",omitempty"`
+	// Group when set, the name of the group sharing its settings with the
+	// job, see Group
+	Group string `json:",omitempty"`
+	// RemoveAfterRun when true, the job with an "@at" schedule is removed
+	// once it ran, see JobAt
+	RemoveAfterRun bool `gcfg:"remove-after-run" mapstructure:"remove-after-run" json:",omitempty"`
+	// SkipIfUnchanged paths of the host, or of a container as
+	// "<container>:<path>", the executions are skipped if they didn't change
+	// since the last successful one, see JobSkipIfUnchanged
+	SkipIfUnchanged []string `gcfg:"skip-if-unchanged" mapstructure:"skip-if-unchanged" json:",omitempty"`
+	// OutputDiff when true, the executions are reported with the changes of
+	// their output since the last successful one, see JobOutputDiff
+	OutputDiff bool `gcfg:"output-diff" mapstructure:"output-diff" json:",omitempty"`
+	// Hosts when set, patterns of the names of the hosts scheduling the job,
+	// e.g. "edge-*,db01", so a config can be shared by a fleet, see JobTargets
+	Hosts []string `json:",omitempty"`
+	// ScheduleSpread when set, e.g. "30m", the scheduled executions are
+	// delayed by an offset within this window derived from the name of the
+	// host, so the hosts sharing a config don't run the job at once, see
+	// JobScheduleSpread
+	ScheduleSpread string `gcfg:"schedule-spread" mapstructure:"schedule-spread" json:",omitempty"`
 
 	middlewareContainer
 	running int32
@@ -25,6 +95,90 @@ func (j *BareJob) GetCommand() string {
 	return j.Command
 }
 
+func (j *BareJob) GetOwner() string {
+	return j.Owner
+}
+
+func (j *BareJob) GetProvenance() Provenance {
+	return Provenance{Container: j.SourceContainer, Service: j.SourceService, Project: j.SourceProject}
+}
+
+func (j *BareJob) GetDockerLabels() bool {
+	return j.DockerLabels
+}
+
+func (j *BareJob) GetDescription() string {
+	return j.Description
+}
+
+func (j *BareJob) GetTags() []string {
+	return j.Tags
+}
+
+func (j *BareJob) GetMaxTimeBetweenSuccess() string {
+	return j.MaxTimeBetweenSuccess
+}
+
+func (j *BareJob) GetDurationAnomaly() string {
+	return j.DurationAnomaly
+}
+
+func (j *BareJob) GetTrigger() string {
+	return j.Trigger
+}
+
+func (j *BareJob) GetStdinFrom() string {
+	return j.StdinFrom
+}
+
+func (j *BareJob) GetNoop() bool {
+	return j.Noop
+}
+
+func (j *BareJob) GetInheritGlobals() string {
+	return j.InheritGlobals
+}
+
+func (j *BareJob) GetMaxSkew() string {
+	return j.MaxSkew
+}
+
+func (j *BareJob) GetMisfire() string {
+	return j.Misfire
+}
+
+func (j *BareJob) GetGroup() string {
+	return j.Group
+}
+
+func (j *BareJob) GetRemoveAfterRun() bool {
+	return j.RemoveAfterRun
+}
+
+func (j *BareJob) GetSkipIfUnchanged() []string {
+	return j.SkipIfUnchanged
+}
+
+func (j *BareJob) GetOutputDiff() bool {
+	return j.OutputDiff
+}
+
+func (j *BareJob) GetHosts() []string {
+	return j.Hosts
+}
+
+func (j *BareJob) GetScheduleSpread() string {
+	return j.ScheduleSpread
+}
+
+func (j *BareJob) GetMatrix() []string {
+	return j.Matrix
+}
+
+func (j *BareJob) GetTemplated() bool {
+	return j.Templated
+}
+
 func (j *BareJob) Running() int32 {
 	return atomic.LoadInt32(&j.running)
 }
@@ -11,11 +11,13 @@ func (s *SuiteBareJob) TestGetters(c *C) {
 		Name:     "foo",
 		Schedule: "bar",
 		Command:  "qux",
+		Owner:    "baz",
 	}
 
 	c.Assert(job.GetName(), Equals, "foo")
 	c.Assert(job.GetSchedule(), Equals, "bar")
 	c.Assert(job.GetCommand(), Equals, "qux")
+	c.Assert(job.GetOwner(), Equals, "baz")
 }
 
 func (s *SuiteBareJob) TestNotifyStartStop(c *C) {
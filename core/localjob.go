@@ -2,6 +2,7 @@ package core
 
 import (
 	"os/exec"
+	"syscall"
 
 	"github.com/gobs/args"
 )
@@ -10,10 +11,34 @@ type LocalJob struct {
 	BareJob     `mapstructure:",squash"`
 	Dir         string
 	Environment []string
+	// Flock lock file locked by the executions, as flock(1), to coordinate
+	// with the other tools of the host
+	Flock        string
+	FlockPolicy  string `gcfg:"flock-policy" mapstructure:"flock-policy"`
+	FlockTimeout string `gcfg:"flock-timeout" mapstructure:"flock-timeout"`
+	// Slice systemd slice the executions are run in, as transient scopes
+	Slice         string
+	SliceProperty []string `gcfg:"slice-property" mapstructure:"slice-property"`
+	// Cgroup cgroup v2 the executions are moved to, relative to the root of
+	// the hierarchy
+	Cgroup      string
+	CgroupLimit []string `gcfg:"cgroup-limit" mapstructure:"cgroup-limit"`
+	// Chroot root directory of the executions
+	Chroot string
+	// PrivateTmp when true, the executions have a /tmp of their own, in
+	// memory, Linux only
+	PrivateTmp bool `gcfg:"private-tmp" mapstructure:"private-tmp"`
+	// NoNewPrivileges when true, the executions can't gain privileges, e.g.
+	// by setuid binaries, Linux only
+	NoNewPrivileges bool `gcfg:"no-new-privileges" mapstructure:"no-new-privileges"`
+
+	EnvConfig `mapstructure:",squash"`
 }
 
-func NewLocalJob() *LocalJob {
-	return &LocalJob{}
+func NewLocalJob(opts ...JobOption) *LocalJob {
+	j := &LocalJob{}
+	j.apply(opts)
+	return j
 }
 
 func (j *LocalJob) Run(ctx *Context) error {
@@ -22,12 +47,76 @@ func (j *LocalJob) Run(ctx *Context) error {
 		return err
 	}
 
-	return cmd.Run()
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	err = j.run(cmd)
+	if exit, ok := err.(*exec.ExitError); ok {
+		ctx.Execution.ExitCode = exit.ExitCode()
+	}
+
+	return err
+}
+
+// run runs the command, moved to the cgroup of the job, if any, once started
+func (j *LocalJob) run(cmd *exec.Cmd) error {
+	var cgroup string
+	if j.Cgroup != "" {
+		dir, err := j.prepareCgroup()
+		if err != nil {
+			return err
+		}
+
+		cgroup = dir
+	}
+
+	if err := j.start(cmd); err != nil {
+		return err
+	}
+
+	if cgroup != "" {
+		if err := j.moveToCgroup(cmd, cgroup); err != nil {
+			return err
+		}
+	}
+
+	return cmd.Wait()
 }
 
 func (j *LocalJob) buildCommand(ctx *Context) (*exec.Cmd, error) {
-	args := args.GetArgs(j.Command)
-	bin, err := exec.LookPath(args[0])
+	cmd, err := ctx.Render(j.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	args := args.GetArgs(cmd)
+	if j.Slice != "" {
+		args = j.sliceCommand(ctx, args)
+	}
+
+	var bin string
+	var attr *syscall.SysProcAttr
+	if j.Chroot != "" {
+		bin, err = lookPathIn(j.Chroot, j.Dir, args[0])
+		attr = &syscall.SysProcAttr{Chroot: j.Chroot}
+	} else {
+		bin, err = exec.LookPath(args[0])
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := ctx.Stdin()
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := ctx.localEnv(j.Environment)
 	if err != nil {
 		return nil, err
 	}
@@ -35,9 +124,12 @@ func (j *LocalJob) buildCommand(ctx *Context) (*exec.Cmd, error) {
 	return &exec.Cmd{
 		Path:   bin,
 		Args:   args,
+		Stdin:  stdin,
 		Stdout: ctx.Execution.OutputStream,
 		Stderr: ctx.Execution.ErrorStream,
-		Env:    j.Environment,
+		Env:    env,
 		Dir:    j.Dir,
+
+		SysProcAttr: attr,
 	}, nil
 }
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"io/ioutil"
+	"path/filepath"
+
 	"github.com/armon/circbuf"
 
 	. "gopkg.in/check.v1"
@@ -22,3 +25,29 @@ func (s *SuiteLocalJob) TestRun(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(b.String(), Equals, "foo bar\n")
 }
+
+func (s *SuiteLocalJob) TestRunParentEnv(c *C) {
+	job := &LocalJob{}
+	job.Command = `sh -c "echo $OFELIA_PARENT; exit 3"`
+
+	e := NewExecution()
+	e.Params = map[string]string{ParentParam: "dump"}
+
+	err := job.Run(&Context{Execution: e})
+	c.Assert(err, NotNil)
+	c.Assert(e.ExitCode, Equals, 3)
+	c.Assert(e.OutputStream.String(), Equals, "dump\n")
+}
+
+func (s *SuiteLocalJob) TestRunEnvFile(c *C) {
+	file := filepath.Join(c.MkDir(), "job.env")
+	c.Assert(ioutil.WriteFile(file, []byte("TOKEN=foo\n"), 0600), IsNil)
+
+	job := &LocalJob{}
+	job.Command = `sh -c "echo $TOKEN"`
+	job.EnvFile = file
+
+	e := NewExecution()
+	c.Assert(job.Run(&Context{Job: job, Execution: e}), IsNil)
+	c.Assert(e.OutputStream.String(), Equals, "foo\n")
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"expvar"
+	"net/http"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// The health of the scheduler itself, beyond the metrics of every job
+var (
+	// SchedulerDrift the time, in seconds, between the scheduled time of the
+	// last scheduled execution and its start, as the expvar
+	// "scheduler_drift_seconds"
+	SchedulerDrift = expvar.NewFloat("scheduler_drift_seconds")
+	// PendingExecutions the executions waiting for a slot of the pool, as the
+	// expvar "pending_executions"
+	PendingExecutions = expvar.NewInt("pending_executions")
+	// DockerRequests the requests to the docker API and the failed ones, the
+	// ones not reaching it or answered with a server error, as the expvar
+	// "docker_requests" with the keys "total" and "errors"
+	DockerRequests = expvar.NewMap("docker_requests")
+	// ReconcileDuration the duration, in seconds, of the last reconciliation
+	// of the docker labels, as the expvar "reconcile_duration_seconds"
+	ReconcileDuration = expvar.NewFloat("reconcile_duration_seconds")
+	// ReloadSuccess the unix time of the last successful reload of the config,
+	// as the expvar "last_reload_success"
+	ReloadSuccess = expvar.NewInt("last_reload_success")
+	// ReloadFailures the number of failed reloads of the config, as the expvar
+	// "reload_failures"
+	ReloadFailures = expvar.NewInt("reload_failures")
+	// GitSyncSuccess the unix time of the last successful sync of the config
+	// from git, as the expvar "last_git_sync_success"
+	GitSyncSuccess = expvar.NewInt("last_git_sync_success")
+	// GitSyncFailures the number of failed syncs of the config from git, as
+	// the expvar "git_sync_failures"
+	GitSyncFailures = expvar.NewInt("git_sync_failures")
+	// GitSyncCommit the commit of the config synced from git, as the expvar
+	// "git_sync_commit"
+	GitSyncCommit = expvar.NewString("git_sync_commit")
+	// KVSyncSuccess the unix time of the last successful sync of the jobs from
+	// the key-value store, as the expvar "last_kv_sync_success"
+	KVSyncSuccess = expvar.NewInt("last_kv_sync_success")
+	// KVSyncFailures the number of failed syncs of the jobs from the key-value
+	// store, as the expvar "kv_sync_failures"
+	KVSyncFailures = expvar.NewInt("kv_sync_failures")
+	// KVSyncIndex the index of the keys of the jobs synced from the key-value
+	// store, as the expvar "kv_sync_index"
+	KVSyncIndex = expvar.NewInt("kv_sync_index")
+)
+
+func init() {
+	DockerRequests.Add("total", 0)
+	DockerRequests.Add("errors", 0)
+}
+
+// InstrumentDockerClient counts the requests of the client in DockerRequests
+func InstrumentDockerClient(c *docker.Client) {
+	if c.HTTPClient == nil {
+		return
+	}
+
+	next := c.HTTPClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	c.HTTPClient.Transport = &countingTransport{next: next}
+}
+
+// countingTransport counts the requests and the failed ones in DockerRequests
+type countingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	DockerRequests.Add("total", 1)
+	if err != nil || resp.StatusCode >= 500 {
+		DockerRequests.Add("errors", 1)
+	}
+
+	return resp, err
+}
+
+// recordReloadMetrics records the success or the failure of the reload
+func recordReloadMetrics(r *Reload) {
+	if r.Error != "" {
+		ReloadFailures.Add(1)
+		return
+	}
+
+	ReloadSuccess.Set(r.Date.Unix())
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteMetrics struct{}
+
+var _ = Suite(&SuiteMetrics{})
+
+func (s *SuiteMetrics) TestInstrumentDockerClient(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_ping" {
+			w.Write([]byte("OK"))
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	defer ts.Close()
+
+	client, err := docker.NewClient(ts.URL)
+	c.Assert(err, IsNil)
+	InstrumentDockerClient(client)
+
+	total, errors := dockerRequests()
+	c.Assert(client.Ping(), IsNil)
+	c.Assert(client.Ping(), IsNil)
+	_, err = client.InspectContainer("foo")
+	c.Assert(err, NotNil)
+
+	t, e := dockerRequests()
+	c.Assert(t-total, Equals, int64(3))
+	c.Assert(e-errors, Equals, int64(1))
+}
+
+func dockerRequests() (total, errors int64) {
+	return DockerRequests.Get("total").(*expvar.Int).Value(),
+		DockerRequests.Get("errors").(*expvar.Int).Value()
+}
+
+func (s *SuiteMetrics) TestRecordReload(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	failures := ReloadFailures.Value()
+
+	sc.RecordReload(&Reload{Date: time.Unix(1600000000, 0)})
+	c.Assert(ReloadSuccess.Value(), Equals, int64(1600000000))
+
+	sc.RecordReload(&Reload{Error: "foo"})
+	c.Assert(ReloadSuccess.Value(), Equals, int64(1600000000))
+	c.Assert(ReloadFailures.Value()-failures, Equals, int64(1))
+}
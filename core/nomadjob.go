@@ -0,0 +1,375 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNomadAddr          = "http://127.0.0.1:4646"
+	defaultNomadWatchInterval = 2 * time.Second
+	nomadTimeout              = 30 * time.Second
+)
+
+// NomadJob dispatches a parameterized batch job of Nomad and waits for it to
+// end, streaming the logs of its allocation to the output of the execution.
+// The exit code of the task is the one of the execution.
+type NomadJob struct {
+	BareJob `mapstructure:",squash"`
+	// Address of the Nomad API, NOMAD_ADDR or http://127.0.0.1:4646 by
+	// default
+	Address string
+	// Token ACL token, NOMAD_TOKEN by default
+	Token     string `secret:"true"`
+	Namespace string
+	Region    string
+	// JobID ID of the parameterized job dispatched, "nomad-job"
+	JobID string `gcfg:"nomad-job" mapstructure:"nomad-job"`
+	// Payload of the dispatched job, rendered with the params of the
+	// execution as the commands
+	Payload string
+	// Meta of the dispatched job, as "<key>=<value>"
+	Meta []string
+	// Task whose logs and exit code are read, the only one of the group by
+	// default
+	Task string
+
+	WatchConfig `mapstructure:",squash"`
+
+	client *http.Client
+}
+
+func NewNomadJob(opts ...JobOption) *NomadJob {
+	j := &NomadJob{}
+	j.apply(opts)
+	return j
+}
+
+// Validate checks the options of the job
+func (j *NomadJob) Validate() error {
+	if j.JobID == "" {
+		return fmt.Errorf("nomad-job is required")
+	}
+
+	if _, err := j.meta(); err != nil {
+		return err
+	}
+
+	if _, err := j.watchInterval(); err != nil {
+		return err
+	}
+
+	_, err := j.maxRuntime()
+	return err
+}
+
+func (j *NomadJob) Run(ctx *Context) error {
+	if err := j.Validate(); err != nil {
+		return err
+	}
+
+	if j.client == nil {
+		j.client = &http.Client{Timeout: nomadTimeout}
+	}
+
+	id, err := j.dispatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx.Log(fmt.Sprintf("Dispatched Nomad job %s", id))
+	alloc, task, err := j.wait(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	state := alloc.TaskStates[task]
+	for i := len(state.Events) - 1; i >= 0; i-- {
+		if e := state.Events[i]; e.Type == "Terminated" {
+			ctx.Execution.ExitCode = e.ExitCode
+			if e.ExitCode != 0 {
+				return exitCodeError(e.ExitCode)
+			}
+
+			break
+		}
+	}
+
+	if alloc.ClientStatus != "complete" || state.Failed {
+		return fmt.Errorf("Nomad allocation %s %s: %s", alloc.ID, alloc.ClientStatus, state.reason())
+	}
+
+	return nil
+}
+
+type nomadEvent struct {
+	Type           string
+	ExitCode       int
+	DisplayMessage string
+}
+
+type nomadTaskState struct {
+	State  string
+	Failed bool
+	Events []nomadEvent
+}
+
+// reason returns the message of the last event of the task
+func (s *nomadTaskState) reason() string {
+	if len(s.Events) == 0 {
+		return "no task events"
+	}
+
+	return s.Events[len(s.Events)-1].DisplayMessage
+}
+
+type nomadAllocation struct {
+	ID           string
+	ClientStatus string
+	CreateIndex  uint64
+	TaskStates   map[string]*nomadTaskState
+}
+
+func (j *NomadJob) dispatch(ctx *Context) (string, error) {
+	payload, err := ctx.Render(j.Payload)
+	if err != nil {
+		return "", err
+	}
+
+	meta, _ := j.meta()
+	in := map[string]interface{}{"Meta": meta}
+	if payload != "" {
+		in["Payload"] = base64.StdEncoding.EncodeToString([]byte(payload))
+	}
+
+	var out struct{ DispatchedJobID string }
+	path := "/v1/job/" + url.PathEscape(j.JobID) + "/dispatch"
+	if err := j.call(http.MethodPost, path, nil, in, &out); err != nil {
+		return "", fmt.Errorf("error dispatching Nomad job: %s", err)
+	}
+
+	return out.DispatchedJobID, nil
+}
+
+// wait waits for the dispatched job to be dead, writing the logs of the task
+// of its last allocation meanwhile, deregistering the job after the
+// max-runtime
+func (j *NomadJob) wait(ctx *Context, id string) (*nomadAllocation, string, error) {
+	interval, _ := j.watchInterval()
+	limit, _ := j.maxRuntime()
+
+	var alloc *nomadAllocation
+	var task string
+	var stdout, stderr int64
+
+	start := time.Now()
+	for {
+		var job struct{ Status string }
+		if err := j.call(http.MethodGet, "/v1/job/"+url.PathEscape(id), nil, nil, &job); err != nil {
+			return nil, "", fmt.Errorf("error reading Nomad job: %s", err)
+		}
+
+		var allocs []*nomadAllocation
+		if err := j.call(http.MethodGet, "/v1/job/"+url.PathEscape(id)+"/allocations", nil, nil, &allocs); err != nil {
+			return nil, "", fmt.Errorf("error listing Nomad allocations: %s", err)
+		}
+
+		if last := lastAllocation(allocs); last != nil {
+			if alloc == nil || last.ID != alloc.ID {
+				ctx.Log(fmt.Sprintf("Nomad allocation %s", last.ID))
+				stdout, stderr = 0, 0
+			}
+
+			alloc = last
+			t, err := j.task(alloc)
+			if err != nil {
+				return nil, "", err
+			}
+
+			task = t
+			if _, ok := alloc.TaskStates[task]; ok {
+				stdout += j.writeLogs(ctx, alloc.ID, task, "stdout", stdout, ctx.Execution.OutputStream)
+				stderr += j.writeLogs(ctx, alloc.ID, task, "stderr", stderr, ctx.Execution.ErrorStream)
+			}
+		}
+
+		if job.Status == "dead" {
+			if alloc == nil || alloc.TaskStates[task] == nil {
+				return nil, "", fmt.Errorf("Nomad job %s dead without running", id)
+			}
+
+			return alloc, task, nil
+		}
+
+		if time.Since(start) > limit {
+			if err := j.call(http.MethodDelete, "/v1/job/"+url.PathEscape(id), nil, nil, nil); err != nil {
+				return nil, "", fmt.Errorf("error stopping Nomad job: %s", err)
+			}
+
+			return nil, "", ErrMaxTimeRunning
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// lastAllocation returns the most recent allocation, the rescheduled ones
+// replacing the failed
+func lastAllocation(allocs []*nomadAllocation) *nomadAllocation {
+	var last *nomadAllocation
+	for _, a := range allocs {
+		if last == nil || a.CreateIndex > last.CreateIndex {
+			last = a
+		}
+	}
+
+	return last
+}
+
+// task returns the task whose logs and exit code are read
+func (j *NomadJob) task(alloc *nomadAllocation) (string, error) {
+	if j.Task != "" {
+		return j.Task, nil
+	}
+
+	var tasks []string
+	for t := range alloc.TaskStates {
+		tasks = append(tasks, t)
+	}
+
+	if len(tasks) > 1 {
+		sort.Strings(tasks)
+		return "", fmt.Errorf("task is required, the Nomad allocation has several: %s", strings.Join(tasks, ", "))
+	}
+
+	if len(tasks) == 0 {
+		return "", nil
+	}
+
+	return tasks[0], nil
+}
+
+// writeLogs writes the logs of the task from the given offset, returning the
+// length written. The errors are logged, the logs being best-effort.
+func (j *NomadJob) writeLogs(ctx *Context, alloc, task, kind string, offset int64, w io.Writer) int64 {
+	query := url.Values{
+		"task":   {task},
+		"type":   {kind},
+		"origin": {"start"},
+		"offset": {fmt.Sprint(offset)},
+		"plain":  {"true"},
+	}
+
+	var logs []byte
+	if err := j.call(http.MethodGet, "/v1/client/fs/logs/"+url.PathEscape(alloc), query, nil, &logs); err != nil {
+		ctx.Warn(fmt.Sprintf("Error reading the %s of the Nomad allocation: %s", kind, err))
+		return 0
+	}
+
+	w.Write(logs)
+	return int64(len(logs))
+}
+
+// call calls the Nomad API, decoding its JSON response in out, or copying it
+// if out is a *[]byte
+func (j *NomadJob) call(method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+
+	if j.Namespace != "" {
+		query.Set("namespace", j.Namespace)
+	}
+
+	if j.Region != "" {
+		query.Set("region", j.Region)
+	}
+
+	req, err := http.NewRequest(method, j.address()+path+"?"+query.Encode(), body)
+	if err != nil {
+		return err
+	}
+
+	if token := j.token(); token != "" {
+		req.Header.Set("X-Nomad-Token", token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("nomad returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*out, err = ioutil.ReadAll(resp.Body)
+		return err
+	default:
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
+
+func (j *NomadJob) address() string {
+	if j.Address != "" {
+		return strings.TrimSuffix(j.Address, "/")
+	}
+
+	if addr := os.Getenv("NOMAD_ADDR"); addr != "" {
+		return strings.TrimSuffix(addr, "/")
+	}
+
+	return defaultNomadAddr
+}
+
+func (j *NomadJob) token() string {
+	if j.Token != "" {
+		return j.Token
+	}
+
+	return os.Getenv("NOMAD_TOKEN")
+}
+
+func (j *NomadJob) meta() (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, m := range j.Meta {
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid meta %q, must be <key>=<value>", m)
+		}
+
+		meta[parts[0]] = parts[1]
+	}
+
+	return meta, nil
+}
+
+func (j *NomadJob) watchInterval() (time.Duration, error) {
+	return parseDuration("watch-interval", j.WatchInterval, defaultNomadWatchInterval)
+}
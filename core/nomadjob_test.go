@@ -0,0 +1,107 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteNomadJob struct{}
+
+var _ = Suite(&SuiteNomadJob{})
+
+func (s *SuiteNomadJob) TestRun(c *C) {
+	var polls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("X-Nomad-Token"), Equals, "s3cr3t")
+		c.Assert(r.URL.Query().Get("namespace"), Equals, "batch")
+
+		switch r.URL.Path {
+		case "/v1/job/report/dispatch":
+			var in struct {
+				Payload string
+				Meta    map[string]string
+			}
+
+			c.Assert(json.NewDecoder(r.Body).Decode(&in), IsNil)
+			payload, _ := base64.StdEncoding.DecodeString(in.Payload)
+			c.Assert(string(payload), Equals, "report foo")
+			c.Assert(in.Meta, DeepEquals, map[string]string{"period": "daily"})
+			w.Write([]byte(`{"DispatchedJobID": "report/dispatch-1"}`))
+		case "/v1/job/report/dispatch-1":
+			polls++
+			if polls < 2 {
+				w.Write([]byte(`{"Status": "running"}`))
+				return
+			}
+
+			w.Write([]byte(`{"Status": "dead"}`))
+		case "/v1/job/report/dispatch-1/allocations":
+			if polls < 2 {
+				w.Write([]byte(`[{"ID": "a1", "ClientStatus": "running", "TaskStates": {"main": {"State": "running"}}}]`))
+				return
+			}
+
+			w.Write([]byte(`[{"ID": "a1", "ClientStatus": "failed", "TaskStates": {"main": {
+				"State": "dead", "Failed": true,
+				"Events": [{"Type": "Started"}, {"Type": "Terminated", "ExitCode": 2}]
+			}}}]`))
+		case "/v1/client/fs/logs/a1":
+			q := r.URL.Query()
+			c.Assert(q.Get("task"), Equals, "main")
+			if q.Get("type") == "stderr" {
+				return
+			}
+
+			w.Write([]byte("line " + q.Get("offset") + "\n"))
+		default:
+			c.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+
+	defer ts.Close()
+
+	job := &NomadJob{Address: ts.URL, Token: "s3cr3t", Namespace: "batch", JobID: "report"}
+	job.Payload = "report {{.target}}"
+	job.Templated = true
+	job.Meta = []string{"period=daily"}
+	job.WatchInterval = "1ms"
+
+	e := NewExecution()
+	e.Params = map[string]string{"target": "foo"}
+	err := job.Run(&Context{Logger: &TestLogger{}, Job: job, Execution: e})
+	c.Assert(err, ErrorMatches, "error non-zero exit code: 2")
+	c.Assert(e.ExitCode, Equals, 2)
+	c.Assert(e.OutputStream.String(), Equals, "line 0\nline 7\n")
+}
+
+func (s *SuiteNomadJob) TestRunSeveralTasks(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/job/report/dispatch":
+			w.Write([]byte(`{"DispatchedJobID": "report/dispatch-1"}`))
+		case "/v1/job/report/dispatch-1":
+			w.Write([]byte(`{"Status": "running"}`))
+		case "/v1/job/report/dispatch-1/allocations":
+			w.Write([]byte(`[{"ID": "a1", "TaskStates": {"main": {}, "proxy": {}}}]`))
+		}
+	}))
+
+	defer ts.Close()
+
+	job := &NomadJob{Address: ts.URL, JobID: "report"}
+	err := job.Run(&Context{Logger: &TestLogger{}, Job: job, Execution: NewExecution()})
+	c.Assert(err, ErrorMatches, "task is required, the Nomad allocation has several: main, proxy")
+}
+
+func (s *SuiteNomadJob) TestValidate(c *C) {
+	job := &NomadJob{}
+	c.Assert(job.Validate(), ErrorMatches, "nomad-job is required")
+
+	job.JobID = "report"
+	job.Meta = []string{"period"}
+	c.Assert(job.Validate(), ErrorMatches, `invalid meta "period", must be <key>=<value>`)
+}
@@ -0,0 +1,21 @@
+package core
+
+// JobNoop returns true if the executions of the given job must not run its
+// command, for staging a job without side effects
+func JobNoop(j Job) bool {
+	if n, ok := j.(interface{ GetNoop() bool }); ok {
+		return n.GetNoop()
+	}
+
+	return false
+}
+
+// logNoop logs the command the execution would have run
+func (c *Context) logNoop() {
+	cmd, err := c.Render(c.Job.GetCommand())
+	if err != nil {
+		cmd = c.Job.GetCommand()
+	}
+
+	c.Log("Noop, would run - " + cmd)
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Clock provides the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock also running functions once a duration elapsed, as
+// measured by the clock, see AfterFunc
+type TimerClock interface {
+	Clock
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a function waiting to be run by a clock, until stopped
+type Timer interface {
+	// Stop prevents the function from running, returning false if it already
+	// ran or was stopped
+	Stop() bool
+}
+
+// AfterFunc runs f once the given duration elapsed, as measured by the given
+// clock if it is a TimerClock, by the system clock otherwise
+func AfterFunc(c Clock, d time.Duration, f func()) Timer {
+	if t, ok := c.(TimerClock); ok {
+		return t.AfterFunc(d, f)
+	}
+
+	return time.AfterFunc(d, f)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SchedulerOption configures a Scheduler, see NewScheduler
+type SchedulerOption func(*Scheduler)
+
+// WithLogger sets the logger of the scheduler
+func WithLogger(l Logger) SchedulerOption {
+	return func(s *Scheduler) {
+		s.Logger = l
+	}
+}
+
+// WithClock sets the clock used to date the executions
+func WithClock(c Clock) SchedulerOption {
+	return func(s *Scheduler) {
+		s.Clock = c
+	}
+}
+
+// WithParser sets the parser of the schedules of the jobs
+func WithParser(p cron.ScheduleParser) SchedulerOption {
+	return func(s *Scheduler) {
+		s.parser = p
+	}
+}
+
+// JobOption configures the common fields of a job, see the job constructors
+type JobOption func(*BareJob)
+
+// WithName sets the name of the job
+func WithName(name string) JobOption {
+	return func(j *BareJob) {
+		j.Name = name
+	}
+}
+
+// WithSchedule sets the schedule of the job
+func WithSchedule(schedule string) JobOption {
+	return func(j *BareJob) {
+		j.Schedule = schedule
+	}
+}
+
+// WithCommand sets the command of the job
+func WithCommand(command string) JobOption {
+	return func(j *BareJob) {
+		j.Command = command
+	}
+}
+
+// WithMiddlewares adds middlewares to the job
+func WithMiddlewares(ms ...Middleware) JobOption {
+	return func(j *BareJob) {
+		j.Use(ms...)
+	}
+}
+
+func (j *BareJob) apply(opts []JobOption) {
+	for _, opt := range opts {
+		opt(j)
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteOptions struct{}
+
+var _ = Suite(&SuiteOptions{})
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func (s *SuiteOptions) TestNewSchedulerOptions(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	logger := &TestLogger{}
+
+	sc := NewScheduler(nil,
+		WithLogger(logger),
+		WithClock(fixedClock(now)),
+		WithParser(cron.NewParser(cron.Second|cron.Minute|cron.Hour|cron.Dom|cron.Month|cron.Dow)),
+	)
+
+	c.Assert(sc.Logger, Equals, logger)
+
+	job := NewLocalJob(WithName("foo"), WithSchedule("0 0 1 * * *"), WithCommand("echo foo"))
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(job.GetName(), Equals, "foo")
+	c.Assert(job.GetCommand(), Equals, "echo foo")
+
+	ctx := NewContext(sc, job, NewExecution())
+	ctx.Start()
+	ctx.Stop(nil)
+
+	c.Assert(ctx.Execution.Date, Equals, now)
+	c.Assert(ctx.Execution.Duration, Equals, time.Duration(0))
+}
+
+func (s *SuiteOptions) TestNewSchedulerDefaultParser(c *C) {
+	job := NewLocalJob(WithSchedule("0 0 1 * * *"))
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), NotNil)
+}
+
+func (s *SuiteOptions) TestRegisterJobType(c *C) {
+	f := func() Job { return &LocalJob{} }
+	RegisterJobType("job-options-test", f)
+
+	_, ok := GetJobFactory("job-options-test")
+	c.Assert(ok, Equals, true)
+	c.Assert(func() { RegisterJobType("job-options-test", f) }, PanicMatches, `job type "job-options-test" already registered`)
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// auditActorOrphans actor of the removals of the orphaned jobs in the audit log
+const auditActorOrphans = "orphans"
+
+// OrphanedJobs the jobs whose container is gone, with the unix time they were
+// found orphaned at, as the expvar "orphaned_jobs"
+var OrphanedJobs = expvar.NewMap("orphaned_jobs")
+
+// ContainerGoneError is returned by the executions of a job whose container
+// doesn't exist anymore, e.g. removed after the job was read from its labels
+type ContainerGoneError struct {
+	Container string
+}
+
+func (e *ContainerGoneError) Error() string {
+	return fmt.Sprintf("container %s is gone, the job is orphaned", e.Container)
+}
+
+// OrphanMonitor tracks the jobs whose container is gone, the orphaned jobs. A
+// job is orphaned when an execution fails with a ContainerGoneError, emitting
+// a JobOrphaned event, until an execution finds its container again. With a
+// grace period, the jobs still orphaned after it are removed.
+type OrphanMonitor struct {
+	s           *Scheduler
+	grace       time.Duration
+	unsubscribe func()
+
+	mu   sync.Mutex
+	jobs map[Job]*orphan
+}
+
+type orphan struct {
+	since time.Time
+	timer *time.Timer
+}
+
+// WatchOrphans returns a OrphanMonitor watching the jobs of the given
+// scheduler, removing the orphaned ones after the given grace period if it
+// isn't zero. The monitor is set as the Orphans of the scheduler.
+func WatchOrphans(s *Scheduler, grace time.Duration) *OrphanMonitor {
+	m := &OrphanMonitor{s: s, grace: grace, jobs: make(map[Job]*orphan)}
+	m.unsubscribe = s.Events.Subscribe(m.handle)
+	s.Orphans = m
+	return m
+}
+
+// Stop stops watching the jobs, the orphaned jobs aren't removed anymore
+func (m *OrphanMonitor) Stop() {
+	m.unsubscribe()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, o := range m.jobs {
+		if o.timer != nil {
+			o.timer.Stop()
+		}
+	}
+}
+
+// Since returns when the given job was found orphaned, zero if it isn't
+func (m *OrphanMonitor) Since(j Job) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if o, ok := m.jobs[j]; ok {
+		return o.since
+	}
+
+	return time.Time{}
+}
+
+func (m *OrphanMonitor) handle(e Event) {
+	switch e := e.(type) {
+	case *ExecutionFinished:
+		if err, ok := e.Execution.Error.(*ContainerGoneError); ok {
+			m.orphaned(e.Job, err.Container)
+			return
+		}
+
+		// any other result reached the container
+		m.forget(e.Job)
+	case *JobRemoved:
+		m.forget(e.Job)
+	}
+}
+
+func (m *OrphanMonitor) orphaned(j Job, container string) {
+	m.mu.Lock()
+	if _, ok := m.jobs[j]; ok {
+		m.mu.Unlock()
+		return
+	}
+
+	o := &orphan{since: m.s.Clock.Now()}
+	if m.grace > 0 {
+		o.timer = time.AfterFunc(m.grace, func() { m.remove(j) })
+	}
+
+	m.jobs[j] = o
+	m.mu.Unlock()
+
+	since := new(expvar.Int)
+	since.Set(o.since.Unix())
+	OrphanedJobs.Set(j.GetName(), since)
+
+	m.s.Logger.Warningf("Job %q orphaned, container %s is gone", j.GetName(), container)
+	m.s.Events.Publish(&JobOrphaned{Job: j, Container: container, Grace: m.grace})
+}
+
+func (m *OrphanMonitor) forget(j Job) {
+	m.mu.Lock()
+	o, ok := m.jobs[j]
+	delete(m.jobs, j)
+	m.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+
+	OrphanedJobs.Delete(j.GetName())
+}
+
+// remove removes the job from the scheduler, at the end of its grace period
+func (m *OrphanMonitor) remove(j Job) {
+	if err := m.s.RemoveJob(j); err != nil {
+		m.s.Logger.Errorf("Unable to remove the orphaned job %q: %s", j.GetName(), err)
+		return
+	}
+
+	m.s.Logger.Noticef("Orphaned job %q removed after %s", j.GetName(), m.grace)
+	m.s.Record(NewAuditEntry(auditActorOrphans, AuditRemove, j, nil))
+}
+
+// OrphanedSince returns when the given job was found orphaned by the Orphans
+// of the scheduler, zero if it isn't or if they aren't watched
+func (s *Scheduler) OrphanedSince(j Job) time.Time {
+	if s.Orphans == nil {
+		return time.Time{}
+	}
+
+	return s.Orphans.Since(j)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteOrphan struct{}
+
+var _ = Suite(&SuiteOrphan{})
+
+func (s *SuiteOrphan) TestOrphaned(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	m := WatchOrphans(sc, 0)
+	defer m.Stop()
+	c.Assert(sc.Orphans, Equals, m)
+
+	orphaned := make(chan *JobOrphaned, 2)
+	sc.Events.Subscribe(func(e Event) {
+		if e, ok := e.(*JobOrphaned); ok {
+			orphaned <- e
+		}
+	})
+
+	job := &TestJob{}
+	job.Name = "orphan-foo"
+	sc.AddTriggeredJob(job)
+
+	gone := func() *Execution {
+		e := NewExecution()
+		e.Error = &ContainerGoneError{Container: "bar"}
+		return e
+	}
+
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: gone()})
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: gone()})
+
+	e := <-orphaned
+	c.Assert(e.Container, Equals, "bar")
+	c.Assert(orphaned, HasLen, 0)
+	c.Assert(sc.OrphanedSince(job).IsZero(), Equals, false)
+	c.Assert(sc.State().Jobs[0].Orphaned, Equals, sc.OrphanedSince(job))
+	c.Assert(OrphanedJobs.Get("orphan-foo"), NotNil)
+
+	// any other result reached the container
+	failed := NewExecution()
+	failed.Error = errors.New("foo")
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: failed})
+	c.Assert(sc.OrphanedSince(job).IsZero(), Equals, true)
+	c.Assert(OrphanedJobs.Get("orphan-foo"), IsNil)
+}
+
+func (s *SuiteOrphan) TestRemoveAfterGrace(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	m := WatchOrphans(sc, 50*time.Millisecond)
+	defer m.Stop()
+
+	job := &TestJob{}
+	job.Name = "orphan-bar"
+	sc.AddTriggeredJob(job)
+
+	e := NewExecution()
+	e.Error = &ContainerGoneError{Container: "bar"}
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: e})
+	c.Assert(sc.GetJob("orphan-bar"), NotNil)
+
+	time.Sleep(200 * time.Millisecond)
+	c.Assert(sc.GetJob("orphan-bar"), IsNil)
+	c.Assert(sc.OrphanedSince(job).IsZero(), Equals, true)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxOutputDiffLines changed lines kept in the output diff of an
+	// execution, the others are counted
+	maxOutputDiffLines = 100
+	// maxDiffedLines lines compared line by line, the larger changes are
+	// reported as the old lines removed and the new ones added
+	maxDiffedLines = 1000
+)
+
+// JobOutputDiff returns true if the executions of the given job are reported
+// with the changes of their output since the last successful execution
+func JobOutputDiff(j Job) bool {
+	if d, ok := j.(interface{ GetOutputDiff() bool }); ok {
+		return d.GetOutputDiff()
+	}
+
+	return false
+}
+
+// diffOutput sets the output diff of the execution against the output of the
+// last successful execution of the job, after being redacted, keeping the
+// output if successful
+func (c *Context) diffOutput(successful bool) {
+	if c.Scheduler == nil || !JobOutputDiff(c.Job) {
+		return
+	}
+
+	key := c.Job.GetName() + "\x00" + c.Execution.Params[MatrixParam]
+	output := c.Execution.OutputStream.String()
+
+	c.Scheduler.diffsMu.Lock()
+	defer c.Scheduler.diffsMu.Unlock()
+
+	if last, ok := c.Scheduler.diffs[key]; ok {
+		c.Execution.OutputDiff = diffLines(last, output)
+	}
+
+	if successful {
+		c.Scheduler.diffs[key] = output
+	}
+}
+
+// diffLines returns the lines removed from a, prefixed by "-", and added to
+// b, prefixed by "+", in order, empty if a and b are equal
+func diffLines(a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	before, after := splitLines(a), splitLines(b)
+
+	// the common prefix and suffix are not compared
+	var prefix int
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+
+	before, after = before[prefix:], after[prefix:]
+	var suffix int
+	for suffix < len(before) && suffix < len(after) && before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	before, after = before[:len(before)-suffix], after[:len(after)-suffix]
+
+	var changes []string
+	if len(before) > maxDiffedLines || len(after) > maxDiffedLines {
+		for _, l := range before {
+			changes = append(changes, "-"+l)
+		}
+
+		for _, l := range after {
+			changes = append(changes, "+"+l)
+		}
+	} else {
+		changes = lcsDiff(before, after)
+	}
+
+	if len(changes) > maxOutputDiffLines {
+		more := len(changes) - maxOutputDiffLines
+		changes = append(changes[:maxOutputDiffLines], fmt.Sprintf("... %d more changed lines", more))
+	}
+
+	return strings.Join(changes, "\n")
+}
+
+// lcsDiff returns the changes between the given lines, keeping their longest
+// common subsequence
+func lcsDiff(before, after []string) []string {
+	// lcs[i][j] length of the longest common subsequence of before[i:] and
+	// after[j:]
+	lcs := make([][]int32, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(after)+1)
+	}
+
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			switch {
+			case before[i] == after[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var changes []string
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			i++
+			j++
+		case j == len(after) || (i < len(before) && lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, "-"+before[i])
+			i++
+		default:
+			changes = append(changes, "+"+after[j])
+			j++
+		}
+	}
+
+	return changes
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteOutputDiff struct{}
+
+var _ = Suite(&SuiteOutputDiff{})
+
+func (s *SuiteOutputDiff) TestDiffLines(c *C) {
+	c.Assert(diffLines("a\nb\n", "a\nb\n"), Equals, "")
+	c.Assert(diffLines("a\nb\nc\n", "a\nc\nd\n"), Equals, "-b\n+d")
+	c.Assert(diffLines("", "a\n"), Equals, "+a")
+	c.Assert(diffLines("a\nb\n", ""), Equals, "-a\n-b")
+	c.Assert(diffLines(
+		"example.com 30 days\nexample.org 12 days\nexample.net 90 days\n",
+		"example.com 29 days\nexample.org 11 days\nexample.net 90 days\n",
+	), Equals, "-example.com 30 days\n-example.org 12 days\n+example.com 29 days\n+example.org 11 days")
+}
+
+func (s *SuiteOutputDiff) TestDiffLinesBounded(c *C) {
+	var before, after []string
+	for i := 0; i < maxDiffedLines+1; i++ {
+		before = append(before, fmt.Sprintf("a%d", i))
+		after = append(after, fmt.Sprintf("b%d", i))
+	}
+
+	diff := strings.Split(diffLines(strings.Join(before, "\n"), strings.Join(after, "\n")), "\n")
+	c.Assert(diff, HasLen, maxOutputDiffLines+1)
+	c.Assert(diff[0], Equals, "-a0")
+	c.Assert(diff[maxOutputDiffLines], Equals, fmt.Sprintf("... %d more changed lines", 2*(maxDiffedLines+1)-maxOutputDiffLines))
+}
+
+func (s *SuiteOutputDiff) TestContextNextOutputDiff(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	job := &LocalJob{}
+	job.Name = "certs"
+	job.OutputDiff = true
+	sc.AddTriggeredJob(job)
+
+	run := func(command string) *Execution {
+		job.Command = command
+		ctx := NewContext(sc, job, NewExecution())
+		ctx.Start()
+		c.Assert(ctx.Next(), IsNil)
+		return ctx.Execution
+	}
+
+	c.Assert(run("echo foo").OutputDiff, Equals, "")
+	c.Assert(run("echo foo").OutputDiff, Equals, "")
+	c.Assert(run("echo bar").OutputDiff, Equals, "-foo\n+bar")
+
+	// compared to the last successful execution
+	e := run("sh -c 'echo baz; false'")
+	c.Assert(e.Failed, Equals, true)
+	c.Assert(e.OutputDiff, Equals, "-bar\n+baz")
+	c.Assert(run("echo bar").OutputDiff, Equals, "")
+}
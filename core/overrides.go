@@ -0,0 +1,184 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrRunNotFound the one-time run to cancel doesn't exist or already ran
+var ErrRunNotFound = errors.New("unable to find the one-time run")
+
+// OneTimeRun is an extra execution of a job at the given time, besides its
+// schedule, see ScheduleRun
+type OneTimeRun struct {
+	ID  string
+	Job string
+	// Actor who scheduled the run, e.g. "api:socket"
+	Actor string
+	At    time.Time
+
+	timer Timer
+}
+
+// SkipJob skips the next n scheduled occurrences of the job with the given
+// name, replacing the previous count, zero resumes the schedule. The action
+// of the given actor is recorded in the audit log.
+func (s *Scheduler) SkipJob(actor, name string, n int) error {
+	if n < 0 {
+		return fmt.Errorf("invalid number of occurrences to skip %d", n)
+	}
+
+	if s.GetJob(name) == nil {
+		return ErrJobNotFound
+	}
+
+	s.overridesMu.Lock()
+	before := s.skips[name]
+	if n == 0 {
+		delete(s.skips, name)
+	} else {
+		s.skips[name] = n
+	}
+
+	s.overridesMu.Unlock()
+
+	e := &AuditEntry{Actor: actor, Action: AuditSkip, Job: name}
+	e.addChange("skip", AuditChange{Before: before, After: n})
+	s.Record(e)
+
+	if n == 0 {
+		s.Logger.Noticef("Job %q resumes its schedule, set by %s", name, actor)
+		return nil
+	}
+
+	s.Logger.Noticef("Job %q skips its next %d occurrences, set by %s", name, n, actor)
+	return nil
+}
+
+// Skips returns the number of scheduled occurrences of the given job left to
+// skip
+func (s *Scheduler) Skips(j Job) int {
+	s.overridesMu.Lock()
+	defer s.overridesMu.Unlock()
+
+	return s.skips[j.GetName()]
+}
+
+// takeSkip returns true if the occurrence of the job scheduled at the given
+// time is skipped, counting it
+func (s *Scheduler) takeSkip(j Job, scheduled time.Time) bool {
+	s.overridesMu.Lock()
+	name := j.GetName()
+	left, ok := s.skips[name]
+	if ok {
+		left--
+		if left == 0 {
+			delete(s.skips, name)
+		} else {
+			s.skips[name] = left
+		}
+	}
+
+	s.overridesMu.Unlock()
+
+	if ok {
+		s.Logger.Noticef("Job %q occurrence at %s skipped, %d left to skip", name, scheduled.Format(time.RFC3339), left)
+	}
+
+	return ok
+}
+
+// ScheduleRun runs the job with the given name once at the given time, besides
+// its schedule. The action of the given actor is recorded in the audit log.
+func (s *Scheduler) ScheduleRun(actor, name string, at time.Time) (*OneTimeRun, error) {
+	if s.GetJob(name) == nil {
+		return nil, ErrJobNotFound
+	}
+
+	wait := at.Sub(s.Clock.Now())
+	if wait <= 0 {
+		return nil, fmt.Errorf("invalid time %s, must be in the future", at.Format(time.RFC3339))
+	}
+
+	r := &OneTimeRun{ID: randomID(), Job: name, Actor: actor, At: at}
+
+	s.overridesMu.Lock()
+	s.runs[r.ID] = r
+	r.timer = AfterFunc(s.Clock, wait, func() { s.fireRun(r) })
+	s.overridesMu.Unlock()
+
+	e := &AuditEntry{Actor: actor, Action: AuditRunOnce, Job: name}
+	e.addChange("at", AuditChange{After: at})
+	s.Record(e)
+
+	s.Logger.Noticef("Job %q runs once at %s, scheduled by %s", name, at.Format(time.RFC3339), actor)
+	return r, nil
+}
+
+// CancelRun cancels the one-time run with the given ID before it runs,
+// recording the action of the given actor in the audit log
+func (s *Scheduler) CancelRun(actor, id string) error {
+	s.overridesMu.Lock()
+	r, ok := s.runs[id]
+	if ok {
+		r.timer.Stop()
+		delete(s.runs, id)
+	}
+
+	s.overridesMu.Unlock()
+
+	if !ok {
+		return ErrRunNotFound
+	}
+
+	e := &AuditEntry{Actor: actor, Action: AuditCancelRun, Job: r.Job}
+	e.addChange("at", AuditChange{Before: r.At})
+	s.Record(e)
+
+	s.Logger.Noticef("Job %q run at %s cancelled by %s", r.Job, r.At.Format(time.RFC3339), actor)
+	return nil
+}
+
+// OneTimeRuns returns the one-time runs not run yet, by time
+func (s *Scheduler) OneTimeRuns() []OneTimeRun {
+	s.overridesMu.Lock()
+	defer s.overridesMu.Unlock()
+
+	runs := make([]OneTimeRun, 0, len(s.runs))
+	for _, r := range s.runs {
+		runs = append(runs, OneTimeRun{ID: r.ID, Job: r.Job, Actor: r.Actor, At: r.At})
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].At.Equal(runs[j].At) {
+			return runs[i].ID < runs[j].ID
+		}
+
+		return runs[i].At.Before(runs[j].At)
+	})
+
+	return runs
+}
+
+// fireRun runs the job of the one-time run, if still registered and the
+// scheduler is running
+func (s *Scheduler) fireRun(r *OneTimeRun) {
+	s.overridesMu.Lock()
+	_, ok := s.runs[r.ID]
+	delete(s.runs, r.ID)
+	s.overridesMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	j := s.GetJob(r.Job)
+	if j == nil || !s.IsRunning() {
+		s.Logger.Warningf("Discarding the one-time run of job %q at %s, the job isn't scheduled", r.Job, r.At.Format(time.RFC3339))
+		return
+	}
+
+	(&jobWrapper{s, j}).runWith(r.At, nil)
+}
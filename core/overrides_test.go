@@ -0,0 +1,133 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteOverrides struct{}
+
+var _ = Suite(&SuiteOverrides{})
+
+type scheduledJob struct {
+	BareJob
+	scheduled chan time.Time
+}
+
+func (j *scheduledJob) Run(ctx *Context) error {
+	j.scheduled <- ctx.Execution.ScheduledAt
+	return nil
+}
+
+func (s *SuiteOverrides) TestSkipJob(c *C) {
+	audit := &memoryAuditLog{}
+	sc := NewScheduler(&TestLogger{})
+	sc.Audit = audit
+
+	job := &scheduledJob{scheduled: make(chan time.Time, 10)}
+	job.Name = "foo"
+	job.Schedule = "@hourly"
+	c.Assert(sc.AddJob(job), IsNil)
+
+	c.Assert(sc.SkipJob("socket", "bar", 2), Equals, ErrJobNotFound)
+	c.Assert(sc.SkipJob("socket", "foo", -1), ErrorMatches, "invalid number .*")
+	c.Assert(sc.SkipJob("socket", "foo", 2), IsNil)
+	c.Assert(sc.Skips(job), Equals, 2)
+
+	w := &jobWrapper{sc, job}
+	w.Run()
+	w.Run()
+	c.Assert(job.scheduled, HasLen, 0)
+	c.Assert(sc.Skips(job), Equals, 0)
+
+	w.Run()
+	c.Assert(job.scheduled, HasLen, 1)
+
+	c.Assert(sc.SkipJob("socket", "foo", 1), IsNil)
+	c.Assert(sc.SkipJob("socket", "foo", 0), IsNil)
+	w.Run()
+	c.Assert(job.scheduled, HasLen, 2)
+
+	c.Assert(audit.entries, HasLen, 3)
+	c.Assert(audit.entries[0].Action, Equals, AuditSkip)
+	c.Assert(audit.entries[0].Job, Equals, "foo")
+	c.Assert(audit.entries[2].Diff["skip"], DeepEquals, AuditChange{Before: 1, After: 0})
+}
+
+func (s *SuiteOverrides) TestScheduleRun(c *C) {
+	audit := &memoryAuditLog{}
+	sc := NewScheduler(&TestLogger{})
+	sc.Audit = audit
+
+	job := &scheduledJob{scheduled: make(chan time.Time, 10)}
+	job.Name = "foo"
+	job.Schedule = "@yearly"
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(sc.Start(), IsNil)
+	defer sc.Stop()
+
+	now := time.Now()
+	_, err := sc.ScheduleRun("socket", "bar", now.Add(time.Hour))
+	c.Assert(err, Equals, ErrJobNotFound)
+
+	_, err = sc.ScheduleRun("socket", "foo", now.Add(-time.Hour))
+	c.Assert(err, ErrorMatches, "invalid time .*, must be in the future")
+
+	later, err := sc.ScheduleRun("socket", "foo", now.Add(time.Hour))
+	c.Assert(err, IsNil)
+
+	soon, err := sc.ScheduleRun("socket", "foo", now.Add(50*time.Millisecond))
+	c.Assert(err, IsNil)
+
+	runs := sc.OneTimeRuns()
+	c.Assert(runs, HasLen, 2)
+	c.Assert(runs[0].ID, Equals, soon.ID)
+	c.Assert(runs[1].Actor, Equals, "socket")
+
+	select {
+	case scheduled := <-job.scheduled:
+		c.Assert(scheduled.Equal(soon.At), Equals, true)
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	c.Assert(sc.CancelRun("socket", soon.ID), Equals, ErrRunNotFound)
+	c.Assert(sc.CancelRun("socket", later.ID), IsNil)
+	c.Assert(sc.OneTimeRuns(), HasLen, 0)
+
+	c.Assert(audit.entries, HasLen, 3)
+	c.Assert(audit.entries[0].Action, Equals, AuditRunOnce)
+	c.Assert(audit.entries[2].Action, Equals, AuditCancelRun)
+	c.Assert(audit.entries[2].Job, Equals, "foo")
+}
+
+func (s *SuiteOverrides) TestScheduleRunClock(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewSimulatedClock(now)
+	sc := NewScheduler(&TestLogger{}, WithClock(clock))
+
+	job := &scheduledJob{scheduled: make(chan time.Time, 10)}
+	job.Name = "foo"
+	job.Schedule = "@yearly"
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(sc.Start(), IsNil)
+	defer sc.Stop()
+
+	later, err := sc.ScheduleRun("socket", "foo", now.Add(2*time.Hour))
+	c.Assert(err, IsNil)
+	soon, err := sc.ScheduleRun("socket", "foo", now.Add(time.Hour))
+	c.Assert(err, IsNil)
+	canceled, err := sc.ScheduleRun("socket", "foo", now.Add(90*time.Minute))
+	c.Assert(err, IsNil)
+	c.Assert(sc.CancelRun("socket", canceled.ID), IsNil)
+
+	clock.Set(now.Add(59 * time.Minute))
+	c.Assert(job.scheduled, HasLen, 0)
+
+	clock.Set(now.Add(3 * time.Hour))
+	c.Assert(job.scheduled, HasLen, 2)
+	c.Assert((<-job.scheduled).Equal(soon.At), Equals, true)
+	c.Assert((<-job.scheduled).Equal(later.At), Equals, true)
+	c.Assert(sc.OneTimeRuns(), HasLen, 0)
+}
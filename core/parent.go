@@ -0,0 +1,41 @@
+package core
+
+import "strconv"
+
+// Params of the executions of the on-success and on-failure jobs, describing
+// the execution triggering them, the parent
+const (
+	ParentParam         = "parent"
+	ParentExitCodeParam = "parent_exit_code"
+	ParentErrorParam    = "parent_error"
+	ParentOutputParam   = "parent_output"
+)
+
+// maxParentOutput the size of the end of the output of the parent execution
+// given to its handler job
+const maxParentOutput = 4 * 1024
+
+// ParentParams returns the params describing the finished execution of the
+// context to the job handling it: the name of the job, the exit code of its
+// command, its error and the end of its output, redacted
+func ParentParams(ctx *Context) map[string]string {
+	params := map[string]string{
+		ParentParam:         ctx.Job.GetName(),
+		ParentExitCodeParam: strconv.Itoa(ctx.Execution.ExitCode),
+		ParentOutputParam:   ctx.Redact(tail(ctx.Execution.OutputStream.String(), maxParentOutput)),
+	}
+
+	if ctx.Execution.Error != nil {
+		params[ParentErrorParam] = ctx.Redact(ctx.Execution.Error.Error())
+	}
+
+	return params
+}
+
+func tail(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	return s[len(s)-max:]
+}
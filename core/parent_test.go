@@ -0,0 +1,29 @@
+package core
+
+import (
+	"errors"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteParent struct{}
+
+var _ = Suite(&SuiteParent{})
+
+func (s *SuiteParent) TestParentParams(c *C) {
+	j := &TestJob{}
+	j.Name = "dump"
+
+	ctx := NewContext(NewScheduler(&TestLogger{}), j, NewExecution())
+	ctx.Execution.OutputStream.Write([]byte(strings.Repeat("a", maxParentOutput) + "end"))
+	ctx.Execution.ExitCode = 2
+	ctx.Execution.Error = errors.New("error non-zero exit code: 2")
+
+	params := ParentParams(ctx)
+	c.Assert(params[ParentParam], Equals, "dump")
+	c.Assert(params[ParentExitCodeParam], Equals, "2")
+	c.Assert(params[ParentErrorParam], Equals, "error non-zero exit code: 2")
+	c.Assert(params[ParentOutputParam], HasLen, maxParentOutput)
+	c.Assert(strings.HasSuffix(params[ParentOutputParam], "end"), Equals, true)
+}
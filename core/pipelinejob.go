@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// PipelineJob runs a list of jobs, one after the other, stopping at the first
+// failed step unless the step is allowed to fail.
+type PipelineJob struct {
+	BareJob         `mapstructure:",squash"`
+	Client          *docker.Client `json:"-"`
+	Steps           []string
+	ContinueOnError []string `gcfg:"continue-on-error" mapstructure:"continue-on-error"`
+	// Workspace path where a volume, shared by all the job-run steps and
+	// created for every execution, is mounted
+	Workspace string
+	// WorkspaceTmpfs when true, the workspace is kept in memory
+	WorkspaceTmpfs bool `gcfg:"workspace-tmpfs" mapstructure:"workspace-tmpfs"`
+
+	jobs []Job
+}
+
+func NewPipelineJob(c *docker.Client, opts ...JobOption) *PipelineJob {
+	j := &PipelineJob{Client: c}
+	j.apply(opts)
+	return j
+}
+
+// SetStepJobs sets the jobs to be run as steps, in the same order as Steps.
+func (j *PipelineJob) SetStepJobs(jobs ...Job) {
+	j.jobs = jobs
+}
+
+// StepJobs returns the jobs run as steps.
+func (j *PipelineJob) StepJobs() []Job {
+	return j.jobs
+}
+
+func (j *PipelineJob) GetCommand() string {
+	return strings.Join(j.Steps, " -> ")
+}
+
+func (j *PipelineJob) Run(ctx *Context) error {
+	if j.Workspace != "" {
+		remove, err := mountWorkspace(j.Client, ctx, j.Workspace, j.WorkspaceTmpfs)
+		if err != nil {
+			return err
+		}
+
+		defer remove()
+	}
+
+	var failed []string
+	for _, step := range j.jobs {
+		e := j.runStep(ctx, step)
+		if !e.Failed {
+			continue
+		}
+
+		if !j.canFail(step.GetName()) {
+			return fmt.Errorf("step %q failed: %s", step.GetName(), e.Error)
+		}
+
+		failed = append(failed, step.GetName())
+	}
+
+	if len(failed) != 0 {
+		ctx.Warn("Finished ignoring the failed steps: " + strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// runStep runs the given step through its middlewares, with the params of the
+// execution of the pipeline, the step holding no slot of its own
+func (j *PipelineJob) runStep(ctx *Context, step Job) *Execution {
+	e := NewExecution()
+	e.Params = ctx.Execution.Params
+	sctx := &Context{
+		Scheduler:   ctx.Scheduler,
+		Logger:      ctx.Logger,
+		Job:         step,
+		Execution:   e,
+		Volumes:     ctx.Volumes,
+		middlewares: step.Middlewares(),
+		step:        true,
+	}
+
+	sctx.Start()
+	sctx.Next()
+	sctx.Stop(nil)
+
+	j.report(ctx, step, e)
+	return e
+}
+
+// report appends the output of the step to the pipeline execution
+func (j *PipelineJob) report(ctx *Context, step Job, e *Execution) {
+	status := "successful"
+	switch {
+	case e.Failed:
+		status = "failed: " + e.Error.Error()
+	case e.Skipped:
+		status = "skipped"
+	}
+
+	msg := fmt.Sprintf("Step %q finished in %q, %s", step.GetName(), e.Duration, status)
+	ctx.Log(msg)
+
+	fmt.Fprintf(ctx.Execution.OutputStream, "==> %s\n", msg)
+	ctx.Execution.OutputStream.Write(e.OutputStream.Bytes())
+
+	if e.ErrorStream.TotalWritten() > 0 {
+		fmt.Fprintf(ctx.Execution.ErrorStream, "==> Step %q\n", step.GetName())
+		ctx.Execution.ErrorStream.Write(e.ErrorStream.Bytes())
+	}
+}
+
+func (j *PipelineJob) canFail(step string) bool {
+	for _, name := range j.ContinueOnError {
+		if name == step {
+			return true
+		}
+	}
+
+	return false
+}
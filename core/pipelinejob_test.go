@@ -0,0 +1,99 @@
+package core
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuitePipelineJob struct{}
+
+var _ = Suite(&SuitePipelineJob{})
+
+func (s *SuitePipelineJob) newStep(name, command string) *LocalJob {
+	j := &LocalJob{}
+	j.Name = name
+	j.Command = command
+	return j
+}
+
+func (s *SuitePipelineJob) newContext(j Job) *Context {
+	sh := NewScheduler(&TestLogger{})
+	return NewContext(sh, j, NewExecution())
+}
+
+func (s *SuitePipelineJob) TestRun(c *C) {
+	job := &PipelineJob{}
+	job.Steps = []string{"foo", "bar"}
+	job.SetStepJobs(s.newStep("foo", "echo foo"), s.newStep("bar", "echo bar"))
+
+	ctx := s.newContext(job)
+	c.Assert(job.Run(ctx), IsNil)
+
+	out := ctx.Execution.OutputStream.String()
+	c.Assert(strings.Contains(out, "foo\n"), Equals, true)
+	c.Assert(strings.Index(out, "foo\n") < strings.Index(out, "bar\n"), Equals, true)
+	c.Assert(job.GetCommand(), Equals, "foo -> bar")
+}
+
+func (s *SuitePipelineJob) TestRunStepFailed(c *C) {
+	last := s.newStep("bar", "echo bar")
+
+	job := &PipelineJob{}
+	job.SetStepJobs(s.newStep("foo", "false"), last)
+
+	ctx := s.newContext(job)
+	err := job.Run(ctx)
+	c.Assert(err, NotNil)
+	c.Assert(strings.Contains(err.Error(), `step "foo" failed`), Equals, true)
+	c.Assert(strings.Contains(ctx.Execution.OutputStream.String(), "bar\n"), Equals, false)
+}
+
+func (s *SuitePipelineJob) TestRunContinueOnError(c *C) {
+	job := &PipelineJob{ContinueOnError: []string{"foo"}}
+	job.SetStepJobs(s.newStep("foo", "false"), s.newStep("bar", "echo bar"))
+
+	ctx := s.newContext(job)
+	c.Assert(job.Run(ctx), IsNil)
+	c.Assert(strings.Contains(ctx.Execution.OutputStream.String(), "bar\n"), Equals, true)
+}
+
+func (s *SuitePipelineJob) TestRunStepMiddlewares(c *C) {
+	m := &TestMiddleware{Nested: true}
+	foo := s.newStep("foo", "echo foo")
+	foo.Use(m)
+
+	skip := &TestMiddleware{Stop: ErrSkippedExecution}
+	bar := s.newStep("bar", "echo bar")
+	bar.Use(skip)
+
+	job := &PipelineJob{}
+	job.SetStepJobs(foo, bar, s.newStep("qux", "echo qux"))
+
+	ctx := s.newContext(job)
+	c.Assert(job.Run(ctx), IsNil)
+	c.Assert(m.Called, Equals, 1)
+	c.Assert(skip.Called, Equals, 1)
+
+	out := ctx.Execution.OutputStream.String()
+	c.Assert(strings.Contains(out, "foo\n"), Equals, true)
+	c.Assert(strings.Contains(out, "bar\n"), Equals, false)
+	c.Assert(strings.Contains(out, `"bar" finished`), Equals, true)
+	c.Assert(strings.Contains(out, "skipped"), Equals, true)
+	c.Assert(strings.Contains(out, "qux\n"), Equals, true)
+}
+
+func (s *SuitePipelineJob) TestRunStepNoop(c *C) {
+	foo := s.newStep("foo", "echo foo")
+	foo.Noop = true
+
+	job := &PipelineJob{}
+	job.SetStepJobs(foo, s.newStep("bar", "echo bar"))
+
+	ctx := s.newContext(job)
+	c.Assert(job.Run(ctx), IsNil)
+
+	out := ctx.Execution.OutputStream.String()
+	c.Assert(strings.Contains(out, "foo\n"), Equals, false)
+	c.Assert(strings.Contains(out, "bar\n"), Equals, true)
+}
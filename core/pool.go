@@ -0,0 +1,62 @@
+package core
+
+import "fmt"
+
+// ExecutionPool bounds the number of concurrent executions of the jobs by
+// kind, e.g. the jobs using docker, not to overwhelm the docker daemon with
+// hundreds of pulls and creations at once. The jobs of the kinds without limit
+// aren't bounded.
+type ExecutionPool struct {
+	kind  func(Job) string
+	slots map[string]chan struct{}
+}
+
+// NewExecutionPool returns a pool running at most the given number of
+// executions at once of every kind, the kind of a job is given by kind
+func NewExecutionPool(kind func(Job) string, limits map[string]int) *ExecutionPool {
+	p := &ExecutionPool{kind: kind, slots: make(map[string]chan struct{})}
+	for k, limit := range limits {
+		p.slots[k] = make(chan struct{}, limit)
+	}
+
+	return p
+}
+
+// Limit returns the number of concurrent executions of the given kind, zero if
+// unbounded
+func (p *ExecutionPool) Limit(kind string) int {
+	return cap(p.slots[kind])
+}
+
+// acquire takes a slot for the execution of the job, calling wait before
+// waiting for one if all are taken, returns the function releasing it
+func (p *ExecutionPool) acquire(j Job, wait func(kind string)) func() {
+	kind := p.kind(j)
+	slots, ok := p.slots[kind]
+	if !ok {
+		return func() {}
+	}
+
+	select {
+	case slots <- struct{}{}:
+	default:
+		wait(kind)
+		PendingExecutions.Add(1)
+		slots <- struct{}{}
+		PendingExecutions.Add(-1)
+	}
+
+	return func() { <-slots }
+}
+
+// acquirePool takes a slot of the pool of the scheduler, if any, for running
+// the command of the execution
+func (c *Context) acquirePool() func() {
+	if c.Scheduler == nil || c.Scheduler.Pool == nil {
+		return func() {}
+	}
+
+	return c.Scheduler.Pool.acquire(c.Job, func(kind string) {
+		c.Log(fmt.Sprintf("Waiting for a slot of the %s pool, %d running", kind, c.Scheduler.Pool.Limit(kind)))
+	})
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuitePool struct{}
+
+var _ = Suite(&SuitePool{})
+
+func (s *SuitePool) TestAcquire(c *C) {
+	p := NewExecutionPool(func(j Job) string { return j.GetName() }, map[string]int{"docker": 1})
+	c.Assert(p.Limit("docker"), Equals, 1)
+	c.Assert(p.Limit("local"), Equals, 0)
+
+	docker, local := &TestJob{}, &TestJob{}
+	docker.Name, local.Name = "docker", "local"
+
+	noWait := func(string) { c.Error("unexpected wait") }
+	release := p.acquire(docker, noWait)
+	p.acquire(local, noWait)()
+	p.acquire(local, noWait)()
+
+	waited := make(chan string, 1)
+	acquired := make(chan bool)
+	go func() {
+		p.acquire(docker, func(kind string) { waited <- kind })()
+		acquired <- true
+	}()
+
+	c.Assert(<-waited, Equals, "docker")
+	select {
+	case <-acquired:
+		c.Fatal("acquired a taken slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	c.Assert(<-acquired, Equals, true)
+}
+
+func (s *SuitePool) TestContextNextPool(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.Pool = NewExecutionPool(func(Job) string { return "test" }, map[string]int{"test": 1})
+
+	j := &TestJob{}
+	ctx := NewContext(sc, j, NewExecution())
+	ctx.Start()
+
+	c.Assert(ctx.Next(), IsNil)
+	c.Assert(j.Called, Equals, 1)
+	c.Assert(sc.Pool.slots["test"], HasLen, 0)
+}
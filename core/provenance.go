@@ -0,0 +1,87 @@
+package core
+
+import "strings"
+
+// Labels of the containers and the services created by the jobs, identifying
+// the container declaring the job with its labels
+const (
+	SourceContainerLabel = "ofelia.source.container"
+	SourceServiceLabel   = "ofelia.source.service"
+	SourceProjectLabel   = "ofelia.source.project"
+)
+
+// Provenance the container declaring a job with its docker labels, attached
+// to the containers created by the job, its metrics and its notifications, so
+// the resources used by the jobs can be attributed on hosts shared by teams
+type Provenance struct {
+	// Container name of the container, empty if the job is declared by all
+	// the replicas of a service
+	Container string
+	// Service the compose or swarm service of the container
+	Service string
+	// Project the compose project of the container
+	Project string
+}
+
+// JobProvenance returns the provenance of the given job, the zero value if it
+// isn't declared by the docker labels
+func JobProvenance(j Job) Provenance {
+	if p, ok := j.(interface{ GetProvenance() Provenance }); ok {
+		return p.GetProvenance()
+	}
+
+	return Provenance{}
+}
+
+// JobDeclaredByLabels returns true if the given job is declared by docker
+// labels, even by all the replicas of a service, so by any container able to
+// set labels, not by the operator of ofelia
+func JobDeclaredByLabels(j Job) bool {
+	l, ok := j.(interface{ GetDockerLabels() bool })
+	return ok && l.GetDockerLabels()
+}
+
+// IsZero returns true if the job isn't declared by the docker labels
+func (p Provenance) IsZero() bool {
+	return p == Provenance{}
+}
+
+// Labels returns the provenance as the labels of a container, the empty ones
+// omitted, nil for the zero value
+func (p Provenance) Labels() map[string]string {
+	if p.IsZero() {
+		return nil
+	}
+
+	labels := make(map[string]string)
+	for name, value := range map[string]string{
+		SourceContainerLabel: p.Container,
+		SourceServiceLabel:   p.Service,
+		SourceProjectLabel:   p.Project,
+	} {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+
+	return labels
+}
+
+// String returns the provenance as "container <name>, service <name>, project
+// <name>", the empty ones omitted
+func (p Provenance) String() string {
+	var parts []string
+	if p.Container != "" {
+		parts = append(parts, "container "+p.Container)
+	}
+
+	if p.Service != "" {
+		parts = append(parts, "service "+p.Service)
+	}
+
+	if p.Project != "" {
+		parts = append(parts, "project "+p.Project)
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuiteProvenance struct{}
+
+var _ = Suite(&SuiteProvenance{})
+
+func (s *SuiteProvenance) TestJobProvenance(c *C) {
+	j := &TestJob{}
+	c.Assert(JobProvenance(j).IsZero(), Equals, true)
+	c.Assert(JobProvenance(j).Labels(), IsNil)
+
+	j.SourceContainer = "shop_web_1"
+	j.SourceService = "web"
+	j.SourceProject = "shop"
+
+	p := JobProvenance(j)
+	c.Assert(p, DeepEquals, Provenance{Container: "shop_web_1", Service: "web", Project: "shop"})
+	c.Assert(p.String(), Equals, "container shop_web_1, service web, project shop")
+	c.Assert(p.Labels(), DeepEquals, map[string]string{
+		SourceContainerLabel: "shop_web_1",
+		SourceServiceLabel:   "web",
+		SourceProjectLabel:   "shop",
+	})
+
+	p.Container = ""
+	c.Assert(p.String(), Equals, "service web, project shop")
+	c.Assert(p.Labels(), DeepEquals, map[string]string{
+		SourceServiceLabel: "web",
+		SourceProjectLabel: "shop",
+	})
+}
+
+func (s *SuiteRunJob) TestBuildContainerProvenance(c *C) {
+	job := &RunJob{Client: s.client}
+	job.Image = ImageFixture
+	job.Name = "test"
+	job.SourceContainer = "shop_web_1"
+	job.SourceProject = "shop"
+
+	ctx := NewContext(NewScheduler(&TestLogger{}), job, NewExecution())
+	container, err := job.buildContainer(ctx, false, "")
+	c.Assert(err, IsNil)
+
+	container, err = s.client.InspectContainer(container.ID)
+	c.Assert(err, IsNil)
+	c.Assert(container.Config.Labels, DeepEquals, map[string]string{
+		SourceContainerLabel: "shop_web_1",
+		SourceProjectLabel:   "shop",
+	})
+}
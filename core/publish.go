@@ -0,0 +1,142 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// publishBuffer max number of events waiting to be published, the events
+// emitted while the buffer is full are dropped
+const publishBuffer = 1000
+
+// Types of the published events
+const (
+	EventJobRegistered     = "job-registered"
+	EventJobRemoved        = "job-removed"
+	EventJobStale          = "job-stale"
+	EventJobOrphaned       = "job-orphaned"
+	EventExecutionStarted  = "execution-started"
+	EventExecutionFinished = "execution-finished"
+	EventExecutionSkipped  = "execution-skipped"
+	EventExecutionOverdue  = "execution-overdue"
+	EventExecutionAnomaly  = "execution-anomaly"
+)
+
+// EventPublisher publishes the events to a message broker, e.g. a Kafka topic
+type EventPublisher interface {
+	Publish(payload []byte) error
+}
+
+// EventMessage is an event, as published in JSON
+type EventMessage struct {
+	Type string
+	Date time.Time
+	// Host running the scheduler
+	Host      string
+	Job       string
+	Execution *ExecutionMessage `json:",omitempty"`
+}
+
+// ExecutionMessage is the execution of an EventMessage
+type ExecutionMessage struct {
+	ID       string
+	Date     time.Time
+	Duration time.Duration
+	Failed   bool
+	Skipped  bool
+	Error    string            `json:",omitempty"`
+	Params   map[string]string `json:",omitempty"`
+	// URL of the page of the execution, if the scheduler has an external URL
+	URL string `json:",omitempty"`
+}
+
+// NewEventMessage returns the message of the given event, the errors redacted
+// by the given redactor
+func NewEventMessage(host string, date time.Time, e Event, r *Redactor) *EventMessage {
+	m := &EventMessage{Date: date, Host: host, Job: e.GetJob().GetName()}
+
+	var exec *Execution
+	switch e := e.(type) {
+	case *JobRegistered:
+		m.Type = EventJobRegistered
+	case *JobRemoved:
+		m.Type = EventJobRemoved
+	case *JobStale:
+		m.Type = EventJobStale
+	case *JobOrphaned:
+		m.Type = EventJobOrphaned
+	case *ExecutionStarted:
+		m.Type, exec = EventExecutionStarted, e.Execution
+	case *ExecutionFinished:
+		m.Type, exec = EventExecutionFinished, e.Execution
+	case *ExecutionSkipped:
+		m.Type, exec = EventExecutionSkipped, e.Execution
+	case *ExecutionOverdue:
+		m.Type, exec = EventExecutionOverdue, e.Execution
+	case *ExecutionAnomaly:
+		m.Type, exec = EventExecutionAnomaly, e.Execution
+	}
+
+	if exec == nil {
+		return m
+	}
+
+	m.Execution = &ExecutionMessage{ID: exec.ID, Date: exec.Date, Params: exec.Params}
+
+	// the running executions have no result yet
+	if m.Type == EventExecutionStarted || m.Type == EventExecutionOverdue {
+		return m
+	}
+
+	m.Execution.Duration = exec.Duration
+	m.Execution.Failed, m.Execution.Skipped = exec.Failed, exec.Skipped
+	if exec.Error != nil {
+		m.Execution.Error = r.Redact(exec.Error.Error())
+	}
+
+	return m
+}
+
+// PublishEvents publishes the events of the scheduler as JSON with the given
+// publisher, in background and in order, until the returned func is called.
+// The failures are logged.
+func PublishEvents(s *Scheduler, p EventPublisher) (stop func()) {
+	host, _ := os.Hostname()
+	payloads := make(chan []byte, publishBuffer)
+
+	unsubscribe := s.Events.Subscribe(func(e Event) {
+		m := NewEventMessage(host, s.Clock.Now(), e, s.Redactor)
+		if m.Execution != nil {
+			m.Execution.URL = s.executionURL(m.Execution.ID)
+		}
+
+		payload, err := json.Marshal(m)
+		if err != nil {
+			s.Logger.Errorf("Unable to encode the event of job %q: %s", e.GetJob().GetName(), err)
+			return
+		}
+
+		select {
+		case payloads <- payload:
+		default:
+			s.Logger.Errorf("Publish buffer full, event of job %q not published", e.GetJob().GetName())
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for payload := range payloads {
+			if err := p.Publish(payload); err != nil {
+				s.Logger.Errorf("Unable to publish event: %s", err)
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		close(payloads)
+		<-done
+	}
+}
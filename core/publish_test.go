@@ -0,0 +1,79 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuitePublish struct{}
+
+var _ = Suite(&SuitePublish{})
+
+type testPublisher struct {
+	mu       sync.Mutex
+	messages []*EventMessage
+}
+
+func (p *testPublisher) Publish(payload []byte) error {
+	m := &EventMessage{}
+	if err := json.Unmarshal(payload, m); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+func (s *SuitePublish) TestNewEventMessage(c *C) {
+	job := &TestJob{}
+	job.Name = "foo"
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewEventMessage("bar", date, &JobRegistered{Job: job}, NewRedactor())
+	c.Assert(m, DeepEquals, &EventMessage{
+		Type: EventJobRegistered, Date: date, Host: "bar", Job: "foo",
+	})
+
+	e := NewExecution()
+	e.Start()
+	m = NewEventMessage("bar", date, &ExecutionStarted{Job: job, Execution: e}, NewRedactor())
+	c.Assert(m.Type, Equals, EventExecutionStarted)
+	c.Assert(m.Execution.ID, Equals, e.ID)
+	c.Assert(m.Execution.Duration, Equals, time.Duration(0))
+}
+
+func (s *SuitePublish) TestPublishEvents(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.Redactor = NewRedactor()
+	sc.Redactor.AddSecret("s3cr3t")
+	sc.ExternalURL = "https://ofelia.example.com"
+
+	p := &testPublisher{}
+	stop := PublishEvents(sc, p)
+
+	job := &TestJob{}
+	job.Name = "foo"
+
+	e := NewExecution()
+	e.Start()
+	e.Stop(errors.New("invalid password s3cr3t"))
+	sc.Events.Publish(&ExecutionStarted{Job: job, Execution: e})
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: e})
+	stop()
+
+	c.Assert(p.messages, HasLen, 2)
+	c.Assert(p.messages[0].Type, Equals, EventExecutionStarted)
+	c.Assert(p.messages[1].Type, Equals, EventExecutionFinished)
+	c.Assert(p.messages[1].Job, Equals, "foo")
+	c.Assert(p.messages[1].Execution.ID, Equals, e.ID)
+	c.Assert(p.messages[1].Execution.Failed, Equals, true)
+	c.Assert(p.messages[1].Execution.Error, Equals, "invalid password *****")
+	c.Assert(p.messages[1].Execution.URL, Equals, "https://ofelia.example.com/api/executions/"+e.ID)
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// pullProgressInterval minimum time between two progress reports of a pull
+var pullProgressInterval = 5 * time.Second
+
+// pullImage pulls the image reporting the progress to the execution log, the
+// time spent is added to the execution. The timeout, if any, replaces the API
+// timeout for the pull. The pull is shared through the scheduler pull cache,
+// if any.
+func pullImage(ctx *Context, c *docker.Client, api DockerAPIConfig, image, timeout string) error {
+	if ctx.Scheduler == nil || ctx.Scheduler.PullCache == nil {
+		return doPullImage(ctx, c, api, image, timeout)
+	}
+
+	shared, err := ctx.Scheduler.PullCache.Pull(image, func() error {
+		return doPullImage(ctx, c, api, image, timeout)
+	})
+
+	if shared && err == nil {
+		ctx.Log("Image " + image + " pulled recently, skipping the pull")
+	}
+
+	return err
+}
+
+func doPullImage(ctx *Context, c *docker.Client, api DockerAPIConfig, image, timeout string) error {
+	if timeout != "" {
+		if _, err := parseDuration("pull-timeout", timeout, 0); err != nil {
+			return err
+		}
+
+		api.APITimeout = timeout
+	}
+
+	start := time.Now()
+	defer func() {
+		ctx.Execution.PullDuration += time.Since(start)
+	}()
+
+	return api.retry(ctx, "pulling image", func(tctx context.Context) error {
+		o, a := buildPullOptions(image)
+		o.Context = tctx
+		o.OutputStream = newPullProgress(image, ctx.Log)
+		o.RawJSONStream = true
+
+		return c.PullImage(o, a)
+	})
+}
+
+// pullProgress parses the JSON stream of a pull, logging periodically the
+// number of layers pulled and the percentage downloaded
+type pullProgress struct {
+	image  string
+	log    func(string)
+	buf    []byte
+	layers map[string]*layerProgress
+	last   time.Time
+}
+
+type layerProgress struct {
+	current, total int64
+	downloaded     bool
+	done           bool
+}
+
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+func newPullProgress(image string, log func(string)) *pullProgress {
+	return &pullProgress{
+		image:  image,
+		log:    log,
+		layers: make(map[string]*layerProgress),
+		last:   time.Now(),
+	}
+}
+
+func (p *pullProgress) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		p.parse(p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+
+	if len(p.layers) != 0 && time.Since(p.last) >= pullProgressInterval {
+		p.last = time.Now()
+		p.log(p.String())
+	}
+
+	return len(b), nil
+}
+
+func (p *pullProgress) parse(line []byte) {
+	var m pullMessage
+	if err := json.Unmarshal(line, &m); err != nil || m.ID == "" {
+		return
+	}
+
+	l := p.layers[m.ID]
+	switch m.Status {
+	case "Pulling fs layer", "Waiting":
+		if l == nil {
+			p.layers[m.ID] = &layerProgress{}
+		}
+	case "Downloading":
+		if l == nil {
+			l = &layerProgress{}
+			p.layers[m.ID] = l
+		}
+
+		l.current, l.total = m.ProgressDetail.Current, m.ProgressDetail.Total
+	case "Verifying Checksum", "Download complete", "Extracting":
+		if l != nil {
+			l.downloaded = true
+		}
+	case "Pull complete", "Already exists":
+		if l == nil {
+			l = &layerProgress{}
+			p.layers[m.ID] = l
+		}
+
+		l.downloaded, l.done = true, true
+	}
+}
+
+func (p *pullProgress) String() string {
+	var done int
+	var current, total int64
+	for _, l := range p.layers {
+		if l.done {
+			done++
+		}
+
+		if l.total == 0 {
+			continue
+		}
+
+		total += l.total
+		if l.downloaded {
+			current += l.total
+		} else {
+			current += l.current
+		}
+	}
+
+	msg := fmt.Sprintf("Pulling image %s: %d/%d layers", p.image, done, len(p.layers))
+	if total != 0 {
+		msg += fmt.Sprintf(", %d%% downloaded", current*100/total)
+	}
+
+	return msg
+}
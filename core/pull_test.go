@@ -0,0 +1,39 @@
+package core
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuitePull struct{}
+
+var _ = Suite(&SuitePull{})
+
+const pullStreamFixture = `{"status":"Pulling from library/foo","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"b"}
+{"status":"Already exists","progressDetail":{},"id":"c"}
+{"status":"Downloading","progressDetail":{"current":50,"total":100},"id":"a"}
+{"status":"Downloading","progressDetail":{"current":10,"total":300},"id":"b"}
+{"status":"Download complete","progressDetail":{},"id":"a"}
+{"status":"Pull complete","progressDetail":{},"id":"a"}
+{"status":"Downloading","progressDetail":{"current":100,"total":300},"id":"b"}
+`
+
+func (s *SuitePull) TestPullProgress(c *C) {
+	var logs []string
+	p := newPullProgress("foo", func(msg string) {
+		logs = append(logs, msg)
+	})
+
+	p.Write([]byte(pullStreamFixture[:100]))
+	p.Write([]byte(pullStreamFixture[100:]))
+	c.Assert(logs, HasLen, 0)
+	c.Assert(p.String(), Equals, "Pulling image foo: 2/3 layers, 50% downloaded")
+
+	old := pullProgressInterval
+	pullProgressInterval = 0
+	defer func() { pullProgressInterval = old }()
+
+	p.Write([]byte(`{"status":"Pull complete","progressDetail":{},"id":"b"}` + "\n"))
+	c.Assert(logs, DeepEquals, []string{"Pulling image foo: 3/3 layers, 100% downloaded"})
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// PullCache shares the image pulls between the jobs, an image is pulled at
+// most once within the window, and the concurrent pulls of the same image wait
+// for the running one, sharing its result.
+type PullCache struct {
+	Window time.Duration
+
+	mu     sync.Mutex
+	images map[string]*cachedPull
+}
+
+type cachedPull struct {
+	done chan struct{}
+	date time.Time
+	err  error
+}
+
+func NewPullCache(window time.Duration) *PullCache {
+	return &PullCache{
+		Window: window,
+		images: make(map[string]*cachedPull),
+	}
+}
+
+// Pull calls pull unless the image was pulled within the window or is being
+// pulled, returns true when the pull was shared.
+func (c *PullCache) Pull(image string, pull func() error) (bool, error) {
+	c.mu.Lock()
+	p, ok := c.images[image]
+	if ok && !c.isStale(p) {
+		c.mu.Unlock()
+
+		<-p.done
+		return true, p.err
+	}
+
+	p = &cachedPull{done: make(chan struct{})}
+	c.images[image] = p
+	c.mu.Unlock()
+
+	p.err = pull()
+	p.date = time.Now()
+	close(p.done)
+
+	return false, p.err
+}
+
+// isStale returns true if the pull finished, failing or outside the window
+func (c *PullCache) isStale(p *cachedPull) bool {
+	select {
+	case <-p.done:
+		return p.err != nil || time.Since(p.date) >= c.Window
+	default:
+		return false
+	}
+}
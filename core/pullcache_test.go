@@ -0,0 +1,71 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuitePullCache struct{}
+
+var _ = Suite(&SuitePullCache{})
+
+func (s *SuitePullCache) TestPullWindow(c *C) {
+	cache := NewPullCache(time.Hour)
+
+	var pulls int
+	pull := func() error {
+		pulls++
+		return nil
+	}
+
+	shared, err := cache.Pull("foo", pull)
+	c.Assert(err, IsNil)
+	c.Assert(shared, Equals, false)
+
+	shared, err = cache.Pull("foo", pull)
+	c.Assert(err, IsNil)
+	c.Assert(shared, Equals, true)
+
+	cache.Pull("bar", pull)
+	c.Assert(pulls, Equals, 2)
+
+	cache.Window = 0
+	cache.Pull("foo", pull)
+	c.Assert(pulls, Equals, 3)
+}
+
+func (s *SuitePullCache) TestPullFailed(c *C) {
+	cache := NewPullCache(time.Hour)
+
+	_, err := cache.Pull("foo", func() error { return errors.New("foo") })
+	c.Assert(err, NotNil)
+
+	shared, err := cache.Pull("foo", func() error { return nil })
+	c.Assert(err, IsNil)
+	c.Assert(shared, Equals, false)
+}
+
+func (s *SuitePullCache) TestPullConcurrent(c *C) {
+	cache := NewPullCache(time.Hour)
+
+	var pulls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Pull("foo", func() error {
+				atomic.AddInt32(&pulls, 1)
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+
+	wg.Wait()
+	c.Assert(pulls, Equals, int32(1))
+}
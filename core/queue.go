@@ -0,0 +1,112 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var queueBucket = []byte("queue")
+
+// ExecutionQueue persists the executions deferred by a job, so they survive
+// daemon restarts and can be resumed in order afterwards.
+type ExecutionQueue interface {
+	// Push appends a pending execution of the given job, returns its id.
+	Push(job string) (uint64, error)
+	// Remove deletes a pending execution from the queue.
+	Remove(id uint64) error
+	// Pending returns all the pending executions in the order they were pushed.
+	Pending() ([]*QueuedExecution, error)
+	Close() error
+}
+
+// QueuedExecution is a execution waiting in a ExecutionQueue.
+type QueuedExecution struct {
+	ID   uint64 `json:"-"`
+	Job  string
+	Date time.Time
+}
+
+// BoltQueue is a ExecutionQueue stored in a BoltDB file.
+type BoltQueue struct {
+	db *bolt.DB
+}
+
+// NewBoltQueue opens, or creates if missing, the given BoltDB file.
+func NewBoltQueue(filename string) (*BoltQueue, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(queueBucket)
+		return err
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltQueue{db: db}, nil
+}
+
+func (q *BoltQueue) Push(job string) (uint64, error) {
+	var id uint64
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(queueBucket)
+
+		var err error
+		id, err = b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		v, err := json.Marshal(&QueuedExecution{Job: job, Date: time.Now()})
+		if err != nil {
+			return err
+		}
+
+		return b.Put(queueKey(id), v)
+	})
+
+	return id, err
+}
+
+func (q *BoltQueue) Remove(id uint64) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).Delete(queueKey(id))
+	})
+}
+
+func (q *BoltQueue) Pending() ([]*QueuedExecution, error) {
+	var pending []*QueuedExecution
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).ForEach(func(k, v []byte) error {
+			e := &QueuedExecution{}
+			if err := json.Unmarshal(v, e); err != nil {
+				return err
+			}
+
+			e.ID = binary.BigEndian.Uint64(k)
+			pending = append(pending, e)
+			return nil
+		})
+	})
+
+	return pending, err
+}
+
+func (q *BoltQueue) Close() error {
+	return q.db.Close()
+}
+
+// keys are big endian, this way the bucket iterates them in insertion order
+func queueKey(id uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, id)
+	return k
+}
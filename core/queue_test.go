@@ -0,0 +1,80 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteBoltQueue struct {
+	dir string
+}
+
+var _ = Suite(&SuiteBoltQueue{})
+
+func (s *SuiteBoltQueue) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "queue")
+	c.Assert(err, IsNil)
+}
+
+func (s *SuiteBoltQueue) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *SuiteBoltQueue) TestPushRemove(c *C) {
+	filename := filepath.Join(s.dir, "queue.db")
+	q, err := NewBoltQueue(filename)
+	c.Assert(err, IsNil)
+
+	idA, err := q.Push("foo")
+	c.Assert(err, IsNil)
+	idB, err := q.Push("bar")
+	c.Assert(err, IsNil)
+	_, err = q.Push("foo")
+	c.Assert(err, IsNil)
+
+	c.Assert(q.Remove(idA), IsNil)
+	c.Assert(q.Close(), IsNil)
+
+	q, err = NewBoltQueue(filename)
+	c.Assert(err, IsNil)
+	defer q.Close()
+
+	pending, err := q.Pending()
+	c.Assert(err, IsNil)
+	c.Assert(pending, HasLen, 2)
+	c.Assert(pending[0].ID, Equals, idB)
+	c.Assert(pending[0].Job, Equals, "bar")
+	c.Assert(pending[1].Job, Equals, "foo")
+}
+
+func (s *SuiteBoltQueue) TestSchedulerResume(c *C) {
+	q, err := NewBoltQueue(filepath.Join(s.dir, "queue.db"))
+	c.Assert(err, IsNil)
+	defer q.Close()
+
+	_, err = q.Push("foo")
+	c.Assert(err, IsNil)
+	_, err = q.Push("qux")
+	c.Assert(err, IsNil)
+
+	job := &TestJob{}
+	job.Name = "foo"
+	job.Schedule = "@hourly"
+
+	sc := NewScheduler(&TestLogger{})
+	sc.Queue = q
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(sc.Start(), IsNil)
+	defer sc.Stop()
+
+	sc.wg.Wait()
+	c.Assert(job.Called, Equals, 1)
+
+	pending, err := q.Pending()
+	c.Assert(err, IsNil)
+	c.Assert(pending, HasLen, 0)
+}
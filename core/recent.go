@@ -0,0 +1,126 @@
+package core
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRecentExecutions number of executions kept by RecentExecutions
+const DefaultRecentExecutions = 100
+
+// ErrExecutionNotFound the execution doesn't exist or isn't kept anymore
+var ErrExecutionNotFound = errors.New("unable to find the execution")
+
+// RecentExecution is a finished execution with its full output, as shown by
+// the page linked from its notifications
+type RecentExecution struct {
+	Job      string
+	ID       string
+	Date     time.Time
+	Duration time.Duration
+	Failed   bool
+	Skipped  bool
+	Error    string            `json:",omitempty"`
+	ExitCode int               `json:",omitempty"`
+	Stdout   string            `json:",omitempty"`
+	Stderr   string            `json:",omitempty"`
+	Params   map[string]string `json:",omitempty"`
+	Messages []LogMessage
+}
+
+// RecentExecutions keeps in memory the last finished executions of the jobs,
+// the oldest ones are dropped once the max is reached
+type RecentExecutions struct {
+	max int
+
+	mu         sync.RWMutex
+	executions []*RecentExecution
+}
+
+// NewRecentExecutions returns a RecentExecutions keeping the given number of
+// executions, DefaultRecentExecutions if not positive
+func NewRecentExecutions(max int) *RecentExecutions {
+	if max <= 0 {
+		max = DefaultRecentExecutions
+	}
+
+	return &RecentExecutions{max: max}
+}
+
+// Add keeps the given finished execution of the job, its output already
+// redacted
+func (r *RecentExecutions) Add(j Job, e *Execution) {
+	rec := &RecentExecution{
+		Job:      j.GetName(),
+		ID:       e.ID,
+		Date:     e.Date,
+		Duration: e.Duration,
+		Failed:   e.Failed,
+		Skipped:  e.Skipped,
+		ExitCode: e.ExitCode,
+		Stdout:   e.OutputStream.String(),
+		Stderr:   e.ErrorStream.String(),
+		Params:   e.Params,
+		Messages: e.Messages(),
+	}
+
+	if e.Error != nil && e.Error != ErrSkippedExecution {
+		rec.Error = e.Error.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.executions = append(r.executions, rec)
+	if len(r.executions) > r.max {
+		r.executions = r.executions[len(r.executions)-r.max:]
+	}
+}
+
+// Get returns the execution with the given ID, nil if it isn't kept
+func (r *RecentExecutions) Get(id string) *RecentExecution {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.executions {
+		if e.ID == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// List returns the executions kept, newest first
+func (r *RecentExecutions) List() []*RecentExecution {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*RecentExecution, 0, len(r.executions))
+	for i := len(r.executions) - 1; i >= 0; i-- {
+		list = append(list, r.executions[i])
+	}
+
+	return list
+}
+
+// ExecutionURL returns the link to the page of the execution, on the external
+// URL of the scheduler, empty if it has none
+func (c *Context) ExecutionURL() string {
+	if c.Scheduler == nil {
+		return ""
+	}
+
+	return c.Scheduler.executionURL(c.Execution.ID)
+}
+
+func (s *Scheduler) executionURL(id string) string {
+	if s.ExternalURL == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(s.ExternalURL, "/") + "/api/executions/" + url.PathEscape(id)
+}
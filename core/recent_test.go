@@ -0,0 +1,46 @@
+package core
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteRecent struct{}
+
+var _ = Suite(&SuiteRecent{})
+
+func (s *SuiteRecent) TestRecentExecutions(c *C) {
+	job := &TestJob{}
+	job.Name = "foo"
+
+	r := NewRecentExecutions(2)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		e := NewExecution()
+		e.Start()
+		e.OutputStream.Write([]byte("foo"))
+		e.Stop(errors.New("bar"))
+		r.Add(job, e)
+		ids = append(ids, e.ID)
+	}
+
+	c.Assert(r.Get(ids[0]), IsNil)
+	c.Assert(r.Get("missing"), IsNil)
+
+	e := r.Get(ids[2])
+	c.Assert(e, NotNil)
+	c.Assert(e.Job, Equals, "foo")
+	c.Assert(e.Stdout, Equals, "foo")
+	c.Assert(e.Failed, Equals, true)
+	c.Assert(e.Error, Equals, "bar")
+}
+
+func (s *SuiteRecent) TestExecutionURL(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	ctx := NewContext(sc, &TestJob{}, NewExecution())
+	c.Assert(ctx.ExecutionURL(), Equals, "")
+
+	sc.ExternalURL = "https://ofelia.example.com/"
+	c.Assert(ctx.ExecutionURL(), Equals, "https://ofelia.example.com/api/executions/"+ctx.Execution.ID)
+}
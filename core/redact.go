@@ -0,0 +1,160 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// RedactedMask replaces the redacted values
+	RedactedMask = "*****"
+	// minSecretLength shorter secrets are not redacted, they would mask any
+	// output
+	minSecretLength = 4
+)
+
+// Redactor masks the secrets in the output of the executions, the values of
+// the secret options and the matches of user patterns
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns an empty Redactor
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// AddSecret adds a value to be masked, the values shorter than 4 characters
+// are ignored.
+func (r *Redactor) AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.secrets {
+		if s == secret {
+			return
+		}
+	}
+
+	r.secrets = append(r.secrets, secret)
+}
+
+// AddSecretsOf adds as secrets the string fields of the given struct tagged
+// with `secret:"true"`, including the fields of its embedded and nested
+// structs, the structs behind pointers are not followed.
+func (r *Redactor) AddSecretsOf(v interface{}) {
+	r.addSecretsOf(reflect.ValueOf(v))
+}
+
+func (r *Redactor) addSecretsOf(v reflect.Value) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		f, field := t.Field(i), v.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		if f.Type.Kind() == reflect.String && f.Tag.Get("secret") == "true" {
+			r.AddSecret(field.String())
+			continue
+		}
+
+		if f.Type.Kind() == reflect.Struct {
+			r.addSecretsOf(field)
+		}
+	}
+}
+
+// AddPattern adds a regular expression, its matches are masked, or only its
+// groups if it has any, e.g. `token=(\w+)` only masks the token.
+func (r *Redactor) AddPattern(expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.patterns = append(r.patterns, re)
+	return nil
+}
+
+// Redact returns the given text with the secrets masked, a nil Redactor
+// returns it as is.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, RedactedMask, -1)
+	}
+
+	for _, re := range r.patterns {
+		s = redactPattern(re, s)
+	}
+
+	return s
+}
+
+func redactPattern(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, RedactedMask)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		for g := 2; g < len(m); g += 2 {
+			if m[g] < 0 || m[g] < last {
+				continue
+			}
+
+			b.WriteString(s[last:m[g]])
+			b.WriteString(RedactedMask)
+			last = m[g+1]
+		}
+	}
+
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// RedactError returns the error with the secrets masked, the errors without
+// secrets are returned as they are.
+func (r *Redactor) RedactError(err error) error {
+	if r == nil || err == nil || err == ErrSkippedExecution {
+		return err
+	}
+
+	if msg := r.Redact(err.Error()); msg != err.Error() {
+		return errors.New(msg)
+	}
+
+	return err
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteRedact struct{}
+
+var _ = Suite(&SuiteRedact{})
+
+func (s *SuiteRedact) TestRedact(c *C) {
+	r := NewRedactor()
+	r.AddSecret("hunter2")
+	r.AddSecret("foo")
+	c.Assert(r.AddPattern(`token=(\w+)`), IsNil)
+	c.Assert(r.AddPattern(`\d{4}-\d{4}`), IsNil)
+	c.Assert(r.AddPattern(`(`), NotNil)
+
+	c.Assert(
+		r.Redact("password hunter2, token=abc and token=def, card 1234-5678, foo"),
+		Equals,
+		"password *****, token=***** and token=*****, card *****, foo",
+	)
+
+	var nilRedactor *Redactor
+	c.Assert(nilRedactor.Redact("hunter2"), Equals, "hunter2")
+}
+
+type secretConfig struct {
+	Password string `secret:"true"`
+	User     string
+}
+
+func (s *SuiteRedact) TestAddSecretsOf(c *C) {
+	r := NewRedactor()
+	r.AddSecretsOf(&struct {
+		secretConfig
+		Nested secretConfig
+		Ptr    *secretConfig
+	}{
+		secretConfig: secretConfig{Password: "p4ssw0rd", User: "admin"},
+		Nested:       secretConfig{Password: "s3cr3t"},
+		Ptr:          &secretConfig{Password: "ignored"},
+	})
+
+	c.Assert(r.Redact("admin p4ssw0rd s3cr3t ignored"), Equals, "admin ***** ***** ignored")
+}
+
+func (s *SuiteRedact) TestRedactError(c *C) {
+	r := NewRedactor()
+	r.AddSecret("hunter2")
+
+	err := errors.New("foo")
+	c.Assert(r.RedactError(err), Equals, err)
+	c.Assert(r.RedactError(ErrSkippedExecution), Equals, ErrSkippedExecution)
+	c.Assert(r.RedactError(errors.New("bad hunter2")), ErrorMatches, `bad \*\*\*\*\*`)
+}
+
+type leakingJob struct {
+	BareJob
+}
+
+func (j *leakingJob) Run(ctx *Context) error {
+	fmt.Fprint(ctx.Execution.OutputStream, "password is hunter2")
+	fmt.Fprint(ctx.Execution.ErrorStream, "token=abc")
+	return errors.New("failed with hunter2")
+}
+
+func (s *SuiteRedact) TestContextRedactOutput(c *C) {
+	sh := NewScheduler(&TestLogger{})
+	sh.Redactor = NewRedactor()
+	sh.Redactor.AddSecret("hunter2")
+	c.Assert(sh.Redactor.AddPattern(`token=(\w+)`), IsNil)
+
+	ctx := NewContext(sh, &leakingJob{}, NewExecution())
+	ctx.Start()
+	c.Assert(ctx.Next(), IsNil)
+
+	c.Assert(ctx.Execution.OutputStream.String(), Equals, "password is *****")
+	c.Assert(ctx.Execution.ErrorStream.String(), Equals, "token=*****")
+	c.Assert(ctx.Execution.Error, ErrorMatches, `failed with \*\*\*\*\*`)
+	c.Assert(ctx.Redact("hunter2"), Equals, RedactedMask)
+}
@@ -0,0 +1,38 @@
+package core
+
+import "time"
+
+// Reload is the result of a reload of the config of the scheduler, e.g. from
+// the docker labels, with the names of the jobs changed by it
+type Reload struct {
+	Date time.Time
+	// Error the reason of the failure of the reload, empty if it succeeded
+	Error   string   `json:",omitempty"`
+	Added   []string `json:",omitempty"`
+	Updated []string `json:",omitempty"`
+	Removed []string `json:",omitempty"`
+}
+
+// RecordReload records the given reload as the last one, dated now if it
+// isn't dated
+func (s *Scheduler) RecordReload(r *Reload) {
+	if r.Date.IsZero() {
+		r.Date = s.Clock.Now()
+	}
+
+	recordReloadMetrics(r)
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	s.lastReload = r
+}
+
+// LastReload returns the last reload of the config, nil if it was never
+// reloaded
+func (s *Scheduler) LastReload() *Reload {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	return s.lastReload
+}
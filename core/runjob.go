@@ -1,12 +1,13 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"strconv"
 	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
-	"github.com/gobs/args"
 )
 
 var dockercfg *docker.AuthConfigurations
@@ -28,71 +29,116 @@ type RunJob struct {
 	// so lets use strings here as workaround
 	Delete string `default:"true"`
 	Pull   string `default:"true"`
+	// PullTimeout timeout of the image pull, e.g. "10m"
+	PullTimeout string `gcfg:"pull-timeout" mapstructure:"pull-timeout"`
+	// WaitHealthy when set, e.g. "2m", the execution waits up to this time
+	// for the healthcheck of the container to report healthy, failing if it
+	// reports unhealthy
+	WaitHealthy string `gcfg:"wait-healthy" mapstructure:"wait-healthy"`
+	// Sidecar containers started before the container of the job and removed
+	// after it, declared as "<name>=<image> [<command>]"
+	Sidecar []string
+	// SidecarNetwork how the sidecars are reached, "shared" (default) sharing
+	// their network namespace with the job container or "network" connecting
+	// them to the network of the job under their names
+	SidecarNetwork string `gcfg:"sidecar-network" mapstructure:"sidecar-network"`
+	// Workspace when true, a volume created for every execution is mounted
+	// in the container of the job and its sidecars, and removed after them
+	Workspace bool
+	// WorkspacePath where the workspace is mounted, DefaultWorkspacePath by
+	// default
+	WorkspacePath string `gcfg:"workspace-path" mapstructure:"workspace-path"`
+	// WorkspaceTmpfs when true, the workspace is kept in memory
+	WorkspaceTmpfs bool `gcfg:"workspace-tmpfs" mapstructure:"workspace-tmpfs"`
+	// RestoreState when true, a container found running is stopped to be run
+	// by the job and started again after the run
+	RestoreState bool `gcfg:"restore-state" mapstructure:"restore-state"`
 
 	Image     string
 	Network   string
 	Container string
 	Volume    []string
-}
 
-func NewRunJob(c *docker.Client) *RunJob {
-	return &RunJob{Client: c}
+	CommandConfig   `mapstructure:",squash"`
+	EnvConfig       `mapstructure:",squash"`
+	DockerAPIConfig `mapstructure:",squash"`
+	WatchConfig     `mapstructure:",squash"`
 }
 
-func (j *RunJob) Run(ctx *Context) error {
-	var container *docker.Container
-	var err error
-	pull, _ := strconv.ParseBool(j.Pull)
+// ValidateCommand checks the command options of the job
+func (j *RunJob) ValidateCommand() error {
+	return j.CommandConfig.validate(j.Command, j.StdinFrom)
+}
 
-	if j.Image != "" && j.Container == "" {
-		if err = func() error {
-			var pullError error
-
-			// if Pull option "true"
-			// try pulling image first
-			if pull {
-				if pullError = j.pullImage(); pullError == nil {
-					ctx.Log("Pulled image " + j.Image)
-					return nil
-				}
-			}
+func NewRunJob(c *docker.Client, opts ...JobOption) *RunJob {
+	j := &RunJob{Client: c}
+	j.apply(opts)
+	return j
+}
 
-			// if Pull option "false"
-			// try to find image locally first
-			searchErr := j.searchLocalImage()
-			if searchErr == nil {
-				ctx.Log("Found locally image " + j.Image)
-				return nil
-			}
+func (j *RunJob) Run(ctx *Context) (rerr error) {
+	stdin, err := ctx.Stdin()
+	if err != nil {
+		return err
+	}
 
-			// if couldn't find image locally, still try to pull
-			if !pull && searchErr == ErrLocalImageNotFound {
-				if pullError = j.pullImage(); pullError == nil {
-					ctx.Log("Pulled image " + j.Image)
-					return nil
-				}
-			}
+	if j.Image == "" || j.Container != "" {
+		if j.Script != "" {
+			return ErrScriptExistingContainer
+		}
 
-			if pullError != nil {
-				return pullError
-			}
+		if stdin != nil {
+			return ErrStdinExistingContainer
+		}
 
-			if searchErr != nil {
-				return searchErr
-			}
+		if len(j.Sidecar) != 0 {
+			return ErrSidecarExistingContainer
+		}
 
-			return nil
-		}(); err != nil {
-			return err
+		if j.Workspace {
+			return ErrWorkspaceExistingContainer
 		}
 
-		container, err = j.buildContainer()
+		return j.runExistingContainer(ctx)
+	}
+
+	if err := j.ensureImage(ctx, j.Image); err != nil {
+		return err
+	}
+
+	// removed last, once the containers using it are removed
+	if j.Workspace {
+		remove, err := mountWorkspace(j.Client, ctx, j.workspacePath(), j.WorkspaceTmpfs)
 		if err != nil {
 			return err
 		}
-	} else {
-		container, err = j.getContainer(j.Container)
-		if err != nil {
+
+		defer remove()
+	}
+
+	sidecars, networkMode, err := j.startSidecars(ctx)
+	defer j.removeSidecars(ctx, sidecars)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if sidecarErr := j.checkSidecars(ctx, sidecars); sidecarErr != nil {
+			if rerr != nil {
+				ctx.Warn("job failed with a sidecar stopped: " + rerr.Error())
+			}
+
+			rerr = sidecarErr
+		}
+	}()
+
+	container, err := j.buildContainer(ctx, stdin != nil, networkMode)
+	if err != nil {
+		return err
+	}
+
+	if stdin != nil {
+		if err := j.attachStdin(container, stdin); err != nil {
 			return err
 		}
 	}
@@ -102,7 +148,13 @@ func (j *RunJob) Run(ctx *Context) error {
 		return err
 	}
 
-	err = j.watchContainer(container.ID)
+	err = j.waitHealthy(ctx, container.ID)
+	if err == nil {
+		err = j.watchContainer(ctx, container.ID)
+	} else if killErr := j.killContainer(ctx, container.ID); killErr != nil {
+		ctx.Warn("failed to kill container: " + killErr.Error())
+	}
+
 	if err == ErrUnexpected {
 		return err
 	}
@@ -119,19 +171,134 @@ func (j *RunJob) Run(ctx *Context) error {
 		ctx.Warn("failed to fetch container logs: " + logsErr.Error())
 	}
 
-	if j.Container == "" {
+	if delErr := j.deleteContainer(ctx, container.ID); delErr != nil {
+		ctx.Warn("failed to delete container: " + delErr.Error())
+	}
+
+	return err
+}
+
+// runExistingContainer starts the container of the job and waits for it to
+// exit. The output is attached before the start, to capture the whole run and
+// nothing of the previous ones. A running container is only run with
+// restore-state, stopped before and started again after the run.
+func (j *RunJob) runExistingContainer(ctx *Context) error {
+	container, err := j.getContainer(ctx, j.Container)
+	if err != nil {
+		return err
+	}
+
+	wasRunning := container.State.Running
+	if wasRunning {
+		if !j.RestoreState {
+			return fmt.Errorf("container %s is already running, see restore-state", j.Container)
+		}
+
+		if err := j.stopContainer(container.ID); err != nil {
+			return err
+		}
+
 		defer func() {
-			if delErr := j.deleteContainer(container.ID); delErr != nil {
-				ctx.Warn("failed to delete container: " + delErr.Error())
+			if err := j.Client.StartContainer(container.ID, nil); err != nil {
+				ctx.Warn("failed to restore the container running: " + err.Error())
+				return
 			}
+
+			ctx.Log("Restored container " + j.Container + " running")
 		}()
 	}
 
-	return err
+	cw, err := j.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+		Container:    container.ID,
+		OutputStream: ctx.Execution.OutputStream,
+		ErrorStream:  ctx.Execution.ErrorStream,
+		Stdout:       true,
+		Stderr:       true,
+		Stream:       true,
+		RawTerminal:  j.TTY,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error attaching to container output: %s", err)
+	}
+
+	defer cw.Close()
+	if err := j.startContainer(ctx.Execution, container); err != nil {
+		return err
+	}
+
+	err = j.waitHealthy(ctx, container.ID)
+	if err != nil {
+		if killErr := j.killContainer(ctx, container.ID); killErr != nil {
+			ctx.Warn("failed to kill container: " + killErr.Error())
+		}
+	}
+
+	code, waitErr := j.waitContainer(container.ID)
+	if waitErr != nil {
+		return waitErr
+	}
+
+	// the attach ends with the container, once the output is fully copied
+	if attachErr := cw.Wait(); attachErr != nil {
+		ctx.Warn("failed to read container output: " + attachErr.Error())
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ctx.Execution.ExitCode = code
+	return exitCodeError(code)
+}
+
+// ensureImage pulls the given image, or finds it locally, following the pull
+// option of the job
+func (j *RunJob) ensureImage(ctx *Context, image string) error {
+	var pullError error
+	pull, _ := strconv.ParseBool(j.Pull)
+
+	// if Pull option "true"
+	// try pulling image first
+	if pull {
+		if pullError = j.pullImage(ctx, image); pullError == nil {
+			ctx.Log(fmt.Sprintf("Pulled image %s in %s", image, ctx.Execution.PullDuration))
+			return nil
+		}
+	}
+
+	// if Pull option "false"
+	// try to find image locally first
+	searchErr := j.searchLocalImage(ctx, image)
+	if searchErr == nil {
+		ctx.Log("Found locally image " + image)
+		return nil
+	}
+
+	// if couldn't find image locally, still try to pull
+	if !pull && searchErr == ErrLocalImageNotFound {
+		if pullError = j.pullImage(ctx, image); pullError == nil {
+			ctx.Log(fmt.Sprintf("Pulled image %s in %s", image, ctx.Execution.PullDuration))
+			return nil
+		}
+	}
+
+	if pullError != nil {
+		return pullError
+	}
+
+	return searchErr
 }
 
-func (j *RunJob) searchLocalImage() error {
-	imgs, err := j.Client.ListImages(buildFindLocalImageOptions(j.Image))
+func (j *RunJob) searchLocalImage(ctx *Context, image string) error {
+	var imgs []docker.APIImages
+	err := j.retry(ctx, "listing images", func(c context.Context) (err error) {
+		o := buildFindLocalImageOptions(image)
+		o.Context = c
+		imgs, err = j.Client.ListImages(o)
+		return
+	})
+
 	if err != nil {
 		return err
 	}
@@ -143,65 +310,126 @@ func (j *RunJob) searchLocalImage() error {
 	return nil
 }
 
-func (j *RunJob) pullImage() error {
-	o, a := buildPullOptions(j.Image)
-	if err := j.Client.PullImage(o, a); err != nil {
-		return fmt.Errorf("error pulling image %q: %s", j.Image, err)
+func (j *RunJob) pullImage(ctx *Context, image string) error {
+	if err := pullImage(ctx, j.Client, j.DockerAPIConfig, image, j.PullTimeout); err != nil {
+		return fmt.Errorf("error pulling image %q: %s", image, err)
 	}
 
 	return nil
 }
 
-func (j *RunJob) buildContainer() (*docker.Container, error) {
-	c, err := j.Client.CreateContainer(docker.CreateContainerOptions{
-		Config: &docker.Config{
-			Image:        j.Image,
-			AttachStdin:  false,
-			AttachStdout: true,
-			AttachStderr: true,
-			Tty:          j.TTY,
-			Cmd:          args.GetArgs(j.Command),
-			User:         j.User,
-		},
-		NetworkingConfig: &docker.NetworkingConfig{},
-		HostConfig: &docker.HostConfig{
-			Binds: j.Volume,
-		},
+// buildContainer creates the container of the job, joining the network
+// namespace of the given network mode if any, instead of the network of the job
+func (j *RunJob) buildContainer(ctx *Context, stdin bool, networkMode string) (*docker.Container, error) {
+	cmd, err := j.commandArgs(ctx, j.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := ctx.Env()
+	if err != nil {
+		return nil, err
+	}
+
+	// not retried, a timed out creation may have created the container
+	var c *docker.Container
+	err = j.call(func(tctx context.Context) (err error) {
+		c, err = j.Client.CreateContainer(docker.CreateContainerOptions{
+			Config: &docker.Config{
+				Image:        j.Image,
+				AttachStdin:  stdin,
+				OpenStdin:    stdin,
+				StdinOnce:    stdin,
+				AttachStdout: true,
+				AttachStderr: true,
+				Tty:          j.TTY,
+				Cmd:          cmd,
+				Env:          env,
+				User:         j.User,
+				Labels:       j.GetProvenance().Labels(),
+			},
+			NetworkingConfig: &docker.NetworkingConfig{},
+			HostConfig: &docker.HostConfig{
+				Binds:       append(append([]string{}, j.Volume...), ctx.Volumes...),
+				NetworkMode: networkMode,
+			},
+			Context: tctx,
+		})
+
+		return
 	})
 
 	if err != nil {
 		return c, fmt.Errorf("error creating exec: %s", err)
 	}
 
-	if j.Network != "" {
-		networkOpts := docker.NetworkFilterOpts{}
-		networkOpts["name"] = map[string]bool{}
-		networkOpts["name"][j.Network] = true
-		if networks, err := j.Client.FilteredListNetworks(networkOpts); err == nil {
-			for _, network := range networks {
-				if err := j.Client.ConnectNetwork(network.ID, docker.NetworkConnectionOptions{
-					Container: c.ID,
-				}); err != nil {
-					return c, fmt.Errorf("error connecting container to network: %s", err)
-				}
-			}
+	if j.Network != "" && networkMode == "" {
+		if err := j.connectNetwork(ctx, c.ID, nil); err != nil {
+			return c, err
 		}
 	}
 
 	return c, nil
 }
 
+// connectNetwork connects the container to the network of the job, reachable
+// by the given aliases. The networks unsupported by the docker runtime are
+// skipped, with a warning.
+func (j *RunJob) connectNetwork(ctx *Context, containerID string, aliases []string) error {
+	networkOpts := docker.NetworkFilterOpts{}
+	networkOpts["name"] = map[string]bool{}
+	networkOpts["name"][j.Network] = true
+	if networks, err := j.Client.FilteredListNetworks(networkOpts); err == nil {
+		for _, network := range networks {
+			if err := ctx.runtime().supportsNetwork(network.Driver); err != nil {
+				ctx.Warn(fmt.Sprintf("Not connected to the network %q: %s", network.Name, err))
+				continue
+			}
+
+			if err := j.Client.ConnectNetwork(network.ID, docker.NetworkConnectionOptions{
+				Container:      containerID,
+				EndpointConfig: &docker.EndpointConfig{Aliases: aliases},
+			}); err != nil {
+				return fmt.Errorf("error connecting container to network: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// attachStdin feeds the given input to the stdin of the container, once
+// started, the stdin is closed at the end of the input
+func (j *RunJob) attachStdin(c *docker.Container, stdin io.Reader) error {
+	_, err := j.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+		Container:   c.ID,
+		InputStream: stdin,
+		Stdin:       true,
+		Stream:      true,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error attaching to container stdin: %s", err)
+	}
+
+	return nil
+}
+
 func (j *RunJob) startContainer(e *Execution, c *docker.Container) error {
 	return j.Client.StartContainer(c.ID, &docker.HostConfig{})
 }
 
-func (j *RunJob) getContainer(id string) (*docker.Container, error) {
-	opts := docker.InspectContainerOptions{
-		Context: nil,
-		ID:      id,
-		Size:    false,
-	}
-	container, err := j.Client.InspectContainerWithOptions(opts)
+func (j *RunJob) getContainer(ctx *Context, id string) (*docker.Container, error) {
+	var container *docker.Container
+	err := j.retry(ctx, "inspecting container", func(c context.Context) (err error) {
+		opts := docker.InspectContainerOptions{
+			Context: c,
+			ID:      id,
+			Size:    false,
+		}
+		container, err = j.Client.InspectContainerWithOptions(opts)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -209,27 +437,33 @@ func (j *RunJob) getContainer(id string) (*docker.Container, error) {
 }
 
 const (
-	watchDuration      = time.Millisecond * 100
-	maxProcessDuration = time.Hour * 24
+	// stopContainerTimeout seconds given to a container to stop before being
+	// killed
+	stopContainerTimeout = 10
 )
 
-func (j *RunJob) watchContainer(containerID string) error {
+func (j *RunJob) watchContainer(ctx *Context, containerID string) error {
+	interval, err := j.watchInterval()
+	if err != nil {
+		return err
+	}
+
+	max, err := j.maxRuntime()
+	if err != nil {
+		return err
+	}
+
 	var s docker.State
 	var r time.Duration
 	for {
-		time.Sleep(watchDuration)
-		r += watchDuration
+		time.Sleep(interval)
+		r += interval
 
-		if r > maxProcessDuration {
+		if r > max {
 			return ErrMaxTimeRunning
 		}
 
-		opts := docker.InspectContainerOptions{
-			Context: nil,
-			ID:      containerID,
-			Size:    false,
-		}
-		c, err := j.Client.InspectContainerWithOptions(opts)
+		c, err := j.getContainer(ctx, containerID)
 		if err != nil {
 			return err
 		}
@@ -240,22 +474,121 @@ func (j *RunJob) watchContainer(containerID string) error {
 		}
 	}
 
-	switch s.ExitCode {
+	ctx.Execution.ExitCode = s.ExitCode
+	return exitCodeError(s.ExitCode)
+}
+
+// waitContainer waits for the container to exit, returning its exit code
+func (j *RunJob) waitContainer(containerID string) (int, error) {
+	max, err := j.maxRuntime()
+	if err != nil {
+		return 0, err
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), max)
+	defer cancel()
+
+	code, err := j.Client.WaitContainerWithContext(containerID, c)
+	if c.Err() == context.DeadlineExceeded {
+		return 0, ErrMaxTimeRunning
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("error waiting for container: %s", err)
+	}
+
+	return code, nil
+}
+
+// stopContainer stops the running container, before being run by the job
+func (j *RunJob) stopContainer(containerID string) error {
+	err := j.call(func(c context.Context) error {
+		return j.Client.StopContainerWithContext(containerID, stopContainerTimeout, c)
+	})
+
+	if err != nil {
+		return fmt.Errorf("error stopping container: %s", err)
+	}
+
+	return nil
+}
+
+// exitCodeError returns the error of the given exit code of the command
+func exitCodeError(code int) error {
+	switch code {
 	case 0:
 		return nil
 	case -1:
 		return ErrUnexpected
 	default:
-		return fmt.Errorf("error non-zero exit code: %d", s.ExitCode)
+		return fmt.Errorf("error non-zero exit code: %d", code)
+	}
+}
+
+// waitHealthy waits for the healthcheck of the container to report healthy,
+// if wait-healthy is set. The containers exiting meanwhile are not waited.
+func (j *RunJob) waitHealthy(ctx *Context, containerID string) error {
+	if j.WaitHealthy == "" {
+		return nil
+	}
+
+	timeout, err := parseDuration("wait-healthy", j.WaitHealthy, 0)
+	if err != nil {
+		return err
 	}
+
+	interval, err := j.watchInterval()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for {
+		c, err := j.getContainer(ctx, containerID)
+		if err != nil {
+			return err
+		}
+
+		if !c.State.Running {
+			return nil
+		}
+
+		// the status is empty until the first check of the healthcheck
+		switch c.State.Health.Status {
+		case "healthy":
+			ctx.Log(fmt.Sprintf("Container healthy after %s", time.Since(start).Round(time.Millisecond)))
+			return nil
+		case "unhealthy":
+			return ErrContainerUnhealthy
+		}
+
+		if time.Since(start) > timeout {
+			return fmt.Errorf("container not healthy after %s, health status %q", timeout, c.State.Health.Status)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// killContainer kills the container not waited by watchContainer
+func (j *RunJob) killContainer(ctx *Context, containerID string) error {
+	return j.call(func(c context.Context) error {
+		return j.Client.KillContainer(docker.KillContainerOptions{
+			ID:      containerID,
+			Context: c,
+		})
+	})
 }
 
-func (j *RunJob) deleteContainer(containerID string) error {
+func (j *RunJob) deleteContainer(ctx *Context, containerID string) error {
 	if delete, _ := strconv.ParseBool(j.Delete); !delete {
 		return nil
 	}
 
-	return j.Client.RemoveContainer(docker.RemoveContainerOptions{
-		ID: containerID,
+	return j.retry(ctx, "removing container", func(c context.Context) error {
+		return j.Client.RemoveContainer(docker.RemoveContainerOptions{
+			ID:      containerID,
+			Context: c,
+		})
 	})
 }
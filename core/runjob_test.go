@@ -76,6 +76,122 @@ func (s *SuiteRunJob) TestRun(c *C) {
 	c.Assert(containers, HasLen, 0)
 }
 
+func (s *SuiteRunJob) runWaitHealthy(c *C, health string, stop bool) error {
+	job := &RunJob{Client: s.client}
+	job.Image = ImageFixture
+	job.Command = "echo foo"
+	job.Delete = "true"
+	job.WaitHealthy = "1s"
+	job.Name = "test"
+
+	ctx := &Context{}
+	ctx.Execution = NewExecution()
+	ctx.Logger = logging.MustGetLogger("ofelia")
+	ctx.Job = job
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		time.Sleep(time.Millisecond * 200)
+
+		containers, err := s.client.ListContainers(docker.ListContainersOptions{})
+		c.Assert(err, IsNil)
+		c.Assert(s.server.MutateContainer(containers[0].ID, docker.State{
+			Running: true,
+			Health:  docker.Health{Status: health},
+		}), IsNil)
+
+		if stop {
+			time.Sleep(time.Millisecond * 200)
+			c.Assert(s.client.StopContainer(containers[0].ID, 0), IsNil)
+		}
+	}()
+
+	err := job.Run(ctx)
+	wg.Wait()
+
+	containers, lerr := s.client.ListContainers(docker.ListContainersOptions{All: true})
+	c.Assert(lerr, IsNil)
+	c.Assert(containers, HasLen, 0)
+
+	return err
+}
+
+func (s *SuiteRunJob) TestRunWaitHealthy(c *C) {
+	c.Assert(s.runWaitHealthy(c, "healthy", true), IsNil)
+}
+
+func (s *SuiteRunJob) TestRunWaitHealthyUnhealthy(c *C) {
+	c.Assert(s.runWaitHealthy(c, "unhealthy", false), Equals, ErrContainerUnhealthy)
+}
+
+func (s *SuiteRunJob) TestRunWaitHealthyTimeout(c *C) {
+	err := s.runWaitHealthy(c, "starting", false)
+	c.Assert(err, ErrorMatches, `container not healthy after 1s, health status "starting"`)
+}
+
+// runExisting runs a job with an existing container, stopped by the test
+// during the run, returning the job error and the container once run
+func (s *SuiteRunJob) runExisting(c *C, running, restore bool) (string, *docker.Container, error) {
+	container, err := s.client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "existing",
+		Config: &docker.Config{Image: ImageFixture},
+	})
+	c.Assert(err, IsNil)
+
+	if running {
+		c.Assert(s.client.StartContainer(container.ID, nil), IsNil)
+	}
+
+	job := &RunJob{Client: s.client}
+	job.Container = "existing"
+	job.RestoreState = restore
+	job.Name = "test"
+
+	ctx := &Context{}
+	ctx.Execution = NewExecution()
+	ctx.Logger = logging.MustGetLogger("ofelia")
+	ctx.Job = job
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		time.Sleep(time.Millisecond * 200)
+		s.client.StopContainer(container.ID, 0)
+	}()
+
+	err = job.Run(ctx)
+	wg.Wait()
+
+	container, ierr := s.client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	c.Assert(ierr, IsNil)
+
+	return ctx.Execution.OutputStream.String(), container, err
+}
+
+func (s *SuiteRunJob) TestRunExistingContainer(c *C) {
+	output, container, err := s.runExisting(c, false, false)
+	c.Assert(err, IsNil)
+	c.Assert(output, Equals, "Container is not running\nWhat happened?\nSomething happened\n")
+	c.Assert(container.State.Running, Equals, false)
+}
+
+func (s *SuiteRunJob) TestRunExistingContainerRunning(c *C) {
+	_, container, err := s.runExisting(c, true, false)
+	c.Assert(err, ErrorMatches, "container existing is already running, see restore-state")
+	c.Assert(container.State.Running, Equals, false)
+}
+
+func (s *SuiteRunJob) TestRunExistingContainerRestoreState(c *C) {
+	_, container, err := s.runExisting(c, true, true)
+	c.Assert(err, IsNil)
+	c.Assert(container.State.Running, Equals, true)
+}
+
 func (s *SuiteRunJob) TestBuildPullImageOptionsBareImage(c *C) {
 	o, _ := buildPullOptions("foo")
 	c.Assert(o.Repository, Equals, "foo")
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -25,18 +26,30 @@ type RunServiceJob struct {
 	Delete  string `default:"true"`
 	Image   string
 	Network string
+	// PullTimeout timeout of the image pull, e.g. "10m"
+	PullTimeout string `gcfg:"pull-timeout" mapstructure:"pull-timeout"`
+
+	DockerAPIConfig `mapstructure:",squash"`
+	WatchConfig     `mapstructure:",squash"`
 }
 
-func NewRunServiceJob(c *docker.Client) *RunServiceJob {
-	return &RunServiceJob{Client: c}
+func NewRunServiceJob(c *docker.Client, opts ...JobOption) *RunServiceJob {
+	j := &RunServiceJob{Client: c}
+	j.apply(opts)
+	return j
 }
 
 func (j *RunServiceJob) Run(ctx *Context) error {
-	if err := j.pullImage(); err != nil {
+	if err := j.pullImage(ctx); err != nil {
+		return err
+	}
+
+	cmd, err := ctx.Render(j.Command)
+	if err != nil {
 		return err
 	}
 
-	svc, err := j.buildService()
+	svc, err := j.buildService(ctx, cmd)
 
 	if err != nil {
 		return err
@@ -51,16 +64,15 @@ func (j *RunServiceJob) Run(ctx *Context) error {
 	return j.deleteService(ctx, svc.ID)
 }
 
-func (j *RunServiceJob) pullImage() error {
-	o, a := buildPullOptions(j.Image)
-	if err := j.Client.PullImage(o, a); err != nil {
+func (j *RunServiceJob) pullImage(ctx *Context) error {
+	if err := pullImage(ctx, j.Client, j.DockerAPIConfig, j.Image, j.PullTimeout); err != nil {
 		return fmt.Errorf("error pulling image %q: %s", j.Image, err)
 	}
 
 	return nil
 }
 
-func (j *RunServiceJob) buildService() (*swarm.Service, error) {
+func (j *RunServiceJob) buildService(ctx *Context, cmd string) (*swarm.Service, error) {
 
 	//createOptions := types.ServiceCreateOptions{}
 
@@ -72,6 +84,10 @@ func (j *RunServiceJob) buildService() (*swarm.Service, error) {
 			Image: j.Image,
 		}
 
+	// The service and its containers are attributed to the provenance of the job
+	createSvcOpts.ServiceSpec.Annotations.Labels = j.GetProvenance().Labels()
+	createSvcOpts.ServiceSpec.TaskTemplate.ContainerSpec.Labels = j.GetProvenance().Labels()
+
 	// Make the service run once and not restart
 	createSvcOpts.ServiceSpec.TaskTemplate.RestartPolicy =
 		&swarm.RestartPolicy{
@@ -89,11 +105,17 @@ func (j *RunServiceJob) buildService() (*swarm.Service, error) {
 		}
 	}
 
-	if j.Command != "" {
-		createSvcOpts.ServiceSpec.TaskTemplate.ContainerSpec.Command = strings.Split(j.Command, " ")
+	if cmd != "" {
+		createSvcOpts.ServiceSpec.TaskTemplate.ContainerSpec.Command = strings.Split(cmd, " ")
 	}
 
-	svc, err := j.Client.CreateService(createSvcOpts)
+	// not retried, a timed out creation may have created the service
+	var svc *swarm.Service
+	err := j.call(func(c context.Context) (err error) {
+		createSvcOpts.Context = c
+		svc, err = j.Client.CreateService(createSvcOpts)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -108,14 +130,26 @@ const (
 	timeoutError = -998
 )
 
-var svcChecker = time.NewTicker(watchDuration)
-
 func (j *RunServiceJob) watchContainer(ctx *Context, svcID string) error {
 	exitCode := swarmError
 
+	interval, err := j.watchInterval()
+	if err != nil {
+		return err
+	}
+
+	max, err := j.maxRuntime()
+	if err != nil {
+		return err
+	}
+
 	ctx.Logger.Noticef("Checking for service ID %s (%s) termination\n", svcID, j.Name)
 
-	svc, err := j.Client.InspectService(svcID)
+	var svc *swarm.Service
+	err = j.retry(ctx, "inspecting service", func(context.Context) (err error) {
+		svc, err = j.Client.InspectService(svcID)
+		return
+	})
 	if err != nil {
 		return fmt.Errorf("failed to inspect service %s: %s", svcID, err.Error())
 	}
@@ -126,9 +160,13 @@ func (j *RunServiceJob) watchContainer(ctx *Context, svcID string) error {
 
 	go func() {
 		defer wg.Done()
+		start := time.Now()
+		svcChecker := time.NewTicker(interval)
+		defer svcChecker.Stop()
+
 		for range svcChecker.C {
 
-			if svc.CreatedAt.After(time.Now().Add(maxProcessDuration)) {
+			if time.Since(start) > max {
 				err = ErrMaxTimeRunning
 				return
 			}
@@ -152,8 +190,14 @@ func (j *RunServiceJob) findtaskstatus(ctx *Context, taskID string) (int, bool)
 	taskFilters := make(map[string][]string)
 	taskFilters["service"] = []string{taskID}
 
-	tasks, err := j.Client.ListTasks(docker.ListTasksOptions{
-		Filters: taskFilters,
+	var tasks []swarm.Task
+	err := j.retry(ctx, "listing tasks", func(c context.Context) (err error) {
+		tasks, err = j.Client.ListTasks(docker.ListTasksOptions{
+			Filters: taskFilters,
+			Context: c,
+		})
+
+		return
 	})
 
 	if err != nil {
@@ -203,8 +247,11 @@ func (j *RunServiceJob) deleteService(ctx *Context, svcID string) error {
 		return nil
 	}
 
-	err := j.Client.RemoveService(docker.RemoveServiceOptions{
-		ID: svcID,
+	err := j.call(func(c context.Context) error {
+		return j.Client.RemoveService(docker.RemoveServiceOptions{
+			ID:      svcID,
+			Context: c,
+		})
 	})
 
 	if _, is := err.(*docker.NoSuchService); is {
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// RuntimeCapabilities what the docker daemon running the jobs supports
+type RuntimeCapabilities struct {
+	// Rootless when true, the daemon runs as an unprivileged user
+	Rootless bool
+	// UserNS when true, the daemon remaps the users of the containers
+	UserNS bool
+	// CgroupDriver the cgroup driver of the daemon, e.g. "systemd"
+	CgroupDriver string
+	// Forced when true, the capabilities were configured, not probed
+	Forced bool
+}
+
+// rootlessNetworkDrivers the network drivers unsupported by rootless docker
+var rootlessNetworkDrivers = []string{"overlay", "macvlan", "ipvlan"}
+
+// ProbeRuntime returns the capabilities of the docker daemon, read from its
+// info
+func ProbeRuntime(c *docker.Client) (*RuntimeCapabilities, error) {
+	info, err := c.Info()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the docker info: %s", err)
+	}
+
+	r := &RuntimeCapabilities{CgroupDriver: info.CgroupDriver}
+	for _, opt := range info.SecurityOptions {
+		// e.g. "name=seccomp,profile=default"
+		name := strings.TrimPrefix(strings.SplitN(opt, ",", 2)[0], "name=")
+		switch name {
+		case "rootless":
+			r.Rootless = true
+		case "userns":
+			r.UserNS = true
+		}
+	}
+
+	return r, nil
+}
+
+func (r *RuntimeCapabilities) String() string {
+	s := fmt.Sprintf("rootless: %t, userns-remap: %t", r.Rootless, r.UserNS)
+	if r.CgroupDriver != "" {
+		s += ", cgroup driver: " + r.CgroupDriver
+	}
+
+	if r.Forced {
+		s += " (configured)"
+	}
+
+	return s
+}
+
+// supportsNetwork returns an error if a container can't be connected to a
+// network of the given driver
+func (r *RuntimeCapabilities) supportsNetwork(driver string) error {
+	if r == nil || !r.Rootless {
+		return nil
+	}
+
+	for _, d := range rootlessNetworkDrivers {
+		if d == driver {
+			return fmt.Errorf("the %s network driver is unsupported by rootless docker", driver)
+		}
+	}
+
+	return nil
+}
+
+// runtime returns the capabilities of the docker daemon, nil if unknown
+func (c *Context) runtime() *RuntimeCapabilities {
+	if c.Scheduler == nil {
+		return nil
+	}
+
+	return c.Scheduler.Runtime
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"net/http"
+
+	docker "github.com/fsouza/go-dockerclient"
+	. "gopkg.in/check.v1"
+)
+
+func (s *SuiteRunJob) TestProbeRuntime(c *C) {
+	s.server.CustomHandler("/info", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"CgroupDriver": "systemd", "SecurityOptions": ["name=seccomp,profile=default", "name=rootless"]}`))
+	}))
+
+	r, err := ProbeRuntime(s.client)
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, &RuntimeCapabilities{Rootless: true, CgroupDriver: "systemd"})
+	c.Assert(r.String(), Equals, "rootless: true, userns-remap: false, cgroup driver: systemd")
+
+	c.Assert((&RuntimeCapabilities{UserNS: true, Forced: true}).String(), Equals, "rootless: false, userns-remap: true (configured)")
+}
+
+func (s *SuiteRunJob) TestRunRootlessNetwork(c *C) {
+	n, err := s.client.CreateNetwork(docker.CreateNetworkOptions{Name: "swarm", Driver: "overlay"})
+	c.Assert(err, IsNil)
+
+	job := &RunJob{Client: s.client}
+	job.Image = ImageFixture
+	job.Network = "swarm"
+	job.Name = "test"
+
+	sched := NewScheduler(&TestLogger{})
+	sched.Runtime = &RuntimeCapabilities{Rootless: true}
+	ctx := NewContext(sched, job, NewExecution())
+
+	container, err := job.buildContainer(ctx, false, "")
+	c.Assert(err, IsNil)
+
+	n, err = s.client.NetworkInfo(n.ID)
+	c.Assert(err, IsNil)
+	c.Assert(n.Containers, HasLen, 0)
+
+	sched.Runtime.Rootless = false
+	container, err = job.buildContainer(ctx, false, "")
+	c.Assert(err, IsNil)
+
+	n, err = s.client.NetworkInfo(n.ID)
+	c.Assert(err, IsNil)
+	c.Assert(n.Containers, HasLen, 1)
+	_, ok := n.Containers[container.ID]
+	c.Assert(ok, Equals, true)
+}
+
+func (s *SuiteRunJob) TestSupportsNetwork(c *C) {
+	var r *RuntimeCapabilities
+	c.Assert(r.supportsNetwork("overlay"), IsNil)
+	c.Assert((&RuntimeCapabilities{}).supportsNetwork("overlay"), IsNil)
+	c.Assert((&RuntimeCapabilities{Rootless: true}).supportsNetwork("bridge"), IsNil)
+	c.Assert((&RuntimeCapabilities{Rootless: true}).supportsNetwork("macvlan"), ErrorMatches,
+		"the macvlan network driver is unsupported by rootless docker")
+}
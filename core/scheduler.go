@@ -3,7 +3,9 @@ package core
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
@@ -13,41 +15,267 @@ var (
 	ErrAlreadyStopped = errors.New("scheduler has already stopped")
 	ErrEmptyScheduler = errors.New("unable to start a empty scheduler")
 	ErrEmptySchedule  = errors.New("unable to add a job with a empty schedule")
+	ErrJobNotFound    = errors.New("unable to find the job")
 )
 
+// MatrixParam name of the param holding the matrix value of a execution
+const MatrixParam = "matrix"
+
+// JobMatrix returns the matrix values of the given job, run once per value,
+// nil if none
+func JobMatrix(j Job) []string {
+	if m, ok := j.(interface{ GetMatrix() []string }); ok {
+		return m.GetMatrix()
+	}
+
+	return nil
+}
+
 type Scheduler struct {
 	Jobs   []Job
 	Logger Logger
+	// Queue when set, stores the deferred executions, to be resumed on Start
+	Queue ExecutionQueue
+	// PullCache when set, shares the image pulls between the jobs
+	PullCache *PullCache
+	// Events emits the registration of the jobs and their executions
+	Events *EventBus
+	// Clock dates the executions
+	Clock Clock
+	// Audit when set, records the control actions on the jobs
+	Audit AuditLog
+	// Redactor when set, masks the secrets in the output of the executions
+	Redactor *Redactor
+	// History when set, stores the executions while the scheduler runs
+	History HistoryStore
+	// Publisher when set, publishes the events while the scheduler runs
+	Publisher EventPublisher
+	// Subscriber when set, runs the jobs triggered by messages while the
+	// scheduler runs
+	Subscriber MessageSubscriber
+	// Pool when set, bounds the concurrent executions of the jobs by kind
+	Pool *ExecutionPool
+	// Orphans when set, tracks the jobs whose container is gone
+	Orphans *OrphanMonitor
+	// Secrets when set, resolves the environment variables of the commands
+	// referencing a secret
+	Secrets *Secrets
+	// JobOverridesGlobal when true, the default, a middleware of a job replaces
+	// the middleware of the same type of the scheduler, otherwise both are used
+	JobOverridesGlobal bool
+	// DockerAPI when set, the only sections of the docker API the jobs can
+	// use, see JobDockerAPI
+	DockerAPI []string
+	// Runtime when set, the capabilities of the docker daemon, see
+	// ProbeRuntime
+	Runtime *RuntimeCapabilities
+	// DockerMonitor when set, watches the docker daemon for the jobs using
+	// it, shared by the jobs built for the scheduler and stopped with it
+	DockerMonitor *DockerMonitor
+	// ExternalURL when set, the URL of the HTTP API as reached by the readers
+	// of the notifications, linking to the executions, see
+	// Context.ExecutionURL
+	ExternalURL string
+	// Recent when set, keeps the last executions, shown by the HTTP API
+	Recent *RecentExecutions
+	// Host when set, the name of the host the schedules are spread for, see
+	// JobScheduleSpread, Hostname by default
+	Host string
 
 	middlewareContainer
 	cron      *cron.Cron
+	parser    cron.ScheduleParser
+	entries   map[Job]cron.EntryID
+	groups    map[string]*Group
+	running   map[*Context]bool
+	runningMu sync.Mutex
+	// outputs last successful output of the jobs read by a stdin-from job
+	outputs   map[string][]byte
+	outputsMu sync.Mutex
+	// lastReload the last reload of the config, see RecordReload
+	lastReload *Reload
+	reloadMu   sync.Mutex
+	// silences suppressing the notifications, see Silence
+	silences   []*Silence
+	silencesMu sync.Mutex
+	// skips and runs the overrides of the schedules of the jobs, by name and
+	// by ID, see SkipJob and ScheduleRun
+	skips map[string]int
+	runs  map[string]*OneTimeRun
+	// completed the jobs that ran their "@at" schedule, by name
+	completed   map[string]completion
+	overridesMu sync.Mutex
+	// checksums of the skip-if-unchanged paths of the last successful
+	// executions, see JobSkipIfUnchanged
+	checksums   map[string]string
+	checksumsMu sync.Mutex
+	// diffs last successful outputs of the jobs with output-diff, see
+	// JobOutputDiff
+	diffs     map[string]string
+	diffsMu   sync.Mutex
+	mu        sync.RWMutex
 	wg        sync.WaitGroup
 	isRunning bool
+	// stopHistory and stopPublisher stop recording the history and
+	// publishing the events, flushing them
+	stopHistory   func()
+	stopPublisher func()
+	// stopTriggers stops the subscriptions of the jobs with a trigger
+	stopTriggers func()
 }
 
-func NewScheduler(l Logger) *Scheduler {
-	return &Scheduler{
-		Logger: l,
-		cron:   cron.New(),
+// NewScheduler returns a scheduler logging to the given logger, configured
+// by the given options.
+func NewScheduler(l Logger, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		Logger:    l,
+		Clock:     systemClock{},
+		entries:   make(map[Job]cron.EntryID),
+		groups:    make(map[string]*Group),
+		running:   make(map[*Context]bool),
+		outputs:   make(map[string][]byte),
+		skips:     make(map[string]int),
+		runs:      make(map[string]*OneTimeRun),
+		completed: make(map[string]completion),
+		checksums: make(map[string]string),
+		diffs:     make(map[string]string),
+		Events:    NewEventBus(),
+
+		JobOverridesGlobal: true,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var copts []cron.Option
+	if s.parser != nil {
+		copts = append(copts, cron.WithParser(s.parser))
+	}
+
+	s.cron = cron.New(copts...)
+	return s
 }
 
 func (s *Scheduler) AddJob(j Job) error {
-	s.Logger.Noticef("New job registered %q - %q - %q", j.GetName(), j.GetCommand(), j.GetSchedule())
-
 	if j.GetSchedule() == "" {
 		return ErrEmptySchedule
 	}
 
-	if _, err := s.cron.AddJob(j.GetSchedule(), &jobWrapper{s, j}); err != nil {
+	s.Logger.Noticef("New job registered %q - %q - %q", j.GetName(), j.GetCommand(), j.GetSchedule())
+
+	schedule, err := s.schedule(j)
+	if err != nil {
 		return err
 	}
 
+	if d := JobAfter(j); d > 0 {
+		schedule = &afterSchedule{delay: d}
+	}
+
+	if at := JobAt(j); !at.IsZero() && !at.After(s.Clock.Now()) {
+		s.Logger.Warningf("Job %q is scheduled at %s, in the past, it won't run", j.GetName(), at.Format(time.RFC3339))
+	}
+
+	s.mu.Lock()
+	s.entries[j] = s.cron.Schedule(schedule, &jobWrapper{s, j})
+	s.register(j)
+	s.mu.Unlock()
+
+	s.Events.Publish(&JobRegistered{Job: j})
+	return nil
+}
+
+// AddTriggeredJob registers a job without schedule, it only runs when is
+// triggered by other job or by RunJob.
+func (s *Scheduler) AddTriggeredJob(j Job) {
+	s.Logger.Noticef("New triggered job registered %q - %q", j.GetName(), j.GetCommand())
+
+	s.mu.Lock()
+	s.register(j)
+	s.mu.Unlock()
+
+	s.Events.Publish(&JobRegistered{Job: j})
+}
+
+func (s *Scheduler) register(j Job) {
+	// the middlewares are merged on Start, jobs added later are merged here
+	if s.isRunning {
+		s.inheritMiddlewares(j)
+	}
+
 	s.Jobs = append(s.Jobs, j)
+}
+
+// RemoveJob removes the given job, the running executions are not affected.
+func (s *Scheduler) RemoveJob(j Job) error {
+	if !s.unregister(j) {
+		return ErrJobNotFound
+	}
+
+	s.Logger.Noticef("Job removed %q", j.GetName())
+	s.Events.Publish(&JobRemoved{Job: j})
+	return nil
+}
+
+func (s *Scheduler) unregister(j Job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, job := range s.Jobs {
+		if job != j {
+			continue
+		}
+
+		if id, ok := s.entries[j]; ok {
+			s.cron.Remove(id)
+			delete(s.entries, j)
+		}
+
+		s.Jobs = append(s.Jobs[:i], s.Jobs[i+1:]...)
+		return true
+	}
+
+	return false
+}
+
+// RunJob runs the job with the given name in background, outside of its
+// schedule, the execution goes through the same middlewares as a scheduled one.
+func (s *Scheduler) RunJob(name string) error {
+	return s.RunJobWithParams(name, nil)
+}
+
+// RunJobWithParams runs the job as RunJob, the given params being available to
+// its command template, e.g. `{{.matrix}}`, or its environment, e.g. the payload
+// of a webhook as OFELIA_PAYLOAD.
+func (s *Scheduler) RunJobWithParams(name string, params map[string]string) error {
+	j := s.GetJob(name)
+	if j == nil {
+		return ErrJobNotFound
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		(&jobWrapper{s, j}).runWith(time.Time{}, params)
+	}()
+
 	return nil
 }
 
 func (s *Scheduler) Start() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	s.resumeQueue()
+	return nil
+}
+
+func (s *Scheduler) start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.isRunning {
 		return ErrAlreadyStarted
 	}
@@ -59,15 +287,165 @@ func (s *Scheduler) Start() error {
 	s.Logger.Debugf("Starting scheduler with %d jobs", len(s.Jobs))
 
 	s.mergeMiddlewares()
+	if s.History != nil {
+		s.stopHistory = RecordHistory(s, s.History)
+	}
+
+	if s.Publisher != nil {
+		s.stopPublisher = PublishEvents(s, s.Publisher)
+	}
+
+	subs := map[string]MessageSubscriber{TriggerWatch: &fileSubscriber{logger: s.Logger}}
+	if s.Subscriber != nil {
+		subs[TriggerNATS] = s.Subscriber
+	}
+
+	s.stopTriggers = watchTriggers(s, subs)
+
 	s.isRunning = true
 	s.cron.Start()
 	return nil
 }
 
+func (s *Scheduler) resumeQueue() {
+	if s.Queue == nil {
+		return
+	}
+
+	pending, err := s.Queue.Pending()
+	if err != nil {
+		s.Logger.Errorf("Unable to read the execution queue: %s", err)
+		return
+	}
+
+	byJob := make(map[string][]*QueuedExecution)
+	for _, e := range pending {
+		byJob[e.Job] = append(byJob[e.Job], e)
+	}
+
+	for name, entries := range byJob {
+		j := s.GetJob(name)
+		if j == nil {
+			s.Logger.Warningf("Discarding %d queued executions of unknown job %q", len(entries), name)
+			s.removeQueued(entries)
+			continue
+		}
+
+		s.Logger.Noticef("Resuming %d queued executions of job %q", len(entries), name)
+		s.wg.Add(1)
+		go s.runQueued(&jobWrapper{s, j}, entries)
+	}
+}
+
+// runQueued runs the queued executions of a job one after the other, keeping
+// the order they were queued.
+func (s *Scheduler) runQueued(w *jobWrapper, entries []*QueuedExecution) {
+	defer s.wg.Done()
+
+	for _, e := range entries {
+		s.removeQueued([]*QueuedExecution{e})
+		w.Run()
+	}
+}
+
+func (s *Scheduler) removeQueued(entries []*QueuedExecution) {
+	for _, e := range entries {
+		if err := s.Queue.Remove(e.ID); err != nil {
+			s.Logger.Errorf("Unable to remove queued execution of job %q: %s", e.Job, err)
+		}
+	}
+}
+
+// TriggerJob runs the job with the given name as RunJob, recording the action
+// of the given actor in the audit log.
+func (s *Scheduler) TriggerJob(actor, name string) error {
+	return s.TriggerJobWithParams(actor, name, nil)
+}
+
+// TriggerJobWithParams runs the job as RunJobWithParams, recording the action
+// of the given actor in the audit log.
+func (s *Scheduler) TriggerJobWithParams(actor, name string, params map[string]string) error {
+	if s.GetJob(name) == nil {
+		return ErrJobNotFound
+	}
+
+	e := NewAuditEntry(actor, AuditTrigger, nil, nil)
+	e.Job = name
+	s.Record(e)
+
+	return s.RunJobWithParams(name, params)
+}
+
+// Record records the entry in the audit log if any, dated now
+func (s *Scheduler) Record(e *AuditEntry) {
+	if s.Audit == nil {
+		return
+	}
+
+	e.Date = s.Clock.Now()
+	if err := s.Audit.Record(e); err != nil {
+		s.Logger.Errorf("Unable to record the %s of job %q in the audit log: %s", e.Action, e.Job, err)
+	}
+}
+
+// GetJobs returns a copy of the registered jobs, safe to use while the jobs
+// are added or removed
+func (s *Scheduler) GetJobs() []Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Job{}, s.Jobs...)
+}
+
+// GetJob returns the registered job with the given name, nil if not found
+func (s *Scheduler) GetJob(name string) Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, j := range s.Jobs {
+		if j.GetName() == name {
+			return j
+		}
+	}
+
+	return nil
+}
+
 func (s *Scheduler) mergeMiddlewares() {
 	for _, j := range s.Jobs {
-		j.Use(s.Middlewares()...)
+		s.inheritMiddlewares(j)
+	}
+}
+
+// inheritMiddlewares adds the middlewares of the scheduler to the given job,
+// after its own ones. With JobOverridesGlobal a middleware of the job replaces
+// the one of the same type of the scheduler, e.g. a job with its own slack
+// middleware doesn't send its reports to the global slack webhook, otherwise
+// both are used, unless they are equal, see SameMiddleware. The jobs not
+// inheriting the globals only use their own middlewares.
+func (s *Scheduler) inheritMiddlewares(j Job) {
+	if !JobInheritGlobals(j) {
+		return
+	}
+
+	if d, ok := j.(interface{ UseDistinct(...Middleware) }); ok && !s.JobOverridesGlobal {
+		d.UseDistinct(s.Middlewares()...)
+		return
+	}
+
+	j.Use(s.Middlewares()...)
+}
+
+// JobInheritGlobals returns false if the given job doesn't use the middlewares
+// of the scheduler, by default it does
+func JobInheritGlobals(j Job) bool {
+	g, ok := j.(interface{ GetInheritGlobals() string })
+	if !ok || g.GetInheritGlobals() == "" {
+		return true
 	}
+
+	inherit, err := strconv.ParseBool(g.GetInheritGlobals())
+	return err != nil || inherit
 }
 
 func (s *Scheduler) Stop() error {
@@ -75,8 +453,28 @@ func (s *Scheduler) Stop() error {
 		return ErrAlreadyStopped
 	}
 
+	// no more executions are triggered by messages or files while waiting
+	if s.stopTriggers != nil {
+		s.stopTriggers()
+		s.stopTriggers = nil
+	}
+
 	s.wg.Wait()
 	s.cron.Stop()
+	if s.stopHistory != nil {
+		s.stopHistory()
+		s.stopHistory = nil
+	}
+
+	if s.stopPublisher != nil {
+		s.stopPublisher()
+		s.stopPublisher = nil
+	}
+
+	if s.DockerMonitor != nil {
+		s.DockerMonitor.Stop()
+	}
+
 	s.isRunning = false
 	return nil
 }
@@ -85,26 +483,93 @@ func (s *Scheduler) IsRunning() bool {
 	return s.isRunning
 }
 
+// setRunning tracks the executions in progress, listed by State
+func (s *Scheduler) setRunning(ctx *Context, running bool) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if running {
+		s.running[ctx] = true
+		return
+	}
+
+	delete(s.running, ctx)
+}
+
 type jobWrapper struct {
 	s *Scheduler
 	j Job
 }
 
 func (w *jobWrapper) Run() {
+	scheduled := w.scheduledTime()
+	missed := w.missedTimes(scheduled)
+
+	for _, t := range append([]time.Time{scheduled}, missed...) {
+		if w.s.takeSkip(w.j, t) {
+			continue
+		}
+
+		w.runWith(t, nil)
+	}
+
+	if !JobAt(w.j).IsZero() {
+		w.s.complete(w.j)
+	}
+
+	if JobAfter(w.j) > 0 {
+		w.s.reschedule(w.j)
+	}
+}
+
+// runWith runs the job with the given params, along with the matrix value,
+// scheduled at the given time if any
+func (w *jobWrapper) runWith(scheduled time.Time, params map[string]string) {
 	w.s.wg.Add(1)
 	defer w.s.wg.Done()
 
+	matrix := JobMatrix(w.j)
+	if len(matrix) == 0 {
+		w.run(scheduled, params)
+		return
+	}
+
+	// one execution per matrix value, one after the other
+	for _, v := range matrix {
+		p := map[string]string{}
+		for k, v := range params {
+			p[k] = v
+		}
+
+		p[MatrixParam] = v
+		w.run(scheduled, p)
+	}
+}
+
+func (w *jobWrapper) run(scheduled time.Time, params map[string]string) {
 	e := NewExecution()
+	e.Params = params
+	e.ScheduledAt = scheduled
 	ctx := NewContext(w.s, w.j, e)
 
 	w.start(ctx)
+	w.s.setRunning(ctx, true)
 	err := ctx.Next()
+	w.s.setRunning(ctx, false)
 	w.stop(ctx, err)
 }
 
 func (w *jobWrapper) start(ctx *Context) {
 	ctx.Start()
-	ctx.Log("Started - " + ctx.Job.GetCommand())
+
+	cmd, err := ctx.Render(ctx.Job.GetCommand())
+	if err != nil {
+		cmd = ctx.Job.GetCommand()
+	}
+
+	// logged first, so the message is part of the started execution
+	ctx.Log("Started - " + cmd)
+	w.s.Events.Publish(&ExecutionStarted{Job: ctx.Job, Execution: ctx.Execution})
 }
 
 func (w *jobWrapper) stop(ctx *Context, err error) {
@@ -129,4 +594,15 @@ func (w *jobWrapper) stop(ctx *Context, err error) {
 	)
 
 	ctx.Log(msg)
+
+	if w.s.Recent != nil {
+		w.s.Recent.Add(ctx.Job, ctx.Execution)
+	}
+
+	if ctx.Execution.Skipped {
+		w.s.Events.Publish(&ExecutionSkipped{Job: ctx.Job, Execution: ctx.Execution})
+		return
+	}
+
+	w.s.Events.Publish(&ExecutionFinished{Job: ctx.Job, Execution: ctx.Execution})
 }
@@ -24,6 +24,20 @@ func (s *SuiteScheduler) TestAddJob(c *C) {
 	c.Assert(e[0].Job.(*jobWrapper).j, DeepEquals, job)
 }
 
+func (s *SuiteScheduler) TestRemoveJob(c *C) {
+	job := &TestJob{}
+	job.Schedule = "@hourly"
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), IsNil)
+
+	c.Assert(sc.RemoveJob(job), IsNil)
+	c.Assert(sc.Jobs, HasLen, 0)
+	c.Assert(sc.cron.Entries(), HasLen, 0)
+
+	c.Assert(sc.RemoveJob(job), Equals, ErrJobNotFound)
+}
+
 func (s *SuiteScheduler) TestStartStop(c *C) {
 	job := &TestJob{}
 	job.Schedule = "@every 1s"
@@ -70,3 +84,88 @@ func (s *SuiteScheduler) TestMergeMiddlewaresSame(c *C) {
 	c.Assert(m, HasLen, 1)
 	c.Assert(m[0], Equals, mB)
 }
+
+func (s *SuiteScheduler) TestMergeMiddlewaresInheritGlobals(c *C) {
+	mA, mB := &TestMiddleware{}, &TestMiddlewareAltA{}
+
+	inherit := &TestJob{}
+	inherit.Schedule = "@every 1s"
+	inherit.Use(mB)
+
+	own := &TestJob{}
+	own.Schedule = "@every 1s"
+	own.InheritGlobals = "false"
+	own.Use(mB)
+
+	sc := NewScheduler(&TestLogger{})
+	sc.Use(mA)
+	sc.AddJob(inherit)
+	sc.AddJob(own)
+	sc.mergeMiddlewares()
+
+	c.Assert(inherit.Middlewares(), DeepEquals, []Middleware{mB, mA})
+	c.Assert(own.Middlewares(), DeepEquals, []Middleware{mB})
+}
+
+func (s *SuiteScheduler) TestMergeMiddlewaresJobOverridesGlobal(c *C) {
+	mA, mB, mC := &TestMiddleware{}, &TestMiddleware{Nested: true}, &TestMiddleware{}
+
+	job := &TestJob{}
+	job.Schedule = "@every 1s"
+	job.Use(mA)
+
+	sc := NewScheduler(&TestLogger{})
+	sc.JobOverridesGlobal = false
+	sc.Use(mB, &TestMiddlewareAltA{})
+	sc.AddJob(job)
+	sc.mergeMiddlewares()
+
+	m := job.Middlewares()
+	c.Assert(m, HasLen, 3)
+	c.Assert(m[0], Equals, mA)
+	c.Assert(m[1], Equals, mB)
+
+	// equal to the middleware of the job
+	job.UseDistinct(mC)
+	c.Assert(job.Middlewares(), HasLen, 3)
+}
+
+func (s *SuiteScheduler) TestJobInheritGlobals(c *C) {
+	j := &TestJob{}
+	c.Assert(JobInheritGlobals(j), Equals, true)
+
+	j.InheritGlobals = "false"
+	c.Assert(JobInheritGlobals(j), Equals, false)
+
+	j.InheritGlobals = "true"
+	c.Assert(JobInheritGlobals(j), Equals, true)
+}
+
+func (s *SuiteScheduler) TestRunMatrix(c *C) {
+	job := &TestJob{}
+	job.Schedule = "@hourly"
+	job.Matrix = []string{"foo", "bar"}
+
+	m := &TestMiddleware{}
+	job.Use(m)
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), IsNil)
+
+	w := &jobWrapper{sc, job}
+	w.Run()
+	c.Assert(m.Called, Equals, 2)
+}
+
+func (s *SuiteScheduler) TestRunMatrixWithParams(c *C) {
+	job := &paramsJob{params: make(chan map[string]string, 2)}
+	job.Matrix = []string{"foo", "bar"}
+
+	sc := NewScheduler(&TestLogger{})
+	sc.AddTriggeredJob(job)
+
+	w := &jobWrapper{sc, job}
+	w.runWith(time.Time{}, map[string]string{PayloadParam: "baz"})
+	c.Assert(<-job.params, DeepEquals, map[string]string{MatrixParam: "foo", PayloadParam: "baz"})
+	c.Assert(<-job.params, DeepEquals, map[string]string{MatrixParam: "bar", PayloadParam: "baz"})
+}
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLabelsSecret a job declared by docker labels references a secret
+var ErrLabelsSecret = errors.New("secrets can't be referenced by the jobs of the docker labels")
+
+// SecretProvider reads the secrets of a backend, e.g. HashiCorp Vault
+type SecretProvider interface {
+	// Secret returns the value of the secret at the given path, the field of
+	// its JSON object if not empty, otherwise its whole value
+	Secret(path, field string) (string, error)
+}
+
+// Secrets resolves the values of the config referencing a secret, as
+// "<provider>:<path>#<field>", e.g. "vault:kv/data/backup#password", with
+// the providers registered. The secrets are read at execution time and
+// cached in memory for the TTL, never written to disk.
+type Secrets struct {
+	TTL   time.Duration
+	Clock Clock
+
+	providers map[string]SecretProvider
+	mu        sync.Mutex
+	cache     map[string]*cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// NewSecrets returns a Secrets caching the secrets for the given TTL, not
+// cached if zero
+func NewSecrets(ttl time.Duration) *Secrets {
+	return &Secrets{
+		TTL:       ttl,
+		Clock:     systemClock{},
+		providers: make(map[string]SecretProvider),
+		cache:     make(map[string]*cachedSecret),
+	}
+}
+
+// Register registers the provider of the references with the given prefix,
+// e.g. "vault"
+func (s *Secrets) Register(prefix string, p SecretProvider) {
+	s.providers[prefix] = p
+}
+
+// IsReference returns if the given value references a secret of a registered
+// provider
+func (s *Secrets) IsReference(value string) bool {
+	_, _, _, ok := s.parse(value)
+	return ok
+}
+
+// Resolve returns the secret referenced by the given value, the value itself
+// if it isn't a reference
+func (s *Secrets) Resolve(value string) (string, error) {
+	p, path, field, ok := s.parse(value)
+	if !ok {
+		return value, nil
+	}
+
+	now := s.Clock.Now()
+	s.mu.Lock()
+	c, cached := s.cache[value]
+	s.mu.Unlock()
+
+	if cached && now.Before(c.expires) {
+		return c.value, nil
+	}
+
+	secret, err := p.Secret(path, field)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret %q: %s", value, err)
+	}
+
+	if s.TTL > 0 {
+		s.mu.Lock()
+		s.cache[value] = &cachedSecret{value: secret, expires: now.Add(s.TTL)}
+		s.mu.Unlock()
+	}
+
+	return secret, nil
+}
+
+func (s *Secrets) parse(value string) (p SecretProvider, path, field string, ok bool) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, "", "", false
+	}
+
+	if p, ok = s.providers[parts[0]]; !ok {
+		return nil, "", "", false
+	}
+
+	path = parts[1]
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		path, field = path[:i], path[i+1:]
+	}
+
+	return p, path, field, true
+}
+
+// resolveEnv resolves the values of the given environment variables
+// referencing a secret, adding the secrets to the given redactor if any
+func (s *Secrets) resolveEnv(env []string, r *Redactor) ([]string, error) {
+	if len(env) == 0 {
+		return env, nil
+	}
+
+	resolved := make([]string, len(env))
+	for i, v := range env {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || !s.IsReference(parts[1]) {
+			resolved[i] = v
+			continue
+		}
+
+		secret, err := s.Resolve(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if r != nil {
+			r.AddSecret(secret)
+		}
+
+		resolved[i] = parts[0] + "=" + secret
+	}
+
+	return resolved, nil
+}
+
+// secretField returns the given field of the secret, a JSON object, or the
+// secret itself if no field is given
+func secretField(secret []byte, field string) (string, error) {
+	if field == "" {
+		return string(secret), nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(secret, &fields); err != nil {
+		return "", fmt.Errorf("the secret isn't a JSON object: %s", err)
+	}
+
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("missing field %q", field)
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b), nil
+}
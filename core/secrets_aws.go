@@ -0,0 +1,36 @@
+package core
+
+// awsSecretsService name of the AWS Secrets Manager service, signing the
+// requests
+const awsSecretsService = "secretsmanager"
+
+// AWSSecretsProvider reads the secrets of AWS Secrets Manager, the path being
+// the name or ARN of the secret. The credentials are read from the AWS
+// environment variables, the AWS credentials file or the IAM role.
+type AWSSecretsProvider struct {
+	*AWSClient
+}
+
+// NewAWSSecretsProvider returns a AWSSecretsProvider of the given region
+func NewAWSSecretsProvider(region string) *AWSSecretsProvider {
+	return &AWSSecretsProvider{AWSClient: NewAWSClient(region)}
+}
+
+// Secret returns the field of the string secret with the given name
+func (p *AWSSecretsProvider) Secret(path, field string) (string, error) {
+	var secret struct {
+		SecretString string
+		SecretBinary []byte
+	}
+
+	in := map[string]string{"SecretId": path}
+	if err := p.Call(awsSecretsService, "secretsmanager.GetSecretValue", in, &secret); err != nil {
+		return "", err
+	}
+
+	if secret.SecretString == "" {
+		return secretField(secret.SecretBinary, field)
+	}
+
+	return secretField([]byte(secret.SecretString), field)
+}
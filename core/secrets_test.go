@@ -0,0 +1,172 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSecrets struct{}
+
+var _ = Suite(&SuiteSecrets{})
+
+type testSecretProvider struct {
+	secrets map[string]string
+	reads   int
+}
+
+func (p *testSecretProvider) Secret(path, field string) (string, error) {
+	p.reads++
+	v, ok := p.secrets[path+"#"+field]
+	if !ok {
+		return "", errors.New("not found")
+	}
+
+	return v, nil
+}
+
+func (s *SuiteSecrets) TestResolve(c *C) {
+	now := time.Now()
+	clock := NewSimulatedClock(now)
+	p := &testSecretProvider{secrets: map[string]string{"kv/data/backup#password": "s3cr3t"}}
+
+	secrets := NewSecrets(time.Minute)
+	secrets.Clock = clock
+	secrets.Register("vault", p)
+
+	v, err := secrets.Resolve("vault:kv/data/backup#password")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "s3cr3t")
+
+	v, err = secrets.Resolve("plain:value")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "plain:value")
+
+	_, err = secrets.Resolve("vault:kv/data/missing#password")
+	c.Assert(err, ErrorMatches, `error reading secret "vault:kv/data/missing#password": not found`)
+
+	// cached until the TTL expires
+	p.secrets["kv/data/backup#password"] = "rotated"
+	v, _ = secrets.Resolve("vault:kv/data/backup#password")
+	c.Assert(v, Equals, "s3cr3t")
+
+	clock.Set(now.Add(2 * time.Minute))
+	v, _ = secrets.Resolve("vault:kv/data/backup#password")
+	c.Assert(v, Equals, "rotated")
+	c.Assert(p.reads, Equals, 3)
+}
+
+func (s *SuiteSecrets) TestEnv(c *C) {
+	p := &testSecretProvider{secrets: map[string]string{"kv/data/backup#password": "s3cr3t"}}
+	sched := NewScheduler(&TestLogger{})
+	sched.Redactor = NewRedactor()
+	sched.Secrets = NewSecrets(0)
+	sched.Secrets.Register("vault", p)
+
+	j := &LocalJob{}
+	j.Environment = []string{"PASSWORD=vault:kv/data/backup#password", "USER=backup"}
+
+	ctx := NewContext(sched, j, NewExecution())
+	env, err := ctx.localEnv(j.Environment)
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"PASSWORD=s3cr3t", "USER=backup"})
+	c.Assert(sched.Redactor.Redact("password s3cr3t"), Equals, "password "+RedactedMask)
+}
+
+func (s *SuiteSecrets) TestEnvDockerLabels(c *C) {
+	p := &testSecretProvider{secrets: map[string]string{"kv/data/backup#password": "s3cr3t"}}
+	sched := NewScheduler(&TestLogger{})
+	sched.Secrets = NewSecrets(0)
+	sched.Secrets.Register("vault", p)
+
+	j := &LocalJob{}
+	j.DockerLabels = true
+	j.Environment = []string{"USER=backup"}
+
+	ctx := NewContext(sched, j, NewExecution())
+	env, err := ctx.localEnv(j.Environment)
+	c.Assert(err, IsNil)
+	c.Assert(env, DeepEquals, []string{"USER=backup"})
+
+	j.Environment = []string{"PASSWORD=vault:kv/data/backup#password"}
+	_, err = ctx.localEnv(j.Environment)
+	c.Assert(err, ErrorMatches, "PASSWORD: secrets can't be referenced by the jobs of the docker labels")
+	c.Assert(p.reads, Equals, 0)
+}
+
+func (s *SuiteSecrets) TestVaultProvider(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("X-Vault-Token"), Equals, "token")
+		switch r.URL.Path {
+		case "/v1/kv/data/backup":
+			w.Write([]byte(`{"data": {"data": {"password": "s3cr3t"}, "metadata": {"version": 1}}}`))
+		case "/v1/secret/backup":
+			w.Write([]byte(`{"data": {"password": "v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ts.Close()
+
+	p := NewVaultProvider(ts.URL+"/", "token")
+	v, err := p.Secret("kv/data/backup", "password")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "s3cr3t")
+
+	v, err = p.Secret("secret/backup", "password")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "v1")
+
+	_, err = p.Secret("kv/data/backup", "user")
+	c.Assert(err, ErrorMatches, `missing field "user"`)
+
+	_, err = p.Secret("kv/data/missing", "password")
+	c.Assert(err, ErrorMatches, "vault returned 404 Not Found")
+}
+
+func (s *SuiteSecrets) TestAWSSecretsProvider(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("X-Amz-Target"), Equals, "secretsmanager.GetSecretValue")
+		c.Assert(strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"), Equals, true)
+
+		var req struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&req)
+		c.Assert(req.SecretId, Equals, "prod/backup")
+		w.Write([]byte(`{"SecretString": "{\"password\": \"s3cr3t\"}"}`))
+	}))
+
+	defer ts.Close()
+
+	p := NewAWSSecretsProvider("eu-west-1")
+	p.Endpoint = ts.URL
+	p.Credentials = credentials.NewStaticV4("key", "secret", "")
+
+	v, err := p.Secret("prod/backup", "password")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "s3cr3t")
+
+	v, err = p.Secret("prod/backup", "")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, `{"password": "s3cr3t"}`)
+}
+
+func (s *SuiteSecrets) TestSignAWSRequest(c *C) {
+	// example of the AWS documentation of the signature version 4
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	creds := credentials.Value{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signAWSRequest(req, nil, creds, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	c.Assert(req.Header.Get("Authorization"), Equals, "AWS4-HMAC-SHA256 "+
+		"Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7")
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// vaultTimeout timeout of the requests to Vault
+const vaultTimeout = 10 * time.Second
+
+// VaultProvider reads the secrets of the KV engines of HashiCorp Vault, the
+// path being the path of the API, e.g. "kv/data/backup" for a KV version 2
+// engine mounted at "kv"
+type VaultProvider struct {
+	Addr  string
+	Token string
+
+	client *http.Client
+}
+
+// NewVaultProvider returns a VaultProvider of the server at the given address,
+// authenticated with the given token
+func NewVaultProvider(addr, token string) *VaultProvider {
+	return &VaultProvider{
+		Addr:   strings.TrimSuffix(addr, "/"),
+		Token:  token,
+		client: &http.Client{Timeout: vaultTimeout},
+	}
+}
+
+// Secret returns the field of the secret at the given path
+func (p *VaultProvider) Secret(path, field string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, p.Addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("X-Vault-Token", p.Token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data json.RawMessage
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	// the KV version 2 engines nest the secret in a data object
+	var kv2 struct {
+		Data     json.RawMessage
+		Metadata json.RawMessage
+	}
+
+	data := body.Data
+	if json.Unmarshal(data, &kv2) == nil && kv2.Data != nil && kv2.Metadata != nil {
+		data = kv2.Data
+	}
+
+	return secretField(data, field)
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gobs/args"
+)
+
+const (
+	// SidecarNetworkShared the sidecars and the job container share the
+	// network namespace of the first sidecar, reaching each other at localhost
+	SidecarNetworkShared = "shared"
+	// SidecarNetworkJob the sidecars are connected to the network of the job,
+	// reached by their name
+	SidecarNetworkJob = "network"
+)
+
+// Sidecar a container started before the container of a job-run and removed
+// after it, e.g. a proxy to a database
+type Sidecar struct {
+	Name    string
+	Image   string
+	Command string
+}
+
+// ParseSidecar parses a sidecar declared as "<name>=<image> [<command>]", the
+// default command of the image is used if no command is given
+func ParseSidecar(s string) (*Sidecar, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return nil, fmt.Errorf("invalid sidecar %q, must be <name>=<image> [<command>]", s)
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(parts[1]), " ", 2)
+	if fields[0] == "" {
+		return nil, fmt.Errorf("invalid sidecar %q, missing image", s)
+	}
+
+	sc := &Sidecar{Name: strings.TrimSpace(parts[0]), Image: fields[0]}
+	if len(fields) == 2 {
+		sc.Command = strings.TrimSpace(fields[1])
+	}
+
+	return sc, nil
+}
+
+// SidecarError a failure of a sidecar, reported apart from the failures of the
+// job container
+type SidecarError struct {
+	Sidecar string
+	Err     error
+}
+
+func (e *SidecarError) Error() string {
+	return fmt.Sprintf("sidecar %q: %s", e.Sidecar, e.Err)
+}
+
+// runningSidecar a sidecar with its container, once created
+type runningSidecar struct {
+	*Sidecar
+	ID string
+}
+
+// ValidateSidecars checks the sidecars of the job and its sidecar-network
+func (j *RunJob) ValidateSidecars() error {
+	if len(j.Sidecar) == 0 {
+		return nil
+	}
+
+	if j.Container != "" {
+		return ErrSidecarExistingContainer
+	}
+
+	names := make(map[string]bool)
+	for _, s := range j.Sidecar {
+		sc, err := ParseSidecar(s)
+		if err != nil {
+			return err
+		}
+
+		if names[sc.Name] {
+			return fmt.Errorf("duplicated sidecar %q", sc.Name)
+		}
+
+		names[sc.Name] = true
+	}
+
+	_, err := j.sidecarNetwork()
+	return err
+}
+
+// sidecarNetwork returns the sidecar-network of the job, shared by default
+func (j *RunJob) sidecarNetwork() (string, error) {
+	switch j.SidecarNetwork {
+	case "", SidecarNetworkShared:
+		return SidecarNetworkShared, nil
+	case SidecarNetworkJob:
+		if j.Network == "" {
+			return "", fmt.Errorf("sidecar-network %q requires network", SidecarNetworkJob)
+		}
+
+		return SidecarNetworkJob, nil
+	}
+
+	return "", fmt.Errorf("invalid sidecar-network %q, must be %q or %q", j.SidecarNetwork, SidecarNetworkShared, SidecarNetworkJob)
+}
+
+// startSidecars starts the sidecars of the job in order, returning the network
+// mode of the job container. The sidecars started are returned even on error,
+// to be removed.
+func (j *RunJob) startSidecars(ctx *Context) ([]*runningSidecar, string, error) {
+	if len(j.Sidecar) == 0 {
+		return nil, "", nil
+	}
+
+	mode, err := j.sidecarNetwork()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var sidecars []*runningSidecar
+	var networkMode string
+	for _, s := range j.Sidecar {
+		sc, err := ParseSidecar(s)
+		if err != nil {
+			return sidecars, "", err
+		}
+
+		if err := j.ensureImage(ctx, sc.Image); err != nil {
+			return sidecars, "", &SidecarError{Sidecar: sc.Name, Err: err}
+		}
+
+		id, err := j.createSidecar(sc, networkMode, ctx.Volumes)
+		if err != nil {
+			return sidecars, "", &SidecarError{Sidecar: sc.Name, Err: err}
+		}
+
+		sidecars = append(sidecars, &runningSidecar{Sidecar: sc, ID: id})
+		if networkMode == "" && j.Network != "" {
+			var aliases []string
+			if mode == SidecarNetworkJob {
+				aliases = []string{sc.Name}
+			}
+
+			if err := j.connectNetwork(ctx, id, aliases); err != nil {
+				return sidecars, "", &SidecarError{Sidecar: sc.Name, Err: err}
+			}
+		}
+
+		if err := j.Client.StartContainer(id, nil); err != nil {
+			return sidecars, "", &SidecarError{Sidecar: sc.Name, Err: fmt.Errorf("error starting container: %s", err)}
+		}
+
+		ctx.Log(fmt.Sprintf("Started sidecar %q, image %s", sc.Name, sc.Image))
+		if mode == SidecarNetworkShared && networkMode == "" {
+			networkMode = "container:" + id
+		}
+	}
+
+	return sidecars, networkMode, nil
+}
+
+// createSidecar creates the container of the sidecar, with the extra binds of
+// the execution, e.g. its workspace
+func (j *RunJob) createSidecar(sc *Sidecar, networkMode string, binds []string) (string, error) {
+	var cmd []string
+	if sc.Command != "" {
+		cmd = args.GetArgs(sc.Command)
+	}
+
+	// not retried, a timed out creation may have created the container
+	var c *docker.Container
+	err := j.call(func(tctx context.Context) (err error) {
+		c, err = j.Client.CreateContainer(docker.CreateContainerOptions{
+			Config: &docker.Config{
+				Image:  sc.Image,
+				Cmd:    cmd,
+				Labels: j.GetProvenance().Labels(),
+			},
+			HostConfig: &docker.HostConfig{
+				Binds:       binds,
+				NetworkMode: networkMode,
+			},
+			Context: tctx,
+		})
+
+		return
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("error creating container: %s", err)
+	}
+
+	return c.ID, nil
+}
+
+// checkSidecars returns an error if a sidecar stopped before the end of the
+// job container
+func (j *RunJob) checkSidecars(ctx *Context, sidecars []*runningSidecar) error {
+	for _, sc := range sidecars {
+		c, err := j.getContainer(ctx, sc.ID)
+		if err != nil {
+			return &SidecarError{Sidecar: sc.Name, Err: err}
+		}
+
+		if !c.State.Running {
+			return &SidecarError{
+				Sidecar: sc.Name,
+				Err:     fmt.Errorf("stopped during the job, exit code %d", c.State.ExitCode),
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeSidecars removes the containers of the sidecars, killing them if
+// running, in the reverse order of their start
+func (j *RunJob) removeSidecars(ctx *Context, sidecars []*runningSidecar) {
+	for i := len(sidecars) - 1; i >= 0; i-- {
+		sc := sidecars[i]
+		err := j.retry(ctx, "removing sidecar", func(c context.Context) error {
+			return j.Client.RemoveContainer(docker.RemoveContainerOptions{
+				ID:      sc.ID,
+				Force:   true,
+				Context: c,
+			})
+		})
+
+		if err != nil {
+			ctx.Warn(fmt.Sprintf("failed to remove sidecar %q: %s", sc.Name, err))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	logging "github.com/op/go-logging"
+	. "gopkg.in/check.v1"
+)
+
+func (s *SuiteRunJob) TestParseSidecar(c *C) {
+	sc, err := ParseSidecar("proxy=gcr.io/proxy:1.33 /proxy -port 5432")
+	c.Assert(err, IsNil)
+	c.Assert(sc, DeepEquals, &Sidecar{Name: "proxy", Image: "gcr.io/proxy:1.33", Command: "/proxy -port 5432"})
+
+	sc, err = ParseSidecar(" redis = redis:6 ")
+	c.Assert(err, IsNil)
+	c.Assert(sc, DeepEquals, &Sidecar{Name: "redis", Image: "redis:6"})
+
+	_, err = ParseSidecar("redis:6")
+	c.Assert(err, ErrorMatches, `invalid sidecar "redis:6", must be .*`)
+
+	_, err = ParseSidecar("redis=")
+	c.Assert(err, ErrorMatches, `invalid sidecar "redis=", missing image`)
+}
+
+func (s *SuiteRunJob) TestValidateSidecars(c *C) {
+	j := &RunJob{Sidecar: []string{"a=foo", "b=bar"}}
+	c.Assert(j.ValidateSidecars(), IsNil)
+
+	j.Sidecar = []string{"a=foo", "a=bar"}
+	c.Assert(j.ValidateSidecars(), ErrorMatches, `duplicated sidecar "a"`)
+
+	j.Sidecar = []string{"a=foo"}
+	j.SidecarNetwork = SidecarNetworkJob
+	c.Assert(j.ValidateSidecars(), ErrorMatches, `sidecar-network "network" requires network`)
+
+	j.SidecarNetwork = "host"
+	c.Assert(j.ValidateSidecars(), ErrorMatches, `invalid sidecar-network "host", .*`)
+
+	j.SidecarNetwork = ""
+	j.Container = "foo"
+	c.Assert(j.ValidateSidecars(), Equals, ErrSidecarExistingContainer)
+}
+
+// runSidecar runs a job with a sidecar, stopping the sidecar too during the
+// job if stopSidecar is true
+func (s *SuiteRunJob) runSidecar(c *C, stopSidecar bool) error {
+	job := &RunJob{Client: s.client}
+	job.Image = ImageFixture
+	job.Command = "echo foo"
+	job.Delete = "true"
+	job.Network = "foo"
+	job.Sidecar = []string{"proxy=" + ImageFixture + " proxy --port 5432"}
+	job.Name = "test"
+
+	ctx := &Context{}
+	ctx.Execution = NewExecution()
+	ctx.Logger = logging.MustGetLogger("ofelia")
+	ctx.Job = job
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		time.Sleep(time.Millisecond * 200)
+
+		containers, err := s.client.ListContainers(docker.ListContainersOptions{})
+		c.Assert(err, IsNil)
+		c.Assert(containers, HasLen, 2)
+
+		for _, container := range containers {
+			if container.Command == "echo foo" || stopSidecar {
+				c.Assert(s.client.StopContainer(container.ID, 0), IsNil)
+			}
+		}
+	}()
+
+	err := job.Run(ctx)
+	wg.Wait()
+
+	containers, lerr := s.client.ListContainers(docker.ListContainersOptions{All: true})
+	c.Assert(lerr, IsNil)
+	c.Assert(containers, HasLen, 0)
+
+	return err
+}
+
+func (s *SuiteRunJob) TestRunSidecar(c *C) {
+	c.Assert(s.runSidecar(c, false), IsNil)
+}
+
+func (s *SuiteRunJob) TestRunSidecarStopped(c *C) {
+	err := s.runSidecar(c, true)
+	c.Assert(err, FitsTypeOf, &SidecarError{})
+	c.Assert(err, ErrorMatches, `sidecar "proxy": stopped during the job, exit code 0`)
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSilenceNotFound the silence to lift doesn't exist or already expired
+var ErrSilenceNotFound = errors.New("unable to find the silence")
+
+// Silence suppresses the notifications of the jobs, all of them or the ones
+// with any of its tags, until it expires, e.g. during a planned maintenance.
+// The executions still run and are recorded.
+type Silence struct {
+	ID string
+	// Actor who silenced the notifications, e.g. "socket"
+	Actor string
+	Date  time.Time
+	Until time.Time
+	// Tags when set, only the jobs with any of them are silenced, see
+	// JobHasTags
+	Tags []string `json:",omitempty"`
+}
+
+// silences returns true if the silence applies to the job at the given time
+func (s *Silence) silences(j Job, now time.Time) bool {
+	if !now.Before(s.Until) {
+		return false
+	}
+
+	return len(s.Tags) == 0 || JobHasTags(j, s.Tags)
+}
+
+// Silence suppresses the notifications of the jobs with any of the given
+// tags, or of all the jobs without tags, for the given duration, recording it
+// in the audit log
+func (s *Scheduler) Silence(actor string, d time.Duration, tags []string) *Silence {
+	now := s.Clock.Now()
+	silence := &Silence{ID: randomID(), Actor: actor, Date: now, Until: now.Add(d), Tags: tags}
+
+	s.silencesMu.Lock()
+	s.silences = append(s.activeSilences(now), silence)
+	s.silencesMu.Unlock()
+
+	e := &AuditEntry{Actor: actor, Action: AuditSilence}
+	e.addChange("until", AuditChange{After: silence.Until})
+	if len(tags) > 0 {
+		e.addChange("tags", AuditChange{After: tags})
+	}
+
+	s.Record(e)
+	s.Logger.Noticef("Notifications silenced until %s by %s", silence.Until.Format(time.RFC3339), actor)
+	return silence
+}
+
+// Unsilence lifts the silence with the given ID before it expires, recording
+// it in the audit log
+func (s *Scheduler) Unsilence(actor, id string) error {
+	s.silencesMu.Lock()
+	active := s.activeSilences(s.Clock.Now())
+	var lifted *Silence
+	for i, silence := range active {
+		if silence.ID == id {
+			lifted = silence
+			active = append(active[:i], active[i+1:]...)
+			break
+		}
+	}
+
+	s.silences = active
+	s.silencesMu.Unlock()
+
+	if lifted == nil {
+		return ErrSilenceNotFound
+	}
+
+	e := &AuditEntry{Actor: actor, Action: AuditUnsilence}
+	e.addChange("until", AuditChange{Before: lifted.Until})
+	if len(lifted.Tags) > 0 {
+		e.addChange("tags", AuditChange{Before: lifted.Tags})
+	}
+
+	s.Record(e)
+	s.Logger.Noticef("Notifications silence %s lifted by %s", id, actor)
+	return nil
+}
+
+// Silences returns the silences not expired yet
+func (s *Scheduler) Silences() []Silence {
+	s.silencesMu.Lock()
+	defer s.silencesMu.Unlock()
+
+	s.silences = s.activeSilences(s.Clock.Now())
+	silences := make([]Silence, len(s.silences))
+	for i, silence := range s.silences {
+		silences[i] = *silence
+	}
+
+	return silences
+}
+
+// Silenced returns true if the notifications of the given job are silenced
+func (s *Scheduler) Silenced(j Job) bool {
+	s.silencesMu.Lock()
+	defer s.silencesMu.Unlock()
+
+	now := s.Clock.Now()
+	for _, silence := range s.silences {
+		if silence.silences(j, now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// activeSilences returns the silences not expired at the given time, must be
+// called with the lock held
+func (s *Scheduler) activeSilences(now time.Time) []*Silence {
+	var active []*Silence
+	for _, silence := range s.silences {
+		if now.Before(silence.Until) {
+			active = append(active, silence)
+		}
+	}
+
+	return active
+}
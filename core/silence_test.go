@@ -0,0 +1,66 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSilence struct{}
+
+var _ = Suite(&SuiteSilence{})
+
+func (s *SuiteSilence) TestSilence(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	audit := &memoryAuditLog{}
+	sc := NewScheduler(&TestLogger{}, WithClock(fixedClock(now)))
+	sc.Audit = audit
+
+	db, web := &TestJob{}, &TestJob{}
+	db.Tags = []string{"role=db"}
+	web.Tags = []string{"role=web"}
+
+	silence := sc.Silence("socket", 30*time.Minute, []string{"role=db"})
+	c.Assert(silence.Until, Equals, now.Add(30*time.Minute))
+	c.Assert(sc.Silenced(db), Equals, true)
+	c.Assert(sc.Silenced(web), Equals, false)
+
+	all := sc.Silence("socket", time.Hour, nil)
+	c.Assert(sc.Silenced(web), Equals, true)
+	c.Assert(sc.Silences(), HasLen, 2)
+
+	c.Assert(sc.Unsilence("socket", all.ID), IsNil)
+	c.Assert(sc.Unsilence("socket", all.ID), Equals, ErrSilenceNotFound)
+	c.Assert(sc.Silenced(web), Equals, false)
+
+	// expired
+	sc.Clock = fixedClock(now.Add(30 * time.Minute))
+	c.Assert(sc.Silenced(db), Equals, false)
+	c.Assert(sc.Silences(), HasLen, 0)
+	c.Assert(sc.Unsilence("socket", silence.ID), Equals, ErrSilenceNotFound)
+
+	c.Assert(audit.entries, HasLen, 3)
+	c.Assert(audit.entries[0].Action, Equals, AuditSilence)
+	c.Assert(audit.entries[0].Diff["tags"].After, DeepEquals, []string{"role=db"})
+	c.Assert(audit.entries[2].Action, Equals, AuditUnsilence)
+	c.Assert(audit.entries[2].Diff["until"].Before, Equals, now.Add(time.Hour))
+}
+
+func (s *SuiteSilence) TestJobHasTags(c *C) {
+	j := &TestJob{}
+	j.Tags = []string{"role=db", "env=production"}
+
+	c.Assert(JobHasTags(j, []string{"role"}), Equals, true)
+	c.Assert(JobHasTags(j, []string{"role=web", "env=production"}), Equals, true)
+	c.Assert(JobHasTags(j, []string{"role=web"}), Equals, false)
+	c.Assert(JobHasTags(j, nil), Equals, false)
+}
+
+type memoryAuditLog struct {
+	entries []*AuditEntry
+}
+
+func (l *memoryAuditLog) Record(e *AuditEntry) error {
+	l.entries = append(l.entries, e)
+	return nil
+}
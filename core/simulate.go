@@ -0,0 +1,153 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SimulatedClock is a Clock set manually, used to fast-forward the schedules,
+// it is a TimerClock
+type SimulatedClock struct {
+	mu     sync.RWMutex
+	now    time.Time
+	timers map[*simulatedTimer]bool
+}
+
+// NewSimulatedClock returns a SimulatedClock set to the given time
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	return &SimulatedClock{now: now}
+}
+
+// Now returns the time the clock is set to
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.now
+}
+
+// Set sets the clock to the given time, running the functions of the timers
+// due by then, in order, before returning
+func (c *SimulatedClock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+
+	var due []*simulatedTimer
+	for t := range c.timers {
+		if !t.at.After(now) {
+			due = append(due, t)
+			delete(c.timers, t)
+		}
+	}
+
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].at.Before(due[j].at)
+	})
+
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// AfterFunc runs f once the clock is set to the given duration from now, or
+// later
+func (c *SimulatedClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timers == nil {
+		c.timers = make(map[*simulatedTimer]bool)
+	}
+
+	t := &simulatedTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers[t] = true
+	return t
+}
+
+// simulatedTimer is a function run by a SimulatedClock at a given time
+type simulatedTimer struct {
+	clock *SimulatedClock
+	at    time.Time
+	f     func()
+}
+
+func (t *simulatedTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	_, ok := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return ok
+}
+
+// Firing is a scheduled execution of a job
+type Firing struct {
+	Time time.Time
+	Job  Job
+}
+
+// Simulation fast-forwards the schedules of the jobs of a scheduler, without
+// starting it
+type Simulation struct {
+	Scheduler *Scheduler
+	Clock     *SimulatedClock
+}
+
+// NewSimulation returns a Simulation of the given scheduler, the executions of
+// the scheduler are dated by the simulated clock from now on.
+func NewSimulation(s *Scheduler) *Simulation {
+	c := NewSimulatedClock(time.Time{})
+	s.Clock = c
+
+	return &Simulation{Scheduler: s, Clock: c}
+}
+
+// Firings returns the executions of the scheduled jobs between from and to,
+// both included, in chronological order, up to limit if it is greater than 0.
+// The jobs run only when triggered are not included.
+func (sim *Simulation) Firings(from, to time.Time, limit int) ([]Firing, error) {
+	var firings []Firing
+	for _, j := range sim.Scheduler.Jobs {
+		if j.GetSchedule() == "" {
+			continue
+		}
+
+		schedule, err := sim.Scheduler.schedule(j)
+		if err != nil {
+			return nil, err
+		}
+
+		var n int
+		for t := schedule.Next(from.Add(-time.Nanosecond)); !t.IsZero() && !t.After(to); t = schedule.Next(t) {
+			if limit > 0 && n >= limit {
+				break
+			}
+
+			firings = append(firings, Firing{Time: t, Job: j})
+			n++
+		}
+	}
+
+	sort.SliceStable(firings, func(i, k int) bool {
+		return firings[i].Time.Before(firings[k].Time)
+	})
+
+	if limit > 0 && len(firings) > limit {
+		firings = firings[:limit]
+	}
+
+	return firings, nil
+}
+
+// Run runs the job of the given firing, through the middlewares of the job and
+// the scheduler, with the clock set to its time. It returns once the execution
+// finishes.
+func (sim *Simulation) Run(f Firing) {
+	sim.Clock.Set(f.Time)
+	sim.Scheduler.inheritMiddlewares(f.Job)
+
+	(&jobWrapper{sim.Scheduler, f.Job}).Run()
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSimulate struct{}
+
+var _ = Suite(&SuiteSimulate{})
+
+func (s *SuiteSimulate) TestFirings(c *C) {
+	sc := NewScheduler(&TestLogger{})
+
+	hourly := &TestJob{}
+	hourly.Name = "hourly"
+	hourly.Schedule = "0 * * * *"
+	c.Assert(sc.AddJob(hourly), IsNil)
+
+	noon := &TestJob{}
+	noon.Name = "noon"
+	noon.Schedule = "30 12 * * *"
+	c.Assert(sc.AddJob(noon), IsNil)
+
+	sc.AddTriggeredJob(&TestJob{})
+
+	from := time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)
+	sim := NewSimulation(sc)
+	firings, err := sim.Firings(from, from.Add(3*time.Hour), 0)
+	c.Assert(err, IsNil)
+	c.Assert(firings, HasLen, 5)
+
+	c.Assert(firings[0].Time, Equals, from)
+	c.Assert(firings[3].Job, Equals, noon)
+	c.Assert(firings[3].Time, Equals, from.Add(150*time.Minute))
+	c.Assert(firings[4].Time, Equals, from.Add(3*time.Hour))
+
+	firings, err = sim.Firings(from, from.Add(3*time.Hour), 2)
+	c.Assert(err, IsNil)
+	c.Assert(firings, HasLen, 2)
+}
+
+func (s *SuiteSimulate) TestRun(c *C) {
+	sc := NewScheduler(&TestLogger{})
+
+	job := &TestJob{}
+	job.Name = "foo"
+	job.Schedule = "@daily"
+	c.Assert(sc.AddJob(job), IsNil)
+
+	var e *Execution
+	sc.Events.Subscribe(func(ev Event) {
+		if f, ok := ev.(*ExecutionFinished); ok {
+			e = f.Execution
+		}
+	})
+
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	NewSimulation(sc).Run(Firing{Time: at, Job: job})
+
+	c.Assert(job.Called, Equals, 1)
+	c.Assert(e, NotNil)
+	c.Assert(e.Date, Equals, at)
+}
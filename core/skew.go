@@ -0,0 +1,180 @@
+package core
+
+import (
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// minReportedSkew skews under this are the usual delay of the scheduler,
+	// not reported by the notifications
+	minReportedSkew = time.Second
+	// maxMisfireQueue maximum number of missed executions run by the queue
+	// misfire policy
+	maxMisfireQueue = 100
+)
+
+// The misfire policies, what happens to the executions starting later than
+// the max-skew of the job
+const (
+	// MisfireSkip skips the late executions
+	MisfireSkip = "skip"
+	// MisfireRunNow runs the late executions
+	MisfireRunNow = "run-now"
+	// MisfireQueue runs the late executions, and the executions missed since
+	// then, e.g. while the host was suspended, one after the other
+	MisfireQueue = "queue"
+)
+
+// StartSkews the start skew, in seconds, of the last scheduled execution of
+// every job, as the expvar "start_skew_seconds"
+var StartSkews = expvar.NewMap("start_skew_seconds")
+
+// JobMaxSkew returns the max-skew of the given job, zero if none
+func JobMaxSkew(j Job) (time.Duration, error) {
+	m, ok := j.(interface{ GetMaxSkew() string })
+	if !ok {
+		return 0, nil
+	}
+
+	return parseDuration("max-skew", m.GetMaxSkew(), 0)
+}
+
+// JobMisfire returns the misfire policy of the given job, MisfireSkip by
+// default
+func JobMisfire(j Job) (string, error) {
+	m, ok := j.(interface{ GetMisfire() string })
+	if !ok || m.GetMisfire() == "" {
+		return MisfireSkip, nil
+	}
+
+	switch m.GetMisfire() {
+	case MisfireSkip, MisfireRunNow, MisfireQueue:
+		return m.GetMisfire(), nil
+	}
+
+	return "", fmt.Errorf(
+		"invalid misfire %q, must be %q, %q or %q",
+		m.GetMisfire(), MisfireSkip, MisfireRunNow, MisfireQueue,
+	)
+}
+
+// entry returns the cron entry of the job, false if not scheduled
+func (w *jobWrapper) entry() (cron.Entry, bool) {
+	w.s.mu.RLock()
+	id, ok := w.s.entries[w.j]
+	w.s.mu.RUnlock()
+
+	if !ok {
+		return cron.Entry{}, false
+	}
+
+	return w.s.cron.Entry(id), true
+}
+
+// scheduledTime returns the time cron scheduled the running execution of the
+// job at
+func (w *jobWrapper) scheduledTime() time.Time {
+	e, ok := w.entry()
+	if !ok {
+		return time.Time{}
+	}
+
+	return e.Prev
+}
+
+// missedTimes returns the scheduled times missed after the given one, late
+// by more than the max-skew of the job, if its misfire policy is queue. The
+// "@after" jobs miss none, scheduled once from the end of every execution,
+// and their schedule fires once, read by the cron too.
+func (w *jobWrapper) missedTimes(scheduled time.Time) []time.Time {
+	max, err := JobMaxSkew(w.j)
+	if err != nil || max == 0 || scheduled.IsZero() || JobAfter(w.j) > 0 {
+		return nil
+	}
+
+	if policy, _ := JobMisfire(w.j); policy != MisfireQueue {
+		return nil
+	}
+
+	e, ok := w.entry()
+	if !ok {
+		return nil
+	}
+
+	now := w.s.Clock.Now()
+	if now.Sub(scheduled) <= max {
+		return nil
+	}
+
+	var missed []time.Time
+	for t := e.Schedule.Next(scheduled); !t.IsZero() && !t.After(now); t = e.Schedule.Next(t) {
+		missed = append(missed, t)
+	}
+
+	if len(missed) > maxMisfireQueue {
+		w.s.Logger.Warningf(
+			"Job %q missed %d executions, queueing the last %d",
+			w.j.GetName(), len(missed), maxMisfireQueue,
+		)
+
+		missed = missed[len(missed)-maxMisfireQueue:]
+	}
+
+	return missed
+}
+
+// Late returns the skew of the execution if long enough to be reported, zero
+// otherwise
+func (e *Execution) Late() time.Duration {
+	if e.Skew < minReportedSkew {
+		return 0
+	}
+
+	return e.Skew.Round(time.Millisecond)
+}
+
+// checkSkew records the skew of the scheduled execution, right before running
+// the command, skipping the execution if it exceeds the max-skew of the job
+// and its misfire policy is skip
+func (c *Context) checkSkew() error {
+	if c.Execution.ScheduledAt.IsZero() {
+		return nil
+	}
+
+	c.Execution.Skew = c.Execution.now().Sub(c.Execution.ScheduledAt)
+	f := new(expvar.Float)
+	f.Set(c.Execution.Skew.Seconds())
+	StartSkews.Set(c.Job.GetName(), f)
+	SchedulerDrift.Set(c.Execution.Skew.Seconds())
+
+	max, err := JobMaxSkew(c.Job)
+	if err != nil {
+		return err
+	}
+
+	if max == 0 || c.Execution.Skew <= max {
+		return nil
+	}
+
+	policy, err := JobMisfire(c.Job)
+	if err != nil {
+		return err
+	}
+
+	late := fmt.Sprintf(
+		"started %s after its scheduled time %s, max-skew %s",
+		c.Execution.Skew.Round(time.Millisecond), c.Execution.ScheduledAt.Format(time.RFC3339), max,
+	)
+
+	if policy != MisfireSkip {
+		c.Log("Misfired, running anyway, " + late)
+		return nil
+	}
+
+	c.Log("Skipping, " + late)
+	return ErrSkippedExecution
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSkew struct{}
+
+var _ = Suite(&SuiteSkew{})
+
+func (s *SuiteSkew) context(scheduled time.Time, maxSkew string) *Context {
+	j := &TestJob{}
+	j.Name = "skewed"
+	j.MaxSkew = maxSkew
+
+	ctx := NewContext(NewScheduler(&TestLogger{}), j, NewExecution())
+	ctx.Execution.ScheduledAt = scheduled
+	ctx.Start()
+
+	return ctx
+}
+
+func (s *SuiteSkew) TestCheckSkew(c *C) {
+	ctx := s.context(time.Now().Add(-2*time.Second), "")
+	c.Assert(ctx.checkSkew(), IsNil)
+	c.Assert(ctx.Execution.Skew >= 2*time.Second, Equals, true)
+	c.Assert(ctx.Execution.Late() >= 2*time.Second, Equals, true)
+	c.Assert(StartSkews.Get("skewed").String()[0], Equals, byte('2'))
+}
+
+func (s *SuiteSkew) TestCheckSkewTriggered(c *C) {
+	ctx := s.context(time.Time{}, "1s")
+	c.Assert(ctx.checkSkew(), IsNil)
+	c.Assert(ctx.Execution.Skew, Equals, time.Duration(0))
+}
+
+func (s *SuiteSkew) TestCheckSkewMaxSkew(c *C) {
+	ctx := s.context(time.Now().Add(-time.Minute), "10s")
+	c.Assert(ctx.checkSkew(), Equals, ErrSkippedExecution)
+
+	ctx = s.context(time.Now().Add(-time.Minute), "5m")
+	c.Assert(ctx.checkSkew(), IsNil)
+
+	ctx = s.context(time.Now(), "foo")
+	c.Assert(ctx.checkSkew(), ErrorMatches, `invalid max-skew "foo".*`)
+}
+
+func (s *SuiteSkew) TestCheckSkewMisfireRunNow(c *C) {
+	ctx := s.context(time.Now().Add(-time.Minute), "10s")
+	ctx.Job.(*TestJob).Misfire = MisfireRunNow
+	c.Assert(ctx.checkSkew(), IsNil)
+
+	ctx.Job.(*TestJob).Misfire = "foo"
+	c.Assert(ctx.checkSkew(), ErrorMatches, `invalid misfire "foo", .*`)
+}
+
+func (s *SuiteSkew) TestMissedTimes(c *C) {
+	job := &TestJob{}
+	job.Schedule = "@every 1h"
+	job.MaxSkew = "1m"
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), IsNil)
+
+	w := &jobWrapper{sc, job}
+	scheduled := time.Now().Add(-210 * time.Minute).Truncate(time.Second)
+	c.Assert(w.missedTimes(scheduled), HasLen, 0)
+
+	job.Misfire = MisfireQueue
+	missed := w.missedTimes(scheduled)
+	c.Assert(missed, HasLen, 3)
+	c.Assert(missed[0].Sub(scheduled), Equals, time.Hour)
+
+	c.Assert(w.missedTimes(time.Now()), HasLen, 0)
+
+	job.Schedule = "@every 1s"
+	c.Assert(sc.RemoveJob(job), IsNil)
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(w.missedTimes(scheduled), HasLen, maxMisfireQueue)
+}
+
+func (s *SuiteSkew) TestMissedTimesAfter(c *C) {
+	job := &TestJob{}
+	job.Schedule = "@after 1m"
+	job.MaxSkew = "1m"
+	job.Misfire = MisfireQueue
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), IsNil)
+
+	w := &jobWrapper{sc, job}
+	c.Assert(w.missedTimes(time.Now().Add(-time.Hour)), HasLen, 0)
+
+	e, ok := w.entry()
+	c.Assert(ok, Equals, true)
+	c.Assert(e.Schedule.(*afterSchedule).fired, Equals, false)
+}
+
+func (s *SuiteSkew) TestContextNextMaxSkew(c *C) {
+	ctx := s.context(time.Now().Add(-time.Minute), "10s")
+	c.Assert(ctx.Next(), IsNil)
+	c.Assert(ctx.Job.(*TestJob).Called, Equals, 0)
+	c.Assert(ctx.Execution.Skipped, Equals, true)
+}
+
+func (s *SuiteSkew) TestLate(c *C) {
+	c.Assert((&Execution{Skew: 300 * time.Millisecond}).Late(), Equals, time.Duration(0))
+	c.Assert((&Execution{Skew: 1500 * time.Millisecond}).Late(), Equals, 1500*time.Millisecond)
+}
+
+type scheduledMiddleware struct {
+	TestMiddleware
+	scheduled chan time.Time
+}
+
+func (m *scheduledMiddleware) Run(ctx *Context) error {
+	m.scheduled <- ctx.Execution.ScheduledAt
+	return ctx.Next()
+}
+
+func (s *SuiteSkew) TestScheduledTime(c *C) {
+	m := &scheduledMiddleware{scheduled: make(chan time.Time, 2)}
+	job := &TestJob{}
+	job.Schedule = "@every 1s"
+	job.Use(m)
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), IsNil)
+	c.Assert(sc.Start(), IsNil)
+	defer sc.Stop()
+
+	scheduled := <-m.scheduled
+	c.Assert(scheduled.IsZero(), Equals, false)
+	c.Assert(time.Since(scheduled) < time.Second, Equals, true)
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// JobScheduleSpread returns the window the scheduled executions of the given
+// job are spread over across the hosts, zero if none, the one of its group
+// being used then
+func JobScheduleSpread(j Job) (time.Duration, error) {
+	s, ok := j.(interface{ GetScheduleSpread() string })
+	if !ok {
+		return 0, nil
+	}
+
+	d, err := parseDuration("schedule-spread", s.GetScheduleSpread(), 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("invalid schedule-spread %q, must be a positive duration", s.GetScheduleSpread())
+	}
+
+	return d, nil
+}
+
+// HostOffset returns the delay of the scheduled executions of the given host
+// within the given window, derived from the hash of its name, so every host
+// gets its own one, the same across the restarts, to the second
+func HostOffset(host string, window time.Duration) time.Duration {
+	seconds := uint64(window / time.Second)
+	if seconds == 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(host))
+	return time.Duration(h.Sum64()%seconds) * time.Second
+}
+
+// scheduleSpread returns the delay of the scheduled executions of the given
+// job on the host of the scheduler, zero if not spread, an invalid window
+// being reported by the validation of the config instead
+func (s *Scheduler) scheduleSpread(j Job) time.Duration {
+	window, _ := JobScheduleSpread(j)
+	if g := s.GetGroup(JobGroup(j)); window == 0 && g != nil {
+		window = g.ScheduleSpread
+	}
+
+	host := s.Host
+	if host == "" {
+		host = Hostname()
+	}
+
+	return HostOffset(host, window)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSpread struct{}
+
+var _ = Suite(&SuiteSpread{})
+
+func (s *SuiteSpread) TestJobScheduleSpread(c *C) {
+	j := &TestJob{}
+	d, err := JobScheduleSpread(j)
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, time.Duration(0))
+
+	j.ScheduleSpread = "30m"
+	d, err = JobScheduleSpread(j)
+	c.Assert(err, IsNil)
+	c.Assert(d, Equals, 30*time.Minute)
+
+	j.ScheduleSpread = "-1m"
+	_, err = JobScheduleSpread(j)
+	c.Assert(err, ErrorMatches, `invalid schedule-spread "-1m", must be a positive duration`)
+}
+
+func (s *SuiteSpread) TestHostOffset(c *C) {
+	c.Assert(HostOffset("web-1", 0), Equals, time.Duration(0))
+	c.Assert(HostOffset("web-1", 500*time.Millisecond), Equals, time.Duration(0))
+
+	offsets := make(map[time.Duration]bool)
+	for _, host := range []string{"web-1", "web-2", "web-3", "db01", "edge-fra1"} {
+		offset := HostOffset(host, time.Hour)
+		c.Assert(offset >= 0 && offset < time.Hour, Equals, true)
+		c.Assert(offset%time.Second, Equals, time.Duration(0))
+		c.Assert(HostOffset(host, time.Hour), Equals, offset)
+		offsets[offset] = true
+	}
+
+	c.Assert(offsets, HasLen, 5)
+}
+
+func (s *SuiteSpread) TestAddJobScheduleSpread(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.Host = "web-1"
+	sc.AddGroup(&Group{Name: "backups", ScheduleOffset: time.Hour, ScheduleSpread: 10 * time.Minute})
+
+	from := time.Date(2020, 1, 1, 3, 0, 0, 0, time.UTC)
+	next := func(j *TestJob) time.Time {
+		c.Assert(sc.AddJob(j), IsNil)
+		return sc.cron.Entry(sc.entries[j]).Schedule.Next(from)
+	}
+
+	j := &TestJob{}
+	j.Schedule, j.ScheduleSpread = "0 4 * * *", "30m"
+	c.Assert(next(j), Equals, time.Date(2020, 1, 1, 4, 0, 0, 0, time.UTC).Add(HostOffset("web-1", 30*time.Minute)))
+
+	grouped := &TestJob{}
+	grouped.Schedule, grouped.Group = "0 4 * * *", "backups"
+	c.Assert(next(grouped), Equals, time.Date(2020, 1, 1, 5, 0, 0, 0, time.UTC).Add(HostOffset("web-1", 10*time.Minute)))
+
+	// the spread of the job replaces the one of its group
+	own := &TestJob{}
+	own.Schedule, own.Group, own.ScheduleSpread = "0 4 * * *", "backups", "30m"
+	c.Assert(next(own), Equals, time.Date(2020, 1, 1, 5, 0, 0, 0, time.UTC).Add(HostOffset("web-1", 30*time.Minute)))
+
+	invalid := &TestJob{}
+	invalid.Schedule, invalid.ScheduleSpread = "0 4 * * *", "soon"
+	c.Assert(next(invalid), Equals, time.Date(2020, 1, 1, 4, 0, 0, 0, time.UTC))
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sqlDialect the statements of a SQL database
+type sqlDialect struct {
+	// placeholder returns the placeholder of the n-th param, from 1
+	placeholder func(n int) string
+	// migrations to the schema, applied in order, the version of the schema
+	// is the number of applied migrations
+	migrations []string
+}
+
+var sqlDialects = map[string]*sqlDialect{
+	"postgres": {
+		placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		migrations: []string{`
+			CREATE TABLE ofelia_executions (
+				id BIGSERIAL PRIMARY KEY,
+				host VARCHAR(255) NOT NULL,
+				job VARCHAR(255) NOT NULL,
+				execution_id VARCHAR(64) NOT NULL,
+				date TIMESTAMP WITH TIME ZONE NOT NULL,
+				duration_ms BIGINT NOT NULL,
+				failed BOOLEAN NOT NULL,
+				skipped BOOLEAN NOT NULL,
+				error TEXT NOT NULL
+			)`,
+			`CREATE INDEX ofelia_executions_job_date ON ofelia_executions (job, date)`,
+		},
+	},
+	"mysql": {
+		placeholder: func(int) string { return "?" },
+		migrations: []string{`
+			CREATE TABLE ofelia_executions (
+				id BIGINT AUTO_INCREMENT PRIMARY KEY,
+				host VARCHAR(255) NOT NULL,
+				job VARCHAR(255) NOT NULL,
+				execution_id VARCHAR(64) NOT NULL,
+				date DATETIME(6) NOT NULL,
+				duration_ms BIGINT NOT NULL,
+				failed BOOLEAN NOT NULL,
+				skipped BOOLEAN NOT NULL,
+				error TEXT NOT NULL
+			)`,
+			`CREATE INDEX ofelia_executions_job_date ON ofelia_executions (job, date)`,
+		},
+	},
+}
+
+// SQLHistoryStore stores the executions in a SQL database, PostgreSQL or
+// MySQL, in the table ofelia_executions. The schema is created and migrated
+// when the store is opened, its version is kept in the table ofelia_schema.
+type SQLHistoryStore struct {
+	db      *sql.DB
+	dialect *sqlDialect
+	insert  string
+}
+
+// NewSQLHistoryStore opens the database with the given driver, "postgres" or
+// "mysql", migrating its schema to the last version. The driver has to be
+// registered, e.g. importing it.
+func NewSQLHistoryStore(driver, dsn string) (*SQLHistoryStore, error) {
+	dialect, ok := sqlDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported history driver %q, must be \"postgres\" or \"mysql\"", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSQLHistoryStore(db, dialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func newSQLHistoryStore(db *sql.DB, dialect *sqlDialect) (*SQLHistoryStore, error) {
+	s := &SQLHistoryStore{db: db, dialect: dialect}
+	if err := s.migrate(); err != nil {
+		return nil, fmt.Errorf("unable to migrate the history schema: %s", err)
+	}
+
+	columns := []string{"host", "job", "execution_id", "date", "duration_ms", "failed", "skipped", "error"}
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = dialect.placeholder(i + 1)
+	}
+
+	s.insert = fmt.Sprintf(
+		"INSERT INTO ofelia_executions (%s) VALUES (%s)",
+		strings.Join(columns, ", "), strings.Join(placeholders, ", "),
+	)
+
+	return s, nil
+}
+
+// migrate applies the migrations not applied yet, each one in a transaction
+func (s *SQLHistoryStore) migrate() error {
+	if _, err := s.db.Exec("CREATE TABLE IF NOT EXISTS ofelia_schema (version INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+
+	var version int
+	err := s.db.QueryRow("SELECT version FROM ofelia_schema").Scan(&version)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := s.db.Exec("INSERT INTO ofelia_schema (version) VALUES (0)"); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	for i := version; i < len(s.dialect.migrations); i++ {
+		if err := s.apply(i); err != nil {
+			return fmt.Errorf("migration %d: %s", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLHistoryStore) apply(i int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(s.dialect.migrations[i]); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	update := "UPDATE ofelia_schema SET version = " + s.dialect.placeholder(1)
+	if _, err := tx.Exec(update, i+1); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Store inserts the record
+func (s *SQLHistoryStore) Store(r *HistoryRecord) error {
+	_, err := s.db.Exec(s.insert,
+		r.Host, r.Job, r.ExecutionID, r.Date, int64(r.Duration/time.Millisecond),
+		r.Failed, r.Skipped, r.Error,
+	)
+
+	return err
+}
+
+// Durations returns the durations of the last successful executions of the
+// job, stored by any host
+func (s *SQLHistoryStore) Durations(job string, limit int) ([]time.Duration, error) {
+	rows, err := s.db.Query(fmt.Sprintf(
+		"SELECT duration_ms FROM ofelia_executions WHERE job = %s AND NOT failed AND NOT skipped "+
+			"ORDER BY date DESC LIMIT %d",
+		s.dialect.placeholder(1), limit,
+	), job)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var durations []time.Duration
+	for rows.Next() {
+		var ms int64
+		if err := rows.Scan(&ms); err != nil {
+			return nil, err
+		}
+
+		durations = append(durations, time.Duration(ms)*time.Millisecond)
+	}
+
+	return durations, rows.Err()
+}
+
+// Close closes the database
+func (s *SQLHistoryStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSQLHistory struct{}
+
+var _ = Suite(&SuiteSQLHistory{})
+
+// fakeDB is a database/sql driver recording the statements, only keeping the
+// version of the schema
+type fakeDB struct {
+	mu         sync.Mutex
+	version    *int64
+	durations  []int64
+	statements []string
+	args       [][]driver.Value
+}
+
+var fakeDBs = make(map[string]*fakeDB)
+
+func init() {
+	sql.Register("fake-history", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{db: fakeDBs[name]}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return errors.New("unexpected rollback") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.statements = append(s.db.statements, s.query)
+	s.db.args = append(s.db.args, args)
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO ofelia_schema"):
+		s.db.version = new(int64)
+	case strings.HasPrefix(s.query, "UPDATE ofelia_schema"):
+		v := args[0].(int64)
+		s.db.version = &v
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if strings.HasPrefix(s.query, "SELECT duration_ms") {
+		s.db.statements = append(s.db.statements, s.query)
+		s.db.args = append(s.db.args, args)
+		return &fakeRows{values: s.db.durations}, nil
+	}
+
+	if s.db.version == nil {
+		return &fakeRows{}, nil
+	}
+
+	return &fakeRows{values: []int64{*s.db.version}}, nil
+}
+
+// fakeRows are rows of a single integer column
+type fakeRows struct {
+	values []int64
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func openFakeHistory(c *C, name string, db *fakeDB) *SQLHistoryStore {
+	fakeDBs[name] = db
+	sqlDB, err := sql.Open("fake-history", name)
+	c.Assert(err, IsNil)
+
+	s, err := newSQLHistoryStore(sqlDB, sqlDialects["postgres"])
+	c.Assert(err, IsNil)
+	return s
+}
+
+func (s *SuiteSQLHistory) TestMigrate(c *C) {
+	db := &fakeDB{}
+	store := openFakeHistory(c, "migrate", db)
+	defer store.Close()
+
+	c.Assert(db.statements, HasLen, 6)
+	c.Assert(db.statements[0], Matches, "CREATE TABLE IF NOT EXISTS ofelia_schema .*")
+	c.Assert(db.statements[2], Matches, "CREATE TABLE ofelia_executions .*")
+	c.Assert(db.statements[3], Equals, "UPDATE ofelia_schema SET version = $1")
+	c.Assert(db.statements[4], Matches, "CREATE INDEX .*")
+	c.Assert(*db.version, Equals, int64(2))
+
+	// already migrated
+	db.statements = nil
+	again := openFakeHistory(c, "migrate", db)
+	defer again.Close()
+	c.Assert(db.statements, HasLen, 1)
+}
+
+func (s *SuiteSQLHistory) TestStore(c *C) {
+	db := &fakeDB{}
+	store := openFakeHistory(c, "store", db)
+	defer store.Close()
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(store.Store(&HistoryRecord{
+		Host:        "foo",
+		Job:         "bar",
+		ExecutionID: "baz",
+		Date:        date,
+		Duration:    1500 * time.Millisecond,
+		Failed:      true,
+		Error:       "qux",
+	}), IsNil)
+
+	last := len(db.statements) - 1
+	c.Assert(db.statements[last], Equals, "INSERT INTO ofelia_executions "+
+		"(host, job, execution_id, date, duration_ms, failed, skipped, error) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)")
+	c.Assert(db.args[last], DeepEquals, []driver.Value{
+		"foo", "bar", "baz", date, int64(1500), true, false, "qux",
+	})
+}
+
+func (s *SuiteSQLHistory) TestDurations(c *C) {
+	db := &fakeDB{durations: []int64{1500, 2000}}
+	store := openFakeHistory(c, "durations", db)
+	defer store.Close()
+
+	durations, err := store.Durations("foo", 100)
+	c.Assert(err, IsNil)
+	c.Assert(durations, DeepEquals, []time.Duration{1500 * time.Millisecond, 2 * time.Second})
+
+	last := len(db.statements) - 1
+	c.Assert(db.statements[last], Equals, "SELECT duration_ms FROM ofelia_executions "+
+		"WHERE job = $1 AND NOT failed AND NOT skipped ORDER BY date DESC LIMIT 100")
+	c.Assert(db.args[last], DeepEquals, []driver.Value{"foo"})
+}
+
+func (s *SuiteSQLHistory) TestUnsupportedDriver(c *C) {
+	_, err := NewSQLHistoryStore("sqlite", "")
+	c.Assert(err, ErrorMatches, `unsupported history driver "sqlite".*`)
+}
+
+type testHistoryStore struct {
+	mu      sync.Mutex
+	records []*HistoryRecord
+}
+
+func (s *testHistoryStore) Store(r *HistoryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *SuiteSQLHistory) TestRecordHistory(c *C) {
+	sc := NewScheduler(&TestLogger{})
+	sc.Redactor = NewRedactor()
+	sc.Redactor.AddSecret("s3cr3t")
+
+	store := &testHistoryStore{}
+	stop := RecordHistory(sc, store)
+
+	job := &TestJob{}
+	job.Name = "foo"
+
+	e := NewExecution()
+	e.Start()
+	e.Stop(errors.New("invalid password s3cr3t"))
+	sc.Events.Publish(&ExecutionStarted{Job: job, Execution: e})
+	sc.Events.Publish(&ExecutionFinished{Job: job, Execution: e})
+	stop()
+
+	c.Assert(store.records, HasLen, 1)
+	c.Assert(store.records[0].Job, Equals, "foo")
+	c.Assert(store.records[0].ExecutionID, Equals, e.ID)
+	c.Assert(store.records[0].Failed, Equals, true)
+	c.Assert(store.records[0].Error, Equals, "invalid password *****")
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+// State is a snapshot of the scheduler, its jobs and the executions in
+// progress
+type State struct {
+	Running    bool
+	Jobs       []JobState
+	Executions []ExecutionState
+}
+
+// JobState is the state of a job
+type JobState struct {
+	Name     string
+	Schedule string
+	// Running number of executions in progress
+	Running int32
+	// Next and Prev scheduled executions, zero for the jobs run only when
+	// triggered or if not scheduled yet
+	Next, Prev time.Time
+	// Orphaned when the container of the job was found gone, zero if it
+	// wasn't, see OrphanMonitor
+	Orphaned time.Time
+}
+
+// ExecutionState is the state of an execution in progress
+type ExecutionState struct {
+	Job     string
+	ID      string
+	Date    time.Time
+	Elapsed time.Duration
+	Params  map[string]string `json:",omitempty"`
+}
+
+// State returns a snapshot of the scheduler, the executions in progress are
+// sorted from the oldest
+func (s *Scheduler) State() *State {
+	state := &State{Running: s.IsRunning()}
+
+	s.mu.RLock()
+	for _, j := range s.Jobs {
+		js := JobState{
+			Name:     j.GetName(),
+			Schedule: j.GetSchedule(),
+			Running:  j.Running(),
+			Orphaned: s.OrphanedSince(j),
+		}
+
+		if id, ok := s.entries[j]; ok {
+			e := s.cron.Entry(id)
+			js.Next, js.Prev = e.Next, e.Prev
+		}
+
+		state.Jobs = append(state.Jobs, js)
+	}
+	s.mu.RUnlock()
+
+	now := s.Clock.Now()
+	s.runningMu.Lock()
+	for ctx := range s.running {
+		state.Executions = append(state.Executions, ExecutionState{
+			Job:     ctx.Job.GetName(),
+			ID:      ctx.Execution.ID,
+			Date:    ctx.Execution.Date,
+			Elapsed: now.Sub(ctx.Execution.Date),
+			Params:  ctx.Execution.Params,
+		})
+	}
+	s.runningMu.Unlock()
+
+	sort.Slice(state.Executions, func(i, j int) bool {
+		return state.Executions[i].Date.Before(state.Executions[j].Date)
+	})
+
+	return state
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteState struct{}
+
+var _ = Suite(&SuiteState{})
+
+func (s *SuiteState) TestState(c *C) {
+	job := &TestJob{}
+	job.Name = "foo"
+	job.Schedule = "@hourly"
+
+	triggered := &TestJob{}
+	triggered.Name = "bar"
+
+	sc := NewScheduler(&TestLogger{})
+	c.Assert(sc.AddJob(job), IsNil)
+	sc.AddTriggeredJob(triggered)
+	c.Assert(sc.Start(), IsNil)
+	defer sc.Stop()
+
+	c.Assert(sc.RunJob("bar"), IsNil)
+
+	var state *State
+	for i := 0; i < 100; i++ {
+		if state = sc.State(); len(state.Executions) > 0 {
+			break
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	c.Assert(state.Running, Equals, true)
+	c.Assert(state.Jobs, HasLen, 2)
+	c.Assert(state.Jobs[0].Name, Equals, "foo")
+	c.Assert(state.Jobs[0].Next.IsZero(), Equals, false)
+	c.Assert(state.Jobs[1].Name, Equals, "bar")
+	c.Assert(state.Jobs[1].Next.IsZero(), Equals, true)
+	c.Assert(state.Jobs[1].Running, Equals, int32(1))
+
+	c.Assert(state.Executions, HasLen, 1)
+	c.Assert(state.Executions[0].Job, Equals, "bar")
+	c.Assert(state.Executions[0].ID, Not(Equals), "")
+}
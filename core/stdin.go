@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JobStdinFrom returns the name of the job whose output is fed to the stdin of
+// the given job, empty if none
+func JobStdinFrom(j Job) string {
+	if s, ok := j.(interface{ GetStdinFrom() string }); ok {
+		return s.GetStdinFrom()
+	}
+
+	return ""
+}
+
+// Stdin returns the input of the command of the execution, the inline script
+// of the job or the last successful output of the stdin-from job, nil if the
+// job has none
+func (c *Context) Stdin() (io.Reader, error) {
+	if script := JobScript(c.Job); script != "" {
+		s, err := c.Render(script)
+		if err != nil {
+			return nil, err
+		}
+
+		return strings.NewReader(s), nil
+	}
+
+	from := JobStdinFrom(c.Job)
+	if from == "" {
+		return nil, nil
+	}
+
+	out, ok := c.Scheduler.LastOutput(from)
+	if !ok {
+		return nil, fmt.Errorf("no successful output of job %q to read from", from)
+	}
+
+	return bytes.NewReader(out), nil
+}
+
+// recordOutput keeps the output of the successful execution, before being
+// redacted, if the job is the stdin-from of other job
+func (c *Context) recordOutput() {
+	if c.Scheduler == nil || !c.Scheduler.isStdinSource(c.Job.GetName()) {
+		return
+	}
+
+	c.Scheduler.outputsMu.Lock()
+	defer c.Scheduler.outputsMu.Unlock()
+
+	// copied, the stream is rewritten when redacted
+	out := c.Execution.OutputStream.Bytes()
+	c.Scheduler.outputs[c.Job.GetName()] = append([]byte(nil), out...)
+}
+
+// LastOutput returns the last successful output of the job with the given
+// name, only kept for the jobs being the stdin-from of other job
+func (s *Scheduler) LastOutput(name string) ([]byte, bool) {
+	s.outputsMu.Lock()
+	defer s.outputsMu.Unlock()
+
+	out, ok := s.outputs[name]
+	return out, ok
+}
+
+func (s *Scheduler) isStdinSource(name string) bool {
+	for _, j := range s.GetJobs() {
+		if JobStdinFrom(j) == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type SuiteStdin struct{}
+
+var _ = Suite(&SuiteStdin{})
+
+func (s *SuiteStdin) TestStdinFrom(c *C) {
+	sc := NewScheduler(&TestLogger{})
+
+	producer := &LocalJob{}
+	producer.Name = "dump"
+	producer.Command = `echo "foo bar"`
+	sc.AddTriggeredJob(producer)
+
+	consumer := &LocalJob{}
+	consumer.Name = "upload"
+	consumer.Command = "cat"
+	consumer.StdinFrom = "dump"
+	sc.AddTriggeredJob(consumer)
+
+	run := func(j Job) *Execution {
+		ctx := NewContext(sc, j, NewExecution())
+		ctx.Start()
+		c.Assert(ctx.Next(), IsNil)
+		return ctx.Execution
+	}
+
+	// no successful output yet
+	e := run(consumer)
+	c.Assert(e.Error, ErrorMatches, `no successful output of job "dump".*`)
+
+	e = run(producer)
+	c.Assert(e.Failed, Equals, false)
+
+	e = run(consumer)
+	c.Assert(e.Failed, Equals, false)
+	c.Assert(e.OutputStream.String(), Equals, "foo bar\n")
+
+	// only the outputs read by other jobs are kept
+	_, ok := sc.LastOutput("upload")
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PayloadParam name of the param holding the payload of the webhook call or
+// message triggering a execution
+const PayloadParam = "payload"
+
+// PathParam name of the param holding the path of the file triggering a
+// execution
+const PathParam = "path"
+
+// triggerEnvParams the params holding the payload and path of the triggers,
+// untrusted, given to the commands only as environment variables, e.g.
+// OFELIA_PAYLOAD, never rendered in their templates
+var triggerEnvParams = []string{PayloadParam, PathParam}
+
+// Kinds of triggers of the jobs
+const (
+	TriggerWebhook = "webhook"
+	TriggerNATS    = "nats"
+	TriggerWatch   = "watch"
+)
+
+// triggerParams the param holding the payload of the subscriptions of every
+// kind of trigger
+var triggerParams = map[string]string{
+	TriggerNATS:  PayloadParam,
+	TriggerWatch: PathParam,
+}
+
+// ParseTrigger parses the trigger of a job, "webhook", "nats:<subject>" or
+// "watch:<directory>", returning its kind and subject if any
+func ParseTrigger(value string) (kind, subject string, err error) {
+	if value == TriggerWebhook {
+		return TriggerWebhook, "", nil
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) == 2 && parts[1] != "" {
+		if _, ok := triggerParams[parts[0]]; ok {
+			return parts[0], parts[1], nil
+		}
+	}
+
+	return "", "", fmt.Errorf("invalid trigger %q, must be webhook, nats:<subject> or watch:<directory>", value)
+}
+
+// JobTrigger returns the trigger of the job, empty if it has none
+func JobTrigger(j Job) string {
+	if t, ok := j.(interface{ GetTrigger() string }); ok {
+		return t.GetTrigger()
+	}
+
+	return ""
+}
+
+// MessageSubscriber subscribes to the subjects of a message broker, e.g. NATS
+type MessageSubscriber interface {
+	Subscribe(subject string, handler func(payload []byte)) (unsubscribe func() error, err error)
+}
+
+// subscriptionTriggers runs the jobs with a nats or watch trigger when a
+// message is received on their subject, or a file changes in their directory
+type subscriptionTriggers struct {
+	s    *Scheduler
+	subs map[string]MessageSubscriber
+
+	mu   sync.Mutex
+	jobs map[string]*subscription
+}
+
+type subscription struct {
+	job         Job
+	unsubscribe func() error
+}
+
+// watchTriggers subscribes to the subjects of the registered jobs with a
+// trigger of the kinds of the given subscribers, and of the jobs registered
+// later, until the returned func is called
+func watchTriggers(s *Scheduler, subs map[string]MessageSubscriber) (stop func()) {
+	t := &subscriptionTriggers{s: s, subs: subs, jobs: make(map[string]*subscription)}
+	unsubscribe := s.Events.Subscribe(t.handle)
+	for _, j := range s.Jobs {
+		t.register(j)
+	}
+
+	return func() {
+		unsubscribe()
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		for name := range t.jobs {
+			t.remove(name)
+		}
+	}
+}
+
+func (t *subscriptionTriggers) handle(e Event) {
+	switch e := e.(type) {
+	case *JobRegistered:
+		t.register(e.Job)
+	case *JobRemoved:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		// the job may have been replaced already
+		if mt, ok := t.jobs[e.Job.GetName()]; ok && mt.job == e.Job {
+			t.remove(e.Job.GetName())
+		}
+	}
+}
+
+func (t *subscriptionTriggers) register(j Job) {
+	value := JobTrigger(j)
+	if value == "" {
+		return
+	}
+
+	kind, subject, err := ParseTrigger(value)
+	if err != nil {
+		t.s.Logger.Errorf("Job %q: %s", j.GetName(), err)
+		return
+	}
+
+	sub, ok := t.subs[kind]
+	if !ok {
+		return
+	}
+
+	name, actor := j.GetName(), kind+":"+subject
+	unsubscribe, err := sub.Subscribe(subject, func(payload []byte) {
+		params := map[string]string{triggerParams[kind]: string(payload)}
+		if err := t.s.TriggerJobWithParams(actor, name, params); err != nil {
+			t.s.Logger.Errorf("Unable to run job %q triggered by %s: %s", name, actor, err)
+		}
+	})
+
+	if err != nil {
+		t.s.Logger.Errorf("Job %q: unable to subscribe to %s: %s", name, actor, err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.remove(name)
+	t.jobs[name] = &subscription{job: j, unsubscribe: unsubscribe}
+}
+
+// remove unsubscribes the job with the given name, if subscribed
+func (t *subscriptionTriggers) remove(name string) {
+	mt, ok := t.jobs[name]
+	if !ok {
+		return
+	}
+
+	delete(t.jobs, name)
+	if err := mt.unsubscribe(); err != nil {
+		t.s.Logger.Errorf("Job %q: unable to unsubscribe: %s", name, err)
+	}
+}
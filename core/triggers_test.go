@@ -0,0 +1,106 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteTriggers struct{}
+
+var _ = Suite(&SuiteTriggers{})
+
+func (s *SuiteTriggers) TestParseTrigger(c *C) {
+	kind, subject, err := ParseTrigger("webhook")
+	c.Assert(err, IsNil)
+	c.Assert(kind, Equals, TriggerWebhook)
+	c.Assert(subject, Equals, "")
+
+	kind, subject, err = ParseTrigger("nats:builds.done")
+	c.Assert(err, IsNil)
+	c.Assert(kind, Equals, TriggerNATS)
+	c.Assert(subject, Equals, "builds.done")
+
+	kind, subject, err = ParseTrigger("watch:/data/incoming")
+	c.Assert(err, IsNil)
+	c.Assert(kind, Equals, TriggerWatch)
+	c.Assert(subject, Equals, "/data/incoming")
+
+	for _, value := range []string{"nats:", "watch:", "kafka:foo", "cron"} {
+		_, _, err = ParseTrigger(value)
+		c.Assert(err, ErrorMatches, "invalid trigger .*")
+	}
+}
+
+type testSubscriber struct {
+	mu       sync.Mutex
+	handlers map[string]func([]byte)
+}
+
+func (s *testSubscriber) Subscribe(subject string, h func([]byte)) (func() error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[subject] = h
+	return func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.handlers, subject)
+		return nil
+	}, nil
+}
+
+func (s *testSubscriber) publish(subject string, payload []byte) bool {
+	s.mu.Lock()
+	h, ok := s.handlers[subject]
+	s.mu.Unlock()
+
+	if ok {
+		h(payload)
+	}
+
+	return ok
+}
+
+type paramsJob struct {
+	BareJob
+	params chan map[string]string
+}
+
+func (j *paramsJob) Run(ctx *Context) error {
+	j.params <- ctx.Execution.Params
+	return nil
+}
+
+func (s *SuiteTriggers) TestMessageTriggers(c *C) {
+	sub := &testSubscriber{handlers: make(map[string]func([]byte))}
+	sc := NewScheduler(&TestLogger{})
+	sc.Subscriber = sub
+
+	job := &paramsJob{params: make(chan map[string]string, 1)}
+	job.Name = "foo"
+	job.Trigger = "nats:builds"
+	sc.AddTriggeredJob(job)
+	c.Assert(sc.Start(), IsNil)
+
+	c.Assert(sub.publish("builds", []byte("bar")), Equals, true)
+	select {
+	case params := <-job.params:
+		c.Assert(params, DeepEquals, map[string]string{PayloadParam: "bar"})
+	case <-time.After(time.Second):
+		c.Fatal("job not run")
+	}
+
+	c.Assert(sc.RemoveJob(job), IsNil)
+	c.Assert(sub.publish("builds", []byte("bar")), Equals, false)
+
+	// the jobs added while running are subscribed too
+	sc.AddTriggeredJob(job)
+	c.Assert(sub.publish("builds", []byte("bar")), Equals, true)
+	<-job.params
+
+	c.Assert(sc.Stop(), IsNil)
+	c.Assert(sub.handlers, HasLen, 0)
+}
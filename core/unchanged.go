@@ -0,0 +1,229 @@
+package core
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// JobSkipIfUnchanged returns the paths checked by the executions of the given
+// job, skipped if unchanged since its last successful execution
+func JobSkipIfUnchanged(j Job) []string {
+	if s, ok := j.(interface{ GetSkipIfUnchanged() []string }); ok {
+		return s.GetSkipIfUnchanged()
+	}
+
+	return nil
+}
+
+// ParseChecksumPath parses a path of skip-if-unchanged, an absolute path of
+// the host, e.g. "/srv/site", or of a container, as "<container>:<path>",
+// e.g. "web:/var/www"
+func ParseChecksumPath(p string) (container, filePath string, err error) {
+	filePath = p
+	if i := strings.Index(p, ":"); i > 0 && !strings.HasPrefix(p, "/") {
+		container, filePath = p[:i], p[i+1:]
+	}
+
+	if !path.IsAbs(filePath) {
+		return "", "", fmt.Errorf("invalid skip-if-unchanged path %q, must be absolute, e.g. /srv/site or web:/var/www", p)
+	}
+
+	return container, filePath, nil
+}
+
+// ValidateSkipIfUnchanged checks the paths of skip-if-unchanged of the given
+// job, the container ones are only read by the jobs using docker
+func ValidateSkipIfUnchanged(j Job) error {
+	for _, p := range JobSkipIfUnchanged(j) {
+		container, _, err := ParseChecksumPath(p)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := j.(dockerJob); container != "" && !ok {
+			return fmt.Errorf("skip-if-unchanged path %q of a container requires a job using docker", p)
+		}
+	}
+
+	return nil
+}
+
+// dockerJob a job using docker, able to read the files of the containers
+type dockerJob interface {
+	dockerClient() *docker.Client
+}
+
+func (j *ExecJob) dockerClient() *docker.Client {
+	return j.Client
+}
+
+func (j *RunJob) dockerClient() *docker.Client {
+	return j.Client
+}
+
+func (j *RunServiceJob) dockerClient() *docker.Client {
+	return j.Client
+}
+
+// checkUnchanged returns the checksum of the skip-if-unchanged paths of the
+// job, or ErrSkippedExecution if unchanged since its last successful
+// execution. The paths not read are warned, and the execution runs.
+func (c *Context) checkUnchanged() (string, error) {
+	paths := JobSkipIfUnchanged(c.Job)
+	if len(paths) == 0 || c.Scheduler == nil {
+		return "", nil
+	}
+
+	h := sha256.New()
+	for _, p := range paths {
+		rendered, err := c.Render(p)
+		if err != nil {
+			return "", err
+		}
+
+		if err := c.checksum(h, rendered); err != nil {
+			c.Warn(fmt.Sprintf("unable to read %q, running anyway: %s", rendered, err))
+			return "", nil
+		}
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.Scheduler.checksumsMu.Lock()
+	last := c.Scheduler.checksums[c.checksumKey()]
+	c.Scheduler.checksumsMu.Unlock()
+
+	if sum == last {
+		c.Log("Skipping, " + strings.Join(paths, ", ") + " unchanged since the last successful execution")
+		return "", ErrSkippedExecution
+	}
+
+	return sum, nil
+}
+
+// recordChecksum keeps the checksum of the paths of the successful execution
+func (c *Context) recordChecksum(sum string) {
+	if sum == "" {
+		return
+	}
+
+	c.Scheduler.checksumsMu.Lock()
+	defer c.Scheduler.checksumsMu.Unlock()
+
+	c.Scheduler.checksums[c.checksumKey()] = sum
+}
+
+// checksumKey the executions of a matrix are checked by value
+func (c *Context) checksumKey() string {
+	return c.Job.GetName() + "\x00" + c.Execution.Params[MatrixParam]
+}
+
+func (c *Context) checksum(h hash.Hash, p string) error {
+	container, filePath, err := ParseChecksumPath(p)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(h, "%s\x00", p)
+	if container == "" {
+		return checksumHost(h, filePath)
+	}
+
+	j, ok := c.Job.(dockerJob)
+	if !ok {
+		return fmt.Errorf("the job doesn't use docker")
+	}
+
+	return checksumContainer(h, j.dockerClient(), container, filePath)
+}
+
+// checksumHost hashes the files of the given path of the host, their names,
+// modes and contents, a missing path is hashed as such
+func checksumHost(h hash.Hash, root string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && p == root {
+			fmt.Fprint(h, "missing\x00")
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(root, p)
+		switch {
+		case info.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+
+			defer f.Close()
+			return hashEntry(h, rel, info.Mode(), "", f)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+
+			return hashEntry(h, rel, info.Mode(), link, nil)
+		}
+
+		return hashEntry(h, rel, info.Mode(), "", nil)
+	})
+}
+
+// checksumContainer hashes the files of the given path of the container, as
+// checksumHost, read as a tar archive
+func checksumContainer(h hash.Hash, c *docker.Client, container, root string) error {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(c.DownloadFromContainer(container, docker.DownloadFromContainerOptions{
+			Path:         root,
+			OutputStream: w,
+		}))
+	}()
+
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if e, ok := err.(*docker.Error); ok && e.Status == http.StatusNotFound {
+			fmt.Fprint(h, "missing\x00")
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := hashEntry(h, hdr.Name, hdr.FileInfo().Mode(), hdr.Linkname, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func hashEntry(h hash.Hash, name string, mode os.FileMode, link string, content io.Reader) error {
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", name, mode, link)
+	if content == nil {
+		return nil
+	}
+
+	_, err := io.Copy(h, content)
+	return err
+}
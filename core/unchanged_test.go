@@ -0,0 +1,139 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	docker "github.com/fsouza/go-dockerclient"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteUnchanged struct{}
+
+var _ = Suite(&SuiteUnchanged{})
+
+func (s *SuiteUnchanged) TestParseChecksumPath(c *C) {
+	container, p, err := ParseChecksumPath("/srv/site")
+	c.Assert(err, IsNil)
+	c.Assert(container, Equals, "")
+	c.Assert(p, Equals, "/srv/site")
+
+	container, p, err = ParseChecksumPath("/srv/a:b")
+	c.Assert(err, IsNil)
+	c.Assert(container, Equals, "")
+	c.Assert(p, Equals, "/srv/a:b")
+
+	container, p, err = ParseChecksumPath("web:/var/www")
+	c.Assert(err, IsNil)
+	c.Assert(container, Equals, "web")
+	c.Assert(p, Equals, "/var/www")
+
+	_, _, err = ParseChecksumPath("site")
+	c.Assert(err, ErrorMatches, `invalid skip-if-unchanged path "site", must be absolute.*`)
+
+	_, _, err = ParseChecksumPath("web:www")
+	c.Assert(err, ErrorMatches, `invalid skip-if-unchanged path "web:www", must be absolute.*`)
+}
+
+func (s *SuiteUnchanged) TestValidateSkipIfUnchanged(c *C) {
+	local := &LocalJob{}
+	local.SkipIfUnchanged = []string{"/srv/site"}
+	c.Assert(ValidateSkipIfUnchanged(local), IsNil)
+
+	local.SkipIfUnchanged = []string{"web:/var/www"}
+	c.Assert(ValidateSkipIfUnchanged(local), ErrorMatches, `skip-if-unchanged path "web:/var/www" of a container requires a job using docker`)
+
+	exec := &ExecJob{}
+	exec.SkipIfUnchanged = []string{"web:/var/www"}
+	c.Assert(ValidateSkipIfUnchanged(exec), IsNil)
+}
+
+func (s *SuiteUnchanged) TestChecksumHost(c *C) {
+	dir := c.MkDir()
+	sum := func() string {
+		h := sha256.New()
+		c.Assert(checksumHost(h, filepath.Join(dir, "site")), IsNil)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	missing := sum()
+	c.Assert(os.MkdirAll(filepath.Join(dir, "site", "css"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "site", "index.html"), []byte("foo"), 0644), IsNil)
+
+	created := sum()
+	c.Assert(created, Not(Equals), missing)
+	c.Assert(sum(), Equals, created)
+
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "site", "index.html"), []byte("bar"), 0644), IsNil)
+	c.Assert(sum(), Not(Equals), created)
+
+	modified := sum()
+	c.Assert(os.Symlink("index.html", filepath.Join(dir, "site", "css", "link")), IsNil)
+	c.Assert(sum(), Not(Equals), modified)
+}
+
+func (s *SuiteUnchanged) TestContextNextSkipIfUnchanged(c *C) {
+	dir := c.MkDir()
+	file := filepath.Join(dir, "index.html")
+	c.Assert(ioutil.WriteFile(file, []byte("foo"), 0644), IsNil)
+
+	sc := NewScheduler(&TestLogger{})
+	job := &LocalJob{}
+	job.Name = "sync"
+	job.Command = "true"
+	job.SkipIfUnchanged = []string{dir}
+	sc.AddTriggeredJob(job)
+
+	run := func() *Execution {
+		ctx := NewContext(sc, job, NewExecution())
+		ctx.Start()
+		c.Assert(ctx.Next(), IsNil)
+		return ctx.Execution
+	}
+
+	c.Assert(run().Skipped, Equals, false)
+	c.Assert(run().Skipped, Equals, true)
+
+	c.Assert(ioutil.WriteFile(file, []byte("bar"), 0644), IsNil)
+	job.Command = "false"
+	e := run()
+	c.Assert(e.Skipped, Equals, false)
+	c.Assert(e.Failed, Equals, true)
+
+	// compared to the last successful execution
+	job.Command = "true"
+	c.Assert(run().Skipped, Equals, false)
+	c.Assert(run().Skipped, Equals, true)
+}
+
+func (s *SuiteRunJob) TestChecksumContainer(c *C) {
+	container, err := s.client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "web",
+		Config: &docker.Config{Image: ImageFixture},
+	})
+	c.Assert(err, IsNil)
+
+	sum := func() string {
+		h := sha256.New()
+		c.Assert(checksumContainer(h, s.client, container.ID, "/var/www"), IsNil)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	missing := sum()
+
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	c.Assert(tw.WriteHeader(&tar.Header{Name: "www", Mode: 0755, Typeflag: tar.TypeDir}), IsNil)
+	c.Assert(tw.Close(), IsNil)
+	c.Assert(s.client.UploadToContainer(container.ID, docker.UploadToContainerOptions{
+		Path:        "/var",
+		InputStream: archive,
+	}), IsNil)
+
+	c.Assert(sum(), Not(Equals), missing)
+}
@@ -0,0 +1,46 @@
+package core
+
+import "time"
+
+const (
+	defaultWatchInterval = time.Millisecond * 100
+	defaultMaxRuntime    = time.Hour * 24
+)
+
+// WatchConfig configures how the containers and services of a job are watched
+// until their end, the empty values are inherited from the global ones
+type WatchConfig struct {
+	// WatchInterval time between two inspections, e.g. "1s", 100ms by default
+	WatchInterval string `gcfg:"watch-interval" mapstructure:"watch-interval"`
+	// MaxRuntime maximum time running, e.g. "72h", 24h by default
+	MaxRuntime string `gcfg:"max-runtime" mapstructure:"max-runtime"`
+}
+
+// Inherit sets the empty values of the config to the given ones
+func (c *WatchConfig) Inherit(global WatchConfig) {
+	if c.WatchInterval == "" {
+		c.WatchInterval = global.WatchInterval
+	}
+
+	if c.MaxRuntime == "" {
+		c.MaxRuntime = global.MaxRuntime
+	}
+}
+
+// Validate checks the values of the config are valid durations
+func (c *WatchConfig) Validate() error {
+	if _, err := c.watchInterval(); err != nil {
+		return err
+	}
+
+	_, err := c.maxRuntime()
+	return err
+}
+
+func (c *WatchConfig) watchInterval() (time.Duration, error) {
+	return parseDuration("watch-interval", c.WatchInterval, defaultWatchInterval)
+}
+
+func (c *WatchConfig) maxRuntime() (time.Duration, error) {
+	return parseDuration("max-runtime", c.MaxRuntime, defaultMaxRuntime)
+}
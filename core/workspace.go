@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// DefaultWorkspacePath path the workspace of a job-run is mounted at by
+// default
+const DefaultWorkspacePath = "/workspace"
+
+// ErrWorkspaceExistingContainer the workspace is mounted in a new container
+// only
+var ErrWorkspaceExistingContainer = errors.New("workspace requires a new container, unsupported with container")
+
+// ValidateWorkspace checks the workspace options of the job
+func (j *RunJob) ValidateWorkspace() error {
+	if !j.Workspace {
+		return nil
+	}
+
+	if j.Container != "" {
+		return ErrWorkspaceExistingContainer
+	}
+
+	if !path.IsAbs(j.workspacePath()) {
+		return fmt.Errorf("invalid workspace-path %q, must be absolute", j.WorkspacePath)
+	}
+
+	return nil
+}
+
+func (j *RunJob) workspacePath() string {
+	if j.WorkspacePath == "" {
+		return DefaultWorkspacePath
+	}
+
+	return j.WorkspacePath
+}
+
+// mountWorkspace creates the workspace volume of the execution, mounted in
+// the containers created by the job from now on, returning the function
+// removing it, once the containers are removed
+func mountWorkspace(c *docker.Client, ctx *Context, mountPath string, tmpfs bool) (func(), error) {
+	opts := docker.CreateVolumeOptions{
+		Name: fmt.Sprintf("ofelia-%s-%s", ctx.Job.GetName(), ctx.Execution.ID),
+	}
+
+	// a tmpfs volume, unlike a tmpfs mount, is shared by the containers
+	if tmpfs {
+		opts.Driver = "local"
+		opts.DriverOpts = map[string]string{"type": "tmpfs", "device": "tmpfs"}
+	}
+
+	v, err := c.CreateVolume(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating workspace volume: %s", err)
+	}
+
+	ctx.Volumes = append(ctx.Volumes, v.Name+":"+mountPath)
+	return func() {
+		if err := c.RemoveVolume(v.Name); err != nil {
+			ctx.Warn("failed to remove workspace volume: " + err.Error())
+		}
+	}, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	logging "github.com/op/go-logging"
+	. "gopkg.in/check.v1"
+)
+
+func (s *SuiteRunJob) TestValidateWorkspace(c *C) {
+	j := &RunJob{}
+	c.Assert(j.ValidateWorkspace(), IsNil)
+
+	j.Workspace = true
+	c.Assert(j.ValidateWorkspace(), IsNil)
+	c.Assert(j.workspacePath(), Equals, DefaultWorkspacePath)
+
+	j.WorkspacePath = "data"
+	c.Assert(j.ValidateWorkspace(), ErrorMatches, `invalid workspace-path "data", must be absolute`)
+
+	j.WorkspacePath = ""
+	j.Container = "foo"
+	c.Assert(j.ValidateWorkspace(), Equals, ErrWorkspaceExistingContainer)
+}
+
+func (s *SuiteRunJob) TestRunWorkspace(c *C) {
+	job := &RunJob{Client: s.client}
+	job.Image = ImageFixture
+	job.Command = "echo foo"
+	job.Delete = "true"
+	job.Sidecar = []string{"proxy=" + ImageFixture + " proxy --port 5432"}
+	job.Workspace = true
+	job.WorkspacePath = "/data"
+	job.WorkspaceTmpfs = true
+	job.Name = "test"
+
+	ctx := &Context{}
+	ctx.Execution = NewExecution()
+	ctx.Logger = logging.MustGetLogger("ofelia")
+	ctx.Job = job
+
+	// inspected while the job runs, asserted after it
+	var volumes []docker.Volume
+	var sidecarBinds []string
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		time.Sleep(time.Millisecond * 200)
+
+		volumes, _ = s.client.ListVolumes(docker.ListVolumesOptions{})
+		containers, _ := s.client.ListContainers(docker.ListContainersOptions{})
+		for _, container := range containers {
+			// the job container is started with a host config, replacing the
+			// one it was created with in the test server
+			if container.Command == "echo foo" {
+				s.client.StopContainer(container.ID, 0)
+			} else if inspected, err := s.client.InspectContainer(container.ID); err == nil {
+				sidecarBinds = inspected.HostConfig.Binds
+			}
+		}
+	}()
+
+	c.Assert(job.Run(ctx), IsNil)
+	wg.Wait()
+
+	c.Assert(volumes, HasLen, 1)
+	c.Assert(volumes[0].Name, Equals, "ofelia-test-"+ctx.Execution.ID)
+
+	c.Assert(ctx.Volumes, DeepEquals, []string{volumes[0].Name + ":/data"})
+	c.Assert(sidecarBinds, DeepEquals, ctx.Volumes)
+
+	volumes, err := s.client.ListVolumes(docker.ListVolumesOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(volumes, HasLen, 0)
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// Alerter is a notifier sending alerts about a job apart from the reports of
+// its executions, e.g. a execution running longer than expected
+type Alerter interface {
+	// Alert sends the alert, the failures are recorded as the ones of Run
+	Alert(ctx *core.Context, title, text string)
+}
+
+// alert sends the alert with all the alerters of the job
+func alert(ctx *core.Context, title, text string) {
+	for _, m := range ctx.Job.Middlewares() {
+		if a, ok := m.(Alerter); ok {
+			a.Alert(ctx, title, text)
+		}
+	}
+}
+
+// AlertEvents sends an alert with the alerters of the jobs reported as stale
+// by the core.FreshnessMonitor of the given scheduler, with anomalous
+// executions by its core.DurationMonitor, or orphaned by its
+// core.OrphanMonitor, until the returned func is called
+func AlertEvents(s *core.Scheduler) (unsubscribe func()) {
+	return s.Events.Subscribe(func(e core.Event) {
+		var ctx *core.Context
+		var title, text string
+		switch e := e.(type) {
+		case *core.JobStale:
+			ctx = core.NewContext(s, e.Job, core.NewExecution())
+			title = "Job stale"
+			text = fmt.Sprintf(
+				"Job %q hasn't succeeded in %s, last success at %s",
+				e.Job.GetName(), e.Window, e.LastSuccess.Format(time.RFC3339),
+			)
+		case *core.ExecutionAnomaly:
+			ctx = core.NewContext(s, e.Job, e.Execution)
+			title = "Execution duration anomaly"
+			text = fmt.Sprintf(
+				"Job %q execution %s took %s, usually %s ± %s",
+				e.Job.GetName(), e.Execution.ID, e.Execution.Duration, e.Mean, e.StdDev,
+			)
+		case *core.JobOrphaned:
+			ctx = core.NewContext(s, e.Job, core.NewExecution())
+			title = "Job orphaned"
+			text = fmt.Sprintf("Job %q is orphaned, container %s is gone", e.Job.GetName(), e.Container)
+			if e.Grace > 0 {
+				text += fmt.Sprintf(", the job is removed in %s", e.Grace)
+			}
+		default:
+			return
+		}
+
+		// the subscribers must not block
+		go alert(ctx, title, text)
+	})
+}
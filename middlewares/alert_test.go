@@ -0,0 +1,111 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteAlert struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteAlert{})
+
+func (s *SuiteAlert) TestAlertEventsStale(c *C) {
+	messages := make(chan slackMessage, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		messages <- m
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.job.Use(NewSlack(&SlackConfig{SlackWebhook: ts.URL}))
+
+	sched := s.ctx.Scheduler
+	defer AlertEvents(sched)()
+
+	sched.Events.Publish(&core.JobStale{
+		Job:         s.job,
+		LastSuccess: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Window:      26 * time.Hour,
+	})
+
+	select {
+	case m := <-messages:
+		c.Assert(m.Text, Equals, `Job "foo" hasn't succeeded in 26h0m0s, last success at 2020-01-01T00:00:00Z`)
+		c.Assert(m.Attachments[0].Title, Equals, "Job stale")
+	case <-time.After(time.Second):
+		c.Fatal("alert not sent")
+	}
+}
+
+func (s *SuiteAlert) TestAlertEventsAnomaly(c *C) {
+	messages := make(chan slackMessage, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		messages <- m
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.job.Use(NewSlack(&SlackConfig{SlackWebhook: ts.URL}))
+
+	sched := s.ctx.Scheduler
+	defer AlertEvents(sched)()
+
+	e := core.NewExecution()
+	e.ID = "bar"
+	e.Duration = time.Hour
+	sched.Events.Publish(&core.ExecutionAnomaly{
+		Job:       s.job,
+		Execution: e,
+		Mean:      10 * time.Minute,
+		StdDev:    time.Minute,
+	})
+
+	select {
+	case m := <-messages:
+		c.Assert(m.Text, Equals, `Job "foo" execution bar took 1h0m0s, usually 10m0s ± 1m0s`)
+		c.Assert(m.Attachments[0].Title, Equals, "Execution duration anomaly")
+	case <-time.After(time.Second):
+		c.Fatal("alert not sent")
+	}
+}
+
+func (s *SuiteAlert) TestAlertEventsOrphaned(c *C) {
+	messages := make(chan slackMessage, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		messages <- m
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.job.Use(NewSlack(&SlackConfig{SlackWebhook: ts.URL}))
+
+	sched := s.ctx.Scheduler
+	defer AlertEvents(sched)()
+
+	sched.Events.Publish(&core.JobOrphaned{Job: s.job, Container: "bar", Grace: time.Hour})
+
+	select {
+	case m := <-messages:
+		c.Assert(m.Text, Equals, `Job "foo" is orphaned, container bar is gone, the job is removed in 1h0m0s`)
+		c.Assert(m.Attachments[0].Title, Equals, "Job orphaned")
+	case <-time.After(time.Second):
+		c.Fatal("alert not sent")
+	}
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const (
+	defaultCloudWatchNamespace = "Ofelia"
+	cloudWatchService          = "monitoring"
+	cloudWatchVersion          = "2010-08-01"
+)
+
+// CloudWatchConfig configuration for the CloudWatch middleware
+type CloudWatchConfig struct {
+	// CloudWatchRegion region of CloudWatch, enabling the middleware
+	CloudWatchRegion string `gcfg:"cloudwatch-region" mapstructure:"cloudwatch-region"`
+	// CloudWatchNamespace namespace of the metrics, "Ofelia" by default
+	CloudWatchNamespace string `gcfg:"cloudwatch-namespace" mapstructure:"cloudwatch-namespace"`
+	CloudWatchRetries   int    `gcfg:"cloudwatch-retries" mapstructure:"cloudwatch-retries"`
+}
+
+// NewCloudWatch returns a CloudWatch middleware if the given configuration is
+// not empty
+func NewCloudWatch(c *CloudWatchConfig) core.Middleware {
+	var m core.Middleware
+	if c.CloudWatchRegion != "" {
+		m = &CloudWatch{CloudWatchConfig: *c, client: core.NewAWSClient(c.CloudWatchRegion)}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "cloudwatch",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &CloudWatchConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewCloudWatch(c.(*CloudWatchConfig)) },
+	})
+}
+
+// CloudWatch middleware publishes the metrics of every execution of the job to
+// AWS CloudWatch, with the name of the job as the JobName dimension
+type CloudWatch struct {
+	CloudWatchConfig
+	TagFilter
+
+	client *core.AWSClient
+}
+
+// ContinueOnStop return allways true, we want always report the final status
+func (m *CloudWatch) ContinueOnStop() bool {
+	return true
+}
+
+// Run publishes the metrics of the execution, the skipped ones aren't
+// published
+func (m *CloudWatch) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	if !m.notifies(ctx) {
+		return err
+	}
+
+	if !ctx.Execution.Skipped {
+		if err := m.Notify(ctx); err != nil {
+			notificationFailed(ctx, "cloudwatch", err)
+		}
+	}
+
+	return err
+}
+
+// Notify publishes the metrics of the execution: Duration, in seconds, and
+// Succeeded and Failed, counting 1 or 0
+func (m *CloudWatch) Notify(ctx *core.Context) error {
+	e := ctx.Execution
+	succeeded, failed := 1.0, 0.0
+	if e.Failed {
+		succeeded, failed = 0, 1
+	}
+
+	namespace := m.CloudWatchNamespace
+	if namespace == "" {
+		namespace = defaultCloudWatchNamespace
+	}
+
+	params := url.Values{"Namespace": {namespace}}
+	for i, metric := range []struct {
+		name, unit string
+		value      float64
+	}{
+		{"Duration", "Seconds", e.Duration.Seconds()},
+		{"Succeeded", "Count", succeeded},
+		{"Failed", "Count", failed},
+	} {
+		prefix := fmt.Sprintf("MetricData.member.%d.", i+1)
+		params.Set(prefix+"MetricName", metric.name)
+		params.Set(prefix+"Unit", metric.unit)
+		params.Set(prefix+"Value", fmt.Sprint(metric.value))
+		params.Set(prefix+"Timestamp", e.Date.Add(e.Duration).UTC().Format(time.RFC3339))
+		params.Set(prefix+"Dimensions.member.1.Name", "JobName")
+		params.Set(prefix+"Dimensions.member.1.Value", ctx.Job.GetName())
+	}
+
+	return notifyWithRetry(m.CloudWatchRetries, func() error {
+		return m.client.Query(cloudWatchService, "PutMetricData", cloudWatchVersion, params)
+	})
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteCloudWatch struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteCloudWatch{})
+
+func (s *SuiteCloudWatch) TestNewCloudWatchEmpty(c *C) {
+	c.Assert(NewCloudWatch(&CloudWatchConfig{}), IsNil)
+}
+
+func (s *SuiteCloudWatch) TestRun(c *C) {
+	var form url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.ParseForm(), IsNil)
+		form = r.PostForm
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.ctx.Start()
+	s.ctx.Stop(errors.New("foo"))
+
+	m := NewCloudWatch(&CloudWatchConfig{CloudWatchRegion: "eu-west-1"}).(*CloudWatch)
+	m.client.Endpoint = ts.URL
+	m.client.Credentials = credentials.NewStaticV4("key", "secret", "")
+
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.NotificationErrors, IsNil)
+	c.Assert(form.Get("Action"), Equals, "PutMetricData")
+	c.Assert(form.Get("Namespace"), Equals, "Ofelia")
+	c.Assert(form.Get("MetricData.member.1.MetricName"), Equals, "Duration")
+	c.Assert(form.Get("MetricData.member.1.Dimensions.member.1.Value"), Equals, "foo")
+	c.Assert(form.Get("MetricData.member.2.MetricName"), Equals, "Succeeded")
+	c.Assert(form.Get("MetricData.member.2.Value"), Equals, "0")
+	c.Assert(form.Get("MetricData.member.3.MetricName"), Equals, "Failed")
+	c.Assert(form.Get("MetricData.member.3.Value"), Equals, "1")
+}
+
+func (s *SuiteCloudWatch) TestRunSkipped(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request")
+	}))
+
+	defer ts.Close()
+
+	s.ctx.Start()
+	s.ctx.Execution.Skipped = true
+	s.ctx.Stop(nil)
+
+	m := NewCloudWatch(&CloudWatchConfig{CloudWatchRegion: "eu-west-1"}).(*CloudWatch)
+	m.client.Endpoint = ts.URL
+	c.Assert(m.Run(s.ctx), IsNil)
+}
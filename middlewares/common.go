@@ -1,6 +1,62 @@
 package middlewares
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// Logger when set, logs the messages of the middlewares instead of the logger
+// of the scheduler, so they can be given their own level
+var Logger core.Logger
+
+// logger returns the logger of the middlewares running the given context
+func logger(ctx *core.Context) core.Logger {
+	if Logger != nil {
+		return Logger
+	}
+
+	return ctx.Logger
+}
+
+// annotations returns the description, the owner, the provenance and the tags
+// of the job, the ones it has, as lines of the notifications
+func annotations(j core.Job) []string {
+	var lines []string
+	if d := core.JobDescription(j); d != "" {
+		lines = append(lines, "Description: "+d)
+	}
+
+	if o := core.JobOwner(j); o != "" {
+		lines = append(lines, "Owner: "+o)
+	}
+
+	if p := core.JobProvenance(j); !p.IsZero() {
+		lines = append(lines, "Source: "+p.String())
+	}
+
+	if tags := sortedTags(j); len(tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(tags, ", "))
+	}
+
+	return lines
+}
+
+// sortedTags returns the tags of the job as "<name>=<value>", sorted by name
+func sortedTags(j core.Job) []string {
+	tags, _ := core.JobTags(j)
+	sorted := make([]string, 0, len(tags))
+	for name, value := range tags {
+		sorted = append(sorted, name+"="+value)
+	}
+
+	sort.Strings(sorted)
+	return sorted
+}
 
 func IsEmpty(i interface{}) bool {
 	t := reflect.TypeOf(i).Elem()
@@ -8,3 +64,66 @@ func IsEmpty(i interface{}) bool {
 
 	return reflect.DeepEqual(i, e)
 }
+
+// queued calls wait storing the execution in the scheduler queue if any, to be
+// resumed after a restart if the process stops while waiting.
+func queued(ctx *core.Context, msg string, wait func()) {
+	q := ctx.Scheduler.Queue
+	var id uint64
+	if q != nil {
+		var err error
+		if id, err = q.Push(ctx.Job.GetName()); err != nil {
+			logger(ctx).Errorf("Queue error: %q", err)
+			q = nil
+		}
+	}
+
+	ctx.Log(msg)
+	wait()
+
+	if q != nil {
+		if err := q.Remove(id); err != nil {
+			logger(ctx).Errorf("Queue error: %q", err)
+		}
+	}
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  uint64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize parses a size in bytes, e.g. "512MB", "1.5G" or "1024", the
+// units being powers of 1024
+func parseSize(value string) (uint64, error) {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	unit := uint64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, unit = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("must be a positive size, e.g. 512MB")
+	}
+
+	return uint64(n * float64(unit)), nil
+}
+
+// formatSize formats a size in bytes with the largest unit fitting it
+func formatSize(bytes uint64) string {
+	for _, u := range sizeUnits[:4] {
+		if bytes >= u.bytes {
+			return strconv.FormatFloat(float64(bytes)/float64(u.bytes), 'f', 1, 64) + u.suffix
+		}
+	}
+
+	return strconv.FormatUint(bytes, 10) + "B"
+}
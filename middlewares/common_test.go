@@ -61,3 +61,26 @@ func (*TestLogger) Debugf(format string, args ...interface{})    {}
 func (*TestLogger) Errorf(format string, args ...interface{})    {}
 func (*TestLogger) Noticef(format string, args ...interface{})   {}
 func (*TestLogger) Warningf(format string, args ...interface{})  {}
+
+func (s *SuiteCommon) TestParseSize(c *C) {
+	for value, expected := range map[string]uint64{
+		"1024":  1024,
+		"512MB": 512 << 20,
+		"512mb": 512 << 20,
+		"2 G":   2 << 30,
+		"64K":   64 << 10,
+		"1.5GB": 3 << 29,
+		"100B":  100,
+		"1TB":   1 << 40,
+	} {
+		size, err := parseSize(value)
+		c.Assert(err, IsNil, Commentf(value))
+		c.Assert(size, Equals, expected, Commentf(value))
+	}
+
+	_, err := parseSize("-1GB")
+	c.Assert(err, NotNil)
+
+	c.Assert(formatSize(512<<20), Equals, "512.0MB")
+	c.Assert(formatSize(100), Equals, "100B")
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// DeadlineConfig configuration for the Deadline middleware
+type DeadlineConfig struct {
+	// ExpectedDuration of the executions, e.g. "10m"
+	ExpectedDuration string `gcfg:"expected-duration" mapstructure:"expected-duration"`
+}
+
+// NewDeadline returns a Deadline middleware if the given configuration is not empty
+func NewDeadline(c *DeadlineConfig) core.Middleware {
+	var m core.Middleware
+	if !IsEmpty(c) {
+		m = &Deadline{*c}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "deadline",
+		Stage:  StageWatch,
+		Config: func() interface{} { return &DeadlineConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewDeadline(c.(*DeadlineConfig)) },
+	})
+}
+
+// Deadline middleware warns when an execution is still running after its
+// expected duration, without stopping it
+type Deadline struct {
+	DeadlineConfig
+}
+
+// ContinueOnStop Deadline is only called if the process is still running
+func (m *Deadline) ContinueOnStop() bool {
+	return false
+}
+
+// Run runs the execution, warning once it exceeds the expected duration
+func (m *Deadline) Run(ctx *core.Context) error {
+	expected, err := time.ParseDuration(m.ExpectedDuration)
+	if err != nil {
+		ctx.Warn(fmt.Sprintf("invalid expected-duration %q: %s", m.ExpectedDuration, err))
+		return ctx.Next()
+	}
+
+	done := make(chan struct{})
+	t := time.AfterFunc(expected, func() {
+		defer close(done)
+		overdue(ctx, expected)
+	})
+
+	err = ctx.Next()
+
+	// the warning being sent finishes before the execution is reported
+	if !t.Stop() {
+		<-done
+	}
+
+	return err
+}
+
+// overdue warns the execution is running longer than expected, with the
+// notifiers of the job
+func overdue(ctx *core.Context, expected time.Duration) {
+	ctx.Warn(fmt.Sprintf("Running longer than expected, %s", expected))
+	ctx.Scheduler.Events.Publish(&core.ExecutionOverdue{
+		Job:       ctx.Job,
+		Execution: ctx.Execution,
+		Expected:  expected,
+	})
+
+	alert(ctx, "Execution overdue", fmt.Sprintf(
+		"Job %q is running longer than expected, %s, command: %s",
+		ctx.Job.GetName(), expected, ctx.Redact(ctx.Job.GetCommand()),
+	))
+}
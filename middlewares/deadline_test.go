@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDeadline struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteDeadline{})
+
+type sleepJob struct {
+	core.BareJob
+	sleep time.Duration
+}
+
+func (j *sleepJob) Run(ctx *core.Context) error {
+	time.Sleep(j.sleep)
+	return nil
+}
+
+func (s *SuiteDeadline) TestNewDeadlineEmpty(c *C) {
+	c.Assert(NewDeadline(&DeadlineConfig{}), IsNil)
+}
+
+func (s *SuiteDeadline) TestRunOverdue(c *C) {
+	var messages []slackMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		messages = append(messages, m)
+	}))
+
+	defer ts.Close()
+
+	job := &sleepJob{sleep: 50 * time.Millisecond}
+	job.Name = "foo"
+	job.Use(
+		NewSlack(&SlackConfig{SlackWebhook: ts.URL, SlackOnlyOnError: true}),
+		NewDeadline(&DeadlineConfig{ExpectedDuration: "10ms"}),
+	)
+
+	sched := core.NewScheduler(&TestLogger{})
+	var events []core.Event
+	sched.Events.Subscribe(func(e core.Event) {
+		if _, ok := e.(*core.ExecutionOverdue); ok {
+			events = append(events, e)
+		}
+	})
+
+	ctx := core.NewContext(sched, job, core.NewExecution())
+	ctx.Start()
+	c.Assert(ctx.Next(), IsNil)
+
+	c.Assert(events, HasLen, 1)
+	c.Assert(events[0].(*core.ExecutionOverdue).Expected, Equals, 10*time.Millisecond)
+	c.Assert(messages, HasLen, 1)
+	c.Assert(messages[0].Text, Matches, `Job "foo" is running longer than expected, 10ms.*`)
+	c.Assert(messages[0].Attachments[0].Title, Equals, "Execution overdue")
+}
+
+func (s *SuiteDeadline) TestRunInTime(c *C) {
+	job := &sleepJob{}
+	job.Use(NewDeadline(&DeadlineConfig{ExpectedDuration: "1s"}))
+
+	sched := core.NewScheduler(&TestLogger{})
+	var events int
+	sched.Events.Subscribe(func(e core.Event) { events++ })
+
+	ctx := core.NewContext(sched, job, core.NewExecution())
+	ctx.Start()
+	c.Assert(ctx.Next(), IsNil)
+	c.Assert(events, Equals, 0)
+}
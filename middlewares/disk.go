@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const defaultMinFreeDiskPath = "/"
+
+// freeDisk returns the space available to the unprivileged users on the
+// filesystem of the given path, in bytes, replaced by the tests
+var freeDisk = statfsFree
+
+// DiskConfig configuration for the Disk middleware
+type DiskConfig struct {
+	// MinFreeDisk the executions are skipped while the free space of the
+	// filesystem of MinFreeDiskPath is below it, e.g. "5G"
+	MinFreeDisk string `gcfg:"min-free-disk" mapstructure:"min-free-disk"`
+	// MinFreeDiskPath path on the filesystem checked, e.g. the mount point of
+	// a backup volume, "/" by default
+	MinFreeDiskPath string `gcfg:"min-free-disk-path" mapstructure:"min-free-disk-path"`
+}
+
+// NewDisk returns a Disk middleware if the given configuration is not empty
+func NewDisk(c *DiskConfig) core.Middleware {
+	var m core.Middleware
+	if c.MinFreeDisk != "" {
+		m = &Disk{DiskConfig: *c}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "disk",
+		Stage:  StageGuard,
+		Global: true,
+		Config: func() interface{} { return &DiskConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewDisk(c.(*DiskConfig)) },
+	})
+}
+
+// Disk middleware skips the executions when the free disk space is too low,
+// instead of running a job failing halfway, e.g. a backup corrupting its target
+type Disk struct {
+	DiskConfig
+}
+
+// ContinueOnStop Disk is only called if the process is still running
+func (m *Disk) ContinueOnStop() bool {
+	return false
+}
+
+// Run skips the execution, alerting the notifiers of the job, when the free
+// space is below min-free-disk
+func (m *Disk) Run(ctx *core.Context) error {
+	min, err := parseSize(m.MinFreeDisk)
+	if err != nil {
+		ctx.Warn(fmt.Sprintf("invalid min-free-disk %q: %s", m.MinFreeDisk, err))
+		return ctx.Next()
+	}
+
+	path := m.path()
+	free, err := freeDisk(path)
+	if err != nil {
+		ctx.Warn(fmt.Sprintf("unable to read the free disk space of %q, running anyway: %s", path, err))
+		return ctx.Next()
+	}
+
+	if free >= min {
+		return ctx.Next()
+	}
+
+	text := fmt.Sprintf(
+		"Free disk space of %q is %s, below min-free-disk %s",
+		path, formatSize(free), formatSize(min),
+	)
+
+	ctx.Log("Skipping, " + text)
+	alert(ctx, "Insufficient disk space", fmt.Sprintf("Job %q skipped: %s", ctx.Job.GetName(), text))
+	ctx.Stop(core.ErrSkippedExecution)
+	return ctx.Next()
+}
+
+func (m *Disk) path() string {
+	if m.MinFreeDiskPath == "" {
+		return defaultMinFreeDiskPath
+	}
+
+	return m.MinFreeDiskPath
+}
+
+func statfsFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDisk struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteDisk{})
+
+func (s *SuiteDisk) TearDownTest(c *C) {
+	freeDisk = statfsFree
+}
+
+func (s *SuiteDisk) TestNewDiskEmpty(c *C) {
+	c.Assert(NewDisk(&DiskConfig{}), IsNil)
+	c.Assert(NewDisk(&DiskConfig{MinFreeDiskPath: "/backups"}), IsNil)
+}
+
+func (s *SuiteDisk) TestRun(c *C) {
+	var checked string
+	freeDisk = func(path string) (uint64, error) {
+		checked = path
+		return 10 << 30, nil
+	}
+
+	m := NewDisk(&DiskConfig{MinFreeDisk: "5G"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(checked, Equals, "/")
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteDisk) TestRunInsufficient(c *C) {
+	freeDisk = func(path string) (uint64, error) {
+		return 2 << 30, nil
+	}
+
+	a := &testAlerter{}
+	s.job.Name = "backup"
+	s.job.Use(a)
+
+	m := NewDisk(&DiskConfig{MinFreeDisk: "5G", MinFreeDiskPath: "/backups"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, true)
+	c.Assert(a.alerts, DeepEquals, []string{
+		`Insufficient disk space: Job "backup" skipped: Free disk space of "/backups" is 2.0GB, below min-free-disk 5.0GB`,
+	})
+}
+
+func (s *SuiteDisk) TestRunUnreadable(c *C) {
+	freeDisk = func(path string) (uint64, error) {
+		return 0, fmt.Errorf("no such file or directory")
+	}
+
+	m := NewDisk(&DiskConfig{MinFreeDisk: "5G", MinFreeDiskPath: "/missing"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteDisk) TestStatfsFree(c *C) {
+	free, err := statfsFree("/")
+	c.Assert(err, IsNil)
+	c.Assert(free > 0, Equals, true)
+
+	_, err = statfsFree("/missing/ofelia")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const (
+	// DockerUnreachableFail fails the executions while the docker daemon is
+	// unreachable
+	DockerUnreachableFail = "fail"
+	// DockerUnreachableQueue defers the executions until the docker daemon is
+	// reachable again
+	DockerUnreachableQueue = "queue"
+)
+
+// DockerHealthConfig configuration for the DockerHealth middleware
+type DockerHealthConfig struct {
+	DockerUnreachable string `gcfg:"docker-unreachable" mapstructure:"docker-unreachable"`
+}
+
+// NewDockerHealth returns a DockerHealth middleware if a monitor is given
+func NewDockerHealth(c *DockerHealthConfig, m *core.DockerMonitor) core.Middleware {
+	if m == nil {
+		return nil
+	}
+
+	return &DockerHealth{DockerHealthConfig: *c, Monitor: m}
+}
+
+// DockerHealth checks the docker daemon is reachable before running the job,
+// failing the execution or waiting until is reachable again
+type DockerHealth struct {
+	DockerHealthConfig
+	Monitor *core.DockerMonitor
+}
+
+// ContinueOnStop DockerHealth is only called if the process is still running
+func (m *DockerHealth) ContinueOnStop() bool {
+	return false
+}
+
+// Run fails the execution if the docker daemon is unreachable, or waits until
+// is reachable again if the policy is queue
+func (m *DockerHealth) Run(ctx *core.Context) error {
+	err := m.Monitor.Err()
+	if err == nil {
+		return ctx.Next()
+	}
+
+	if m.DockerUnreachable != DockerUnreachableQueue {
+		return fmt.Errorf("docker daemon is unreachable: %s", err)
+	}
+
+	queued(ctx, "Queued until the docker daemon is reachable", m.Monitor.Wait)
+	return ctx.Next()
+}
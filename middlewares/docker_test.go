@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/fsouza/go-dockerclient/testing"
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteDockerHealth struct {
+	BaseSuite
+	server  *testing.DockerServer
+	monitor *core.DockerMonitor
+}
+
+var _ = Suite(&SuiteDockerHealth{})
+
+func (s *SuiteDockerHealth) SetUpTest(c *C) {
+	s.BaseSuite.SetUpTest(c)
+
+	var err error
+	s.server, err = testing.NewServer("127.0.0.1:0", nil, nil)
+	c.Assert(err, IsNil)
+
+	client, err := docker.NewClient(s.server.URL())
+	c.Assert(err, IsNil)
+
+	s.monitor = core.NewDockerMonitor(client, s.ctx.Logger)
+	s.monitor.MinBackoff = 10 * time.Millisecond
+}
+
+func (s *SuiteDockerHealth) TearDownTest(c *C) {
+	s.monitor.Stop()
+	s.server.Stop()
+}
+
+func (s *SuiteDockerHealth) TestNewDockerHealthEmpty(c *C) {
+	c.Assert(NewDockerHealth(&DockerHealthConfig{}, nil), IsNil)
+}
+
+func (s *SuiteDockerHealth) TestRun(c *C) {
+	m := NewDockerHealth(&DockerHealthConfig{}, s.monitor)
+	c.Assert(m.Run(s.ctx), IsNil)
+}
+
+func (s *SuiteDockerHealth) TestRunUnreachable(c *C) {
+	s.server.PrepareFailure("ping", "/_ping")
+
+	m := NewDockerHealth(&DockerHealthConfig{}, s.monitor)
+	c.Assert(m.Run(s.ctx), ErrorMatches, "docker daemon is unreachable: (?s).*")
+}
+
+func (s *SuiteDockerHealth) TestRunUnreachableQueue(c *C) {
+	// the pings fail until reachable is set, the first failure being sent
+	var reachable int32
+	failed := make(chan struct{}, 1)
+	s.server.CustomHandler("/_ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&reachable) == 1 {
+			s.server.DefaultHandler().ServeHTTP(w, r)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+	}))
+
+	m := NewDockerHealth(&DockerHealthConfig{DockerUnreachable: DockerUnreachableQueue}, s.monitor)
+
+	done := make(chan error)
+	go func() { done <- m.Run(s.ctx) }()
+
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		c.Fatal("the daemon wasn't pinged")
+	}
+
+	atomic.StoreInt32(&reachable, 1)
+
+	select {
+	case err := <-done:
+		c.Assert(err, IsNil)
+	case <-time.After(time.Second):
+		c.Fatal("still waiting after the daemon is reachable")
+	}
+}
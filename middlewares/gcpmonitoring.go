@@ -0,0 +1,216 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const (
+	gcpMonitoringEndpoint = "https://monitoring.googleapis.com"
+	gcpMetricPrefix       = "custom.googleapis.com/ofelia/job/"
+	gcpTimeout            = 10 * time.Second
+)
+
+// GCPMonitoringConfig configuration for the GCPMonitoring middleware
+type GCPMonitoringConfig struct {
+	// GCPMonitoringProject ID of the project of the metrics, enabling the
+	// middleware
+	GCPMonitoringProject string `gcfg:"gcp-monitoring-project" mapstructure:"gcp-monitoring-project"`
+	GCPMonitoringRetries int    `gcfg:"gcp-monitoring-retries" mapstructure:"gcp-monitoring-retries"`
+}
+
+// NewGCPMonitoring returns a GCPMonitoring middleware if the given
+// configuration is not empty
+func NewGCPMonitoring(c *GCPMonitoringConfig) core.Middleware {
+	var m core.Middleware
+	if c.GCPMonitoringProject != "" {
+		metadata := "http://metadata.google.internal"
+		if host := os.Getenv("GCE_METADATA_HOST"); host != "" {
+			metadata = "http://" + host
+		}
+
+		m = &GCPMonitoring{
+			GCPMonitoringConfig: *c,
+			endpoint:            gcpMonitoringEndpoint,
+			metadata:            metadata,
+			client:              &http.Client{Timeout: gcpTimeout},
+		}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "gcp-monitoring",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &GCPMonitoringConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewGCPMonitoring(c.(*GCPMonitoringConfig)) },
+	})
+}
+
+// GCPMonitoring middleware writes the metrics of every execution of the job
+// to Google Cloud Monitoring, with the name of the job as the job label. The
+// credentials are the ones of the service account of the instance, read from
+// the metadata server.
+type GCPMonitoring struct {
+	GCPMonitoringConfig
+	TagFilter
+
+	endpoint string
+	metadata string
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// ContinueOnStop return allways true, we want always report the final status
+func (m *GCPMonitoring) ContinueOnStop() bool {
+	return true
+}
+
+// Run writes the metrics of the execution, the skipped ones aren't written
+func (m *GCPMonitoring) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	if !m.notifies(ctx) {
+		return err
+	}
+
+	if !ctx.Execution.Skipped {
+		if err := m.Notify(ctx); err != nil {
+			notificationFailed(ctx, "gcp-monitoring", err)
+		}
+	}
+
+	return err
+}
+
+// Notify writes the metrics of the execution, the gauges duration, in
+// seconds, failed, 1 or 0, and exit_code
+func (m *GCPMonitoring) Notify(ctx *core.Context) error {
+	e := ctx.Execution
+	failed := "0"
+	if e.Failed {
+		failed = "1"
+	}
+
+	labels := map[string]string{"job": ctx.Job.GetName()}
+	for _, t := range tagLabels(ctx.Job) {
+		labels[t[0]] = t[1]
+	}
+
+	end := e.Date.Add(e.Duration).UTC().Format(time.RFC3339Nano)
+	series := func(name, kind string, value map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"metric": map[string]interface{}{
+				"type":   gcpMetricPrefix + name,
+				"labels": labels,
+			},
+			"resource": map[string]interface{}{
+				"type":   "global",
+				"labels": map[string]string{"project_id": m.GCPMonitoringProject},
+			},
+			"metricKind": "GAUGE",
+			"valueType":  kind,
+			"points": []interface{}{map[string]interface{}{
+				"interval": map[string]string{"endTime": end},
+				"value":    value,
+			}},
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"timeSeries": []interface{}{
+		series("duration", "DOUBLE", map[string]interface{}{"doubleValue": e.Duration.Seconds()}),
+		series("failed", "INT64", map[string]interface{}{"int64Value": failed}),
+		series("exit_code", "INT64", map[string]interface{}{"int64Value": fmt.Sprint(e.ExitCode)}),
+	}})
+
+	return notifyWithRetry(m.GCPMonitoringRetries, func() error {
+		return m.write(body)
+	})
+}
+
+func (m *GCPMonitoring) write(body []byte) error {
+	token, err := m.accessToken()
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/v3/projects/%s/timeSeries", m.endpoint, m.GCPMonitoringProject)
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	return m.do(req, nil)
+}
+
+// accessToken returns the access token of the service account of the
+// instance, cached until it expires
+func (m *GCPMonitoring) accessToken() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.token != "" && time.Now().Before(m.expires) {
+		return m.token, nil
+	}
+
+	req, err := http.NewRequest(
+		http.MethodGet, m.metadata+"/computeMetadata/v1/instance/service-accounts/default/token", nil,
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if err := m.do(req, &token); err != nil {
+		return "", fmt.Errorf("error reading the token of the instance: %s", err)
+	}
+
+	// renewed a minute before its expiration
+	m.token = token.AccessToken
+	m.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return m.token, nil
+}
+
+func (m *GCPMonitoring) do(req *http.Request, out interface{}) error {
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
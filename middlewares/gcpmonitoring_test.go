@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteGCPMonitoring struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteGCPMonitoring{})
+
+func (s *SuiteGCPMonitoring) TestNewGCPMonitoringEmpty(c *C) {
+	c.Assert(NewGCPMonitoring(&GCPMonitoringConfig{}), IsNil)
+}
+
+func (s *SuiteGCPMonitoring) TestRun(c *C) {
+	var tokens, writes int
+	var series []struct {
+		Metric struct {
+			Type   string
+			Labels map[string]string
+		}
+		ValueType string
+		Points    []struct {
+			Value map[string]interface{}
+		}
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			c.Assert(r.Header.Get("Metadata-Flavor"), Equals, "Google")
+			tokens++
+			w.Write([]byte(`{"access_token": "t0k3n", "expires_in": 3600}`))
+		case "/v3/projects/acme/timeSeries":
+			c.Assert(r.Header.Get("Authorization"), Equals, "Bearer t0k3n")
+			writes++
+
+			var body struct{ TimeSeries json.RawMessage }
+			c.Assert(json.NewDecoder(r.Body).Decode(&body), IsNil)
+			c.Assert(json.Unmarshal(body.TimeSeries, &series), IsNil)
+		default:
+			c.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+
+	defer ts.Close()
+
+	m := NewGCPMonitoring(&GCPMonitoringConfig{GCPMonitoringProject: "acme"}).(*GCPMonitoring)
+	m.endpoint, m.metadata = ts.URL, ts.URL
+
+	s.job.Name = "foo"
+	for i := 0; i < 2; i++ {
+		s.ctx.Start()
+		s.ctx.Stop(nil)
+		c.Assert(m.Run(s.ctx), IsNil)
+	}
+
+	c.Assert(s.ctx.Execution.NotificationErrors, IsNil)
+	c.Assert(tokens, Equals, 1)
+	c.Assert(writes, Equals, 2)
+	c.Assert(series, HasLen, 3)
+	c.Assert(series[0].Metric.Type, Equals, "custom.googleapis.com/ofelia/job/duration")
+	c.Assert(series[0].Metric.Labels, DeepEquals, map[string]string{"job": "foo"})
+	c.Assert(series[1].Metric.Type, Equals, "custom.googleapis.com/ofelia/job/failed")
+	c.Assert(series[1].ValueType, Equals, "INT64")
+	c.Assert(series[1].Points[0].Value, DeepEquals, map[string]interface{}{"int64Value": "0"})
+}
@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// GroupStatus middleware sends the status of a group of jobs with the given
+// alerters, once every job of the group finished an execution since the last
+// status: all succeeded or some failed. The jobs of the group are the ones of
+// the scheduler declaring it, see core.JobGroup.
+type GroupStatus struct {
+	Group string
+
+	alerters []Alerter
+	mu       sync.Mutex
+	// failed the jobs finished since the last status, true if any of their
+	// executions failed
+	failed map[string]bool
+}
+
+// NewGroupStatus returns a GroupStatus middleware of the given group, sending
+// its status with the alerters of the given middlewares, nil if none
+func NewGroupStatus(group string, ms []core.Middleware) core.Middleware {
+	var alerters []Alerter
+	for _, m := range ms {
+		if a, ok := m.(Alerter); ok {
+			alerters = append(alerters, a)
+		}
+	}
+
+	if len(alerters) == 0 {
+		return nil
+	}
+
+	return &GroupStatus{Group: group, alerters: alerters, failed: make(map[string]bool)}
+}
+
+// ContinueOnStop return allways true, we want always report the final status
+func (m *GroupStatus) ContinueOnStop() bool {
+	return true
+}
+
+// Run records the result of the execution, sending the status of the group if
+// it was the last job pending
+func (m *GroupStatus) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	if ctx.Execution.Skipped {
+		return err
+	}
+
+	if title, text, ok := m.add(ctx); ok {
+		for _, a := range m.alerters {
+			a.Alert(ctx, title, text)
+		}
+	}
+
+	return err
+}
+
+// add records the execution, returning the status once all the jobs of the
+// group finished
+func (m *GroupStatus) add(ctx *core.Context) (title, text string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failed[ctx.Job.GetName()] = m.failed[ctx.Job.GetName()] || ctx.Execution.Failed
+
+	var jobs int
+	for _, j := range ctx.Scheduler.GetJobs() {
+		if core.JobGroup(j) != m.Group {
+			continue
+		}
+
+		if _, finished := m.failed[j.GetName()]; !finished {
+			return "", "", false
+		}
+
+		jobs++
+	}
+
+	var failed []string
+	for name, f := range m.failed {
+		if f {
+			failed = append(failed, name)
+		}
+	}
+
+	m.failed = make(map[string]bool)
+	if len(failed) == 0 {
+		return "Group succeeded", fmt.Sprintf("Group %q finished, all %d jobs succeeded", m.Group, jobs), true
+	}
+
+	sort.Strings(failed)
+	return "Group failed", fmt.Sprintf(
+		"Group %q finished, %d of %d jobs failed: %s",
+		m.Group, len(failed), jobs, strings.Join(failed, ", "),
+	), true
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"errors"
+
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteGroupStatus struct{}
+
+var _ = Suite(&SuiteGroupStatus{})
+
+type testAlerter struct {
+	alerts []string
+}
+
+func (a *testAlerter) ContinueOnStop() bool        { return true }
+func (a *testAlerter) Run(ctx *core.Context) error { return ctx.Next() }
+
+func (a *testAlerter) Alert(ctx *core.Context, title, text string) {
+	a.alerts = append(a.alerts, title+": "+text)
+}
+
+func (s *SuiteGroupStatus) TestNewGroupStatus(c *C) {
+	c.Assert(NewGroupStatus("backups", nil), IsNil)
+	c.Assert(NewGroupStatus("backups", []core.Middleware{&Overlap{}}), IsNil)
+	c.Assert(NewGroupStatus("backups", []core.Middleware{&testAlerter{}}), NotNil)
+}
+
+func (s *SuiteGroupStatus) TestRun(c *C) {
+	a := &testAlerter{}
+	m := NewGroupStatus("backups", []core.Middleware{a})
+
+	sh := core.NewScheduler(&TestLogger{})
+	foo, bar, other := &TestJob{}, &TestJob{}, &TestJob{}
+	foo.Name, foo.Group = "foo", "backups"
+	bar.Name, bar.Group = "bar", "backups"
+	other.Name = "other"
+	for _, j := range []*TestJob{foo, bar, other} {
+		sh.AddTriggeredJob(j)
+	}
+
+	run := func(j core.Job, err error) {
+		ctx := core.NewContext(sh, j, core.NewExecution())
+		ctx.Start()
+		if err != nil {
+			ctx.Stop(err)
+		}
+
+		m.Run(ctx)
+	}
+
+	run(foo, nil)
+	run(foo, nil)
+	c.Assert(a.alerts, HasLen, 0)
+
+	run(bar, nil)
+	c.Assert(a.alerts, DeepEquals, []string{`Group succeeded: Group "backups" finished, all 2 jobs succeeded`})
+
+	run(bar, errors.New("foo"))
+	run(bar, nil)
+	run(foo, nil)
+	c.Assert(a.alerts[1:], DeepEquals, []string{`Group failed: Group "backups" finished, 1 of 2 jobs failed: bar`})
+}
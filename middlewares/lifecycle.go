@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// Announcer is a notifier announcing the lifecycle of the daemon: its start,
+// the reloads of its config and its stop
+type Announcer interface {
+	// Announce sends the announcement if the lifecycle is enabled, the
+	// failures are logged
+	Announce(s *core.Scheduler, title, text string)
+}
+
+// Announce sends the announcement with the global notifiers of the given
+// scheduler
+func Announce(s *core.Scheduler, title, text string) {
+	for _, m := range s.Middlewares() {
+		if a, ok := m.(Announcer); ok {
+			a.Announce(s, title, text)
+		}
+	}
+}
+
+// AnnounceStart announces the start of the daemon, with its number of jobs
+func AnnounceStart(s *core.Scheduler) {
+	Announce(s, "Ofelia started", fmt.Sprintf("Ofelia started on %s with %d jobs", hostname(), len(s.GetJobs())))
+}
+
+// AnnounceReload announces a reload of the config, with the names of the
+// jobs added, updated and removed, nothing if none changed
+func AnnounceReload(s *core.Scheduler, added, updated, removed []string) {
+	if len(added)+len(updated)+len(removed) == 0 {
+		return
+	}
+
+	Announce(s, "Ofelia config reloaded", fmt.Sprintf(
+		"Ofelia config reloaded on %s, %s, %s, %s, %d jobs",
+		hostname(), changes("added", added), changes("updated", updated),
+		changes("removed", removed), len(s.GetJobs()),
+	))
+}
+
+// AnnounceStop announces the stop of the daemon
+func AnnounceStop(s *core.Scheduler) {
+	Announce(s, "Ofelia stopped", fmt.Sprintf("Ofelia stopped on %s with %d jobs", hostname(), len(s.GetJobs())))
+}
+
+// changes returns the number of jobs with the given change, and their names
+func changes(change string, jobs []string) string {
+	if len(jobs) == 0 {
+		return "0 " + change
+	}
+
+	sorted := append([]string{}, jobs...)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%d %s (%s)", len(jobs), change, strings.Join(sorted, ", "))
+}
+
+func hostname() string {
+	host, _ := os.Hostname()
+	return host
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteLifecycle struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteLifecycle{})
+
+func (s *SuiteLifecycle) TestAnnounce(c *C) {
+	var titles []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		titles = append(titles, m.Attachments[0].Title)
+	}))
+
+	defer ts.Close()
+
+	sh := s.ctx.Scheduler
+	sh.Use(NewSlack(&SlackConfig{SlackWebhook: ts.URL, SlackLifecycle: true}))
+	sh.Use(NewSlack(&SlackConfig{SlackWebhook: ts.URL}))
+
+	AnnounceStart(sh)
+	AnnounceReload(sh, nil, nil, nil)
+	AnnounceReload(sh, []string{"foo"}, nil, nil)
+	AnnounceStop(sh)
+
+	c.Assert(titles, DeepEquals, []string{"Ofelia started", "Ofelia config reloaded", "Ofelia stopped"})
+}
+
+func (s *SuiteLifecycle) TestChanges(c *C) {
+	c.Assert(changes("added", nil), Equals, "0 added")
+	c.Assert(changes("removed", []string{"foo", "bar"}), Equals, "2 removed (bar, foo)")
+	c.Assert(strings.HasPrefix(changes("updated", []string{"foo"}), "1 updated"), Equals, true)
+}
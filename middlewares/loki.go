@@ -0,0 +1,188 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const lokiTimeout = 10 * time.Second
+
+// LokiConfig configuration for the Loki middleware
+type LokiConfig struct {
+	// LokiURL base URL of Loki, e.g. "http://loki:3100"
+	LokiURL string `gcfg:"loki-url" mapstructure:"loki-url"`
+	// LokiLabels extra labels of the streams, as "<name>=<value>"
+	LokiLabels []string `gcfg:"loki-label" mapstructure:"loki-label"`
+	// LokiTenant tenant of a multi-tenant Loki, sent as X-Scope-OrgID
+	LokiTenant      string `gcfg:"loki-tenant" mapstructure:"loki-tenant"`
+	LokiToken       string `gcfg:"loki-token" mapstructure:"loki-token" secret:"true"`
+	LokiOnlyOnError bool   `gcfg:"loki-only-on-error" mapstructure:"loki-only-on-error"`
+	LokiRetries     int    `gcfg:"loki-retries" mapstructure:"loki-retries"`
+}
+
+// NewLoki returns a Loki middleware if the given configuration is not empty
+func NewLoki(c *LokiConfig) core.Middleware {
+	var m core.Middleware
+	if c.LokiURL != "" {
+		m = &Loki{LokiConfig: *c, client: &http.Client{Timeout: lokiTimeout}}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "loki",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &LokiConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewLoki(c.(*LokiConfig)) },
+	})
+}
+
+// Loki middleware pushes the output of every execution, and the messages
+// logged about it, to Loki
+type Loki struct {
+	LokiConfig
+	TagFilter
+
+	client *http.Client
+}
+
+// ContinueOnStop return allways true, we want always report the final status
+func (m *Loki) ContinueOnStop() bool {
+	return true
+}
+
+// Run pushes the logs of the execution
+func (m *Loki) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	if !m.notifies(ctx) {
+		return err
+	}
+
+	if ctx.Execution.Failed || !m.LokiOnlyOnError {
+		if err := m.Notify(ctx); err != nil {
+			notificationFailed(ctx, "loki", err)
+		}
+	}
+
+	return err
+}
+
+// lokiStream a stream of the push API of Loki, its values being the
+// timestamp, in nanoseconds, and the line
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Notify pushes the logs of the execution, as three streams, labeled by
+// their source: "ofelia", with the messages logged about the execution,
+// "stdout" and "stderr". The lines of the output are dated at the end of the
+// execution, in order.
+func (m *Loki) Notify(ctx *core.Context) error {
+	e := ctx.Execution
+	labels := map[string]string{
+		"job":       ctx.Job.GetName(),
+		"status":    executionLabel(e),
+		"execution": e.ID,
+	}
+
+	for _, l := range m.LokiLabels {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid loki-label %q, must be <name>=<value>", l)
+		}
+
+		labels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	end := e.Date.Add(e.Duration)
+	summary := fmt.Sprintf("Finished in %q, failed: %t, skipped: %t", e.Duration, e.Failed, e.Skipped)
+	if e.Error != nil {
+		summary += ", error: " + e.Error.Error()
+	}
+
+	var messages [][2]string
+	for _, msg := range e.Messages() {
+		messages = append(messages, lokiValue(msg.Date, msg.Level+": "+msg.Text))
+	}
+
+	messages = append(messages, lokiValue(end, ctx.Redact(summary)))
+
+	streams := []lokiStream{{Stream: lokiLabels(labels, "ofelia"), Values: messages}}
+	for source, output := range map[string]string{"stdout": e.OutputStream.String(), "stderr": e.ErrorStream.String()} {
+		var values [][2]string
+		s := bufio.NewScanner(strings.NewReader(ctx.Redact(output)))
+		s.Buffer(nil, 1024*1024)
+		for i := 0; s.Scan(); i++ {
+			values = append(values, lokiValue(end.Add(time.Duration(i)), s.Text()))
+		}
+
+		if len(values) > 0 {
+			streams = append(streams, lokiStream{Stream: lokiLabels(labels, source), Values: values})
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"streams": streams})
+	return notifyWithRetry(m.LokiRetries, func() error {
+		return m.push(body)
+	})
+}
+
+func (m *Loki) push(body []byte) error {
+	req, err := http.NewRequest(
+		http.MethodPost, strings.TrimSuffix(m.LokiURL, "/")+"/loki/api/v1/push", bytes.NewReader(body),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if m.LokiTenant != "" {
+		req.Header.Set("X-Scope-OrgID", m.LokiTenant)
+	}
+
+	if m.LokiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+m.LokiToken)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("loki returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// lokiLabels returns the given labels with the source one
+func lokiLabels(labels map[string]string, source string) map[string]string {
+	l := map[string]string{"source": source}
+	for k, v := range labels {
+		l[k] = v
+	}
+
+	return l
+}
+
+func lokiValue(t time.Time, line string) [2]string {
+	return [2]string{fmt.Sprint(t.UnixNano()), line}
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteLoki struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteLoki{})
+
+func (s *SuiteLoki) TestNewLokiEmpty(c *C) {
+	c.Assert(NewLoki(&LokiConfig{}), IsNil)
+}
+
+func (s *SuiteLoki) TestRun(c *C) {
+	var push struct{ Streams []lokiStream }
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, Equals, "/loki/api/v1/push")
+		c.Assert(r.Header.Get("X-Scope-OrgID"), Equals, "acme")
+		c.Assert(json.NewDecoder(r.Body).Decode(&push), IsNil)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.ctx.Start()
+	s.ctx.Warn("disk almost full")
+	s.ctx.Execution.OutputStream.Write([]byte("foo\nbar\n"))
+	s.ctx.Stop(errors.New("exit 1"))
+
+	m := NewLoki(&LokiConfig{LokiURL: ts.URL + "/", LokiTenant: "acme", LokiLabels: []string{"env = prod"}})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.NotificationErrors, IsNil)
+
+	sources := make(map[string]lokiStream)
+	for _, stream := range push.Streams {
+		sources[stream.Stream["source"]] = stream
+	}
+
+	c.Assert(sources, HasLen, 2)
+	c.Assert(sources["stdout"].Stream, DeepEquals, map[string]string{
+		"source":    "stdout",
+		"job":       "foo",
+		"status":    "failed",
+		"execution": s.ctx.Execution.ID,
+		"env":       "prod",
+	})
+
+	stdout := sources["stdout"].Values
+	c.Assert(stdout, HasLen, 2)
+	c.Assert(stdout[0][1], Equals, "foo")
+	c.Assert(stdout[1][1], Equals, "bar")
+	c.Assert(stdout[0][0] < stdout[1][0], Equals, true)
+
+	messages := sources["ofelia"].Values
+	c.Assert(messages, HasLen, 2)
+	c.Assert(messages[0][1], Equals, "warning: disk almost full")
+	c.Assert(messages[1][1], Matches, `Finished in ".*", failed: true, skipped: false, error: exit 1`)
+}
+
+func (s *SuiteLoki) TestRunOnlyOnError(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected push")
+	}))
+
+	defer ts.Close()
+
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewLoki(&LokiConfig{LokiURL: ts.URL, LokiOnlyOnError: true})
+	c.Assert(m.Run(s.ctx), IsNil)
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/gomail.v2"
 
@@ -19,10 +21,17 @@ type MailConfig struct {
 	SMTPHost        string `gcfg:"smtp-host" mapstructure:"smtp-host"`
 	SMTPPort        int    `gcfg:"smtp-port" mapstructure:"smtp-port"`
 	SMTPUser        string `gcfg:"smtp-user" mapstructure:"smtp-user"`
-	SMTPPassword    string `gcfg:"smtp-password" mapstructure:"smtp-password"`
+	SMTPPassword    string `gcfg:"smtp-password" mapstructure:"smtp-password" secret:"true"`
 	EmailTo         string `gcfg:"email-to" mapstructure:"email-to"`
 	EmailFrom       string `gcfg:"email-from" mapstructure:"email-from"`
 	MailOnlyOnError bool   `gcfg:"mail-only-on-error" mapstructure:"mail-only-on-error"`
+	MailRetries     int    `gcfg:"mail-retries" mapstructure:"mail-retries"`
+	// MailDigest batches the executions in a single mail, sent "hourly" or
+	// "daily", instead of a mail per execution
+	MailDigest string `gcfg:"mail-digest" mapstructure:"mail-digest"`
+	// MailLifecycle sends a mail when the daemon starts, stops or reloads
+	// its config, only used by the global config
+	MailLifecycle bool `gcfg:"mail-lifecycle" mapstructure:"mail-lifecycle"`
 }
 
 // NewMail returns a Mail middleware if the given configuration is not empty
@@ -30,15 +39,33 @@ func NewMail(c *MailConfig) core.Middleware {
 	var m core.Middleware
 
 	if !IsEmpty(c) {
-		m = &Mail{*c}
+		mail := &Mail{MailConfig: *c}
+		if next := digestPeriod(c.MailDigest); next != nil {
+			mail.digest = &mailDigest{mail: mail, next: next}
+		}
+
+		m = mail
 	}
 
 	return m
 }
 
+func init() {
+	Register(Plugin{
+		Name:   "mail",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &MailConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewMail(c.(*MailConfig)) },
+	})
+}
+
 // Mail middleware delivers a email just after an execution finishes
 type Mail struct {
 	MailConfig
+	TagFilter
+
+	digest *mailDigest
 }
 
 // ContinueOnStop return allways true, we want always report the final status
@@ -51,16 +78,82 @@ func (m *Mail) Run(ctx *core.Context) error {
 	err := ctx.Next()
 	ctx.Stop(err)
 
-	if ctx.Execution.Failed || !m.MailOnlyOnError {
-		err := m.sendMail(ctx)
-		if err != nil {
-			ctx.Logger.Errorf("Mail error: %q", err)
-		}
+	if !m.notifies(ctx) {
+		return err
+	}
+
+	if !ctx.Execution.Failed && m.MailOnlyOnError {
+		return err
+	}
+
+	if m.digest != nil {
+		m.digest.add(ctx)
+		return err
+	}
+
+	if m.MailDigest != "" {
+		ctx.Warn(fmt.Sprintf("invalid mail-digest %q, must be \"hourly\" or \"daily\"", m.MailDigest))
+	}
+
+	if err := m.Notify(ctx); err != nil {
+		notificationFailed(ctx, "mail", err)
 	}
 
 	return err
 }
 
+// Notify sends the email, retrying on failure
+func (m *Mail) Notify(ctx *core.Context) error {
+	return notifyWithRetry(m.MailRetries, func() error {
+		return m.sendMail(ctx)
+	})
+}
+
+// Alert sends a email with the alert
+func (m *Mail) Alert(ctx *core.Context, title, text string) {
+	if !m.notifies(ctx) {
+		return
+	}
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", m.from())
+	msg.SetHeader("To", strings.Split(m.EmailTo, ",")...)
+	msg.SetHeader("Subject", fmt.Sprintf("[%s] Job %s", title, ctx.Job.GetName()))
+	msg.SetBody("text/plain", ctx.Redact(text))
+
+	err := notifyWithRetry(m.MailRetries, func() error {
+		d := gomail.NewPlainDialer(m.SMTPHost, m.SMTPPort, m.SMTPUser, m.SMTPPassword)
+		return d.DialAndSend(msg)
+	})
+
+	if err != nil {
+		notificationFailed(ctx, "mail", err)
+	}
+}
+
+// Announce sends a mail with the announcement, if mail-lifecycle is set
+func (m *Mail) Announce(s *core.Scheduler, title, text string) {
+	if !m.MailLifecycle {
+		return
+	}
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", m.from())
+	msg.SetHeader("To", strings.Split(m.EmailTo, ",")...)
+	msg.SetHeader("Subject", fmt.Sprintf("[%s] %s", title, hostname()))
+	msg.SetBody("text/plain", text)
+
+	err := notifyWithRetry(m.MailRetries, func() error {
+		d := gomail.NewPlainDialer(m.SMTPHost, m.SMTPPort, m.SMTPUser, m.SMTPPassword)
+		return d.DialAndSend(msg)
+	})
+
+	if err != nil {
+		NotificationFailures.Add("mail", 1)
+		s.Logger.Errorf("Mail error: %q", err)
+	}
+}
+
 func (m *Mail) sendMail(ctx *core.Context) error {
 	msg := gomail.NewMessage()
 	msg.SetHeader("From", m.from())
@@ -85,7 +178,7 @@ func (m *Mail) sendMail(ctx *core.Context) error {
 			"Execution": ctx.Execution,
 		}, "", "  ")
 
-		_, err := w.Write(js)
+		_, err := w.Write([]byte(ctx.Redact(string(js))))
 		return err
 	}))
 
@@ -110,21 +203,131 @@ func (m *Mail) subject(ctx *core.Context) string {
 	buf := bytes.NewBuffer(nil)
 	mailSubjectTemplate.Execute(buf, ctx)
 
-	return buf.String()
+	return ctx.Redact(buf.String())
 }
 
 func (m *Mail) body(ctx *core.Context) string {
 	buf := bytes.NewBuffer(nil)
 	mailBodyTemplate.Execute(buf, ctx)
 
-	return buf.String()
+	return ctx.Redact(buf.String())
+}
+
+// digestEntry is an execution listed at a digest
+type digestEntry struct {
+	Job      string
+	Date     time.Time
+	Duration time.Duration
+	Status   string
+	Failed   bool
+	Error    string
+}
+
+// mailDigest accumulates the executions, sending a single mail listing them
+// at the end of each period
+type mailDigest struct {
+	mail *Mail
+	next func(time.Time) time.Time
+
+	mu      sync.Mutex
+	entries []digestEntry
+	logger  core.Logger
+	timer   *time.Timer
+}
+
+func (d *mailDigest) add(ctx *core.Context) {
+	e := digestEntry{
+		Job:      ctx.Job.GetName(),
+		Date:     ctx.Execution.Date,
+		Duration: ctx.Execution.Duration,
+		Status:   executionLabel(ctx.Execution),
+		Failed:   ctx.Execution.Failed,
+	}
+
+	if ctx.Execution.Error != nil {
+		e.Error = ctx.Redact(ctx.Execution.Error.Error())
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries = append(d.entries, e)
+	d.logger = logger(ctx)
+	if d.timer == nil {
+		now := time.Now()
+		d.timer = time.AfterFunc(d.next(now).Sub(now), d.flush)
+	}
+}
+
+// flush sends the digest of the executions added since the previous one
+func (d *mailDigest) flush() {
+	d.mu.Lock()
+	entries, logger := d.entries, d.logger
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+
+	d.entries, d.timer = nil, nil
+	d.mu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	err := notifyWithRetry(d.mail.MailRetries, func() error {
+		return d.mail.sendDigest(entries)
+	})
+
+	if err != nil {
+		NotificationFailures.Add("mail", 1)
+		logger.Errorf("Mail error: %q", err)
+	}
 }
 
+func (m *Mail) sendDigest(entries []digestEntry) error {
+	var failed int
+	for _, e := range entries {
+		if e.Failed {
+			failed++
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := mailDigestTemplate.Execute(buf, entries); err != nil {
+		return err
+	}
+
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", m.from())
+	msg.SetHeader("To", strings.Split(m.EmailTo, ",")...)
+	msg.SetHeader("Subject", fmt.Sprintf("[Digest] %d executions, %d failed", len(entries), failed))
+	msg.SetBody("text/html", buf.String())
+
+	d := gomail.NewPlainDialer(m.SMTPHost, m.SMTPPort, m.SMTPUser, m.SMTPPassword)
+	return d.DialAndSend(msg)
+}
+
+var mailDigestTemplate = template.Must(template.New("mail-digest").Parse(`
+	<table>
+		<tr><th>Date</th><th>Job</th><th>Status</th><th>Duration</th><th>Error</th></tr>
+		{{range .}}
+		<tr{{if .Failed}} style="color: #c00; font-weight: bold"{{end}}>
+			<td>{{.Date.Format "2006-01-02 15:04:05"}}</td>
+			<td>{{.Job}}</td>
+			<td>{{.Status}}</td>
+			<td>{{.Duration}}</td>
+			<td>{{.Error}}</td>
+		</tr>
+		{{end}}
+	</table>
+`))
+
 var mailBodyTemplate, mailSubjectTemplate *template.Template
 
 func init() {
 	f := map[string]interface{}{
-		"status": executionLabel,
+		"status":      executionLabel,
+		"annotations": annotations,
 	}
 
 	mailBodyTemplate = template.New("mail-body")
@@ -136,12 +339,22 @@ func init() {
 		<p>
 			Job ​<b>{{.Job.GetName}}</b>,
 			Execution <b>{{status .Execution}}</b> in ​<b>{{.Execution.Duration}}</b>​,
+			{{- with .Execution.Late}} started <b>{{.}}</b> late,{{end}}
 			command: ​<pre>{{.Job.GetCommand}}</pre>​
+			{{- range annotations .Job}}
+			<br>{{.}}
+			{{- end}}
+			{{- with .Execution.OutputDiff}}
+			<br>Output changes since the last successful execution: <pre>{{.}}</pre>
+			{{- end}}
+			{{- with .ExecutionURL}}
+			<br><a href="{{.}}">Execution details</a>
+			{{- end}}
 		</p>
   `))
 
 	template.Must(mailSubjectTemplate.Parse(
-		"[Execution {{status .Execution}}] Job {{.Job.GetName}} finished in {{.Execution.Duration}}",
+		"[Execution {{status .Execution}}] Job {{.Job.GetName}}{{with .Execution.Params.matrix}} ({{.}}){{end}} finished in {{.Execution.Duration}}",
 	))
 }
 
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bradfitz/go-smtpd/smtpd"
 
@@ -77,3 +78,71 @@ func (s *MailSuite) TestRunSuccess(c *C) {
 
 	wg.Wait()
 }
+
+func (s *MailSuite) TestRunDigest(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewMail(&MailConfig{
+		SMTPHost:   s.smtpdHost,
+		SMTPPort:   s.smtpdPort,
+		EmailTo:    "foo@foo.com",
+		EmailFrom:  "qux@qux.com",
+		MailDigest: "hourly",
+	}).(*Mail)
+
+	var mu sync.Mutex
+	var mails int
+	s.smtpd.OnNewMail = func(_ smtpd.Connection, from smtpd.MailAddress) (smtpd.Envelope, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		mails++
+
+		return nil, nil
+	}
+
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(m.digest.entries, HasLen, 2)
+
+	m.digest.flush()
+	c.Assert(m.digest.entries, HasLen, 0)
+	c.Assert(m.digest.timer, IsNil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(mails, Equals, 1)
+}
+
+func (s *MailSuite) TestBodyLate(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewMail(&MailConfig{EmailTo: "foo@foo.com"}).(*Mail)
+	c.Assert(strings.Contains(m.body(s.ctx), " late,"), Equals, false)
+
+	s.ctx.Execution.Skew = 2 * time.Second
+	c.Assert(strings.Contains(m.body(s.ctx), "started <b>2s</b> late,"), Equals, true)
+}
+
+func (s *MailSuite) TestBodyOutputDiff(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewMail(&MailConfig{EmailTo: "foo@foo.com"}).(*Mail)
+	c.Assert(strings.Contains(m.body(s.ctx), "Output changes"), Equals, false)
+
+	s.ctx.Execution.OutputDiff = "-a <b>\n+b"
+	c.Assert(strings.Contains(m.body(s.ctx), "<pre>-a &lt;b&gt;\n&#43;b</pre>"), Equals, true)
+}
+
+func (s *MailSuite) TestBodyExecutionURL(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewMail(&MailConfig{EmailTo: "foo@foo.com"}).(*Mail)
+	c.Assert(strings.Contains(m.body(s.ctx), "Execution details"), Equals, false)
+
+	s.ctx.Scheduler.ExternalURL = "https://ofelia.example.com"
+	c.Assert(strings.Contains(m.body(s.ctx), `<a href="https://ofelia.example.com/api/executions/`+s.ctx.Execution.ID+`">Execution details</a>`), Equals, true)
+}
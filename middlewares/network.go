@@ -0,0 +1,161 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const (
+	// NetworkSkip skips the executions while a required target is unreachable
+	NetworkSkip = "skip"
+	// NetworkDefer defers the executions until the required targets are
+	// reachable, up to the network-deadline
+	NetworkDefer = "defer"
+
+	defaultNetworkTimeout  = 5 * time.Second
+	defaultNetworkDeadline = time.Hour
+)
+
+// NetworkConfig configuration for the Network middleware
+type NetworkConfig struct {
+	// RequireNetwork targets reachable before running the executions, comma
+	// separated, as "host:port" or URL, e.g. "db:5432,https://example.com"
+	RequireNetwork string `gcfg:"require-network" mapstructure:"require-network"`
+	// NetworkPolicy what happens to the executions while a target is
+	// unreachable, "skip" (default) or "defer"
+	NetworkPolicy string `gcfg:"network-policy" mapstructure:"network-policy"`
+	// NetworkTimeout of the check of every target, 5s by default
+	NetworkTimeout string `gcfg:"network-timeout" mapstructure:"network-timeout"`
+	// NetworkDeadline the executions still deferred after it are skipped, 1h
+	// by default
+	NetworkDeadline string `gcfg:"network-deadline" mapstructure:"network-deadline"`
+}
+
+// NewNetwork returns a Network middleware if the given configuration is not
+// empty
+func NewNetwork(c *NetworkConfig) core.Middleware {
+	var m core.Middleware
+	if c.RequireNetwork != "" {
+		m = &Network{NetworkConfig: *c}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "network",
+		Stage:  StageGuard,
+		Global: true,
+		Config: func() interface{} { return &NetworkConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewNetwork(c.(*NetworkConfig)) },
+	})
+}
+
+// Network middleware skips or defers the executions while the targets they
+// require are unreachable, e.g. a sync job on an offline edge device, instead
+// of failing
+type Network struct {
+	NetworkConfig
+}
+
+// ContinueOnStop Network is only called if the process is still running
+func (m *Network) ContinueOnStop() bool {
+	return false
+}
+
+// Run skips the execution, or defers it with exponential backoff up to the
+// deadline, while any required target is unreachable
+func (m *Network) Run(ctx *core.Context) error {
+	policy, timeout, deadline, err := m.parse()
+	if err != nil {
+		ctx.Warn(err.Error())
+		return ctx.Next()
+	}
+
+	targets := m.targets()
+	check := func() (string, error) {
+		for _, target := range targets {
+			if err := reach(target, timeout); err != nil {
+				return fmt.Sprintf("%s unreachable: %s", target, err), nil
+			}
+		}
+
+		return "", nil
+	}
+
+	if policy == NetworkDefer {
+		deferWhile(ctx, "network", deadline, check)
+		return ctx.Next()
+	}
+
+	if reason, _ := check(); reason != "" {
+		ctx.Log("Skipping, " + reason)
+		ctx.Stop(core.ErrSkippedExecution)
+	}
+
+	return ctx.Next()
+}
+
+func (m *Network) targets() []string {
+	var targets []string
+	for _, t := range strings.Split(m.RequireNetwork, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
+
+	return targets
+}
+
+func (m *Network) parse() (policy string, timeout, deadline time.Duration, err error) {
+	policy, timeout, deadline = NetworkSkip, defaultNetworkTimeout, defaultNetworkDeadline
+	switch m.NetworkPolicy {
+	case "", NetworkSkip:
+	case NetworkDefer:
+		policy = NetworkDefer
+	default:
+		return "", 0, 0, fmt.Errorf("invalid network-policy %q, must be %q or %q", m.NetworkPolicy, NetworkSkip, NetworkDefer)
+	}
+
+	if m.NetworkTimeout != "" {
+		if timeout, err = time.ParseDuration(m.NetworkTimeout); err != nil || timeout <= 0 {
+			return "", 0, 0, fmt.Errorf("invalid network-timeout %q, must be a positive duration", m.NetworkTimeout)
+		}
+	}
+
+	if m.NetworkDeadline != "" {
+		if deadline, err = time.ParseDuration(m.NetworkDeadline); err != nil || deadline <= 0 {
+			return "", 0, 0, fmt.Errorf("invalid network-deadline %q, must be a positive duration", m.NetworkDeadline)
+		}
+	}
+
+	return policy, timeout, deadline, nil
+}
+
+// reach returns an error if the given target isn't reachable within the
+// timeout, a URL is reachable if it answers, whatever the status, and a
+// "host:port" if it accepts a TCP connection
+func reach(target string, timeout time.Duration) error {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Head(target)
+		if err != nil {
+			return err
+		}
+
+		return resp.Body.Close()
+	}
+
+	conn, err := net.DialTimeout("tcp", target, timeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
@@ -0,0 +1,132 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteNetwork struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteNetwork{})
+
+func (s *SuiteNetwork) SetUpTest(c *C) {
+	s.BaseSuite.SetUpTest(c)
+	workloadBackoff, workloadMaxBackoff = time.Millisecond, 4*time.Millisecond
+}
+
+func (s *SuiteNetwork) TearDownTest(c *C) {
+	workloadBackoff, workloadMaxBackoff = 30*time.Second, 5*time.Minute
+}
+
+// closedAddr returns the address of a closed listener, refusing connections
+func closedAddr(c *C) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	addr := l.Addr().String()
+	c.Assert(l.Close(), IsNil)
+
+	return addr
+}
+
+func (s *SuiteNetwork) TestNewNetworkEmpty(c *C) {
+	c.Assert(NewNetwork(&NetworkConfig{}), IsNil)
+	c.Assert(NewNetwork(&NetworkConfig{NetworkPolicy: NetworkDefer}), IsNil)
+}
+
+func (s *SuiteNetwork) TestReach(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	c.Assert(reach(l.Addr().String(), time.Second), IsNil)
+	c.Assert(reach(closedAddr(c), time.Second), NotNil)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c.Assert(reach(ts.URL, time.Second), IsNil)
+	c.Assert(reach("http://"+closedAddr(c), time.Second), NotNil)
+}
+
+func (s *SuiteNetwork) TestRun(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	m := NewNetwork(&NetworkConfig{RequireNetwork: l.Addr().String()})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteNetwork) TestRunSkip(c *C) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+
+	m := NewNetwork(&NetworkConfig{RequireNetwork: l.Addr().String() + ", " + closedAddr(c)})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, true)
+}
+
+func (s *SuiteNetwork) TestRunDefer(c *C) {
+	addr := closedAddr(c)
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l, _ := net.Listen("tcp", addr)
+		listening <- l
+	}()
+
+	m := NewNetwork(&NetworkConfig{RequireNetwork: addr, NetworkPolicy: NetworkDefer})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+
+	l := <-listening
+	c.Assert(l, NotNil)
+	defer l.Close()
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteNetwork) TestRunDeferDeadline(c *C) {
+	m := NewNetwork(&NetworkConfig{
+		RequireNetwork:  closedAddr(c),
+		NetworkPolicy:   NetworkDefer,
+		NetworkDeadline: "10ms",
+	})
+
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, true)
+}
+
+func (s *SuiteNetwork) TestParse(c *C) {
+	policy, timeout, deadline, err := (&Network{NetworkConfig{RequireNetwork: "db:5432"}}).parse()
+	c.Assert(err, IsNil)
+	c.Assert(policy, Equals, NetworkSkip)
+	c.Assert(timeout, Equals, 5*time.Second)
+	c.Assert(deadline, Equals, time.Hour)
+
+	_, _, _, err = (&Network{NetworkConfig{NetworkPolicy: "wait"}}).parse()
+	c.Assert(err, ErrorMatches, `invalid network-policy "wait", must be "skip" or "defer"`)
+
+	_, _, _, err = (&Network{NetworkConfig{NetworkTimeout: "-1s"}}).parse()
+	c.Assert(err, ErrorMatches, `invalid network-timeout "-1s", must be a positive duration`)
+
+	_, _, _, err = (&Network{NetworkConfig{NetworkDeadline: "soon"}}).parse()
+	c.Assert(err, ErrorMatches, `invalid network-deadline "soon", must be a positive duration`)
+}
+
+func (s *SuiteNetwork) TestTargets(c *C) {
+	m := &Network{NetworkConfig{RequireNetwork: " db:5432, ,https://example.com "}}
+	c.Assert(m.targets(), DeepEquals, []string{"db:5432", "https://example.com"})
+}
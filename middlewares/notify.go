@@ -0,0 +1,169 @@
+package middlewares
+
+import (
+	"expvar"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// NotificationFailures counts the notifications failed, by notifier, published
+// as the expvar "notification_failures"
+var NotificationFailures = expvar.NewMap("notification_failures")
+
+// notifyRetryBackoff delay before the first retry of a notification, doubled
+// on every retry
+var notifyRetryBackoff = time.Second
+
+// Notifier is a middleware reporting the executions
+type Notifier interface {
+	core.Middleware
+	// Notify reports the execution, whatever its result
+	Notify(ctx *core.Context) error
+}
+
+// NotifyConfig configuration of the delivery of the notifications
+type NotifyConfig struct {
+	// NotifyFallback comma separated names of notifiers, e.g. "save,mail",
+	// only used when other notifier fails
+	NotifyFallback string `gcfg:"notify-fallback" mapstructure:"notify-fallback"`
+	// NotifyOnlyTags comma separated tags, e.g. "db,tier=critical", only the
+	// jobs with any of them are reported, prefixed by the name of a notifier,
+	// e.g. "slack:critical", they only apply to it. See TagFilter.
+	NotifyOnlyTags []string `gcfg:"notify-only-tags" mapstructure:"notify-only-tags"`
+	// NotifyExcludeTags comma separated tags, the jobs with any of them
+	// aren't reported, as NotifyOnlyTags
+	NotifyExcludeTags []string `gcfg:"notify-exclude-tags" mapstructure:"notify-exclude-tags"`
+	// Notifiers comma separated names of named notifiers, e.g. "ops,dev",
+	// declared as sections, e.g. [slack "ops"], reporting the executions
+	// along the other notifiers
+	Notifiers string `gcfg:"notifiers" mapstructure:"notifiers"`
+}
+
+// NotifierNames returns the names of the named notifiers of the config
+func (c *NotifyConfig) NotifierNames() []string {
+	var names []string
+	for _, name := range strings.Split(c.Notifiers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// newNotifiers returns the Fallback middleware of the notifiers listed in the
+// given configuration, and the other notifiers. The fallback should be used
+// before any other middleware.
+func newNotifiers(c *NotifyConfig, all []namedMiddleware) (core.Middleware, []core.Middleware) {
+	byName := make(map[string]core.Middleware, len(all))
+	for _, m := range all {
+		byName[m.name] = m.Middleware
+	}
+
+	// the fallback notifiers are tried in the listed order
+	f := &Fallback{}
+	for _, name := range strings.Split(c.NotifyFallback, ",") {
+		name = strings.TrimSpace(name)
+		if n, ok := byName[name].(Notifier); ok {
+			f.names = append(f.names, name)
+			f.notifiers = append(f.notifiers, n)
+			delete(byName, name)
+		}
+	}
+
+	var notifiers []core.Middleware
+	for _, m := range all {
+		if _, ok := byName[m.name]; ok {
+			notifiers = append(notifiers, m.Middleware)
+		}
+	}
+
+	if len(f.notifiers) == 0 {
+		return nil, notifiers
+	}
+
+	return f, notifiers
+}
+
+// Fallback middleware reports the execution with the first of its notifiers
+// succeeding, only if any other notifier failed
+type Fallback struct {
+	names     []string
+	notifiers []Notifier
+}
+
+// ContinueOnStop return allways true, we want always report the final status
+func (m *Fallback) ContinueOnStop() bool {
+	return true
+}
+
+// Run notifies the execution if any notification failed
+func (m *Fallback) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	if len(ctx.Execution.NotificationErrors) == 0 {
+		return err
+	}
+
+	for i, n := range m.notifiers {
+		if f, ok := n.(interface{ notifies(*core.Context) bool }); ok && !f.notifies(ctx) {
+			continue
+		}
+
+		if nerr := n.Notify(ctx); nerr != nil {
+			notificationFailed(ctx, m.names[i], nerr)
+			continue
+		}
+
+		ctx.Log("Notified by the fallback " + m.names[i])
+		break
+	}
+
+	return err
+}
+
+// digestPeriod returns the function returning the end of the period of a
+// digest or summary, nil if the value isn't "hourly" or "daily"
+func digestPeriod(value string) func(time.Time) time.Time {
+	switch value {
+	case "hourly":
+		return func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		}
+	case "daily":
+		return func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		}
+	}
+
+	return nil
+}
+
+// notifyWithRetry calls notify, retrying it with exponential backoff
+func notifyWithRetry(retries int, notify func() error) error {
+	backoff := notifyRetryBackoff
+	for i := 0; ; i++ {
+		err := notify()
+		if err == nil || i >= retries {
+			return err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// notificationFailed logs and records the failed notification
+func notificationFailed(ctx *core.Context, notifier string, err error) {
+	NotificationFailures.Add(notifier, 1)
+
+	if ctx.Execution.NotificationErrors == nil {
+		ctx.Execution.NotificationErrors = make(map[string]string)
+	}
+
+	ctx.Execution.NotificationErrors[notifier] = err.Error()
+	logger(ctx).Errorf("%s error: %q", strings.Title(notifier), err)
+}
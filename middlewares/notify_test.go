@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteNotify struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteNotify{})
+
+func (s *SuiteNotify) SetUpTest(c *C) {
+	s.BaseSuite.SetUpTest(c)
+	notifyRetryBackoff = time.Millisecond
+}
+
+func (s *SuiteNotify) TestBuildNotifiers(c *C) {
+	ms, err := Build(decodeFrom(
+		&NotifyConfig{NotifyFallback: "save, mail"},
+		&SlackConfig{SlackWebhook: "http://localhost"},
+		&SaveConfig{SaveFolder: "/tmp"},
+	))
+
+	c.Assert(err, IsNil)
+	c.Assert(ms, HasLen, 2)
+	c.Assert(ms[0].(*Fallback).names, DeepEquals, []string{"save"})
+	c.Assert(ms[1], FitsTypeOf, &Slack{})
+
+	ms, err = Build(decodeFrom(&SaveConfig{SaveFolder: "/tmp"}))
+	c.Assert(err, IsNil)
+	c.Assert(ms, HasLen, 1)
+	c.Assert(ms[0], FitsTypeOf, &Save{})
+}
+
+func (s *SuiteNotify) TestRetry(c *C) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	defer ts.Close()
+
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewSlack(&SlackConfig{SlackWebhook: ts.URL, SlackRetries: 2})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(calls, Equals, 3)
+	c.Assert(s.ctx.Execution.NotificationErrors, HasLen, 0)
+}
+
+func (s *SuiteNotify) TestFallback(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "fallback")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	ms, err := Build(decodeFrom(
+		&NotifyConfig{NotifyFallback: "save"},
+		&SlackConfig{SlackWebhook: ts.URL},
+		&SaveConfig{SaveFolder: dir},
+	))
+	c.Assert(err, IsNil)
+
+	s.job.Name = "foo"
+	s.job.Use(ms...)
+
+	before := failures("slack")
+	ctx := core.NewContext(s.ctx.Scheduler, s.job, core.NewExecution())
+	ctx.Start()
+	c.Assert(ctx.Next(), IsNil)
+
+	c.Assert(ctx.Execution.NotificationErrors, HasLen, 1)
+	c.Assert(failures("slack"), Equals, before+1)
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 3)
+	c.Assert(filepath.Ext(files[0].Name()), Equals, ".json")
+}
+
+func failures(notifier string) int64 {
+	v := NotificationFailures.Get(notifier)
+	if v == nil {
+		return 0
+	}
+
+	return v.(interface{ Value() int64 }).Value()
+}
+
+func (s *SuiteNotify) TestDigestPeriod(c *C) {
+	t := time.Date(2020, 1, 31, 23, 15, 0, 0, time.UTC)
+	c.Assert(digestPeriod("hourly")(t), Equals, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(digestPeriod("daily")(t), Equals, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(digestPeriod("weekly"), IsNil)
+}
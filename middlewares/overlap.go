@@ -1,26 +1,62 @@
 package middlewares
 
-import "github.com/mcuadros/ofelia/core"
+import (
+	"fmt"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const (
+	// OverlapSkip skips the executions exceeding the max concurrency
+	OverlapSkip = "skip"
+	// OverlapQueue defers the executions exceeding the max concurrency until
+	// the running ones finish, the pending executions are kept in order.
+	OverlapQueue = "queue"
+)
 
 // OverlapConfig configuration for the Overlap middleware
 type OverlapConfig struct {
-	NoOverlap bool `gcfg:"no-overlap" mapstructure:"no-overlap"`
+	NoOverlap      bool   `gcfg:"no-overlap" mapstructure:"no-overlap"`
+	OverlapPolicy  string `gcfg:"overlap-policy" mapstructure:"overlap-policy"`
+	MaxConcurrency int    `gcfg:"max-concurrency" mapstructure:"max-concurrency"`
+}
+
+// validate checks the overlap policy is skip or queue, if set
+func (c *OverlapConfig) validate() error {
+	switch c.OverlapPolicy {
+	case "", OverlapSkip, OverlapQueue:
+		return nil
+	}
+
+	return fmt.Errorf("invalid overlap-policy %q, must be %s or %s", c.OverlapPolicy, OverlapSkip, OverlapQueue)
 }
 
 // NewOverlap returns a Overlap middleware if the given configuration is not empty
 func NewOverlap(c *OverlapConfig) core.Middleware {
 	var m core.Middleware
 	if !IsEmpty(c) {
-		m = &Overlap{*c}
+		o := &Overlap{OverlapConfig: *c}
+		o.slots = make(chan struct{}, o.limit())
+		m = o
 	}
 
 	return m
 }
 
+func init() {
+	Register(Plugin{
+		Name:   "overlap",
+		Stage:  StageGuard,
+		Config: func() interface{} { return &OverlapConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewOverlap(c.(*OverlapConfig)) },
+	})
+}
+
 // Overlap when this middleware is enabled avoid to overlap executions from a
-// specific job
+// specific job, skipping or queuing the exceeding executions
 type Overlap struct {
 	OverlapConfig
+	slots chan struct{}
 }
 
 // ContinueOnStop Overlap is only called if the process is still running
@@ -28,11 +64,57 @@ func (m *Overlap) ContinueOnStop() bool {
 	return false
 }
 
-// Run stops the execution if the another execution is already running
+// Run stops the execution if the another execution is already running, or
+// waits until it finishes if the policy is queue
 func (m *Overlap) Run(ctx *core.Context) error {
-	if m.NoOverlap && ctx.Job.Running() > 1 {
-		ctx.Stop(core.ErrSkippedExecution)
+	switch m.policy() {
+	case OverlapQueue:
+		m.acquire(ctx)
+		defer m.release()
+	case OverlapSkip:
+		if int(ctx.Job.Running()) > m.limit() {
+			ctx.Stop(core.ErrSkippedExecution)
+		}
 	}
 
 	return ctx.Next()
 }
+
+func (m *Overlap) policy() string {
+	switch {
+	case m.OverlapPolicy != "":
+		return m.OverlapPolicy
+	case m.NoOverlap:
+		return OverlapSkip
+	case m.MaxConcurrency > 0:
+		return OverlapQueue
+	}
+
+	return ""
+}
+
+func (m *Overlap) limit() int {
+	if m.MaxConcurrency > 0 {
+		return m.MaxConcurrency
+	}
+
+	return 1
+}
+
+// acquire blocks until a execution slot is free, while waiting the execution
+// is stored in the scheduler queue if any, to be resumed after a restart.
+func (m *Overlap) acquire(ctx *core.Context) {
+	select {
+	case m.slots <- struct{}{}:
+		return
+	default:
+	}
+
+	queued(ctx, "Queued until the running executions finish", func() {
+		m.slots <- struct{}{}
+	})
+}
+
+func (m *Overlap) release() {
+	<-m.slots
+}
@@ -1,6 +1,15 @@
 package middlewares
 
-import . "gopkg.in/check.v1"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
 
 type SuiteOverlap struct {
 	BaseSuite
@@ -27,3 +36,44 @@ func (s *SuiteOverlap) TestRunOverlap(c *C) {
 	c.Assert(s.ctx.Execution.IsRunning, Equals, false)
 	c.Assert(s.ctx.Execution.Skipped, Equals, true)
 }
+
+func (s *SuiteOverlap) TestRunOverlapMaxConcurrency(c *C) {
+	s.ctx.Execution.Start()
+	s.ctx.Job.NotifyStart()
+	s.ctx.Job.NotifyStart()
+
+	m := NewOverlap(&OverlapConfig{OverlapPolicy: OverlapSkip, MaxConcurrency: 2})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteOverlap) TestRunOverlapQueue(c *C) {
+	dir, err := ioutil.TempDir("", "overlap")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	q, err := core.NewBoltQueue(filepath.Join(dir, "queue.db"))
+	c.Assert(err, IsNil)
+	defer q.Close()
+	s.ctx.Scheduler.Queue = q
+
+	m := NewOverlap(&OverlapConfig{OverlapPolicy: OverlapQueue}).(*Overlap)
+	m.slots <- struct{}{}
+
+	s.ctx.Execution.Start()
+	done := make(chan error)
+	go func() { done <- m.Run(s.ctx) }()
+
+	time.Sleep(time.Millisecond * 50)
+	pending, err := q.Pending()
+	c.Assert(err, IsNil)
+	c.Assert(pending, HasLen, 1)
+
+	m.release()
+	c.Assert(<-done, IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+
+	pending, err = q.Pending()
+	c.Assert(err, IsNil)
+	c.Assert(pending, HasLen, 0)
+}
@@ -0,0 +1,218 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const (
+	// PowerSkip skips the executions while the host is on battery
+	PowerSkip = "skip"
+	// PowerDefer defers the executions until the host is plugged in, up to
+	// the power-deadline
+	PowerDefer = "defer"
+
+	defaultPowerDeadline = time.Hour
+)
+
+// powerSupplyDir directory of the power supplies of the host, replaced by the
+// tests
+var powerSupplyDir = "/sys/class/power_supply"
+
+// PowerConfig configuration for the Power middleware
+type PowerConfig struct {
+	// PowerPolicy what happens to the executions while the host is on
+	// battery, "skip" (default) or "defer"
+	PowerPolicy string `gcfg:"power-policy" mapstructure:"power-policy"`
+	// MinBattery when set, e.g. "20", the policy only applies while the
+	// charge of the battery is below this percentage
+	MinBattery string `gcfg:"min-battery" mapstructure:"min-battery"`
+	// PowerDeadline the executions still deferred after it are skipped, 1h by
+	// default
+	PowerDeadline string `gcfg:"power-deadline" mapstructure:"power-deadline"`
+}
+
+// NewPower returns a Power middleware if the given configuration is not empty
+func NewPower(c *PowerConfig) core.Middleware {
+	var m core.Middleware
+	if c.PowerPolicy != "" || c.MinBattery != "" {
+		m = &Power{PowerConfig: *c}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "power",
+		Stage:  StageGuard,
+		Global: true,
+		Config: func() interface{} { return &PowerConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewPower(c.(*PowerConfig)) },
+	})
+}
+
+// Power middleware skips or defers the executions while the host is on
+// battery, e.g. an edge device or a laptop running local tasks
+type Power struct {
+	PowerConfig
+}
+
+// ContinueOnStop Power is only called if the process is still running
+func (m *Power) ContinueOnStop() bool {
+	return false
+}
+
+// Run skips the execution, or defers it with exponential backoff up to the
+// deadline, while the host is on battery, below min-battery if set
+func (m *Power) Run(ctx *core.Context) error {
+	policy, min, deadline, err := m.parse()
+	if err != nil {
+		ctx.Warn(err.Error())
+		return ctx.Next()
+	}
+
+	check := func() (string, error) {
+		s, err := readPowerState(powerSupplyDir)
+		if err != nil {
+			return "", fmt.Errorf("unable to read the power state of the host, running anyway: %s", err)
+		}
+
+		return s.restricts(min), nil
+	}
+
+	if policy == PowerDefer {
+		deferWhile(ctx, "power", deadline, check)
+		return ctx.Next()
+	}
+
+	reason, err := check()
+	if err != nil {
+		ctx.Warn(err.Error())
+		return ctx.Next()
+	}
+
+	if reason != "" {
+		ctx.Log("Skipping, " + reason)
+		ctx.Stop(core.ErrSkippedExecution)
+	}
+
+	return ctx.Next()
+}
+
+func (m *Power) parse() (policy string, min float64, deadline time.Duration, err error) {
+	policy, deadline = PowerSkip, defaultPowerDeadline
+	switch m.PowerPolicy {
+	case "", PowerSkip:
+	case PowerDefer:
+		policy = PowerDefer
+	default:
+		return "", 0, 0, fmt.Errorf("invalid power-policy %q, must be %q or %q", m.PowerPolicy, PowerSkip, PowerDefer)
+	}
+
+	if m.MinBattery != "" {
+		min, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(m.MinBattery), "%"), 64)
+		if err != nil || min <= 0 || min > 100 {
+			return "", 0, 0, fmt.Errorf("invalid min-battery %q, must be a percentage, e.g. 20", m.MinBattery)
+		}
+	}
+
+	if m.PowerDeadline != "" {
+		if deadline, err = time.ParseDuration(m.PowerDeadline); err != nil || deadline <= 0 {
+			return "", 0, 0, fmt.Errorf("invalid power-deadline %q, must be a positive duration", m.PowerDeadline)
+		}
+	}
+
+	return policy, min, deadline, nil
+}
+
+// powerState the state of the power supplies of the host
+type powerState struct {
+	// Batteries number of batteries, none on most servers
+	Batteries int
+	// Plugged when an external power supply is online
+	Plugged bool
+	// Charge mean charge of the batteries, as a percentage
+	Charge float64
+}
+
+// restricts returns why the executions are restricted by the state, below the
+// given charge if not zero, empty if they aren't
+func (s *powerState) restricts(min float64) string {
+	if s.Batteries == 0 || s.Plugged {
+		return ""
+	}
+
+	if min == 0 {
+		return fmt.Sprintf("on battery, %.0f%% charged", s.Charge)
+	}
+
+	if s.Charge < min {
+		return fmt.Sprintf("on battery, %.0f%% charged, below min-battery %g%%", s.Charge, min)
+	}
+
+	return ""
+}
+
+// readPowerState reads the state of the power supplies in the given sysfs
+// directory, the hosts without it have no battery
+func readPowerState(dir string) (*powerState, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return &powerState{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &powerState{}
+	var charge float64
+	for _, e := range entries {
+		supply := filepath.Join(dir, e.Name())
+		switch readSysfs(supply, "type") {
+		case "Battery":
+			// the batteries of the peripherals, e.g. a mouse, aren't powering
+			// the host
+			if readSysfs(supply, "scope") == "Device" {
+				continue
+			}
+
+			capacity, err := strconv.ParseFloat(readSysfs(supply, "capacity"), 64)
+			if err != nil {
+				continue
+			}
+
+			s.Batteries++
+			charge += capacity
+		default:
+			if readSysfs(supply, "online") == "1" {
+				s.Plugged = true
+			}
+		}
+	}
+
+	if s.Batteries > 0 {
+		s.Charge = charge / float64(s.Batteries)
+	}
+
+	return s, nil
+}
+
+// readSysfs returns the trimmed content of the given attribute of a sysfs
+// entry, empty if it can't be read
+func readSysfs(entry, attr string) string {
+	content, err := ioutil.ReadFile(filepath.Join(entry, attr))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuitePower struct {
+	BaseSuite
+	dir string
+}
+
+var _ = Suite(&SuitePower{})
+
+func (s *SuitePower) SetUpTest(c *C) {
+	s.BaseSuite.SetUpTest(c)
+	s.dir = c.MkDir()
+	powerSupplyDir = s.dir
+	workloadBackoff, workloadMaxBackoff = time.Millisecond, 4*time.Millisecond
+}
+
+func (s *SuitePower) TearDownTest(c *C) {
+	powerSupplyDir = "/sys/class/power_supply"
+	workloadBackoff, workloadMaxBackoff = 30*time.Second, 5*time.Minute
+}
+
+func (s *SuitePower) supply(c *C, name string, attrs map[string]string) {
+	dir := filepath.Join(s.dir, name)
+	c.Assert(os.MkdirAll(dir, 0755), IsNil)
+	for attr, value := range attrs {
+		c.Assert(ioutil.WriteFile(filepath.Join(dir, attr), []byte(value+"\n"), 0644), IsNil)
+	}
+}
+
+func (s *SuitePower) TestNewPowerEmpty(c *C) {
+	c.Assert(NewPower(&PowerConfig{}), IsNil)
+	c.Assert(NewPower(&PowerConfig{PowerDeadline: "10m"}), IsNil)
+}
+
+func (s *SuitePower) TestReadPowerState(c *C) {
+	state, err := readPowerState(filepath.Join(s.dir, "missing"))
+	c.Assert(err, IsNil)
+	c.Assert(state, DeepEquals, &powerState{})
+
+	s.supply(c, "AC", map[string]string{"type": "Mains", "online": "0"})
+	s.supply(c, "BAT0", map[string]string{"type": "Battery", "capacity": "40"})
+	s.supply(c, "BAT1", map[string]string{"type": "Battery", "capacity": "20"})
+	s.supply(c, "hidpp_battery_0", map[string]string{"type": "Battery", "scope": "Device", "capacity": "5"})
+
+	state, err = readPowerState(s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(state, DeepEquals, &powerState{Batteries: 2, Charge: 30})
+
+	s.supply(c, "AC", map[string]string{"online": "1"})
+	state, err = readPowerState(s.dir)
+	c.Assert(err, IsNil)
+	c.Assert(state.Plugged, Equals, true)
+}
+
+func (s *SuitePower) TestRestricts(c *C) {
+	c.Assert((&powerState{}).restricts(0), Equals, "")
+	c.Assert((&powerState{Batteries: 1, Plugged: true, Charge: 5}).restricts(0), Equals, "")
+	c.Assert((&powerState{Batteries: 1, Charge: 80}).restricts(0), Equals, "on battery, 80% charged")
+	c.Assert((&powerState{Batteries: 1, Charge: 80}).restricts(20), Equals, "")
+	c.Assert((&powerState{Batteries: 1, Charge: 15}).restricts(20), Equals, "on battery, 15% charged, below min-battery 20%")
+}
+
+func (s *SuitePower) TestRunSkip(c *C) {
+	s.supply(c, "AC", map[string]string{"type": "Mains", "online": "0"})
+	s.supply(c, "BAT0", map[string]string{"type": "Battery", "capacity": "15"})
+
+	m := NewPower(&PowerConfig{MinBattery: "20%"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, true)
+}
+
+func (s *SuitePower) TestRunDefer(c *C) {
+	s.supply(c, "AC", map[string]string{"type": "Mains", "online": "0"})
+	s.supply(c, "BAT0", map[string]string{"type": "Battery", "capacity": "80"})
+
+	online := filepath.Join(s.dir, "AC", "online")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ioutil.WriteFile(online, []byte("1\n"), 0644)
+	}()
+
+	m := NewPower(&PowerConfig{PowerPolicy: PowerDefer})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuitePower) TestRunDeferDeadline(c *C) {
+	s.supply(c, "BAT0", map[string]string{"type": "Battery", "capacity": "80"})
+
+	m := NewPower(&PowerConfig{PowerPolicy: PowerDefer, PowerDeadline: "10ms"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, true)
+}
+
+func (s *SuitePower) TestParse(c *C) {
+	policy, min, deadline, err := (&Power{PowerConfig{MinBattery: "20"}}).parse()
+	c.Assert(err, IsNil)
+	c.Assert(policy, Equals, PowerSkip)
+	c.Assert(min, Equals, 20.0)
+	c.Assert(deadline, Equals, time.Hour)
+
+	_, _, _, err = (&Power{PowerConfig{PowerPolicy: "wait"}}).parse()
+	c.Assert(err, ErrorMatches, `invalid power-policy "wait", must be "skip" or "defer"`)
+
+	_, _, _, err = (&Power{PowerConfig{MinBattery: "120"}}).parse()
+	c.Assert(err, ErrorMatches, `invalid min-battery "120", must be a percentage, e.g. 20`)
+
+	_, _, _, err = (&Power{PowerConfig{PowerPolicy: PowerDefer, PowerDeadline: "soon"}}).parse()
+	c.Assert(err, ErrorMatches, `invalid power-deadline "soon", must be a positive duration`)
+}
@@ -0,0 +1,297 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/mcuadros/ofelia/core"
+)
+
+// pushTimeout timeout of the pushes of the metrics
+const pushTimeout = 10 * time.Second
+
+// PushConfig configuration for the Push middleware
+type PushConfig struct {
+	// PushGateway URL of a Prometheus Pushgateway, e.g.
+	// "http://pushgateway:9091"
+	PushGateway string `gcfg:"push-gateway" mapstructure:"push-gateway"`
+	// PushRemoteWrite URL of a Prometheus remote-write endpoint, e.g.
+	// "http://prometheus:9090/api/v1/write"
+	PushRemoteWrite string `gcfg:"push-remote-write" mapstructure:"push-remote-write"`
+	// PushInstance value of the instance label, the hostname by default
+	PushInstance string `gcfg:"push-instance" mapstructure:"push-instance"`
+	// PushToken bearer token of the pushes
+	PushToken   string `gcfg:"push-token" mapstructure:"push-token" secret:"true"`
+	PushRetries int    `gcfg:"push-retries" mapstructure:"push-retries"`
+}
+
+// NewPush returns a Push middleware if the given configuration is not empty
+func NewPush(c *PushConfig) core.Middleware {
+	var m core.Middleware
+	if c.PushGateway != "" || c.PushRemoteWrite != "" {
+		m = &Push{PushConfig: *c, client: &http.Client{Timeout: pushTimeout}}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "push",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &PushConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewPush(c.(*PushConfig)) },
+	})
+}
+
+// Push middleware pushes the metrics of every execution of the job to a
+// Prometheus Pushgateway or remote-write endpoint, for the deployments that
+// can't be scraped
+type Push struct {
+	PushConfig
+	TagFilter
+
+	client *http.Client
+}
+
+// ContinueOnStop return allways true, we want always report the final status
+func (m *Push) ContinueOnStop() bool {
+	return true
+}
+
+// Run pushes the metrics of the execution, the skipped ones aren't pushed
+func (m *Push) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	if !m.notifies(ctx) {
+		return err
+	}
+
+	if !ctx.Execution.Skipped {
+		if err := m.Notify(ctx); err != nil {
+			notificationFailed(ctx, "push", err)
+		}
+	}
+
+	return err
+}
+
+// Notify pushes the metrics of the execution
+func (m *Push) Notify(ctx *core.Context) error {
+	metrics := executionMetrics(ctx.Execution)
+	tags := tagLabels(ctx.Job)
+	return notifyWithRetry(m.PushRetries, func() error {
+		if m.PushGateway != "" {
+			if err := m.pushGateway(ctx.Job.GetName(), metrics, tags); err != nil {
+				return err
+			}
+		}
+
+		if m.PushRemoteWrite != "" {
+			return m.remoteWrite(ctx.Job.GetName(), metrics, tags, ctx.Execution.Date.Add(ctx.Execution.Duration))
+		}
+
+		return nil
+	})
+}
+
+// pushMetric a gauge of the execution of a job
+type pushMetric struct {
+	Name  string
+	Value float64
+}
+
+// executionMetrics returns the metrics of the execution, the timestamp of the
+// last success only if it succeeded
+func executionMetrics(e *core.Execution) []pushMetric {
+	end := float64(e.Date.Add(e.Duration).UnixNano()) / 1e9
+	failed := 0.0
+	if e.Failed {
+		failed = 1
+	}
+
+	metrics := []pushMetric{
+		{"ofelia_job_last_run_timestamp_seconds", end},
+		{"ofelia_job_last_duration_seconds", e.Duration.Seconds()},
+		{"ofelia_job_last_exit_code", float64(e.ExitCode)},
+		{"ofelia_job_last_failed", failed},
+	}
+
+	if !e.Failed {
+		metrics = append(metrics, pushMetric{"ofelia_job_last_success_timestamp_seconds", end})
+	}
+
+	return metrics
+}
+
+func (m *Push) instance() string {
+	if m.PushInstance != "" {
+		return m.PushInstance
+	}
+
+	return hostname()
+}
+
+// reservedLabels are the labels set by ofelia, never overridden by the tags
+var reservedLabels = map[string]bool{
+	"__name__": true, "instance": true, "job": true, "ofelia_job": true,
+	"source_container": true, "source_service": true, "source_project": true,
+}
+
+// tagLabels returns the provenance and the tags of the job as labels of its
+// metrics, the names of the labels limited to the characters allowed by
+// Prometheus
+func tagLabels(j core.Job) [][2]string {
+	var labels [][2]string
+	p := core.JobProvenance(j)
+	for _, l := range [][2]string{
+		{"source_container", p.Container},
+		{"source_service", p.Service},
+		{"source_project", p.Project},
+	} {
+		if l[1] != "" {
+			labels = append(labels, l)
+		}
+	}
+
+	for _, tag := range sortedTags(j) {
+		parts := strings.SplitN(tag, "=", 2)
+		if name := labelName(parts[0]); !reservedLabels[name] {
+			labels = append(labels, [2]string{name, parts[1]})
+		}
+	}
+
+	return labels
+}
+
+// labelName replaces the characters not allowed in the names of the labels
+// with underscores
+func labelName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	return b.String()
+}
+
+// pushGateway pushes the metrics to the group of the job, with POST, so the
+// metrics not pushed, e.g. the last success, keep their value
+func (m *Push) pushGateway(job string, metrics []pushMetric, tags [][2]string) error {
+	var labels string
+	if len(tags) > 0 {
+		var l []string
+		for _, t := range tags {
+			l = append(l, fmt.Sprintf("%s=%q", t[0], t[1]))
+		}
+
+		labels = "{" + strings.Join(l, ",") + "}"
+	}
+
+	var body strings.Builder
+	for _, metric := range metrics {
+		fmt.Fprintf(&body, "# TYPE %s gauge\n%s%s %g\n", metric.Name, metric.Name, labels, metric.Value)
+	}
+
+	u := fmt.Sprintf(
+		"%s/metrics/job/ofelia/instance/%s/ofelia_job/%s",
+		strings.TrimSuffix(m.PushGateway, "/"), url.PathEscape(m.instance()), url.PathEscape(job),
+	)
+
+	req, err := http.NewRequest(http.MethodPost, u, strings.NewReader(body.String()))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	return m.do(req)
+}
+
+// remoteWrite writes the metrics with the protocol of the Prometheus
+// remote-write, a snappy compressed protobuf WriteRequest
+func (m *Push) remoteWrite(job string, metrics []pushMetric, tags [][2]string, at time.Time) error {
+	labels := append([][2]string{{"instance", m.instance()}, {"job", "ofelia"}, {"ofelia_job", job}}, tags...)
+	// the labels of a series are sorted by name
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+
+	var request []byte
+	for _, metric := range metrics {
+		var series []byte
+		series = protoBytes(series, 1, protoLabel("__name__", metric.Name))
+		for _, l := range labels {
+			series = protoBytes(series, 1, protoLabel(l[0], l[1]))
+		}
+
+		var sample []byte
+		sample = protoVarint(sample, 1<<3|1)
+		sample = append(sample, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(sample[len(sample)-8:], math.Float64bits(metric.Value))
+		sample = protoVarint(sample, 2<<3)
+		sample = protoVarint(sample, uint64(at.UnixNano()/int64(time.Millisecond)))
+
+		series = protoBytes(series, 2, sample)
+		request = protoBytes(request, 1, series)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, m.PushRemoteWrite, bytes.NewReader(snappy.Encode(nil, request)))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	return m.do(req)
+}
+
+func (m *Push) do(req *http.Request) error {
+	if m.PushToken != "" {
+		req.Header.Set("Authorization", "Bearer "+m.PushToken)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+func protoLabel(name, value string) []byte {
+	var l []byte
+	l = protoBytes(l, 1, []byte(name))
+	return protoBytes(l, 2, []byte(value))
+}
+
+// protoBytes appends the length-delimited field to the protobuf message
+func protoBytes(b []byte, field uint64, data []byte) []byte {
+	b = protoVarint(b, field<<3|2)
+	b = protoVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func protoVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
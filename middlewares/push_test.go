@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/golang/snappy"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuitePush struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuitePush{})
+
+func (s *SuitePush) TestNewPushEmpty(c *C) {
+	c.Assert(NewPush(&PushConfig{}), IsNil)
+}
+
+func (s *SuitePush) TestRunPushGateway(c *C) {
+	var path, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Method, Equals, http.MethodPost)
+		c.Assert(r.Header.Get("Authorization"), Equals, "Bearer s3cr3t")
+
+		b, _ := ioutil.ReadAll(r.Body)
+		path, body = r.URL.Path, string(b)
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.ctx.Start()
+	s.ctx.Execution.ExitCode = 2
+	s.ctx.Stop(errors.New("foo"))
+
+	m := NewPush(&PushConfig{PushGateway: ts.URL, PushInstance: "host", PushToken: "s3cr3t"})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(path, Equals, "/metrics/job/ofelia/instance/host/ofelia_job/foo")
+	c.Assert(strings.Contains(body, "# TYPE ofelia_job_last_exit_code gauge\nofelia_job_last_exit_code 2\n"), Equals, true)
+	c.Assert(strings.Contains(body, "ofelia_job_last_failed 1\n"), Equals, true)
+	c.Assert(strings.Contains(body, "ofelia_job_last_success_timestamp_seconds"), Equals, false)
+}
+
+func (s *SuitePush) TestRunRemoteWrite(c *C) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.Header.Get("Content-Encoding"), Equals, "snappy")
+		c.Assert(r.Header.Get("Content-Type"), Equals, "application/x-protobuf")
+
+		b, _ := ioutil.ReadAll(r.Body)
+		var err error
+		body, err = snappy.Decode(nil, b)
+		c.Assert(err, IsNil)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewPush(&PushConfig{PushRemoteWrite: ts.URL, PushInstance: "host"})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.NotificationErrors, IsNil)
+
+	c.Assert(strings.Contains(string(body), "\n\x08__name__\x12%ofelia_job_last_run_timestamp_seconds"), Equals, true)
+	c.Assert(strings.Contains(string(body), "\n\nofelia_job\x12\x03foo"), Equals, true)
+	c.Assert(strings.Contains(string(body), "ofelia_job_last_success_timestamp_seconds"), Equals, true)
+}
+
+func (s *SuitePush) TestRunError(c *C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("out of order sample"))
+	}))
+
+	defer ts.Close()
+
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewPush(&PushConfig{PushRemoteWrite: ts.URL})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.NotificationErrors["push"], Matches, ".* returned 400 Bad Request: out of order sample")
+}
+
+func (s *SuitePush) TestTagLabels(c *C) {
+	s.job.Tags = []string{"tier=critical", "cost-center=42", "job=other"}
+	c.Assert(tagLabels(s.job), DeepEquals, [][2]string{{"cost_center", "42"}, {"tier", "critical"}})
+	c.Assert(labelName("9lives.x"), Equals, "_lives_x")
+}
+
+func (s *SuitePush) TestTagLabelsProvenance(c *C) {
+	s.job.SourceService = "web"
+	s.job.SourceProject = "shop"
+	s.job.Tags = []string{"tier=critical", "source_project=other"}
+	c.Assert(tagLabels(s.job), DeepEquals, [][2]string{
+		{"source_service", "web"}, {"source_project", "shop"}, {"tier", "critical"},
+	})
+}
+
+func (s *SuitePush) TestRunPushGatewayTags(c *C) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	defer ts.Close()
+
+	s.job.Name = "foo"
+	s.job.Tags = []string{"tier=critical"}
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewPush(&PushConfig{PushGateway: ts.URL, PushInstance: "host"})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(strings.Contains(body, "ofelia_job_last_failed{tier=\"critical\"} 0\n"), Equals, true)
+}
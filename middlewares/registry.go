@@ -0,0 +1,189 @@
+package middlewares
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// Stage position of a middleware in the chain of a job
+type Stage int
+
+const (
+	// StageGuard middlewares decide if the execution runs, e.g. overlap
+	StageGuard Stage = iota
+	// StageNotify middlewares report the execution, they can be used as
+	// fallback if they implement Notifier
+	StageNotify
+	// StageTrigger middlewares act once the execution is reported
+	StageTrigger
+	// StageWatch middlewares watch the execution of the job itself, e.g.
+	// deadline
+	StageWatch
+)
+
+// Plugin is a middleware built from the config of the jobs
+type Plugin struct {
+	// Name of the middleware, e.g. "slack"
+	Name  string
+	Stage Stage
+	// Global when true, the middleware can be configured in the global section,
+	// applying to all the jobs
+	Global bool
+	// Config returns a pointer to a new config, its fields are the params of
+	// the middleware, checked by its validate method if any once decoded
+	Config func() interface{}
+	// New returns the middleware of the given config, nil if disabled
+	New func(config interface{}) core.Middleware
+}
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = make(map[string]Plugin)
+)
+
+// Register registers a middleware plugin, it panics if a plugin with the same
+// name is already registered.
+func Register(p Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	if _, ok := plugins[p.Name]; ok {
+		panic(fmt.Sprintf("middleware %q already registered", p.Name))
+	}
+
+	plugins[p.Name] = p
+}
+
+// Plugins returns the registered plugins, sorted by stage and name
+func Plugins() []Plugin {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	all := make([]Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		all = append(all, p)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Stage != all[j].Stage {
+			return all[i].Stage < all[j].Stage
+		}
+
+		return all[i].Name < all[j].Name
+	})
+
+	return all
+}
+
+// Build returns the middlewares of a job, of all the registered plugins, the
+// config of every plugin is filled by decode. The notifiers listed in the
+// NotifyConfig are grouped in a Fallback middleware, first in the chain.
+func Build(decode func(config interface{}) error) ([]core.Middleware, error) {
+	return build(decode, false)
+}
+
+// BuildGlobal is Build, only with the plugins that can be configured globally
+func BuildGlobal(decode func(config interface{}) error) ([]core.Middleware, error) {
+	return build(decode, true)
+}
+
+func build(decode func(config interface{}) error, global bool) ([]core.Middleware, error) {
+	c := &NotifyConfig{}
+	if err := decode(c); err != nil {
+		return nil, err
+	}
+
+	stages := make(map[Stage][]core.Middleware)
+	var notifiers []namedMiddleware
+	for _, p := range Plugins() {
+		if global && !p.Global {
+			continue
+		}
+
+		config := p.Config()
+		if err := decode(config); err != nil {
+			return nil, fmt.Errorf("middleware %q: %s", p.Name, err)
+		}
+
+		if v, ok := config.(interface{ validate() error }); ok {
+			if err := v.validate(); err != nil {
+				return nil, fmt.Errorf("middleware %q: %s", p.Name, err)
+			}
+		}
+
+		m := p.New(config)
+		if m == nil {
+			continue
+		}
+
+		if p.Stage == StageNotify {
+			if f, ok := m.(interface{ setTagFilter(TagFilter) }); ok {
+				f.setTagFilter(c.tagFilter(p.Name))
+			}
+
+			notifiers = append(notifiers, namedMiddleware{p.Name, m})
+			continue
+		}
+
+		stages[p.Stage] = append(stages[p.Stage], m)
+	}
+
+	fallback, others := newNotifiers(c, notifiers)
+
+	var chain []core.Middleware
+	if fallback != nil {
+		chain = append(chain, fallback)
+	}
+
+	chain = append(chain, stages[StageGuard]...)
+	chain = append(chain, others...)
+	chain = append(chain, stages[StageTrigger]...)
+	return append(chain, stages[StageWatch]...), nil
+}
+
+// IsNotifier returns true if the plugin with the given name is a notifier
+func IsNotifier(name string) bool {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	p, ok := plugins[name]
+	return ok && p.Stage == StageNotify
+}
+
+// BuildNotifier returns the notifier of the plugin with the given name, its
+// config and its NotifyConfig filled by decode, nil if disabled. It's used by
+// the named notifiers, declared apart from the jobs.
+func BuildNotifier(name string, decode func(config interface{}) error) (core.Middleware, error) {
+	pluginsMu.RLock()
+	p, ok := plugins[name]
+	pluginsMu.RUnlock()
+
+	if !ok || p.Stage != StageNotify {
+		return nil, fmt.Errorf("unknown notifier %q", name)
+	}
+
+	c := &NotifyConfig{}
+	if err := decode(c); err != nil {
+		return nil, err
+	}
+
+	config := p.Config()
+	if err := decode(config); err != nil {
+		return nil, err
+	}
+
+	m := p.New(config)
+	if f, ok := m.(interface{ setTagFilter(TagFilter) }); ok {
+		f.setTagFilter(c.tagFilter(p.Name))
+	}
+
+	return m, nil
+}
+
+type namedMiddleware struct {
+	name string
+	core.Middleware
+}
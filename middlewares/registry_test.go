@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/mcuadros/ofelia/core"
+	. "gopkg.in/check.v1"
+)
+
+type SuiteRegistry struct{}
+
+var _ = Suite(&SuiteRegistry{})
+
+type echoConfig struct {
+	Echo string `gcfg:"echo" mapstructure:"echo"`
+}
+
+type echo struct {
+	echoConfig
+}
+
+func (m *echo) ContinueOnStop() bool {
+	return true
+}
+
+func (m *echo) Run(ctx *core.Context) error {
+	return ctx.Next()
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "echo-test",
+		Stage:  StageNotify,
+		Config: func() interface{} { return &echoConfig{} },
+		New: func(c interface{}) core.Middleware {
+			if c.(*echoConfig).Echo == "" {
+				return nil
+			}
+
+			return &echo{*c.(*echoConfig)}
+		},
+	})
+}
+
+func (s *SuiteRegistry) TestRegisterDuplicated(c *C) {
+	c.Assert(func() { Register(Plugin{Name: "slack"}) }, PanicMatches, `middleware "slack" already registered`)
+}
+
+func (s *SuiteRegistry) TestBuild(c *C) {
+	ms, err := Build(decodeFrom(
+		&TriggerConfig{OnSuccess: "foo"},
+		&SlackConfig{SlackWebhook: "http://localhost"},
+		&OverlapConfig{NoOverlap: true},
+		&SaveConfig{SaveFolder: "/tmp"},
+		&NotifyConfig{NotifyFallback: "save"},
+		&echoConfig{Echo: "foo"},
+	))
+
+	c.Assert(err, IsNil)
+	c.Assert(ms, HasLen, 5)
+	c.Assert(ms[0], FitsTypeOf, &Fallback{})
+	c.Assert(ms[1], FitsTypeOf, &Overlap{})
+	c.Assert(ms[2], FitsTypeOf, &echo{})
+	c.Assert(ms[3], FitsTypeOf, &Slack{})
+	c.Assert(ms[4], FitsTypeOf, &Trigger{})
+}
+
+func (s *SuiteRegistry) TestBuildGlobal(c *C) {
+	ms, err := BuildGlobal(decodeFrom(
+		&OverlapConfig{NoOverlap: true},
+		&SlackConfig{SlackWebhook: "http://localhost"},
+	))
+
+	c.Assert(err, IsNil)
+	c.Assert(ms, HasLen, 1)
+	c.Assert(ms[0], FitsTypeOf, &Slack{})
+}
+
+func (s *SuiteRegistry) TestBuildError(c *C) {
+	_, err := Build(func(config interface{}) error {
+		if _, ok := config.(*SlackConfig); ok {
+			return errors.New("foo")
+		}
+
+		return nil
+	})
+
+	c.Assert(err, ErrorMatches, `middleware "slack": foo`)
+}
+
+func (s *SuiteRegistry) TestBuildInvalid(c *C) {
+	_, err := Build(decodeFrom(&OverlapConfig{OverlapPolicy: "drop"}))
+	c.Assert(err, ErrorMatches, `middleware "overlap": invalid overlap-policy "drop", must be skip or queue`)
+
+	ms, err := Build(decodeFrom(&OverlapConfig{OverlapPolicy: OverlapQueue}))
+	c.Assert(err, IsNil)
+	c.Assert(ms, HasLen, 1)
+}
+
+// decodeFrom returns a decoder copying the given configs
+func decodeFrom(configs ...interface{}) func(interface{}) error {
+	return func(config interface{}) error {
+		v := reflect.ValueOf(config).Elem()
+		for _, c := range configs {
+			if cv := reflect.ValueOf(c).Elem(); cv.Type() == v.Type() {
+				v.Set(cv)
+			}
+		}
+
+		return nil
+	}
+}
@@ -1,34 +1,87 @@
 package middlewares
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"text/template"
+	"time"
 
 	"github.com/mcuadros/ofelia/core"
 )
 
+// defaultSaveFilename name of the saved files, without extension
+const defaultSaveFilename = `{{.Date.Format "20060102_150405"}}_{{.Job}}`
+
 // SaveConfig configuration for the Save middleware
 type SaveConfig struct {
 	SaveFolder      string `gcfg:"save-folder" mapstructure:"save-folder"`
 	SaveOnlyOnError bool   `gcfg:"save-only-on-error" mapstructure:"save-only-on-error"`
+	// SaveFilename template of the name of the files, without extension,
+	// relative to the folder, e.g. "{{.Job}}/{{.Status}}-{{.ID}}", see
+	// saveFilenameData
+	SaveFilename string `gcfg:"save-filename" mapstructure:"save-filename"`
+	// SaveCombined writes a single JSON report including the output streams,
+	// instead of the report and a file per stream
+	SaveCombined bool `gcfg:"save-combined" mapstructure:"save-combined"`
+	// SaveMaxAge when set, e.g. "720h", the reports older than this are
+	// removed from the folder, see savePruner
+	SaveMaxAge string `gcfg:"save-max-age" mapstructure:"save-max-age"`
+	// SaveMaxTotalSize when set, the oldest reports are removed from the
+	// folder while their total size exceeds this number of bytes
+	SaveMaxTotalSize int64 `gcfg:"save-max-total-size" mapstructure:"save-max-total-size"`
+}
+
+// saveFilenameData data of the save-filename template
+type saveFilenameData struct {
+	// Job name of the job
+	Job string
+	// Date start of the execution
+	Date time.Time
+	// ID of the execution
+	ID string
+	// Status "successful", "failed" or "skipped"
+	Status string
 }
 
 // NewSave returns a Save middleware if the given configuration is not empty
 func NewSave(c *SaveConfig) core.Middleware {
 	var m core.Middleware
 	if !IsEmpty(c) {
-		m = &Save{*c}
+		filename := c.SaveFilename
+		if filename == "" {
+			filename = defaultSaveFilename
+		}
+
+		save := &Save{SaveConfig: *c}
+		save.filename, save.filenameErr = template.New("save-filename").Parse(filename)
+		m = save
 	}
 
 	return m
 }
 
+func init() {
+	Register(Plugin{
+		Name:   "save",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &SaveConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewSave(c.(*SaveConfig)) },
+	})
+}
+
 // Save the save middleware saves to disk a dump of the stdout and stderr after
 // every execution of the process
 type Save struct {
 	SaveConfig
+	TagFilter
+
+	filename    *template.Template
+	filenameErr error
 }
 
 // ContinueOnStop return allways true, we want always report the final status
@@ -41,24 +94,40 @@ func (m *Save) Run(ctx *core.Context) error {
 	err := ctx.Next()
 	ctx.Stop(err)
 
-	if ctx.Execution.Failed || !m.SaveOnlyOnError {
-		err := m.saveToDisk(ctx)
-		if err != nil {
-			ctx.Logger.Errorf("Save error: %q", err)
+	if (ctx.Execution.Failed || !m.SaveOnlyOnError) && m.notifies(ctx) {
+		if err := m.Notify(ctx); err != nil {
+			notificationFailed(ctx, "save", err)
 		}
 	}
 
+	m.startPruner(ctx)
 	return err
 }
 
+// Notify saves the result of the execution to disk
+func (m *Save) Notify(ctx *core.Context) error {
+	return m.saveToDisk(ctx)
+}
+
 func (m *Save) saveToDisk(ctx *core.Context) error {
-	root := filepath.Join(m.SaveFolder, fmt.Sprintf(
-		"%s_%s",
-		ctx.Execution.Date.Format("20060102_150405"), ctx.Job.GetName(),
-	))
+	root, err := m.root(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(root), 0755); err != nil {
+		return err
+	}
 
 	e := ctx.Execution
-	err := m.writeFile(e.ErrorStream.Bytes(), fmt.Sprintf("%s.stderr.log", root))
+	if m.SaveCombined {
+		return m.saveContextToDisk(ctx, fmt.Sprintf("%s.json", root), map[string]interface{}{
+			"Stdout": e.OutputStream.String(),
+			"Stderr": e.ErrorStream.String(),
+		})
+	}
+
+	err = m.writeFile(e.ErrorStream.Bytes(), fmt.Sprintf("%s.stderr.log", root))
 	if err != nil {
 		return err
 	}
@@ -68,7 +137,7 @@ func (m *Save) saveToDisk(ctx *core.Context) error {
 		return err
 	}
 
-	err = m.saveContextToDisk(ctx, fmt.Sprintf("%s.json", root))
+	err = m.saveContextToDisk(ctx, fmt.Sprintf("%s.json", root), nil)
 	if err != nil {
 		return err
 	}
@@ -76,13 +145,60 @@ func (m *Save) saveToDisk(ctx *core.Context) error {
 	return nil
 }
 
-func (m *Save) saveContextToDisk(ctx *core.Context, filename string) error {
-	js, _ := json.MarshalIndent(map[string]interface{}{
+// startPruner starts the pruner of the folder if the retention is set, once
+// per folder
+func (m *Save) startPruner(ctx *core.Context) {
+	if m.SaveMaxAge == "" && m.SaveMaxTotalSize <= 0 {
+		return
+	}
+
+	var maxAge time.Duration
+	if m.SaveMaxAge != "" {
+		var err error
+		if maxAge, err = time.ParseDuration(m.SaveMaxAge); err != nil || maxAge <= 0 {
+			ctx.Warn(fmt.Sprintf("invalid save-max-age %q, must be a positive duration", m.SaveMaxAge))
+			return
+		}
+	}
+
+	startSavePruner(m.SaveFolder, maxAge, m.SaveMaxTotalSize, logger(ctx))
+}
+
+// root returns the path of the files of the execution, without extension
+func (m *Save) root(ctx *core.Context) (string, error) {
+	if m.filenameErr != nil {
+		return "", fmt.Errorf("invalid save-filename %q: %s", m.SaveFilename, m.filenameErr)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := m.filename.Execute(buf, &saveFilenameData{
+		Job:    ctx.Job.GetName(),
+		Date:   ctx.Execution.Date,
+		ID:     ctx.Execution.ID,
+		Status: executionLabel(ctx.Execution),
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("error rendering save-filename: %s", err)
+	}
+
+	return filepath.Join(m.SaveFolder, buf.String()), nil
+}
+
+// saveContextToDisk writes the JSON report of the execution, with the given
+// extra fields
+func (m *Save) saveContextToDisk(ctx *core.Context, filename string, extra map[string]interface{}) error {
+	report := map[string]interface{}{
 		"Job":       ctx.Job,
 		"Execution": ctx.Execution,
-	}, "", "  ")
+	}
+
+	for k, v := range extra {
+		report[k] = v
+	}
 
-	return m.writeFile(js, filename)
+	js, _ := json.MarshalIndent(report, "", "  ")
+	return m.writeFile([]byte(ctx.Redact(string(js))), filename)
 }
 
 func (m *Save) writeFile(data []byte, filename string) error {
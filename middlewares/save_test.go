@@ -1,6 +1,8 @@
 package middlewares
 
 import (
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -58,3 +60,60 @@ func (s *SuiteSave) TestRunSuccessOnError(c *C) {
 	_, err = os.Stat(filepath.Join(dir, "00010101_000000_foo.json"))
 	c.Assert(err, Not(IsNil))
 }
+
+func (s *SuiteSave) TestRunFilename(c *C) {
+	dir, err := ioutil.TempDir("/tmp", "save")
+	c.Assert(err, IsNil)
+
+	s.ctx.Start()
+	s.ctx.Stop(errors.New("foo"))
+
+	s.job.Name = "foo"
+	s.ctx.Execution.ID = "bar"
+
+	m := NewSave(&SaveConfig{SaveFolder: dir, SaveFilename: "{{.Job}}/{{.Status}}-{{.ID}}"})
+	c.Assert(m.Run(s.ctx), IsNil)
+
+	for _, name := range []string{"failed-bar.json", "failed-bar.stdout.log", "failed-bar.stderr.log"} {
+		_, err = os.Stat(filepath.Join(dir, "foo", name))
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *SuiteSave) TestRunCombined(c *C) {
+	dir, err := ioutil.TempDir("/tmp", "save")
+	c.Assert(err, IsNil)
+
+	s.ctx.Start()
+	s.ctx.Execution.OutputStream.Write([]byte("out"))
+	s.ctx.Execution.ErrorStream.Write([]byte("err"))
+	s.ctx.Stop(nil)
+
+	s.job.Name = "foo"
+	s.ctx.Execution.Date = time.Time{}
+
+	m := NewSave(&SaveConfig{SaveFolder: dir, SaveCombined: true})
+	c.Assert(m.Run(s.ctx), IsNil)
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, IsNil)
+	c.Assert(files, HasLen, 1)
+	c.Assert(files[0].Name(), Equals, "00010101_000000_foo.json")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	c.Assert(err, IsNil)
+
+	var report struct{ Stdout, Stderr string }
+	c.Assert(json.Unmarshal(content, &report), IsNil)
+	c.Assert(report.Stdout, Equals, "out")
+	c.Assert(report.Stderr, Equals, "err")
+}
+
+func (s *SuiteSave) TestRunInvalidFilename(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewSave(&SaveConfig{SaveFolder: "/tmp", SaveFilename: "{{.Job"})
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.NotificationErrors["save"], Matches, `invalid save-filename "{{.Job": .*`)
+}
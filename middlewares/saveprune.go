@@ -0,0 +1,125 @@
+package middlewares
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// savePruneInterval interval between the prunes of a save folder
+var savePruneInterval = time.Hour
+
+// saveReportExtensions extensions of the files written by Save, the only ones
+// removed by the pruner
+var saveReportExtensions = []string{".json", ".stdout.log", ".stderr.log"}
+
+var (
+	pruners   = make(map[string]*savePruner)
+	prunersMu sync.Mutex
+)
+
+// savePruner removes the old reports of a save folder in background, at
+// every savePruneInterval
+type savePruner struct {
+	folder  string
+	maxAge  time.Duration
+	maxSize int64
+	logger  core.Logger
+}
+
+// startSavePruner starts the pruner of the given folder, unless it is already
+// running, the limits of the first one started apply
+func startSavePruner(folder string, maxAge time.Duration, maxSize int64, l core.Logger) {
+	folder = filepath.Clean(folder)
+
+	prunersMu.Lock()
+	defer prunersMu.Unlock()
+
+	if _, ok := pruners[folder]; ok {
+		return
+	}
+
+	p := &savePruner{folder: folder, maxAge: maxAge, maxSize: maxSize, logger: l}
+	pruners[folder] = p
+	go p.run()
+}
+
+func (p *savePruner) run() {
+	removed, err := pruneReports(p.folder, p.maxAge, p.maxSize, time.Now())
+	if err != nil {
+		p.logger.Errorf("Save prune error at %q: %s", p.folder, err)
+	}
+
+	if removed > 0 {
+		p.logger.Noticef("Removed %d old reports from %q", removed, p.folder)
+	}
+
+	time.AfterFunc(savePruneInterval, p.run)
+}
+
+type reportFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// pruneReports removes from the folder, and its subfolders, the reports older
+// than maxAge, and the oldest ones while the total size exceeds maxSize, the
+// zero values disable the limits. It returns the number of files removed.
+func pruneReports(folder string, maxAge time.Duration, maxSize int64, now time.Time) (int, error) {
+	var files []reportFile
+	var total int64
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() && isSaveReport(path) {
+			files = append(files, reportFile{path, info.Size(), info.ModTime()})
+			total += info.Size()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var removed int
+	for _, f := range files {
+		expired := maxAge > 0 && now.Sub(f.modTime) > maxAge
+		exceeded := maxSize > 0 && total > maxSize
+		if !expired && !exceeded {
+			break
+		}
+
+		if err := os.Remove(f.path); err != nil {
+			return removed, err
+		}
+
+		total -= f.size
+		removed++
+	}
+
+	return removed, nil
+}
+
+func isSaveReport(path string) bool {
+	for _, ext := range saveReportExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteSavePrune struct{}
+
+var _ = Suite(&SuiteSavePrune{})
+
+func (s *SuiteSavePrune) writeReports(c *C, dir string, now time.Time) {
+	c.Assert(os.MkdirAll(filepath.Join(dir, "foo"), 0755), IsNil)
+	for i, name := range []string{"a.json", "foo/b.stdout.log", "c.stderr.log", "d.json", "other.txt"} {
+		path := filepath.Join(dir, name)
+		c.Assert(ioutil.WriteFile(path, make([]byte, 10), 0644), IsNil)
+
+		date := now.Add(-time.Duration(5-i) * 24 * time.Hour)
+		c.Assert(os.Chtimes(path, date, date), IsNil)
+	}
+}
+
+func (s *SuiteSavePrune) TestPruneReportsMaxAge(c *C) {
+	dir := c.MkDir()
+	now := time.Now()
+	s.writeReports(c, dir, now)
+
+	removed, err := pruneReports(dir, 60*time.Hour, 0, now)
+	c.Assert(err, IsNil)
+	c.Assert(removed, Equals, 3)
+
+	for name, exists := range map[string]bool{
+		"a.json": false, "foo/b.stdout.log": false, "c.stderr.log": false,
+		"d.json": true, "other.txt": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		c.Assert(err == nil, Equals, exists, Commentf("%s", name))
+	}
+}
+
+func (s *SuiteSavePrune) TestPruneReportsMaxSize(c *C) {
+	dir := c.MkDir()
+	now := time.Now()
+	s.writeReports(c, dir, now)
+
+	removed, err := pruneReports(dir, 0, 25, now)
+	c.Assert(err, IsNil)
+	c.Assert(removed, Equals, 2)
+
+	_, err = os.Stat(filepath.Join(dir, "c.stderr.log"))
+	c.Assert(err, IsNil)
+
+	removed, err = pruneReports(dir, 0, 0, now)
+	c.Assert(err, IsNil)
+	c.Assert(removed, Equals, 0)
+}
+
+func (s *SuiteSavePrune) TestStartSavePruner(c *C) {
+	dir := c.MkDir()
+	startSavePruner(dir+"/", time.Hour, 0, &TestLogger{})
+	startSavePruner(dir, 2*time.Hour, 0, &TestLogger{})
+
+	prunersMu.Lock()
+	defer prunersMu.Unlock()
+
+	c.Assert(pruners[dir], NotNil)
+	c.Assert(pruners[dir].maxAge, Equals, time.Hour)
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/mcuadros/ofelia/core"
 )
@@ -17,23 +20,55 @@ var (
 
 // SlackConfig configuration for the Slack middleware
 type SlackConfig struct {
-	SlackWebhook     string `gcfg:"slack-webhook" mapstructure:"slack-webhook"`
+	SlackWebhook     string `gcfg:"slack-webhook" mapstructure:"slack-webhook" secret:"true"`
 	SlackOnlyOnError bool   `gcfg:"slack-only-on-error" mapstructure:"slack-only-on-error"`
+	SlackRetries     int    `gcfg:"slack-retries" mapstructure:"slack-retries"`
+	// SlackSummary posts a summary of the executions "hourly" or "daily",
+	// instead of a message per execution
+	SlackSummary string `gcfg:"slack-summary" mapstructure:"slack-summary"`
+	// SlackEscalateAfter posts a message when a job fails this number of
+	// times in a row, instead of a message per execution
+	SlackEscalateAfter int `gcfg:"slack-escalate-after" mapstructure:"slack-escalate-after"`
+	// SlackLifecycle posts a message when the daemon starts, stops or
+	// reloads its config, only used by the global config
+	SlackLifecycle bool `gcfg:"slack-lifecycle" mapstructure:"slack-lifecycle"`
 }
 
 // NewSlack returns a Slack middleware if the given configuration is not empty
 func NewSlack(c *SlackConfig) core.Middleware {
 	var m core.Middleware
 	if !IsEmpty(c) {
-		m = &Slack{*c}
+		slack := &Slack{SlackConfig: *c}
+		if c.SlackSummary != "" || c.SlackEscalateAfter > 0 {
+			slack.history = &slackHistory{
+				slack: slack,
+				next:  digestPeriod(c.SlackSummary),
+				jobs:  make(map[string]*jobHistory),
+			}
+		}
+
+		m = slack
 	}
 
 	return m
 }
 
+func init() {
+	Register(Plugin{
+		Name:   "slack",
+		Stage:  StageNotify,
+		Global: true,
+		Config: func() interface{} { return &SlackConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewSlack(c.(*SlackConfig)) },
+	})
+}
+
 // Slack middleware calls to a Slack input-hook after every execution of a job
 type Slack struct {
 	SlackConfig
+	TagFilter
+
+	history *slackHistory
 }
 
 // ContinueOnStop return allways true, we want alloways report the final status
@@ -47,24 +82,103 @@ func (m *Slack) Run(ctx *core.Context) error {
 	err := ctx.Next()
 	ctx.Stop(err)
 
+	if !m.notifies(ctx) {
+		return err
+	}
+
+	if m.history != nil {
+		if m.SlackSummary != "" && m.history.next == nil {
+			ctx.Warn(fmt.Sprintf("invalid slack-summary %q, must be \"hourly\" or \"daily\"", m.SlackSummary))
+		}
+
+		m.history.add(ctx)
+		return err
+	}
+
 	if ctx.Execution.Failed || !m.SlackOnlyOnError {
-		m.pushMessage(ctx)
+		if err := m.Notify(ctx); err != nil {
+			notificationFailed(ctx, "slack", err)
+		}
 	}
 
 	return err
 }
 
-func (m *Slack) pushMessage(ctx *core.Context) {
+// Alert sends a message with the alert
+func (m *Slack) Alert(ctx *core.Context, title, text string) {
+	if !m.notifies(ctx) {
+		return
+	}
+
+	msg := &slackMessage{
+		Username: slackUsername,
+		IconURL:  slackAvatarURL,
+		Text:     ctx.Redact(text),
+		Attachments: []slackAttachment{{
+			Title: title,
+			Color: "#FFA500",
+		}},
+	}
+
+	err := notifyWithRetry(m.SlackRetries, func() error {
+		return m.post(msg)
+	})
+
+	if err != nil {
+		notificationFailed(ctx, "slack", err)
+	}
+}
+
+// Announce sends a message with the announcement, if slack-lifecycle is set
+func (m *Slack) Announce(s *core.Scheduler, title, text string) {
+	if !m.SlackLifecycle {
+		return
+	}
+
+	msg := &slackMessage{
+		Username:    slackUsername,
+		IconURL:     slackAvatarURL,
+		Text:        text,
+		Attachments: []slackAttachment{{Title: title, Color: "#439FE0"}},
+	}
+
+	err := notifyWithRetry(m.SlackRetries, func() error {
+		return m.post(msg)
+	})
+
+	if err != nil {
+		NotificationFailures.Add("slack", 1)
+		s.Logger.Errorf("Slack error: %q", err)
+	}
+}
+
+// Notify sends the message to the slack channel, retrying on failure
+func (m *Slack) Notify(ctx *core.Context) error {
+	return notifyWithRetry(m.SlackRetries, func() error {
+		return m.pushMessage(ctx)
+	})
+}
+
+func (m *Slack) pushMessage(ctx *core.Context) error {
+	return m.post(m.buildMessage(ctx))
+}
+
+func (m *Slack) post(msg *slackMessage) error {
 	values := make(url.Values, 0)
-	content, _ := json.Marshal(m.buildMessage(ctx))
+	content, _ := json.Marshal(msg)
 	values.Add(slackPayloadVar, string(content))
 
 	r, err := http.PostForm(m.SlackWebhook, values)
 	if err != nil {
-		ctx.Logger.Errorf("Slack error calling %q error: %q", m.SlackWebhook, err)
-	} else if r.StatusCode != 200 {
-		ctx.Logger.Errorf("Slack error non-200 status code calling %q", m.SlackWebhook)
+		return fmt.Errorf("error calling %q: %s", m.SlackWebhook, err)
 	}
+
+	r.Body.Close()
+	if r.StatusCode != 200 {
+		return fmt.Errorf("non-200 status code calling %q", m.SlackWebhook)
+	}
+
+	return nil
 }
 
 func (m *Slack) buildMessage(ctx *core.Context) *slackMessage {
@@ -75,13 +189,25 @@ func (m *Slack) buildMessage(ctx *core.Context) *slackMessage {
 
 	msg.Text = fmt.Sprintf(
 		"Job *%q* finished in *%s*, command `%s`",
-		ctx.Job.GetName(), ctx.Execution.Duration, ctx.Job.GetCommand(),
+		ctx.Job.GetName(), ctx.Execution.Duration, ctx.Redact(ctx.Job.GetCommand()),
 	)
 
+	if v, ok := ctx.Execution.Params[core.MatrixParam]; ok {
+		msg.Text += fmt.Sprintf(", matrix `%s`", v)
+	}
+
+	if late := ctx.Execution.Late(); late != 0 {
+		msg.Text += fmt.Sprintf(", started *%s* late", late)
+	}
+
+	for _, line := range annotations(ctx.Job) {
+		msg.Text += "\n" + ctx.Redact(line)
+	}
+
 	if ctx.Execution.Failed {
 		msg.Attachments = append(msg.Attachments, slackAttachment{
 			Title: "Execution failed",
-			Text:  ctx.Execution.Error.Error(),
+			Text:  ctx.Redact(ctx.Execution.Error.Error()),
 			Color: "#F35A00",
 		})
 	} else if ctx.Execution.Skipped {
@@ -96,9 +222,203 @@ func (m *Slack) buildMessage(ctx *core.Context) *slackMessage {
 		})
 	}
 
+	// the status links to the page of the execution, with its full output
+	msg.Attachments[0].TitleLink = ctx.ExecutionURL()
+
+	if diff := ctx.Execution.OutputDiff; diff != "" {
+		msg.Attachments = append(msg.Attachments, slackAttachment{
+			Title: "Output changes since the last successful execution",
+			Text:  "```" + diff + "```",
+		})
+	}
+
+	return msg
+}
+
+// jobHistory of the executions of a job
+type jobHistory struct {
+	// Executions and Failures since the last summary
+	Executions, Failures int
+	// Streak of consecutive failures
+	Streak      int
+	LastSuccess time.Time
+	LastError   string
+}
+
+// slackHistory keeps the history of the executions of the jobs, posting the
+// summaries and the escalations of the failure streaks
+type slackHistory struct {
+	slack *Slack
+	next  func(time.Time) time.Time
+	since time.Time
+
+	mu     sync.Mutex
+	jobs   map[string]*jobHistory
+	logger core.Logger
+	timer  *time.Timer
+}
+
+func (h *slackHistory) add(ctx *core.Context) {
+	h.mu.Lock()
+	name := ctx.Job.GetName()
+	j, ok := h.jobs[name]
+	if !ok {
+		j = &jobHistory{}
+		h.jobs[name] = j
+	}
+
+	j.Executions++
+	switch {
+	case ctx.Execution.Failed:
+		j.Failures++
+		j.Streak++
+		j.LastError = ctx.Redact(ctx.Execution.Error.Error())
+	case !ctx.Execution.Skipped:
+		j.Streak = 0
+		j.LastSuccess = ctx.Execution.Date
+	}
+
+	var msg *slackMessage
+	if n := h.slack.SlackEscalateAfter; n > 0 && j.Streak > 0 && j.Streak%n == 0 {
+		msg = h.slack.buildEscalation(name, j)
+	}
+
+	h.logger = logger(ctx)
+	if h.next != nil && h.timer == nil {
+		now := time.Now()
+		if h.since.IsZero() {
+			h.since = now
+		}
+
+		h.timer = time.AfterFunc(h.next(now).Sub(now), h.flush)
+	}
+
+	h.mu.Unlock()
+
+	if msg == nil {
+		return
+	}
+
+	err := notifyWithRetry(h.slack.SlackRetries, func() error {
+		return h.slack.post(msg)
+	})
+
+	if err != nil {
+		notificationFailed(ctx, "slack", err)
+	}
+}
+
+// flush posts the summary of the executions since the previous one
+func (h *slackHistory) flush() {
+	h.mu.Lock()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+
+	now := time.Now()
+	msg := h.slack.buildSummary(h.since, now, h.jobs)
+	for _, j := range h.jobs {
+		j.Executions, j.Failures = 0, 0
+	}
+
+	logger := h.logger
+	h.since, h.timer = now, nil
+	h.mu.Unlock()
+
+	if msg == nil {
+		return
+	}
+
+	err := notifyWithRetry(h.slack.SlackRetries, func() error {
+		return h.slack.post(msg)
+	})
+
+	if err != nil {
+		NotificationFailures.Add("slack", 1)
+		logger.Errorf("Slack error: %q", err)
+	}
+}
+
+func (m *Slack) buildEscalation(name string, j *jobHistory) *slackMessage {
+	last := "never"
+	if !j.LastSuccess.IsZero() {
+		last = ago(time.Since(j.LastSuccess))
+	}
+
+	return &slackMessage{
+		Username: slackUsername,
+		IconURL:  slackAvatarURL,
+		Text:     fmt.Sprintf("Job *%q* has failed %d times in a row, last success %s", name, j.Streak, last),
+		Attachments: []slackAttachment{{
+			Title: "Last error",
+			Text:  j.LastError,
+			Color: "#F35A00",
+		}},
+	}
+}
+
+// buildSummary returns the summary of the executions of the jobs, nil if
+// there are none
+func (m *Slack) buildSummary(since, until time.Time, jobs map[string]*jobHistory) *slackMessage {
+	var names []string
+	var executions, failures int
+	for name, j := range jobs {
+		if j.Executions == 0 {
+			continue
+		}
+
+		names = append(names, name)
+		executions += j.Executions
+		failures += j.Failures
+	}
+
+	if executions == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	msg := &slackMessage{
+		Username: slackUsername,
+		IconURL:  slackAvatarURL,
+		Text: fmt.Sprintf(
+			"Summary from %s to %s: *%d* executions, *%d* failed",
+			since.Format("2006-01-02 15:04"), until.Format("2006-01-02 15:04"), executions, failures,
+		),
+	}
+
+	for _, name := range names {
+		j := jobs[name]
+		a := slackAttachment{
+			Title: fmt.Sprintf("Job %q: %d executions, %d failed", name, j.Executions, j.Failures),
+			Color: "#7CD197",
+		}
+
+		if j.Failures > 0 {
+			a.Text, a.Color = j.LastError, "#F35A00"
+		}
+
+		msg.Attachments = append(msg.Attachments, a)
+	}
+
 	return msg
 }
 
+// ago returns the given duration in days, hours or minutes
+func ago(d time.Duration) string {
+	n, unit := int(d/time.Minute), "minute"
+	if d >= 24*time.Hour {
+		n, unit = int(d/(24*time.Hour)), "day"
+	} else if d >= time.Hour {
+		n, unit = int(d/time.Hour), "hour"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
+
 type slackMessage struct {
 	Text        string            `json:"text"`
 	Username    string            `json:"username"`
@@ -109,5 +429,7 @@ type slackMessage struct {
 type slackAttachment struct {
 	Color string `json:"color,omitempty"`
 	Title string `json:"title,omitempty"`
-	Text  string `json:"text"`
+	// TitleLink link of the title, e.g. to the page of the execution
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text"`
 }
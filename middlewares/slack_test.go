@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	. "gopkg.in/check.v1"
 )
@@ -64,3 +65,109 @@ func (s *SuiteSlack) TestRunSuccessOnError(c *C) {
 	m := NewSlack(&SlackConfig{SlackWebhook: ts.URL, SlackOnlyOnError: true})
 	c.Assert(m.Run(s.ctx), IsNil)
 }
+
+func (s *SuiteSlack) TestRunEscalation(c *C) {
+	var messages []slackMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		messages = append(messages, m)
+	}))
+
+	defer ts.Close()
+
+	m := NewSlack(&SlackConfig{SlackWebhook: ts.URL, SlackEscalateAfter: 2}).(*Slack)
+	for i := 0; i < 5; i++ {
+		s.ctx.Start()
+		s.ctx.Stop(errors.New("foo"))
+		c.Assert(m.Run(s.ctx), IsNil)
+	}
+
+	c.Assert(messages, HasLen, 2)
+	c.Assert(messages[0].Text, Equals, `Job *""* has failed 2 times in a row, last success never`)
+	c.Assert(messages[1].Text, Matches, `.* has failed 4 times .*`)
+	c.Assert(messages[1].Attachments[0].Text, Equals, "foo")
+}
+
+func (s *SuiteSlack) TestRunSummary(c *C) {
+	var messages []slackMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		json.Unmarshal([]byte(r.FormValue(slackPayloadVar)), &m)
+		messages = append(messages, m)
+	}))
+
+	defer ts.Close()
+
+	m := NewSlack(&SlackConfig{SlackWebhook: ts.URL, SlackSummary: "daily"}).(*Slack)
+
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+	c.Assert(m.Run(s.ctx), IsNil)
+
+	s.ctx.Start()
+	s.ctx.Stop(errors.New("foo"))
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(messages, HasLen, 0)
+
+	m.history.flush()
+	c.Assert(messages, HasLen, 1)
+	c.Assert(messages[0].Text, Matches, `Summary from .*: \*2\* executions, \*1\* failed`)
+	c.Assert(messages[0].Attachments, HasLen, 1)
+	c.Assert(messages[0].Attachments[0].Text, Equals, "foo")
+
+	m.history.flush()
+	c.Assert(messages, HasLen, 1)
+}
+
+func (s *SuiteSlack) TestAgo(c *C) {
+	c.Assert(ago(30*time.Second), Equals, "0 minutes ago")
+	c.Assert(ago(time.Hour+time.Minute), Equals, "1 hour ago")
+	c.Assert(ago(3*24*time.Hour+time.Hour), Equals, "3 days ago")
+}
+
+func (s *SuiteSlack) TestBuildMessageLate(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+	s.ctx.Execution.Skew = 90 * time.Second
+
+	m := NewSlack(&SlackConfig{SlackWebhook: "http://localhost"}).(*Slack)
+	c.Assert(m.buildMessage(s.ctx).Text, Matches, ".*, started \\*1m30s\\* late")
+}
+
+func (s *SuiteSlack) TestBuildMessageAnnotations(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+	s.job.Description = "Says foo"
+	s.job.Owner = "team-a"
+	s.job.SourceContainer = "shop_web_1"
+	s.job.SourceProject = "shop"
+	s.job.Tags = []string{"tier=critical", "env=production"}
+
+	m := NewSlack(&SlackConfig{SlackWebhook: "http://localhost"}).(*Slack)
+	c.Assert(m.buildMessage(s.ctx).Text, Matches, "(?s).*\nDescription: Says foo\nOwner: team-a\nSource: container shop_web_1, project shop\nTags: env=production, tier=critical")
+}
+
+func (s *SuiteSlack) TestBuildMessageExecutionURL(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewSlack(&SlackConfig{SlackWebhook: "http://localhost"}).(*Slack)
+	c.Assert(m.buildMessage(s.ctx).Attachments[0].TitleLink, Equals, "")
+
+	s.ctx.Scheduler.ExternalURL = "https://ofelia.example.com"
+	c.Assert(m.buildMessage(s.ctx).Attachments[0].TitleLink, Equals, "https://ofelia.example.com/api/executions/"+s.ctx.Execution.ID)
+}
+
+func (s *SuiteSlack) TestBuildMessageOutputDiff(c *C) {
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewSlack(&SlackConfig{SlackWebhook: "http://localhost"}).(*Slack)
+	c.Assert(m.buildMessage(s.ctx).Attachments, HasLen, 1)
+
+	s.ctx.Execution.OutputDiff = "-a\n+b"
+	msg := m.buildMessage(s.ctx)
+	c.Assert(msg.Attachments, HasLen, 2)
+	c.Assert(msg.Attachments[1].Text, Equals, "```-a\n+b```")
+}
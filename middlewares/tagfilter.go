@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// TagFilter selects the jobs reported by a notifier by their tags, see
+// core.JobTags. A tag of the filter is "<name>", matching any value, or
+// "<name>=<value>".
+type TagFilter struct {
+	// OnlyTags when set, only the jobs with any of these tags are reported
+	OnlyTags []string `json:",omitempty"`
+	// ExcludeTags the jobs with any of these tags aren't reported
+	ExcludeTags []string `json:",omitempty"`
+}
+
+// accepts returns true if the notifier reports the given job
+func (f *TagFilter) accepts(j core.Job) bool {
+	if len(f.OnlyTags) > 0 && !core.JobHasTags(j, f.OnlyTags) {
+		return false
+	}
+
+	return !core.JobHasTags(j, f.ExcludeTags)
+}
+
+// notifies returns true if the notifier reports the job of the context, it
+// accepts the job and its notifications aren't silenced, see core.Silence
+func (f *TagFilter) notifies(ctx *core.Context) bool {
+	if ctx.Scheduler != nil && ctx.Scheduler.Silenced(ctx.Job) {
+		return false
+	}
+
+	return f.accepts(ctx.Job)
+}
+
+func (f *TagFilter) setTagFilter(filter TagFilter) {
+	*f = filter
+}
+
+// tagFilter returns the filter of the notifier with the given name, its own
+// tags replacing the ones of all the notifiers
+func (c *NotifyConfig) tagFilter(notifier string) TagFilter {
+	return TagFilter{
+		OnlyTags:    notifierTags(c.NotifyOnlyTags, notifier),
+		ExcludeTags: notifierTags(c.NotifyExcludeTags, notifier),
+	}
+}
+
+// notifierTags returns the tags of the given values for the notifier, the
+// values are comma separated tags, for all the notifiers, or prefixed by the
+// name of a notifier, e.g. "slack:critical", for that notifier only
+func notifierTags(values []string, notifier string) []string {
+	var all, own []string
+	for _, v := range values {
+		target, tags := "", v
+		if parts := strings.SplitN(v, ":", 2); len(parts) == 2 && isPlugin(strings.TrimSpace(parts[0])) {
+			target, tags = strings.TrimSpace(parts[0]), parts[1]
+		}
+
+		if target != "" && target != notifier {
+			continue
+		}
+
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag == "" {
+				continue
+			}
+
+			if target == "" {
+				all = append(all, tag)
+			} else {
+				own = append(own, tag)
+			}
+		}
+	}
+
+	if own != nil {
+		return own
+	}
+
+	return all
+}
+
+func isPlugin(name string) bool {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	_, ok := plugins[name]
+	return ok
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteTagFilter struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteTagFilter{})
+
+func (s *SuiteTagFilter) TestAccepts(c *C) {
+	s.job.Tags = []string{"db=postgres", "tier=critical"}
+
+	c.Assert((&TagFilter{}).accepts(s.job), Equals, true)
+	c.Assert((&TagFilter{OnlyTags: []string{"db"}}).accepts(s.job), Equals, true)
+	c.Assert((&TagFilter{OnlyTags: []string{"db=mysql", "tier=critical"}}).accepts(s.job), Equals, true)
+	c.Assert((&TagFilter{OnlyTags: []string{"db=mysql"}}).accepts(s.job), Equals, false)
+	c.Assert((&TagFilter{ExcludeTags: []string{"noisy"}}).accepts(s.job), Equals, true)
+	c.Assert((&TagFilter{OnlyTags: []string{"db"}, ExcludeTags: []string{"tier"}}).accepts(s.job), Equals, false)
+	c.Assert((&TagFilter{OnlyTags: []string{"db"}}).accepts(&TestJob{}), Equals, false)
+}
+
+func (s *SuiteTagFilter) TestNotifyConfigTagFilter(c *C) {
+	config := &NotifyConfig{
+		NotifyOnlyTags:    []string{"db, critical", "slack:tier=critical", "unknown:foo"},
+		NotifyExcludeTags: []string{"mail: noisy"},
+	}
+
+	c.Assert(config.tagFilter("slack"), DeepEquals, TagFilter{OnlyTags: []string{"tier=critical"}})
+	c.Assert(config.tagFilter("mail"), DeepEquals, TagFilter{
+		OnlyTags:    []string{"db", "critical", "unknown:foo"},
+		ExcludeTags: []string{"noisy"},
+	})
+}
+
+func (s *SuiteTagFilter) TestBuildTagFilter(c *C) {
+	var called bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	defer ts.Close()
+
+	ms, err := Build(decodeFrom(
+		&SlackConfig{SlackWebhook: ts.URL},
+		&NotifyConfig{NotifyOnlyTags: []string{"slack:critical"}},
+	))
+
+	c.Assert(err, IsNil)
+	c.Assert(ms, HasLen, 1)
+	c.Assert(ms[0].(*Slack).TagFilter, DeepEquals, TagFilter{OnlyTags: []string{"critical"}})
+
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+	c.Assert(ms[0].Run(s.ctx), IsNil)
+	c.Assert(called, Equals, false)
+
+	s.job.Tags = []string{"critical=true"}
+	c.Assert(ms[0].Run(s.ctx), IsNil)
+	c.Assert(called, Equals, true)
+}
+
+func (s *SuiteTagFilter) TestNotifiesSilenced(c *C) {
+	s.job.Tags = []string{"db=postgres"}
+
+	f := &TagFilter{}
+	c.Assert(f.notifies(s.ctx), Equals, true)
+
+	silence := s.ctx.Scheduler.Silence("socket", time.Hour, []string{"db=mysql"})
+	c.Assert(f.notifies(s.ctx), Equals, true)
+	c.Assert(s.ctx.Scheduler.Unsilence("socket", silence.ID), IsNil)
+
+	s.ctx.Scheduler.Silence("socket", time.Hour, []string{"db"})
+	c.Assert(f.notifies(s.ctx), Equals, false)
+}
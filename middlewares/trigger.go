@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+// TriggerConfig configuration for the Trigger middleware
+type TriggerConfig struct {
+	OnSuccess string `gcfg:"on-success" mapstructure:"on-success"`
+	OnFailure string `gcfg:"on-failure" mapstructure:"on-failure"`
+}
+
+// NewTrigger returns a Trigger middleware if the given configuration is not empty
+func NewTrigger(c *TriggerConfig) core.Middleware {
+	var m core.Middleware
+	if !IsEmpty(c) {
+		m = &Trigger{*c}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "trigger",
+		Stage:  StageTrigger,
+		Config: func() interface{} { return &TriggerConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewTrigger(c.(*TriggerConfig)) },
+	})
+}
+
+// Trigger middleware runs other jobs based on the result of the execution
+type Trigger struct {
+	TriggerConfig
+}
+
+// ContinueOnStop return allways true, failed executions trigger jobs too
+func (m *Trigger) ContinueOnStop() bool {
+	return true
+}
+
+// Run runs the on-success or on-failure job after the execution finishes,
+// with the params describing it, see core.ParentParams. Skipped executions
+// don't trigger any job
+func (m *Trigger) Run(ctx *core.Context) error {
+	err := ctx.Next()
+	ctx.Stop(err)
+
+	var name string
+	switch {
+	case ctx.Execution.Skipped:
+	case ctx.Execution.Failed:
+		name = m.OnFailure
+	default:
+		name = m.OnSuccess
+	}
+
+	if name == "" {
+		return err
+	}
+
+	if err := ctx.Scheduler.RunJobWithParams(name, core.ParentParams(ctx)); err != nil {
+		logger(ctx).Errorf("Trigger error running %q: %q", name, err)
+	} else {
+		ctx.Log("Triggered job " + name)
+	}
+
+	return err
+}
+
+// Check verifies the triggered jobs are registered at the given scheduler
+func (m *Trigger) Check(s *core.Scheduler) error {
+	for _, name := range []string{m.OnSuccess, m.OnFailure} {
+		if name != "" && s.GetJob(name) == nil {
+			return fmt.Errorf("unknown job %q at on-success/on-failure", name)
+		}
+	}
+
+	return nil
+}
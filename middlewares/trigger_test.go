@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"errors"
+
+	"github.com/mcuadros/ofelia/core"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteTrigger struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteTrigger{})
+
+func (s *SuiteTrigger) TestNewTriggerEmpty(c *C) {
+	c.Assert(NewTrigger(&TriggerConfig{}), IsNil)
+}
+
+func (s *SuiteTrigger) TestRunOnSuccess(c *C) {
+	success, failure := s.addJob("success"), s.addJob("failure")
+
+	s.ctx.Start()
+	s.ctx.Stop(nil)
+
+	m := NewTrigger(&TriggerConfig{OnSuccess: "success", OnFailure: "failure"})
+	c.Assert(m.Run(s.ctx), IsNil)
+
+	s.wait(c)
+	c.Assert(success.Called, Equals, 1)
+	c.Assert(failure.Called, Equals, 0)
+}
+
+func (s *SuiteTrigger) TestRunOnFailure(c *C) {
+	success, failure := s.addJob("success"), s.addJob("failure")
+
+	s.ctx.Start()
+	s.ctx.Stop(errors.New("foo"))
+
+	m := NewTrigger(&TriggerConfig{OnSuccess: "success", OnFailure: "failure"})
+	c.Assert(m.Run(s.ctx), IsNil)
+
+	s.wait(c)
+	c.Assert(success.Called, Equals, 0)
+	c.Assert(failure.Called, Equals, 1)
+	c.Assert(failure.Params[core.ParentParam], Equals, s.ctx.Job.GetName())
+	c.Assert(failure.Params[core.ParentErrorParam], Equals, "foo")
+}
+
+func (s *SuiteTrigger) TestCheck(c *C) {
+	s.addJob("success")
+
+	m := &Trigger{TriggerConfig{OnSuccess: "success"}}
+	c.Assert(m.Check(s.ctx.Scheduler), IsNil)
+
+	m = &Trigger{TriggerConfig{OnFailure: "failure"}}
+	c.Assert(m.Check(s.ctx.Scheduler), NotNil)
+}
+
+func (s *SuiteTrigger) addJob(name string) *TestCountJob {
+	j := &TestCountJob{}
+	j.Name = name
+	s.ctx.Scheduler.AddTriggeredJob(j)
+
+	return j
+}
+
+// wait waits for the triggered executions, stopping the scheduler
+func (s *SuiteTrigger) wait(c *C) {
+	c.Assert(s.ctx.Scheduler.Start(), IsNil)
+	c.Assert(s.ctx.Scheduler.Stop(), IsNil)
+}
+
+type TestCountJob struct {
+	core.BareJob
+	Called int
+	Params map[string]string
+}
+
+func (j *TestCountJob) Run(ctx *core.Context) error {
+	j.Called++
+	j.Params = ctx.Execution.Params
+	return nil
+}
@@ -0,0 +1,224 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/ofelia/core"
+)
+
+const defaultWorkloadDeadline = time.Hour
+
+// workloadBackoff delay before checking the workload of the host again, doubled
+// every time up to workloadMaxBackoff
+var (
+	workloadBackoff    = 30 * time.Second
+	workloadMaxBackoff = 5 * time.Minute
+)
+
+// hostWorkload returns the load average of the last minute and the available
+// memory, in bytes, of the host, replaced by the tests
+var hostWorkload = readHostWorkload
+
+// WorkloadConfig configuration for the Workload middleware
+type WorkloadConfig struct {
+	// MaxLoad the executions are deferred while the load average of the last
+	// minute of the host is above it, e.g. "4"
+	MaxLoad string `gcfg:"max-load" mapstructure:"max-load"`
+	// MinFreeMemory the executions are deferred while the available memory of
+	// the host is below it, e.g. "512MB"
+	MinFreeMemory string `gcfg:"min-free-memory" mapstructure:"min-free-memory"`
+	// WorkloadDeadline the executions still deferred after it are skipped,
+	// 1h by default
+	WorkloadDeadline string `gcfg:"workload-deadline" mapstructure:"workload-deadline"`
+}
+
+// NewWorkload returns a Workload middleware if the given configuration is not
+// empty
+func NewWorkload(c *WorkloadConfig) core.Middleware {
+	var m core.Middleware
+	if c.MaxLoad != "" || c.MinFreeMemory != "" {
+		m = &Workload{WorkloadConfig: *c}
+	}
+
+	return m
+}
+
+func init() {
+	Register(Plugin{
+		Name:   "workload",
+		Stage:  StageGuard,
+		Global: true,
+		Config: func() interface{} { return &WorkloadConfig{} },
+		New:    func(c interface{}) core.Middleware { return NewWorkload(c.(*WorkloadConfig)) },
+	})
+}
+
+// Workload middleware defers the executions while the host is busy, protecting
+// its workloads from the heavy jobs, e.g. maintenance ones
+type Workload struct {
+	WorkloadConfig
+}
+
+// ContinueOnStop Workload is only called if the process is still running
+func (m *Workload) ContinueOnStop() bool {
+	return false
+}
+
+// Run waits until the workload of the host is under the thresholds, checking
+// it with exponential backoff, skipping the execution past the deadline
+func (m *Workload) Run(ctx *core.Context) error {
+	limits, err := m.limits()
+	if err != nil {
+		ctx.Warn(err.Error())
+		return ctx.Next()
+	}
+
+	deferWhile(ctx, "workload", limits.deadline, func() (string, error) {
+		busy, err := limits.busy()
+		if err != nil {
+			return "", fmt.Errorf("unable to read the workload of the host, running anyway: %s", err)
+		}
+
+		return busy, nil
+	})
+
+	return ctx.Next()
+}
+
+// deferWhile waits while check returns why the execution can't run yet,
+// checking again with exponential backoff, and stops the execution as skipped
+// past the given deadline. The errors of check are warned, and the execution
+// runs.
+func deferWhile(ctx *core.Context, name string, deadline time.Duration, check func() (string, error)) {
+	until := time.Now().Add(deadline)
+	backoff := workloadBackoff
+	for {
+		reason, err := check()
+		if err != nil {
+			ctx.Warn(err.Error())
+			return
+		}
+
+		if reason == "" {
+			return
+		}
+
+		if !time.Now().Add(backoff).Before(until) {
+			ctx.Log(fmt.Sprintf("Skipping, %s after the %s deadline %s", reason, name, deadline))
+			ctx.Stop(core.ErrSkippedExecution)
+			return
+		}
+
+		ctx.Log(fmt.Sprintf("Deferred for %s, %s", backoff, reason))
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > workloadMaxBackoff {
+			backoff = workloadMaxBackoff
+		}
+	}
+}
+
+// workloadLimits the parsed thresholds of a Workload middleware
+type workloadLimits struct {
+	maxLoad  float64
+	minFree  uint64
+	deadline time.Duration
+}
+
+func (m *Workload) limits() (*workloadLimits, error) {
+	l := &workloadLimits{deadline: defaultWorkloadDeadline}
+	if m.MaxLoad != "" {
+		var err error
+		if l.maxLoad, err = strconv.ParseFloat(m.MaxLoad, 64); err != nil || l.maxLoad <= 0 {
+			return nil, fmt.Errorf("invalid max-load %q, must be a positive number", m.MaxLoad)
+		}
+	}
+
+	if m.MinFreeMemory != "" {
+		var err error
+		if l.minFree, err = parseSize(m.MinFreeMemory); err != nil {
+			return nil, fmt.Errorf("invalid min-free-memory %q: %s", m.MinFreeMemory, err)
+		}
+	}
+
+	if m.WorkloadDeadline != "" {
+		var err error
+		if l.deadline, err = time.ParseDuration(m.WorkloadDeadline); err != nil || l.deadline <= 0 {
+			return nil, fmt.Errorf("invalid workload-deadline %q, must be a positive duration", m.WorkloadDeadline)
+		}
+	}
+
+	return l, nil
+}
+
+// busy returns why the host is too busy to run the execution, empty if it
+// isn't
+func (l *workloadLimits) busy() (string, error) {
+	load, free, err := hostWorkload()
+	if err != nil {
+		return "", err
+	}
+
+	if l.maxLoad > 0 && load > l.maxLoad {
+		return fmt.Sprintf("load %.2f above max-load %g", load, l.maxLoad), nil
+	}
+
+	if l.minFree > 0 && free < l.minFree {
+		return fmt.Sprintf("free memory %s below min-free-memory %s", formatSize(free), formatSize(l.minFree)), nil
+	}
+
+	return "", nil
+}
+
+// readHostWorkload reads the workload of the host from /proc, linux only
+func readHostWorkload() (float64, uint64, error) {
+	loadavg, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(string(loadavg))
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("unexpected /proc/loadavg %q", loadavg)
+	}
+
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// MemAvailable:    8024564 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return load, kb * 1024, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	return 0, 0, fmt.Errorf("MemAvailable not found in /proc/meminfo")
+}
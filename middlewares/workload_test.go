@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type SuiteWorkload struct {
+	BaseSuite
+}
+
+var _ = Suite(&SuiteWorkload{})
+
+func (s *SuiteWorkload) SetUpTest(c *C) {
+	s.BaseSuite.SetUpTest(c)
+	workloadBackoff, workloadMaxBackoff = time.Millisecond, 4*time.Millisecond
+}
+
+func (s *SuiteWorkload) TearDownTest(c *C) {
+	hostWorkload = readHostWorkload
+	workloadBackoff, workloadMaxBackoff = 30*time.Second, 5*time.Minute
+}
+
+func (s *SuiteWorkload) TestNewWorkloadEmpty(c *C) {
+	c.Assert(NewWorkload(&WorkloadConfig{}), IsNil)
+	c.Assert(NewWorkload(&WorkloadConfig{WorkloadDeadline: "10m"}), IsNil)
+}
+
+func (s *SuiteWorkload) TestRunDeferred(c *C) {
+	var checks int
+	hostWorkload = func() (float64, uint64, error) {
+		checks++
+		if checks < 3 {
+			return 8, 1 << 30, nil
+		}
+
+		return 1, 1 << 30, nil
+	}
+
+	m := NewWorkload(&WorkloadConfig{MaxLoad: "4", MinFreeMemory: "512MB"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(checks, Equals, 3)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteWorkload) TestRunSkippedAfterDeadline(c *C) {
+	hostWorkload = func() (float64, uint64, error) {
+		return 1, 100 << 20, nil
+	}
+
+	m := NewWorkload(&WorkloadConfig{MinFreeMemory: "512MB", WorkloadDeadline: "10ms"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, true)
+}
+
+func (s *SuiteWorkload) TestRunUnreadable(c *C) {
+	hostWorkload = func() (float64, uint64, error) {
+		return 0, 0, fmt.Errorf("no /proc")
+	}
+
+	m := NewWorkload(&WorkloadConfig{MaxLoad: "4"})
+	s.ctx.Execution.Start()
+	c.Assert(m.Run(s.ctx), IsNil)
+	c.Assert(s.ctx.Execution.Skipped, Equals, false)
+}
+
+func (s *SuiteWorkload) TestLimits(c *C) {
+	l, err := (&Workload{WorkloadConfig{MaxLoad: "2.5", MinFreeMemory: "1.5G"}}).limits()
+	c.Assert(err, IsNil)
+	c.Assert(l, DeepEquals, &workloadLimits{maxLoad: 2.5, minFree: 3 << 29, deadline: time.Hour})
+
+	_, err = (&Workload{WorkloadConfig{MaxLoad: "high"}}).limits()
+	c.Assert(err, ErrorMatches, `invalid max-load "high", must be a positive number`)
+
+	_, err = (&Workload{WorkloadConfig{MinFreeMemory: "lots"}}).limits()
+	c.Assert(err, ErrorMatches, `invalid min-free-memory "lots": must be a positive size, e.g. 512MB`)
+
+	_, err = (&Workload{WorkloadConfig{MaxLoad: "4", WorkloadDeadline: "-1m"}}).limits()
+	c.Assert(err, ErrorMatches, `invalid workload-deadline "-1m", must be a positive duration`)
+}
+
+func (s *SuiteWorkload) TestReadHostWorkload(c *C) {
+	if _, err := os.Stat("/proc/loadavg"); err != nil {
+		c.Skip("no /proc/loadavg")
+	}
+
+	load, free, err := readHostWorkload()
+	c.Assert(err, IsNil)
+	c.Assert(load >= 0, Equals, true)
+	c.Assert(free > 0, Equals, true)
+}
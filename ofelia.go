@@ -15,6 +15,18 @@ func main() {
 	parser := flags.NewNamedParser("ofelia", flags.Default)
 	parser.AddCommand("daemon", "daemon process", "", &cli.DaemonCommand{})
 	parser.AddCommand("validate", "validates the config file", "", &cli.ValidateCommand{})
+	parser.AddCommand("simulate", "lists the executions of the jobs in a period of time", "", &cli.SimulateCommand{})
+	parser.AddCommand("explain", "describes a schedule and lists its next times", "", &cli.ExplainCommand{})
+	parser.AddCommand("status", "lists the jobs of the running daemon", "", &cli.StatusCommand{})
+	parser.AddCommand("run", "runs a job of the running daemon", "", &cli.RunCommand{})
+	parser.AddCommand("silence", "silences the notifications of the running daemon", "", &cli.SilenceCommand{})
+	parser.AddCommand("skip", "skips the next occurrences of a job of the running daemon", "", &cli.SkipCommand{})
+	parser.AddCommand("apply", "reconciles the jobs of the running daemon to a job set", "", &cli.ApplyCommand{})
+	parser.AddCommand("cluster", "lists the jobs or executions of several running daemons", "", &cli.ClusterCommand{})
+	parser.AddCommand("once", "runs a job of the running daemon once at a given time", "", &cli.OnceCommand{})
+	config, _ := parser.AddCommand("config", "inspects the configuration", "", &cli.ConfigCommand{})
+	config.AddCommand("dump", "prints the configuration with the defaults applied", "", &cli.ConfigDumpCommand{})
+	parser.AddCommand("convert", "converts a crontab to job-local sections", "", &cli.ConvertCommand{})
 
 	if _, err := parser.Parse(); err != nil {
 		if _, ok := err.(*flags.Error); ok {